@@ -5,11 +5,11 @@ import (
 	"fmt"
 	"math/rand"
 	"net"
+	"path/filepath"
 	"sync"
 	"time"
 
 	"github.com/VividCortex/ewma"
-	"github.com/niubaoshu/gotiny"
 	"github.com/p9c/qu"
 	"go.uber.org/atomic"
 
@@ -23,13 +23,16 @@ import (
 	"github.com/p9c/pod/pkg/chainrpc/job"
 	"github.com/p9c/pod/pkg/chainrpc/p2padvt"
 	"github.com/p9c/pod/pkg/chainrpc/pause"
+	"github.com/p9c/pod/pkg/chainrpc/share"
 	"github.com/p9c/pod/pkg/chainrpc/sol"
 	"github.com/p9c/pod/pkg/chainrpc/templates"
 	"github.com/p9c/pod/pkg/constant"
 	"github.com/p9c/pod/pkg/fork"
 	"github.com/p9c/pod/pkg/mining"
+	"github.com/p9c/pod/pkg/pool"
 	rav "github.com/p9c/pod/pkg/ring"
 	"github.com/p9c/pod/pkg/rpcclient"
+	"github.com/p9c/pod/pkg/stratum"
 	"github.com/p9c/pod/pkg/transport"
 	"github.com/p9c/pod/pkg/wire"
 	"github.com/p9c/pod/pod/config"
@@ -56,6 +59,8 @@ type State struct {
 	msgBlockTemplates *templates.RecentMessages
 	templateShards    [][]byte
 	multiConn         *transport.Channel
+	stratum           *stratum.Server
+	ledger            *pool.Ledger
 	otherNodes        map[uint64]*nodeSpec
 	hashSampleBuf     *rav.BufferUint64
 	hashCount         atomic.Uint64
@@ -103,12 +108,31 @@ func New(
 	}
 	s.lastBlockUpdate.Store(time.Now().Add(-time.Second * 3).Unix())
 	s.generator = chainrpc.GetBlkTemplateGenerator(node, cfg, stateCfg)
+	var id *transport.Identity
+	if id, e = transport.LoadIdentity(filepath.Join(cfg.DataDir.V(), "multicast.key")); E.Chk(e) {
+		return
+	}
+	I.Ln("multicast public key of the controller is", id.Public())
+	// solutions, shares and hashrate reports are only accepted from the pinned keys of the miners, without them anyone
+	// who knows the multicast password can send them in the name of any miner
+	var trust *transport.Trust
+	if trust, e = transport.NewTrust(cfg.MulticastWorkerKeys.S(), sol.Magic, share.Magic, hashrate.Magic); E.Chk(e) {
+		return
+	}
+	if len(cfg.MulticastWorkerKeys.S()) == 0 {
+		W.Ln(
+			"no multicast worker keys are configured, solutions, shares and hashrate reports are accepted from" +
+				" anyone who knows the multicast password; set MulticastWorkerKeys to the keys the kopach miners print" +
+				" when they start",
+		)
+	}
 	var mc *transport.Channel
-	I.S(cfg.MulticastPass.V(), cfg.MulticastPass.Bytes())
 	if mc, e = transport.NewBroadcastChannel(
 		"controller",
 		s,
 		cfg.MulticastPass.Bytes(),
+		id,
+		trust,
 		transport.DefaultPort,
 		constant.MaxDatagramSize,
 		handlersMulticast,
@@ -117,6 +141,30 @@ func New(
 		return
 	}
 	s.multiConn = mc
+	if cfg.PoolMode.True() {
+		I.Ln("opening pool share ledger")
+		if s.ledger, e = pool.NewLedger(node.DB); E.Chk(e) {
+			return
+		}
+	}
+	if stratumListeners := cfg.StratumListeners.S(); len(stratumListeners) > 0 {
+		I.Ln("starting stratum server")
+		stratumCfg := stratum.Config{
+			Listeners:  stratumListeners,
+			Submitter:  &chainrpc.SyncManager{Server: node, SyncMgr: node.SyncManager},
+			Difficulty: cfg.StratumDifficulty.V(),
+			ShareTime:  stratum.DefaultShareTime,
+		}
+		if s.ledger != nil {
+			// in pool mode stratum miners are credited in the ledger like the workers of the controller
+			stratumCfg.CheckWorker = s.checkStratumWorker
+			stratumCfg.OnShare = s.creditStratumShare
+		}
+		if s.stratum, e = stratum.New(stratumCfg, quit); E.Chk(e) {
+			return
+		}
+		s.stratum.Start()
+	}
 	go func() {
 		I.Ln("starting shutdown signal watcher")
 		select {
@@ -399,7 +447,8 @@ func (s *State) checkConnected() (connected bool) {
 
 func (s *State) doBlockUpdate(prev *block.Block) (e error) {
 	I.Ln("do block update")
-	if s.nextAddress == nil {
+	// blocks pay to the configured mining payouts when there are any, otherwise to a new address from the wallet
+	if s.nextAddress == nil && len(s.stateCfg.ActiveMiningPayouts) == 0 {
 		I.Ln("getting new address for templates")
 		// if s.nextAddress, e = s.GetNewAddressFromMiningAddrs(); T.Chk(e) {
 		if s.nextAddress, e = s.GetNewAddressFromWallet(); T.Chk(e) {
@@ -416,6 +465,9 @@ func (s *State) doBlockUpdate(prev *block.Block) (e error) {
 	}
 	// I.S(tpl)
 	s.msgBlockTemplates.Add(tpl)
+	if s.stratum != nil {
+		s.stratum.SetTemplate(tpl)
+	}
 	// I.Ln(tpl.Timestamp)
 	I.Ln("caching error corrected message shards...")
 	srl := tpl.Serialize()
@@ -429,7 +481,9 @@ func (s *State) doBlockUpdate(prev *block.Block) (e error) {
 }
 
 // GetMsgBlockTemplate gets a Message building on given block paying to a given
-// address
+// address, or to the configured mining payouts if there are any. In pool mode
+// the block reward is split between the miners with shares in the PPLNS window
+// instead
 func (s *State) GetMsgBlockTemplate(
 	prev *block.Block, addr btcaddr.Address,
 ) (mbt *templates.Message, e error) {
@@ -443,11 +497,38 @@ func (s *State) GetMsgBlockTemplate(
 		Bits:      make(templates.Diffs),
 		Merkles:   make(templates.Merkles),
 	}
+	if s.ledger != nil {
+		mbt.ShareBits = make(templates.Diffs)
+	}
+	// shares older than every PPLNS window no longer earn anything and are pruned from the ledger
+	var pruneBefore uint64
+	prune := s.ledger != nil
 	for next, curr, more := fork.AlgoVerIterator(mbt.Height); more(); next() {
 		// I.Ln("creating template for", curr())
+		payouts := s.stateCfg.ActiveMiningPayouts
+		if len(payouts) == 0 {
+			payouts = []mining.Payout{{Address: addr, Weight: 1}}
+		}
+		if s.ledger != nil {
+			var poolPayouts []mining.Payout
+			var oldest uint64
+			if poolPayouts, mbt.ShareBits[curr()], oldest, e = s.poolPayouts(curr(), mbt.Height); E.Chk(e) {
+				delete(mbt.ShareBits, curr())
+				prune = false
+			} else {
+				if len(poolPayouts) > 0 {
+					payouts = poolPayouts
+				}
+				if oldest == 0 {
+					prune = false
+				} else if pruneBefore == 0 || oldest < pruneBefore {
+					pruneBefore = oldest
+				}
+			}
+		}
 		var templateX *mining.BlockTemplate
-		if templateX, e = s.generator.NewBlockTemplate(
-			addr,
+		if templateX, e = s.generator.NewBlockTemplateWithPayouts(
+			payouts,
 			fork.GetAlgoName(curr(), mbt.Height),
 		); D.Chk(e) || templateX == nil {
 		} else {
@@ -461,6 +542,10 @@ func (s *State) GetMsgBlockTemplate(
 			mbt.SetTxs(curr(), newB.Transactions)
 		}
 	}
+	if prune {
+		if e = s.ledger.Prune(pruneBefore); E.Chk(e) {
+		}
+	}
 	return
 }
 
@@ -520,7 +605,8 @@ func (s *State) GetNewAddressFromWallet() (addr btcaddr.Address, e error) {
 // }
 
 var handlersMulticast = transport.Handlers{
-	string(sol.Magic): processSolMsg,
+	string(sol.Magic):   processSolMsg,
+	string(share.Magic): processShareMsg,
 	// string(p2padvt.Magic):  processAdvtMsg,
 	string(hashrate.Magic): processHashrateMsg,
 }
@@ -530,8 +616,10 @@ func processAdvtMsg(
 ) (e error) {
 	I.Ln("processing advertisment message", src, dst)
 	s := ctx.(*State)
-	var j p2padvt.Advertisment
-	gotiny.Unmarshal(b, &j)
+	var j *p2padvt.Advertisment
+	if j, e = p2padvt.Deserialize(b); e != nil {
+		return
+	}
 	var uuid uint64
 	uuid = j.UUID
 	// I.Ln("uuid of advertisment", uuid, s.otherNodes)
@@ -588,8 +676,10 @@ func processSolMsg(
 ) (e error) {
 	I.Ln("received solution", src, dst)
 	s := ctx.(*State)
-	var so sol.Solution
-	gotiny.Unmarshal(b, &so)
+	var so *sol.Solution
+	if so, e = sol.Deserialize(b); e != nil {
+		return
+	}
 	tpl := s.msgBlockTemplates.Find(so.Nonce)
 	if tpl == nil {
 		I.Ln("solution nonce", so.Nonce, "is not known by this controller")
@@ -599,10 +689,7 @@ func processSolMsg(
 		I.Ln("solution is for another controller")
 		return
 	}
-	var newHeader *wire.BlockHeader
-	if newHeader, e = so.Decode(); E.Chk(e) {
-		return
-	}
+	newHeader := so.Header
 	if newHeader.PrevBlock != tpl.PrevBlock {
 		I.Ln("blk submitted by kopach miner worker is stale")
 		return
@@ -682,8 +769,10 @@ func processHashrateMsg(
 	ctx interface{}, src net.Addr, dst string, b []byte,
 ) (e error) {
 	s := ctx.(*State)
-	var hr hashrate.Hashrate
-	gotiny.Unmarshal(b, &hr)
+	var hr *hashrate.Hashrate
+	if hr, e = hashrate.Deserialize(b); e != nil {
+		return
+	}
 	// only count each one once
 	if s.lastNonce == hr.Nonce {
 		return
@@ -0,0 +1,190 @@
+package ctrl
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+
+	"github.com/p9c/pod/pkg/bits"
+	"github.com/p9c/pod/pkg/blockchain"
+	"github.com/p9c/pod/pkg/btcaddr"
+	"github.com/p9c/pod/pkg/chainrpc/share"
+	"github.com/p9c/pod/pkg/fork"
+	"github.com/p9c/pod/pkg/mining"
+	"github.com/p9c/pod/pkg/pool"
+	"github.com/p9c/pod/pkg/wire"
+)
+
+const (
+	// poolShareRatio is how many times larger the share target of a template is than its block target, so on average
+	// this many shares are found for every block
+	poolShareRatio = 256
+	// maxPoolPayouts is the most outputs a pool coinbase pays to, the addresses with the smallest credit in the window
+	// are left out beyond this
+	maxPoolPayouts = 100
+)
+
+// targetWeight returns the difficulty of a target relative to the minimum difficulty of the algorithm, which is the
+// weight of a share found at the target
+func targetWeight(target *big.Int, algo string, height int32) (weight float64) {
+	if target.Sign() <= 0 {
+		return 0
+	}
+	weight, _ = new(big.Float).Quo(
+		new(big.Float).SetInt(fork.GetMinDiff(algo, height)), new(big.Float).SetInt(target),
+	).Float64()
+	return
+}
+
+// shareBits returns the compact share target for a template with the given block target, which is poolShareRatio
+// times easier than the block, but never easier than the minimum difficulty of the algorithm
+func shareBits(blockBits uint32, algo string, height int32) uint32 {
+	target := new(big.Int).Mul(bits.CompactToBig(blockBits), big.NewInt(poolShareRatio))
+	if minDiff := fork.GetMinDiff(algo, height); target.Cmp(minDiff) > 0 {
+		target = minDiff
+	}
+	return bits.BigToCompact(target)
+}
+
+// poolPayouts computes the PPLNS payouts for the next block of the given algorithm version, from the shares that add up
+// to the configured number of blocks worth of work at the current block difficulty. It also returns the share target
+// for the template, and the sequence number of the oldest share that is still in the window.
+func (s *State) poolPayouts(vers, height int32) (payouts []mining.Payout, sBits uint32, oldest uint64, e error) {
+	algo := fork.GetAlgoName(vers, height)
+	var blockBits uint32
+	if blockBits, e = s.node.Chain.CalcNextRequiredDifficulty(algo); E.Chk(e) {
+		return
+	}
+	sBits = shareBits(blockBits, algo, height)
+	window := float64(s.cfg.PoolWindow.V()) * targetWeight(bits.CompactToBig(blockBits), algo, height)
+	var credits []pool.Credit
+	if credits, oldest, e = s.ledger.Credits(window); E.Chk(e) {
+		return
+	}
+	for i := range credits {
+		if len(payouts) >= maxPoolPayouts {
+			break
+		}
+		var addr btcaddr.Address
+		if addr, e = btcaddr.Decode(credits[i].Address, s.node.ChainParams); E.Chk(e) {
+			e = nil
+			continue
+		}
+		payouts = append(payouts, mining.Payout{Address: addr, Weight: credits[i].Weight})
+	}
+	return
+}
+
+// checkShare validates a share against the template it was found for and returns its weight. Shares are only accepted
+// for the next block on the current best chain, templates that are still kept for an earlier tip or height are stale.
+func (s *State) checkShare(sh *share.Share) (weight float64, hdr *wire.BlockHeader, height int32, e error) {
+	if sh.UUID != s.uuid {
+		return 0, nil, 0, errors.New("share is for another controller")
+	}
+	tpl := s.msgBlockTemplates.Get(sh.Nonce)
+	if tpl == nil {
+		return 0, nil, 0, errors.New("share is for a template not known by this controller")
+	}
+	hdr = sh.Header
+	height = tpl.Height
+	best := s.node.Chain.BestSnapshot()
+	if hdr.PrevBlock != best.Hash || height != best.Height+1 {
+		return 0, nil, 0, errors.New("share is stale")
+	}
+	if hdr.PrevBlock != tpl.PrevBlock {
+		return 0, nil, 0, errors.New("share header does not match the template")
+	}
+	sBits, ok := tpl.ShareBits[hdr.Version]
+	if !ok || hdr.Bits != tpl.Bits[hdr.Version] || hdr.MerkleRoot != tpl.Merkles[hdr.Version] {
+		return 0, nil, 0, errors.New("share header does not match the template")
+	}
+	hash := hdr.BlockHashWithAlgos(height)
+	target := bits.CompactToBig(sBits)
+	if blockchain.HashToBig(&hash).Cmp(target) > 0 {
+		return 0, nil, 0, errors.New("share does not meet the share target")
+	}
+	if e = s.checkPayoutAddress(sh.Address); e != nil {
+		return 0, nil, 0, errors.New("share has an invalid payout address: " + e.Error())
+	}
+	weight = targetWeight(target, fork.GetAlgoName(hdr.Version, height), height)
+	return
+}
+
+// checkPayoutAddress checks that shares can be credited to an address
+func (s *State) checkPayoutAddress(address string) (e error) {
+	var addr btcaddr.Address
+	if addr, e = btcaddr.Decode(address, s.node.ChainParams); e != nil {
+		return
+	}
+	if !addr.IsForNet(s.node.ChainParams) {
+		return errors.New("address is for another network")
+	}
+	return
+}
+
+// stratumWorkerAddress returns the payout address of a stratum worker, which is its worker name up to the first dot, so
+// that miners can tell their rigs apart with names like <address>.<rig>
+func stratumWorkerAddress(worker string) string {
+	return strings.SplitN(worker, ".", 2)[0]
+}
+
+// checkStratumWorker refuses stratum workers whose names don't start with a payout address, as their shares could not
+// be credited
+func (s *State) checkStratumWorker(worker string) (e error) {
+	if e = s.checkPayoutAddress(stratumWorkerAddress(worker)); e != nil {
+		return errors.New("worker name must be a payout address: " + e.Error())
+	}
+	return
+}
+
+// creditStratumShare records a share accepted by the stratum server in the ledger. The share difficulty of stratum is
+// relative to the minimum difficulty of the algorithm, the same as the weight of the shares in the ledger.
+func (s *State) creditStratumShare(worker string, difficulty float64, height, version int32, work string) (e error) {
+	address := stratumWorkerAddress(worker)
+	T.Ln("stratum share for", address, "weight", difficulty)
+	if e = s.ledger.Add(
+		&pool.Share{Address: address, Weight: difficulty, Height: height, Version: version, Work: work},
+	); e != nil && e != pool.ErrDuplicateShare {
+		E.Ln(e)
+	}
+	return
+}
+
+// Shares submitted by workers in pool mode
+func processShareMsg(
+	ctx interface{}, src net.Addr, dst string, b []byte,
+) (e error) {
+	s := ctx.(*State)
+	if s.ledger == nil {
+		return
+	}
+	var sh *share.Share
+	if sh, e = share.Deserialize(b); e != nil {
+		return
+	}
+	var weight float64
+	var hdr *wire.BlockHeader
+	var height int32
+	if weight, hdr, height, e = s.checkShare(sh); e != nil {
+		D.Ln("rejected share from", src, e)
+		return nil
+	}
+	// the work of the share is the whole header, along with the template it was mined on
+	var buf bytes.Buffer
+	if e = hdr.Serialize(&buf); E.Chk(e) {
+		return
+	}
+	work := fmt.Sprintf("%016x:%x", sh.Nonce, buf.Bytes())
+	T.Ln("share from", src, "for", sh.Address, "weight", weight)
+	if e = s.ledger.Add(
+		&pool.Share{Address: sh.Address, Weight: weight, Height: height, Version: hdr.Version, Work: work},
+	); e == pool.ErrDuplicateShare || e == pool.ErrStaleShare {
+		D.Ln("rejected share from", src, e)
+		return nil
+	} else if E.Chk(e) {
+	}
+	return
+}
@@ -10,7 +10,6 @@ import (
 	"sync"
 	"time"
 
-	"github.com/niubaoshu/gotiny"
 	"github.com/tyler-smith/go-bip39"
 
 	"github.com/p9c/log"
@@ -151,6 +150,8 @@ func (wg *WalletGUI) Run() (e error) {
 		"controller",
 		wg,
 		wg.cx.Config.MulticastPass.Bytes(),
+		nil,
+		nil,
 		transport.DefaultPort,
 		16384,
 		handlersMulticast,
@@ -645,8 +646,10 @@ func processAdvtMsg(
 		T.Ln("no chain client to process advertisment")
 		return
 	}
-	var j p2padvt.Advertisment
-	gotiny.Unmarshal(b, &j)
+	var j *p2padvt.Advertisment
+	if j, e = p2padvt.Deserialize(b); e != nil {
+		return
+	}
 	// I.S(j)
 	var peerUUID uint64
 	peerUUID = j.UUID
@@ -67,6 +67,22 @@ func (c *Client) Stop() (e error) {
 	return
 }
 
+// SendIdentity sends the seed of the multicast identity of the miner to the
+// workers so they send their solutions in its name. It must be sent before the
+// pass
+func (c *Client) SendIdentity(seed []byte) (e error) {
+	D.Ln("sending multicast identity")
+	var reply bool
+	e = c.Call("Worker.SendIdentity", seed, &reply)
+	if e != nil {
+		return
+	}
+	if reply != true {
+		e = errors.New("send identity command not acknowledged")
+	}
+	return
+}
+
 // SendPass sends the multicast PSK to the workers so they can dispatch their
 // solutions
 func (c *Client) SendPass(pass []byte) (e error) {
@@ -81,3 +97,18 @@ func (c *Client) SendPass(pass []byte) (e error) {
 	}
 	return
 }
+
+// SetPayoutAddress sends the address that shares are credited to when mining
+// for a controller in pool mode
+func (c *Client) SetPayoutAddress(addr string) (e error) {
+	D.Ln("sending payout address")
+	var reply bool
+	e = c.Call("Worker.SetPayoutAddress", addr, &reply)
+	if e != nil {
+		return
+	}
+	if reply != true {
+		e = errors.New("set payout address command not acknowledged")
+	}
+	return
+}
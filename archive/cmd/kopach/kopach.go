@@ -6,11 +6,10 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"path/filepath"
 	"runtime"
 	"time"
 
-	"github.com/niubaoshu/gotiny"
-
 	"github.com/p9c/log"
 	"github.com/p9c/pod/pkg/chainrpc/p2padvt"
 	"github.com/p9c/pod/pkg/chainrpc/templates"
@@ -81,6 +80,8 @@ type Worker struct {
 	hashSampleBuf *rav.BufferUint64
 	hashrate      float64
 	lastNonce     uint64
+	// identity is the multicast key the miner and its workers send in the name of, which the controller can pin
+	identity *transport.Identity
 }
 
 func (w *Worker) Start() {
@@ -94,10 +95,17 @@ func (w *Worker) Start() {
 		w.clients = append(w.clients, client.New(cmd.StdConn))
 	}
 	for i := range w.clients {
-		T.Ln("sending pass to worker", i)
-		e := w.clients[i].SendPass(w.cx.Config.MulticastPass.Bytes())
+		T.Ln("sending multicast identity and pass to worker", i)
+		e := w.clients[i].SendIdentity(w.identity.Seed())
+		if E.Chk(e) {
+		}
+		e = w.clients[i].SendPass(w.cx.Config.MulticastPass.Bytes())
 		if e != nil {
 		}
+		if addr := w.cx.Config.PoolPayoutAddress.V(); addr != "" {
+			if e = w.clients[i].SetPayoutAddress(addr); E.Chk(e) {
+			}
+		}
 	}
 	D.Ln("setting workers to active")
 	w.active.Store(true)
@@ -145,9 +153,17 @@ func Run(cx *state.State) (e error) {
 	}
 	w.lastSent.Store(time.Now().UnixNano())
 	w.active.Store(false)
+	if w.identity, e = transport.LoadIdentity(filepath.Join(cx.Config.DataDir.V(), "kopach-multicast.key")); E.Chk(e) {
+		return
+	}
+	I.Ln("multicast public key of the miner is", w.identity.Public(), "- add it to MulticastWorkerKeys of the controller")
 	D.Ln("opening broadcast channel listener")
+	var trust *transport.Trust
+	if trust, e = transport.NewTrust(cx.Config.MulticastTrustedKeys.S(), job.Magic, pause.Magic); E.Chk(e) {
+		return
+	}
 	w.conn, e = transport.NewBroadcastChannel(
-		"kopachmain", w, cx.Config.MulticastPass.Bytes(),
+		"kopachmain", w, cx.Config.MulticastPass.Bytes(), w.identity, trust,
 		transport.DefaultPort, constant.MaxDatagramSize, handlers,
 		w.quit,
 	)
@@ -263,8 +279,10 @@ var handlers = transport.Handlers{
 			D.Ln("not active")
 			return
 		}
-		var hr hashrate.Hashrate
-		gotiny.Unmarshal(b, &hr)
+		var hr *hashrate.Hashrate
+		if hr, e = hashrate.Deserialize(b); e != nil {
+			return
+		}
 		// if this is not one of our workers reports ignore it
 		if hr.ID != c.id {
 			return
@@ -283,8 +301,10 @@ var handlers = transport.Handlers{
 			T.Ln("not active")
 			return
 		}
-		jr := templates.Message{}
-		gotiny.Unmarshal(b, &jr)
+		var jr *templates.Message
+		if jr, e = templates.DeserializeMsgBlockTemplate(b); e != nil {
+			return
+		}
 		w.height = jr.Height
 		cN := jr.UUID
 		firstSender := w.FirstSender.Load()
@@ -303,7 +323,7 @@ var handlers = transport.Handlers{
 		T.Ln("received job, starting workers on it", jr.Nonce, jr.UUID)
 		w.lastSent.Store(time.Now().UnixNano())
 		for i := range w.clients {
-			if e = w.clients[i].NewJob(&jr); E.Chk(e) {
+			if e = w.clients[i].NewJob(jr); E.Chk(e) {
 			}
 		}
 		return
@@ -312,8 +332,10 @@ var handlers = transport.Handlers{
 		ctx interface{}, src net.Addr, dst string, b []byte,
 	) (e error) {
 		w := ctx.(*Worker)
-		var advt p2padvt.Advertisment
-		gotiny.Unmarshal(b, &advt)
+		var advt *p2padvt.Advertisment
+		if advt, e = p2padvt.Deserialize(b); e != nil {
+			return
+		}
 		// p := pause.LoadPauseContainer(b)
 		fs := w.FirstSender.Load()
 		ni := advt.IPs
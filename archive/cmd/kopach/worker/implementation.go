@@ -18,6 +18,7 @@ import (
 
 	"github.com/p9c/pod/pkg/blockchain"
 	"github.com/p9c/pod/pkg/chainrpc/hashrate"
+	"github.com/p9c/pod/pkg/chainrpc/share"
 	"github.com/p9c/pod/pkg/chainrpc/sol"
 
 	"go.uber.org/atomic"
@@ -47,6 +48,8 @@ type Worker struct {
 	running          atomic.Bool
 	hashCount        atomic.Uint64
 	hashSampleBuf    *ring.BufferUint64
+	payoutAddress    atomic.String
+	identity         *transport.Identity
 }
 
 type Counter struct {
@@ -230,6 +233,15 @@ out:
 					// D.S(blockHeader)
 					hash := blockHeader.BlockHashWithAlgos(newHeight)
 					bigHash := blockchain.HashToBig(&hash)
+					// a controller in pool mode credits hashes that meet the share target to the payout address
+					if shareBits, ok := w.templatesMessage.ShareBits[vers]; ok && w.payoutAddress.Load() != "" &&
+						bigHash.Cmp(bits.CompactToBig(shareBits)) <= 0 {
+						srs := share.Encode(
+							w.templatesMessage.Nonce, w.templatesMessage.UUID, w.payoutAddress.Load(), blockHeader,
+						)
+						if e := w.dispatchConn.SendMany(share.Magic, transport.GetShards(srs)); E.Chk(e) {
+						}
+					}
 					if bigHash.Cmp(bits.CompactToBig(blockHeader.Bits)) <= 0 {
 						D.Ln("found solution", newHeight, w.templatesMessage.Nonce, w.templatesMessage.UUID)
 						srs := sol.Encode(w.templatesMessage.Nonce, w.templatesMessage.UUID, blockHeader)
@@ -320,6 +332,17 @@ func (w *Worker) Stop(_ int, reply *bool) (e error) {
 	return
 }
 
+// SendIdentity gives the seed of the multicast identity of the kopach miner, which the controller can pin, for the
+// workers to dispatch their solutions in its name
+func (w *Worker) SendIdentity(seed []byte, reply *bool) (e error) {
+	D.Ln("receiving multicast identity")
+	if w.identity, e = transport.IdentityFromSeed(seed); E.Chk(e) {
+		return
+	}
+	*reply = true
+	return
+}
+
 // SendPass gives the encryption key configured in the kopach controller ( pod) configuration to allow workers to
 // dispatch their solutions
 func (w *Worker) SendPass(pass []byte, reply *bool) (e error) {
@@ -332,6 +355,8 @@ func (w *Worker) SendPass(pass []byte, reply *bool) (e error) {
 		"kopachworker",
 		w,
 		pass,
+		w.identity,
+		nil,
 		transport.DefaultPort,
 		constant.MaxDatagramSize,
 		transport.Handlers{},
@@ -344,3 +369,12 @@ func (w *Worker) SendPass(pass []byte, reply *bool) (e error) {
 	*reply = true
 	return
 }
+
+// SetPayoutAddress sets the address that shares found by the worker are credited to when the controller is in pool
+// mode, no shares are sent while it is empty
+func (w *Worker) SetPayoutAddress(addr string, reply *bool) (e error) {
+	D.Ln("setting payout address", addr)
+	w.payoutAddress.Store(addr)
+	*reply = true
+	return
+}
@@ -0,0 +1,70 @@
+// This is a simulator of the difficulty adjustment of the hard forks, writing the block times and difficulties of one
+// of the standard scenarios of the diffsim package as CSV, and a summary of the blocks after the change in the
+// scenario to stderr
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/p9c/log"
+
+	"github.com/p9c/pod/pkg/diffsim"
+)
+
+func printErrorAndDie(stuff ...interface{}) {
+	fmt.Fprintln(os.Stderr, stuff...)
+	os.Exit(1)
+}
+
+func main() {
+	hf := flag.Int("fork", 1, "the hard fork to simulate, 0 for halcyon days or 1 for plan 9")
+	scenario := flag.String("scenario", "steady", "the scenario to run: steady, step, vanish or timewarp")
+	blocks := flag.Int("blocks", 3000, "the number of blocks to mine")
+	seed := flag.Int64("seed", 1, "the seed of the random source")
+	rate := flag.Float64("rate", 16, "the starting hashrate of every algorithm as a multiple of the minimum")
+	out := flag.String("out", "", "the file to write the CSV to instead of stdout")
+	level := flag.String("loglevel", "warn", "the level of logging of the difficulty adjustment")
+	flag.Parse()
+	log.SetLogLevel(*level)
+	var sc *diffsim.Scenario
+	var names []string
+	for _, s := range diffsim.Scenarios(*hf, int32(*blocks), *seed, *rate) {
+		names = append(names, s.Name)
+		if s.Name == *scenario {
+			sc = s
+		}
+	}
+	if names == nil {
+		printErrorAndDie("there is no hard fork", *hf)
+	}
+	if sc == nil {
+		printErrorAndDie("unknown scenario", *scenario, "- the scenarios are", strings.Join(names, ", "))
+	}
+	r, e := diffsim.Run(sc)
+	if e != nil {
+		printErrorAndDie(e)
+	}
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		var f *os.File
+		if f, e = os.Create(*out); e != nil {
+			printErrorAndDie(e)
+		}
+		defer f.Close()
+		w = f
+	}
+	if e = r.WriteCSV(w); e != nil {
+		printErrorAndDie(e)
+	}
+	s := r.Summarise(sc.Blocks / 2)
+	fmt.Fprintf(os.Stderr, "%s: %d blocks after the change, %.1f seconds apart\n", sc.Name, s.Blocks, s.AverageInterval)
+	for _, a := range s.Algos {
+		fmt.Fprintf(os.Stderr, "%10s %6d blocks %10.1f seconds apart, target %6.0f\n",
+			a.Name, a.Blocks, a.AverageInterval, a.Interval,
+		)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/p9c/pod/pkg/amt"
 	"github.com/p9c/pod/pkg/btcaddr"
 	"github.com/p9c/pod/pkg/connmgr"
+	"github.com/p9c/pod/pkg/mining"
 
 	"github.com/p9c/pod/pkg/chaincfg"
 )
@@ -18,6 +19,7 @@ type Config struct {
 	Dial                func(string, string, time.Duration) (net.Conn, error)
 	AddedCheckpoints    []chaincfg.Checkpoint
 	ActiveMiningAddrs   []btcaddr.Address
+	ActiveMiningPayouts []mining.Payout
 	ActiveMinerKey      []byte
 	ActiveMinRelayTxFee amt.Amount
 	ActiveWhitelists    []*net.IPNet
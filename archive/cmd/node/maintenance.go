@@ -0,0 +1,360 @@
+package node
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/p9c/interrupt"
+	"github.com/p9c/qu"
+
+	"github.com/p9c/pod/pkg/apputil"
+	"github.com/p9c/pod/pkg/blockchain"
+	"github.com/p9c/pod/pkg/chainhash"
+	"github.com/p9c/pod/pkg/database"
+	"github.com/p9c/pod/pkg/database/blockdb"
+	"github.com/p9c/pod/pkg/indexers"
+	"github.com/p9c/pod/pkg/util/prompt"
+	"github.com/p9c/pod/pod/state"
+)
+
+// resetSuffix is appended to the block database path while a chain reset is in progress. The database is moved aside
+// before any file is deleted so an interrupted reset never leaves a half deleted database where the node will look for
+// it, and running resetchain again finishes the job.
+const resetSuffix = ".reset"
+
+// errMaintenanceInterrupted is returned when a maintenance operation is stopped by an interrupt before it completed.
+var errMaintenanceInterrupted = errors.New("interrupted before completion, run the command again to finish")
+
+// DropIndexes drops the indexes selected by the DropAddrIndex, DropTxIndex and DropCfIndex flags of the node state
+// configuration from the block database and then returns without starting the node.
+//
+// The user is asked for confirmation first. Drops are resumable, if an interrupt arrives the index is marked as being
+// dropped and the drop is completed the next time the database is opened by the node or this command is run again.
+func DropIndexes(cx *state.State) (e error) {
+	if cx.Config.DbType.V() == "memdb" {
+		I.Ln("the memory database has no persistent indexes to drop")
+		return
+	}
+	var names []string
+	if cx.StateCfg.DropAddrIndex {
+		names = append(names, "address")
+	}
+	if cx.StateCfg.DropTxIndex {
+		names = append(names, "transaction")
+	}
+	if cx.StateCfg.DropCfIndex {
+		names = append(names, "cfilter")
+	}
+	if len(names) == 0 {
+		return
+	}
+	dbPath := state.BlockDb(cx, cx.Config.DbType.V(), blockdb.NamePrefix)
+	var db database.DB
+	if db, e = openExistingBlockDB(cx, dbPath); E.Chk(e) || db == nil {
+		return
+	}
+	defer func() {
+		if e := db.Close(); E.Chk(e) {
+		}
+	}()
+	var ok bool
+	if ok, e = prompt.Confirm(
+		fmt.Sprintf("drop the %v index(es) from '%s'?", names, dbPath),
+	); E.Chk(e) || !ok {
+		return
+	}
+	return dropIndexes(cx, db, maintenanceQuit())
+}
+
+// dropIndexes drops the indexes selected in the node state configuration from an open block database.
+//
+// NOTE: The order is important here because dropping the tx index also drops the address index since it relies on it
+func dropIndexes(cx *state.State, db database.DB, quit qu.C) (e error) {
+	if cx.StateCfg.DropAddrIndex {
+		W.Ln("dropping address index")
+		if e = indexers.DropAddrIndex(db, quit); E.Chk(e) {
+			return
+		}
+	}
+	if cx.StateCfg.DropTxIndex {
+		W.Ln("dropping transaction index")
+		if e = indexers.DropTxIndex(db, quit); E.Chk(e) {
+			return
+		}
+	}
+	if cx.StateCfg.DropCfIndex {
+		W.Ln("dropping cfilter index")
+		if e = indexers.DropCfIndex(db, quit); E.Chk(e) {
+			return
+		}
+	}
+	return
+}
+
+// ResetChain deletes the block database so the chain will be downloaded again from scratch the next time the node
+// starts.
+//
+// The database is opened first to make sure no running node holds it, the user is asked for confirmation, and then the
+// database directory is moved aside and deleted file by file with progress reporting. If the deletion is interrupted,
+// running the command again completes it.
+func ResetChain(cx *state.State) (e error) {
+	if cx.Config.DbType.V() == "memdb" {
+		I.Ln("the memory database has no chain data to reset")
+		return
+	}
+	dbPath := state.BlockDb(cx, cx.Config.DbType.V(), blockdb.NamePrefix)
+	resetPath := dbPath + resetSuffix
+	quit := maintenanceQuit()
+	if apputil.FileExists(resetPath) {
+		I.F("finishing previously interrupted chain reset of '%s'", resetPath)
+		if e = removeWithProgress(resetPath, quit); E.Chk(e) {
+			return
+		}
+	}
+	if !apputil.FileExists(dbPath) {
+		I.F("no block database found at '%s', nothing to reset", dbPath)
+		return
+	}
+	// opening the database acquires its lock, which fails if a node is currently using it
+	var db database.DB
+	if db, e = openExistingBlockDB(cx, dbPath); E.Chk(e) || db == nil {
+		return
+	}
+	if e = db.Close(); E.Chk(e) {
+		return
+	}
+	var ok bool
+	if ok, e = prompt.Confirm(
+		fmt.Sprintf("delete the block database at '%s' and download the chain again?", dbPath),
+	); E.Chk(e) || !ok {
+		return
+	}
+	if e = os.Rename(dbPath, resetPath); E.Chk(e) {
+		return
+	}
+	if e = removeWithProgress(resetPath, quit); E.Chk(e) {
+		return
+	}
+	I.Ln("chain reset complete, the chain will be downloaded again when the node starts")
+	return
+}
+
+// ExportUtxoSnapshot writes a snapshot of the utxo set at the tip of the chain in the block database to the file set
+// by the UTXOSnapshot option, which another node can be bootstrapped from with ImportUtxoSnapshot.
+//
+// The snapshot is written to a temporary file that is renamed once it is complete, so an existing snapshot is only
+// replaced by a complete one.
+func ExportUtxoSnapshot(cx *state.State) (e error) {
+	if cx.Config.DbType.V() == "memdb" {
+		I.Ln("the memory database has no chain to export")
+		return
+	}
+	dbPath := state.BlockDb(cx, cx.Config.DbType.V(), blockdb.NamePrefix)
+	var db database.DB
+	if db, e = openExistingBlockDB(cx, dbPath); E.Chk(e) || db == nil {
+		return
+	}
+	defer func() {
+		if e := db.Close(); E.Chk(e) {
+		}
+	}()
+	var chain *blockchain.BlockChain
+	if chain, e = blockchain.New(
+		&blockchain.Config{
+			DB:          db,
+			ChainParams: cx.ActiveNet,
+			TimeSource:  blockchain.NewMedianTime(),
+		},
+	); E.Chk(e) {
+		return
+	}
+	path := utxoSnapshotPath(cx)
+	tmpPath := path + ".tmp"
+	var f *os.File
+	if f, e = os.Create(tmpPath); E.Chk(e) {
+		return
+	}
+	best := chain.BestSnapshot()
+	I.F("writing a snapshot of the utxo set at block %s (height %d) to '%s'", best.Hash, best.Height, path)
+	w := bufio.NewWriterSize(f, 1<<20)
+	var h *blockchain.SnapshotHeader
+	if h, e = chain.ExportUtxoSnapshot(w); !E.Chk(e) {
+		e = w.Flush()
+	}
+	if ce := f.Close(); E.Chk(ce) && e == nil {
+		e = ce
+	}
+	if e != nil {
+		if re := os.Remove(tmpPath); E.Chk(re) {
+		}
+		return
+	}
+	if e = os.Rename(tmpPath, path); E.Chk(e) {
+		return
+	}
+	I.F(
+		"wrote %d utxos at block %s (height %d) with set hash %s", h.NumUtxos, h.Hash, h.Height, h.SetHash,
+	)
+	return
+}
+
+// ImportUtxoSnapshot creates a new block database from the utxo set snapshot in the file set by the UTXOSnapshot
+// option, so the node can start validating the chain from the snapshot block without validating the blocks below it,
+// which are downloaded and connected in the background once the node has caught up to verify the snapshot.
+//
+// There must not be a block database yet. The set hash of the snapshot must match the one set by the UTXOSnapshotHash
+// option, which should be the one gettxoutsetinfo reports for the snapshot block on a trusted node, or the one in the
+// chain parameters when it is empty. The user is asked for confirmation after the snapshot header is shown. A failed
+// import removes the database again; an interrupted one leaves a database the node refuses to load, which resetchain
+// removes.
+func ImportUtxoSnapshot(cx *state.State) (e error) {
+	if cx.Config.DbType.V() == "memdb" {
+		I.Ln("a utxo snapshot can't be imported into the memory database")
+		return
+	}
+	dbPath := state.BlockDb(cx, cx.Config.DbType.V(), blockdb.NamePrefix)
+	if apputil.FileExists(dbPath) {
+		return fmt.Errorf("a block database already exists at '%s', remove it with resetchain first", dbPath)
+	}
+	var expected *chainhash.Hash
+	if setHash := cx.Config.UTXOSnapshotHash.V(); setHash != "" {
+		if expected, e = chainhash.NewHashFromStr(setHash); E.Chk(e) {
+			return fmt.Errorf("invalid utxo snapshot set hash '%s': %v", setHash, e)
+		}
+	}
+	path := utxoSnapshotPath(cx)
+	var f *os.File
+	if f, e = os.Open(path); E.Chk(e) {
+		return
+	}
+	defer func() {
+		if e := f.Close(); E.Chk(e) {
+		}
+	}()
+	r := bufio.NewReaderSize(f, 1<<20)
+	var h *blockchain.SnapshotHeader
+	if h, e = blockchain.ReadSnapshotHeader(r); E.Chk(e) {
+		return fmt.Errorf("unable to read utxo snapshot '%s': %v", path, e)
+	}
+	if h.Net != cx.ActiveNet.Net {
+		return fmt.Errorf("utxo snapshot '%s' is not for %s", path, cx.ActiveNet.Name)
+	}
+	I.F(
+		"utxo snapshot '%s' has %d utxos at block %s (height %d) with set hash %s", path, h.NumUtxos, h.Hash,
+		h.Height, h.SetHash,
+	)
+	var ok bool
+	if ok, e = prompt.Confirm(
+		fmt.Sprintf(
+			"create the block database at '%s' from this snapshot, trusting it until the chain below it is verified?",
+			dbPath,
+		),
+	); E.Chk(e) || !ok {
+		return
+	}
+	var db database.DB
+	if db, e = database.Create(cx.Config.DbType.V(), dbPath, cx.ActiveNet.Net); E.Chk(e) {
+		return
+	}
+	e = blockchain.ImportUtxoSnapshot(db, cx.ActiveNet, h, expected, r)
+	if ce := db.Close(); E.Chk(ce) && e == nil {
+		e = ce
+	}
+	if e != nil {
+		if re := os.RemoveAll(dbPath); E.Chk(re) {
+		}
+		return fmt.Errorf("unable to import utxo snapshot '%s': %v", path, e)
+	}
+	I.F(
+		"imported the chain state at block %s (height %d), the node downloads and verifies the blocks below it"+
+			" once it has caught up with the chain", h.Hash, h.Height,
+	)
+	return
+}
+
+// utxoSnapshotPath returns the path of the utxo snapshot file used by the exportutxo and importutxo commands
+func utxoSnapshotPath(cx *state.State) string {
+	if path := cx.Config.UTXOSnapshot.V(); path != "" {
+		return path
+	}
+	return filepath.Join(cx.Config.DataDir.V(), cx.ActiveNet.Name, "utxo.snapshot")
+}
+
+// openExistingBlockDB opens the block database at dbPath without creating it if it does not exist, in which case it
+// returns a nil database and no error. An error opening the database usually means another process holds it.
+func openExistingBlockDB(cx *state.State, dbPath string) (db database.DB, e error) {
+	if db, e = database.Open(cx.Config.DbType.V(), dbPath, cx.ActiveNet.Net); e != nil {
+		if dbErr, ok := e.(database.DBError); ok && dbErr.ErrorCode == database.ErrDbDoesNotExist {
+			I.F("no block database found at '%s'", dbPath)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unable to open block database '%s', is a node already running? %v", dbPath, e)
+	}
+	return
+}
+
+// maintenanceQuit returns a channel that is closed when an interrupt is received, so that long running maintenance
+// operations can stop at a safe point and return normally.
+func maintenanceQuit() (quit qu.C) {
+	quit = qu.T()
+	interrupt.AddHandler(
+		func() {
+			W.Ln("interrupt received, stopping at the next safe point")
+			quit.Q()
+		},
+	)
+	return
+}
+
+// removeWithProgress removes the directory tree at path, deleting the files one at a time and logging progress
+// periodically. It stops and returns an error if the quit channel is closed before it completes.
+func removeWithProgress(path string, quit qu.C) (e error) {
+	var files []string
+	var totalSize int64
+	if e = filepath.Walk(
+		path, func(p string, info os.FileInfo, e error) error {
+			if e != nil {
+				return e
+			}
+			if !info.IsDir() {
+				files = append(files, p)
+				totalSize += info.Size()
+			}
+			return nil
+		},
+	); E.Chk(e) {
+		return
+	}
+	sort.Strings(files)
+	I.F("removing %d files (%d MB) from '%s'", len(files), totalSize>>20, path)
+	var removedSize int64
+	lastReport := time.Now()
+	for i := range files {
+		select {
+		case <-quit.Wait():
+			W.F("removed %d of %d files before interrupt", i, len(files))
+			return errMaintenanceInterrupted
+		default:
+		}
+		var info os.FileInfo
+		if info, e = os.Stat(files[i]); e == nil {
+			removedSize += info.Size()
+		}
+		if e = os.Remove(files[i]); E.Chk(e) {
+			return
+		}
+		if time.Since(lastReport) > time.Second*5 || i == len(files)-1 {
+			lastReport = time.Now()
+			I.F(
+				"removed %d/%d files, %d/%d MB", i+1, len(files),
+				removedSize>>20, totalSize>>20,
+			)
+		}
+	}
+	return os.RemoveAll(path)
+}
@@ -20,8 +20,10 @@ VERSION:
 
 COMMANDS:
      dropaddrindex  drop the address search index
-     droptxindex    drop the address search index
-     dropcfindex    drop the address search index
+     droptxindex    drop the transaction index
+     dropcfindex    drop the cfilter index
+     dropindexes    drop all of the indexes
+     resetchain     delete the block database to force redownload
 
 GLOBAL OPTIONS:
    --help, -h  show help
@@ -47,7 +49,6 @@ import (
 	"github.com/p9c/pod/pkg/constant"
 	"github.com/p9c/pod/pkg/database"
 	"github.com/p9c/pod/pkg/database/blockdb"
-	"github.com/p9c/pod/pkg/indexers"
 	"github.com/p9c/pod/pod/state"
 )
 
@@ -134,27 +135,9 @@ func NodeMain(cx *state.State) (e error) {
 	if interrupt.Requested() {
 		return nil
 	}
-	// drop indexes and exit if requested.
-	//
-	// NOTE: The order is important here because dropping the tx index also drops the address index since it relies on
-	// it
-	if cx.StateCfg.DropAddrIndex {
-		W.Ln("dropping address index")
-		if e = indexers.DropAddrIndex(db, interrupt.ShutdownRequestChan); E.Chk(e) {
-			return
-		}
-	}
-	if cx.StateCfg.DropTxIndex {
-		W.Ln("dropping transaction index")
-		if e = indexers.DropTxIndex(db, interrupt.ShutdownRequestChan); E.Chk(e) {
-			return
-		}
-	}
-	if cx.StateCfg.DropCfIndex {
-		W.Ln("dropping cfilter index")
-		if e = indexers.DropCfIndex(db, interrupt.ShutdownRequestChan); E.Chk(e) {
-			return
-		}
+	// drop indexes if requested.
+	if e = dropIndexes(cx, db, interrupt.ShutdownRequestChan); E.Chk(e) {
+		return
 	}
 	// return now if an interrupt signal was triggered
 	if interrupt.Requested() {
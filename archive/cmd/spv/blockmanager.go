@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"container/list"
 	"fmt"
-	"github.com/p9c/pod/pkg/bits"
 	"github.com/p9c/pod/pkg/block"
 	"github.com/p9c/pod/pkg/fork"
 	"math"
@@ -13,7 +12,7 @@ import (
 	"sync/atomic"
 	"time"
 	
-	"github.com/p9c/pod/pkg/util/qu"
+	"github.com/p9c/qu"
 	
 	"github.com/p9c/pod/cmd/spv/headerfs"
 	"github.com/p9c/pod/cmd/spv/headerlist"
@@ -136,18 +135,14 @@ type (
 		startHeader         *headerlist.Node
 		nextCheckpoint      *chaincfg.Checkpoint
 		lastRequested       chainhash.Hash
-		minRetargetTimespan int64 // target timespan / adjustment factor
-		maxRetargetTimespan int64 // target timespan * adjustment factor
-		blocksPerRetarget   int32 // target timespan / target time per block
+		// difficulty checks the bits of the headers against the difficulty adjustment of their algorithm
+		difficulty *blockchain.HeaderDifficulty
 	}
 )
 
 // newBlockManager returns a new bitcoin block manager. Use Start to begin processing asynchronous block and inv
 // updates.
 func newBlockManager(s *ChainService) (*blockManager, error) {
-	targetTimespan := s.chainParams.TargetTimespan
-	targetTimePerBlock := s.chainParams.TargetTimePerBlock
-	adjustmentFactor := s.chainParams.RetargetAdjustmentFactor
 	bm := blockManager{
 		server:   s,
 		peerChan: make(chan interface{}, MaxPeers*3),
@@ -163,10 +158,7 @@ func newBlockManager(s *ChainService) (*blockManager, error) {
 		reorgList: headerlist.NewBoundedMemoryChain(
 			numMaxMemHeaders,
 		),
-		quit:                qu.T(),
-		blocksPerRetarget:   int32(targetTimespan / targetTimePerBlock),
-		minRetargetTimespan: targetTimespan / adjustmentFactor,
-		maxRetargetTimespan: targetTimespan * adjustmentFactor,
+		quit: qu.T(),
 	}
 	// Next we'll create the two signals that goroutines will use to wait on a particular header chain height before
 	// starting their normal duties.
@@ -192,6 +184,9 @@ func newBlockManager(s *ChainService) (*blockManager, error) {
 	)
 	bm.headerTip = height
 	bm.headerTipHash = header.BlockHash()
+	if bm.difficulty, e = loadHeaderDifficulty(s.BlockHeaders, &s.chainParams, height); e != nil {
+		return nil, e
+	}
 	// Finally, we'll set the filter header tip so any goroutines waiting on the condition obtain the correct initial
 	// state.
 	_, bm.filterHeaderTip, e = s.RegFilterHeaders.ChainTip()
@@ -1736,7 +1731,7 @@ func (b *blockManager) handleHeadersMsg(hmsg *headersMsg) {
 		var e error
 		if prevHash.IsEqual(&blockHeader.PrevBlock) {
 			e = b.checkHeaderSanity(
-				blockHeader, maxTimestamp, false,
+				blockHeader, maxTimestamp,
 				prevNode.Height+1,
 			)
 			if e != nil {
@@ -1744,6 +1739,10 @@ func (b *blockManager) handleHeadersMsg(hmsg *headersMsg) {
 				hmsg.peer.Disconnect()
 				return
 			}
+			if e = b.difficulty.Add(blockHeader); E.Chk(e) {
+				hmsg.peer.Disconnect()
+				return
+			}
 			node.Height = prevNode.Height + 1
 			finalHeight = node.Height
 			// This header checks out, so we'll add it to our write batch.
@@ -1819,20 +1818,26 @@ func (b *blockManager) handleHeadersMsg(hmsg *headersMsg) {
 			)
 			totalWork := big.NewInt(0)
 			for j, reorgHeader := range msg.Headers[i:] {
+				reorgHeight := int32(backHeight+1) + int32(j)
 				e = b.checkHeaderSanity(
-					reorgHeader, maxTimestamp, true,
-					prevNode.Height+1,
+					reorgHeader, maxTimestamp,
+					reorgHeight,
 				)
 				if e != nil {
 					W.F("header doesn't pass sanity check: %s -- disconnecting peer", e)
 					hmsg.peer.Disconnect()
 					return
 				}
-				totalWork.Add(totalWork, blockchain.CalcWork(reorgHeader.Bits, prevNode.Height+1, reorgHeader.Version))
+				// the headers of the branch are kept even if it has less work, so the branch can be extended later
+				if e = b.difficulty.Add(reorgHeader); E.Chk(e) {
+					hmsg.peer.Disconnect()
+					return
+				}
+				totalWork.Add(totalWork, blockchain.CalcWork(reorgHeader.Bits, reorgHeight, reorgHeader.Version))
 				b.reorgList.PushBack(
 					headerlist.Node{
 						Header: *reorgHeader,
-						Height: int32(backHeight+1) + int32(j),
+						Height: reorgHeight,
 					},
 				)
 			}
@@ -1985,18 +1990,15 @@ func (b *blockManager) handleHeadersMsg(hmsg *headersMsg) {
 	b.newHeadersSignal.Broadcast()
 }
 
-// checkHeaderSanity checks the PoW, and timestamp of a block header.
+// checkHeaderSanity checks the difficulty, PoW, and timestamp of a block header. The bits of the header must be the
+// difficulty the adjustment of its algorithm requires after the header it builds on, and the header must meet them.
 func (b *blockManager) checkHeaderSanity(
 	blockHeader *wire.BlockHeader,
-	maxTimestamp time.Time, reorgAttempt bool, height int32,
+	maxTimestamp time.Time, height int32,
 ) (e error) {
-	diff, e := b.calcNextRequiredDifficulty(
-		blockHeader.Timestamp, reorgAttempt,
-	)
-	if e != nil {
+	if e = b.difficulty.Check(blockHeader); e != nil {
 		return e
 	}
-	blockHeader.Bits = diff
 	stubBlock := block.NewBlock(
 		&wire.Block{
 			Header: *blockHeader,
@@ -2019,135 +2021,34 @@ func (b *blockManager) checkHeaderSanity(
 	return nil
 }
 
-// calcNextRequiredDifficulty calculates the required difficulty for the
-// block after the passed previous block node based on the difficulty
-// retarget rules.
-func (b *blockManager) calcNextRequiredDifficulty(
-	newBlockTime time.Time,
-	reorgAttempt bool,
-) (uint32, error) {
-	hList := b.headerList
-	if reorgAttempt {
-		hList = b.reorgList
-	}
-	lastNode := hList.Back()
-	// Genesis block.
-	if lastNode == nil {
-		return b.server.chainParams.PowLimitBits, nil
-	}
-	// Return the previous block's difficulty requirements if this block is not at a difficulty retarget interval.
-	if (lastNode.Height+1)%b.blocksPerRetarget != 0 {
-		// For networks that support it, allow special reduction of the required difficulty once too much time has
-		// elapsed without mining a block.
-		if b.server.chainParams.ReduceMinDifficulty {
-			// Return minimum difficulty when more than the desired amount of time has elapsed without mining a block.
-			reductionTime := int64(
-				b.server.chainParams.MinDiffReductionTime /
-					time.Second,
-			)
-			allowMinTime := lastNode.Header.Timestamp.Unix() +
-				reductionTime
-			if newBlockTime.Unix() > allowMinTime {
-				return b.server.chainParams.PowLimitBits, nil
-			}
-			// The block was mined within the desired timeframe, so return the difficulty for the last block which did
-			// not have the special minimum difficulty rule applied.
-			prevBits, e := b.findPrevTestNetDifficulty(hList)
-			if e != nil {
-				return 0, e
-			}
-			return prevBits, nil
-		}
-		// For the main network (or any unrecognized networks), simply return the previous block's difficulty
-		// requirements.
-		return lastNode.Header.Bits, nil
-	}
-	// Get the block node at the previous retarget (targetTimespan days worth of blocks).
-	firstNode, e := b.server.BlockHeaders.FetchHeaderByHeight(
-		uint32(lastNode.Height + 1 - b.blocksPerRetarget),
-	)
-	if e != nil {
-		return 0, e
-	}
-	// Limit the amount of adjustment that can occur to the previous difficulty.
-	actualTimespan := lastNode.Header.Timestamp.Unix() -
-		firstNode.Timestamp.Unix()
-	adjustedTimespan := actualTimespan
-	if actualTimespan < b.minRetargetTimespan {
-		adjustedTimespan = b.minRetargetTimespan
-	} else if actualTimespan > b.maxRetargetTimespan {
-		adjustedTimespan = b.maxRetargetTimespan
-	}
-	// Calculate new target difficulty as:
-	//
-	//  currentDifficulty * (adjustedTimespan / targetTimespan)
-	//
-	// The result uses integer division which means it will be slightly rounded down. Bitcoind also uses integer
-	// division to calculate this result.
-	oldTarget := bits.CompactToBig(lastNode.Header.Bits)
-	newTarget := new(big.Int).Mul(oldTarget, big.NewInt(adjustedTimespan))
-	targetTimeSpan := b.server.chainParams.TargetTimespan
-	newTarget.Div(newTarget, big.NewInt(targetTimeSpan))
-	// Limit new value to the proof of work limit.
-	if newTarget.Cmp(b.server.chainParams.PowLimit) > 0 {
-		newTarget.Set(b.server.chainParams.PowLimit)
-	}
-	// Log new target difficulty and return it. The new target logging is intentionally converting the bits back to a
-	// number instead of using newTarget since conversion to the compact representation loses precision.
-	newTargetBits := bits.BigToCompact(newTarget)
-	D.C(
-		func() string {
-			return fmt.Sprintf(
-				"difficulty retarget at block height %d old target %08x ("+
-					"%064x) new target %08x (%064x) actual timespan %v, adjusted timespan %v, target timespan %v",
-				lastNode.Height+1,
-				lastNode.Header.Bits, oldTarget,
-				newTargetBits, bits.CompactToBig(newTargetBits),
-				time.Duration(actualTimespan)*time.Second,
-				time.Duration(adjustedTimespan)*time.Second,
-				b.server.chainParams.TargetTimespan,
-			)
-		},
-	)
-	return newTargetBits, nil
-}
+// headerDifficultyBatch is the number of headers read from the store at a time to load the difficulty adjustment
+const headerDifficultyBatch = 2000
 
-// findPrevTestNetDifficulty returns the difficulty of the previous block which did not have the special testnet minimum
-// difficulty rule applied.
-func (b *blockManager) findPrevTestNetDifficulty(hList headerlist.Chain) (uint32, error) {
-	startNode := hList.Back()
-	// Genesis block.
-	if startNode == nil {
-		return b.server.chainParams.PowLimitBits, nil
-	}
-	// Search backwards through the chain for the last block without the special rule applied.
-	iterEl := startNode
-	iterNode := &startNode.Header
-	iterHeight := startNode.Height
-	for iterNode != nil && iterHeight%b.blocksPerRetarget != 0 &&
-		iterNode.Bits == b.server.chainParams.PowLimitBits {
-		// Get the previous block node. This function is used over simply accessing iterNode.parent directly as it will
-		// dynamically create previous block nodes as needed. This helps allow only the pieces of the chain that are
-		// needed to remain in memory.
-		iterHeight--
-		el := iterEl.Prev()
-		if el != nil {
-			iterNode = &el.Header
-		} else {
-			node, e := b.server.BlockHeaders.FetchHeaderByHeight(
-				uint32(iterHeight),
-			)
-			if e != nil {
-				E.Ln("getBlockByHeight:", e)
-				return 0, e
+// loadHeaderDifficulty adds the headers in the store up to its tip to the difficulty adjustment, without checking
+// them again, so the headers that follow can be checked.
+func loadHeaderDifficulty(
+	store headerfs.BlockHeaderStore, params *chaincfg.Params, tip uint32,
+) (difficulty *blockchain.HeaderDifficulty, e error) {
+	difficulty = blockchain.NewHeaderDifficulty(params)
+	for start := uint32(1); start <= tip; start += headerDifficultyBatch {
+		end := start + headerDifficultyBatch - 1
+		if end > tip {
+			end = tip
+		}
+		var last *wire.BlockHeader
+		if last, e = store.FetchHeaderByHeight(end); e != nil {
+			return
+		}
+		lastHash := last.BlockHash()
+		var headers []wire.BlockHeader
+		if headers, _, e = store.FetchHeaderAncestors(end-start, &lastHash); e != nil {
+			return
+		}
+		for i := range headers {
+			if e = difficulty.Add(&headers[i]); e != nil {
+				return
 			}
-			iterNode = node
 		}
 	}
-	// Return the found difficulty or the minimum difficulty if no appropriate block was found.
-	lastBits := b.server.chainParams.PowLimitBits
-	if iterNode != nil {
-		lastBits = iterNode.Bits
-	}
-	return lastBits, nil
+	return
 }
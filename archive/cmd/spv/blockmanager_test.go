@@ -1,8 +1,17 @@
 package spv
 
 import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
 	"github.com/p9c/pod/cmd/spv/headerfs"
+	"github.com/p9c/pod/pkg/blockchain"
+	"github.com/p9c/pod/pkg/chaincfg"
 	"github.com/p9c/pod/pkg/chainhash"
+	"github.com/p9c/pod/pkg/fork"
+	"github.com/p9c/pod/pkg/walletdb"
 	"github.com/p9c/pod/pkg/wire"
 )
 
@@ -504,3 +513,58 @@ const maxHeight = 20 * uint32(wire.CFCheckptInterval)
 // 	}
 // 	return bm, hdrStore, cfStore, cleanUp, nil
 // }
+
+// TestCheckHeaderSanity checks that the difficulty adjustment is loaded from the headers in the store, and that headers
+// whose bits are not the difficulty their algorithm requires are rejected before their proof of work is checked.
+func TestCheckHeaderSanity(t *testing.T) {
+	tempDir, e := ioutil.TempDir("", "neutrino")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(tempDir)
+	db, e := walletdb.Create("bdb", tempDir+"/weks.db")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer db.Close()
+	params := chaincfg.SimNetParams
+	store, e := headerfs.NewBlockHeaderStore(tempDir, db, &params)
+	if e != nil {
+		t.Fatal(e)
+	}
+	// the first blocks of each algorithm have the minimum difficulty
+	prev := params.GenesisBlock.Header
+	for height := uint32(1); height <= 5; height++ {
+		header := wire.BlockHeader{
+			Version:   2,
+			PrevBlock: prev.BlockHash(),
+			Timestamp: prev.Timestamp.Add(time.Minute),
+			Bits:      fork.GetMinBits(fork.SHA256d, int32(height)),
+		}
+		if e = store.WriteHeaders(headerfs.BlockHeader{BlockHeader: &header, Height: height}); e != nil {
+			t.Fatal(e)
+		}
+		prev = header
+	}
+	b := &blockManager{}
+	if b.difficulty, e = loadHeaderDifficulty(store, &params, 5); e != nil {
+		t.Fatal(e)
+	}
+	header := wire.BlockHeader{
+		Version:   514,
+		PrevBlock: prev.BlockHash(),
+		Timestamp: prev.Timestamp.Add(time.Minute),
+		Bits:      fork.GetMinBits(fork.Scrypt, 6) - 1,
+	}
+	maxTimestamp := time.Now().Add(maxTimeOffset)
+	e = b.checkHeaderSanity(&header, maxTimestamp, 6)
+	if re, ok := e.(blockchain.RuleError); !ok || re.ErrorCode != blockchain.ErrUnexpectedDifficulty {
+		t.Fatalf("header with the wrong difficulty returned %v", e)
+	}
+	// with the right difficulty the header gets as far as the proof of work, which it does not meet
+	header.Bits++
+	e = b.checkHeaderSanity(&header, maxTimestamp, 6)
+	if re, ok := e.(blockchain.RuleError); !ok || re.ErrorCode != blockchain.ErrHighHash {
+		t.Fatalf("header with the right difficulty returned %v", e)
+	}
+}
@@ -3,7 +3,7 @@ package spv
 import (
 	"fmt"
 	
-	"github.com/p9c/pod/pkg/util/qu"
+	"github.com/p9c/qu"
 	
 	"github.com/p9c/pod/pkg/wire"
 )
@@ -321,7 +321,7 @@ func TestConcurrencySimple(t *testing.T) {
 
 			if e != nil {
 
-				t.Fatal(e)
+				t.Error(e)
 			}
 		}(i)
 	}
@@ -335,7 +335,7 @@ func TestConcurrencySimple(t *testing.T) {
 
 			if e != nil && e != cache.ErrElementNotFound {
 
-				t.Fatal(e)
+				t.Error(e)
 			}
 		}(i)
 	}
@@ -362,7 +362,7 @@ func TestConcurrencySmallCache(t *testing.T) {
 
 			if e != nil {
 
-				t.Fatal(e)
+				t.Error(e)
 			}
 		}(i)
 	}
@@ -376,7 +376,7 @@ func TestConcurrencySmallCache(t *testing.T) {
 
 			if e != nil && e != cache.ErrElementNotFound {
 
-				t.Fatal(e)
+				t.Error(e)
 			}
 		}(i)
 	}
@@ -403,7 +403,7 @@ func TestConcurrencyBigCache(t *testing.T) {
 
 			if e != nil {
 
-				t.Fatal(e)
+				t.Error(e)
 			}
 		}(i)
 	}
@@ -417,7 +417,7 @@ func TestConcurrencyBigCache(t *testing.T) {
 
 			if e != nil && e != cache.ErrElementNotFound {
 
-				t.Fatal(e)
+				t.Error(e)
 			}
 		}(i)
 	}
@@ -7,7 +7,7 @@ import (
 	"sync/atomic"
 	"time"
 	
-	"github.com/p9c/pod/pkg/util/qu"
+	"github.com/p9c/qu"
 	
 	"github.com/davecgh/go-spew/spew"
 	
@@ -751,10 +751,7 @@ func (s *ChainService) GetBlock(
 	// can check what inv type to use.
 	qo := defaultQueryOptions()
 	qo.applyQueryOptions(options...)
-	invType := wire.InvTypeWitnessBlock
-	if qo.encoding == wire.BaseEncoding {
-		invType = wire.InvTypeBlock
-	}
+	invType := wire.InvTypeBlock
 	// Create an inv vector for getting this block.
 	inv := wire.NewInvVect(invType, &blockHash)
 	// If the block is already in the cache, we can return it immediately.
@@ -853,10 +850,7 @@ func (s *ChainService) SendTransaction(
 	// transaction.
 	qo := defaultQueryOptions()
 	qo.applyQueryOptions(options...)
-	invType := wire.InvTypeWitnessTx
-	if qo.encoding == wire.BaseEncoding {
-		invType = wire.InvTypeTx
-	}
+	invType := wire.InvTypeTx
 	// Create an inv.
 	txHash := tx.TxHash()
 	inv := wire.NewMsgInv()
@@ -7,7 +7,7 @@ import (
 	"sync/atomic"
 	"time"
 	
-	"github.com/p9c/pod/pkg/util/qu"
+	"github.com/p9c/qu"
 	
 	"github.com/p9c/pod/pkg/chainhash"
 	am "github.com/p9c/pod/pkg/waddrmgr"
@@ -1,87 +1,110 @@
 package wallet
 
 import (
-	"encoding/binary"
-	"path/filepath"
+	"fmt"
+	"time"
 
+	"github.com/p9c/pod/pkg/waddrmgr"
 	"github.com/p9c/pod/pkg/walletdb"
 	"github.com/p9c/pod/pkg/wtxmgr"
-	"github.com/p9c/pod/pod/config"
 )
 
-func DropWalletHistory(w *Wallet, cfg *config.Config) (e error) {
-	var (
-		// Namespace keys.
-		syncBucketName    = []byte("sync")
-		waddrmgrNamespace = []byte("waddrmgr")
-		wtxmgrNamespace   = []byte("wtxmgr")
-		// Sync related key names (sync bucket).
-		syncedToName     = []byte("syncedto")
-		startBlockName   = []byte("startblock")
-		recentBlocksName = []byte("recentblocks")
-	)
-	dbPath := filepath.Join(
-		cfg.DataDir.V(),
-		cfg.Network.V(), "wallet.db",
+// dropHistoryLockTimeout is how long DropHistory waits for the wallet database lock before concluding that a wallet
+// server is using it.
+const dropHistoryLockTimeout = time.Second * 3
+
+// DropWalletHistory clears the transaction history of a running wallet. The wallet must be restarted afterwards so the
+// in memory state is reloaded from the database and the chain is rescanned from the wallet's birthday block.
+func DropWalletHistory(w *Wallet) (e error) {
+	var removed wtxmgr.StoreSummary
+	var from, to *waddrmgr.BlockStamp
+	if removed, from, to, e = dropHistory(w.Database()); E.Chk(e) {
+		return
+	}
+	I.F(
+		"dropped %d transactions from the wallet history, sync tip rewound from %d to %d",
+		removed.Transactions, from.Height, to.Height,
 	)
-	// I.Ln("dbPath", dbPath)
+	return
+}
+
+// DropHistory opens the wallet database at dbPath without loading the wallet, clears the transaction history and rewinds
+// the address manager sync tip to the birthday block, then prints what was removed. It refuses to run if the database
+// is held open by a wallet server.
+func DropHistory(dbPath string) (e error) {
 	var db walletdb.DB
-	db, e = walletdb.Open("bdb", dbPath)
-	if E.Chk(e) {
-		// DBError("failed to open database:", err)
-		return e
+	if db, e = walletdb.Open("bdb", dbPath, dropHistoryLockTimeout); e != nil {
+		if e == walletdb.ErrDbLocked {
+			return fmt.Errorf(
+				"wallet database '%s' is in use, stop the wallet server before dropping the history", dbPath,
+			)
+		}
+		return fmt.Errorf("failed to open wallet database '%s': %v", dbPath, e)
+	}
+	defer func() {
+		if e := db.Close(); E.Chk(e) {
+		}
+	}()
+	var removed wtxmgr.StoreSummary
+	var from, to *waddrmgr.BlockStamp
+	if removed, from, to, e = dropHistory(db); E.Chk(e) {
+		return
 	}
-	defer db.Close()
-	D.Ln("dropping wtxmgr namespace")
+	fmt.Printf(
+		"dropped wallet history from '%s':\n"+
+			"  transactions:       %d (%d unmined)\n"+
+			"  blocks:             %d\n"+
+			"  credits:            %d (%d unspent)\n"+
+			"  debits:             %d\n"+
+			"  mined balance:      %v\n"+
+			"  sync tip rewound from height %d (%v)\n"+
+			"                     to height %d (%v)\n"+
+			"the history will be rebuilt by rescanning the chain the next time the wallet starts\n",
+		dbPath,
+		removed.Transactions, removed.Unmined,
+		removed.Blocks,
+		removed.Credits, removed.Unspent,
+		removed.Debits,
+		removed.MinedBalance,
+		from.Height, from.Hash,
+		to.Height, to.Hash,
+	)
+	return
+}
+
+// dropHistory replaces the wtxmgr namespace with a freshly created empty store and rewinds the waddrmgr sync tip to the
+// wallet's start block in a single database transaction. It returns a summary of the store that was dropped, and the
+// previous and new sync tips.
+func dropHistory(db walletdb.DB) (
+	removed wtxmgr.StoreSummary, from, to *waddrmgr.BlockStamp, e error,
+) {
 	e = walletdb.Update(
 		db, func(tx walletdb.ReadWriteTx) (e error) {
-			D.Ln("deleting top level bucket")
-			if e = tx.DeleteTopLevelBucket(wtxmgrNamespace); E.Chk(e) {
+			addrmgrNs := tx.ReadWriteBucket(waddrmgrNamespaceKey)
+			if addrmgrNs == nil {
+				return fmt.Errorf("wallet database has no %s namespace", waddrmgrNamespaceKey)
 			}
-			if e != nil && e != walletdb.ErrBucketNotFound {
-				return e
+			if txmgrNs := tx.ReadBucket(wtxmgrNamespaceKey); txmgrNs != nil {
+				if removed, e = wtxmgr.Summarize(txmgrNs); E.Chk(e) {
+					return
+				}
+				D.Ln("deleting wtxmgr namespace")
+				if e = tx.DeleteTopLevelBucket(wtxmgrNamespaceKey); E.Chk(e) {
+					return
+				}
 			}
-			var ns walletdb.ReadWriteBucket
-			D.Ln("creating new top level bucket")
-			if ns, e = tx.CreateTopLevelBucket(wtxmgrNamespace); E.Chk(e) {
-				return e
+			D.Ln("creating new wtxmgr namespace")
+			var txmgrNs walletdb.ReadWriteBucket
+			if txmgrNs, e = tx.CreateTopLevelBucket(wtxmgrNamespaceKey); E.Chk(e) {
+				return
 			}
-			if e = wtxmgr.Create(ns); E.Chk(e) {
-				return e
+			if e = wtxmgr.Create(txmgrNs); E.Chk(e) {
+				return
 			}
-			ns = tx.ReadWriteBucket(waddrmgrNamespace).NestedReadWriteBucket(syncBucketName)
-			startBlock := ns.Get(startBlockName)
-			D.Ln("putting start block", startBlock)
-			if e = ns.Put(syncedToName, startBlock); E.Chk(e) {
-				return e
-			}
-			recentBlocks := make([]byte, 40)
-			copy(recentBlocks[0:4], startBlock[0:4])
-			copy(recentBlocks[8:], startBlock[4:])
-			binary.LittleEndian.PutUint32(recentBlocks[4:8], uint32(1))
-			defer D.Ln("put recent blocks")
-			return ns.Put(recentBlocksName, recentBlocks)
+			D.Ln("rewinding sync tip to the start block")
+			from, to, e = waddrmgr.RewindSyncedTo(addrmgrNs)
+			return
 		},
 	)
-	if E.Chk(e) {
-		return e
-	}
-	D.Ln("updated wallet")
-	// if w != nil {
-	// 	// Rescan chain to ensure balance is correctly regenerated
-	// 	job := &wallet.RescanJob{
-	// 		InitialSync: true,
-	// 	}
-	// 	// Submit rescan job and log when the import has completed.
-	// 	// Do not block on finishing the rescan.  The rescan success
-	// 	// or failure is logged elsewhere, and the channel is not
-	// 	// required to be read, so discard the return value.
-	// 	errC := w.SubmitRescan(job)
-	// 	select {
-	// 	case e := <-errC:
-	// 		DB		// 		// case <-time.After(time.Second * 5):
-	// 		// 	break
-	// 	}
-	// }
-	return e
+	return
 }
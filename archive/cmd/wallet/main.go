@@ -4,6 +4,8 @@ import (
 	"fmt"
 	// This enables pprof
 	// _ "net/http/pprof"
+	"os"
+	"path/filepath"
 	"sync"
 
 	"github.com/p9c/qu"
@@ -15,7 +17,9 @@ import (
 
 	"github.com/p9c/interrupt"
 
+	"github.com/p9c/pod/cmd/spv"
 	"github.com/p9c/pod/pkg/chainclient"
+	"github.com/p9c/pod/pkg/walletdb"
 )
 
 // Main is a work-around main function that is required since deferred functions
@@ -152,59 +156,38 @@ func rpcClientConnectLoop(
 	loader *Loader,
 ) {
 	T.Ln("rpcClientConnectLoop", log.Caller("which was started at:", 2))
-	// var certs []byte
-	// if !cx.PodConfig.UseSPV {
-	certs := cx.Config.ReadCAFile()
-	// }
+	var certs []byte
+	if !cx.Config.UseSPV.True() {
+		certs = cx.Config.ReadCAFile()
+	}
 	for {
 		var (
 			chainClient chainclient.Interface
+			spvDB       walletdb.DB
 			e           error
 		)
-		// if cx.PodConfig.UseSPV {
-		// 	var (
-		// 		chainService *neutrino.ChainService
-		// 		spvdb        walletdb.DB
-		// 	)
-		// 	netDir := networkDir(cx.PodConfig.AppDataDir.value, ActiveNet.Params)
-		// 	spvdb, e = walletdb.Create("bdb",
-		// 		filepath.Join(netDir, "neutrino.db"))
-		// 	defer spvdb.Close()
-		// 	if e != nil  {
-		// 		log<-cl.Errorf{"unable to create Neutrino DB: %s", e)
-		// 		continue
-		// 	}
-		// 	chainService, e = neutrino.NewChainService(
-		// 		neutrino.Config{
-		// 			DataDir:      netDir,
-		// 			Database:     spvdb,
-		// 			ChainParams:  *ActiveNet.Params,
-		// 			ConnectPeers: cx.PodConfig.ConnectPeers,
-		// 			AddPeers:     cx.PodConfig.AddPeers,
-		// 		})
-		// 	if e != nil  {
-		// 		log<-cl.Errorf{"couldn't create Neutrino ChainService: %s", e)
-		// 		continue
-		// 	}
-		// 	chainClient = chain.NewNeutrinoClient(ActiveNet.Params, chainService)
-		// 	e = chainClient.Start()
-		// 	if e != nil  {
-		// 		log<-cl.Errorf{"couldn't start Neutrino client: %s", e)
-		// 	}
-		// } else {
-		var cc *chainclient.RPCClient
-		T.Ln("starting wallet's ChainClient")
-		cc, e = StartChainRPC(cx.Config, cx.ActiveNet, certs, cx.KillAll)
-		if e != nil {
-			E.Ln(
-				"unable to open connection to consensus RPC server:", e,
-			)
-			continue
+		if cx.Config.UseSPV.True() {
+			var nc *chainclient.NeutrinoClient
+			T.Ln("starting wallet's SPV chain client")
+			if nc, spvDB, e = StartChainSPV(cx.Config, cx.ActiveNet, cx.KillAll); E.Chk(e) {
+				return
+			}
+			chainClient = nc
+		} else {
+			var cc *chainclient.RPCClient
+			T.Ln("starting wallet's ChainClient")
+			cc, e = StartChainRPC(cx.Config, cx.ActiveNet, certs, cx.KillAll)
+			if e != nil {
+				E.Ln(
+					"unable to open connection to consensus RPC server:", e,
+				)
+				continue
+			}
+			T.Ln("storing chain client")
+			cx.ChainClient = cc
+			cx.ChainClientReady.Q()
+			chainClient = cc
 		}
-		T.Ln("storing chain client")
-		cx.ChainClient = cc
-		cx.ChainClientReady.Q()
-		chainClient = cc
 		// Rather than inlining this logic directly into the loader callback, a function
 		// variable is used to avoid running any of this after the client disconnects by
 		// setting it to nil. This prevents the callback from associating a wallet
@@ -236,6 +219,10 @@ func rpcClientConnectLoop(
 			},
 		)
 		chainClient.WaitForShutdown()
+		if spvDB != nil {
+			if e = spvDB.Close(); E.Chk(e) {
+			}
+		}
 		mu.Lock()
 		associateRPCClient = nil
 		mu.Unlock()
@@ -255,6 +242,49 @@ func rpcClientConnectLoop(
 	}
 }
 
+// StartChainSPV starts a neutrino chain service that syncs the block headers and compact filters from the peer to peer
+// network, and returns a chain client for the wallet backed by it. The peers connected to are the ones configured for
+// the node, and the headers and filters are stored in the network directory of the wallet. The client stops when quit is
+// closed, and the returned database has to be closed once it has shut down.
+func StartChainSPV(
+	config *config.Config,
+	activeNet *chaincfg.Params,
+	quit qu.C,
+) (nc *chainclient.NeutrinoClient, db walletdb.DB, e error) {
+	netDir := NetworkDir(config.DataDir.V(), activeNet)
+	if e = os.MkdirAll(netDir, 0700); E.Chk(e) {
+		return
+	}
+	if db, e = walletdb.Create("bdb", filepath.Join(netDir, "neutrino.db")); E.Chk(e) {
+		return
+	}
+	var cs *spv.ChainService
+	if cs, e = spv.NewChainService(
+		spv.Config{
+			DataDir:      netDir,
+			Database:     db,
+			ChainParams:  *activeNet,
+			ConnectPeers: config.ConnectPeers.S(),
+			AddPeers:     config.AddPeers.S(),
+		},
+	); E.Chk(e) {
+		_ = db.Close()
+		return nil, nil, e
+	}
+	nc = chainclient.NewNeutrinoClient(activeNet, cs)
+	if e = nc.Start(); E.Chk(e) {
+		_ = db.Close()
+		return nil, nil, e
+	}
+	go func() {
+		<-quit.Wait()
+		nc.Stop()
+		if e := nc.CS.Stop(); E.Chk(e) {
+		}
+	}()
+	return
+}
+
 // StartChainRPC opens a RPC client connection to a pod server for blockchain
 // services. This function uses the RPC options from the global config and there
 // is no recovery in case the server is not available or if there is an
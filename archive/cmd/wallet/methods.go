@@ -350,11 +350,13 @@ func LazyApplyHandler(request *btcjson.Request, w *Wallet, chainClient chainclie
 				D.Ln("handler call succeeded")
 				return resp, nil
 			default:
-				D.Ln("client is unknown")
-				return nil, &btcjson.RPCError{
-					Code:    -1,
-					Message: "Chain RPC is inactive",
+				// the SPV client has no chain server to pass through to, so only the handlers that do not need one work
+				D.Ln("client is not a chain.RPCClient")
+				var resp interface{}
+				if resp, e = handlerData.Handler(cmd, w); E.Chk(e) {
+					return nil, JSONError(e)
 				}
+				return resp, nil
 			}
 		}
 	}
@@ -1192,7 +1194,7 @@ func HandleDropWalletHistory(icmd interface{}, w *Wallet, chainClient ...*chainc
 	out interface{}, e error,
 ) {
 	D.Ln("dropping wallet history")
-	if e = DropWalletHistory(w, w.PodConfig); E.Chk(e) {
+	if e = DropWalletHistory(w); E.Chk(e) {
 	}
 	D.Ln("dropped wallet history")
 	// go func() {
@@ -1232,6 +1234,9 @@ func HelpWithChainRPC(
 	icmd interface{}, w *Wallet,
 	chainClient ...*chainclient.RPCClient,
 ) (interface{}, error) {
+	if len(chainClient) < 1 {
+		return Help(icmd, w, nil)
+	}
 	return Help(icmd, w, chainClient[0])
 }
 
@@ -156,8 +156,8 @@ func (w *Wallet) SynchronizeRPC(chainClient chainclient.Interface) {
 	w.chainClient = chainClient
 	// If the chain client is a NeutrinoClient instance, set a birthday so we don't download all the filters as we go.
 	switch cc := chainClient.(type) {
-	// case *chainclient.NeutrinoClient:
-	// 	cc.SetStartTime(w.Manager.Birthday())
+	case *chainclient.NeutrinoClient:
+		cc.SetStartTime(w.Manager.Birthday())
 	case *chainclient.BitcoindClient:
 		cc.SetBirthday(w.Manager.Birthday())
 	}
@@ -421,25 +421,26 @@ func (w *Wallet) syncWithChain() (e error) {
 				}
 				return e
 			}
-			// // If we're using the Neutrino backend, we can check if it's current or not. For other backends we'll assume
-			// // it is current if the best height has reached the last checkpoint.
-			// isCurrent := func(bestHeight int32) bool {
-			// 	switch c := chainClient.(type) {
-			// 	case *chainclient.NeutrinoClient:
-			// 		return c.CS.IsCurrent()
-			// 	}
-			// 	return bestHeight >= checkHeight
-			// }
+			// If we're using the Neutrino backend, we can check if it's current or not. For other backends we'll assume
+			// it is current.
+			isCurrent := func() bool {
+				switch c := chainClient.(type) {
+				case *chainclient.NeutrinoClient:
+					return c.CS.IsCurrent()
+				}
+				return true
+			}
 			// If we've found the best height the backend knows about, and the backend is still synchronizing, we'll
 			// wait. We can give it a little bit of time to synchronize further before updating the best height based on
 			// the backend. Once we see that the backend has advanced, we can catch up to it.
-			for height == bestHeight { // && !isCurrent(bestHeight) {
+			for height == bestHeight {
 				I.Ln("getting best height from chain")
 				if _, bestHeight, e = chainClient.GetBestBlock(); E.Chk(e) {
 					if e = tx.Rollback(); E.Chk(e) {
 					}
 					return e
-				} else {
+				}
+				if height < bestHeight || isCurrent() {
 					break
 				}
 				time.Sleep(time.Second)
@@ -2013,11 +2014,11 @@ func (w *Wallet) GetTransactions(
 				if e != nil {
 					return nil, e
 				}
-				// case *chainclient.NeutrinoClient:
-				// 	start, e = client.GetBlockHeight(startBlock.hash)
-				// 	if e != nil {
-				// 		return nil, e
-				// 	}
+			case *chainclient.NeutrinoClient:
+				start, e = client.GetBlockHeight(startBlock.hash)
+				if e != nil {
+					return nil, e
+				}
 			}
 		}
 	}
@@ -2031,11 +2032,11 @@ func (w *Wallet) GetTransactions(
 			switch client := chainClient.(type) {
 			case *chainclient.RPCClient:
 				endResp = client.GetBlockVerboseTxAsync(endBlock.hash)
-				// case *chainclient.NeutrinoClient:
-				// 	end, e = client.GetBlockHeight(endBlock.hash)
-				// 	if e != nil {
-				// 		return nil, e
-				// 	}
+			case *chainclient.NeutrinoClient:
+				end, e = client.GetBlockHeight(endBlock.hash)
+				if e != nil {
+					return nil, e
+				}
 			}
 		}
 	}
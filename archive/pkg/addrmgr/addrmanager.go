@@ -1,6 +1,7 @@
 package addrmgr
 
 import (
+	"bytes"
 	"container/list"
 	crand "crypto/rand" // for seeding
 	"encoding/base32"
@@ -19,6 +20,7 @@ import (
 	"time"
 	
 	"github.com/p9c/qu"
+	"golang.org/x/crypto/sha3"
 	
 	"github.com/p9c/pod/pkg/chainhash"
 	"github.com/p9c/pod/pkg/wire"
@@ -44,7 +46,9 @@ type AddrManager struct {
 	localAddresses map[string]*localAddress
 }
 type serializedKnownAddress struct {
-	Addr        string
+	Addr string
+	// Network is the BIP0155 network of Addr, so addresses of networks a build does not know can be skipped.
+	Network     wire.NetworkID
 	Src         string
 	Attempts    int
 	TimeStamp   int64
@@ -114,8 +118,11 @@ const (
 	getAddrMax = 2500
 	// getAddrPercent is the percentage of total addresses known that we will share with a call to AddressCache.
 	getAddrPercent = 23
-	// serialisationVersion is the current version of the on-disk format.
-	serialisationVersion = 1
+	// serialisationVersion is the current version of the on-disk format. Version 2 added the network of each address,
+	// files of version 1 are migrated when they are loaded.
+	serialisationVersion = 2
+	// torV3Version is the version byte at the end of a Tor v3 onion address.
+	torV3Version = 3
 )
 
 // updateAddress is a helper function to either update an address already known to the address manager, or to add the
@@ -306,6 +313,7 @@ func (a *AddrManager) savePeers() {
 	for k, v := range a.addrIndex {
 		ska := new(serializedKnownAddress)
 		ska.Addr = k
+		ska.Network = v.na.NetworkID()
 		ska.TimeStamp = v.na.Timestamp.Unix()
 		ska.Src = NetAddressKey(v.srcAddr)
 		ska.Attempts = v.attempts
@@ -395,14 +403,22 @@ func (a *AddrManager) deserializePeers(filePath string) (e error) {
 		E.Ln(e)
 		return fmt.Errorf("error reading %s: %v", filePath, e)
 	}
-	if sam.Version != serialisationVersion {
+	// Version 1 files only contain IP and Tor v2 addresses, whose network is known from the address itself.
+	if sam.Version < 1 || sam.Version > serialisationVersion {
 		return fmt.Errorf(
 			"unknown version %v in serialized addrmanager",
 			sam.Version,
 		)
 	}
 	copy(a.key[:], sam.Key[:])
+	// Addresses of networks this version does not know are dropped along with their bucket entries.
+	skipped := make(map[string]struct{})
 	for _, v := range sam.Addresses {
+		if sam.Version > 1 && !v.Network.IsKnown() {
+			D.F("dropping address %s of unknown network %v", v.Addr, v.Network)
+			skipped[v.Addr] = struct{}{}
+			continue
+		}
 		ka := new(KnownAddress)
 		ka.na, e = a.DeserializeNetAddress(v.Addr)
 		if e != nil {
@@ -425,6 +441,9 @@ func (a *AddrManager) deserializePeers(filePath string) (e error) {
 	}
 	for i := range sam.NewBuckets {
 		for _, val := range sam.NewBuckets[i] {
+			if _, ok := skipped[val]; ok {
+				continue
+			}
 			ka, ok := a.addrIndex[val]
 			if !ok {
 				return fmt.Errorf("newbucket contains %s but "+
@@ -440,6 +459,9 @@ func (a *AddrManager) deserializePeers(filePath string) (e error) {
 	}
 	for i := range sam.TriedBuckets {
 		for _, val := range sam.TriedBuckets[i] {
+			if _, ok := skipped[val]; ok {
+				continue
+			}
 			ka, ok := a.addrIndex[val]
 			if !ok {
 				return fmt.Errorf(
@@ -618,10 +640,20 @@ func (a *AddrManager) reset() {
 
 // HostToNetAddress returns a netaddress given a host address.
 //
-// If the address is a Tor .onion address this will be taken care of.
+// If the address is a Tor .onion address or an I2P .b32.i2p address this will be taken care of.
 //
 // Else if the host is not an IP address it will be resolved ( via Tor if required).
 func (a *AddrManager) HostToNetAddress(host string, port uint16, services wire.ServiceFlag) (*wire.NetAddress, error) {
+	// Tor v3 address is 56 char base32 + ".onion" and I2P address is 52 char base32 + ".b32.i2p", neither of which
+	// fit in an IP address.
+	if network, addr, e := decodeAddrV2Host(host); network != 0 || e != nil {
+		if e != nil {
+			return nil, e
+		}
+		na := wire.NewNetAddressIPPort(nil, port, services)
+		na.Network, na.Addr = network, addr
+		return na, nil
+	}
 	// Tor address is 16 char base32 + ".onion"
 	var ip net.IP
 	if len(host) == 22 && host[16:] == ".onion" {
@@ -653,6 +685,12 @@ func (a *AddrManager) HostToNetAddress(host string, port uint16, services wire.S
 // ipString returns a string for the ip from the provided NetAddress. If the ip is in the range used for Tor addresses
 // then it will be transformed into the relevant .onion address.
 func ipString(na *wire.NetAddress) string {
+	switch {
+	case IsTorV3(na):
+		return torV3Host(na.Addr)
+	case IsI2P(na):
+		return strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(na.Addr)) + ".b32.i2p"
+	}
 	if IsOnionCatTor(na) {
 		// We know now that na.IP is long enough.
 		s := base32.StdEncoding.EncodeToString(na.IP[6:])
@@ -661,6 +699,46 @@ func ipString(na *wire.NetAddress) string {
 	return na.IP.String()
 }
 
+// torV3Checksum returns the checksum of a Tor v3 onion address with the passed public key, which is the first two bytes
+// of the SHA3-256 of ".onion checksum", the key and the version.
+func torV3Checksum(pubKey []byte) []byte {
+	h := sha3.New256()
+	_, _ = h.Write([]byte(".onion checksum"))
+	_, _ = h.Write(pubKey)
+	_, _ = h.Write([]byte{torV3Version})
+	return h.Sum(nil)[:2]
+}
+
+// torV3Host returns the .onion host name of a Tor v3 onion service with the passed public key.
+func torV3Host(pubKey []byte) string {
+	b := append(append(append([]byte{}, pubKey...), torV3Checksum(pubKey)...), torV3Version)
+	return strings.ToLower(base32.StdEncoding.EncodeToString(b)) + ".onion"
+}
+
+// decodeAddrV2Host returns the network and address of a Tor v3 or I2P host name, or a zero network if the host is
+// neither. An error is returned if the host looks like one of them but can not be decoded.
+func decodeAddrV2Host(host string) (network wire.NetworkID, addr []byte, e error) {
+	switch {
+	case len(host) == 62 && strings.HasSuffix(host, ".onion"):
+		var data []byte
+		if data, e = base32.StdEncoding.DecodeString(strings.ToUpper(host[:56])); E.Chk(e) {
+			return
+		}
+		pubKey := data[:32]
+		if data[34] != torV3Version || !bytes.Equal(data[32:34], torV3Checksum(pubKey)) {
+			return 0, nil, fmt.Errorf("invalid tor v3 address %s", host)
+		}
+		return wire.NetworkTorV3, pubKey, nil
+	case len(host) == 60 && strings.HasSuffix(host, ".b32.i2p"):
+		enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+		if addr, e = enc.DecodeString(strings.ToUpper(host[:52])); E.Chk(e) {
+			return
+		}
+		return wire.NetworkI2P, addr, nil
+	}
+	return
+}
+
 // NetAddressKey returns a string key in the form of ip:port for IPv4 addresses or [ip]:port for IPv6 addresses.
 func NetAddressKey(na *wire.NetAddress) string {
 	port := strconv.FormatUint(uint64(na.Port), 10)
@@ -883,6 +961,23 @@ func (a *AddrManager) AddLocalAddress(na *wire.NetAddress, priority AddressPrior
 	return nil
 }
 
+// LocalAddress is a known local address together with the priority score it is advertised with.
+type LocalAddress struct {
+	NetAddress *wire.NetAddress
+	Score      AddressPriority
+}
+
+// LocalAddresses returns the known local addresses and their scores, in no particular order.
+func (a *AddrManager) LocalAddresses() (addrs []LocalAddress) {
+	a.lamtx.Lock()
+	defer a.lamtx.Unlock()
+	addrs = make([]LocalAddress, 0, len(a.localAddresses))
+	for _, la := range a.localAddresses {
+		addrs = append(addrs, LocalAddress{NetAddress: la.na, Score: la.score})
+	}
+	return
+}
+
 // getReachabilityFrom returns the relative reachability of the provided local address to the provided remote address.
 func getReachabilityFrom(localAddr, remoteAddr *wire.NetAddress) int {
 	const (
@@ -897,8 +992,8 @@ func getReachabilityFrom(localAddr, remoteAddr *wire.NetAddress) int {
 	if !IsRoutable(remoteAddr) {
 		return Unreachable
 	}
-	if IsOnionCatTor(remoteAddr) {
-		if IsOnionCatTor(localAddr) {
+	if IsOnionCatTor(remoteAddr) || IsTorV3(remoteAddr) {
+		if IsOnionCatTor(localAddr) || IsTorV3(localAddr) {
 			return Private
 		}
 		if IsRoutable(localAddr) && IsIPv4(localAddr) {
@@ -972,7 +1067,7 @@ func (a *AddrManager) GetBestLocalAddress(remoteAddr *wire.NetAddress) *wire.Net
 		)
 		// Send something unroutable if nothing suitable.
 		var ip net.IP
-		if !IsIPv4(remoteAddr) && !IsOnionCatTor(remoteAddr) {
+		if !IsIPv4(remoteAddr) && !IsOnionCatTor(remoteAddr) && !IsTorV3(remoteAddr) {
 			ip = net.IPv6zero
 		} else {
 			ip = net.IPv4zero
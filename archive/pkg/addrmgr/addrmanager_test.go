@@ -1,9 +1,12 @@
 package addrmgr_test
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net"
+	"os"
 	"reflect"
 	"testing"
 	"time"
@@ -425,3 +428,84 @@ func TestNetAddressKey(t *testing.T) {
 		}
 	}
 }
+
+// TestAddrV2Hosts ensures that Tor v3 and I2P host names are decoded into addresses that are kept by the address
+// manager and encoded back into the same host names.
+func TestAddrV2Hosts(t *testing.T) {
+	amgr := addrmgr.New("testaddrv2hosts", lookupFunc)
+	tests := []struct {
+		host    string
+		network wire.NetworkID
+		valid   bool
+	}{
+		{"duckduckgogg42xjoc72x3sjasowoarfbgcmvfimaftt6twagswzczad.onion", wire.NetworkTorV3, true},
+		// Wrong checksum.
+		{"duckduckgogg42xjoc72x3sjasowoarfbgcmvfimaftt6twagswzczaa.onion", 0, false},
+		{"ukeu3k5oycgaauneqgtnvselmt4yemvoilkln7jpvamvfx7dnkdq.b32.i2p", wire.NetworkI2P, true},
+	}
+	for i, test := range tests {
+		na, e := amgr.HostToNetAddress(test.host, 11047, wire.SFNodeNetwork)
+		if !test.valid {
+			if e == nil {
+				t.Errorf("HostToNetAddress #%d decoded invalid host %s", i, test.host)
+			}
+			continue
+		}
+		if e != nil {
+			t.Errorf("HostToNetAddress #%d: %v", i, e)
+			continue
+		}
+		if na.NetworkID() != test.network || na.IsAddrV1Compatible() {
+			t.Errorf("HostToNetAddress #%d got network %v, want %v", i, na.NetworkID(), test.network)
+		}
+		if !addrmgr.IsRoutable(na) {
+			t.Errorf("HostToNetAddress #%d address of %s is not routable", i, test.host)
+		}
+		if key, want := addrmgr.NetAddressKey(na), net.JoinHostPort(test.host, "11047"); key != want {
+			t.Errorf("NetAddressKey #%d got %s, want %s", i, key, want)
+		}
+	}
+}
+
+// TestPeersFileMigration ensures that a peers file of the first version is loaded and saved with the network of each
+// address.
+func TestPeersFileMigration(t *testing.T) {
+	dir, e := ioutil.TempDir("", "testpeersfilemigration")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+	addr := someIP + ":11047"
+	v1 := fmt.Sprintf(
+		`{"Version":1,"Addresses":[{"Addr":%q,"Src":%q,"TimeStamp":1,"LastAttempt":0,"LastSuccess":0}],`+
+			`"NewBuckets":[[%q]]}`, addr, addr, addr,
+	)
+	amgr := addrmgr.New(dir, lookupFunc)
+	if e = ioutil.WriteFile(amgr.PeersFile, []byte(v1), 0600); e != nil {
+		t.Fatal(e)
+	}
+	amgr.Start()
+	if n := amgr.NumAddresses(); n != 1 {
+		t.Fatalf("loaded %d addresses, want 1", n)
+	}
+	if e = amgr.Stop(); e != nil {
+		t.Fatal(e)
+	}
+	var saved struct {
+		Version   int
+		Addresses []struct {
+			Addr    string
+			Network wire.NetworkID
+		}
+	}
+	b, e := ioutil.ReadFile(amgr.PeersFile)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if e = json.Unmarshal(b, &saved); e != nil {
+		t.Fatal(e)
+	}
+	if saved.Version != 2 || len(saved.Addresses) != 1 || saved.Addresses[0].Network != wire.NetworkIPv4 {
+		t.Fatalf("saved peers file %+v, want version 2 with an ipv4 address", saved)
+	}
+}
@@ -80,6 +80,18 @@ func IsOnionCatTor(na *wire.NetAddress) bool {
 	return onionCatNet.Contains(na.IP)
 }
 
+// IsTorV3 returns whether or not the passed address is the address of a Tor v3 onion service, which can only be relayed
+// in addrv2 messages.
+func IsTorV3(na *wire.NetAddress) bool {
+	return na.Network == wire.NetworkTorV3
+}
+
+// IsI2P returns whether or not the passed address is the address of an I2P destination, which can only be relayed in
+// addrv2 messages.
+func IsI2P(na *wire.NetAddress) bool {
+	return na.Network == wire.NetworkI2P
+}
+
 // IsRFC1918 returns whether or not the passed address is part of the IPv4 private network address space as defined by
 // RFC1918 (10.0.0.0/8, 172.16.0.0/12, or 192.168.0.0/16).
 func IsRFC1918(na *wire.NetAddress) bool {
@@ -175,6 +187,10 @@ func IsRFC6598(na *wire.NetAddress) bool {
 //
 // Pv6: It is either a zero or RFC3849 documentation address.
 func IsValid(na *wire.NetAddress) bool {
+	// Tor v3 and I2P addresses are the only addresses that are not IP addresses which are kept.
+	if !na.IsAddrV1Compatible() {
+		return (IsTorV3(na) || IsI2P(na)) && len(na.Addr) == 32
+	}
 	// IsUnspecified returns if address is 0, so only all bits set, and RFC3849 need to be explicitly checked.
 	return na.IP != nil && !(na.IP.IsUnspecified() ||
 		na.IP.Equal(net.IPv4bcast))
@@ -188,7 +204,8 @@ func IsRoutable(na *wire.NetAddress) bool {
 
 // GroupKey returns a string representing the network group an address is part of. This is the /16 for IPv4, the /32
 // (/36 for he.net) for IPv6, the string "local" for a local address, the string "tor:key" where key is the /4 of the
-// onion address for Tor address, and the string "unroutable" for an unroutable address.
+// onion address for Tor address, the string "i2p:key" likewise for I2P addresses, and the string "unroutable" for an
+// unroutable address.
 func GroupKey(na *wire.NetAddress) string {
 	if IsLocal(na) {
 		return "local"
@@ -196,6 +213,13 @@ func GroupKey(na *wire.NetAddress) string {
 	if !IsRoutable(na) {
 		return "unroutable"
 	}
+	if IsTorV3(na) {
+		// group is keyed off the first 4 bits of the onion service key like Tor v2 addresses.
+		return fmt.Sprintf("tor:%d", na.Addr[0]&((1<<4)-1))
+	}
+	if IsI2P(na) {
+		return fmt.Sprintf("i2p:%d", na.Addr[0]&((1<<4)-1))
+	}
 	if IsIPv4(na) {
 		return na.IP.Mask(net.CIDRMask(16, 32)).String()
 	}
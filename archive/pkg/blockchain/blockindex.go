@@ -259,6 +259,46 @@ func (bi *blockIndex) UnsetStatusFlags(node *BlockNode, flags blockStatus) {
 	bi.Unlock()
 }
 
+// tips returns the nodes in the index that no other node builds on, which are the tips of the main chain and of every
+// side chain branch. This function is safe for concurrent access.
+func (bi *blockIndex) tips() (tips []*BlockNode) {
+	bi.RLock()
+	parents := make(map[*BlockNode]struct{}, len(bi.index))
+	for _, node := range bi.index {
+		if node.parent != nil {
+			parents[node.parent] = struct{}{}
+		}
+	}
+	for _, node := range bi.index {
+		if _, ok := parents[node]; !ok {
+			tips = append(tips, node)
+		}
+	}
+	bi.RUnlock()
+	return
+}
+
+// descendants returns every node in the index that builds on the given node, directly or indirectly, ordered so that
+// each node comes after its parent. This function is safe for concurrent access.
+func (bi *blockIndex) descendants(node *BlockNode) (descendants []*BlockNode) {
+	bi.RLock()
+	children := make(map[*BlockNode][]*BlockNode)
+	for _, n := range bi.index {
+		if n.parent != nil && n.height > node.height {
+			children[n.parent] = append(children[n.parent], n)
+		}
+	}
+	bi.RUnlock()
+	queue := children[node]
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		descendants = append(descendants, n)
+		queue = append(queue, children[n]...)
+	}
+	return
+}
+
 // flushToDB writes all dirty block nodes to the database. If all writes succeed, this clears the dirty set.
 func (bi *blockIndex) flushToDB() (e error) {
 	bi.Lock()
@@ -2,6 +2,7 @@ package blockchain
 
 import (
 	"container/list"
+	"errors"
 	"fmt"
 	block2 "github.com/p9c/pod/pkg/block"
 	"github.com/p9c/pod/pkg/fork"
@@ -91,6 +92,8 @@ type BlockChain struct {
 	sigCache            *txscript.SigCache
 	indexManager        IndexManager
 	hashCache           *txscript.HashCache
+	pruneTarget         uint64
+	assumeValid         *chainhash.Hash
 	// The following fields are calculated based upon the provided chain parameters.
 	// They are also set when the instance is created and can't be changed
 	// afterwards, so there is no need to protect them with a separate mutex.
@@ -116,6 +119,39 @@ type BlockChain struct {
 	// These fields are related to checkpoint handling. They are protected by the chain lock.
 	nextCheckpoint *chaincfg.Checkpoint
 	checkpointNode *BlockNode
+	// pruneHeight is the height of the first block of the main chain whose data has not been pruned. It is protected
+	// by the chain lock.
+	pruneHeight int32
+	// snapshotHeight is the height of the utxo snapshot the chain state was imported from while the blocks below it
+	// have not all been downloaded, and historyHeight the height of the first block of the main chain below it whose
+	// data is missing. They are protected by the chain lock.
+	snapshotHeight int32
+	historyHeight  int32
+	// snapshotSetHash is the trusted set hash of the utxo snapshot and snapshotFailed whether the utxo set built from
+	// the blocks below it did not match it. They are protected by the chain lock.
+	snapshotSetHash chainhash.Hash
+	snapshotFailed  bool
+	// replayHeight is the height of the last block below the utxo snapshot connected to the history utxo set, and
+	// replayStats the statistics of that set. They are protected by replayMx. historySignal wakes the history replayer
+	// when a block below the snapshot was stored.
+	replayMx      sync.Mutex
+	replayHeight  int32
+	replayStats   *utxoStats
+	historySignal chan struct{}
+	// assumeValidChain are the headers from assumeValidBase up to the assume-valid block, once CheckBlockHeaders has
+	// seen it. They are protected by the chain lock.
+	assumeValidBase  int32
+	assumeValidChain []*BlockNode
+	// bestHeader is the tip of the chain of headers with the most work checked by CheckBlockHeaders, and
+	// assumeValidBuried is set while the assume-valid block is in it with assumeValidMinAge of blocks on top. They are
+	// protected by the chain lock.
+	bestHeader        *BlockNode
+	assumeValidBuried bool
+	// p9Start caches the first block of the plan 9 hard fork for the difficulty adjustment of chains of headers that
+	// pass it while the best chain does not.
+	p9Start p9StartCache
+	// utxoStats are the statistics of the utxo set at the best block. They are protected by the chain lock.
+	utxoStats *utxoStats
 	// The state is used as a fairly efficient way to cache information about the
 	// current best chain state that is returned to callers when requested. It
 	// operates on the principle of MVCC such that any time a new block becomes the
@@ -147,8 +183,10 @@ type BlockChain struct {
 	notificationsLock sync.RWMutex
 	// DifficultyAdjustments keeps track of the latest difficulty adjustment for each algorithm
 	DifficultyAdjustments map[string]float64
-	DifficultyBits        atomic.Value
-	DifficultyHeight      atomic.Int32
+	// DifficultyBits caches the difficulty targets of all of the algorithms for the block after the one with the hash
+	// in DifficultyPrev, so blocks and headers that build on another block at the same height don't get them
+	DifficultyBits atomic.Value
+	DifficultyPrev atomic.Value
 }
 
 // HaveBlock returns whether or not the chain instance has the block represented
@@ -514,6 +552,9 @@ func (b *BlockChain) connectBlock(
 	)
 	// Atomically insert info into the database.
 	T.Ln("inserting block into database")
+	var pruned []chainhash.Hash
+	pruneHeight := b.pruneHeight
+	stats := b.utxoStats.clone()
 	e = b.db.Update(
 		func(dbTx database.Tx) (e error) {
 			// update best block state.
@@ -530,11 +571,14 @@ func (b *BlockChain) connectBlock(
 			}
 			// update the utxo set using the state of the utxo view. This entails removing all of the utxos spent and adding
 			// the new ones created by the block.
-			e = dbPutUtxoView(dbTx, view)
+			e = dbPutUtxoView(dbTx, view, stats)
 			if e != nil {
 				T.Ln("dbPutUtxoView", e)
 				return e
 			}
+			if e = dbPutUtxoStats(dbTx, stats); E.Chk(e) {
+				return e
+			}
 			
 			// Update the transaction spend journal by adding a record for the block that contains all txos spent by it.
 			e = dbPutSpendJournalEntry(dbTx, block.Hash(), stxos)
@@ -551,6 +595,12 @@ func (b *BlockChain) connectBlock(
 					return e
 				}
 			}
+			// Delete the data of the oldest blocks when the stored blocks take up more than the prune target.
+			if b.pruneTarget > 0 {
+				if pruned, pruneHeight, e = b.dbPruneBlocks(dbTx, node); E.Chk(e) {
+					return e
+				}
+			}
 			return nil
 		},
 	)
@@ -558,6 +608,17 @@ func (b *BlockChain) connectBlock(
 		T.Ln("error updating database ", e)
 		return e
 	}
+	// Mark the pruned blocks as no longer having their data stored so they are not used for reorganizations.
+	for i := range pruned {
+		if n := b.Index.LookupNode(&pruned[i]); n != nil {
+			b.Index.UnsetStatusFlags(n, statusDataStored)
+		}
+	}
+	if len(pruned) > 0 {
+		I.F("pruned %d blocks, the first block stored on the main chain is at height %d", len(pruned), pruneHeight)
+	}
+	b.pruneHeight = pruneHeight
+	b.utxoStats = stats
 	// Prune fully spent entries and mark all entries in the view unmodified now that the modifications have been
 	// committed to the database.
 	T.Ln("committing new view")
@@ -633,6 +694,7 @@ func (b *BlockChain) disconnectBlock(
 		prevNode, blockSize, blockWeight, numTxns,
 		newTotalTxns, prevNode.CalcPastMedianTime(),
 	)
+	stats := b.utxoStats.clone()
 	e = b.db.Update(
 		func(dbTx database.Tx) (e error) {
 			// Update best block state.
@@ -647,10 +709,13 @@ func (b *BlockChain) disconnectBlock(
 			}
 			// Update the utxo set using the state of the utxo view. This entails restoring all of the utxos spent and
 			// removing the new ones created by the block.
-			e = dbPutUtxoView(dbTx, view)
+			e = dbPutUtxoView(dbTx, view, stats)
 			if e != nil {
 				return e
 			}
+			if e = dbPutUtxoStats(dbTx, stats); E.Chk(e) {
+				return e
+			}
 			// Before we delete the spend journal entry for this back, we'll fetch it as is so the indexers can utilize if
 			// needed.
 			stxos, e := dbFetchSpendJournalEntry(dbTx, block)
@@ -676,6 +741,7 @@ func (b *BlockChain) disconnectBlock(
 	if e != nil {
 		return e
 	}
+	b.utxoStats = stats
 	// Prune fully spent entries and mark all entries in the view unmodified now that the modifications have been
 	// committed to the database.
 	view.commit()
@@ -1124,6 +1190,88 @@ func (b *BlockChain) MainChainHasBlock(hash *chainhash.Hash) bool {
 	return node != nil && b.BestChain.Contains(node)
 }
 
+// ChainTipStatus describes the validation state of the branch ending in a chain tip.
+type ChainTipStatus int
+
+const (
+	// StatusActive is the tip of the main chain.
+	StatusActive ChainTipStatus = iota
+	// StatusValidFork is a fully validated side chain that is not part of the main chain.
+	StatusValidFork
+	// StatusValidHeaders is a side chain with all blocks available but not fully validated.
+	StatusValidHeaders
+	// StatusHeadersOnly is a side chain for which not all blocks are available.
+	StatusHeadersOnly
+	// StatusInvalid is a branch that contains at least one invalid block.
+	StatusInvalid
+)
+
+// chainTipStatusStrings are the names of the chain tip statuses as used by the getchaintips RPC.
+var chainTipStatusStrings = map[ChainTipStatus]string{
+	StatusActive:       "active",
+	StatusValidFork:    "valid-fork",
+	StatusValidHeaders: "valid-headers",
+	StatusHeadersOnly:  "headers-only",
+	StatusInvalid:      "invalid",
+}
+
+// String returns the ChainTipStatus as the human-readable name used by the getchaintips RPC.
+func (s ChainTipStatus) String() string {
+	if str, ok := chainTipStatusStrings[s]; ok {
+		return str
+	}
+	return fmt.Sprintf("Unknown ChainTipStatus (%d)", int(s))
+}
+
+// ChainTip describes the tip of the main chain or of a side chain branch in the block index.
+type ChainTip struct {
+	Height int32
+	Hash   chainhash.Hash
+	// BranchLen is the number of blocks between the tip and the main chain, zero for the main chain tip.
+	BranchLen int32
+	Status    ChainTipStatus
+}
+
+// ChainTips returns the tips of all known branches of the block tree, including the main chain tip and side chains
+// that have been seen but did not become the best chain. This function is safe for concurrent access.
+func (b *BlockChain) ChainTips() (tips []ChainTip) {
+	b.ChainLock.RLock()
+	defer b.ChainLock.RUnlock()
+	best := b.BestChain.Tip()
+	for _, node := range b.Index.tips() {
+		tip := ChainTip{Height: node.height, Hash: node.hash}
+		if fork := b.BestChain.FindFork(node); fork != nil {
+			tip.BranchLen = node.height - fork.height
+		}
+		status := b.Index.NodeStatus(node)
+		switch {
+		case node == best:
+			tip.Status = StatusActive
+		case status.KnownInvalid():
+			tip.Status = StatusInvalid
+		case !b.branchHasData(node):
+			tip.Status = StatusHeadersOnly
+		case status.KnownValid():
+			tip.Status = StatusValidFork
+		default:
+			tip.Status = StatusValidHeaders
+		}
+		tips = append(tips, tip)
+	}
+	return
+}
+
+// branchHasData returns whether the block data is stored for every block from node back to where its branch forks
+// from the main chain. This function MUST be called with the chain state lock held (for reads).
+func (b *BlockChain) branchHasData(node *BlockNode) bool {
+	for n := node; n != nil && !b.BestChain.contains(n); n = n.parent {
+		if !b.Index.NodeStatus(n).HaveData() {
+			return false
+		}
+	}
+	return true
+}
+
 // BlockLocatorFromHash returns a block locator for the passed block hash. See BlockLocator for details on the algorithm
 // used to create a block locator. In addition to the general algorithm referenced above, this function will return the
 // block locator for the latest known tip of the main (best) chain if the passed hash is not currently known.
@@ -1485,6 +1633,14 @@ type Config struct {
 	// O(N^2) validation complexity due to the SigHashAll flag. This field can be nil if the caller is not interested in
 	// using a signature cache.
 	HashCache *txscript.HashCache
+	// PruneTarget is the number of bytes the stored blocks may take up before the data of the oldest ones is deleted,
+	// keeping at least the last MinPrunedBlocks blocks of the main chain. This field can be zero to keep all blocks.
+	PruneTarget uint64
+	// AssumeValid is the hash of a block whose scripts and those of its ancestors are assumed to be valid, so they are
+	// not checked when the blocks are connected while its header is in the chain of headers with the most work checked by
+	// CheckBlockHeaders, with two weeks of blocks on top of it. This field can be nil to check the scripts of all blocks
+	// after the checkpoints.
+	AssumeValid *chainhash.Hash
 }
 
 // New returns a BlockChain instance using the provided configuration details.
@@ -1533,6 +1689,8 @@ func New(config *Config) (*BlockChain, error) {
 		blocksPerRetarget:   int32(targetTimespan / targetTimePerBlock),
 		Index:               newBlockIndex(config.DB, params),
 		hashCache:           config.HashCache,
+		pruneTarget:         config.PruneTarget,
+		assumeValid:         config.AssumeValid,
 		BestChain:           newChainView(nil),
 		orphans:             make(map[chainhash.Hash]*orphanBlock),
 		prevOrphans:         make(map[chainhash.Hash][]*orphanBlock),
@@ -1546,10 +1704,20 @@ func New(config *Config) (*BlockChain, error) {
 	if e := b.initChainState(); E.Chk(e) {
 		return nil, e
 	}
+	if b.snapshotFailed {
+		return nil, errors.New(
+			"the utxo snapshot the chain state was imported from did not match the blocks below it, reset the chain" +
+				" to download and validate it from the genesis block",
+		)
+	}
 	// Perform any upgrades to the various chain-specific buckets as needed.
 	if e := b.maybeUpgradeDbBuckets(config.Interrupt); E.Chk(e) {
 		return nil, e
 	}
+	// Load the statistics of the utxo set, which are computed the first time.
+	if e := b.initUtxoStats(); E.Chk(e) {
+		return nil, e
+	}
 	// Initialize and catch up all of the currently active optional indexes as needed.
 	if config.IndexManager != nil {
 		e := config.IndexManager.Init(&b, config.Interrupt)
@@ -1557,6 +1725,14 @@ func New(config *Config) (*BlockChain, error) {
 			return nil, e
 		}
 	}
+	// Verify the utxo snapshot the chain state was imported from by connecting the blocks below it as they arrive.
+	if b.snapshotHeight > 0 {
+		if b.pruneTarget > 0 {
+			W.Ln("the blocks below the utxo snapshot are not downloaded when pruning, so it can't be verified")
+		}
+		b.historySignal = make(chan struct{}, 1)
+		go b.historyReplayer(config.Interrupt)
+	}
 	// // Initialize rule change threshold state caches.
 	// if e := b.initThresholdCaches(); E.Chk(e) {
 	// 	return nil, e
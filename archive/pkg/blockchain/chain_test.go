@@ -873,3 +873,141 @@ func TestIntervalBlockHashes(t *testing.T) {
 		}
 	}
 }
+
+// TestBestCandidate ensures that the branch chosen when reorganizing after blocks are invalidated or reconsidered is the
+// one with the most work, that the current tip wins ties, and that branches that are known to be invalid or that have
+// blocks missing are skipped.
+func TestBestCandidate(t *testing.T) {
+	// Construct a synthetic block chain with a block index consisting of the following structure, where 4b has two and
+	// a half times the work of the other blocks.
+	//
+	// 	genesis -> 1 -> 2 -> 3 -> 4 -> 5
+	// 	                \-> 3a -> 4a -> 5a -> 6a -> 7a
+	//	                      \-> 4b
+	tip := tstTip
+	chain := newFakeChain(&chaincfg.MainNetParams)
+	branch0Nodes := chainedNodes(chain.BestChain.Genesis(), 5)
+	branch1Nodes := chainedNodes(branch0Nodes[1], 5)
+	branch2Nodes := chainedNodes(branch1Nodes[0], 1)
+	for _, nodes := range [][]*BlockNode{branch0Nodes, branch1Nodes, branch2Nodes} {
+		for _, node := range nodes {
+			node.bits = 0x1d00ffff
+			node.status = statusDataStored
+			chain.Index.AddNode(node)
+		}
+	}
+	branch2Nodes[0].bits = 0x1d006666
+	chain.BestChain.SetTip(tip(branch0Nodes))
+	descendants := chain.Index.descendants(branch0Nodes[1])
+	if len(descendants) != 9 {
+		t.Fatalf("descendants: unexpected count -- got %d, want 9", len(descendants))
+	}
+	seen := make(map[*BlockNode]struct{})
+	for _, node := range descendants {
+		if _, ok := seen[node.parent]; !ok && node.parent != branch0Nodes[1] {
+			t.Fatalf("descendants: node at height %d listed before its parent", node.height)
+		}
+		seen[node] = struct{}{}
+	}
+	tests := []struct {
+		name string
+		// status flags to set on the given nodes before checking
+		invalid  []*BlockNode
+		noData   []*BlockNode
+		expected *BlockNode
+	}{
+		{
+			name:     "branch with the most work wins",
+			expected: tip(branch1Nodes),
+		},
+		{
+			name:     "shorter branch with more work wins",
+			invalid:  []*BlockNode{branch1Nodes[3]},
+			expected: tip(branch2Nodes),
+		},
+		{
+			name:     "current tip wins a tie",
+			invalid:  []*BlockNode{branch1Nodes[3], branch2Nodes[0]},
+			expected: tip(branch0Nodes),
+		},
+		{
+			name:     "branch cut short by missing block",
+			noData:   []*BlockNode{branch1Nodes[4]},
+			expected: branch1Nodes[3],
+		},
+		{
+			name:     "whole side tree invalid",
+			invalid:  []*BlockNode{branch1Nodes[0]},
+			expected: tip(branch0Nodes),
+		},
+	}
+	for _, test := range tests {
+		for _, nodes := range [][]*BlockNode{branch1Nodes, branch2Nodes} {
+			for _, node := range nodes {
+				node.status = statusDataStored
+			}
+		}
+		for _, node := range test.invalid {
+			node.status |= statusValidateFailed
+		}
+		for _, node := range test.noData {
+			node.status &^= statusDataStored
+		}
+		if best := chain.bestCandidate(); best != test.expected {
+			t.Errorf("%s: unexpected best candidate -- got %v, want %v", test.name, best, test.expected)
+		}
+	}
+}
+
+// TestChainTips ensures the tips of the main chain and of every side chain are reported with their branch lengths and
+// the status of the blocks of their branch.
+func TestChainTips(t *testing.T) {
+	// Construct a synthetic block chain with a block index consisting of the following structure.
+	//
+	// 	genesis -> 1 -> 2 -> 3 -> 4 -> 5
+	// 	                |          \-> 5d (data stored)
+	// 	                \-> 3a -> 4a (valid)
+	// 	                \-> 3b -> 4b (no data for 3b)
+	// 	                      \-> 4c (invalid)
+	tip := tstTip
+	chain := newFakeChain(&chaincfg.MainNetParams)
+	branch0Nodes := chainedNodes(chain.BestChain.Genesis(), 5)
+	branch1Nodes := chainedNodes(branch0Nodes[1], 2)
+	branch2Nodes := chainedNodes(branch0Nodes[1], 2)
+	branch3Nodes := chainedNodes(branch2Nodes[0], 1)
+	branch4Nodes := chainedNodes(branch0Nodes[3], 1)
+	for _, nodes := range [][]*BlockNode{branch0Nodes, branch1Nodes, branch2Nodes, branch3Nodes, branch4Nodes} {
+		for _, node := range nodes {
+			node.status = statusDataStored
+			chain.Index.AddNode(node)
+		}
+	}
+	for _, node := range branch1Nodes {
+		node.status |= statusValid
+	}
+	branch2Nodes[0].status = 0
+	branch3Nodes[0].status |= statusValidateFailed
+	chain.BestChain.SetTip(tip(branch0Nodes))
+	expected := map[chainhash.Hash]ChainTip{
+		tip(branch0Nodes).hash: {Height: 5, BranchLen: 0, Status: StatusActive},
+		tip(branch1Nodes).hash: {Height: 4, BranchLen: 2, Status: StatusValidFork},
+		tip(branch2Nodes).hash: {Height: 4, BranchLen: 2, Status: StatusHeadersOnly},
+		tip(branch3Nodes).hash: {Height: 4, BranchLen: 2, Status: StatusInvalid},
+		tip(branch4Nodes).hash: {Height: 5, BranchLen: 1, Status: StatusValidHeaders},
+	}
+	tips := chain.ChainTips()
+	if len(tips) != len(expected) {
+		t.Fatalf("ChainTips: unexpected count -- got %d, want %d", len(tips), len(expected))
+	}
+	for _, got := range tips {
+		want, ok := expected[got.Hash]
+		if !ok {
+			t.Errorf("ChainTips: unexpected tip %v at height %d", got.Hash, got.Height)
+			continue
+		}
+		want.Hash = got.Hash
+		if got != want {
+			t.Errorf("ChainTips: unexpected tip -- got %+v, want %+v", got, want)
+		}
+	}
+}
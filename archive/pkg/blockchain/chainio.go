@@ -46,6 +46,21 @@ var (
 	utxoSetVersionKeyName = []byte("utxosetversion")
 	// utxoSetBucketName is the name of the db bucket used to house the unspent transaction output set.
 	utxoSetBucketName = []byte("utxosetv2")
+	// pruneHeightKeyName is the name of the db key used to store the height of the first block of the main chain whose
+	// data has not been pruned.
+	pruneHeightKeyName = []byte("pruneheight")
+	// snapshotHeightKeyName is the name of the db key used to store the height of the utxo snapshot the chain state
+	// was imported from, while the blocks below it have not all been downloaded.
+	snapshotHeightKeyName = []byte("snapshotheight")
+	// historyUtxoSetBucketName is the name of the db bucket used to house the utxo set built up from the genesis block
+	// by connecting the blocks below the utxo snapshot the chain state was imported from.
+	historyUtxoSetBucketName = []byte("historyutxoset")
+	// historyReplayKeyName is the name of the db key used to store the height of the last block connected to the
+	// history utxo set and the statistics of that set.
+	historyReplayKeyName = []byte("historyreplay")
+	// utxoStatsKeyName is the name of the db key used to store the statistics of the utxo set, which are updated with
+	// it as blocks are connected and disconnected.
+	utxoStatsKeyName = []byte("utxostats")
 	// byteOrder is the preferred byte order used for serializing numeric fields for storage in the database.
 	byteOrder = binary.LittleEndian
 )
@@ -596,10 +611,15 @@ func deserializeUtxoEntry(serialized []byte) (entry *UtxoEntry, e error) {
 // this as efficiently as possible. When there are no entries for the provided hash, nil will be returned for the both
 // the entry and the error.
 func dbFetchUtxoEntryByHash(dbTx database.Tx, hash *chainhash.Hash) (*UtxoEntry, error) {
+	return fetchUtxoEntryByHash(dbTx.Metadata().Bucket(utxoSetBucketName), hash)
+}
+
+// fetchUtxoEntryByHash finds and fetches a utxo for the given hash from the utxo set in the given bucket.
+func fetchUtxoEntryByHash(utxoBucket database.Bucket, hash *chainhash.Hash) (*UtxoEntry, error) {
 	// Attempt to find an entry by seeking for the hash along with a zero index. Due to the fact the keys are serialized
 	// as <hash><index>, where the index uses an MSB encoding, if there are any entries for the hash at all, one will be
 	// found.
-	cursor := dbTx.Metadata().Bucket(utxoSetBucketName).Cursor()
+	cursor := utxoBucket.Cursor()
 	key := outpointKey(wire.OutPoint{Hash: *hash, Index: 0})
 	ok := cursor.Seek(*key)
 	recycleOutpointKey(key)
@@ -621,10 +641,14 @@ func dbFetchUtxoEntryByHash(dbTx database.Tx, hash *chainhash.Hash) (*UtxoEntry,
 // dbFetchUtxoEntry uses an existing database transaction to fetch the specified transaction output from the utxo set.
 // When there is no entry for the provided output, nil will be returned for both the entry and the error.
 func dbFetchUtxoEntry(dbTx database.Tx, outpoint wire.OutPoint) (*UtxoEntry, error) {
+	return fetchUtxoEntry(dbTx.Metadata().Bucket(utxoSetBucketName), outpoint)
+}
+
+// fetchUtxoEntry fetches the specified transaction output from the utxo set in the given bucket.
+func fetchUtxoEntry(utxoBucket database.Bucket, outpoint wire.OutPoint) (*UtxoEntry, error) {
 	// Fetch the unspent transaction output information for the passed transaction output. Return now when there is no
 	// entry.
 	key := outpointKey(outpoint)
-	utxoBucket := dbTx.Metadata().Bucket(utxoSetBucketName)
 	serializedUtxo := utxoBucket.Get(*key)
 	recycleOutpointKey(key)
 	if serializedUtxo == nil {
@@ -659,19 +683,43 @@ func dbFetchUtxoEntry(dbTx database.Tx, outpoint wire.OutPoint) (*UtxoEntry, err
 }
 
 // dbPutUtxoView uses an existing database transaction to update the utxo set in the database based on the provided utxo
-// view contents and state.
-//
-// In particular, only the entries that have been marked as modified are written to the database.
-func dbPutUtxoView(dbTx database.Tx, view *UtxoViewpoint) (e error) {
-	utxoBucket := dbTx.Metadata().Bucket(utxoSetBucketName)
+// view contents and state. In particular, only the entries that have been marked as modified are written to the
+// database. The statistics of the utxo set are updated with the changes when stats is not nil.
+func dbPutUtxoView(dbTx database.Tx, view *UtxoViewpoint, stats *utxoStats) (e error) {
+	utxoBucket := view.dbBucket(dbTx)
+	// Record which of the transactions with modified outputs had unspent outputs before, so the number of transactions
+	// in the utxo set can be updated afterwards.
+	var hadOutputs map[chainhash.Hash]bool
+	if stats != nil {
+		hadOutputs = make(map[chainhash.Hash]bool)
+		for outpoint, entry := range view.entries {
+			if entry == nil || !entry.isModified() {
+				continue
+			}
+			if _, ok := hadOutputs[outpoint.Hash]; !ok {
+				if hadOutputs[outpoint.Hash], e = haveTxOutputs(utxoBucket, &outpoint.Hash); E.Chk(e) {
+					return e
+				}
+			}
+		}
+	}
 	for outpoint, entry := range view.entries {
 		// No need to update the database if the entry was not modified.
 		if entry == nil || !entry.isModified() {
 			continue
 		}
+		key := outpointKey(outpoint)
+		if stats != nil {
+			if old := utxoBucket.Get(*key); old != nil {
+				oldEntry, e := deserializeUtxoEntry(old)
+				if e != nil {
+					return e
+				}
+				stats.remove(outpoint, oldEntry, *key, old)
+			}
+		}
 		// Remove the utxo entry if it is spent.
 		if entry.IsSpent() {
-			key := outpointKey(outpoint)
 			e := utxoBucket.Delete(*key)
 			recycleOutpointKey(key)
 			if e != nil {
@@ -684,13 +732,27 @@ func dbPutUtxoView(dbTx database.Tx, view *UtxoViewpoint) (e error) {
 		if e != nil {
 			return e
 		}
-		key := outpointKey(outpoint)
 		e = utxoBucket.Put(*key, serialized)
 		// NOTE: The key is intentionally not recycled here since the database interface contract prohibits
 		// modifications. It will be garbage collected normally when the database is done with it.
 		if e != nil {
 			return e
 		}
+		if stats != nil {
+			stats.add(outpoint, entry, *key, serialized)
+		}
+	}
+	for hash, had := range hadOutputs {
+		var have bool
+		if have, e = haveTxOutputs(utxoBucket, &hash); E.Chk(e) {
+			return e
+		}
+		switch {
+		case had && !have:
+			stats.transactions--
+		case !had && have:
+			stats.transactions++
+		}
 	}
 	return nil
 }
@@ -864,6 +926,54 @@ func dbPutBestState(dbTx database.Tx, snapshot *BestState, workSum *big.Int) (e
 	return dbTx.Metadata().Put(chainStateKeyName, serializedData)
 }
 
+// dbCreateChainBuckets uses an existing database transaction to create the buckets that house the chain state and to
+// store their versions.
+func dbCreateChainBuckets(dbTx database.Tx) (e error) {
+	meta := dbTx.Metadata()
+	// Create the bucket that houses the block index data.
+	_, e = meta.CreateBucket(blockIndexBucketName)
+	if e != nil {
+		return e
+	}
+	// Create the bucket that houses the chain block hash to height index.
+	_, e = meta.CreateBucket(hashIndexBucketName)
+	if e != nil {
+		return e
+	}
+	// Create the bucket that houses the chain block height to hash index.
+	_, e = meta.CreateBucket(heightIndexBucketName)
+	if e != nil {
+		return e
+	}
+	// Create the bucket that houses the spend journal data and store its
+	// version.
+	_, e = meta.CreateBucket(spendJournalBucketName)
+	if e != nil {
+		return e
+	}
+	e = dbPutVersion(
+		dbTx, utxoSetVersionKeyName,
+		latestUtxoSetBucketVersion,
+	)
+	if e != nil {
+		return e
+	}
+	// Create the bucket that houses the utxo set and store its version. Note that the genesis block coinbase
+	// transaction is intentionally not inserted here since it is not spendable by consensus rules.
+	_, e = meta.CreateBucket(utxoSetBucketName)
+	if e != nil {
+		return e
+	}
+	e = dbPutVersion(
+		dbTx, spendJournalVersionKeyName,
+		latestSpendJournalBucketVersion,
+	)
+	if e != nil {
+		return e
+	}
+	return nil
+}
+
 // createChainState initializes both the database and the chain state to the genesis block. This includes creating the
 // necessary buckets and inserting the genesis block so it must only be called on an uninitialized database.
 func (b *BlockChain) createChainState() (e error) {
@@ -898,46 +1008,7 @@ func (b *BlockChain) createChainState() (e error) {
 	// genesis block.
 	e = b.db.Update(
 		func(dbTx database.Tx) (e error) {
-			meta := dbTx.Metadata()
-			// Create the bucket that houses the block index data.
-			_, e = meta.CreateBucket(blockIndexBucketName)
-			if e != nil {
-				return e
-			}
-			// Create the bucket that houses the chain block hash to height index.
-			_, e = meta.CreateBucket(hashIndexBucketName)
-			if e != nil {
-				return e
-			}
-			// Create the bucket that houses the chain block height to hash index.
-			_, e = meta.CreateBucket(heightIndexBucketName)
-			if e != nil {
-				return e
-			}
-			// Create the bucket that houses the spend journal data and store its
-			// version.
-			_, e = meta.CreateBucket(spendJournalBucketName)
-			if e != nil {
-				return e
-			}
-			e = dbPutVersion(
-				dbTx, utxoSetVersionKeyName,
-				latestUtxoSetBucketVersion,
-			)
-			if e != nil {
-				return e
-			}
-			// Create the bucket that houses the utxo set and store its version. Note that the genesis block coinbase
-			// transaction is intentionally not inserted here since it is not spendable by consensus rules.
-			_, e = meta.CreateBucket(utxoSetBucketName)
-			if e != nil {
-				return e
-			}
-			e = dbPutVersion(
-				dbTx, spendJournalVersionKeyName,
-				latestSpendJournalBucketVersion,
-			)
-			if e != nil {
+			if e = dbCreateChainBuckets(dbTx); E.Chk(e) {
 				return e
 			}
 			// Save the genesis block to the block index database.
@@ -1072,6 +1143,10 @@ func (b *BlockChain) initChainState() (e error) {
 				)
 			}
 			b.BestChain.SetTip(tip)
+			b.pruneHeight = dbFetchPruneHeight(dbTx)
+			if e = b.loadSnapshotState(dbTx); E.Chk(e) {
+				return e
+			}
 			// Load the raw blk bytes for the best blk.
 			blockBytes, e := dbTx.FetchBlock(&state.hash)
 			if e != nil {
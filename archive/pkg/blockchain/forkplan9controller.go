@@ -36,32 +36,18 @@ func (b *BlockChain) CalcNextRequiredDifficultyPlan9Controller(lastNode *BlockNo
 ) {
 	nH := lastNode.height + 1
 	currFork := fork.GetCurrent(nH)
-	nTB := make(Diffs)
 	switch currFork {
 	case 0:
-		for i := range fork.List[0].Algos {
-			v := fork.List[currFork].Algos[i].Version
-			nTB[v], e = b.CalcNextRequiredDifficultyHalcyon(lastNode, i, true)
-		}
-		return nTB, nil
+		return b.calcNextRequiredDifficulties(lastNode)
 	case 1:
-		if b.DifficultyHeight.Load() != nH {
-			b.DifficultyHeight.Store(nH)
-			currFork := fork.GetCurrent(nH)
-			algos := make(AlgoList, len(fork.List[currFork].Algos))
-			var counter int
-			for i := range fork.List[1].Algos {
-				algos[counter] = Algo{
-					Name:   i,
-					Params: fork.List[currFork].Algos[i],
-				}
-				counter++
+		// the cache is keyed by the hash of the previous block, as blocks of other chains can have the same height
+		if prev, ok := b.DifficultyPrev.Load().(chainhash.Hash); !ok || prev != lastNode.hash {
+			if diffs, e = b.calcNextRequiredDifficulties(lastNode); e != nil {
+				return
 			}
-			sort.Sort(algos)
-			for _, v := range algos {
-				nTB[v.Params.Version], _, e = b.CalcNextRequiredDifficultyPlan9(lastNode, v.Name, true)
-			}
-			diffs = nTB
+			// the bits are stored before the hash so the cache never holds the hash without its bits
+			b.DifficultyBits.Store(diffs)
+			b.DifficultyPrev.Store(lastNode.hash)
 			// Traces(diffs)
 		} else {
 			diffs = b.DifficultyBits.Load().(Diffs)
@@ -71,3 +57,34 @@ func (b *BlockChain) CalcNextRequiredDifficultyPlan9Controller(lastNode *BlockNo
 	F.Ln("should not fall through here")
 	return
 }
+
+// calcNextRequiredDifficulties returns the difficulty targets of all of the algorithms for the block after lastNode
+// without using or updating the cache of the controller.
+func (b *BlockChain) calcNextRequiredDifficulties(lastNode *BlockNode) (diffs Diffs, e error) {
+	nH := lastNode.height + 1
+	currFork := fork.GetCurrent(nH)
+	diffs = make(Diffs)
+	switch currFork {
+	case 0:
+		for i := range fork.List[0].Algos {
+			v := fork.List[currFork].Algos[i].Version
+			diffs[v], e = b.CalcNextRequiredDifficultyHalcyon(lastNode, i, true)
+		}
+		return diffs, nil
+	case 1:
+		algos := make(AlgoList, len(fork.List[currFork].Algos))
+		var counter int
+		for i := range fork.List[1].Algos {
+			algos[counter] = Algo{
+				Name:   i,
+				Params: fork.List[currFork].Algos[i],
+			}
+			counter++
+		}
+		sort.Sort(algos)
+		for _, v := range algos {
+			diffs[v.Params.Version], _, e = b.CalcNextRequiredDifficultyPlan9(lastNode, v.Name, true)
+		}
+	}
+	return
+}
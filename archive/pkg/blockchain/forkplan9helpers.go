@@ -1,6 +1,8 @@
 package blockchain
 
 import (
+	"sync"
+
 	"github.com/VividCortex/ewma"
 	"github.com/p9c/pod/pkg/fork"
 	
@@ -26,10 +28,7 @@ func (b *BlockChain) GetCommonP9Averages(lastNode *BlockNode, nH int32) (
 		return
 	}
 	var oldestStamp int64
-	f, _ := b.BlockByHeight(startHeight)
-	if f != nil {
-		fh := f.WireBlock().Header.BlockHash()
-		first := b.Index.LookupNode(&fh)
+	if first := b.p9StartNode(lastNode, startHeight); first != nil {
 		allTime := float64(lastNode.timestamp - first.timestamp)
 		allBlocks := float64(lastNode.height - first.height)
 		// time from lastNode timestamp until start
@@ -43,7 +42,7 @@ func (b *BlockChain) GetCommonP9Averages(lastNode *BlockNode, nH int32) (
 			allTimeDiv = float64(1)
 		}
 		allTimeDiv *= allTimeDiv * allTimeDiv * allTimeDiv * allTimeDiv
-		oldestStamp = f.WireBlock().Header.Timestamp.Unix()
+		oldestStamp = first.timestamp
 	} else {
 		// the previous if should prevent this occurring
 	}
@@ -252,3 +251,26 @@ func capP9Adjustment(adjustment float64) float64 {
 	}
 	return adjustment
 }
+
+// p9StartCache remembers the first block of the plan 9 hard fork found for the last block it was looked up for, which is
+// the same for the block after it.
+type p9StartCache struct {
+	sync.Mutex
+	last, first *BlockNode
+}
+
+// p9StartNode returns the block at the activation height of the plan 9 hard fork in the chain of a block. The best chain
+// is used when it reaches that height, otherwise the block is found by walking back from the last block, as is the case
+// for chains of headers checked before their blocks are downloaded.
+func (b *BlockChain) p9StartNode(lastNode *BlockNode, startHeight int32) *BlockNode {
+	if first := b.BestChain.NodeByHeight(startHeight); first != nil {
+		return first
+	}
+	b.p9Start.Lock()
+	defer b.p9Start.Unlock()
+	if b.p9Start.first == nil || (lastNode.parent != b.p9Start.last && lastNode != b.p9Start.last) {
+		b.p9Start.first = lastNode.Ancestor(startHeight)
+	}
+	b.p9Start.last = lastNode
+	return b.p9Start.first
+}
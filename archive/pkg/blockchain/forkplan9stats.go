@@ -0,0 +1,90 @@
+package blockchain
+
+import (
+	"math/big"
+	"sort"
+	
+	"github.com/p9c/pod/pkg/fork"
+)
+
+// AlgoStats is the performance of one of the plan 9 algorithms over a window of blocks ending at the chain tip
+type AlgoStats struct {
+	Name    string
+	Version int32
+	// Blocks is the number of blocks in the window mined with the algorithm
+	Blocks int32
+	// AverageInterval is the exponential weighted moving average of the seconds between blocks of the algorithm, and
+	// Adjustment is its ratio to the TargetInterval, the VersionInterval of the algorithm
+	AverageInterval float64
+	TargetInterval  int64
+	Adjustment      float64
+	// Bits is the target the next block of the algorithm must meet
+	Bits uint32
+	// Work is the sum of the work of the blocks of the algorithm in the window
+	Work *big.Int
+}
+
+// ChainAlgoStats is the per algorithm statistics of a window of blocks along with the averages common to all of the
+// algorithms that the plan 9 difficulty adjustment is based on
+type ChainAlgoStats struct {
+	Height    int32
+	Blocks    int32
+	Timespan  int64
+	AllTimeAv float64
+	AllTimeDiv,
+	QHourDiv,
+	HourDiv,
+	DayDiv float64
+	Algos []AlgoStats
+}
+
+// GetAlgoStats gathers the statistics of each of the plan 9 algorithms over the last window blocks of the main chain.
+// The targets of the next blocks are calculated without the cache of the difficulty controller, which only the chain
+// updates as it processes blocks.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) GetAlgoStats(window int32) (cs *ChainAlgoStats, e error) {
+	b.ChainLock.RLock()
+	defer b.ChainLock.RUnlock()
+	tip := b.BestChain.Tip()
+	startHeight := tip.height - window
+	if startHeight < 0 {
+		startHeight = 0
+	}
+	var diffs Diffs
+	if diffs, e = b.calcNextRequiredDifficulties(tip); E.Chk(e) {
+		return
+	}
+	cs = &ChainAlgoStats{Height: tip.height, Blocks: tip.height - startHeight}
+	cs.AllTimeAv, cs.AllTimeDiv, cs.QHourDiv, cs.HourDiv, cs.DayDiv = b.GetCommonP9Averages(tip, tip.height+1)
+	stats := make(map[int32]*AlgoStats, len(fork.P9AlgosNumeric))
+	for version, params := range fork.P9AlgosNumeric {
+		name := fork.P9AlgoVers[version]
+		as := &AlgoStats{
+			Name:           name,
+			Version:        version,
+			TargetInterval: int64(params.VersionInterval),
+			Bits:           diffs[version],
+			Work:           big.NewInt(0),
+		}
+		if _, _, algStamps, _ := GetAlgStamps(name, startHeight, tip); len(algStamps) > 1 {
+			as.AverageInterval, as.Adjustment = GetAlg(algStamps, float64(params.VersionInterval))
+		}
+		stats[version] = as
+	}
+	ln := tip
+	for ; ln != nil && ln.height > startHeight; ln = ln.parent {
+		if as, ok := stats[ln.version]; ok {
+			as.Blocks++
+			as.Work.Add(as.Work, CalcWork(ln.bits, ln.height, ln.version))
+		}
+	}
+	if ln != nil {
+		cs.Timespan = tip.timestamp - ln.timestamp
+	}
+	for _, as := range stats {
+		cs.Algos = append(cs.Algos, *as)
+	}
+	sort.Slice(cs.Algos, func(i, j int) bool { return cs.Algos[i].Version < cs.Algos[j].Version })
+	return
+}
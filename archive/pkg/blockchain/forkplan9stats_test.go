@@ -0,0 +1,69 @@
+package blockchain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/p9c/pod/pkg/chaincfg"
+	"github.com/p9c/pod/pkg/fork"
+)
+
+// TestGetAlgoStatsDifficultyCache checks that gathering the algorithm statistics doesn't leave the difficulty cache of
+// the chain claiming to hold the targets of the next block when it still holds those of the block before, so the chain
+// keeps requiring the targets the adjustment calculates for the next block.
+func TestGetAlgoStatsDifficultyCache(t *testing.T) {
+	// the plan 9 hard fork starts at the genesis block on testnet
+	fork.IsTestnet = true
+	defer func() { fork.IsTestnet = false }()
+	params := &chaincfg.TestNet3Params
+	chain := newFakeChain(params)
+	chain.DifficultyBits.Store(make(Diffs))
+	var versions []int32
+	for version := range fork.P9AlgosNumeric {
+		versions = append(versions, version)
+	}
+	algoName := fork.P9AlgoVers[versions[0]]
+	tip := chain.BestChain.Tip()
+	addBlock := func(i int, interval int64) {
+		version := versions[i%len(versions)]
+		bits, _, e := chain.CalcNextRequiredDifficultyPlan9(tip, fork.P9AlgoVers[version], false)
+		if e != nil {
+			t.Fatal(e)
+		}
+		tip = newFakeNode(tip, version, bits, time.Unix(tip.timestamp+interval, 0))
+		chain.Index.AddNode(tip)
+		chain.BestChain.SetTip(tip)
+	}
+	for i := 0; i < 50; i++ {
+		addBlock(i, 5)
+	}
+	// the chain caches the targets of the next block as it processes the tip
+	if _, e := chain.CalcNextRequiredDifficultyFromNode(tip, algoName, false); e != nil {
+		t.Fatal(e)
+	}
+	// a slow block changes the targets of the block after it
+	addBlock(50, 600)
+	expected, _, e := chain.CalcNextRequiredDifficultyPlan9(tip, algoName, false)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if previous, _, _ := chain.CalcNextRequiredDifficultyPlan9(tip.parent, algoName, false); previous == expected {
+		t.Fatal("the slow block did not change the difficulty")
+	}
+	stats, e := chain.GetAlgoStats(100)
+	if e != nil {
+		t.Fatal(e)
+	}
+	for _, as := range stats.Algos {
+		if as.Name == algoName && as.Bits != expected {
+			t.Fatalf("statistics have target %08x for the next %s block, expected %08x", as.Bits, algoName, expected)
+		}
+	}
+	bits, e := chain.CalcNextRequiredDifficultyFromNode(tip, algoName, false)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if bits != expected {
+		t.Fatalf("next %s block requires %08x after gathering statistics, expected %08x", algoName, bits, expected)
+	}
+}
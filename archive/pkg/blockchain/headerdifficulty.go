@@ -0,0 +1,86 @@
+package blockchain
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/p9c/pod/pkg/bits"
+	"github.com/p9c/pod/pkg/chaincfg"
+	"github.com/p9c/pod/pkg/chainhash"
+	"github.com/p9c/pod/pkg/fork"
+	"github.com/p9c/pod/pkg/wire"
+)
+
+// HeaderDifficulty checks the difficulty of block headers for clients that keep a chain of headers without a block
+// database, such as SPV wallets. The difficulty of each algorithm is calculated by the same adjustment as the full
+// node's, which looks back at the blocks of all the algorithms as far as the first block of the plan 9 hard fork, so a
+// block node is kept for every header that was added.
+type HeaderDifficulty struct {
+	sync.Mutex
+	// chain only provides the parameters and the plan 9 start block cache of the difficulty adjustment
+	chain *BlockChain
+	nodes map[chainhash.Hash]*BlockNode
+}
+
+// NewHeaderDifficulty returns a HeaderDifficulty for the chain of the given parameters, starting from its genesis
+// block.
+func NewHeaderDifficulty(params *chaincfg.Params) *HeaderDifficulty {
+	genesis := NewBlockNode(&params.GenesisBlock.Header, nil)
+	return &HeaderDifficulty{
+		chain: &BlockChain{params: params, BestChain: newChainView(nil)},
+		nodes: map[chainhash.Hash]*BlockNode{genesis.hash: genesis},
+	}
+}
+
+// Check returns an error if the bits of a header are not the difficulty its algorithm requires after the header it
+// builds on, which must have been added before.
+//
+// This function is safe for concurrent access.
+func (h *HeaderDifficulty) Check(header *wire.BlockHeader) (e error) {
+	h.Lock()
+	defer h.Unlock()
+	prev, ok := h.nodes[header.PrevBlock]
+	if !ok {
+		str := fmt.Sprintf("previous block %v of header %v is not known", header.PrevBlock, header.BlockHash())
+		return ruleError(ErrPreviousBlockUnknown, str)
+	}
+	height := prev.height + 1
+	algoName := fork.GetAlgoName(header.Version, height)
+	var expected uint32
+	switch fork.GetCurrent(height) {
+	case 0:
+		expected, e = h.chain.CalcNextRequiredDifficultyHalcyon(prev, algoName, false)
+	case 1:
+		// the plan 9 adjustment is calculated directly, as the cache of the full node only holds one parent
+		expected, _, e = h.chain.CalcNextRequiredDifficultyPlan9(prev, algoName, false)
+	}
+	if e != nil {
+		return
+	}
+	if header.Bits != expected {
+		str := fmt.Sprintf(
+			"%d block difficulty of %08x %064x at height %d is not the expected value of %08x %064x",
+			header.Version, header.Bits, bits.CompactToBig(header.Bits), height, expected, bits.CompactToBig(expected),
+		)
+		return ruleError(ErrUnexpectedDifficulty, str)
+	}
+	return
+}
+
+// Add records a header without checking it, so the headers after it can be checked. The header it builds on must have
+// been added before. Headers that were already checked, such as those loaded from the store of a client, are added
+// without checking them again.
+//
+// This function is safe for concurrent access.
+func (h *HeaderDifficulty) Add(header *wire.BlockHeader) (e error) {
+	h.Lock()
+	defer h.Unlock()
+	prev, ok := h.nodes[header.PrevBlock]
+	if !ok {
+		str := fmt.Sprintf("previous block %v of header %v is not known", header.PrevBlock, header.BlockHash())
+		return ruleError(ErrPreviousBlockUnknown, str)
+	}
+	node := NewBlockNode(header, prev)
+	h.nodes[node.hash] = node
+	return
+}
@@ -0,0 +1,67 @@
+package blockchain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/p9c/pod/pkg/chaincfg"
+	"github.com/p9c/pod/pkg/fork"
+	"github.com/p9c/pod/pkg/wire"
+)
+
+// TestHeaderDifficulty checks that the headers of both algorithms are only accepted with the difficulty the full node
+// requires of them, and that headers which do not build on a known header are rejected.
+func TestHeaderDifficulty(t *testing.T) {
+	params := &chaincfg.MainNetParams
+	chain := newFakeChain(params)
+	h := NewHeaderDifficulty(params)
+	prev := chain.BestChain.Tip()
+	var adjusted bool
+	for i := 0; i < 40; i++ {
+		version := int32(2)
+		if i%2 == 1 {
+			version = 514
+		}
+		height := prev.height + 1
+		// the blocks come in faster than the target, so the difficulty goes up once there are enough of them
+		header := wire.BlockHeader{
+			Version:   version,
+			PrevBlock: prev.hash,
+			Timestamp: time.Unix(prev.timestamp+30, 0),
+		}
+		algoName := fork.GetAlgoName(version, height)
+		var e error
+		if header.Bits, e = chain.CalcNextRequiredDifficultyFromNode(prev, algoName, false); e != nil {
+			t.Fatal(e)
+		}
+		if header.Bits != fork.GetMinBits(algoName, height) {
+			adjusted = true
+		}
+		wrong := header
+		wrong.Bits--
+		if e = h.Check(&wrong); e == nil {
+			t.Fatalf("header at height %d with bits %08x was accepted", height, wrong.Bits)
+		} else if re, ok := e.(RuleError); !ok || re.ErrorCode != ErrUnexpectedDifficulty {
+			t.Fatalf("unexpected error %v", e)
+		}
+		if e = h.Check(&header); e != nil {
+			t.Fatalf("header at height %d: %v", height, e)
+		}
+		if e = h.Add(&header); e != nil {
+			t.Fatal(e)
+		}
+		prev = NewBlockNode(&header, prev)
+	}
+	if !adjusted {
+		t.Fatal("the difficulty was never adjusted")
+	}
+	orphan := wire.BlockHeader{Version: 2, PrevBlock: chain.BestChain.Tip().hash, Bits: 1}
+	orphan.PrevBlock[0] ^= 1
+	for _, f := range []func(*wire.BlockHeader) error{h.Check, h.Add} {
+		if e := f(&orphan); e == nil {
+			t.Fatal("header of an unknown block was accepted")
+		} else if re, ok := e.(RuleError); !ok || re.ErrorCode != ErrPreviousBlockUnknown {
+			t.Fatalf("unexpected error %v", e)
+		}
+	}
+}
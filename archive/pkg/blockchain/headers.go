@@ -0,0 +1,210 @@
+package blockchain
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/p9c/pod/pkg/chainhash"
+	"github.com/p9c/pod/pkg/fork"
+	"github.com/p9c/pod/pkg/wire"
+)
+
+// assumeValidMinAge is how long the blocks on top of the assume-valid block in the chain of headers with the most work
+// take to mine at the target time per block before the scripts of it and its ancestors are not checked. The difficulty
+// of every header is checked, so each of them stands for the work of a block of its algorithm.
+const assumeValidMinAge = 14 * 24 * time.Hour
+
+// HeaderTip is the last of a chain of block headers checked by CheckBlockHeaders. The headers are kept as block nodes
+// linked to the block index but not added to it, so the blocks they describe can be downloaded and processed as usual
+// afterwards.
+type HeaderTip struct {
+	node *BlockNode
+}
+
+// Hash returns the hash of the last header.
+func (t *HeaderTip) Hash() *chainhash.Hash {
+	return &t.node.hash
+}
+
+// Height returns the height of the last header.
+func (t *HeaderTip) Height() int32 {
+	return t.node.height
+}
+
+// headerAlgo returns the algorithm version of a block header at a height. Before the plan 9 hard fork every version
+// other than the scrypt one is sha256d.
+func headerAlgo(header *wire.BlockHeader, height int32) (algo int32) {
+	switch fork.GetCurrent(height) {
+	case 0:
+		if header.Version != 514 {
+			algo = 2
+		} else {
+			algo = 514
+		}
+	case 1:
+		algo = header.Version
+	}
+	return
+}
+
+// CheckBlockHeaders checks that a chain of block headers links to a block in the block index or to the tip of headers
+// checked before, and that each of them is valid in its place in that chain: the proof of work meets the difficulty the
+// adjustment of its algorithm requires, the timestamp is in range and it matches the checkpoints. The tip of the headers
+// is returned so it can be passed back in to check the headers that follow, and compared against the best chain with
+// IsBetterHeaderChain.
+//
+// When the assume-valid block is among the headers, the scripts of it and its ancestors are not checked when they are
+// connected, as long as it is in the chain of headers with the most work with assumeValidMinAge of blocks on top.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) CheckBlockHeaders(tip *HeaderTip, headers []wire.BlockHeader) (*HeaderTip, error) {
+	if len(headers) == 0 {
+		return tip, nil
+	}
+	b.ChainLock.Lock()
+	defer b.ChainLock.Unlock()
+	var prev *BlockNode
+	if tip != nil && tip.node.hash.IsEqual(&headers[0].PrevBlock) {
+		prev = tip.node
+	} else if prev = b.Index.LookupNode(&headers[0].PrevBlock); prev == nil {
+		str := fmt.Sprintf("previous block %v of header %v is not known", headers[0].PrevBlock, headers[0].BlockHash())
+		return nil, ruleError(ErrPreviousBlockUnknown, str)
+	}
+	if b.Index.NodeStatus(prev).KnownInvalid() {
+		str := fmt.Sprintf("header %v extends invalid block %v", headers[0].BlockHash(), prev.hash)
+		return nil, ruleError(ErrInvalidAncestorBlock, str)
+	}
+	var assumeValidSeen bool
+	for i := range headers {
+		header := &headers[i]
+		if !header.PrevBlock.IsEqual(&prev.hash) {
+			str := fmt.Sprintf("header %v does not link to the header before it %v", header.BlockHash(), prev.hash)
+			return nil, ruleError(ErrPreviousBlockUnknown, str)
+		}
+		height := prev.height + 1
+		powLimit := fork.GetMinDiff(fork.GetAlgoName(headerAlgo(header, height), height), height)
+		e := checkBlockHeaderSanity(header, powLimit, b.timeSource, BFNone, height, prev.Header().Timestamp)
+		if e != nil {
+			return nil, e
+		}
+		if e = b.checkBlockHeaderContext(header, prev, BFNone); e != nil {
+			return nil, e
+		}
+		// Creating a node sets the work sum of its parent, which must not change for the blocks in the index as it
+		// decides when a side chain becomes the best chain.
+		workSum := prev.workSum
+		node := NewBlockNode(header, prev)
+		prev.workSum = workSum
+		if b.assumeValid != nil && node.hash.IsEqual(b.assumeValid) {
+			b.setAssumeValidChain(node)
+			assumeValidSeen = true
+		}
+		prev = node
+	}
+	b.updateBestHeader(prev, assumeValidSeen)
+	return &HeaderTip{node: prev}, nil
+}
+
+// IsBetterHeaderChain returns whether the chain of headers ending at a tip has more work than the best chain since the
+// block where they fork. The work is added up from the bits of each block rather than taken from the work sums of the
+// nodes, which only include the parent.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) IsBetterHeaderChain(tip *HeaderTip) bool {
+	b.ChainLock.RLock()
+	defer b.ChainLock.RUnlock()
+	fork := b.BestChain.FindFork(tip.node)
+	return chainWork(tip.node, fork).Cmp(chainWork(b.BestChain.Tip(), fork)) > 0
+}
+
+// chainWork adds up the work of the blocks from a node down to an ancestor, which is not included.
+func chainWork(node, ancestor *BlockNode) *big.Int {
+	work := new(big.Int)
+	for ; node != nil && node != ancestor; node = node.parent {
+		work.Add(work, CalcWork(node.bits, node.height, node.version))
+	}
+	return work
+}
+
+// moreChainWork returns whether the chain of a node has more work than the chain of another since the block where they
+// fork. The work sums stored in the block nodes are not cumulative, so the work of each block is added up.
+func moreChainWork(node, other *BlockNode) bool {
+	a, b := node, other
+	if a.height > b.height {
+		a = a.Ancestor(b.height)
+	} else {
+		b = b.Ancestor(a.height)
+	}
+	for a != nil && b != nil && a.hash != b.hash {
+		a, b = a.parent, b.parent
+	}
+	if a == nil || b == nil {
+		return false
+	}
+	return chainWork(node, a).Cmp(chainWork(other, b)) > 0
+}
+
+// updateBestHeader records the tip of a chain of headers when it has more work than the best chain of headers before,
+// and whether the assume-valid block is buried deep enough in the best chain of headers for its scripts and those of
+// its ancestors to be assumed valid. The blocks on top are only counted again when the best chain of headers does not
+// extend the one before or the assume-valid block was among the headers.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) updateBestHeader(tip *BlockNode, assumeValidSeen bool) {
+	best := b.bestHeader
+	extends := best != nil && tip.Ancestor(best.height) == best
+	switch {
+	case best == nil || extends || moreChainWork(tip, best):
+		b.bestHeader = tip
+	case !assumeValidSeen:
+		return
+	}
+	if len(b.assumeValidChain) == 0 || extends && b.assumeValidBuried && !assumeValidSeen {
+		return
+	}
+	assumeValid := b.assumeValidChain[len(b.assumeValidChain)-1]
+	var age time.Duration
+	n := b.bestHeader
+	for ; n != nil && n.height > assumeValid.height; n = n.parent {
+		age += time.Duration(fork.GetTargetTimePerBlock(n.height)) * time.Second
+	}
+	b.assumeValidBuried = n != nil && n.hash == assumeValid.hash && age >= assumeValidMinAge
+	if b.assumeValidBuried {
+		I.F(
+			"assuming the scripts of block %v at height %d and its ancestors are valid", assumeValid.hash,
+			assumeValid.height,
+		)
+	}
+}
+
+// setAssumeValidChain records the headers from the assume-valid block down to the block index, so its ancestors can be
+// recognised when they are connected without walking back from it every time.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) setAssumeValidChain(node *BlockNode) {
+	base := node
+	for base.parent != nil && b.Index.LookupNode(&base.hash) != base {
+		base = base.parent
+	}
+	chain := make([]*BlockNode, node.height-base.height+1)
+	for n := node; n != base.parent; n = n.parent {
+		chain[n.height-base.height] = n
+	}
+	b.assumeValidBase, b.assumeValidChain = base.height, chain
+}
+
+// isAssumedValid returns whether a block is the assume-valid block or one of its ancestors, whose scripts do not need
+// to be checked while the assume-valid block is buried deep enough in the best chain of headers.
+//
+// This function MUST be called with the chain state lock held (for reads).
+func (b *BlockChain) isAssumedValid(node *BlockNode) bool {
+	if !b.assumeValidBuried {
+		return false
+	}
+	i := node.height - b.assumeValidBase
+	if i < 0 || int(i) >= len(b.assumeValidChain) {
+		return false
+	}
+	return b.assumeValidChain[i].hash == node.hash
+}
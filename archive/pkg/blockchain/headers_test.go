@@ -0,0 +1,244 @@
+package blockchain
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/p9c/pod/pkg/chaincfg"
+	"github.com/p9c/pod/pkg/fork"
+	"github.com/p9c/pod/pkg/wire"
+)
+
+// fakeHeaderNodes returns a chain of nodes after a parent with the same bits, so they each have the same work.
+func fakeHeaderNodes(parent *BlockNode, count int, version int32) []*BlockNode {
+	nodes := make([]*BlockNode, count)
+	for i := range nodes {
+		parent = newFakeNode(parent, version, 0x207fffff, time.Unix(parent.timestamp+1, 0))
+		nodes[i] = parent
+	}
+	return nodes
+}
+
+// TestHeaderChain checks that headers must link to a known block, that a chain of headers is compared against the best
+// chain by the work of both since they fork, and that the ancestors of the assume-valid block are recognised once it is
+// buried deep enough in the best chain of headers.
+func TestHeaderChain(t *testing.T) {
+	chain := newFakeChain(&chaincfg.MainNetParams)
+	genesis := chain.BestChain.Tip()
+	// The first blocks are in the index and the best chain, the others are only headers.
+	nodes := fakeHeaderNodes(genesis, 10, 1)
+	for _, node := range nodes[:3] {
+		chain.Index.AddNode(node)
+	}
+	chain.BestChain.SetTip(nodes[2])
+	orphan := wire.BlockHeader{PrevBlock: nodes[5].hash}
+	if _, e := chain.CheckBlockHeaders(nil, []wire.BlockHeader{orphan}); e == nil {
+		t.Fatal("headers of an unknown block were accepted")
+	} else if re, ok := e.(RuleError); !ok || re.ErrorCode != ErrPreviousBlockUnknown {
+		t.Fatalf("unexpected error %v", e)
+	}
+	tip := &HeaderTip{node: nodes[9]}
+	if tip, e := chain.CheckBlockHeaders(tip, nil); e != nil || tip.Height() != 10 {
+		t.Fatalf("checking no headers returned %v, %v", tip, e)
+	}
+	// The nodes have the same bits, so the longer chain has more work.
+	if !chain.IsBetterHeaderChain(tip) {
+		t.Fatal("a longer chain of headers does not have more work")
+	}
+	side := fakeHeaderNodes(nodes[0], 2, 2)
+	if chain.IsBetterHeaderChain(&HeaderTip{node: side[1]}) {
+		t.Fatal("a chain of headers as long as the best chain has more work")
+	}
+	// The assume-valid block is only trusted once the best chain of headers has two weeks of blocks on top of it.
+	buried := int(assumeValidMinAge / (time.Duration(fork.GetTargetTimePerBlock(1)) * time.Second))
+	nodes = append(nodes, fakeHeaderNodes(nodes[9], 8+buried-len(nodes), 1)...)
+	chain.setAssumeValidChain(nodes[7])
+	expectAssumedValid := func(when string, assumed bool) {
+		for i, node := range nodes {
+			// The blocks in the index below the last one are connected already.
+			want := assumed && i >= 2 && i <= 7
+			if got := chain.isAssumedValid(node); got != want {
+				t.Fatalf("%s: block at height %d is assumed valid: %v, want %v", when, node.height, got, want)
+			}
+		}
+	}
+	chain.updateBestHeader(nodes[6+buried], true)
+	expectAssumedValid("not buried deep enough", false)
+	chain.updateBestHeader(nodes[7+buried], false)
+	expectAssumedValid("buried", true)
+	if chain.isAssumedValid(side[1]) {
+		t.Fatal("a block of a side chain is assumed valid")
+	}
+	// A chain of headers with more work that does not include the assume-valid block is the best one afterwards.
+	other := fakeHeaderNodes(nodes[5], len(nodes)-5, 2)
+	chain.updateBestHeader(other[len(other)-1], false)
+	expectAssumedValid("on another chain", false)
+	if chain.bestHeader != other[len(other)-1] {
+		t.Fatal("the chain of headers with more work is not the best one")
+	}
+	chain.updateBestHeader(nodes[7+buried], false)
+	if chain.bestHeader != other[len(other)-1] {
+		t.Fatal("a chain of headers with less work became the best one")
+	}
+}
+
+// TestHeaderChainForkBoundary checks that chains of headers across the activation of the plan 9 hard fork, which are
+// not in the best chain, are checked against the difficulty the adjustment of each algorithm requires of the block in
+// the best chain, and that headers with the wrong bits or timestamps are rejected on both sides of the boundary.
+func TestHeaderChainForkBoundary(t *testing.T) {
+	fork.IsTestnet = true
+	testnetStart := fork.List[1].TestnetStart
+	fork.List[1].TestnetStart = 4
+	defer func() {
+		fork.IsTestnet = false
+		fork.List[1].TestnetStart = testnetStart
+	}()
+	params := &chaincfg.TestNet3Params
+	var versions []int32
+	for version := range fork.P9AlgosNumeric {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	// The headers are made with the bits a chain that has the blocks in its best chain requires, for which the first
+	// block of the hard fork is found by height.
+	connected := newFakeChain(params)
+	connected.DifficultyBits.Store(make(Diffs))
+	prev := connected.BestChain.Tip()
+	var headers []wire.BlockHeader
+	var adjusted bool
+	makeHeader := func(prev *BlockNode, timestamp time.Time, wrongBits bool) wire.BlockHeader {
+		height := prev.height + 1
+		header := wire.BlockHeader{Version: 2, PrevBlock: prev.hash, Timestamp: timestamp}
+		if fork.GetCurrent(height) > 0 {
+			header.Version = versions[int(height)%len(versions)]
+		}
+		var e error
+		algoName := fork.GetAlgoName(header.Version, height)
+		if header.Bits, e = connected.CalcNextRequiredDifficultyFromNode(prev, algoName, false); e != nil {
+			t.Fatal(e)
+		}
+		if wrongBits {
+			header.Bits--
+		}
+		mineHeader(t, &header, height)
+		return header
+	}
+	for height := int32(1); height <= 30; height++ {
+		// The blocks after the fork come in faster than the target, so the difficulty goes up.
+		interval := int64(5)
+		if fork.GetCurrent(height) == 0 {
+			interval = 30
+		}
+		header := makeHeader(prev, time.Unix(prev.timestamp+interval, 0), false)
+		if fork.GetCurrent(height) > 0 && header.Bits != fork.GetMinBits(fork.GetAlgoName(header.Version, height), height) {
+			adjusted = true
+		}
+		headers = append(headers, header)
+		prev = NewBlockNode(&header, prev)
+		connected.Index.AddNode(prev)
+		connected.BestChain.SetTip(prev)
+	}
+	if !adjusted {
+		t.Fatal("the difficulty was never adjusted after the fork")
+	}
+	// nodes returns the node of the header at an index in the best chain of the connected chain
+	nodes := func(i int) *BlockNode {
+		return connected.BestChain.NodeByHeight(int32(i + 1))
+	}
+	// replace returns the headers up to an index with the one at the index replaced
+	replace := func(i int, header wire.BlockHeader) []wire.BlockHeader {
+		return append(append([]wire.BlockHeader{}, headers[:i]...), header)
+	}
+	tests := []struct {
+		name    string
+		batches [][]wire.BlockHeader
+		code    ErrorCode
+	}{
+		{"correct bits", [][]wire.BlockHeader{headers}, 0},
+		{"correct bits in batches", [][]wire.BlockHeader{headers[:2], headers[2:5], headers[5:]}, 0},
+		{
+			"wrong bits before the fork",
+			[][]wire.BlockHeader{replace(2, makeHeader(nodes(1), headers[2].Timestamp, true))},
+			ErrUnexpectedDifficulty,
+		},
+		{
+			"wrong bits at the fork",
+			[][]wire.BlockHeader{replace(3, makeHeader(nodes(2), headers[3].Timestamp, true))},
+			ErrUnexpectedDifficulty,
+		},
+		{
+			"wrong bits after the fork",
+			[][]wire.BlockHeader{headers[:10], replace(20, makeHeader(nodes(19), headers[20].Timestamp, true))[10:]},
+			ErrUnexpectedDifficulty,
+		},
+		{
+			"timestamp not after the median before the fork",
+			[][]wire.BlockHeader{replace(2, makeHeader(nodes(1), headers[0].Timestamp, false))},
+			ErrTimeTooOld,
+		},
+		{
+			"timestamp not after the previous block after the fork",
+			[][]wire.BlockHeader{replace(20, makeHeader(nodes(19), headers[19].Timestamp, false))},
+			ErrTimeTooOld,
+		},
+		{
+			"timestamp too far in the future",
+			[][]wire.BlockHeader{replace(20, makeHeader(nodes(19), time.Unix(time.Now().Unix()+3*60*60, 0), false))},
+			ErrTimeTooNew,
+		},
+	}
+	for _, test := range tests {
+		// Only the genesis block is in the best chain of the chain checking the headers.
+		chain := newFakeChain(params)
+		chain.DifficultyBits.Store(make(Diffs))
+		var tip *HeaderTip
+		var e error
+		for _, batch := range test.batches {
+			if tip, e = chain.CheckBlockHeaders(tip, batch); e != nil {
+				break
+			}
+		}
+		if test.code == 0 {
+			if e != nil {
+				t.Errorf("%s: %v", test.name, e)
+			} else if *tip.Hash() != headers[len(headers)-1].BlockHash() || tip.Height() != int32(len(headers)) {
+				t.Errorf("%s: unexpected tip %v at height %d", test.name, tip.Hash(), tip.Height())
+			}
+			continue
+		}
+		if re, ok := e.(RuleError); !ok || re.ErrorCode != test.code {
+			t.Errorf("%s: got error %v, want %v", test.name, e, test.code)
+		}
+	}
+	// Checking the headers of a chain that forks from the best chain doesn't change the difficulty required of the block
+	// after the tip of the best chain, which has the same height as the last of the headers.
+	tipNode := connected.BestChain.Tip()
+	next := makeHeader(tipNode, time.Unix(tipNode.timestamp+5, 0), false)
+	var forkHeaders []wire.BlockHeader
+	for forkNode := nodes(27); forkNode.height < tipNode.height+1; {
+		height := forkNode.height + 1
+		header := wire.BlockHeader{
+			Version:   versions[int(height)%len(versions)],
+			PrevBlock: forkNode.hash,
+			Timestamp: time.Unix(forkNode.timestamp+600, 0),
+		}
+		var e error
+		algoName := fork.GetAlgoName(header.Version, height)
+		if header.Bits, _, e = connected.CalcNextRequiredDifficultyPlan9(forkNode, algoName, false); e != nil {
+			t.Fatal(e)
+		}
+		mineHeader(t, &header, height)
+		forkHeaders = append(forkHeaders, header)
+		forkNode = NewBlockNode(&header, forkNode)
+	}
+	if last := forkHeaders[len(forkHeaders)-1]; last.Version != next.Version || last.Bits == next.Bits {
+		t.Fatalf("the slower chain of headers requires the same difficulty %08x as the best chain", next.Bits)
+	}
+	if _, e := connected.CheckBlockHeaders(nil, forkHeaders); e != nil {
+		t.Fatal(e)
+	}
+	if e := connected.checkBlockHeaderContext(&next, tipNode, BFNone); e != nil {
+		t.Fatalf("the next block of the best chain is checked against the difficulty of another chain: %v", e)
+	}
+}
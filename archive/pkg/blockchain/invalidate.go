@@ -0,0 +1,185 @@
+package blockchain
+
+import (
+	"container/list"
+	"fmt"
+
+	"github.com/p9c/pod/pkg/chainhash"
+)
+
+// InvalidateBlock permanently marks the block with the given hash as invalid, along with every block that builds on
+// it. If the block is part of the main chain it is disconnected together with all of its descendants, and the chain is
+// then reorganized onto the best remaining branch that is not known to be invalid.
+//
+// The invalid status is stored in the block index, so it persists across restarts until ReconsiderBlock is called for
+// the block. This function is safe for concurrent access.
+func (b *BlockChain) InvalidateBlock(hash *chainhash.Hash) (e error) {
+	b.ChainLock.Lock()
+	defer b.ChainLock.Unlock()
+	node := b.Index.LookupNode(hash)
+	if node == nil {
+		return fmt.Errorf("block %v is not in the block index", hash)
+	}
+	if node.parent == nil {
+		return fmt.Errorf("the genesis block cannot be invalidated")
+	}
+	b.Index.SetStatusFlags(node, statusValidateFailed)
+	for _, n := range b.Index.descendants(node) {
+		b.Index.SetStatusFlags(n, statusInvalidAncestor)
+	}
+	if b.BestChain.Contains(node) {
+		detachNodes := list.New()
+		for n := b.BestChain.Tip(); n != node.parent; n = n.parent {
+			detachNodes.PushBack(n)
+		}
+		W.F(
+			"INVALIDATE: disconnecting %d blocks from the main chain back to height %d",
+			detachNodes.Len(), node.parent.height,
+		)
+		if e = b.reorganizeChain(detachNodes, list.New()); E.Chk(e) {
+			if writeErr := b.Index.flushToDB(); writeErr != nil {
+				E.Ln("error flushing block index changes to disk:", writeErr)
+			}
+			return
+		}
+	}
+	if e = b.activateBestChain(); E.Chk(e) {
+	}
+	if writeErr := b.Index.flushToDB(); writeErr != nil && e == nil {
+		e = writeErr
+	}
+	return
+}
+
+// ReconsiderBlock removes the invalid status from the block with the given hash, from its ancestors and from its
+// descendants, so that they will be validated again. If this makes a branch better than the current main chain
+// available, the chain is reorganized onto it.
+//
+// Blocks that really are invalid fail validation again during the reorganization and are marked invalid once more.
+// This function is safe for concurrent access.
+func (b *BlockChain) ReconsiderBlock(hash *chainhash.Hash) (e error) {
+	b.ChainLock.Lock()
+	defer b.ChainLock.Unlock()
+	node := b.Index.LookupNode(hash)
+	if node == nil {
+		return fmt.Errorf("block %v is not in the block index", hash)
+	}
+	const invalidFlags = statusValidateFailed | statusInvalidAncestor
+	for n := node; n != nil; n = n.parent {
+		if b.Index.NodeStatus(n).KnownInvalid() {
+			b.Index.UnsetStatusFlags(n, invalidFlags)
+		}
+	}
+	for _, n := range b.Index.descendants(node) {
+		if b.Index.NodeStatus(n).KnownInvalid() {
+			b.Index.UnsetStatusFlags(n, invalidFlags)
+		}
+	}
+	if e = b.activateBestChain(); E.Chk(e) {
+	}
+	if writeErr := b.Index.flushToDB(); writeErr != nil && e == nil {
+		e = writeErr
+	}
+	return
+}
+
+// PreciousBlock makes the branch ending at the block with the given hash the main chain if it has at least as much work
+// as the current main chain. This resolves ties between competing branches of equal work in favour of the given block,
+// as though it had been received first. The choice holds until another branch overtakes it.
+//
+// A block with less work behind it than the main chain, or that is already part of it, is left alone. This function is
+// safe for concurrent access.
+func (b *BlockChain) PreciousBlock(hash *chainhash.Hash) (e error) {
+	b.ChainLock.Lock()
+	defer b.ChainLock.Unlock()
+	node := b.Index.LookupNode(hash)
+	if node == nil {
+		return fmt.Errorf("block %v is not in the block index", hash)
+	}
+	if b.BestChain.Contains(node) {
+		return
+	}
+	if b.Index.NodeStatus(node).KnownInvalid() {
+		return fmt.Errorf("block %v is marked invalid, reconsider it first", hash)
+	}
+	if usable := b.usableAncestor(node); usable != node {
+		return fmt.Errorf("block %v or one of its ancestors is invalid or has not been downloaded", hash)
+	}
+	if tip := b.BestChain.Tip(); moreChainWork(tip, node) {
+		I.F(
+			"block %v at height %d has less work than the main chain at height %d, not switching to it",
+			hash, node.height, tip.height,
+		)
+		return
+	}
+	detachNodes, attachNodes := b.getReorganizeNodes(node)
+	W.F("PRECIOUS: block %v is causing a reorganize", hash)
+	e = b.reorganizeChain(detachNodes, attachNodes)
+	if writeErr := b.Index.flushToDB(); writeErr != nil && e == nil {
+		e = writeErr
+	}
+	return
+}
+
+// activateBestChain reorganizes the chain onto the best branch in the block index that has all of its blocks stored and
+// is not known to be invalid, if that is better than the current main chain. When a branch fails validation during the
+// reorganization its failing block is marked invalid and the next best branch is tried.
+//
+// This function may modify node statuses in the block index without flushing. This function MUST be called with the
+// chain state lock held (for writes).
+func (b *BlockChain) activateBestChain() (e error) {
+	for {
+		best := b.bestCandidate()
+		if best == b.BestChain.Tip() {
+			return
+		}
+		detachNodes, attachNodes := b.getReorganizeNodes(best)
+		if attachNodes.Len() == 0 {
+			return
+		}
+		W.F("REORGANIZE: switching to branch ending at %v (height %d)", best.hash, best.height)
+		if e = b.reorganizeChain(detachNodes, attachNodes); e == nil {
+			return
+		}
+		// a rule error while checking the branch marks the failing block invalid, so the branch will not be selected
+		// again, any other error is a problem with the database that must be returned
+		if _, ok := e.(RuleError); !ok || !b.Index.NodeStatus(best).KnownInvalid() {
+			return
+		}
+		W.Ln("branch failed validation, trying the next best branch:", e)
+	}
+}
+
+// bestCandidate returns the best node in the block index that could become the tip of the main chain, which is the
+// current tip if no other branch is better.
+//
+// Branches are compared by the work done on them since they fork, and a branch only replaces the best one found so far
+// when it has strictly more work, so the current tip wins ties.
+//
+// This function MUST be called with the chain state lock held (for reads).
+func (b *BlockChain) bestCandidate() (best *BlockNode) {
+	best = b.BestChain.Tip()
+	for _, tip := range b.Index.tips() {
+		if candidate := b.usableAncestor(tip); moreChainWork(candidate, best) {
+			best = candidate
+		}
+	}
+	return
+}
+
+// usableAncestor returns the highest node on the branch ending at node for which it and all of its ancestors back to
+// the main chain have their block data stored and are not known to be invalid. It returns the fork point with the main
+// chain if there is no such node.
+//
+// This function MUST be called with the chain state lock held (for reads).
+func (b *BlockChain) usableAncestor(node *BlockNode) (usable *BlockNode) {
+	forkNode := b.BestChain.FindFork(node)
+	usable = node
+	for n := node; n != nil && n != forkNode; n = n.parent {
+		status := b.Index.NodeStatus(n)
+		if status.KnownInvalid() || !status.HaveData() {
+			usable = n.parent
+		}
+	}
+	return
+}
@@ -0,0 +1,130 @@
+package blockchain
+
+import (
+	"testing"
+
+	"github.com/p9c/pod/pkg/block"
+	"github.com/p9c/pod/pkg/chaincfg"
+	"github.com/p9c/pod/pkg/chainhash"
+	"github.com/p9c/pod/pkg/wire"
+)
+
+// TestInvalidateReconsiderPrecious runs InvalidateBlock, ReconsiderBlock and PreciousBlock on a chain of mined blocks
+// with two branches, and checks the tip, the status of the blocks and the utxo set after each of them.
+func TestInvalidateReconsiderPrecious(t *testing.T) {
+	// The blocks are processed in the order 1, 2b, 3b, 4b, 2a, 3a, so the chain starts out on branch b.
+	//
+	// 	genesis -> 1 -> 2a -> 3a -> 4a
+	// 	            \-> 2b -> 3b -> 4b
+	params := &chaincfg.MainNetParams
+	chain, teardown, e := chainSetup("invalidate", params)
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer teardown()
+	hd := NewHeaderDifficulty(params)
+	mine := func(prev *wire.Block, height int32, branch string) *wire.Block {
+		blk := branchBlock(t, params, hd, prev, height, []byte(branch), false)
+		if e := hd.Add(&blk.Header); e != nil {
+			t.Fatal(e)
+		}
+		return blk
+	}
+	process := func(blk *wire.Block, height int32) {
+		if _, isOrphan, e := chain.ProcessBlock(0, block.NewBlock(blk), BFNone, height); e != nil || isOrphan {
+			t.Fatalf("processing block at height %d: orphan %v, %v", height, isOrphan, e)
+		}
+	}
+	hashOf := func(blk *wire.Block) *chainhash.Hash {
+		hash := blk.BlockHash()
+		return &hash
+	}
+	checkTip := func(step string, want *wire.Block) {
+		if best := chain.BestSnapshot(); best.Hash != want.BlockHash() {
+			t.Fatalf("%s: tip is %v at height %d, want %v", step, best.Hash, best.Height, want.BlockHash())
+		}
+	}
+	checkCoinbase := func(step string, blk *wire.Block, want bool) {
+		entry, e := chain.FetchUtxoEntry(wire.OutPoint{Hash: blk.Transactions[0].TxHash()})
+		if e != nil {
+			t.Fatal(e)
+		}
+		if have := entry != nil && !entry.IsSpent(); have != want {
+			t.Fatalf("%s: coinbase of block %v in the utxo set is %v, want %v", step, blk.BlockHash(), have, want)
+		}
+	}
+	checkStatus := func(step string, blk *wire.Block, invalid bool) {
+		status := chain.Index.NodeStatus(chain.Index.LookupNode(hashOf(blk)))
+		if status.KnownInvalid() != invalid {
+			t.Fatalf("%s: block %v is invalid %v, want %v", step, blk.BlockHash(), status.KnownInvalid(), invalid)
+		}
+	}
+	b1 := mine(params.GenesisBlock, 1, "")
+	b2b := mine(b1, 2, "b")
+	b3b := mine(b2b, 3, "b")
+	b4b := mine(b3b, 4, "b")
+	b2a := mine(b1, 2, "a")
+	b3a := mine(b2a, 3, "a")
+	for i, blk := range []*wire.Block{b1, b2b, b3b, b4b, b2a, b3a} {
+		process(blk, []int32{1, 2, 3, 4, 2, 3}[i])
+	}
+	checkTip("processing", b4b)
+	// a branch with less work than the main chain is not switched to
+	if e = chain.PreciousBlock(hashOf(b3a)); e != nil {
+		t.Fatal(e)
+	}
+	checkTip("precious block with less work", b4b)
+	// invalidating a block of the main chain moves the tip to the branch with the most work that is left
+	if e = chain.InvalidateBlock(hashOf(b3b)); e != nil {
+		t.Fatal(e)
+	}
+	checkTip("invalidate", b3a)
+	checkStatus("invalidate", b3b, true)
+	checkStatus("invalidate", b4b, true)
+	checkStatus("invalidate", b2b, false)
+	checkCoinbase("invalidate", b3b, false)
+	checkCoinbase("invalidate", b3a, true)
+	if e = chain.PreciousBlock(hashOf(b4b)); e == nil {
+		t.Fatal("precious block accepted an invalid block")
+	}
+	b4a := mine(b3a, 4, "a")
+	process(b4a, 4)
+	checkTip("extending", b4a)
+	checkCoinbase("extending", b4a, true)
+	// the reconsidered branch has as much work as the main chain, and the main chain wins the tie
+	if e = chain.ReconsiderBlock(hashOf(b3b)); e != nil {
+		t.Fatal(e)
+	}
+	checkTip("reconsider", b4a)
+	checkStatus("reconsider", b3b, false)
+	checkStatus("reconsider", b4b, false)
+	// precious block resolves the tie in favour of the given block, back and forth
+	if e = chain.PreciousBlock(hashOf(b4b)); e != nil {
+		t.Fatal(e)
+	}
+	checkTip("precious block", b4b)
+	checkCoinbase("precious block", b4a, false)
+	checkCoinbase("precious block", b4b, true)
+	if e = chain.PreciousBlock(hashOf(b4a)); e != nil {
+		t.Fatal(e)
+	}
+	checkTip("precious block again", b4a)
+	checkCoinbase("precious block again", b4a, true)
+	checkCoinbase("precious block again", b4b, false)
+	// invalidating the block both branches fork from leaves only the genesis block, and reconsidering a block only
+	// clears its own branch
+	if e = chain.InvalidateBlock(hashOf(b1)); e != nil {
+		t.Fatal(e)
+	}
+	checkTip("invalidate fork point", params.GenesisBlock)
+	checkCoinbase("invalidate fork point", b1, false)
+	checkStatus("invalidate fork point", b4a, true)
+	checkStatus("invalidate fork point", b4b, true)
+	if e = chain.ReconsiderBlock(hashOf(b4a)); e != nil {
+		t.Fatal(e)
+	}
+	checkTip("reconsider fork point", b4a)
+	checkStatus("reconsider fork point", b1, false)
+	checkStatus("reconsider fork point", b4b, true)
+	checkCoinbase("reconsider fork point", b1, true)
+}
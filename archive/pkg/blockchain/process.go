@@ -46,32 +46,21 @@ func (b *BlockChain) ProcessBlock(
 	flags BehaviorFlags, blockHeight int32,
 ) (bool, bool, error,) {
 	T.Ln("blockchain.ProcessBlock", blockHeight, log.Caller("\nfrom", 1))
-	var prevBlock *block.Block
 	var e error
-	prevBlock, e = b.BlockByHash(&candidateBlock.WireBlock().Header.PrevBlock)
-	if prevBlock != nil {
-		blockHeight = prevBlock.Height() + 1
+	// the parent is looked up in the block index rather than the main chain, as blocks of a side chain build on blocks
+	// that are not in the main chain
+	parent := &candidateBlock.WireBlock().Header.PrevBlock
+	if prevNode := b.Index.LookupNode(parent); prevNode != nil {
+		blockHeight = prevNode.height + 1
 	} else {
-		return false, false, e
+		return false, false, fmt.Errorf("previous block %s is not in the block index", parent)
 	}
-	// trc.S(prevBlock)
 	b.ChainLock.Lock()
 	defer b.ChainLock.Unlock()
 	fastAdd := flags&BFFastAdd == BFFastAdd
 	blockHash := candidateBlock.Hash()
-	hf := fork.GetCurrent(blockHeight)
 	bhwa := candidateBlock.WireBlock().BlockHashWithAlgos
-	var algo int32
-	switch hf {
-	case 0:
-		if candidateBlock.WireBlock().Header.Version != 514 {
-			algo = 2
-		} else {
-			algo = 514
-		}
-	case 1:
-		algo = candidateBlock.WireBlock().Header.Version
-	}
+	algo := headerAlgo(&candidateBlock.WireBlock().Header, blockHeight)
 	// The candidateBlock must not already exist in the main chain or side chains.
 	var exists bool
 	if exists, e = b.blockExists(blockHash); E.Chk(e) {
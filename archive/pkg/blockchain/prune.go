@@ -0,0 +1,79 @@
+package blockchain
+
+import (
+	"github.com/p9c/pod/pkg/chainhash"
+	"github.com/p9c/pod/pkg/database"
+)
+
+// MinPrunedBlocks is the number of the most recent blocks of the main chain whose data is never pruned, so a node in
+// pruned mode can still handle reorganizations of that depth and serve recent blocks to its peers.
+const MinPrunedBlocks = 288
+
+// dbFetchPruneHeight uses an existing database transaction to retrieve the height of the first block of the main chain
+// whose data has not been pruned, which is 0 when no blocks have been pruned.
+func dbFetchPruneHeight(dbTx database.Tx) int32 {
+	serialized := dbTx.Metadata().Get(pruneHeightKeyName)
+	if len(serialized) < 4 {
+		return 0
+	}
+	return int32(byteOrder.Uint32(serialized))
+}
+
+// dbPutPruneHeight uses an existing database transaction to store the height of the first block of the main chain
+// whose data has not been pruned.
+func dbPutPruneHeight(dbTx database.Tx, height int32) (e error) {
+	var serialized [4]byte
+	byteOrder.PutUint32(serialized[:], uint32(height))
+	return dbTx.Metadata().Put(pruneHeightKeyName, serialized[:])
+}
+
+// dbPruneBlocks uses an existing database transaction to prune the data of the oldest blocks down to the prune target,
+// keeping the last MinPrunedBlocks blocks of the main chain ending with the node being connected. The spend journal
+// entries of the pruned main chain blocks are removed, as the blocks can no longer be disconnected, and the new prune
+// height is stored. It returns the hashes of the pruned blocks and the new prune height.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) dbPruneBlocks(dbTx database.Tx, node *BlockNode) (
+	pruned []chainhash.Hash, pruneHeight int32, e error,
+) {
+	pruneHeight = b.pruneHeight
+	keep := node.Ancestor(node.height - MinPrunedBlocks)
+	if keep == nil {
+		return
+	}
+	if pruned, e = dbTx.PruneBlocks(b.pruneTarget, &keep.hash); E.Chk(e) || len(pruned) == 0 {
+		return
+	}
+	for i := range pruned {
+		n := b.Index.LookupNode(&pruned[i])
+		if n == nil || node.Ancestor(n.height) != n {
+			continue
+		}
+		if e = dbRemoveSpendJournalEntry(dbTx, &n.hash); E.Chk(e) {
+			return
+		}
+		if n.height >= pruneHeight {
+			pruneHeight = n.height + 1
+		}
+	}
+	e = dbPutPruneHeight(dbTx, pruneHeight)
+	return
+}
+
+// PruneHeight returns the height of the first block of the main chain whose data has not been pruned, which is 0 when
+// no blocks have been pruned.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) PruneHeight() int32 {
+	b.ChainLock.RLock()
+	defer b.ChainLock.RUnlock()
+	return b.pruneHeight
+}
+
+// Pruned returns whether the chain prunes old blocks or has pruned them, in which case it can't serve the full history
+// of the chain to peers or build indexes that need the data of all blocks.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) Pruned() bool {
+	return b.pruneTarget > 0 || b.PruneHeight() > 0
+}
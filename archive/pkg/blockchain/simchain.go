@@ -0,0 +1,28 @@
+package blockchain
+
+import (
+	"github.com/p9c/pod/pkg/chaincfg"
+)
+
+// NewSimChain returns a chain holding only the genesis block of the passed parameters and no database, for driving the
+// difficulty adjustment with synthetic blocks that are connected with NewBlockNode and BestChain.SetTip, as the
+// difficulty simulator does. Only the functions that work from the block index in memory can be used on it.
+func NewSimChain(params *chaincfg.Params) *BlockChain {
+	node := NewBlockNode(&params.GenesisBlock.Header, nil)
+	index := newBlockIndex(nil, params)
+	index.AddNode(node)
+	targetTimespan := params.TargetTimespan
+	adjustmentFactor := params.RetargetAdjustmentFactor
+	b := &BlockChain{
+		params:                params,
+		timeSource:            NewMedianTime(),
+		minRetargetTimespan:   targetTimespan / adjustmentFactor,
+		maxRetargetTimespan:   targetTimespan * adjustmentFactor,
+		blocksPerRetarget:     int32(targetTimespan / params.TargetTimePerBlock),
+		Index:                 index,
+		BestChain:             newChainView(node),
+		DifficultyAdjustments: make(map[string]float64),
+	}
+	b.DifficultyBits.Store(make(Diffs))
+	return b
+}
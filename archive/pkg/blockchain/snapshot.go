@@ -0,0 +1,687 @@
+package blockchain
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/p9c/pod/pkg/block"
+	"github.com/p9c/pod/pkg/chaincfg"
+	"github.com/p9c/pod/pkg/chainhash"
+	"github.com/p9c/pod/pkg/database"
+	"github.com/p9c/pod/pkg/fork"
+	"github.com/p9c/pod/pkg/muhash"
+	"github.com/p9c/pod/pkg/wire"
+)
+
+const (
+	// snapshotVersion is the version of the utxo snapshot format written by ExportUtxoSnapshot
+	snapshotVersion = 1
+	// snapshotHeaderSize is the size of a serialized snapshot header
+	snapshotHeaderSize = 8 + 4 + 4 + chainhash.HashSize + 4 + 8 + 8 + chainhash.HashSize
+	// maxSnapshotRecord is the largest key or value of a utxo accepted from a snapshot
+	maxSnapshotRecord = wire.MaxBlockPayload
+	// snapshotBatchSize is the number of block headers or utxos imported in each database transaction
+	snapshotBatchSize = 10000
+)
+
+// snapshotMagic starts every utxo snapshot file
+var snapshotMagic = [8]byte{'p', 'o', 'd', 'u', 't', 'x', 'o', 0}
+
+// SnapshotHeader describes the chain state a utxo snapshot was taken at. In a snapshot file it is followed by the
+// headers of all the blocks of the main chain up to and including the snapshot block, the snapshot block itself, and
+// the entries of the utxo set in the order of their keys. SetHash commits to the entries, so the set can be compared
+// with the one of another node at the same block.
+type SnapshotHeader struct {
+	Net       wire.BitcoinNet
+	Hash      chainhash.Hash
+	Height    int32
+	TotalTxns uint64
+	NumUtxos  uint64
+	SetHash   chainhash.Hash
+}
+
+// Serialize writes the snapshot header to w.
+func (h *SnapshotHeader) Serialize(w io.Writer) (e error) {
+	var buf [snapshotHeaderSize]byte
+	offset := copy(buf[:], snapshotMagic[:])
+	byteOrder.PutUint32(buf[offset:], snapshotVersion)
+	offset += 4
+	byteOrder.PutUint32(buf[offset:], uint32(h.Net))
+	offset += 4
+	offset += copy(buf[offset:], h.Hash[:])
+	byteOrder.PutUint32(buf[offset:], uint32(h.Height))
+	offset += 4
+	byteOrder.PutUint64(buf[offset:], h.TotalTxns)
+	offset += 8
+	byteOrder.PutUint64(buf[offset:], h.NumUtxos)
+	offset += 8
+	copy(buf[offset:], h.SetHash[:])
+	_, e = w.Write(buf[:])
+	return
+}
+
+// ReadSnapshotHeader reads the header at the start of a utxo snapshot file.
+func ReadSnapshotHeader(r io.Reader) (h *SnapshotHeader, e error) {
+	var buf [snapshotHeaderSize]byte
+	if _, e = io.ReadFull(r, buf[:]); E.Chk(e) {
+		return
+	}
+	if !bytes.Equal(buf[:len(snapshotMagic)], snapshotMagic[:]) {
+		return nil, errors.New("not a utxo snapshot file")
+	}
+	offset := len(snapshotMagic)
+	if version := byteOrder.Uint32(buf[offset:]); version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported utxo snapshot version %d", version)
+	}
+	offset += 4
+	h = &SnapshotHeader{Net: wire.BitcoinNet(byteOrder.Uint32(buf[offset:]))}
+	offset += 4
+	offset += copy(h.Hash[:], buf[offset:])
+	h.Height = int32(byteOrder.Uint32(buf[offset:]))
+	offset += 4
+	h.TotalTxns = byteOrder.Uint64(buf[offset:])
+	offset += 8
+	h.NumUtxos = byteOrder.Uint64(buf[offset:])
+	offset += 8
+	copy(h.SetHash[:], buf[offset:])
+	return
+}
+
+// writeSnapshotRecord writes the key and value of an entry of the utxo set as they are stored in a snapshot
+func writeSnapshotRecord(w io.Writer, key, value []byte) (e error) {
+	if e = wire.WriteVarBytes(w, 0, key); E.Chk(e) {
+		return
+	}
+	return wire.WriteVarBytes(w, 0, value)
+}
+
+// assumedSetHash returns the set hash of the utxo set at a block given in the chain parameters, or nil if there is none.
+func assumedSetHash(params *chaincfg.Params, hash *chainhash.Hash) *chainhash.Hash {
+	for i := range params.AssumeUtxo {
+		if params.AssumeUtxo[i].Hash.IsEqual(hash) {
+			return params.AssumeUtxo[i].SetHash
+		}
+	}
+	return nil
+}
+
+// ExportUtxoSnapshot writes a snapshot of the utxo set at the current best block to w, and returns its header. The set
+// hash of the header is the one the gettxoutsetinfo RPC reports for the block.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) ExportUtxoSnapshot(w io.Writer) (h *SnapshotHeader, e error) {
+	e = b.db.View(
+		func(dbTx database.Tx) (e error) {
+			var state bestChainState
+			if state, e = deserializeBestChainState(dbTx.Metadata().Get(chainStateKeyName)); E.Chk(e) {
+				return
+			}
+			tip := b.Index.LookupNode(&state.hash)
+			if tip == nil {
+				return AssertError(fmt.Sprintf("ExportUtxoSnapshot: cannot find chain tip %s in block index", state.hash))
+			}
+			var blockBytes []byte
+			if blockBytes, e = dbTx.FetchBlock(&tip.hash); E.Chk(e) {
+				return
+			}
+			// the statistics are stored along with the utxo set, so they match the set read in the same transaction
+			var stats *utxoStats
+			if stats, e = dbFetchUtxoStats(dbTx); E.Chk(e) {
+				return
+			}
+			if stats == nil {
+				if stats, e = dbComputeUtxoStats(dbTx); E.Chk(e) {
+					return
+				}
+			}
+			h = &SnapshotHeader{
+				Net:       b.params.Net,
+				Hash:      tip.hash,
+				Height:    tip.height,
+				TotalTxns: state.totalTxns,
+				NumUtxos:  stats.outputs,
+				SetHash:   stats.setHash.Finalize(),
+			}
+			if e = h.Serialize(w); E.Chk(e) {
+				return
+			}
+			nodes := make([]*BlockNode, tip.height+1)
+			for node := tip; node != nil; node = node.parent {
+				nodes[node.height] = node
+			}
+			for i := range nodes {
+				header := nodes[i].Header()
+				if e = header.Serialize(w); E.Chk(e) {
+					return
+				}
+			}
+			if e = wire.WriteVarBytes(w, 0, blockBytes); E.Chk(e) {
+				return
+			}
+			return dbTx.Metadata().Bucket(utxoSetBucketName).ForEach(
+				func(k, v []byte) error {
+					return writeSnapshotRecord(w, k, v)
+				},
+			)
+		},
+	)
+	return
+}
+
+// ImportUtxoSnapshot creates the chain state of an empty database from a utxo snapshot, whose header has already been
+// read from r.
+//
+// The set hash of the snapshot must match the expected one, which comes from a trusted node through the configuration,
+// or from the chain parameters when it is nil. The headers of the main chain must link from the genesis block to the
+// snapshot block and each of them must have the proof of work and the difficulty its algorithm requires and match the
+// checkpoints, and the MuHash of the utxo set must match the set hash.
+//
+// The chain is validated from the snapshot block forward. The blocks below it are downloaded in the background and
+// connected to a utxo set built up from the genesis block, and the snapshot is only trusted once that set matches it.
+func ImportUtxoSnapshot(
+	db database.DB, params *chaincfg.Params, h *SnapshotHeader, expected *chainhash.Hash, r io.Reader,
+) (e error) {
+	if h.Net != params.Net {
+		return fmt.Errorf("utxo snapshot is for network %v, not %v", h.Net, params.Net)
+	}
+	if h.Height < 1 {
+		return errors.New("utxo snapshot is of the genesis block")
+	}
+	if expected == nil {
+		if expected = assumedSetHash(params, &h.Hash); expected == nil {
+			return fmt.Errorf(
+				"there is no trusted set hash for the utxo set at block %s, set the one gettxoutsetinfo reports for"+
+					" it on a node you trust", h.Hash,
+			)
+		}
+	}
+	if !h.SetHash.IsEqual(expected) {
+		return fmt.Errorf("utxo snapshot set hash is %s instead of the trusted %s", h.SetHash, expected)
+	}
+	if e = db.Update(
+		func(dbTx database.Tx) (e error) {
+			if dbTx.Metadata().Get(chainStateKeyName) != nil {
+				return errors.New("the database already has a chain state")
+			}
+			if e = dbCreateChainBuckets(dbTx); E.Chk(e) {
+				return
+			}
+			_, e = dbTx.Metadata().CreateBucket(historyUtxoSetBucketName)
+			return
+		},
+	); E.Chk(e) {
+		return
+	}
+	checkpoints := make(map[int32]*chainhash.Hash)
+	for i := range params.Checkpoints {
+		checkpoints[params.Checkpoints[i].Height] = params.Checkpoints[i].Hash
+	}
+	difficulty := NewHeaderDifficulty(params)
+	// store the headers of the main chain, marking them valid so the chain is not validated again below the snapshot
+	workSum := big.NewInt(0)
+	var prevHash chainhash.Hash
+	for height := int32(0); height <= h.Height; {
+		if e = db.Update(
+			func(dbTx database.Tx) (e error) {
+				blockIndexBucket := dbTx.Metadata().Bucket(blockIndexBucketName)
+				for end := height + snapshotBatchSize; height <= h.Height && height < end; height++ {
+					var header wire.BlockHeader
+					if e = header.Deserialize(r); E.Chk(e) {
+						return
+					}
+					blockHash := header.BlockHash()
+					switch {
+					case height == 0 && !blockHash.IsEqual(params.GenesisHash):
+						return errors.New("utxo snapshot does not start at the genesis block")
+					case height > 0 && !header.PrevBlock.IsEqual(&prevHash):
+						return fmt.Errorf("utxo snapshot header at height %d does not connect to its parent", height)
+					case checkpoints[height] != nil && !checkpoints[height].IsEqual(&blockHash):
+						return fmt.Errorf("utxo snapshot header at height %d does not match the checkpoint", height)
+					}
+					if height > 0 {
+						powLimit := fork.GetMinDiff(fork.GetAlgoName(headerAlgo(&header, height), height), height)
+						if e = checkProofOfWork(&header, powLimit, BFNone, height); e != nil {
+							return fmt.Errorf("utxo snapshot header at height %d is invalid: %v", height, e)
+						}
+						if e = difficulty.Check(&header); e != nil {
+							return fmt.Errorf("utxo snapshot header at height %d is invalid: %v", height, e)
+						}
+						if e = difficulty.Add(&header); E.Chk(e) {
+							return
+						}
+					}
+					status := statusValid
+					if height == 0 || height == h.Height {
+						status |= statusDataStored
+					}
+					w := bytes.NewBuffer(make([]byte, 0, blockHdrSize+1))
+					if e = header.Serialize(w); E.Chk(e) {
+						return
+					}
+					if e = w.WriteByte(byte(status)); E.Chk(e) {
+						return
+					}
+					if e = blockIndexBucket.Put(blockIndexKey(&blockHash, uint32(height)), w.Bytes()); E.Chk(e) {
+						return
+					}
+					if e = dbPutBlockIndex(dbTx, &blockHash, height); E.Chk(e) {
+						return
+					}
+					workSum.Add(workSum, CalcWork(header.Bits, height, header.Version))
+					prevHash = blockHash
+				}
+				return
+			},
+		); E.Chk(e) {
+			return
+		}
+	}
+	if !prevHash.IsEqual(&h.Hash) {
+		return fmt.Errorf("utxo snapshot headers end at %s instead of %s", prevHash, h.Hash)
+	}
+	// store the genesis block and the snapshot block, which is needed to load the chain state
+	var blockBytes []byte
+	if blockBytes, e = wire.ReadVarBytes(r, 0, wire.MaxBlockPayload, "snapshot block"); E.Chk(e) {
+		return
+	}
+	var tip *block.Block
+	if tip, e = block.NewFromBytes(blockBytes); E.Chk(e) {
+		return
+	}
+	if !tip.Hash().IsEqual(&h.Hash) {
+		return fmt.Errorf("utxo snapshot block is %s instead of %s", tip.Hash(), h.Hash)
+	}
+	merkleRoot := BuildMerkleTreeStore(tip.Transactions(), false).GetRoot()
+	if !tip.WireBlock().Header.MerkleRoot.IsEqual(merkleRoot) {
+		return fmt.Errorf("utxo snapshot block has an invalid merkle root %s", merkleRoot)
+	}
+	if e = db.Update(
+		func(dbTx database.Tx) (e error) {
+			if e = dbStoreBlock(dbTx, block.NewBlock(params.GenesisBlock)); E.Chk(e) {
+				return
+			}
+			return dbStoreBlock(dbTx, tip)
+		},
+	); E.Chk(e) {
+		return
+	}
+	// the statistics of the set are computed as it is read, and stored with it so they don't have to be computed again
+	stats := &utxoStats{setHash: muhash.New()}
+	var prevKey []byte
+	for n := uint64(0); n < h.NumUtxos; {
+		if e = db.Update(
+			func(dbTx database.Tx) (e error) {
+				utxoBucket := dbTx.Metadata().Bucket(utxoSetBucketName)
+				for end := n + snapshotBatchSize; n < h.NumUtxos && n < end; n++ {
+					var key, value []byte
+					if key, e = wire.ReadVarBytes(r, 0, maxSnapshotRecord, "utxo key"); E.Chk(e) {
+						return
+					}
+					if value, e = wire.ReadVarBytes(r, 0, maxSnapshotRecord, "utxo entry"); E.Chk(e) {
+						return
+					}
+					// the entries are in the order of their keys, so each is stored once and the outputs of a
+					// transaction are next to each other
+					if prevKey != nil && bytes.Compare(key, prevKey) <= 0 {
+						return fmt.Errorf("utxo snapshot entry %d is out of order", n)
+					}
+					var outpoint wire.OutPoint
+					if outpoint, e = decodeOutpointKey(key); e != nil {
+						return fmt.Errorf("utxo snapshot entry %d has an invalid key: %v", n, e)
+					}
+					var entry *UtxoEntry
+					if entry, e = deserializeUtxoEntry(value); e != nil {
+						return fmt.Errorf("utxo snapshot entry %d is invalid: %v", n, e)
+					}
+					if prevKey == nil || !bytes.Equal(key[:chainhash.HashSize], prevKey[:chainhash.HashSize]) {
+						stats.transactions++
+					}
+					stats.add(outpoint, entry, key, value)
+					if e = utxoBucket.Put(key, value); E.Chk(e) {
+						return
+					}
+					prevKey = key
+				}
+				return
+			},
+		); E.Chk(e) {
+			return
+		}
+	}
+	if setHash := chainhash.Hash(stats.setHash.Finalize()); !setHash.IsEqual(expected) {
+		return fmt.Errorf("utxo snapshot set hash is %s instead of the trusted %s", setHash, expected)
+	}
+	// the best state is stored last, so an interrupted import leaves a database the chain refuses to load
+	return db.Update(
+		func(dbTx database.Tx) (e error) {
+			if e = dbPutUtxoStats(dbTx, stats); E.Chk(e) {
+				return
+			}
+			if e = dbPutHistoryReplay(dbTx, 0, &utxoStats{setHash: muhash.New()}); E.Chk(e) {
+				return
+			}
+			if e = dbPutBestState(
+				dbTx, &BestState{Hash: h.Hash, Height: h.Height, TotalTxns: h.TotalTxns}, workSum,
+			); E.Chk(e) {
+				return
+			}
+			return dbPutSnapshotState(dbTx, h.Height, expected, false)
+		},
+	)
+}
+
+// dbFetchSnapshotHeight uses an existing database transaction to retrieve the height of the utxo snapshot the chain
+// state was imported from, which is 0 when it was not or the history below it has been verified.
+func dbFetchSnapshotHeight(dbTx database.Tx) int32 {
+	height, _, _ := dbFetchSnapshotState(dbTx)
+	return height
+}
+
+// dbFetchSnapshotState uses an existing database transaction to retrieve the height and the trusted set hash of the
+// utxo snapshot the chain state was imported from, and whether the utxo set built from the blocks below it did not
+// match the snapshot.
+func dbFetchSnapshotState(dbTx database.Tx) (height int32, setHash chainhash.Hash, failed bool) {
+	serialized := dbTx.Metadata().Get(snapshotHeightKeyName)
+	if len(serialized) < 4+chainhash.HashSize+1 {
+		return
+	}
+	height = int32(byteOrder.Uint32(serialized))
+	copy(setHash[:], serialized[4:])
+	failed = serialized[4+chainhash.HashSize] != 0
+	return
+}
+
+// dbPutSnapshotState uses an existing database transaction to store the height and the trusted set hash of the utxo
+// snapshot the chain state was imported from, and whether the utxo set built from the blocks below it did not match the
+// snapshot.
+func dbPutSnapshotState(dbTx database.Tx, height int32, setHash *chainhash.Hash, failed bool) (e error) {
+	var serialized [4 + chainhash.HashSize + 1]byte
+	byteOrder.PutUint32(serialized[:], uint32(height))
+	copy(serialized[4:], setHash[:])
+	if failed {
+		serialized[4+chainhash.HashSize] = 1
+	}
+	return dbTx.Metadata().Put(snapshotHeightKeyName, serialized[:])
+}
+
+// dbFetchHistoryReplay uses an existing database transaction to retrieve the height of the last block below the utxo
+// snapshot that was connected to the utxo set built from the genesis block, and the statistics of that set.
+func dbFetchHistoryReplay(dbTx database.Tx) (height int32, stats *utxoStats, e error) {
+	serialized := dbTx.Metadata().Get(historyReplayKeyName)
+	if len(serialized) < 4 {
+		return 0, nil, errDeserialize("history replay state is missing")
+	}
+	height = int32(byteOrder.Uint32(serialized))
+	stats, e = deserializeUtxoStats(serialized[4:])
+	return
+}
+
+// dbPutHistoryReplay uses an existing database transaction to store the height of the last block below the utxo
+// snapshot that was connected to the utxo set built from the genesis block, and the statistics of that set.
+func dbPutHistoryReplay(dbTx database.Tx, height int32, stats *utxoStats) (e error) {
+	serialized := make([]byte, 4, 4+utxoStatsSize)
+	byteOrder.PutUint32(serialized, uint32(height))
+	return dbTx.Metadata().Put(historyReplayKeyName, append(serialized, stats.serialize()...))
+}
+
+// loadSnapshotState loads the state of the utxo snapshot the chain state was imported from and finds the first block
+// below it that is missing. It must be called after the best chain has been loaded.
+func (b *BlockChain) loadSnapshotState(dbTx database.Tx) (e error) {
+	b.snapshotHeight, b.snapshotSetHash, b.snapshotFailed = dbFetchSnapshotState(dbTx)
+	b.historyHeight = 0
+	if b.snapshotHeight == 0 {
+		return
+	}
+	if b.replayHeight, b.replayStats, e = dbFetchHistoryReplay(dbTx); E.Chk(e) {
+		return
+	}
+	b.advanceHistoryHeight()
+	return
+}
+
+// advanceHistoryHeight moves the history height to the first block of the main chain below the snapshot whose data is
+// missing.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) advanceHistoryHeight() {
+	for ; b.historyHeight < b.snapshotHeight; b.historyHeight++ {
+		node := b.BestChain.NodeByHeight(b.historyHeight)
+		if node == nil || !node.status.HaveData() {
+			return
+		}
+	}
+}
+
+// HistoryMissing returns whether the chain state was imported from a utxo snapshot that has not been verified yet by
+// downloading the blocks below it and connecting them, so the node can't serve the full history of the chain to peers
+// or build indexes that need the data of all blocks.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) HistoryMissing() bool {
+	b.ChainLock.RLock()
+	defer b.ChainLock.RUnlock()
+	return b.snapshotHeight > 0
+}
+
+// DBHistoryMissing returns whether the chain state in a database was imported from a utxo snapshot that has not been
+// verified yet, for use before the chain is created.
+func DBHistoryMissing(db database.DB) (missing bool) {
+	if e := db.View(
+		func(dbTx database.Tx) (e error) {
+			missing = dbFetchSnapshotHeight(dbTx) > 0
+			return
+		},
+	); E.Chk(e) {
+	}
+	return
+}
+
+// MissingHistoryBlocks returns the hashes of up to max of the first blocks of the main chain below the utxo snapshot
+// whose data is missing. It returns nothing when the chain prunes old blocks, as the history would be pruned again.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) MissingHistoryBlocks(max int) (hashes []chainhash.Hash) {
+	if b.pruneTarget > 0 {
+		return
+	}
+	b.ChainLock.RLock()
+	defer b.ChainLock.RUnlock()
+	for height := b.historyHeight; height < b.snapshotHeight && len(hashes) < max; height++ {
+		node := b.BestChain.NodeByHeight(height)
+		if node != nil && !node.status.HaveData() {
+			hashes = append(hashes, node.hash)
+		}
+	}
+	return
+}
+
+// StoreHistoryBlock stores a block of the main chain below the utxo snapshot the chain state was imported from. The
+// block has to match its header, which is already known to be valid, so only its transactions are checked to match
+// the merkle root here. The block is fully validated when the history replayer connects it. It returns false if the
+// block is not a missing block below the snapshot, in which case it should be processed as usual.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) StoreHistoryBlock(blk *block.Block) (ok bool, e error) {
+	b.ChainLock.Lock()
+	defer b.ChainLock.Unlock()
+	if b.snapshotHeight == 0 {
+		return
+	}
+	node := b.Index.LookupNode(blk.Hash())
+	if node == nil || node.height == 0 || node.height >= b.snapshotHeight || node.status.HaveData() ||
+		!b.BestChain.Contains(node) {
+		return
+	}
+	ok = true
+	algo := blk.WireBlock().Header.Version
+	powLimit := fork.GetMinDiff(fork.GetAlgoName(algo, node.height), node.height)
+	if e = checkBlockSanity(
+		blk, powLimit, b.timeSource, BFNoPoWCheck, true, node.height, node.parent.Header().Timestamp,
+	); E.Chk(e) {
+		return
+	}
+	if e = b.db.Update(
+		func(dbTx database.Tx) (e error) {
+			return dbStoreBlock(dbTx, blk)
+		},
+	); E.Chk(e) {
+		return
+	}
+	b.Index.SetStatusFlags(node, statusDataStored)
+	if e = b.Index.flushToDB(); E.Chk(e) {
+		return
+	}
+	b.advanceHistoryHeight()
+	if b.historyHeight == b.snapshotHeight {
+		I.F("downloaded all %d blocks below the utxo snapshot", b.snapshotHeight)
+	}
+	// the replayer connects the blocks in the order of their heights, so it only has work if this was the next one
+	select {
+	case b.historySignal <- struct{}{}:
+	default:
+	}
+	return
+}
+
+// historyReplayer connects the blocks below the utxo snapshot to a utxo set built up from the genesis block as they
+// are downloaded, until the set reaches the snapshot block and has been compared with it or interrupt is closed.
+//
+// This MUST be run as a goroutine.
+func (b *BlockChain) historyReplayer(interrupt <-chan struct{}) {
+	for !b.replayHistory(interrupt) {
+		select {
+		case <-b.historySignal:
+		case <-interrupt:
+			return
+		}
+	}
+}
+
+// replayHistory connects the downloaded blocks after the last one that was replayed. It returns true when there is
+// nothing left to do, because the snapshot was verified or failed to verify, or interrupt was closed.
+func (b *BlockChain) replayHistory(interrupt <-chan struct{}) (done bool) {
+	b.replayMx.Lock()
+	defer b.replayMx.Unlock()
+	for {
+		select {
+		case <-interrupt:
+			return true
+		default:
+		}
+		b.ChainLock.RLock()
+		if b.snapshotHeight == 0 || b.snapshotFailed {
+			b.ChainLock.RUnlock()
+			return true
+		}
+		snapshotHeight := b.snapshotHeight
+		node := b.BestChain.NodeByHeight(b.replayHeight + 1)
+		if node == nil || !node.status.HaveData() {
+			b.ChainLock.RUnlock()
+			return false
+		}
+		e := b.replayBlock(node)
+		b.ChainLock.RUnlock()
+		if e != nil {
+			if _, ok := e.(RuleError); ok {
+				b.failSnapshot(fmt.Sprintf("block %s at height %d is invalid: %v", node.hash, node.height, e))
+				return true
+			}
+			E.Ln("unable to replay the blocks below the utxo snapshot:", e)
+			return false
+		}
+		if node.height == snapshotHeight {
+			b.verifySnapshot()
+			return true
+		}
+	}
+}
+
+// replayBlock connects a block below the utxo snapshot to the utxo set built up from the genesis block.
+//
+// This function MUST be called with the chain state lock held (for reads) and the replay lock held.
+func (b *BlockChain) replayBlock(node *BlockNode) (e error) {
+	var blk *block.Block
+	if e = b.db.View(
+		func(dbTx database.Tx) (e error) {
+			blk, e = dbFetchBlockByNode(dbTx, node)
+			return
+		},
+	); E.Chk(e) {
+		return
+	}
+	view := NewUtxoViewpoint()
+	view.bucket = historyUtxoSetBucketName
+	view.SetBestHash(&node.parent.hash)
+	if e = b.checkConnectBlock(node, blk, view, nil); e != nil {
+		return
+	}
+	stats := b.replayStats.clone()
+	if e = b.db.Update(
+		func(dbTx database.Tx) (e error) {
+			if e = dbPutUtxoView(dbTx, view, stats); E.Chk(e) {
+				return
+			}
+			return dbPutHistoryReplay(dbTx, node.height, stats)
+		},
+	); E.Chk(e) {
+		return
+	}
+	b.replayHeight, b.replayStats = node.height, stats
+	return
+}
+
+// verifySnapshot compares the set hash of the utxo set built up from the genesis block with the trusted one of the
+// snapshot once it has reached the snapshot block, and stops treating the chain state as imported if they match.
+//
+// This function MUST be called with the replay lock held.
+func (b *BlockChain) verifySnapshot() {
+	setHash := chainhash.Hash(b.replayStats.setHash.Finalize())
+	if !setHash.IsEqual(&b.snapshotSetHash) {
+		b.failSnapshot(
+			fmt.Sprintf("the set hash of the utxo set built from them is %s instead of %s", setHash, b.snapshotSetHash),
+		)
+		return
+	}
+	b.ChainLock.Lock()
+	defer b.ChainLock.Unlock()
+	if e := b.db.Update(
+		func(dbTx database.Tx) (e error) {
+			if e = dbTx.Metadata().Delete(snapshotHeightKeyName); E.Chk(e) {
+				return
+			}
+			if e = dbTx.Metadata().Delete(historyReplayKeyName); E.Chk(e) {
+				return
+			}
+			return dbTx.Metadata().DeleteBucket(historyUtxoSetBucketName)
+		},
+	); E.Chk(e) {
+		return
+	}
+	I.F(
+		"the utxo set built from the %d blocks below the utxo snapshot matches it, restart the node to build the"+
+			" indexes that need them", b.snapshotHeight,
+	)
+	b.snapshotHeight = 0
+	b.replayStats = nil
+}
+
+// failSnapshot records that the blocks below the utxo snapshot do not lead to it, so the chain state imported from it
+// can't be trusted and the chain refuses to load it again.
+func (b *BlockChain) failSnapshot(reason string) {
+	b.ChainLock.Lock()
+	defer b.ChainLock.Unlock()
+	b.snapshotFailed = true
+	if e := b.db.Update(
+		func(dbTx database.Tx) (e error) {
+			return dbPutSnapshotState(dbTx, b.snapshotHeight, &b.snapshotSetHash, true)
+		},
+	); E.Chk(e) {
+	}
+	E.F(
+		"THE UTXO SNAPSHOT THE CHAIN STATE WAS IMPORTED FROM IS NOT VALID: of the blocks below it %s. Stop the node"+
+			" and reset the chain to download and validate it from the genesis block", reason,
+	)
+}
@@ -0,0 +1,337 @@
+package blockchain
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/p9c/pod/pkg/bits"
+	"github.com/p9c/pod/pkg/block"
+	"github.com/p9c/pod/pkg/chaincfg"
+	"github.com/p9c/pod/pkg/chainhash"
+	"github.com/p9c/pod/pkg/database"
+	"github.com/p9c/pod/pkg/fork"
+	"github.com/p9c/pod/pkg/muhash"
+	"github.com/p9c/pod/pkg/wire"
+)
+
+// snapshotRecords are the keys and values of the entries of a utxo set in the order of their keys
+type snapshotRecords [][2][]byte
+
+// mineHeader finds a nonce for which the header meets the target of its bits
+func mineHeader(t *testing.T, header *wire.BlockHeader, height int32) {
+	target := bits.CompactToBig(header.Bits)
+	for header.Nonce = 0; ; header.Nonce++ {
+		hash := header.BlockHashWithAlgos(height)
+		if HashToBig(&hash).Cmp(target) <= 0 {
+			return
+		}
+		if header.Nonce == ^uint32(0) {
+			t.Fatal("no nonce meets the target")
+		}
+	}
+}
+
+// snapshotBlock returns a block on top of prev whose coinbase pays the subsidy at the height, with the bits the
+// difficulty adjustment requires, or with them off by one if wrongBits is set, and a valid proof of work.
+func snapshotBlock(
+	t *testing.T, params *chaincfg.Params, hd *HeaderDifficulty, prev *wire.Block, height int32, wrongBits bool,
+) *wire.Block {
+	return branchBlock(t, params, hd, prev, height, nil, wrongBits)
+}
+
+// branchBlock returns a block like snapshotBlock does, with the tag added to the signature script of its coinbase so
+// that blocks on different branches at the same height differ.
+func branchBlock(
+	t *testing.T, params *chaincfg.Params, hd *HeaderDifficulty, prev *wire.Block, height int32, tag []byte,
+	wrongBits bool,
+) *wire.Block {
+	coinbase := wire.NewMsgTx(1)
+	coinbase.AddTxIn(
+		&wire.TxIn{
+			PreviousOutPoint: *wire.NewOutPoint(&chainhash.Hash{}, wire.MaxPrevOutIndex),
+			SignatureScript:  append([]byte{1, byte(height)}, tag...),
+			Sequence:         wire.MaxTxInSequenceNum,
+		},
+	)
+	coinbase.AddTxOut(wire.NewTxOut(CalcBlockSubsidy(height, params, 2), []byte{0x51}))
+	blk := &wire.Block{
+		Header: wire.BlockHeader{
+			Version:   2,
+			PrevBlock: prev.BlockHash(),
+			Timestamp: prev.Header.Timestamp.Add(time.Minute),
+		},
+		Transactions: []*wire.MsgTx{coinbase},
+	}
+	blk.Header.MerkleRoot = *BuildMerkleTreeStore(block.NewBlock(blk).Transactions(), false).GetRoot()
+	var e error
+	prevNode := hd.nodes[blk.Header.PrevBlock]
+	algoName := fork.GetAlgoName(blk.Header.Version, height)
+	if blk.Header.Bits, e = hd.chain.CalcNextRequiredDifficultyHalcyon(prevNode, algoName, false); e != nil {
+		t.Fatal(e)
+	}
+	if wrongBits {
+		blk.Header.Bits--
+	}
+	mineHeader(t, &blk.Header, height)
+	return blk
+}
+
+// snapshotOf returns the header and the entries of the snapshot of the utxo set made of the coinbase outputs of the
+// blocks after the genesis block, and its statistics.
+func snapshotOf(t *testing.T, params *chaincfg.Params, blocks []*wire.Block) (
+	h *SnapshotHeader, records snapshotRecords, stats *utxoStats,
+) {
+	tip := blocks[len(blocks)-1]
+	h = &SnapshotHeader{
+		Net:       params.Net,
+		Hash:      tip.BlockHash(),
+		Height:    int32(len(blocks) - 1),
+		TotalTxns: uint64(len(blocks)),
+	}
+	stats = &utxoStats{setHash: muhash.New()}
+	for height := 1; height < len(blocks); height++ {
+		coinbase := blocks[height].Transactions[0]
+		outpoint := wire.OutPoint{Hash: coinbase.TxHash()}
+		entry := &UtxoEntry{
+			amount:      coinbase.TxOut[0].Value,
+			pkScript:    coinbase.TxOut[0].PkScript,
+			blockHeight: int32(height),
+			packedFlags: tfCoinBase,
+		}
+		value, e := serializeUtxoEntry(entry)
+		if e != nil {
+			t.Fatal(e)
+		}
+		key := *outpointKey(outpoint)
+		records = append(records, [2][]byte{key, value})
+		stats.transactions++
+		stats.add(outpoint, entry, key, value)
+	}
+	sort.Slice(records, func(i, j int) bool { return bytes.Compare(records[i][0], records[j][0]) < 0 })
+	h.NumUtxos = stats.outputs
+	h.SetHash = stats.setHash.Finalize()
+	return
+}
+
+// serializeSnapshot writes a snapshot file with the given header, block headers, snapshot block and utxos
+func serializeSnapshot(
+	t *testing.T, h *SnapshotHeader, headers []wire.BlockHeader, tip *wire.Block, records snapshotRecords,
+) []byte {
+	var snapshot bytes.Buffer
+	if e := h.Serialize(&snapshot); e != nil {
+		t.Fatal(e)
+	}
+	for i := range headers {
+		if e := headers[i].Serialize(&snapshot); e != nil {
+			t.Fatal(e)
+		}
+	}
+	var tipBytes bytes.Buffer
+	if e := tip.Serialize(&tipBytes); e != nil {
+		t.Fatal(e)
+	}
+	if e := wire.WriteVarBytes(&snapshot, 0, tipBytes.Bytes()); e != nil {
+		t.Fatal(e)
+	}
+	for i := range records {
+		if e := writeSnapshotRecord(&snapshot, records[i][0], records[i][1]); e != nil {
+			t.Fatal(e)
+		}
+	}
+	return snapshot.Bytes()
+}
+
+// waitForSnapshot waits until the history below the utxo snapshot of the chain has been verified or has failed to
+// verify, and returns whether it failed.
+func waitForSnapshot(t *testing.T, chain *BlockChain) (failed bool) {
+	timeout := time.After(time.Second * 10)
+	for {
+		chain.ChainLock.RLock()
+		verified, failed := chain.snapshotHeight == 0, chain.snapshotFailed
+		chain.ChainLock.RUnlock()
+		if verified || failed {
+			return failed
+		}
+		select {
+		case <-timeout:
+			t.Fatal("the history below the utxo snapshot was not replayed")
+		case <-time.After(time.Millisecond * 10):
+		}
+	}
+}
+
+// TestUtxoSnapshot imports a snapshot of a short chain, checks that the chain loads from it with the block below the
+// snapshot missing, that exporting it again yields the same snapshot, and that storing the missing block verifies the
+// snapshot. Snapshots without a trusted set hash or with headers that don't have the required proof of work or
+// difficulty are rejected, and one whose set hash the history does not lead to fails once the history is replayed.
+func TestUtxoSnapshot(t *testing.T) {
+	params := &chaincfg.MainNetParams
+	hd := NewHeaderDifficulty(params)
+	blocks := []*wire.Block{params.GenesisBlock}
+	for height := int32(1); height <= 2; height++ {
+		blk := snapshotBlock(t, params, hd, blocks[height-1], height, false)
+		if e := hd.Add(&blk.Header); e != nil {
+			t.Fatal(e)
+		}
+		blocks = append(blocks, blk)
+	}
+	headers := make([]wire.BlockHeader, len(blocks))
+	for i := range blocks {
+		headers[i] = blocks[i].Header
+	}
+	tip := blocks[len(blocks)-1]
+	h, records, _ := snapshotOf(t, params, blocks)
+	snapshot := serializeSnapshot(t, h, headers, tip, records)
+	dir, e := ioutil.TempDir("", "snapshottest")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+	// a snapshot whose utxos don't match the set hash must be rejected
+	corrupt := append(append([]byte{}, snapshot[:len(snapshot)-1]...), snapshot[len(snapshot)-1]^1)
+	if e = importSnapshot(filepath.Join(dir, "corrupt"), params, &h.SetHash, corrupt); e == nil {
+		t.Fatal("imported a snapshot with a wrong set hash")
+	}
+	// the set hash of the snapshot proves nothing by itself, it has to match a trusted one
+	if e = importSnapshot(filepath.Join(dir, "untrusted"), params, nil, snapshot); e == nil {
+		t.Fatal("imported a snapshot without a trusted set hash")
+	}
+	other := chainhash.HashH(h.SetHash[:])
+	if e = importSnapshot(filepath.Join(dir, "mismatch"), params, &other, snapshot); e == nil {
+		t.Fatal("imported a snapshot with another set hash than the trusted one")
+	}
+	// the headers must have a valid proof of work with the difficulty of their algorithm
+	badPoW := append([]wire.BlockHeader{}, headers...)
+	badPoW[1].Nonce++
+	if e = importSnapshot(
+		filepath.Join(dir, "pow"), params, &h.SetHash, serializeSnapshot(t, h, badPoW, tip, records),
+	); e == nil {
+		t.Fatal("imported a snapshot with a header without proof of work")
+	}
+	wrongDifficulty := append([]wire.BlockHeader{}, headers...)
+	wrongDifficulty[1] = snapshotBlock(t, params, NewHeaderDifficulty(params), blocks[0], 1, true).Header
+	if e = importSnapshot(
+		filepath.Join(dir, "difficulty"), params, &h.SetHash, serializeSnapshot(t, h, wrongDifficulty, tip, records),
+	); e == nil {
+		t.Fatal("imported a snapshot with a header with the wrong difficulty")
+	}
+	db, e := database.Create(testDbType, filepath.Join(dir, "db"), params.Net)
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer db.Close()
+	r := bytes.NewReader(snapshot)
+	read, e := ReadSnapshotHeader(r)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !reflect.DeepEqual(read, h) {
+		t.Fatalf("read snapshot header %+v, expected %+v", read, h)
+	}
+	if e = ImportUtxoSnapshot(db, params, read, &h.SetHash, r); e != nil {
+		t.Fatal(e)
+	}
+	interrupt := make(chan struct{})
+	defer close(interrupt)
+	chain, e := New(&Config{DB: db, ChainParams: params, TimeSource: NewMedianTime(), Interrupt: interrupt})
+	if e != nil {
+		t.Fatal(e)
+	}
+	if best := chain.BestSnapshot(); best.Height != h.Height || best.Hash != h.Hash || best.TotalTxns != h.TotalTxns {
+		t.Fatalf("chain tip is %s (height %d) after importing the snapshot", best.Hash, best.Height)
+	}
+	if stats := chain.UtxoStats(); stats.SetHash != h.SetHash || stats.Outputs != h.NumUtxos {
+		t.Fatalf("utxo set has %d outputs with set hash %s after importing the snapshot", stats.Outputs, stats.SetHash)
+	}
+	missing := chain.MissingHistoryBlocks(10)
+	if !chain.HistoryMissing() || len(missing) != 1 || missing[0] != blocks[1].BlockHash() {
+		t.Fatalf("missing history blocks are %v, expected block 1", missing)
+	}
+	var exported bytes.Buffer
+	if _, e = chain.ExportUtxoSnapshot(&exported); e != nil {
+		t.Fatal(e)
+	}
+	if !bytes.Equal(exported.Bytes(), snapshot) {
+		t.Fatal("exported snapshot differs from the imported one")
+	}
+	if ok, e := chain.StoreHistoryBlock(block.NewBlock(blocks[2])); ok || e != nil {
+		t.Fatalf("stored block above the snapshot as history: %v %v", ok, e)
+	}
+	if ok, e := chain.StoreHistoryBlock(block.NewBlock(blocks[1])); !ok || e != nil {
+		t.Fatalf("failed to store missing block: %v %v", ok, e)
+	}
+	if waitForSnapshot(t, chain) {
+		t.Fatal("the history below the snapshot does not lead to it")
+	}
+	if chain.HistoryMissing() || len(chain.MissingHistoryBlocks(10)) != 0 || DBHistoryMissing(db) {
+		t.Fatal("history still missing after it was verified")
+	}
+	// a snapshot that a trusted set hash vouches for but that the history does not lead to is not trusted once the
+	// history has been replayed, and the chain refuses to load it again
+	bogus := append(snapshotRecords{}, records...)
+	bogus[0] = [2][]byte{bogus[0][0], append([]byte{}, bogus[0][1]...)}
+	bogus[0][1][len(bogus[0][1])-1] ^= 1
+	bogusHeader := *h
+	bogusStats := &utxoStats{setHash: muhash.New()}
+	for i := range bogus {
+		outpoint, e := decodeOutpointKey(bogus[i][0])
+		if e != nil {
+			t.Fatal(e)
+		}
+		entry, e := deserializeUtxoEntry(bogus[i][1])
+		if e != nil {
+			t.Fatal(e)
+		}
+		bogusStats.add(outpoint, entry, bogus[i][0], bogus[i][1])
+	}
+	bogusHeader.SetHash = bogusStats.setHash.Finalize()
+	bogusDB, e := database.Create(testDbType, filepath.Join(dir, "bogus"), params.Net)
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer bogusDB.Close()
+	r = bytes.NewReader(serializeSnapshot(t, &bogusHeader, headers, tip, bogus))
+	if _, e = ReadSnapshotHeader(r); e != nil {
+		t.Fatal(e)
+	}
+	if e = ImportUtxoSnapshot(bogusDB, params, &bogusHeader, &bogusHeader.SetHash, r); e != nil {
+		t.Fatal(e)
+	}
+	config := &Config{DB: bogusDB, ChainParams: params, TimeSource: NewMedianTime(), Interrupt: interrupt}
+	if chain, e = New(config); e != nil {
+		t.Fatal(e)
+	}
+	if ok, e := chain.StoreHistoryBlock(block.NewBlock(blocks[1])); !ok || e != nil {
+		t.Fatalf("failed to store missing block: %v %v", ok, e)
+	}
+	if !waitForSnapshot(t, chain) {
+		t.Fatal("the history below a bogus snapshot verified it")
+	}
+	if !chain.HistoryMissing() {
+		t.Fatal("a bogus snapshot is trusted after replaying the history")
+	}
+	if _, e = New(config); e == nil {
+		t.Fatal("the chain loaded a snapshot that failed to verify")
+	}
+}
+
+// importSnapshot imports a serialized snapshot into a new database at dbPath
+func importSnapshot(dbPath string, params *chaincfg.Params, expected *chainhash.Hash, snapshot []byte) (e error) {
+	var db database.DB
+	if db, e = database.Create(testDbType, dbPath, params.Net); e != nil {
+		return
+	}
+	defer db.Close()
+	r := bytes.NewReader(snapshot)
+	var h *SnapshotHeader
+	if h, e = ReadSnapshotHeader(r); e != nil {
+		return
+	}
+	return ImportUtxoSnapshot(db, params, h, expected, r)
+}
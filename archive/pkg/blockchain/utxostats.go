@@ -0,0 +1,234 @@
+package blockchain
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/p9c/pod/pkg/chainhash"
+	"github.com/p9c/pod/pkg/database"
+	"github.com/p9c/pod/pkg/muhash"
+	"github.com/p9c/pod/pkg/wire"
+)
+
+// utxoStatsSize is the size of the serialized utxo set statistics
+const utxoStatsSize = 5*8 + muhash.SerializedSize
+
+// UtxoStats are statistics of the utxo set at the best block, as returned by the gettxoutsetinfo RPC.
+type UtxoStats struct {
+	Hash   chainhash.Hash
+	Height int32
+	// Transactions is the number of transactions with unspent outputs
+	Transactions uint64
+	// Outputs is the number of unspent outputs
+	Outputs uint64
+	// BogoSize is the size of the set estimated the way bitcoind does, which doesn't depend on how it is stored
+	BogoSize uint64
+	// SerializedSize is the size of the keys and values of the utxo set in the database
+	SerializedSize uint64
+	// TotalAmount is the sum of the amounts of the unspent outputs
+	TotalAmount int64
+	// SetHash is the MuHash3072 of the unspent outputs serialized as bitcoind does it
+	SetHash chainhash.Hash
+}
+
+// utxoStats are the statistics of the utxo set that are updated as utxos are added and removed
+type utxoStats struct {
+	transactions   uint64
+	outputs        uint64
+	bogoSize       uint64
+	serializedSize uint64
+	totalAmount    int64
+	setHash        *muhash.MuHash
+}
+
+// clone returns a copy of the statistics
+func (s *utxoStats) clone() *utxoStats {
+	c := *s
+	c.setHash = s.setHash.Clone()
+	return &c
+}
+
+// add counts a utxo stored under key with the serialized value
+func (s *utxoStats) add(outpoint wire.OutPoint, entry *UtxoEntry, key, value []byte) {
+	s.outputs++
+	s.bogoSize += utxoBogoSize(entry)
+	s.serializedSize += uint64(len(key) + len(value))
+	s.totalAmount += entry.Amount()
+	s.setHash.Add(serializeUtxoForHash(outpoint, entry))
+}
+
+// remove uncounts a utxo stored under key with the serialized value
+func (s *utxoStats) remove(outpoint wire.OutPoint, entry *UtxoEntry, key, value []byte) {
+	s.outputs--
+	s.bogoSize -= utxoBogoSize(entry)
+	s.serializedSize -= uint64(len(key) + len(value))
+	s.totalAmount -= entry.Amount()
+	s.setHash.Remove(serializeUtxoForHash(outpoint, entry))
+}
+
+// serialize returns the statistics serialized for storage in the database
+func (s *utxoStats) serialize() []byte {
+	b := make([]byte, 5*8, utxoStatsSize)
+	byteOrder.PutUint64(b[0:], s.transactions)
+	byteOrder.PutUint64(b[8:], s.outputs)
+	byteOrder.PutUint64(b[16:], s.bogoSize)
+	byteOrder.PutUint64(b[24:], s.serializedSize)
+	byteOrder.PutUint64(b[32:], uint64(s.totalAmount))
+	return append(b, s.setHash.Serialize()...)
+}
+
+// deserializeUtxoStats decodes the statistics stored in the database
+func deserializeUtxoStats(b []byte) (s *utxoStats, e error) {
+	if len(b) != utxoStatsSize {
+		return nil, errors.New("serialized utxo set statistics have the wrong size")
+	}
+	s = &utxoStats{
+		transactions:   byteOrder.Uint64(b[0:]),
+		outputs:        byteOrder.Uint64(b[8:]),
+		bogoSize:       byteOrder.Uint64(b[16:]),
+		serializedSize: byteOrder.Uint64(b[24:]),
+		totalAmount:    int64(byteOrder.Uint64(b[32:])),
+	}
+	s.setHash, e = muhash.Deserialize(b[40:])
+	return
+}
+
+// utxoBogoSize returns the size of a utxo estimated the way bitcoind does
+func utxoBogoSize(entry *UtxoEntry) uint64 {
+	// txid, output index, height and coinbase flag, amount, script length
+	return 32 + 4 + 4 + 8 + 2 + uint64(len(entry.PkScript()))
+}
+
+// serializeUtxoForHash serializes a utxo the way bitcoind does to compute the MuHash of the utxo set: the outpoint,
+// the height shifted left by one with the coinbase flag in the lowest bit, and the output.
+func serializeUtxoForHash(outpoint wire.OutPoint, entry *UtxoEntry) []byte {
+	var buf bytes.Buffer
+	buf.Grow(chainhash.HashSize + 4 + 4 + 8 + 9 + len(entry.PkScript()))
+	buf.Write(outpoint.Hash[:])
+	var b [8]byte
+	byteOrder.PutUint32(b[:], outpoint.Index)
+	buf.Write(b[:4])
+	code := uint32(entry.BlockHeight()) << 1
+	if entry.IsCoinBase() {
+		code |= 1
+	}
+	byteOrder.PutUint32(b[:], code)
+	buf.Write(b[:4])
+	byteOrder.PutUint64(b[:], uint64(entry.Amount()))
+	buf.Write(b[:])
+	if e := wire.WriteVarBytes(&buf, 0, entry.PkScript()); E.Chk(e) {
+	}
+	return buf.Bytes()
+}
+
+// decodeOutpointKey returns the outpoint of a key in the utxo set bucket
+func decodeOutpointKey(key []byte) (outpoint wire.OutPoint, e error) {
+	if len(key) <= chainhash.HashSize {
+		return outpoint, errDeserialize("utxo key is too short")
+	}
+	copy(outpoint.Hash[:], key[:chainhash.HashSize])
+	index, _ := deserializeVLQ(key[chainhash.HashSize:])
+	outpoint.Index = uint32(index)
+	return
+}
+
+// dbFetchUtxoStats uses an existing database transaction to retrieve the statistics of the utxo set, which are nil if
+// they have not been stored yet.
+func dbFetchUtxoStats(dbTx database.Tx) (s *utxoStats, e error) {
+	serialized := dbTx.Metadata().Get(utxoStatsKeyName)
+	if serialized == nil {
+		return
+	}
+	return deserializeUtxoStats(serialized)
+}
+
+// dbPutUtxoStats uses an existing database transaction to store the statistics of the utxo set.
+func dbPutUtxoStats(dbTx database.Tx, s *utxoStats) (e error) {
+	return dbTx.Metadata().Put(utxoStatsKeyName, s.serialize())
+}
+
+// dbComputeUtxoStats uses an existing database transaction to compute the statistics of the utxo set from all of its
+// entries.
+func dbComputeUtxoStats(dbTx database.Tx) (s *utxoStats, e error) {
+	s = &utxoStats{setHash: muhash.New()}
+	var prevHash chainhash.Hash
+	e = dbTx.Metadata().Bucket(utxoSetBucketName).ForEach(
+		func(k, v []byte) (e error) {
+			var outpoint wire.OutPoint
+			if outpoint, e = decodeOutpointKey(k); E.Chk(e) {
+				return
+			}
+			var entry *UtxoEntry
+			if entry, e = deserializeUtxoEntry(v); E.Chk(e) {
+				return
+			}
+			// the keys start with the transaction hash, so the outputs of a transaction are next to each other
+			if s.outputs == 0 || outpoint.Hash != prevHash {
+				s.transactions++
+				prevHash = outpoint.Hash
+			}
+			s.add(outpoint, entry, k, v)
+			return
+		},
+	)
+	return
+}
+
+// haveTxOutputs returns whether the utxo set in the given bucket has an unspent output of the transaction with the
+// given hash.
+func haveTxOutputs(utxoBucket database.Bucket, hash *chainhash.Hash) (have bool, e error) {
+	var entry *UtxoEntry
+	entry, e = fetchUtxoEntryByHash(utxoBucket, hash)
+	return entry != nil, e
+}
+
+// initUtxoStats loads the statistics of the utxo set, computing and storing them first if the database doesn't have
+// them yet.
+func (b *BlockChain) initUtxoStats() (e error) {
+	if e = b.db.View(
+		func(dbTx database.Tx) (e error) {
+			b.utxoStats, e = dbFetchUtxoStats(dbTx)
+			return
+		},
+	); E.Chk(e) {
+		W.Ln("unable to load the utxo set statistics, computing them again:", e)
+		b.utxoStats = nil
+	}
+	if b.utxoStats != nil {
+		return
+	}
+	I.Ln("computing the statistics of the utxo set, this may take a while")
+	return b.db.Update(
+		func(dbTx database.Tx) (e error) {
+			var s *utxoStats
+			if s, e = dbComputeUtxoStats(dbTx); E.Chk(e) {
+				return
+			}
+			if e = dbPutUtxoStats(dbTx, s); E.Chk(e) {
+				return
+			}
+			b.utxoStats = s
+			return
+		},
+	)
+}
+
+// UtxoStats returns the statistics of the utxo set at the best block.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) UtxoStats() *UtxoStats {
+	b.ChainLock.RLock()
+	defer b.ChainLock.RUnlock()
+	best := b.BestSnapshot()
+	s := b.utxoStats.clone()
+	return &UtxoStats{
+		Hash:           best.Hash,
+		Height:         best.Height,
+		Transactions:   s.transactions,
+		Outputs:        s.outputs,
+		BogoSize:       s.bogoSize,
+		SerializedSize: s.serializedSize,
+		TotalAmount:    s.totalAmount,
+		SetHash:        s.setHash.Finalize(),
+	}
+}
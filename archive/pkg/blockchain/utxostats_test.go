@@ -0,0 +1,88 @@
+package blockchain
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/p9c/pod/pkg/chainhash"
+	"github.com/p9c/pod/pkg/database"
+	"github.com/p9c/pod/pkg/muhash"
+	"github.com/p9c/pod/pkg/wire"
+)
+
+// TestUtxoStats checks that the statistics updated as utxo views are written to the database match the ones computed
+// from the whole utxo set.
+func TestUtxoStats(t *testing.T) {
+	dir, e := ioutil.TempDir("", "utxostatstest")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+	db, e := database.Create(testDbType, filepath.Join(dir, "db"), wire.MainNet)
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer db.Close()
+	if e = db.Update(dbCreateChainBuckets); e != nil {
+		t.Fatal(e)
+	}
+	stats := &utxoStats{setHash: muhash.New()}
+	// apply writes a view with the given outputs added and spent, and compares the statistics with the computed ones
+	apply := func(add, spend []wire.OutPoint) {
+		view := NewUtxoViewpoint()
+		for i, outpoint := range add {
+			view.entries[outpoint] = &UtxoEntry{
+				amount:      int64(i+1) * 1000,
+				pkScript:    []byte{0x51, byte(i)},
+				blockHeight: int32(i),
+				packedFlags: tfModified,
+			}
+		}
+		for _, outpoint := range spend {
+			entry := &UtxoEntry{}
+			entry.Spend()
+			view.entries[outpoint] = entry
+		}
+		if e := db.Update(
+			func(dbTx database.Tx) (e error) {
+				if e = dbPutUtxoView(dbTx, view, stats); e != nil {
+					return
+				}
+				var computed *utxoStats
+				if computed, e = dbComputeUtxoStats(dbTx); e != nil {
+					return
+				}
+				// finalizing divides out the denominators, so equal sets serialize the same
+				computed.setHash.Finalize()
+				stats.setHash.Finalize()
+				if !bytes.Equal(computed.serialize(), stats.serialize()) {
+					t.Fatalf("updated statistics %+v differ from the computed %+v", stats, computed)
+				}
+				return
+			},
+		); e != nil {
+			t.Fatal(e)
+		}
+	}
+	tx1 := chainhash.HashH([]byte{1})
+	tx2 := chainhash.HashH([]byte{2})
+	apply([]wire.OutPoint{{Hash: tx1, Index: 0}, {Hash: tx1, Index: 1}, {Hash: tx2, Index: 0}}, nil)
+	if stats.transactions != 2 || stats.outputs != 3 || stats.totalAmount != 6000 {
+		t.Fatalf("unexpected statistics %+v", stats)
+	}
+	// spending one of two outputs of a transaction leaves it in the set, spending its only output removes it
+	apply(nil, []wire.OutPoint{{Hash: tx1, Index: 0}, {Hash: tx2, Index: 0}})
+	if stats.transactions != 1 || stats.outputs != 1 {
+		t.Fatalf("unexpected statistics %+v", stats)
+	}
+	apply(nil, []wire.OutPoint{{Hash: tx1, Index: 1}})
+	if stats.transactions != 0 || stats.outputs != 0 || stats.serializedSize != 0 || stats.totalAmount != 0 {
+		t.Fatalf("unexpected statistics of the empty set %+v", stats)
+	}
+	if stats.setHash.Finalize() != muhash.New().Finalize() {
+		t.Fatal("set hash of the empty set differs")
+	}
+}
@@ -102,6 +102,16 @@ func (entry *UtxoEntry) Clone() *UtxoEntry {
 type UtxoViewpoint struct {
 	entries  map[wire.OutPoint]*UtxoEntry
 	bestHash chainhash.Hash
+	// bucket is the name of the bucket of the utxo set the view is loaded from and stored to, the main one when nil
+	bucket []byte
+}
+
+// dbBucket returns the bucket of the utxo set of the view.
+func (view *UtxoViewpoint) dbBucket(dbTx database.Tx) database.Bucket {
+	if view.bucket != nil {
+		return dbTx.Metadata().Bucket(view.bucket)
+	}
+	return dbTx.Metadata().Bucket(utxoSetBucketName)
 }
 
 // BestHash returns the hash of the best block in the chain the view currently represents.
@@ -256,7 +266,7 @@ func (view *UtxoViewpoint) fetchEntryByHash(db database.DB, hash *chainhash.Hash
 	// referenced utxos are loaded into the view.
 	e = db.View(
 		func(dbTx database.Tx) (e error) {
-			entry, e = dbFetchUtxoEntryByHash(dbTx, hash)
+			entry, e = fetchUtxoEntryByHash(view.dbBucket(dbTx), hash)
 			return e
 		},
 	)
@@ -411,7 +421,7 @@ func (view *UtxoViewpoint) fetchUtxosMain(db database.DB, outpoints map[wire.Out
 	return db.View(
 		func(dbTx database.Tx) (e error) {
 			for outpoint := range outpoints {
-				entry, e := dbFetchUtxoEntry(dbTx, outpoint)
+				entry, e := fetchUtxoEntry(view.dbBucket(dbTx), outpoint)
 				if e != nil {
 					return e
 				}
@@ -294,6 +294,12 @@ func (b *BlockChain) checkConnectBlock(
 	if checkpoint != nil && node.height <= checkpoint.Height {
 		runScripts = false
 	}
+	// Neither are they run for the assume-valid block and its ancestors once it is in the chain of headers with the most
+	// work with two weeks of blocks on top of it, as the configuration vouches for them and the work on top proves they
+	// are in the chain the network builds on.
+	if runScripts && b.isAssumedValid(node) {
+		runScripts = false
+	}
 	// BlockC created after the BIP0016 activation time need to have the pay -to-script-hash checks enabled.
 	var scriptFlags txscript.ScriptFlags
 	if enforceBIP0016 {
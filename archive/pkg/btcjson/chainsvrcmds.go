@@ -98,6 +98,77 @@ func NewGetAddedNodeInfoCmd(dns bool, node *string) *GetAddedNodeInfoCmd {
 	}
 }
 
+// AddressRequest is the object of addresses, and optionally the range of block heights, that the address index queries
+// take, the same as the insight api does.
+type AddressRequest struct {
+	Addresses []string `json:"addresses"`
+	Start     int32    `json:"start,omitempty"`
+	End       int32    `json:"end,omitempty"`
+}
+
+// GetAddressBalanceCmd defines the getaddressbalance JSON-RPC command.
+type GetAddressBalanceCmd struct {
+	Request AddressRequest
+}
+
+// NewGetAddressBalanceCmd returns a new instance which can be used to issue a getaddressbalance JSON-RPC command.
+func NewGetAddressBalanceCmd(addresses []string) *GetAddressBalanceCmd {
+	return &GetAddressBalanceCmd{
+		Request: AddressRequest{Addresses: addresses},
+	}
+}
+
+// GetAddressDeltasCmd defines the getaddressdeltas JSON-RPC command.
+type GetAddressDeltasCmd struct {
+	Request AddressRequest
+}
+
+// NewGetAddressDeltasCmd returns a new instance which can be used to issue a getaddressdeltas JSON-RPC command. A start
+// and end of zero don't limit the range of block heights.
+func NewGetAddressDeltasCmd(addresses []string, start, end int32) *GetAddressDeltasCmd {
+	return &GetAddressDeltasCmd{
+		Request: AddressRequest{Addresses: addresses, Start: start, End: end},
+	}
+}
+
+// GetAddressTxIDsCmd defines the getaddresstxids JSON-RPC command.
+type GetAddressTxIDsCmd struct {
+	Request AddressRequest
+}
+
+// NewGetAddressTxIDsCmd returns a new instance which can be used to issue a getaddresstxids JSON-RPC command. A start
+// and end of zero don't limit the range of block heights.
+func NewGetAddressTxIDsCmd(addresses []string, start, end int32) *GetAddressTxIDsCmd {
+	return &GetAddressTxIDsCmd{
+		Request: AddressRequest{Addresses: addresses, Start: start, End: end},
+	}
+}
+
+// GetAddressUtxosCmd defines the getaddressutxos JSON-RPC command.
+type GetAddressUtxosCmd struct {
+	Request AddressRequest
+}
+
+// NewGetAddressUtxosCmd returns a new instance which can be used to issue a getaddressutxos JSON-RPC command.
+func NewGetAddressUtxosCmd(addresses []string) *GetAddressUtxosCmd {
+	return &GetAddressUtxosCmd{
+		Request: AddressRequest{Addresses: addresses},
+	}
+}
+
+// GetAlgoStatsCmd defines the getalgostats JSON-RPC command.
+type GetAlgoStatsCmd struct {
+	Blocks *int `jsonrpcdefault:"3600"`
+}
+
+// NewGetAlgoStatsCmd returns a new instance which can be used to issue a getalgostats JSON-RPC command. The parameters
+// which are pointers indicate they are optional. Passing nil for optional parameters will use the default value.
+func NewGetAlgoStatsCmd(blocks *int) *GetAlgoStatsCmd {
+	return &GetAlgoStatsCmd{
+		Blocks: blocks,
+	}
+}
+
 // GetBestBlockHashCmd defines the getbestblockhash JSON-RPC command.
 type GetBestBlockHashCmd struct{}
 
@@ -181,6 +252,14 @@ type TemplateRequest struct {
 	// Block proposal from BIP 0023.  Data is only provided when Mode is "proposal".
 	Data   string `json:"data,omitempty"`
 	WorkID string `json:"workid,omitempty"`
+	// Optional addresses to split the block reward between in the coinbase transaction, in proportion to their weights.
+	Payouts []TemplateRequestPayout `json:"payouts,omitempty"`
+}
+
+// TemplateRequestPayout is an address the coinbase of a block template pays a share of the block reward to.
+type TemplateRequestPayout struct {
+	Address string  `json:"address"`
+	Weight  float64 `json:"weight"`
 }
 
 // convertTemplateRequestField potentially converts the provided value as needed.
@@ -456,12 +535,14 @@ func NewGetTxOutSetInfoCmd() *GetTxOutSetInfoCmd {
 // GetWorkCmd defines the getwork JSON-RPC command.
 type GetWorkCmd struct {
 	Data *string
+	Algo *string
 }
 
 // NewGetWorkCmd returns a new instance which can be used to issue a getwork JSON-RPC command. The parameters which are pointers indicate they are optional.  Passing nil for optional parameters will use the default value.
-func NewGetWorkCmd(data *string) *GetWorkCmd {
+func NewGetWorkCmd(data, algo *string) *GetWorkCmd {
 	return &GetWorkCmd{
 		Data: data,
+		Algo: algo,
 	}
 }
 
@@ -693,6 +774,11 @@ func init() {
 	MustRegisterCmd("decoderawtransaction", (*DecodeRawTransactionCmd)(nil), flags)
 	MustRegisterCmd("decodescript", (*DecodeScriptCmd)(nil), flags)
 	MustRegisterCmd("getaddednodeinfo", (*GetAddedNodeInfoCmd)(nil), flags)
+	MustRegisterCmd("getaddressbalance", (*GetAddressBalanceCmd)(nil), flags)
+	MustRegisterCmd("getaddressdeltas", (*GetAddressDeltasCmd)(nil), flags)
+	MustRegisterCmd("getaddresstxids", (*GetAddressTxIDsCmd)(nil), flags)
+	MustRegisterCmd("getaddressutxos", (*GetAddressUtxosCmd)(nil), flags)
+	MustRegisterCmd("getalgostats", (*GetAlgoStatsCmd)(nil), flags)
 	MustRegisterCmd("getbestblockhash", (*GetBestBlockHashCmd)(nil), flags)
 	MustRegisterCmd("getblock", (*GetBlockCmd)(nil), flags)
 	MustRegisterCmd("getblockchaininfo", (*GetBlockChainInfoCmd)(nil), flags)
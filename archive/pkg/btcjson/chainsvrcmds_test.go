@@ -125,6 +125,58 @@ func TestChainSvrCmds(t *testing.T) {
 				Node: btcjson.String("127.0.0.1"),
 			},
 		},
+		{
+			name: "getaddressbalance",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getaddressbalance", `{"addresses":["1Address"]}`)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetAddressBalanceCmd([]string{"1Address"})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getaddressbalance","netparams":[{"addresses":["1Address"]}],"id":1}`,
+			unmarshalled: &btcjson.GetAddressBalanceCmd{
+				Request: btcjson.AddressRequest{Addresses: []string{"1Address"}},
+			},
+		},
+		{
+			name: "getaddressdeltas",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getaddressdeltas", `{"addresses":["1Address"],"start":10,"end":20}`)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetAddressDeltasCmd([]string{"1Address"}, 10, 20)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getaddressdeltas","netparams":[{"addresses":["1Address"],"start":10,"end":20}],"id":1}`,
+			unmarshalled: &btcjson.GetAddressDeltasCmd{
+				Request: btcjson.AddressRequest{Addresses: []string{"1Address"}, Start: 10, End: 20},
+			},
+		},
+		{
+			name: "getalgostats",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getalgostats")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetAlgoStatsCmd(nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getalgostats","netparams":[],"id":1}`,
+			unmarshalled: &btcjson.GetAlgoStatsCmd{
+				Blocks: btcjson.Int(3600),
+			},
+		},
+		{
+			name: "getalgostats optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getalgostats", 100)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetAlgoStatsCmd(btcjson.Int(100))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getalgostats","netparams":[100],"id":1}`,
+			unmarshalled: &btcjson.GetAlgoStatsCmd{
+				Blocks: btcjson.Int(100),
+			},
+		},
 		{
 			name: "getbestblockhash",
 			newCmd: func() (interface{}, error) {
@@ -322,6 +374,37 @@ func TestChainSvrCmds(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "getblocktemplate optional - template request with payouts",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd(
+					"getblocktemplate",
+					`{"mode":"template","capabilities":["coinbasetxn"],"payouts":[{"address":"addr1","weight":3},{"address":"addr2","weight":1}]}`,
+				)
+			},
+			staticCmd: func() interface{} {
+				template := btcjson.TemplateRequest{
+					Mode:         "template",
+					Capabilities: []string{"coinbasetxn"},
+					Payouts: []btcjson.TemplateRequestPayout{
+						{Address: "addr1", Weight: 3},
+						{Address: "addr2", Weight: 1},
+					},
+				}
+				return btcjson.NewGetBlockTemplateCmd(&template)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getblocktemplate","netparams":[{"mode":"template","capabilities":["coinbasetxn"],"payouts":[{"address":"addr1","weight":3},{"address":"addr2","weight":1}]}],"id":1}`,
+			unmarshalled: &btcjson.GetBlockTemplateCmd{
+				Request: &btcjson.TemplateRequest{
+					Mode:         "template",
+					Capabilities: []string{"coinbasetxn"},
+					Payouts: []btcjson.TemplateRequestPayout{
+						{Address: "addr1", Weight: 3},
+						{Address: "addr2", Weight: 1},
+					},
+				},
+			},
+		},
 		{
 			name: "getcfilter",
 			newCmd: func() (interface{}, error) {
@@ -673,7 +756,7 @@ func TestChainSvrCmds(t *testing.T) {
 				return btcjson.NewCmd("getwork")
 			},
 			staticCmd: func() interface{} {
-				return btcjson.NewGetWorkCmd(nil)
+				return btcjson.NewGetWorkCmd(nil, nil)
 			},
 			marshalled: `{"jsonrpc":"1.0","method":"getwork","netparams":[],"id":1}`,
 			unmarshalled: &btcjson.GetWorkCmd{
@@ -686,13 +769,26 @@ func TestChainSvrCmds(t *testing.T) {
 				return btcjson.NewCmd("getwork", "00112233")
 			},
 			staticCmd: func() interface{} {
-				return btcjson.NewGetWorkCmd(btcjson.String("00112233"))
+				return btcjson.NewGetWorkCmd(btcjson.String("00112233"), nil)
 			},
 			marshalled: `{"jsonrpc":"1.0","method":"getwork","netparams":["00112233"],"id":1}`,
 			unmarshalled: &btcjson.GetWorkCmd{
 				Data: btcjson.String("00112233"),
 			},
 		},
+		{
+			name: "getwork algo",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getwork", nil, "blake2b")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetWorkCmd(nil, btcjson.String("blake2b"))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getwork","netparams":[null,"blake2b"],"id":1}`,
+			unmarshalled: &btcjson.GetWorkCmd{
+				Algo: btcjson.String("blake2b"),
+			},
+		},
 		{
 			name: "help",
 			newCmd: func() (interface{}, error) {
@@ -41,6 +41,59 @@ type GetAddedNodeInfoResultAddr struct {
 	Connected string `json:"connected"`
 }
 
+// GetAddressBalanceResult models the data returned from the getaddressbalance command.
+type GetAddressBalanceResult struct {
+	Balance  int64 `json:"balance"`
+	Received int64 `json:"received"`
+}
+
+// GetAddressDeltasResult models the data of each change of the balance of an address returned from the
+// getaddressdeltas command.
+type GetAddressDeltasResult struct {
+	Satoshis   int64  `json:"satoshis"`
+	TxID       string `json:"txid"`
+	Index      uint32 `json:"index"`
+	BlockIndex uint32 `json:"blockindex"`
+	Height     int32  `json:"height"`
+	Address    string `json:"address"`
+}
+
+// GetAddressUtxosResult models the data of each unspent output returned from the getaddressutxos command.
+type GetAddressUtxosResult struct {
+	Address     string `json:"address"`
+	TxID        string `json:"txid"`
+	OutputIndex uint32 `json:"outputIndex"`
+	Script      string `json:"script"`
+	Satoshis    int64  `json:"satoshis"`
+	Height      int32  `json:"height"`
+}
+
+// GetAlgoStatsResult models the data returned from the getalgostats command.
+type GetAlgoStatsResult struct {
+	Height             int32                    `json:"height"`
+	Blocks             int32                    `json:"blocks"`
+	Timespan           int64                    `json:"timespan"`
+	AllTimeAverage     float64                  `json:"alltimeaverage"`
+	AllTimeDivisor     float64                  `json:"alltimedivisor"`
+	QuarterHourDivisor float64                  `json:"quarterhourdivisor"`
+	HourDivisor        float64                  `json:"hourdivisor"`
+	DayDivisor         float64                  `json:"daydivisor"`
+	Algos              []GetAlgoStatsResultAlgo `json:"algos"`
+}
+
+// GetAlgoStatsResultAlgo models the statistics of each algorithm returned from the getalgostats command.
+type GetAlgoStatsResultAlgo struct {
+	Algo            string  `json:"algo"`
+	Version         int32   `json:"version"`
+	Blocks          int32   `json:"blocks"`
+	AverageInterval float64 `json:"averageinterval"`
+	TargetInterval  int64   `json:"targetinterval"`
+	Adjustment      float64 `json:"adjustment"`
+	Bits            string  `json:"bits"`
+	Difficulty      float64 `json:"difficulty"`
+	NetworkHashPS   int64   `json:"networkhashps"`
+}
+
 // GetBlockChainInfoResult models the data returned from the getblockchaininfo command.
 type GetBlockChainInfoResult struct {
 	Chain                string  `json:"chain"`
@@ -152,6 +205,14 @@ type GetBlockVerboseResult struct {
 	NextHash      string        `json:"nextblockhash,omitempty"`
 }
 
+// GetChainTipsResult models one entry of the data returned from the getchaintips command.
+type GetChainTipsResult struct {
+	Height    int32  `json:"height"`
+	Hash      string `json:"hash"`
+	BranchLen int32  `json:"branchlen"`
+	Status    string `json:"status"`
+}
+
 // GetMempoolEntryResult models the data returned from the getmempoolentry command.
 type GetMempoolEntryResult struct {
 	Size             int32    `json:"size"`
@@ -295,6 +356,18 @@ type GetTxOutResult struct {
 	Coinbase      bool               `json:"coinbase"`
 }
 
+// GetTxOutSetInfoResult models the data from the gettxoutsetinfo command.
+type GetTxOutSetInfoResult struct {
+	Height         int32   `json:"height"`
+	BestBlock      string  `json:"bestblock"`
+	Transactions   uint64  `json:"transactions"`
+	TxOuts         uint64  `json:"txouts"`
+	BogoSize       uint64  `json:"bogosize"`
+	SerializedSize uint64  `json:"serializedsize"`
+	MuHash         string  `json:"muhash"`
+	TotalAmount    float64 `json:"total_amount"`
+}
+
 // GetWorkResult models the data from the getwork command.
 type GetWorkResult struct {
 	Data     string `json:"data"`
@@ -93,6 +93,14 @@ type Checkpoint struct {
 	Hash   *chainhash.Hash
 }
 
+// AssumeUtxo identifies the utxo set at a known good point in the block chain by its MuHash3072 set hash, as reported
+// by the gettxoutsetinfo RPC, so a utxo snapshot of that block can be imported without trusting the snapshot file.
+type AssumeUtxo struct {
+	Height  int32
+	Hash    *chainhash.Hash
+	SetHash *chainhash.Hash
+}
+
 // DNSSeed identifies a DNS seed.
 type DNSSeed struct {
 	// Host defines the hostname of the seed.
@@ -184,6 +192,8 @@ type Params struct {
 	GenerateSupported bool
 	// Checkpoints ordered from oldest to newest.
 	Checkpoints []Checkpoint
+	// AssumeUtxo are the set hashes utxo snapshots are checked against, ordered from oldest to newest.
+	AssumeUtxo []AssumeUtxo
 	// These fields are related to voting on consensus rule changes as defined by BIP0009.
 	//
 	// RuleChangeActivationThreshold is the number of blocks in a threshold state retarget window for which a positive
@@ -227,39 +227,6 @@ func (s *NeutrinoClient) FilterBlocks(
 	return nil, nil
 }
 
-// buildFilterBlocksWatchList constructs a watchlist used for matching against a cfilter from a FilterBlocksRequest. The
-// watchlist will be populated with all external addresses, internal addresses, and outpoints contained in the request.
-func buildFilterBlocksWatchList(req *FilterBlocksRequest) ([][]byte, error) {
-	// Construct a watch list containing the script addresses of all internal and external addresses that were
-	// requested, in addition to the set of outpoints currently being watched.
-	watchListSize := len(req.ExternalAddrs) +
-		len(req.InternalAddrs) +
-		len(req.WatchedOutPoints)
-	watchList := make([][]byte, 0, watchListSize)
-	for _, addr := range req.ExternalAddrs {
-		p2shAddr, e := txscript.PayToAddrScript(addr)
-		if e != nil {
-			return nil, e
-		}
-		watchList = append(watchList, p2shAddr)
-	}
-	for _, addr := range req.InternalAddrs {
-		p2shAddr, e := txscript.PayToAddrScript(addr)
-		if e != nil {
-			return nil, e
-		}
-		watchList = append(watchList, p2shAddr)
-	}
-	for _, addr := range req.WatchedOutPoints {
-		addr, e := txscript.PayToAddrScript(addr)
-		if e != nil {
-			return nil, e
-		}
-		watchList = append(watchList, addr)
-	}
-	return watchList, nil
-}
-
 // pollCFilter attempts to fetch a CFilter from the neutrino client. This is used to get around the fact that the filter
 // headers may lag behind the highest known block header.
 func (s *NeutrinoClient) pollCFilter(hash *chainhash.Hash) (filter *gcs.Filter, e error) {
@@ -0,0 +1,104 @@
+package chainclient_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/p9c/pod/cmd/node/integration/rpctest"
+	"github.com/p9c/pod/cmd/spv"
+	"github.com/p9c/pod/pkg/chaincfg"
+	"github.com/p9c/pod/pkg/chainclient"
+	"github.com/p9c/pod/pkg/walletdb"
+	_ "github.com/p9c/pod/pkg/walletdb/bdb"
+	"github.com/p9c/pod/pkg/wire"
+)
+
+// TestNeutrinoClientSync syncs an SPV chain service from a harness node and checks that the neutrino client reports
+// the chain of the node and serves its blocks and filters.
+func TestNeutrinoClientSync(t *testing.T) {
+	h, e := rpctest.New(&chaincfg.SimNetParams, nil, nil)
+	if e != nil {
+		t.Skip("unable to create harness:", e)
+	}
+	defer func() {
+		if e := h.TearDown(); e != nil {
+			t.Log(e)
+		}
+	}()
+	if e = h.SetUp(false, 0); e != nil {
+		t.Skip("unable to set up harness:", e)
+	}
+	if _, e = h.Node.Generate(50); e != nil {
+		t.Fatal(e)
+	}
+	nodeHash, nodeHeight, e := h.Node.GetBestBlock()
+	if e != nil {
+		t.Fatal(e)
+	}
+	tempDir, e := ioutil.TempDir("", "neutrino")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(tempDir)
+	db, e := walletdb.Create("bdb", filepath.Join(tempDir, "neutrino.db"))
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer db.Close()
+	cs, e := spv.NewChainService(
+		spv.Config{
+			DataDir:      tempDir,
+			Database:     db,
+			ChainParams:  chaincfg.SimNetParams,
+			ConnectPeers: []string{h.P2PAddress()},
+		},
+	)
+	if e != nil {
+		t.Fatal(e)
+	}
+	nc := chainclient.NewNeutrinoClient(&chaincfg.SimNetParams, cs)
+	if e = nc.Start(); e != nil {
+		t.Fatal(e)
+	}
+	defer nc.Stop()
+	deadline := time.Now().Add(time.Minute)
+	for {
+		hash, height, e := nc.GetBestBlock()
+		if e != nil {
+			t.Fatal(e)
+		}
+		if height == nodeHeight && *hash == *nodeHash && cs.IsCurrent() {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("client is at height %d %v, node is at %d %v", height, hash, nodeHeight, nodeHash)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	for height := int64(1); height <= int64(nodeHeight); height += 10 {
+		want, e := h.Node.GetBlockHash(height)
+		if e != nil {
+			t.Fatal(e)
+		}
+		hash, e := nc.GetBlockHash(height)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if *hash != *want {
+			t.Fatalf("block %d is %v on the client and %v on the node", height, hash, want)
+		}
+		block, e := nc.GetBlock(hash)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if block.BlockHash() != *want {
+			t.Fatalf("block %d fetched by the client has hash %v", height, block.BlockHash())
+		}
+		if _, e = cs.GetCFilter(*hash, wire.GCSFilterRegular); e != nil {
+			t.Fatalf("unable to fetch filter of block %d: %v", height, e)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package chainrpc
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/p9c/pod/pkg/connmgr"
+)
+
+const (
+	// BlockRelayOnlyPeers is the number of outbound connections that only relay blocks. These don't relay transactions
+	// or addresses, which makes them hard for an attacker to find, so they keep the node on the best chain even when
+	// its other connections are taken over.
+	BlockRelayOnlyPeers = 2
+	// BlockRelayInterval is the interval at which block relay only connections that failed or closed are replaced.
+	BlockRelayInterval = 30 * time.Second
+	// AnchorsFileName is the name of the file in the data directory of the network that the addresses of the block
+	// relay only peers are saved to on shutdown, so they are connected to again first on the next start.
+	AnchorsFileName = "anchors.json"
+)
+
+// anchorsPath returns the path of the anchors file of the active network.
+func (n *Node) anchorsPath() string {
+	return filepath.Join(n.Config.DataDir.V(), n.ActiveNet.Name, AnchorsFileName)
+}
+
+// loadAnchors reads the addresses of the block relay only peers that were connected when the node last shut down. The
+// file is removed straight away so that if one of them makes the node crash it is not connected to again.
+func (n *Node) loadAnchors() (anchors []net.Addr) {
+	path := n.anchorsPath()
+	b, e := ioutil.ReadFile(path)
+	if e != nil {
+		if !os.IsNotExist(e) {
+			E.Ln("failed to read anchors file:", e)
+		}
+		return
+	}
+	if e = os.Remove(path); E.Chk(e) {
+	}
+	var addrs []string
+	if e = json.Unmarshal(b, &addrs); E.Chk(e) {
+		return
+	}
+	for _, addr := range addrs {
+		if len(anchors) == BlockRelayOnlyPeers {
+			break
+		}
+		var netAddr net.Addr
+		if netAddr, e = AddrStringToNetAddr(n.Config, n.StateCfg, addr); E.Chk(e) {
+			continue
+		}
+		anchors = append(anchors, netAddr)
+	}
+	I.F("connecting to %d anchor peers from %s", len(anchors), path)
+	return
+}
+
+// saveAnchors writes the addresses of the connected block relay only peers to the anchors file. It is invoked from the
+// peerHandler goroutine when shutting down.
+func (n *Node) saveAnchors(state *PeerState) {
+	var anchors []string
+	for _, sp := range state.OutboundPeers {
+		if sp.BlockRelayOnly && sp.Connected() && sp.VerAckReceived() {
+			anchors = append(anchors, sp.Addr())
+		}
+	}
+	if len(anchors) == 0 {
+		return
+	}
+	b, e := json.Marshal(anchors)
+	if E.Chk(e) {
+		return
+	}
+	if e = ioutil.WriteFile(n.anchorsPath(), b, 0600); E.Chk(e) {
+		return
+	}
+	D.F("saved %d anchor peers", len(anchors))
+}
+
+// BlockRelayHandler keeps up BlockRelayOnlyPeers outbound connections that only relay blocks, to the anchors saved on
+// the last shutdown first and then to addresses from the address manager. It must be run as a goroutine.
+func (n *Node) BlockRelayHandler() {
+	anchors := n.loadAnchors()
+	reqs := make([]*connmgr.ConnReq, 0, BlockRelayOnlyPeers)
+	ticker := time.NewTicker(BlockRelayInterval)
+out:
+	for {
+		reqs, anchors = n.replaceBlockRelayConns(reqs, anchors)
+		select {
+		case <-ticker.C:
+		case <-n.Quit.Wait():
+			break out
+		}
+	}
+	ticker.Stop()
+	n.WG.Done()
+}
+
+// replaceBlockRelayConns forgets the block relay only connection requests that failed or closed and makes new ones in
+// their place, to the anchors first and then to addresses from the address manager. It returns the requests that are
+// kept up and the anchors that are left.
+func (n *Node) replaceBlockRelayConns(reqs []*connmgr.ConnReq, anchors []net.Addr) ([]*connmgr.ConnReq, []net.Addr) {
+	open := reqs[:0]
+	for _, req := range reqs {
+		switch req.State() {
+		case connmgr.ConnFailing, connmgr.ConnCanceled, connmgr.ConnDisconnected:
+		default:
+			open = append(open, req)
+		}
+	}
+	reqs = open
+	for len(reqs) < BlockRelayOnlyPeers {
+		var addr net.Addr
+		if len(anchors) > 0 {
+			addr, anchors = anchors[0], anchors[1:]
+		} else {
+			var e error
+			if addr, e = n.newAddress(); e != nil {
+				T.Ln("no address for a block relay only connection:", e)
+				break
+			}
+		}
+		req := &connmgr.ConnReq{Addr: addr, BlockRelayOnly: true}
+		reqs = append(reqs, req)
+		go n.ConnManager.Connect(req)
+	}
+	return reqs, anchors
+}
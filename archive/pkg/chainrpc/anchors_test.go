@@ -0,0 +1,181 @@
+package chainrpc
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/p9c/opts/meta"
+	"github.com/p9c/opts/text"
+	"github.com/p9c/qu"
+
+	"github.com/p9c/pod/pkg/chaincfg"
+	"github.com/p9c/pod/pkg/connmgr"
+	"github.com/p9c/pod/pkg/peer"
+	"github.com/p9c/pod/pkg/wire"
+	"github.com/p9c/pod/pod/config"
+)
+
+// pipeConn is one end of a net.Pipe with the addresses of a network connection.
+type pipeConn struct {
+	net.Conn
+	laddr, raddr net.Addr
+}
+
+func (c pipeConn) LocalAddr() net.Addr  { return c.laddr }
+func (c pipeConn) RemoteAddr() net.Addr { return c.raddr }
+
+// anchorPeer returns a server peer that is connected to the address, and has exchanged versions with it when
+// handshake is set.
+func anchorPeer(t *testing.T, address string, blockRelayOnly, handshake bool) *NodePeer {
+	verack := qu.Ts(2)
+	cfg := &peer.Config{
+		Listeners: peer.MessageListeners{
+			OnVerAck: func(p *peer.Peer, msg *wire.MsgVerAck) {
+				verack <- struct{}{}
+			},
+		},
+		ChainParams: &chaincfg.MainNetParams,
+	}
+	p, e := peer.NewOutboundPeer(cfg, address)
+	if e != nil {
+		t.Fatal(e)
+	}
+	sp := &NodePeer{Peer: p, BlockRelayOnly: blockRelayOnly}
+	if !handshake {
+		return sp
+	}
+	remote := peer.NewInboundPeer(cfg)
+	local := &net.TCPAddr{IP: net.ParseIP("10.0.0.100"), Port: 11047}
+	remoteAddr, e := net.ResolveTCPAddr("tcp", address)
+	if e != nil {
+		t.Fatal(e)
+	}
+	inConn, outConn := net.Pipe()
+	remote.AssociateConnection(pipeConn{inConn, remoteAddr, local})
+	p.AssociateConnection(pipeConn{outConn, local, remoteAddr})
+	for i := 0; i < 2; i++ {
+		select {
+		case <-verack.Wait():
+		case <-time.After(time.Second * 5):
+			t.Fatalf("%s: verack timeout", address)
+		}
+	}
+	return sp
+}
+
+// waitState waits for the connection requests to reach the state.
+func waitState(t *testing.T, reqs []*connmgr.ConnReq, state connmgr.ConnState) {
+	deadline := time.Now().Add(time.Second * 5)
+	for _, req := range reqs {
+		for req.State() != state {
+			if time.Now().After(deadline) {
+				t.Fatalf("%v: got state %v, want %v", req, req.State(), state)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
+
+// TestAnchors checks that the addresses of the connected block relay only peers are saved to the anchors file and
+// loaded from it once, and that the block relay only connections to anchors that fail are replaced with connections
+// to new addresses.
+func TestAnchors(t *testing.T) {
+	peer.AllowSelfConns = true
+	defer func() { peer.AllowSelfConns = false }()
+	dir, e := ioutil.TempDir("", "anchorstest")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+	n := &Node{
+		Config:    &config.Config{DataDir: text.New(meta.Data{}, dir)},
+		ActiveNet: &chaincfg.MainNetParams,
+	}
+	if e = os.MkdirAll(filepath.Join(dir, n.ActiveNet.Name), 0700); e != nil {
+		t.Fatal(e)
+	}
+	// Only the block relay only peers that are connected and have exchanged versions are anchors.
+	state := &PeerState{
+		OutboundPeers: map[int32]*NodePeer{
+			1: anchorPeer(t, "10.0.0.1:11047", true, true),
+			2: anchorPeer(t, "10.0.0.2:11047", false, true),
+			3: anchorPeer(t, "10.0.0.3:11047", true, false),
+			4: anchorPeer(t, "10.0.0.4:11047", true, true),
+		},
+	}
+	for _, sp := range state.OutboundPeers {
+		defer sp.Disconnect()
+	}
+	n.saveAnchors(state)
+	anchors := n.loadAnchors()
+	want := map[string]bool{"10.0.0.1:11047": true, "10.0.0.4:11047": true}
+	if len(anchors) != len(want) {
+		t.Fatalf("loaded anchors %v, want %v", anchors, want)
+	}
+	for _, anchor := range anchors {
+		if !want[anchor.String()] {
+			t.Fatalf("loaded anchors %v, want %v", anchors, want)
+		}
+	}
+	if _, e = os.Stat(n.anchorsPath()); !os.IsNotExist(e) {
+		t.Fatalf("the anchors file was not removed after loading it: %v", e)
+	}
+	if again := n.loadAnchors(); len(again) != 0 {
+		t.Fatalf("loaded anchors %v again", again)
+	}
+	// The anchors don't answer, the new addresses from the address manager do.
+	if n.ConnManager, e = connmgr.New(
+		&connmgr.Config{
+			Dial: func(addr net.Addr) (net.Conn, error) {
+				if want[addr.String()] {
+					return nil, errors.New("connection refused")
+				}
+				c, _ := net.Pipe()
+				return c, nil
+			},
+		},
+	); e != nil {
+		t.Fatal(e)
+	}
+	n.ConnManager.Start()
+	defer n.ConnManager.Stop()
+	var newAddrs int
+	n.newAddress = func() (net.Addr, error) {
+		newAddrs++
+		return &net.TCPAddr{IP: net.ParseIP(fmt.Sprintf("10.0.1.%d", newAddrs)), Port: 11047}, nil
+	}
+	reqs, left := n.replaceBlockRelayConns(nil, anchors)
+	if len(reqs) != BlockRelayOnlyPeers || len(left) != 0 || newAddrs != 0 {
+		t.Fatalf("%d connections with %d anchors left and %d new addresses, want connections to the anchors",
+			len(reqs), len(left), newAddrs,
+		)
+	}
+	for i, req := range reqs {
+		if req.Addr != anchors[i] || !req.BlockRelayOnly {
+			t.Fatalf("connection %v, want a block relay only connection to %v", req, anchors[i])
+		}
+	}
+	waitState(t, reqs, connmgr.ConnFailing)
+	reqs, _ = n.replaceBlockRelayConns(reqs, left)
+	if len(reqs) != BlockRelayOnlyPeers || newAddrs != BlockRelayOnlyPeers {
+		t.Fatalf("%d connections with %d new addresses, want the failed anchors replaced", len(reqs), newAddrs)
+	}
+	for i, req := range reqs {
+		if address := fmt.Sprintf("10.0.1.%d:11047", i+1); req.Addr.String() != address || !req.BlockRelayOnly {
+			t.Fatalf("connection %v, want a block relay only connection to %v", req, address)
+		}
+	}
+	waitState(t, reqs, connmgr.ConnEstablished)
+	// The connections that are up are kept.
+	kept := append([]*connmgr.ConnReq(nil), reqs...)
+	reqs, _ = n.replaceBlockRelayConns(reqs, nil)
+	if len(reqs) != len(kept) || reqs[0] != kept[0] || reqs[1] != kept[1] || newAddrs != BlockRelayOnlyPeers {
+		t.Fatalf("connections %v were replaced with %v", kept, reqs)
+	}
+}
@@ -0,0 +1,217 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/p9c/pod/pkg/chainhash"
+	"github.com/p9c/pod/pkg/wire"
+)
+
+// Version is the encoding version written after the magic of all message types
+const Version = 1
+
+// headerSize is the size of the magic and the version byte at the start of every message
+const headerSize = 5
+
+// Encoder builds a message out of tagged fields. Fields should be added in order of their tags.
+type Encoder struct {
+	buf []byte
+}
+
+// NewEncoder starts a message of the type with the given magic
+func NewEncoder(magic []byte) *Encoder {
+	buf := make([]byte, headerSize, 128)
+	copy(buf, magic)
+	buf[4] = Version
+	return &Encoder{buf: buf}
+}
+
+// Encode returns the encoded message
+func (enc *Encoder) Encode() []byte {
+	return enc.buf
+}
+
+// PutBytes adds a field with the given value
+func (enc *Encoder) PutBytes(tag byte, v []byte) *Encoder {
+	var l [binary.MaxVarintLen64]byte
+	enc.buf = append(enc.buf, tag)
+	enc.buf = append(enc.buf, l[:binary.PutUvarint(l[:], uint64(len(v)))]...)
+	enc.buf = append(enc.buf, v...)
+	return enc
+}
+
+// PutString adds a string field
+func (enc *Encoder) PutString(tag byte, v string) *Encoder {
+	return enc.PutBytes(tag, []byte(v))
+}
+
+// PutUint16 adds a uint16 field
+func (enc *Encoder) PutUint16(tag byte, v uint16) *Encoder {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], v)
+	return enc.PutBytes(tag, b[:])
+}
+
+// PutUint32 adds a uint32 field
+func (enc *Encoder) PutUint32(tag byte, v uint32) *Encoder {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	return enc.PutBytes(tag, b[:])
+}
+
+// PutInt32 adds an int32 field
+func (enc *Encoder) PutInt32(tag byte, v int32) *Encoder {
+	return enc.PutUint32(tag, uint32(v))
+}
+
+// PutUint64 adds a uint64 field
+func (enc *Encoder) PutUint64(tag byte, v uint64) *Encoder {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	return enc.PutBytes(tag, b[:])
+}
+
+// PutInt64 adds an int64 field
+func (enc *Encoder) PutInt64(tag byte, v int64) *Encoder {
+	return enc.PutUint64(tag, uint64(v))
+}
+
+// PutTime adds a time field
+func (enc *Encoder) PutTime(tag byte, v time.Time) *Encoder {
+	return enc.PutInt64(tag, v.UnixNano())
+}
+
+// PutHash adds a hash field
+func (enc *Encoder) PutHash(tag byte, v *chainhash.Hash) *Encoder {
+	return enc.PutBytes(tag, v[:])
+}
+
+// PutHeader adds a block header field
+func (enc *Encoder) PutHeader(tag byte, v *wire.BlockHeader) *Encoder {
+	var buf bytes.Buffer
+	var e error
+	if e = v.Serialize(&buf); E.Chk(e) {
+	}
+	return enc.PutBytes(tag, buf.Bytes())
+}
+
+// Decoder reads the fields of a message
+type Decoder struct {
+	buf []byte
+	err error
+}
+
+// NewDecoder checks the magic and the version of a message and returns a decoder for its fields
+func NewDecoder(magic []byte, b []byte) (dec *Decoder, e error) {
+	if len(b) < headerSize {
+		return nil, errors.New("message is too short")
+	}
+	if !bytes.Equal(b[:4], magic) {
+		return nil, fmt.Errorf("message has magic %q, expected %q", b[:4], magic)
+	}
+	if b[4] > Version {
+		return nil, fmt.Errorf("message has encoding version %d, only up to %d is supported", b[4], Version)
+	}
+	return &Decoder{buf: b[headerSize:]}, nil
+}
+
+// Next returns the tag and the value of the next field, ok is false when there are no more fields or the message is
+// malformed, which Err then reports
+func (dec *Decoder) Next() (tag byte, value []byte, ok bool) {
+	if dec.err != nil || len(dec.buf) == 0 {
+		return
+	}
+	tag = dec.buf[0]
+	l, n := binary.Uvarint(dec.buf[1:])
+	if n <= 0 || l > uint64(len(dec.buf)-1-n) {
+		dec.err = fmt.Errorf("field %d has a bad length", tag)
+		return 0, nil, false
+	}
+	start := 1 + n
+	value = dec.buf[start : start+int(l)]
+	dec.buf = dec.buf[start+int(l):]
+	return tag, value, true
+}
+
+// Err returns the error that stopped the decoding of the fields, if there was one
+func (dec *Decoder) Err() error {
+	return dec.err
+}
+
+// checkLen returns an error if a value is not of the size of its type
+func checkLen(v []byte, size int) (e error) {
+	if len(v) != size {
+		e = fmt.Errorf("value has %d bytes, expected %d", len(v), size)
+	}
+	return
+}
+
+// Uint16 decodes a uint16 value
+func Uint16(v []byte) (uint16, error) {
+	if e := checkLen(v, 2); e != nil {
+		return 0, e
+	}
+	return binary.BigEndian.Uint16(v), nil
+}
+
+// Uint32 decodes a uint32 value
+func Uint32(v []byte) (uint32, error) {
+	if e := checkLen(v, 4); e != nil {
+		return 0, e
+	}
+	return binary.BigEndian.Uint32(v), nil
+}
+
+// Int32 decodes an int32 value
+func Int32(v []byte) (int32, error) {
+	u, e := Uint32(v)
+	return int32(u), e
+}
+
+// Uint64 decodes a uint64 value
+func Uint64(v []byte) (uint64, error) {
+	if e := checkLen(v, 8); e != nil {
+		return 0, e
+	}
+	return binary.BigEndian.Uint64(v), nil
+}
+
+// Int64 decodes an int64 value
+func Int64(v []byte) (int64, error) {
+	u, e := Uint64(v)
+	return int64(u), e
+}
+
+// Time decodes a time value
+func Time(v []byte) (time.Time, error) {
+	n, e := Int64(v)
+	if e != nil {
+		return time.Time{}, e
+	}
+	return time.Unix(0, n), nil
+}
+
+// Hash decodes a hash value
+func Hash(v []byte) (h chainhash.Hash, e error) {
+	if e = checkLen(v, chainhash.HashSize); e != nil {
+		return
+	}
+	copy(h[:], v)
+	return
+}
+
+// Header decodes a block header value
+func Header(v []byte) (hdr *wire.BlockHeader, e error) {
+	if e = checkLen(v, wire.MaxBlockHeaderPayload); e != nil {
+		return
+	}
+	hdr = &wire.BlockHeader{}
+	if e = hdr.Deserialize(bytes.NewReader(v)); E.Chk(e) {
+		return nil, e
+	}
+	return
+}
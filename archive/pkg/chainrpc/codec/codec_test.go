@@ -0,0 +1,223 @@
+package codec_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/p9c/pod/pkg/chainhash"
+	"github.com/p9c/pod/pkg/chainrpc/codec"
+	"github.com/p9c/pod/pkg/chainrpc/hashrate"
+	"github.com/p9c/pod/pkg/chainrpc/p2padvt"
+	"github.com/p9c/pod/pkg/chainrpc/share"
+	"github.com/p9c/pod/pkg/chainrpc/sol"
+	"github.com/p9c/pod/pkg/chainrpc/templates"
+	"github.com/p9c/pod/pkg/wire"
+)
+
+// testHeader is the block header in the golden vectors.
+var testHeader = wire.BlockHeader{
+	Version:    514,
+	PrevBlock:  chainhash.Hash{1, 2, 3},
+	MerkleRoot: chainhash.Hash{4, 5, 6},
+	Timestamp:  time.Unix(1600000000, 0),
+	Bits:       0x1d00ffff,
+	Nonce:      0x01020304,
+}
+
+const testHeaderHex = "02020000" +
+	"0102030000000000000000000000000000000000000000000000000000000000" +
+	"0405060000000000000000000000000000000000000000000000000000000000" +
+	"00105e5f" + "ffff001d" + "04030201"
+
+// golden returns the golden vector built from hex fragments.
+func golden(t *testing.T, fragments ...string) []byte {
+	var s string
+	for i := range fragments {
+		s += fragments[i]
+	}
+	b, e := hex.DecodeString(s)
+	if e != nil {
+		t.Fatal(e)
+	}
+	return b
+}
+
+func checkGolden(t *testing.T, name string, got, want []byte) {
+	if !bytes.Equal(got, want) {
+		t.Fatalf("%s encoding\n%x\nwant\n%x", name, got, want)
+	}
+}
+
+// TestTemplateGolden checks the encoding of a template against the golden vector and that it decodes to the same
+// template.
+func TestTemplateGolden(t *testing.T) {
+	msg := &templates.Message{
+		Nonce:     0x1122334455667788,
+		UUID:      42,
+		Height:    1000,
+		PrevBlock: chainhash.Hash{0xaa},
+		Bits:      templates.Diffs{514: 0x1d00ffff, 2: 0x1e0fffff},
+		Merkles:   templates.Merkles{2: chainhash.Hash{0xbb}, 514: chainhash.Hash{0xcc}},
+		Timestamp: time.Unix(1600000000, 0),
+		ShareBits: templates.Diffs{2: 0x1f00ffff},
+	}
+	want := golden(t,
+		"6a6f6201", "01",
+		"0108", "1122334455667788",
+		"0208", "000000000000002a",
+		"0304", "000003e8",
+		"0420", "aa00000000000000000000000000000000000000000000000000000000000000",
+		"0508", "00000002", "1e0fffff",
+		"0508", "00000202", "1d00ffff",
+		"0624", "00000002", "bb00000000000000000000000000000000000000000000000000000000000000",
+		"0624", "00000202", "cc00000000000000000000000000000000000000000000000000000000000000",
+		"0708", "16345785d8a00000",
+		"0808", "00000002", "1f00ffff",
+	)
+	checkGolden(t, "template", msg.Serialize(), want)
+	got, e := templates.DeserializeMsgBlockTemplate(want)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if got.Nonce != msg.Nonce || got.UUID != msg.UUID || got.Height != msg.Height || got.PrevBlock != msg.PrevBlock ||
+		!reflect.DeepEqual(got.Bits, msg.Bits) || !reflect.DeepEqual(got.Merkles, msg.Merkles) ||
+		!got.Timestamp.Equal(msg.Timestamp) || !reflect.DeepEqual(got.ShareBits, msg.ShareBits) {
+		t.Fatalf("decoded template %+v, want %+v", got, msg)
+	}
+}
+
+// TestSolutionGolden checks the encoding of a solution against the golden vector and that it decodes to the same
+// solution.
+func TestSolutionGolden(t *testing.T) {
+	want := golden(t,
+		"736f6c01", "01",
+		"0108", "0000000000000007",
+		"0208", "000000000000002a",
+		"0350", testHeaderHex,
+	)
+	checkGolden(t, "solution", sol.Encode(7, 42, &testHeader), want)
+	got, e := sol.Deserialize(want)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if got.Nonce != 7 || got.UUID != 42 || got.Header.BlockHash() != testHeader.BlockHash() {
+		t.Fatalf("decoded solution %+v", got)
+	}
+}
+
+// TestShareGolden checks the encoding of a share against the golden vector and that it decodes to the same share.
+func TestShareGolden(t *testing.T) {
+	want := golden(t,
+		"73687201", "01",
+		"0108", "0000000000000007",
+		"0208", "000000000000002a",
+		"0304", hex.EncodeToString([]byte("addr")),
+		"0450", testHeaderHex,
+	)
+	checkGolden(t, "share", share.Encode(7, 42, "addr", &testHeader), want)
+	got, e := share.Deserialize(want)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if got.Nonce != 7 || got.UUID != 42 || got.Address != "addr" || got.Header.BlockHash() != testHeader.BlockHash() {
+		t.Fatalf("decoded share %+v", got)
+	}
+}
+
+// TestHashrateGolden checks the encoding of a hashrate report against the golden vector and that it decodes to the
+// same report.
+func TestHashrateGolden(t *testing.T) {
+	hr := &hashrate.Hashrate{
+		Time:    time.Unix(1600000000, 0),
+		IP:      net.IP{192, 168, 0, 1},
+		Count:   1000000,
+		Version: 514,
+		Height:  1000,
+		Nonce:   -2,
+		ID:      "w1",
+	}
+	want := golden(t,
+		"68617301", "01",
+		"0108", "16345785d8a00000",
+		"0204", "c0a80001",
+		"0308", "00000000000f4240",
+		"0404", "00000202",
+		"0504", "000003e8",
+		"0604", "fffffffe",
+		"0702", hex.EncodeToString([]byte("w1")),
+	)
+	checkGolden(t, "hashrate", hr.Serialize(), want)
+	got, e := hashrate.Deserialize(want)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !got.Time.Equal(hr.Time) || !got.IP.Equal(hr.IP) || got.Count != hr.Count || got.Version != hr.Version ||
+		got.Height != hr.Height || got.Nonce != hr.Nonce || got.ID != hr.ID {
+		t.Fatalf("decoded hashrate %+v, want %+v", got, hr)
+	}
+}
+
+// TestAdvertismentGolden checks the encoding of an advertisment against the golden vector and that it decodes to the
+// same advertisment.
+func TestAdvertismentGolden(t *testing.T) {
+	adv := &p2padvt.Advertisment{
+		IPs:  map[string]struct{}{"10.0.0.2": {}, "10.0.0.1": {}},
+		P2P:  11047,
+		UUID: 42,
+	}
+	want := golden(t,
+		"61647601", "01",
+		"0108", hex.EncodeToString([]byte("10.0.0.1")),
+		"0108", hex.EncodeToString([]byte("10.0.0.2")),
+		"0202", "2b27",
+		"0308", "000000000000002a",
+	)
+	checkGolden(t, "advertisment", adv.Serialize(), want)
+	got, e := p2padvt.Deserialize(want)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !reflect.DeepEqual(got, adv) {
+		t.Fatalf("decoded advertisment %+v, want %+v", got, adv)
+	}
+}
+
+// TestUnknownFields checks that fields with tags a decoder does not know are skipped, including ones with multi byte
+// lengths.
+func TestUnknownFields(t *testing.T) {
+	msg := codec.NewEncoder(p2padvt.Magic).
+		PutUint16(2, 11047).
+		PutBytes(9, make([]byte, 300)).
+		PutUint64(3, 42).
+		Encode()
+	got, e := p2padvt.Deserialize(msg)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if got.P2P != 11047 || got.UUID != 42 {
+		t.Fatalf("decoded advertisment %+v", got)
+	}
+}
+
+// TestRejected checks that messages that are not of the expected type, of a newer version, or malformed are rejected.
+func TestRejected(t *testing.T) {
+	good := sol.Encode(7, 42, &testHeader)
+	newer := append([]byte{}, good...)
+	newer[4] = codec.Version + 1
+	for name, msg := range map[string][]byte{
+		"short":          good[:3],
+		"other type":     share.Encode(7, 42, "addr", &testHeader),
+		"newer version":  newer,
+		"truncated":      good[:len(good)-1],
+		"bad value size": codec.NewEncoder(sol.Magic).PutUint32(1, 7).PutHeader(3, &testHeader).Encode(),
+		"no header":      codec.NewEncoder(sol.Magic).PutUint64(1, 7).Encode(),
+	} {
+		if _, e := sol.Deserialize(msg); e == nil {
+			t.Errorf("%s message was accepted", name)
+		}
+	}
+}
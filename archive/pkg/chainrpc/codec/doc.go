@@ -0,0 +1,75 @@
+/*
+Package codec is the binary encoding of the messages that mining controllers and kopach miners exchange over the
+multicast transport. It replaces encoding the Go structs of the messages directly, which breaks silently whenever a
+field changes, with a format that is versioned and can be implemented in any language.
+
+Message layout
+
+Every message starts with the 4 byte magic of its type, followed by a version byte and a sequence of fields:
+
+	magic   4 bytes  the message type, for example 's' 'o' 'l' 0x01 for a solution
+	version 1 byte   the encoding version of the message type, currently 1 for all of them
+	fields  ...      until the end of the message
+
+Each field is a tag, the length of the value and the value:
+
+	tag     1 byte   the field number, 0 is not used
+	length  varint   the number of bytes in the value, as an unsigned LEB128 varint (protobuf style)
+	value   length bytes
+
+Fields are written in order of their tags. A field that is repeated, such as an entry of a map, appears once for each
+element. A decoder skips fields with tags it does not know and leaves fields that are missing at their zero value, so
+fields can be added to a message type without changing its version. The version is only raised for changes that older
+decoders cannot handle, and decoders reject messages with a version higher than they know.
+
+Values are encoded as follows:
+
+	uint16, int32, uint32, int64, uint64   big endian, two's complement for signed values, of the size of the type
+	time                                    int64 nanoseconds since the unix epoch
+	hash                                    32 bytes in the order of chainhash.Hash, as in block headers on the wire
+	string, bytes                           as is, strings are UTF-8
+	block header                            the 80 byte block header as serialized on the wire
+
+Message types
+
+Template ('j' 'o' 'b' 0x01), the work a controller hands out to miners:
+
+	1 nonce       uint64  identifies the template to the controller
+	2 uuid        uint64  identifies the controller
+	3 height      int32   height of the block being mined
+	4 prevblock   hash    hash of the previous block
+	5 bits        int32 block version followed by uint32 compact target, repeated for each algorithm
+	6 merkles     int32 block version followed by hash merkle root, repeated for each algorithm
+	7 timestamp   time    time to put in the block header
+	8 sharebits   int32 block version followed by uint32 compact share target, repeated, only in pool mode
+
+Solution ('s' 'o' 'l' 0x01), a block header that meets the block target of a template:
+
+	1 nonce   uint64        the nonce of the template
+	2 uuid    uint64        the uuid of the controller of the template
+	3 header  block header  the solved header
+
+Share ('s' 'h' 'r' 0x01), a block header that meets the share target of a template in pool mode:
+
+	1 nonce    uint64        the nonce of the template
+	2 uuid     uint64        the uuid of the controller of the template
+	3 address  string        the payout address the share is credited to
+	4 header   block header  the header of the share
+
+Hashrate ('h' 'a' 's' 0x01), a report of the hashes a miner has done:
+
+	1 time     time    when the report was made
+	2 ip       bytes   the 4 or 16 byte address of the miner
+	3 count    int64   the number of hashes done
+	4 version  int32   the block version last mined
+	5 height   int32   the height last mined
+	6 nonce    int32   random number to recognise repeated reports
+	7 id       string  the identifier of the miner
+
+Advertisment ('a' 'd' 'v' 0x01), the contact details of a node, also sent as the payload of a pause message:
+
+	1 ip    string  an address of the node, repeated for each address
+	2 p2p   uint16  the peer to peer port of the node
+	3 uuid  uint64  the uuid of the node
+*/
+package codec
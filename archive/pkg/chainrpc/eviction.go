@@ -0,0 +1,173 @@
+package chainrpc
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/aead/siphash"
+
+	"github.com/p9c/pod/pkg/addrmgr"
+)
+
+const (
+	// EvictionProtectNetGroups is the number of inbound peers protected from eviction by their network group. The
+	// groups are compared under a key that is secret to the node, so an attacker can't pick addresses that are sure to
+	// be protected.
+	EvictionProtectNetGroups = 4
+	// EvictionProtectPing is the number of inbound peers with the lowest ping times protected from eviction.
+	EvictionProtectPing = 8
+	// EvictionProtectTxRelay is the number of inbound peers that most recently relayed a new transaction protected from
+	// eviction.
+	EvictionProtectTxRelay = 4
+	// EvictionProtectBlockRelayOnly is the number of inbound peers that don't relay transactions, and so can't be
+	// protected for it, that most recently relayed a new block protected from eviction.
+	EvictionProtectBlockRelayOnly = 8
+	// EvictionProtectBlockRelay is the number of inbound peers that most recently relayed a new block protected from
+	// eviction.
+	EvictionProtectBlockRelay = 4
+)
+
+// evictionCandidate is the state of an inbound peer that decides whether it is protected from eviction.
+type evictionCandidate struct {
+	id        int32
+	connected time.Time
+	// minPing is the lowest ping time of the peer, or zero when it is not known yet.
+	minPing       time.Duration
+	lastBlockTime time.Time
+	lastTxTime    time.Time
+	relayTxs      bool
+	netGroup      string
+	keyedNetGroup uint64
+}
+
+// protectEvictionCandidates sorts the candidates for eviction with the most deserving first and removes up to count of
+// the first of them from the candidates, only those matching if match is not nil.
+func protectEvictionCandidates(
+	candidates []evictionCandidate, better func(a, b *evictionCandidate) bool, count int,
+	match func(c *evictionCandidate) bool,
+) []evictionCandidate {
+	sort.SliceStable(
+		candidates, func(i, j int) bool {
+			return better(&candidates[i], &candidates[j])
+		},
+	)
+	rest := candidates[:0]
+	for i := range candidates {
+		if count > 0 && (match == nil || match(&candidates[i])) {
+			count--
+			continue
+		}
+		rest = append(rest, candidates[i])
+	}
+	return rest
+}
+
+// olderConnection orders the candidates by how long they have been connected.
+func olderConnection(a, b *evictionCandidate) bool {
+	if !a.connected.Equal(b.connected) {
+		return a.connected.Before(b.connected)
+	}
+	return a.id < b.id
+}
+
+// selectPeerToEvict picks the inbound peer to disconnect to make room for a new one, following the reference client.
+// In turn the peers in the most network groups, with the lowest ping times, that most recently relayed new transactions
+// and blocks and finally the older half of those left are protected from eviction, so an attacker would need to beat
+// the honest peers at all of these to take over all of the inbound slots. Of the peers left, the newest one of the
+// network group with the most of them is picked, so the peers of a single network group are evicted first. False is
+// returned when every peer is protected.
+func selectPeerToEvict(candidates []evictionCandidate) (id int32, ok bool) {
+	candidates = protectEvictionCandidates(
+		candidates, func(a, b *evictionCandidate) bool {
+			if a.keyedNetGroup != b.keyedNetGroup {
+				return a.keyedNetGroup < b.keyedNetGroup
+			}
+			return olderConnection(a, b)
+		}, EvictionProtectNetGroups, nil,
+	)
+	candidates = protectEvictionCandidates(
+		candidates, func(a, b *evictionCandidate) bool {
+			if a.minPing != b.minPing {
+				// A peer that has not answered a ping yet is the worst.
+				return b.minPing == 0 || a.minPing != 0 && a.minPing < b.minPing
+			}
+			return olderConnection(a, b)
+		}, EvictionProtectPing, nil,
+	)
+	candidates = protectEvictionCandidates(
+		candidates, func(a, b *evictionCandidate) bool {
+			if !a.lastTxTime.Equal(b.lastTxTime) {
+				return a.lastTxTime.After(b.lastTxTime)
+			}
+			if a.relayTxs != b.relayTxs {
+				return a.relayTxs
+			}
+			return olderConnection(a, b)
+		}, EvictionProtectTxRelay, nil,
+	)
+	newerBlock := func(a, b *evictionCandidate) bool {
+		if !a.lastBlockTime.Equal(b.lastBlockTime) {
+			return a.lastBlockTime.After(b.lastBlockTime)
+		}
+		return olderConnection(a, b)
+	}
+	candidates = protectEvictionCandidates(
+		candidates, newerBlock, EvictionProtectBlockRelayOnly, func(c *evictionCandidate) bool {
+			return !c.relayTxs
+		},
+	)
+	candidates = protectEvictionCandidates(candidates, newerBlock, EvictionProtectBlockRelay, nil)
+	candidates = protectEvictionCandidates(candidates, olderConnection, len(candidates)/2, nil)
+	if len(candidates) == 0 {
+		return
+	}
+	// The candidates are sorted by the time they connected, so the last of each group is its newest peer, and of the
+	// groups with the most peers the one that got there last has the newest peer.
+	groups := make(map[string][]*evictionCandidate)
+	var evictGroup []*evictionCandidate
+	for i := range candidates {
+		c := &candidates[i]
+		group := append(groups[c.netGroup], c)
+		groups[c.netGroup] = group
+		if len(group) >= len(evictGroup) {
+			evictGroup = group
+		}
+	}
+	return evictGroup[len(evictGroup)-1].id, true
+}
+
+// EvictInboundPeer disconnects an inbound peer picked by selectPeerToEvict to make room for a new inbound peer.
+// Whitelisted peers are never evicted. It returns false when there is no peer that can be evicted. It is invoked from
+// the peerHandler goroutine.
+func (n *Node) EvictInboundPeer(state *PeerState) bool {
+	candidates := make([]evictionCandidate, 0, len(state.InboundPeers))
+	for id, sp := range state.InboundPeers {
+		if sp.IsWhitelisted || !sp.Connected() {
+			continue
+		}
+		group := addrmgr.GroupKey(sp.NA())
+		candidates = append(
+			candidates, evictionCandidate{
+				id:            id,
+				connected:     sp.TimeConnected(),
+				minPing:       time.Duration(atomic.LoadInt64(&sp.MinPingMicros)) * time.Microsecond,
+				lastBlockTime: time.Unix(0, atomic.LoadInt64(&sp.LastBlockTime)),
+				lastTxTime:    time.Unix(0, atomic.LoadInt64(&sp.LastTxTime)),
+				relayTxs:      !sp.IsRelayTxDisabled(),
+				netGroup:      group,
+				keyedNetGroup: siphash.Sum64([]byte(group), &n.netGroupKey),
+			},
+		)
+	}
+	id, ok := selectPeerToEvict(candidates)
+	if !ok {
+		return false
+	}
+	sp := state.InboundPeers[id]
+	D.F("evicting inbound peer %s to make room for a new one", sp)
+	// The peer is removed right away so it does not count against the maximum any more.
+	delete(state.InboundPeers, id)
+	sp.Disconnect()
+	return true
+}
@@ -0,0 +1,50 @@
+package chainrpc
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestSelectPeerToEvict ensures that no peer is evicted while they are all protected, and that when a single network
+// group holds most of the inbound slots its newest peer is evicted, unless it relayed a new block.
+func TestSelectPeerToEvict(t *testing.T) {
+	start := time.Unix(1600000000, 0)
+	var honest []evictionCandidate
+	for i := 0; i < 20; i++ {
+		honest = append(
+			honest, evictionCandidate{
+				id:            int32(i),
+				connected:     start.Add(time.Duration(i) * time.Minute),
+				minPing:       100 * time.Millisecond,
+				relayTxs:      true,
+				netGroup:      fmt.Sprintf("honest%d", i),
+				keyedNetGroup: uint64(i),
+			},
+		)
+	}
+	if id, ok := selectPeerToEvict(append([]evictionCandidate(nil), honest[:10]...)); ok {
+		t.Fatalf("peer %d was evicted while all of the peers are protected", id)
+	}
+	// An attacker fills the other slots from a single network group with peers that answer pings faster.
+	candidates := honest
+	for i := 0; i < 30; i++ {
+		candidates = append(
+			candidates, evictionCandidate{
+				id:            int32(100 + i),
+				connected:     start.Add(time.Hour + time.Duration(i)*time.Minute),
+				minPing:       time.Millisecond,
+				relayTxs:      true,
+				netGroup:      "attacker",
+				keyedNetGroup: 1000,
+			},
+		)
+	}
+	if id, ok := selectPeerToEvict(append([]evictionCandidate(nil), candidates...)); !ok || id != 129 {
+		t.Fatalf("evicted peer %d (%v), want the newest peer of the attacker 129", id, ok)
+	}
+	candidates[len(candidates)-1].lastBlockTime = start.Add(2 * time.Hour)
+	if id, ok := selectPeerToEvict(append([]evictionCandidate(nil), candidates...)); !ok || id != 128 {
+		t.Fatalf("evicted peer %d (%v), want 128 as 129 relayed a new block", id, ok)
+	}
+}
@@ -67,6 +67,36 @@ var handlers = handlersT{
 		Cmd:     "*btcjson.GetAddedNodeInfoCmd",
 		ResType: "[]btcjson.GetAddedNodeInfoResultAddr",
 	},
+	{
+		Method:  "getaddressbalance",
+		Handler: "GetAddressBalance",
+		Cmd:     "*btcjson.GetAddressBalanceCmd",
+		ResType: "btcjson.GetAddressBalanceResult",
+	},
+	{
+		Method:  "getaddressdeltas",
+		Handler: "GetAddressDeltas",
+		Cmd:     "*btcjson.GetAddressDeltasCmd",
+		ResType: "[]btcjson.GetAddressDeltasResult",
+	},
+	{
+		Method:  "getaddresstxids",
+		Handler: "GetAddressTxIDs",
+		Cmd:     "*btcjson.GetAddressTxIDsCmd",
+		ResType: "[]string",
+	},
+	{
+		Method:  "getaddressutxos",
+		Handler: "GetAddressUtxos",
+		Cmd:     "*btcjson.GetAddressUtxosCmd",
+		ResType: "[]btcjson.GetAddressUtxosResult",
+	},
+	{
+		Method:  "getalgostats",
+		Handler: "GetAlgoStats",
+		Cmd:     "*btcjson.GetAlgoStatsCmd",
+		ResType: "btcjson.GetAlgoStatsResult",
+	},
 	{
 		Method:  "getbestblock",
 		Handler: "GetBestBlock",
@@ -127,6 +157,12 @@ var handlers = handlersT{
 		Cmd:     "*btcjson.GetCFilterHeaderCmd",
 		ResType: "string",
 	},
+	{
+		Method:  "getchaintips",
+		Handler: "GetChainTips",
+		Cmd:     "*None",
+		ResType: "[]btcjson.GetChainTipsResult",
+	},
 	{
 		Method:  "getconnectioncount",
 		Handler: "GetConnectionCount",
@@ -169,6 +205,12 @@ var handlers = handlersT{
 		Cmd:     "*None",
 		ResType: "btcjson.InfoChainResult0",
 	},
+	{
+		Method:  "getmempoolentry",
+		Handler: "GetMempoolEntry",
+		Cmd:     "*btcjson.GetMempoolEntryCmd",
+		ResType: "btcjson.GetMempoolEntryResult",
+	},
 	{
 		Method:  "getmempoolinfo",
 		Handler: "GetMempoolInfo",
@@ -193,6 +235,12 @@ var handlers = handlersT{
 		Cmd:     "*btcjson.GetNetworkHashPSCmd",
 		ResType: "[]btcjson.GetPeerInfoResult",
 	},
+	{
+		Method:  "getnetworkinfo",
+		Handler: "GetNetworkInfo",
+		Cmd:     "*None",
+		ResType: "btcjson.GetNetworkInfoResult",
+	},
 	{
 		Method:  "getpeerinfo",
 		Handler: "GetPeerInfo",
@@ -217,12 +265,30 @@ var handlers = handlersT{
 		Cmd:     "*btcjson.GetTxOutCmd",
 		ResType: "string",
 	},
+	{
+		Method:  "gettxoutsetinfo",
+		Handler: "GetTxOutSetInfo",
+		Cmd:     "*None",
+		ResType: "btcjson.GetTxOutSetInfoResult",
+	},
+	{
+		Method:  "getwork",
+		Handler: "GetWork",
+		Cmd:     "*btcjson.GetWorkCmd",
+		ResType: "btcjson.GetWorkResult",
+	},
 	{
 		Method:  "help",
 		Handler: "Help",
 		Cmd:     "*btcjson.HelpCmd",
 		ResType: "string",
 	},
+	{
+		Method:  "invalidateblock",
+		Handler: "InvalidateBlock",
+		Cmd:     "*btcjson.InvalidateBlockCmd",
+		ResType: "None",
+	},
 	{
 		Method:  "node",
 		Handler: "Node",
@@ -235,6 +301,18 @@ var handlers = handlersT{
 		Cmd:     "*None",
 		ResType: "None",
 	},
+	{
+		Method:  "preciousblock",
+		Handler: "PreciousBlock",
+		Cmd:     "*btcjson.PreciousBlockCmd",
+		ResType: "None",
+	},
+	{
+		Method:  "reconsiderblock",
+		Handler: "ReconsiderBlock",
+		Cmd:     "*btcjson.ReconsiderBlockCmd",
+		ResType: "None",
+	},
 	{
 		Method:  "searchrawtransactions",
 		Handler: "SearchRawTransactions",
@@ -6,7 +6,9 @@ import (
 	"encoding/hex"
 	"fmt"
 	"math/big"
-	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/p9c/pod/pkg/bits"
@@ -20,6 +22,7 @@ import (
 	"github.com/p9c/pod/pkg/blockchain"
 	"github.com/p9c/pod/pkg/btcjson"
 	"github.com/p9c/pod/pkg/chainhash"
+	"github.com/p9c/pod/pkg/chainrpc/templates"
 	"github.com/p9c/pod/pkg/wire"
 )
 
@@ -65,13 +68,81 @@ func BigToLEUint256(n *big.Int) [Uint256Size]byte {
 	return buf
 }
 
+// GetWorkState houses the work handed out by getwork so that solutions submitted by miners can be matched with the
+// block they were built from.
+//
+// The block for the current previous block is kept in a templates.Message, which holds the difficulty bits, merkle root
+// and transactions for the algorithm version being mined. Every request bumps the extra nonce in the coinbase, and the
+// resulting coinbase signature script is saved keyed by the merkle root it produces.
+type GetWorkState struct {
+	sync.Mutex
+	LastTxUpdate  time.Time
+	LastGenerated time.Time
+	extraNonce    uint64
+	message       *templates.Message
+	blockInfo     map[chainhash.Hash]*workStateBlockInfo
+}
+
+// workStateBlockInfo houses information about how to reconstruct a block from the header submitted by a getwork miner.
+type workStateBlockInfo struct {
+	message         *templates.Message
+	signatureScript []byte
+}
+
+// NewGetWorkState returns a new instance of a GetWorkState with all internal fields initialized and ready to use.
+func NewGetWorkState() *GetWorkState {
+	return &GetWorkState{
+		blockInfo: make(map[chainhash.Hash]*workStateBlockInfo),
+	}
+}
+
+// getWorkAlgoVersion returns the block version of the algorithm getwork hands out work for at a height. A miner can ask
+// for any algorithm of the hard fork at the height, by name or by block version number. Otherwise the work is for the
+// algorithm of the endpoint the miner connects to, which is sha256d or scrypt, so after the plan 9 hard fork, which
+// uses neither, a miner has to ask for one of its algorithms.
+func getWorkAlgoVersion(algo, endpoint string, height int32) (vers int32, e error) {
+	hf := fork.List[fork.GetCurrent(height)]
+	if algo == "" {
+		if params, ok := hf.Algos[endpoint]; ok {
+			return params.Version, nil
+		}
+		e = fmt.Errorf(
+			"%s is not a proof of work algorithm of the %q hard fork at height %d, ask for one of %s with the"+
+				" algo parameter of getwork", endpoint, hf.Name, height, getWorkAlgoNames(height),
+		)
+		return
+	}
+	if v, er := strconv.ParseInt(algo, 10, 32); er == nil {
+		if _, ok := hf.AlgoVers[int32(v)]; ok {
+			return int32(v), nil
+		}
+	}
+	if params, ok := hf.Algos[algo]; ok {
+		return params.Version, nil
+	}
+	e = fmt.Errorf(
+		"%s is not a proof of work algorithm of the %q hard fork at height %d, ask for one of %s",
+		algo, hf.Name, height, getWorkAlgoNames(height),
+	)
+	return
+}
+
+// getWorkAlgoNames returns the names of the algorithms of the hard fork at a height for error messages.
+func getWorkAlgoNames(height int32) string {
+	var names []string
+	for _, vers := range fork.GetAlgoVerSlice(height) {
+		names = append(names, fork.GetAlgoName(vers, height))
+	}
+	return strings.Join(names, ", ")
+}
+
 // HandleGetWork handles the getwork call
 func HandleGetWork(s *Server, cmd interface{}, closeChan qu.C) (interface{}, error) {
 	c := cmd.(*btcjson.GetWorkCmd)
-	if len(s.StateCfg.ActiveMiningAddrs) == 0 {
+	if len(s.StateCfg.ActiveMiningAddrs) == 0 && len(s.StateCfg.ActiveMiningPayouts) == 0 {
 		return nil, &btcjson.RPCError{
 			Code:    btcjson.ErrRPCInternal.Code,
-			Message: "No payment addresses specified via --miningaddr",
+			Message: "No payment addresses specified via --miningaddr or --miningpayouts",
 		}
 	}
 	netwk := (s.Config.Network.V())[0]
@@ -83,49 +154,53 @@ func HandleGetWork(s *Server, cmd interface{}, closeChan qu.C) (interface{}, err
 		}
 	}
 	// No point in generating or accepting work before the chain is synced.
-	latestHeight := s.Cfg.Chain.BestSnapshot().Height
-	if latestHeight != 0 && !s.Cfg.SyncMgr.IsCurrent() {
+	best := s.Cfg.Chain.BestSnapshot()
+	if best.Height != 0 && !s.Cfg.SyncMgr.IsCurrent() {
 		return nil, &btcjson.RPCError{
 			Code:    btcjson.ErrRPCClientInInitialDownload,
 			Message: "Pod is not yet synchronised...",
 		}
 	}
-	state := s.GBTWorkState
+	state := s.GetWorkState
 	state.Lock()
 	defer state.Unlock()
 	if c.Data != nil {
 		return HandleGetWorkSubmission(s, *c.Data)
 	}
-	// Choose a payment address at random.
-	rand.Seed(time.Now().UnixNano())
-	payToAddr := s.StateCfg.ActiveMiningAddrs[rand.Intn(len(s.StateCfg.ActiveMiningAddrs))]
-	lastTxUpdate := s.GBTWorkState.LastTxUpdate
-	latestHash := &s.Cfg.Chain.BestSnapshot().Hash
 	generator := s.Cfg.Generator
-	if state.Template == nil {
-		var e error
-		state.Template, e = generator.NewBlockTemplate(payToAddr, s.Cfg.Algo)
-		if e != nil {
-			return nil, e
+	if generator == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInternal.Code,
+			Message: "No block template generator is available",
 		}
 	}
-	msgBlock := state.Template.Block
-	if msgBlock == nil || state.prevHash == nil ||
-		!state.prevHash.IsEqual(latestHash) ||
+	// The algorithm asked for, or else that of this endpoint, is resolved to the version number that is valid at the
+	// height of the next block, which is the key for the bits, merkle root and transactions in the template message.
+	height := best.Height + 1
+	var algo string
+	if c.Algo != nil {
+		algo = *c.Algo
+	}
+	vers, e := getWorkAlgoVersion(algo, s.Cfg.Algo, height)
+	if e != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: e.Error(),
+		}
+	}
+	lastTxUpdate := generator.GetTxSource().LastUpdated()
+	msg := state.message
+	if msg == nil || msg.PrevBlock != best.Hash || msg.GetTxs()[vers] == nil ||
 		(state.LastTxUpdate != lastTxUpdate &&
 			time.Now().After(state.LastGenerated.Add(time.Minute))) {
-		//	Reset the extra nonce and clear all cached template variations if the best block changed.
-		if state.prevHash != nil && !state.prevHash.IsEqual(latestHash) {
-			e := state.UpdateBlockTemplate(s, false)
-			if e != nil {
-				W.Ln("failed to update block template", e)
-			}
+		// Reset the extra nonce and clear all cached template variations if the best block changed.
+		if msg == nil || msg.PrevBlock != best.Hash {
+			state.extraNonce = 0
+			state.blockInfo = make(map[chainhash.Hash]*workStateBlockInfo)
 		}
-		//	Reset the previous best hash the block template was generated against so any errors below cause the next
-		//	invocation to try again.
-		state.prevHash = nil
-		var e error
-		state.Template, e = generator.NewBlockTemplate(payToAddr, s.Cfg.Algo)
+		// Clear the message so any errors below cause the next invocation to try again.
+		state.message = nil
+		template, e := generator.NewBlockTemplateWithPayouts(s.MiningPayouts(), fork.GetAlgoName(vers, height))
 		if e != nil {
 			errStr := fmt.Sprintf("Failed to create new block template: %v", e)
 			E.Ln(errStr)
@@ -134,61 +209,60 @@ func HandleGetWork(s *Server, cmd interface{}, closeChan qu.C) (interface{}, err
 				Message: errStr,
 			}
 		}
-		msgBlock = state.Template.Block
+		header := template.Block.Header
+		msg = &templates.Message{
+			Height:    template.Height,
+			PrevBlock: header.PrevBlock,
+			Bits:      templates.Diffs{vers: header.Bits},
+			Merkles:   templates.Merkles{vers: header.MerkleRoot},
+			Timestamp: header.Timestamp,
+		}
+		msg.SetTxs(vers, template.Block.Transactions)
 		// Update work state to ensure another block template isn't generated until needed.
-		state.Template.Block = msgBlock
+		state.message = msg
 		state.LastGenerated = time.Now()
 		state.LastTxUpdate = lastTxUpdate
-		state.prevHash = latestHash
-		D.C(
-			func() string {
-				return fmt.Sprintf(
-					"generated block template (timestamp %v, target %064x, "+
-						"merkle root %s, signature script %x)",
-					msgBlock.Header.Timestamp, bits.CompactToBig(
-						msgBlock.Header.
-							Bits,
-					), msgBlock.Header.MerkleRoot,
-					msgBlock.Transactions[0].TxIn[0].SignatureScript,
-				)
-			},
-		)
-	} else {
-		// At this point, there is a saved block template and a new request for work was made but either the available
-		// transactions haven't change or it hasn't been long enough to trigger a new block template to be generated.
-		//
-		// So, update the existing block template and track the variations so each variation can be regenerated if a
-		// caller finds an answer and makes a submission against it. Update the time of the block template to the
-		// current time while accounting for the median time of the past several blocks per the chain consensus rules.
-		e := generator.UpdateBlockTime(0, msgBlock)
-		if e != nil {
-			W.Ln("failed to update block time", e)
-		}
-		// Increment the extra nonce and update the block template with the new value by regenerating the coinbase
-		// script and setting the merkle root to the new value.
 		D.F(
-			"updated block template (timestamp %v, target %064x, "+
-				"merkle root %s, signature script %x)",
-			msgBlock.Header.Timestamp, bits.CompactToBig(msgBlock.Header.Bits),
-			msgBlock.Header.MerkleRoot, msgBlock.Transactions[0].TxIn[0].
-				SignatureScript,
+			"generated %s getwork template at height %d (timestamp %v, target %064x, merkle root %s)",
+			fork.GetAlgoName(vers, height), msg.Height, msg.Timestamp, bits.CompactToBig(msg.Bits[vers]),
+			msg.Merkles[vers],
 		)
 	}
-	// In order to efficiently store the variations of block templates that have been provided to callers save a pointer
-	// to the block as well as the modified signature script keyed by the merkle root. This information, along with the
-	// data that is included in a work submission, is used to rebuild the block before checking the submitted solution.
-	/*
-		coinbaseTx := msgBlock.Transactions[0]
-		state.blockInfo[msgBlock.Header.MerkleRoot] = &workStateBlockInfo{
-			msgBlock:        msgBlock,
-			signatureScript: coinbaseTx.TxIn[0].SignatureScript,
+	// Each request gets a distinct variation of the block, made by updating the time to the current time while
+	// accounting for the median time of the past several blocks per the chain consensus rules, and by incrementing the
+	// extra nonce, which regenerates the coinbase script and sets the merkle root to the new value.
+	msgBlock := &wire.Block{Header: *msg.GenBlockHeader(vers), Transactions: msg.GetTxs()[vers]}
+	if e := generator.UpdateBlockTime(0, msgBlock); e != nil {
+		W.Ln("failed to update block time", e)
+	}
+	state.extraNonce++
+	if e := generator.UpdateExtraNonce(msgBlock, msg.Height, state.extraNonce); E.Chk(e) {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInternal.Code,
+			Message: fmt.Sprintf("Failed to update extra nonce: %v", e),
 		}
-	*/
+	}
+	msg.Timestamp = msgBlock.Header.Timestamp
+	msg.Bits[vers] = msgBlock.Header.Bits
+	msg.Merkles[vers] = msgBlock.Header.MerkleRoot
+	// In order to efficiently store the variations of block templates that have been provided to callers save a pointer
+	// to the message as well as the modified signature script keyed by the merkle root. This information, along with
+	// the data that is included in a work submission, is used to rebuild the block before checking the submitted
+	// solution.
+	state.blockInfo[msgBlock.Header.MerkleRoot] = &workStateBlockInfo{
+		message:         msg,
+		signatureScript: msgBlock.Transactions[0].TxIn[0].SignatureScript,
+	}
+	D.F(
+		"handing out getwork block (timestamp %v, target %064x, merkle root %s, signature script %x)",
+		msgBlock.Header.Timestamp, bits.CompactToBig(msgBlock.Header.Bits), msgBlock.Header.MerkleRoot,
+		msgBlock.Transactions[0].TxIn[0].SignatureScript,
+	)
 	// Serialize the block header into a buffer large enough to hold the the block header and the internal sha256
 	// padding that is added and returned as part of the data below.
 	data := make([]byte, 0, GetworkDataLen)
 	buf := bytes.NewBuffer(data)
-	e := msgBlock.Header.Serialize(buf)
+	e = msgBlock.Header.Serialize(buf)
 	if e != nil {
 		errStr := fmt.Sprintf("Failed to serialize data: %v", e)
 		W.Ln(errStr)
@@ -201,6 +275,7 @@ func HandleGetWork(s *Server, cmd interface{}, closeChan qu.C) (interface{}, err
 	// the first chunk of the block header (sha256 operates on 64-byte chunks) which is before the nonce.
 	//
 	// This allows sophisticated callers to avoid hashing the first chunk over and over while iterating the nonce range.
+	// Miners for algorithms other than sha256d ignore it.
 	data = data[:buf.Len()]
 	midstate := fastsha256.MidState256(data)
 	// Expand the data slice to include the full data buffer and apply the internal sha256 padding which consists of a
@@ -244,7 +319,7 @@ func HandleGetWork(s *Server, cmd interface{}, closeChan qu.C) (interface{}, err
 // HandleGetWorkSubmission is a helper for handleGetWork which deals with the calling submitting work to be verified and
 // processed.
 //
-// This function MUST be called with the RPC workstate locked.
+// This function MUST be called with the getwork state locked.
 func HandleGetWorkSubmission(s *Server, hexData string) (interface{}, error) {
 	// Ensure the provided data is sane.
 	if len(hexData)%2 != 0 {
@@ -290,27 +365,36 @@ func HandleGetWorkSubmission(s *Server, hexData string) (interface{}, error) {
 	// Look up the full block for the provided data based on the merkle root.
 	//
 	// Return false to indicate the solve failed if it's not available.
-	state := s.GBTWorkState
-
-	if state.Template.Block.Header.MerkleRoot.String() == "" {
+	blockInfo, ok := s.GetWorkState.blockInfo[submittedHeader.MerkleRoot]
+	if !ok {
 		D.Ln(
-			"Block submitted via getwork has no matching template for merkle root",
+			"block submitted via getwork has no matching template for merkle root",
 			submittedHeader.MerkleRoot,
 		)
 		return false, nil
 	}
+	msg := blockInfo.message
+	if msg.GetTxs()[submittedHeader.Version] == nil {
+		D.Ln("block submitted via getwork has unknown version", submittedHeader.Version)
+		return false, nil
+	}
 	// Reconstruct the block using the submitted header stored block info.
-	msgBlock := state.Template.Block
+	msgBlock, e := msg.Reconstruct(&submittedHeader)
+	if e != nil {
+		D.Ln("block submitted via getwork is stale:", e)
+		return false, nil
+	}
+	msgBlock.Transactions[0].TxIn[0].SignatureScript = blockInfo.signatureScript
 	block := block2.NewBlock(msgBlock)
-	msgBlock.Header.Timestamp = submittedHeader.Timestamp
-	msgBlock.Header.Nonce = submittedHeader.Nonce
-	msgBlock.Transactions[0].TxIn[0].SignatureScript = state.Template.Block.
-		Transactions[0].TxIn[0].SignatureScript
 	merkles := blockchain.BuildMerkleTreeStore(block.Transactions(), false)
-	msgBlock.Header.MerkleRoot = *merkles.GetRoot()
+	if *merkles.GetRoot() != submittedHeader.MerkleRoot {
+		D.Ln("block submitted via getwork does not match the merkle root of its template")
+		return false, nil
+	}
+	block.SetHeight(msg.Height)
 	// Ensure the submitted block hash is less than the target difficulty.
-	pl := fork.GetMinDiff(s.Cfg.Algo, s.Cfg.Chain.BestSnapshot().Height)
-	e = blockchain.CheckProofOfWork(block, pl, s.Cfg.Chain.BestSnapshot().Height)
+	pl := fork.GetMinDiff(fork.GetAlgoName(submittedHeader.Version, msg.Height), msg.Height)
+	e = blockchain.CheckProofOfWork(block, pl, msg.Height)
 	if e != nil {
 		// Anything other than a rule violation is an unexpected error, so return that error as an internal error.
 		if _, ok := e.(blockchain.RuleError); !ok {
@@ -332,13 +416,10 @@ func HandleGetWorkSubmission(s *Server, hexData string) (interface{}, error) {
 	}
 	// Process this block using the same rules as blocks coming from other nodes. This will in turn relay it to the
 	// network like normal.
-	_, isOrphan, e := s.Cfg.Chain.ProcessBlock(
-		0, block, 0,
-		s.Cfg.Chain.BestSnapshot().Height,
-	)
+	isOrphan, e := s.Cfg.SyncMgr.SubmitBlock(block, blockchain.BFNone)
 	if e != nil || isOrphan {
 		// Anything other than a rule violation is an unexpected error, so return that error as an internal error.
-		if _, ok := e.(blockchain.RuleError); !ok {
+		if _, ok := e.(blockchain.RuleError); !ok && e != nil {
 			return nil, &btcjson.RPCError{
 				Code:    btcjson.ErrRPCInternal.Code,
 				Message: fmt.Sprintf("Unexpected error while processing block: %v", e),
@@ -348,8 +429,7 @@ func HandleGetWorkSubmission(s *Server, hexData string) (interface{}, error) {
 		return false, nil
 	}
 	// The block was accepted.
-	blockSha := block.Hash()
-	I.Ln("block submitted via getwork accepted:", blockSha)
+	I.Ln("block submitted via getwork accepted:", block.Hash())
 	return true, nil
 }
 
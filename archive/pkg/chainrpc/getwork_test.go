@@ -0,0 +1,60 @@
+package chainrpc
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/p9c/pod/pkg/fork"
+)
+
+// TestGetWorkAlgoVersion ensures the getwork listeners get the version of their own algorithm before the plan 9 hard
+// fork unless a miner asks for another one, and that afterwards the plan 9 algorithms are served to miners that ask for
+// them by name or version while the sha256d and scrypt listeners refuse miners that don't.
+func TestGetWorkAlgoVersion(t *testing.T) {
+	if fork.IsTestnet {
+		t.Skip("the plan 9 hard fork activates at the testnet start height on testnet")
+	}
+	p9Height := fork.List[1].ActivationHeight
+	p9Algo := fork.AlgoSlices[1][0]
+	p9Version := strconv.Itoa(int(p9Algo.Version))
+	tests := []struct {
+		name     string
+		algo     string
+		endpoint string
+		height   int32
+		version  int32
+		fails    bool
+	}{
+		{name: "sha256d before plan 9", endpoint: fork.SHA256d, height: 1, version: 2},
+		{name: "scrypt before plan 9", endpoint: fork.Scrypt, height: 1, version: 514},
+		{name: "sha256d at last block before plan 9", endpoint: fork.SHA256d, height: p9Height - 1, version: 2},
+		{name: "scrypt at last block before plan 9", endpoint: fork.Scrypt, height: p9Height - 1, version: 514},
+		{name: "scrypt asked for on sha256d", algo: fork.Scrypt, endpoint: fork.SHA256d, height: 1, version: 514},
+		{name: "version asked for before plan 9", algo: "2", endpoint: fork.Scrypt, height: 1, version: 2},
+		{name: "sha256d at plan 9", endpoint: fork.SHA256d, height: p9Height, fails: true},
+		{name: "scrypt after plan 9", endpoint: fork.Scrypt, height: p9Height + 1000, fails: true},
+		{
+			name: "plan 9 algorithm at plan 9", algo: p9Algo.Name, endpoint: fork.SHA256d, height: p9Height,
+			version: p9Algo.Version,
+		},
+		{
+			name: "plan 9 version after plan 9", algo: p9Version, endpoint: fork.Scrypt, height: p9Height + 1000,
+			version: p9Algo.Version,
+		},
+		{name: "plan 9 algorithm before plan 9", algo: p9Algo.Name, endpoint: fork.SHA256d, height: 1, fails: true},
+		{name: "scrypt asked for at plan 9", algo: fork.Scrypt, endpoint: fork.Scrypt, height: p9Height, fails: true},
+		{name: "unknown algorithm", algo: "x11", endpoint: fork.SHA256d, height: 1, fails: true},
+		{name: "unknown version", algo: "3", endpoint: fork.SHA256d, height: 1, fails: true},
+	}
+	for _, test := range tests {
+		version, e := getWorkAlgoVersion(test.algo, test.endpoint, test.height)
+		switch {
+		case test.fails && e == nil:
+			t.Errorf("%s: got version %d, want an error", test.name, version)
+		case !test.fails && e != nil:
+			t.Errorf("%s: unexpected error: %v", test.name, e)
+		case !test.fails && version != test.version:
+			t.Errorf("%s: got version %d, want %d", test.name, version, test.version)
+		}
+	}
+}
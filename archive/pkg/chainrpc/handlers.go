@@ -13,6 +13,7 @@ import (
 	"github.com/p9c/log"
 	"math/big"
 	"net"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -24,11 +25,14 @@ import (
 	"github.com/p9c/pod/pkg/chainhash"
 	"github.com/p9c/pod/pkg/database"
 	"github.com/p9c/pod/pkg/ecc"
+	"github.com/p9c/pod/pkg/indexers"
 	"github.com/p9c/interrupt"
 	"github.com/p9c/pod/pkg/mempool"
+	"github.com/p9c/pod/pkg/mining"
 	"github.com/p9c/pod/pkg/txscript"
 	"github.com/p9c/pod/pkg/util"
 	"github.com/p9c/pod/pkg/wire"
+	"github.com/p9c/pod/version"
 )
 
 // HandleAddNode handles addnode commands.
@@ -513,6 +517,195 @@ func HandleGetAddedNodeInfo(
 	return results, nil
 }
 
+// addressRequestAddresses returns the decoded addresses of an address index query, or an error if the address index is
+// not enabled or an address is invalid.
+func addressRequestAddresses(s *Server, req *btcjson.AddressRequest) (addrs []btcaddr.Address, e error) {
+	if s.Cfg.AddrIndex == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Address index must be enabled (--addrindex)",
+		}
+	}
+	for _, address := range req.Addresses {
+		var addr btcaddr.Address
+		if addr, e = btcaddr.Decode(address, s.Cfg.ChainParams); E.Chk(e) {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCInvalidAddressOrKey,
+				Message: "Invalid address or key: " + e.Error(),
+			}
+		}
+		addrs = append(addrs, addr)
+	}
+	return
+}
+
+// addressDeltas returns the changes of the balances of the addresses of an address index query in its range of block
+// heights, ordered by height and position in the block.
+func addressDeltas(s *Server, req *btcjson.AddressRequest) (deltas []btcjson.GetAddressDeltasResult, e error) {
+	var addrs []btcaddr.Address
+	if addrs, e = addressRequestAddresses(s, req); e != nil {
+		return
+	}
+	for i, addr := range addrs {
+		var addrDeltas []indexers.AddrDelta
+		if addrDeltas, e = s.Cfg.AddrIndex.AddressDeltas(addr, req.Start, req.End); E.Chk(e) {
+			return nil, InternalRPCError(e.Error(), "Failed to fetch address deltas")
+		}
+		for _, d := range addrDeltas {
+			deltas = append(
+				deltas, btcjson.GetAddressDeltasResult{
+					Satoshis:   d.Amount,
+					TxID:       d.TxHash.String(),
+					Index:      d.Index,
+					BlockIndex: d.TxIndex,
+					Height:     d.Height,
+					Address:    req.Addresses[i],
+				},
+			)
+		}
+	}
+	sort.SliceStable(
+		deltas, func(i, j int) bool {
+			if deltas[i].Height != deltas[j].Height {
+				return deltas[i].Height < deltas[j].Height
+			}
+			return deltas[i].BlockIndex < deltas[j].BlockIndex
+		},
+	)
+	return
+}
+
+// HandleGetAddressBalance implements the getaddressbalance command.
+func HandleGetAddressBalance(s *Server, cmd interface{}, closeChan qu.C) (interface{}, error) {
+	c := cmd.(*btcjson.GetAddressBalanceCmd)
+	addrs, e := addressRequestAddresses(s, &c.Request)
+	if e != nil {
+		return nil, e
+	}
+	result := &btcjson.GetAddressBalanceResult{}
+	for _, addr := range addrs {
+		var balance, received int64
+		if balance, received, e = s.Cfg.AddrIndex.AddressBalance(addr); E.Chk(e) {
+			return nil, InternalRPCError(e.Error(), "Failed to fetch address balance")
+		}
+		result.Balance += balance
+		result.Received += received
+	}
+	return result, nil
+}
+
+// HandleGetAddressDeltas implements the getaddressdeltas command.
+func HandleGetAddressDeltas(s *Server, cmd interface{}, closeChan qu.C) (interface{}, error) {
+	c := cmd.(*btcjson.GetAddressDeltasCmd)
+	deltas, e := addressDeltas(s, &c.Request)
+	if e != nil {
+		return nil, e
+	}
+	return deltas, nil
+}
+
+// HandleGetAddressTxIDs implements the getaddresstxids command.
+func HandleGetAddressTxIDs(s *Server, cmd interface{}, closeChan qu.C) (interface{}, error) {
+	c := cmd.(*btcjson.GetAddressTxIDsCmd)
+	deltas, e := addressDeltas(s, &c.Request)
+	if e != nil {
+		return nil, e
+	}
+	// Every transaction involving an address changes its balance, and the deltas of a transaction are next to each
+	// other as they are ordered by their position in the block.
+	txids := make([]string, 0, len(deltas))
+	seen := make(map[string]struct{}, len(deltas))
+	for _, d := range deltas {
+		if _, ok := seen[d.TxID]; ok {
+			continue
+		}
+		seen[d.TxID] = struct{}{}
+		txids = append(txids, d.TxID)
+	}
+	return txids, nil
+}
+
+// HandleGetAddressUtxos implements the getaddressutxos command.
+func HandleGetAddressUtxos(s *Server, cmd interface{}, closeChan qu.C) (interface{}, error) {
+	c := cmd.(*btcjson.GetAddressUtxosCmd)
+	addrs, e := addressRequestAddresses(s, &c.Request)
+	if e != nil {
+		return nil, e
+	}
+	result := []btcjson.GetAddressUtxosResult{}
+	for i, addr := range addrs {
+		var utxos []indexers.AddrUtxo
+		if utxos, e = s.Cfg.AddrIndex.AddressUtxos(addr); E.Chk(e) {
+			return nil, InternalRPCError(e.Error(), "Failed to fetch address utxos")
+		}
+		for _, u := range utxos {
+			result = append(
+				result, btcjson.GetAddressUtxosResult{
+					Address:     c.Request.Addresses[i],
+					TxID:        u.OutPoint.Hash.String(),
+					OutputIndex: u.OutPoint.Index,
+					Script:      hex.EncodeToString(u.PkScript),
+					Satoshis:    u.Amount,
+					Height:      u.Height,
+				},
+			)
+		}
+	}
+	return result, nil
+}
+
+// HandleGetAlgoStats implements the getalgostats command.
+func HandleGetAlgoStats(s *Server, cmd interface{}, closeChan qu.C) (interface{}, error) {
+	c := cmd.(*btcjson.GetAlgoStatsCmd)
+	window := int32(3600)
+	if c.Blocks != nil {
+		window = int32(*c.Blocks)
+	}
+	if window <= 0 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "the number of blocks must be positive",
+		}
+	}
+	stats, e := s.Cfg.Chain.GetAlgoStats(window)
+	if e != nil {
+		return nil, InternalRPCError(e.Error(), "Failed to calculate algorithm statistics")
+	}
+	result := &btcjson.GetAlgoStatsResult{
+		Height:             stats.Height,
+		Blocks:             stats.Blocks,
+		Timespan:           stats.Timespan,
+		AllTimeAverage:     stats.AllTimeAv,
+		AllTimeDivisor:     stats.AllTimeDiv,
+		QuarterHourDivisor: stats.QHourDiv,
+		HourDivisor:        stats.HourDiv,
+		DayDivisor:         stats.DayDiv,
+		Algos:              make([]btcjson.GetAlgoStatsResultAlgo, 0, len(stats.Algos)),
+	}
+	for _, as := range stats.Algos {
+		ar := btcjson.GetAlgoStatsResultAlgo{
+			Algo:            as.Name,
+			Version:         as.Version,
+			Blocks:          as.Blocks,
+			AverageInterval: as.AverageInterval,
+			TargetInterval:  as.TargetInterval,
+			Adjustment:      as.Adjustment,
+			Bits:            strconv.FormatInt(int64(as.Bits), 16),
+		}
+		// Before the hard fork there is no target for the plan 9 algorithms
+		if as.Bits != 0 {
+			ar.Difficulty = GetDifficultyRatio(as.Bits, s.Cfg.ChainParams, as.Version)
+		}
+		// The blocks of each algorithm are spread over the whole window so the hashrate is the work of the algorithm
+		// over the time span of the window, as getnetworkhashps calculates it for all of the blocks.
+		if stats.Timespan > 0 {
+			ar.NetworkHashPS = new(big.Int).Div(as.Work, big.NewInt(stats.Timespan)).Int64()
+		}
+		result.Algos = append(result.Algos, ar)
+	}
+	return result, nil
+}
+
 // HandleGetBestBlock implements the getbestblock command.
 func HandleGetBestBlock(s *Server, cmd interface{}, closeChan qu.C) (interface{}, error) {
 	// All other "get block" commands give either the height, the hash, or both but require the block SHA. This gets
@@ -561,6 +754,12 @@ func HandleGetBlock(s *Server, cmd interface{}, closeChan qu.C) (interface{}, er
 			return e
 		},
 	)
+	if dbErr, ok := e.(database.DBError); ok && dbErr.ErrorCode == database.ErrBlockPruned {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Block not available (pruned data)",
+		}
+	}
 	if e != nil {
 		return nil, &btcjson.RPCError{
 			Code:    btcjson.ErrRPCBlockNotFound,
@@ -665,7 +864,8 @@ func HandleGetBlockChainInfo(
 		BestBlockHash: chainSnapshot.Hash.String(),
 		Difficulty:    GetDifficultyRatio(chainSnapshot.Bits, params, 2),
 		MedianTime:    chainSnapshot.MedianTime.Unix(),
-		Pruned:        false,
+		Pruned:        chain.Pruned(),
+		PruneHeight:   chain.PruneHeight(),
 		// Bip9SoftForks: make(map[string]*btcjson.Bip9SoftForkDescription),
 	}
 	// Next, populate the response with information describing the current status of soft-forks deployed via the
@@ -925,13 +1125,13 @@ func HandleGetBlockTemplate(
 func HandleGetBlockTemplateLongPoll(
 	s *Server,
 	longPollID string,
-	useCoinbaseValue bool, closeChan qu.C,
+	useCoinbaseValue bool, payouts []mining.Payout, closeChan qu.C,
 ) (interface{}, error) {
 	state := s.GBTWorkState
 	state.Lock()
 	// The state unlock is intentionally not deferred here since it needs to be manually unlocked before waiting for a
 	// notification about block template changes.
-	if e := state.UpdateBlockTemplate(s, useCoinbaseValue); E.Chk(e) {
+	if e := state.UpdateBlockTemplate(s, useCoinbaseValue, payouts); E.Chk(e) {
 		state.Unlock()
 		return nil, e
 	}
@@ -981,7 +1181,7 @@ func HandleGetBlockTemplateLongPoll(
 	// Get the lastest block template
 	state.Lock()
 	defer state.Unlock()
-	if e = state.UpdateBlockTemplate(s, useCoinbaseValue); E.Chk(e) {
+	if e = state.UpdateBlockTemplate(s, useCoinbaseValue, payouts); E.Chk(e) {
 		return nil, e
 	}
 	// Include whether or not it is valid to submit work against the old block template depending on whether or not a
@@ -1065,6 +1265,8 @@ func HandleGetBlockTemplateRequest(
 	// Extract the relevant passed capabilities and restrict the result to either a coinbase value or a coinbase
 	// transaction object depending on the request. Default to only providing a coinbase value.
 	useCoinbaseValue := true
+	// Payouts requested by the caller take the place of the ones configured with --miningpayouts.
+	payouts := s.StateCfg.ActiveMiningPayouts
 	if request != nil {
 		var hasCoinbaseValue, hasCoinbaseTxn bool
 		for _, capability := range request.Capabilities {
@@ -1078,15 +1280,31 @@ func HandleGetBlockTemplateRequest(
 		if hasCoinbaseTxn && !hasCoinbaseValue {
 			useCoinbaseValue = false
 		}
+		// Requested payouts are only of use in a coinbase transaction created by the Server.
+		if len(request.Payouts) > 0 {
+			useCoinbaseValue = false
+			payouts = make([]mining.Payout, len(request.Payouts))
+			for i := range request.Payouts {
+				var e error
+				if payouts[i], e = mining.NewPayout(
+					request.Payouts[i].Address, request.Payouts[i].Weight, s.Cfg.ChainParams,
+				); e != nil {
+					return nil, &btcjson.RPCError{
+						Code:    btcjson.ErrRPCInvalidParameter,
+						Message: e.Error(),
+					}
+				}
+			}
+		}
 	}
 	// When a coinbase transaction has been requested, respond with an error if there are no addresses to pay the
 	// created block template to.
-	if !useCoinbaseValue && len(s.StateCfg.ActiveMiningAddrs) == 0 {
+	if !useCoinbaseValue && len(payouts) == 0 && len(s.StateCfg.ActiveMiningAddrs) == 0 {
 		return nil, &btcjson.RPCError{
 			Code: btcjson.ErrRPCInternal.Code,
 			Message: "A coinbase transaction has been requested, " +
 				"but the Server has not been configured with " +
-				"any payment addresses via --miningaddr",
+				"any payment addresses via --miningaddr or --miningpayouts",
 		}
 	}
 	// Return an error if there are no peers connected since there is no way to relay a found block or receive
@@ -1113,7 +1331,7 @@ func HandleGetBlockTemplateRequest(
 	if request != nil && request.LongPollID != "" {
 		return HandleGetBlockTemplateLongPoll(
 			s, request.LongPollID,
-			useCoinbaseValue, closeChan,
+			useCoinbaseValue, payouts, closeChan,
 		)
 	}
 	// Protect concurrent access when updating block templates.
@@ -1126,7 +1344,7 @@ func HandleGetBlockTemplateRequest(
 	//
 	// Otherwise, the timestamp for the existing block template is updated (and possibly the difficulty on testnet per
 	// the consesus rules).
-	if e := workState.UpdateBlockTemplate(s, useCoinbaseValue); E.Chk(e) {
+	if e := workState.UpdateBlockTemplate(s, useCoinbaseValue, payouts); E.Chk(e) {
 		return nil, e
 	}
 	return workState.BlockTemplateResult(useCoinbaseValue, nil)
@@ -1226,6 +1444,21 @@ func HandleGetCFilterHeader(s *Server, cmd interface{}, closeChan qu.C) (interfa
 	return hash.String(), nil
 }
 
+// HandleGetChainTips implements the getchaintips command.
+func HandleGetChainTips(s *Server, cmd interface{}, closeChan qu.C) (interface{}, error) {
+	tips := s.Cfg.Chain.ChainTips()
+	ret := make([]btcjson.GetChainTipsResult, len(tips))
+	for i := range tips {
+		ret[i] = btcjson.GetChainTipsResult{
+			Height:    tips[i].Height,
+			Hash:      tips[i].Hash.String(),
+			BranchLen: tips[i].BranchLen,
+			Status:    tips[i].Status.String(),
+		}
+	}
+	return ret, nil
+}
+
 // HandleGetConnectionCount implements the getconnectioncount command.
 func HandleGetConnectionCount(s *Server, cmd interface{}, closeChan qu.C) (interface{}, error) {
 	return s.Cfg.ConnMgr.ConnectedCount(), nil
@@ -1521,6 +1754,23 @@ func HandleGetInfo(
 	return ret, nil
 }
 
+// HandleGetMempoolEntry implements the getmempoolentry command.
+func HandleGetMempoolEntry(s *Server, cmd interface{}, closeChan qu.C) (interface{}, error) {
+	c := cmd.(*btcjson.GetMempoolEntryCmd)
+	txHash, e := chainhash.NewHashFromStr(c.TxID)
+	if e != nil {
+		return nil, DecodeHexError(c.TxID)
+	}
+	entry, e := s.Cfg.TxMemPool.MempoolEntry(txHash)
+	if e != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCNoTxInfo,
+			Message: "Transaction not in mempool",
+		}
+	}
+	return entry, nil
+}
+
 // HandleGetMempoolInfo implements the getmempoolinfo command.
 func HandleGetMempoolInfo(s *Server, cmd interface{}, closeChan qu.C) (interface{}, error) {
 	mempoolTxns := s.Cfg.TxMemPool.TxDescs()
@@ -1787,6 +2037,79 @@ func HandleGetNetworkHashPS(s *Server, cmd interface{}, closeChan qu.C) (interfa
 	return hashesPerSec.Int64(), nil
 }
 
+// HandleGetNetworkInfo implements the getnetworkinfo command.
+func HandleGetNetworkInfo(s *Server, cmd interface{}, closeChan qu.C) (interface{}, error) {
+	msg := wire.MsgVersion{UserAgent: wire.DefaultUserAgent}
+	if e := msg.AddUserAgent(
+		UserAgentName, UserAgentVersion,
+		s.Config.UserAgentComments.S()...,
+	); E.Chk(e) {
+	}
+	// Outbound connections to ipv4 and ipv6 addresses use the general proxy if one is configured, onion addresses use
+	// the onion specific proxy in preference to it and are only reachable when tor is enabled and some proxy is set.
+	proxy := s.Config.ProxyAddress.V()
+	onionProxy := s.Config.OnionProxyAddress.V()
+	if onionProxy == "" {
+		onionProxy = proxy
+	}
+	onionReachable := s.Config.OnionEnabled.True() && onionProxy != ""
+	if !onionReachable {
+		onionProxy = ""
+	}
+	torIsolation := s.Config.TorIsolation.True()
+	networks := []btcjson.NetworksResult{
+		{
+			Name:                      "ipv4",
+			Reachable:                 true,
+			Proxy:                     proxy,
+			ProxyRandomizeCredentials: torIsolation && proxy != "",
+		},
+		{
+			Name:                      "ipv6",
+			Reachable:                 true,
+			Proxy:                     proxy,
+			ProxyRandomizeCredentials: torIsolation && proxy != "",
+		},
+		{
+			Name:                      "onion",
+			Limited:                   !onionReachable,
+			Reachable:                 onionReachable,
+			Proxy:                     onionProxy,
+			ProxyRandomizeCredentials: torIsolation && onionReachable,
+		},
+	}
+	localAddrs := s.Cfg.ConnMgr.LocalAddresses()
+	localAddresses := make([]btcjson.LocalAddressesResult, len(localAddrs))
+	for i := range localAddrs {
+		localAddresses[i] = btcjson.LocalAddressesResult{
+			Address: localAddrs[i].NetAddress.IP.String(),
+			Port:    localAddrs[i].NetAddress.Port,
+			Score:   int32(localAddrs[i].Score),
+		}
+	}
+	relayFee := s.StateCfg.ActiveMinRelayTxFee.ToDUO()
+	ret := &btcjson.GetNetworkInfoResult{
+		Version: int32(
+			1000000*version.Major +
+				10000*version.Minor +
+				100*version.Patch,
+		),
+		SubVersion:      msg.UserAgent,
+		ProtocolVersion: int32(MaxProtocolVersion),
+		LocalServices:   fmt.Sprintf("%016x", uint64(s.Cfg.ConnMgr.Services())),
+		LocalRelay:      s.Config.BlocksOnly.False(),
+		TimeOffset:      int64(s.Cfg.TimeSource.Offset().Seconds()),
+		Connections:     s.Cfg.ConnMgr.ConnectedCount(),
+		NetworkActive:   true,
+		Networks:        networks,
+		RelayFee:        relayFee,
+		// the pool does not replace transactions, so the fee increment a replacement would need is simply the relay fee
+		IncrementalFee: relayFee,
+		LocalAddresses: localAddresses,
+	}
+	return ret, nil
+}
+
 // HandleGetPeerInfo implements the getpeerinfo command.
 func HandleGetPeerInfo(s *Server, cmd interface{}, closeChan qu.C) (interface{}, error) {
 	peers := s.Cfg.ConnMgr.ConnectedPeers()
@@ -2086,6 +2409,21 @@ func HandleGetTxOut(s *Server, cmd interface{}, closeChan qu.C) (interface{}, er
 	return txOutReply, nil
 }
 
+// HandleGetTxOutSetInfo handles gettxoutsetinfo commands.
+func HandleGetTxOutSetInfo(s *Server, cmd interface{}, closeChan qu.C) (interface{}, error) {
+	stats := s.Cfg.Chain.UtxoStats()
+	return &btcjson.GetTxOutSetInfoResult{
+		Height:         stats.Height,
+		BestBlock:      stats.Hash.String(),
+		Transactions:   stats.Transactions,
+		TxOuts:         stats.Outputs,
+		BogoSize:       stats.BogoSize,
+		SerializedSize: stats.SerializedSize,
+		MuHash:         stats.SetHash.String(),
+		TotalAmount:    amt.Amount(stats.TotalAmount).ToDUO(),
+	}, nil
+}
+
 // HandleHelp implements the help command.
 func HandleHelp(s *Server, cmd interface{}, closeChan qu.C) (
 	interface{}, error,
@@ -2138,6 +2476,22 @@ func HandleHelp(s *Server, cmd interface{}, closeChan qu.C) (
 	return help, nil
 }
 
+// HandleInvalidateBlock implements the invalidateblock command.
+func HandleInvalidateBlock(s *Server, cmd interface{}, closeChan qu.C) (interface{}, error) {
+	c := cmd.(*btcjson.InvalidateBlockCmd)
+	hash, e := KnownBlockHash(s, c.BlockHash)
+	if e != nil {
+		return nil, e
+	}
+	if e = s.Cfg.Chain.InvalidateBlock(hash); E.Chk(e) {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCDatabase,
+			Message: e.Error(),
+		}
+	}
+	return nil, nil
+}
+
 // HandleNode handles node commands.
 func HandleNode(s *Server, cmd interface{}, closeChan qu.C) (interface{}, error) {
 	var msg string
@@ -2253,6 +2607,38 @@ func HandlePing(s *Server, cmd interface{}, closeChan qu.C) (
 	return nil, nil
 }
 
+// HandlePreciousBlock implements the preciousblock command.
+func HandlePreciousBlock(s *Server, cmd interface{}, closeChan qu.C) (interface{}, error) {
+	c := cmd.(*btcjson.PreciousBlockCmd)
+	hash, e := KnownBlockHash(s, c.BlockHash)
+	if e != nil {
+		return nil, e
+	}
+	if e = s.Cfg.Chain.PreciousBlock(hash); E.Chk(e) {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCDatabase,
+			Message: e.Error(),
+		}
+	}
+	return nil, nil
+}
+
+// HandleReconsiderBlock implements the reconsiderblock command.
+func HandleReconsiderBlock(s *Server, cmd interface{}, closeChan qu.C) (interface{}, error) {
+	c := cmd.(*btcjson.ReconsiderBlockCmd)
+	hash, e := KnownBlockHash(s, c.BlockHash)
+	if e != nil {
+		return nil, e
+	}
+	if e = s.Cfg.Chain.ReconsiderBlock(hash); E.Chk(e) {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCDatabase,
+			Message: e.Error(),
+		}
+	}
+	return nil, nil
+}
+
 // HandleSearchRawTransactions implements the searchrawtransactions command.
 // TODO: simplify this, break it up
 func HandleSearchRawTransactions(s *Server, cmd interface{}, closeChan qu.C) (interface{}, error) {
@@ -0,0 +1,105 @@
+package chainrpc
+
+import (
+	"testing"
+
+	"github.com/p9c/opts/binary"
+	"github.com/p9c/opts/list"
+	"github.com/p9c/opts/meta"
+	"github.com/p9c/opts/text"
+
+	"github.com/p9c/pod/cmd/node/active"
+	"github.com/p9c/pod/pkg/addrmgr"
+	"github.com/p9c/pod/pkg/blockchain"
+	"github.com/p9c/pod/pkg/btcjson"
+	"github.com/p9c/pod/pkg/mempool"
+	"github.com/p9c/pod/pkg/wire"
+	"github.com/p9c/pod/pod/config"
+)
+
+// fakeConnManager is a connection manager for the handlers that don't need peers, the methods that are not overridden
+// panic.
+type fakeConnManager struct {
+	ServerConnManager
+	services wire.ServiceFlag
+	local    []addrmgr.LocalAddress
+}
+
+func (cm *fakeConnManager) ConnectedCount() int32 {
+	return 3
+}
+
+func (cm *fakeConnManager) Services() wire.ServiceFlag {
+	return cm.services
+}
+
+func (cm *fakeConnManager) LocalAddresses() []addrmgr.LocalAddress {
+	return cm.local
+}
+
+// TestHandleGetNetworkInfo ensures the services are reported as 16 hex digits like bitcoind does, and that onion
+// addresses are only reachable through a proxy when tor is enabled.
+func TestHandleGetNetworkInfo(t *testing.T) {
+	s := &Server{
+		Cfg: ServerConfig{
+			ConnMgr: &fakeConnManager{
+				services: wire.SFNodeNetwork | wire.SFNodeCF,
+				local: []addrmgr.LocalAddress{
+					{NetAddress: wire.NewNetAddressIPPort([]byte{1, 2, 3, 4}, 11047, 0), Score: 2},
+				},
+			},
+			TimeSource: blockchain.NewMedianTime(),
+		},
+		StateCfg: &active.Config{ActiveMinRelayTxFee: 1000},
+		Config: &config.Config{
+			UserAgentComments: list.New(meta.Data{}, nil),
+			ProxyAddress:      text.New(meta.Data{}, ""),
+			OnionProxyAddress: text.New(meta.Data{}, "127.0.0.1:9050"),
+			OnionEnabled:      binary.New(meta.Data{}, true),
+			TorIsolation:      binary.New(meta.Data{}, false),
+			BlocksOnly:        binary.New(meta.Data{}, false),
+		},
+	}
+	res, e := HandleGetNetworkInfo(s, nil, nil)
+	if e != nil {
+		t.Fatalf("getnetworkinfo: %v", e)
+	}
+	info := res.(*btcjson.GetNetworkInfoResult)
+	if info.LocalServices != "0000000000000041" {
+		t.Errorf("getnetworkinfo: got local services %q, want %q", info.LocalServices, "0000000000000041")
+	}
+	if info.Connections != 3 || !info.LocalRelay {
+		t.Errorf("getnetworkinfo: got %d connections and local relay %v", info.Connections, info.LocalRelay)
+	}
+	if len(info.LocalAddresses) != 1 || info.LocalAddresses[0].Address != "1.2.3.4" ||
+		info.LocalAddresses[0].Port != 11047 {
+		t.Errorf("getnetworkinfo: unexpected local addresses %+v", info.LocalAddresses)
+	}
+	for _, network := range info.Networks {
+		if network.Name == "onion" && (!network.Reachable || network.Proxy != "127.0.0.1:9050") {
+			t.Errorf("getnetworkinfo: unexpected onion network %+v", network)
+		}
+	}
+}
+
+// TestHandleGetMempoolEntry ensures that a malformed transaction hash and a transaction that is not in the pool are
+// reported with the errors bitcoind uses.
+func TestHandleGetMempoolEntry(t *testing.T) {
+	s := &Server{Cfg: ServerConfig{TxMemPool: mempool.New(&mempool.Config{})}}
+	tests := []struct {
+		txid string
+		code btcjson.RPCErrorCode
+	}{
+		{txid: "not hex", code: btcjson.ErrRPCDecodeHexString},
+		{
+			txid: "0000000000000000000000000000000000000000000000000000000000000001",
+			code: btcjson.ErrRPCNoTxInfo,
+		},
+	}
+	for _, test := range tests {
+		_, e := HandleGetMempoolEntry(s, &btcjson.GetMempoolEntryCmd{TxID: test.txid}, nil)
+		if re, ok := e.(*btcjson.RPCError); !ok || re.Code != test.code {
+			t.Errorf("getmempoolentry %s: got error %v, want code %d", test.txid, e, test.code)
+		}
+	}
+}
@@ -10,8 +10,7 @@ import (
 	"net"
 	"time"
 	
-	"github.com/niubaoshu/gotiny"
-	
+	"github.com/p9c/pod/pkg/chainrpc/codec"
 	"github.com/p9c/pod/pkg/util/routeable"
 )
 
@@ -46,9 +45,7 @@ func Get(count int32, version int32, height int32, id string) []byte {
 		Nonce:   int32(binary.LittleEndian.Uint32(nonce)),
 		ID:      id,
 	}
-	srlz := gotiny.Marshal(&hr)
-	// D.S(srlz)
-	return srlz
+	return hr.Serialize()
 	// return Container{*simplebuffer.Serializers{
 	// 	Time.New().Put(time.Now()),
 	// 	IPs.GetListenable(),
@@ -60,6 +57,55 @@ func Get(count int32, version int32, height int32, id string) []byte {
 	// }.CreateContainer(Magic)}
 }
 
+// Serialize a hashrate report, in the encoding described in package codec
+func (hr *Hashrate) Serialize() []byte {
+	return codec.NewEncoder(Magic).
+		PutTime(1, hr.Time).
+		PutBytes(2, hr.IP).
+		PutInt64(3, int64(hr.Count)).
+		PutInt32(4, hr.Version).
+		PutInt32(5, hr.Height).
+		PutInt32(6, hr.Nonce).
+		PutString(7, hr.ID).
+		Encode()
+}
+
+// Deserialize a hashrate report
+func Deserialize(b []byte) (hr *Hashrate, e error) {
+	var dec *codec.Decoder
+	if dec, e = codec.NewDecoder(Magic, b); E.Chk(e) {
+		return
+	}
+	hr = &Hashrate{}
+	var count int64
+	for tag, v, ok := dec.Next(); ok && e == nil; tag, v, ok = dec.Next() {
+		switch tag {
+		case 1:
+			hr.Time, e = codec.Time(v)
+		case 2:
+			hr.IP = append(net.IP{}, v...)
+		case 3:
+			count, e = codec.Int64(v)
+			hr.Count = int(count)
+		case 4:
+			hr.Version, e = codec.Int32(v)
+		case 5:
+			hr.Height, e = codec.Int32(v)
+		case 6:
+			hr.Nonce, e = codec.Int32(v)
+		case 7:
+			hr.ID = string(v)
+		}
+	}
+	if e == nil {
+		e = dec.Err()
+	}
+	if E.Chk(e) {
+		return nil, e
+	}
+	return
+}
+
 //
 // // LoadContainer takes a message byte slice payload and loads it into a container
 // // ready to be decoded
@@ -1,8 +1,9 @@
 package p2padvt
 
 import (
-	"github.com/niubaoshu/gotiny"
+	"sort"
 	
+	"github.com/p9c/pod/pkg/chainrpc/codec"
 	"github.com/p9c/pod/pkg/util"
 	"github.com/p9c/pod/pkg/util/routeable"
 )
@@ -31,6 +32,45 @@ func Get(uuid uint64, listeners string) []byte {
 		UUID: uuid,
 		// Services: node.Services,
 	}
-	ad := gotiny.Marshal(&adv)
-	return ad
+	return adv.Serialize()
+}
+
+// Serialize an advertisment, in the encoding described in package codec
+func (a *Advertisment) Serialize() []byte {
+	ips := make([]string, 0, len(a.IPs))
+	for ip := range a.IPs {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+	enc := codec.NewEncoder(Magic)
+	for i := range ips {
+		enc.PutString(1, ips[i])
+	}
+	return enc.PutUint16(2, a.P2P).PutUint64(3, a.UUID).Encode()
+}
+
+// Deserialize an advertisment
+func Deserialize(b []byte) (a *Advertisment, e error) {
+	var dec *codec.Decoder
+	if dec, e = codec.NewDecoder(Magic, b); E.Chk(e) {
+		return
+	}
+	a = &Advertisment{IPs: make(map[string]struct{})}
+	for tag, v, ok := dec.Next(); ok && e == nil; tag, v, ok = dec.Next() {
+		switch tag {
+		case 1:
+			a.IPs[string(v)] = struct{}{}
+		case 2:
+			a.P2P, e = codec.Uint16(v)
+		case 3:
+			a.UUID, e = codec.Uint64(v)
+		}
+	}
+	if e == nil {
+		e = dec.Err()
+	}
+	if E.Chk(e) {
+		return nil, e
+	}
+	return
 }
@@ -0,0 +1,442 @@
+package chainrpc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	js "encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/p9c/qu"
+
+	"github.com/p9c/pod/pkg/amt"
+	"github.com/p9c/pod/pkg/blockchain"
+	"github.com/p9c/pod/pkg/btcjson"
+	"github.com/p9c/pod/pkg/chainhash"
+	"github.com/p9c/pod/pkg/txscript"
+	"github.com/p9c/pod/pkg/util"
+	"github.com/p9c/pod/pkg/wire"
+)
+
+// The REST interface serves the same lookups as bitcoind's /rest/ without authentication, as it only reads public data
+// of the block chain and mempool. The format of a response is chosen by the extension of the requested path:
+//   /rest/block/<hash>.<bin|hex|json>
+//   /rest/block/notxdetails/<hash>.<bin|hex|json>
+//   /rest/headers/<count>/<hash>.<bin|hex|json>
+//   /rest/blockhashbyheight/<height>.<bin|hex|json>
+//   /rest/tx/<txid>.<bin|hex|json>
+//   /rest/chaininfo.json
+//   /rest/mempool/info.json
+//   /rest/mempool/contents.json
+//   /rest/getutxos[/checkmempool]/<txid>-<n>/<txid>-<n>/....<bin|hex|json>
+// The JSON of blocks is the same as the getblock RPC returns, which includes the name of the proof of work algorithm of
+// the block and its proof of work hash.
+const (
+	// RESTPrefix is the path the REST interface is served under
+	RESTPrefix = "/rest/"
+	// restMaxHeaders is the maximum number of headers a headers request returns
+	restMaxHeaders = 2000
+	// restMaxOutpoints is the maximum number of outpoints a getutxos request can look up
+	restMaxOutpoints = 15
+)
+
+// restFormat is the format of a REST response
+type restFormat int
+
+const (
+	restBinary restFormat = iota
+	restHex
+	restJSON
+)
+
+// restFormats maps the extensions of requested paths to the formats of the responses
+var restFormats = map[string]restFormat{
+	"bin":  restBinary,
+	"hex":  restHex,
+	"json": restJSON,
+}
+
+// restError is an error of a REST request with the HTTP status to respond with
+type restError struct {
+	status  int
+	message string
+}
+
+// Error returns the message of the error
+func (e *restError) Error() string {
+	return e.message
+}
+
+// restErr converts an error returned by an RPC handler to the error of a REST request
+func restErr(e error) *restError {
+	if re, ok := e.(*restError); ok {
+		return re
+	}
+	rpcErr, ok := e.(*btcjson.RPCError)
+	if !ok {
+		return &restError{status: http.StatusInternalServerError, message: e.Error()}
+	}
+	// the codes of unknown blocks, transactions and addresses are the same, so they are compared one by one
+	switch code := rpcErr.Code; {
+	case code == btcjson.ErrRPCBlockNotFound, code == btcjson.ErrRPCNoTxInfo,
+		code == btcjson.ErrRPCInvalidAddressOrKey, code == btcjson.ErrRPCMisc:
+		return &restError{status: http.StatusNotFound, message: rpcErr.Message}
+	case code == btcjson.ErrRPCInvalidParameter, code == btcjson.ErrRPCDecodeHexString:
+		return &restError{status: http.StatusBadRequest, message: rpcErr.Message}
+	}
+	return &restError{status: http.StatusInternalServerError, message: rpcErr.Message}
+}
+
+// restBadRequest returns the error of a malformed REST request
+func restBadRequest(message string) *restError {
+	return &restError{status: http.StatusBadRequest, message: message}
+}
+
+// restRPCHandler is an RPC handler whose result a REST request responds with
+type restRPCHandler func(*Server, interface{}, qu.C) (interface{}, error)
+
+// restResponse is the response to a REST request, either raw bytes that are encoded as binary or hex, or a value that
+// is encoded as JSON
+type restResponse struct {
+	raw    []byte
+	result interface{}
+}
+
+// RegisterREST serves the REST interface under RESTPrefix on the mux when it is enabled by the REST option.
+func (s *Server) RegisterREST(mux *http.ServeMux) {
+	if s.Config.REST.True() {
+		mux.HandleFunc(RESTPrefix, s.HandleREST)
+	}
+}
+
+// HandleREST serves a request of the REST interface.
+func (s *Server) HandleREST(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "405 Method not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+	// Limit the number of connections to max allowed.
+	if s.LimitConnections(w, r.RemoteAddr) {
+		return
+	}
+	// Keep track of the number of connected clients.
+	s.IncrementClients()
+	defer s.DecrementClients()
+	path := strings.TrimPrefix(r.URL.Path, RESTPrefix)
+	dot := strings.LastIndex(path, ".")
+	if dot < 0 {
+		http.Error(w, "output format not found (available: bin, hex, json)", http.StatusBadRequest)
+		return
+	}
+	format, ok := restFormats[path[dot+1:]]
+	if !ok {
+		http.Error(w, "output format not found (available: bin, hex, json)", http.StatusBadRequest)
+		return
+	}
+	parts := strings.Split(path[:dot], "/")
+	var res *restResponse
+	var e error
+	switch {
+	case parts[0] == "block" && len(parts) == 2:
+		res, e = s.restBlock(parts[1], format, true)
+	case parts[0] == "block" && len(parts) == 3 && parts[1] == "notxdetails":
+		res, e = s.restBlock(parts[2], format, false)
+	case parts[0] == "headers" && len(parts) == 3:
+		res, e = s.restHeaders(parts[1], parts[2], format)
+	case parts[0] == "blockhashbyheight" && len(parts) == 2:
+		res, e = s.restBlockHashByHeight(parts[1], format)
+	case parts[0] == "tx" && len(parts) == 2:
+		res, e = s.restTx(parts[1], format)
+	case parts[0] == "chaininfo" && len(parts) == 1 && format == restJSON:
+		res, e = s.restRPC(HandleGetBlockChainInfo, nil)
+	case parts[0] == "mempool" && len(parts) == 2 && parts[1] == "info" && format == restJSON:
+		res, e = s.restRPC(HandleGetMempoolInfo, nil)
+	case parts[0] == "mempool" && len(parts) == 2 && parts[1] == "contents" && format == restJSON:
+		res, e = s.restRPC(HandleGetRawMempool, &btcjson.GetRawMempoolCmd{Verbose: btcjson.Bool(true)})
+	case parts[0] == "getutxos":
+		res, e = s.restGetUtxos(parts[1:], format)
+	default:
+		http.Error(w, "invalid URI format", http.StatusNotFound)
+		return
+	}
+	if e != nil {
+		re := restErr(e)
+		http.Error(w, re.message, re.status)
+		return
+	}
+	var body []byte
+	switch format {
+	case restBinary:
+		w.Header().Set("Content-Type", "application/octet-stream")
+		body = res.raw
+	case restHex:
+		w.Header().Set("Content-Type", "text/plain")
+		body = []byte(hex.EncodeToString(res.raw) + "\n")
+	case restJSON:
+		w.Header().Set("Content-Type", "application/json")
+		if body, e = js.Marshal(res.result); E.Chk(e) {
+			http.Error(w, e.Error(), http.StatusInternalServerError)
+			return
+		}
+		body = append(body, '\n')
+	}
+	if _, e = w.Write(body); E.Chk(e) {
+	}
+}
+
+// restRPC returns the result of an RPC handler as the response to a REST request
+func (s *Server) restRPC(handler restRPCHandler, cmd interface{}) (res *restResponse, e error) {
+	var result interface{}
+	if result, e = handler(s, cmd, nil); e != nil {
+		return
+	}
+	return &restResponse{result: result}, nil
+}
+
+// restHexRPC returns the hex string result of an RPC handler decoded as the raw bytes of the response to a REST request
+func (s *Server) restHexRPC(handler restRPCHandler, cmd interface{}) (res *restResponse, e error) {
+	var result interface{}
+	if result, e = handler(s, cmd, nil); e != nil {
+		return
+	}
+	hexString, ok := result.(string)
+	if !ok {
+		return nil, &restError{status: http.StatusInternalServerError, message: "unexpected result type"}
+	}
+	res = &restResponse{}
+	if res.raw, e = hex.DecodeString(hexString); E.Chk(e) {
+		return nil, e
+	}
+	return
+}
+
+// restBlock looks up a block, with the details of its transactions in JSON if txDetails is set.
+func (s *Server) restBlock(hash string, format restFormat, txDetails bool) (*restResponse, error) {
+	if format != restJSON {
+		return s.restHexRPC(HandleGetBlock, &btcjson.GetBlockCmd{Hash: hash, Verbose: btcjson.Bool(false)})
+	}
+	return s.restRPC(
+		HandleGetBlock, &btcjson.GetBlockCmd{
+			Hash: hash, Verbose: btcjson.Bool(true), VerboseTx: btcjson.Bool(txDetails),
+		},
+	)
+}
+
+// restHeaders looks up count headers of the main chain starting with the header of the block with the hash.
+func (s *Server) restHeaders(countString, hashString string, format restFormat) (res *restResponse, e error) {
+	count, e := strconv.Atoi(countString)
+	if e != nil || count < 1 || count > restMaxHeaders {
+		return nil, restBadRequest("header count out of range: " + countString)
+	}
+	var hash *chainhash.Hash
+	if hash, e = chainhash.NewHashFromStr(hashString); e != nil {
+		return nil, restBadRequest("invalid hash: " + hashString)
+	}
+	chain := s.Cfg.Chain
+	var hashes []*chainhash.Hash
+	if chain.MainChainHasBlock(hash) {
+		var height int32
+		if height, e = chain.BlockHeightByHash(hash); E.Chk(e) {
+			return
+		}
+		best := chain.BestSnapshot().Height
+		for ; len(hashes) < count && height <= best; height++ {
+			var h *chainhash.Hash
+			if h, e = chain.BlockHashByHeight(height); e != nil {
+				// The chain was reorganized while the headers were looked up.
+				break
+			}
+			hashes = append(hashes, h)
+		}
+	}
+	if format == restJSON {
+		results := make([]interface{}, 0, len(hashes))
+		for _, h := range hashes {
+			var result interface{}
+			if result, e = HandleGetBlockHeader(
+				s, &btcjson.GetBlockHeaderCmd{Hash: h.String(), Verbose: btcjson.Bool(true)}, nil,
+			); e != nil {
+				return
+			}
+			results = append(results, result)
+		}
+		return &restResponse{result: results}, nil
+	}
+	var buf bytes.Buffer
+	for _, h := range hashes {
+		var header wire.BlockHeader
+		if header, e = chain.HeaderByHash(h); E.Chk(e) {
+			return
+		}
+		if e = header.Serialize(&buf); E.Chk(e) {
+			return
+		}
+	}
+	return &restResponse{raw: buf.Bytes()}, nil
+}
+
+// restBlockHashByHeight looks up the hash of the block of the main chain at the height.
+func (s *Server) restBlockHashByHeight(heightString string, format restFormat) (res *restResponse, e error) {
+	height, e := strconv.ParseInt(heightString, 10, 32)
+	if e != nil || height < 0 {
+		return nil, restBadRequest("invalid height: " + heightString)
+	}
+	var hash *chainhash.Hash
+	if hash, e = s.Cfg.Chain.BlockHashByHeight(int32(height)); e != nil {
+		return nil, &restError{status: http.StatusNotFound, message: "block height out of range"}
+	}
+	if format == restJSON {
+		return &restResponse{result: map[string]string{"blockhash": hash.String()}}, nil
+	}
+	return &restResponse{raw: hash[:]}, nil
+}
+
+// restTx looks up a transaction in the mempool, or in the blocks if the transaction index is enabled.
+func (s *Server) restTx(txid string, format restFormat) (*restResponse, error) {
+	verbose := 0
+	if format == restJSON {
+		verbose = 1
+		return s.restRPC(HandleGetRawTransaction, &btcjson.GetRawTransactionCmd{Txid: txid, Verbose: &verbose})
+	}
+	return s.restHexRPC(HandleGetRawTransaction, &btcjson.GetRawTransactionCmd{Txid: txid, Verbose: &verbose})
+}
+
+// restUtxo is an unspent output in the JSON response of a getutxos request
+type restUtxo struct {
+	Height       int32                      `json:"height"`
+	Value        float64                    `json:"value"`
+	ScriptPubKey btcjson.ScriptPubKeyResult `json:"scriptPubKey"`
+}
+
+// restUtxosResult is the JSON response of a getutxos request
+type restUtxosResult struct {
+	ChainHeight  int32      `json:"chainHeight"`
+	ChainTipHash string     `json:"chaintipHash"`
+	Bitmap       string     `json:"bitmap"`
+	Utxos        []restUtxo `json:"utxos"`
+}
+
+// restGetUtxos looks up which of the outpoints are unspent, and the outputs of those that are. When the first part of
+// the path is checkmempool, outputs of transactions in the mempool are included and outputs spent by transactions in
+// the mempool are not.
+func (s *Server) restGetUtxos(parts []string, format restFormat) (res *restResponse, e error) {
+	checkMempool := len(parts) > 0 && parts[0] == "checkmempool"
+	if checkMempool {
+		parts = parts[1:]
+	}
+	if len(parts) == 0 || len(parts) > restMaxOutpoints {
+		return nil, restBadRequest("the number of outpoints must be between 1 and " + strconv.Itoa(restMaxOutpoints))
+	}
+	outpoints := make([]wire.OutPoint, len(parts))
+	for i, part := range parts {
+		dash := strings.LastIndex(part, "-")
+		if dash < 0 {
+			return nil, restBadRequest("parse error: " + part)
+		}
+		var hash *chainhash.Hash
+		if hash, e = chainhash.NewHashFromStr(part[:dash]); e != nil {
+			return nil, restBadRequest("parse error: " + part)
+		}
+		var index uint64
+		if index, e = strconv.ParseUint(part[dash+1:], 10, 32); e != nil {
+			return nil, restBadRequest("parse error: " + part)
+		}
+		outpoints[i] = wire.OutPoint{Hash: *hash, Index: uint32(index)}
+	}
+	best := s.Cfg.Chain.BestSnapshot()
+	bitmap := make([]byte, (len(outpoints)+7)/8)
+	var bitmapString []byte
+	var utxos []*wire.TxOut
+	var heights []int32
+	for i, outpoint := range outpoints {
+		// Outputs of transactions in the mempool have no height, which bitcoind marks with the largest one.
+		var txOut *wire.TxOut
+		height := int32(0x7fffffff)
+		var mempoolTx *util.Tx
+		spentInMempool := checkMempool && s.Cfg.TxMemPool.CheckSpend(outpoint) != nil
+		if checkMempool && !spentInMempool {
+			mempoolTx, _ = s.Cfg.TxMemPool.FetchTransaction(&outpoint.Hash)
+		}
+		switch {
+		case spentInMempool:
+		case mempoolTx != nil:
+			if int(outpoint.Index) < len(mempoolTx.MsgTx().TxOut) {
+				txOut = mempoolTx.MsgTx().TxOut[outpoint.Index]
+			}
+		default:
+			var entry *blockchain.UtxoEntry
+			if entry, e = s.Cfg.Chain.FetchUtxoEntry(outpoint); E.Chk(e) {
+				return nil, e
+			}
+			if entry != nil && !entry.IsSpent() {
+				txOut = wire.NewTxOut(entry.Amount(), entry.PkScript())
+				height = entry.BlockHeight()
+			}
+		}
+		if txOut == nil {
+			bitmapString = append(bitmapString, '0')
+			continue
+		}
+		bitmap[i/8] |= 1 << uint(i%8)
+		bitmapString = append(bitmapString, '1')
+		utxos = append(utxos, txOut)
+		heights = append(heights, height)
+	}
+	if format == restJSON {
+		result := &restUtxosResult{
+			ChainHeight:  best.Height,
+			ChainTipHash: best.Hash.String(),
+			Bitmap:       string(bitmapString),
+			Utxos:        make([]restUtxo, len(utxos)),
+		}
+		for i, txOut := range utxos {
+			// The disassembled script contains [error] inline if it doesn't fully parse, and an error extracting the
+			// addresses only means there is no more information about the script.
+			disbuf, _ := txscript.DisasmString(txOut.PkScript)
+			scriptClass, addrs, reqSigs, _ := txscript.ExtractPkScriptAddrs(txOut.PkScript, s.Cfg.ChainParams)
+			addresses := make([]string, len(addrs))
+			for j, addr := range addrs {
+				addresses[j] = addr.EncodeAddress()
+			}
+			result.Utxos[i] = restUtxo{
+				Height: heights[i],
+				Value:  amt.Amount(txOut.Value).ToDUO(),
+				ScriptPubKey: btcjson.ScriptPubKeyResult{
+					Asm:       disbuf,
+					Hex:       hex.EncodeToString(txOut.PkScript),
+					ReqSigs:   int32(reqSigs),
+					Type:      scriptClass.String(),
+					Addresses: addresses,
+				},
+			}
+		}
+		return &restResponse{result: result}, nil
+	}
+	// The binary response is serialized the same as bitcoind does it: the chain height and tip hash, the bitmap, and
+	// the outputs each preceded by a zero transaction version and the height.
+	var buf bytes.Buffer
+	var b [8]byte
+	binary.LittleEndian.PutUint32(b[:], uint32(best.Height))
+	buf.Write(b[:4])
+	buf.Write(best.Hash[:])
+	if e = wire.WriteVarBytes(&buf, 0, bitmap); E.Chk(e) {
+		return
+	}
+	if e = wire.WriteVarInt(&buf, 0, uint64(len(utxos))); E.Chk(e) {
+		return
+	}
+	for i, txOut := range utxos {
+		binary.LittleEndian.PutUint32(b[:], 0)
+		buf.Write(b[:4])
+		binary.LittleEndian.PutUint32(b[:], uint32(heights[i]))
+		buf.Write(b[:4])
+		binary.LittleEndian.PutUint64(b[:], uint64(txOut.Value))
+		buf.Write(b[:])
+		if e = wire.WriteVarBytes(&buf, 0, txOut.PkScript); E.Chk(e) {
+			return
+		}
+	}
+	return &restResponse{raw: buf.Bytes()}, nil
+}
@@ -0,0 +1,213 @@
+package chainrpc
+
+import (
+	"bytes"
+	"encoding/hex"
+	js "encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/p9c/opts/binary"
+	"github.com/p9c/opts/integer"
+	"github.com/p9c/opts/meta"
+
+	"github.com/p9c/pod/pkg/blockchain"
+	"github.com/p9c/pod/pkg/btcjson"
+	"github.com/p9c/pod/pkg/chaincfg"
+	"github.com/p9c/pod/pkg/chainhash"
+	"github.com/p9c/pod/pkg/database"
+	_ "github.com/p9c/pod/pkg/database/ffldb"
+	"github.com/p9c/pod/pkg/mempool"
+	"github.com/p9c/pod/pod/config"
+)
+
+// restServer returns a server with a chain of only the genesis block, an empty mempool and no transaction index and REST
+// enabled or not, and the mux it serves the REST interface on.
+func restServer(t *testing.T, enabled bool) (s *Server, mux *http.ServeMux, cleanup func()) {
+	params := &chaincfg.MainNetParams
+	dir, e := ioutil.TempDir("", "resttest")
+	if e != nil {
+		t.Fatal(e)
+	}
+	db, e := database.Create("ffldb", filepath.Join(dir, "db"), params.Net)
+	if e != nil {
+		os.RemoveAll(dir)
+		t.Fatal(e)
+	}
+	cleanup = func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+	chain, e := blockchain.New(
+		&blockchain.Config{DB: db, ChainParams: params, TimeSource: blockchain.NewMedianTime()},
+	)
+	if e != nil {
+		cleanup()
+		t.Fatal(e)
+	}
+	s = &Server{
+		Cfg: ServerConfig{
+			Chain:       chain,
+			DB:          db,
+			ChainParams: params,
+			TimeSource:  blockchain.NewMedianTime(),
+			TxMemPool:   mempool.New(&mempool.Config{ChainParams: params}),
+		},
+		Config: &config.Config{
+			REST:          binary.New(meta.Data{}, enabled),
+			RPCMaxClients: integer.New(meta.Data{}, 10, 1, 100),
+		},
+	}
+	mux = http.NewServeMux()
+	s.RegisterREST(mux)
+	return
+}
+
+// restGet requests the path from the mux and returns the status and body of the response
+func restGet(mux *http.ServeMux, method, path string) (status int, body []byte) {
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	return w.Code, w.Body.Bytes()
+}
+
+// TestREST checks the binary, hex and JSON responses of the REST interface for the lookups of blocks, headers and
+// block hashes, the errors for unknown blocks and malformed requests, and that nothing is served when it is disabled.
+func TestREST(t *testing.T) {
+	s, mux, cleanup := restServer(t, true)
+	defer cleanup()
+	genesis := s.Cfg.ChainParams.GenesisBlock
+	genesisHash := genesis.BlockHash()
+	var genesisBytes, headerBytes bytes.Buffer
+	if e := genesis.Serialize(&genesisBytes); e != nil {
+		t.Fatal(e)
+	}
+	if e := genesis.Header.Serialize(&headerBytes); e != nil {
+		t.Fatal(e)
+	}
+	unknown := chainhash.HashH(genesisHash[:])
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+		body   []byte
+	}{
+		{"block bin", "GET", "/rest/block/" + genesisHash.String() + ".bin", 200, genesisBytes.Bytes()},
+		{
+			"block hex", "GET", "/rest/block/" + genesisHash.String() + ".hex", 200,
+			[]byte(hex.EncodeToString(genesisBytes.Bytes()) + "\n"),
+		},
+		{
+			"block notxdetails bin", "GET", "/rest/block/notxdetails/" + genesisHash.String() + ".bin", 200,
+			genesisBytes.Bytes(),
+		},
+		{"headers bin", "GET", "/rest/headers/5/" + genesisHash.String() + ".bin", 200, headerBytes.Bytes()},
+		{
+			"headers hex", "GET", "/rest/headers/1/" + genesisHash.String() + ".hex", 200,
+			[]byte(hex.EncodeToString(headerBytes.Bytes()) + "\n"),
+		},
+		{"blockhashbyheight bin", "GET", "/rest/blockhashbyheight/0.bin", 200, genesisHash[:]},
+		{
+			"blockhashbyheight hex", "GET", "/rest/blockhashbyheight/0.hex", 200,
+			[]byte(hex.EncodeToString(genesisHash[:]) + "\n"),
+		},
+		{
+			"blockhashbyheight json", "GET", "/rest/blockhashbyheight/0.json", 200,
+			[]byte(`{"blockhash":"` + genesisHash.String() + `"}` + "\n"),
+		},
+		// headers of blocks that are not in the main chain are an empty list, as bitcoind responds
+		{"headers unknown", "GET", "/rest/headers/5/" + unknown.String() + ".bin", 200, []byte{}},
+		{"headers unknown json", "GET", "/rest/headers/5/" + unknown.String() + ".json", 200, []byte("[]\n")},
+		{"block unknown bin", "GET", "/rest/block/" + unknown.String() + ".bin", 404, nil},
+		{"block unknown hex", "GET", "/rest/block/" + unknown.String() + ".hex", 404, nil},
+		{"block unknown json", "GET", "/rest/block/" + unknown.String() + ".json", 404, nil},
+		// transactions that are not in the mempool can't be looked up without the transaction index
+		{"tx unknown bin", "GET", "/rest/tx/" + unknown.String() + ".bin", 404, nil},
+		{"tx unknown hex", "GET", "/rest/tx/" + unknown.String() + ".hex", 404, nil},
+		{"tx unknown json", "GET", "/rest/tx/" + unknown.String() + ".json", 404, nil},
+		{"tx invalid txid", "GET", "/rest/tx/xyz.bin", 400, nil},
+		{"blockhashbyheight out of range", "GET", "/rest/blockhashbyheight/1.bin", 404, nil},
+		{"block invalid hash", "GET", "/rest/block/xyz.bin", 400, nil},
+		{"headers invalid count", "GET", "/rest/headers/0/" + genesisHash.String() + ".bin", 400, nil},
+		{"blockhashbyheight invalid", "GET", "/rest/blockhashbyheight/-1.bin", 400, nil},
+		{"unknown format", "GET", "/rest/block/" + genesisHash.String() + ".xml", 400, nil},
+		{"no format", "GET", "/rest/block/" + genesisHash.String(), 400, nil},
+		{"chaininfo not json", "GET", "/rest/chaininfo.bin", 404, nil},
+		{"unknown path", "GET", "/rest/blocks/" + genesisHash.String() + ".bin", 404, nil},
+		{"post", "POST", "/rest/block/" + genesisHash.String() + ".bin", 405, nil},
+	}
+	for _, test := range tests {
+		status, body := restGet(mux, test.method, test.path)
+		if status != test.status {
+			t.Errorf("%s: got status %d, want %d: %s", test.name, status, test.status, body)
+			continue
+		}
+		if test.body != nil && !bytes.Equal(body, test.body) {
+			t.Errorf("%s: got body %q, want %q", test.name, body, test.body)
+		}
+	}
+	// the JSON of blocks is the one of the getblock RPC, with the transactions as ids or in full
+	for _, txDetails := range []bool{false, true} {
+		path := "/rest/block/" + genesisHash.String() + ".json"
+		if !txDetails {
+			path = "/rest/block/notxdetails/" + genesisHash.String() + ".json"
+		}
+		status, body := restGet(mux, "GET", path)
+		if status != 200 {
+			t.Fatalf("%s: got status %d: %s", path, status, body)
+		}
+		var result btcjson.GetBlockVerboseResult
+		if e := js.Unmarshal(body, &result); e != nil {
+			t.Fatalf("%s: %v", path, e)
+		}
+		if result.Hash != genesisHash.String() || result.Height != 0 || result.PowAlgo == "" {
+			t.Errorf("%s: unexpected block %+v", path, result)
+		}
+		coinbase := genesis.Transactions[0].TxHash().String()
+		if txDetails && (len(result.RawTx) != 1 || result.RawTx[0].Txid != coinbase) {
+			t.Errorf("%s: unexpected transactions %+v", path, result.RawTx)
+		}
+		if !txDetails && (len(result.Tx) != 1 || result.Tx[0] != coinbase) {
+			t.Errorf("%s: unexpected transaction ids %v", path, result.Tx)
+		}
+	}
+	status, body := restGet(mux, "GET", "/rest/headers/1/"+genesisHash.String()+".json")
+	var headers []btcjson.GetBlockHeaderVerboseResult
+	if status != 200 {
+		t.Fatalf("headers json: got status %d: %s", status, body)
+	}
+	if e := js.Unmarshal(body, &headers); e != nil {
+		t.Fatal(e)
+	}
+	if len(headers) != 1 || headers[0].Hash != genesisHash.String() {
+		t.Errorf("headers json: unexpected headers %+v", headers)
+	}
+	// unspent outputs of transactions that are not in the utxo set are marked missing in the bitmap
+	status, body = restGet(mux, "GET", "/rest/getutxos/"+unknown.String()+"-0.json")
+	var utxos restUtxosResult
+	if status != 200 {
+		t.Fatalf("getutxos json: got status %d: %s", status, body)
+	}
+	if e := js.Unmarshal(body, &utxos); e != nil {
+		t.Fatal(e)
+	}
+	if utxos.Bitmap != "0" || len(utxos.Utxos) != 0 || utxos.ChainTipHash != genesisHash.String() {
+		t.Errorf("getutxos json: unexpected result %+v", utxos)
+	}
+	status, body = restGet(mux, "GET", "/rest/getutxos/"+unknown.String()+"-0.bin")
+	// chain height, tip hash, the one byte bitmap and no outputs
+	want := append(append([]byte{0, 0, 0, 0}, genesisHash[:]...), 1, 0, 0)
+	if status != 200 || !bytes.Equal(body, want) {
+		t.Errorf("getutxos bin: got status %d and body %x, want %x", status, body, want)
+	}
+	// nothing is served under the prefix when the REST interface is disabled
+	_, disabled, disabledCleanup := restServer(t, false)
+	defer disabledCleanup()
+	if status, _ = restGet(disabled, "GET", "/rest/block/"+genesisHash.String()+".bin"); status != 404 {
+		t.Errorf("disabled REST: got status %d, want 404", status)
+	}
+}
@@ -3,6 +3,7 @@ package chainrpc
 import (
 	"sync/atomic"
 
+	"github.com/p9c/pod/pkg/addrmgr"
 	"github.com/p9c/pod/pkg/block"
 
 	"github.com/p9c/pod/pkg/blockchain"
@@ -149,6 +150,20 @@ func (cm *ConnManager) NetTotals() (uint64, uint64) {
 	return cm.Server.NetTotals()
 }
 
+// LocalAddresses returns the local addresses the server advertises to its peers along with their scores.
+//
+// This function is safe for concurrent access and is part of the RPCServerConnManager interface implementation.
+func (cm *ConnManager) LocalAddresses() []addrmgr.LocalAddress {
+	return cm.Server.AddrManager.LocalAddresses()
+}
+
+// Services returns the service flags the server advertises to its peers.
+//
+// This function is safe for concurrent access and is part of the RPCServerConnManager interface implementation.
+func (cm *ConnManager) Services() wire.ServiceFlag {
+	return cm.Server.Services
+}
+
 // ConnectedPeers returns an array consisting of all connected peers.
 //
 // This function is safe for concurrent access and is part of the RPCServerConnManager interface implementation.
@@ -67,6 +67,16 @@ type (
 	GenerateRes struct { Res *[]string; Err error }
 	// GetAddedNodeInfoRes is the result from a call to GetAddedNodeInfo
 	GetAddedNodeInfoRes struct { Res *[]btcjson.GetAddedNodeInfoResultAddr; Err error }
+	// GetAddressBalanceRes is the result from a call to GetAddressBalance
+	GetAddressBalanceRes struct { Res *btcjson.GetAddressBalanceResult; Err error }
+	// GetAddressDeltasRes is the result from a call to GetAddressDeltas
+	GetAddressDeltasRes struct { Res *[]btcjson.GetAddressDeltasResult; Err error }
+	// GetAddressTxIDsRes is the result from a call to GetAddressTxIDs
+	GetAddressTxIDsRes struct { Res *[]string; Err error }
+	// GetAddressUtxosRes is the result from a call to GetAddressUtxos
+	GetAddressUtxosRes struct { Res *[]btcjson.GetAddressUtxosResult; Err error }
+	// GetAlgoStatsRes is the result from a call to GetAlgoStats
+	GetAlgoStatsRes struct { Res *btcjson.GetAlgoStatsResult; Err error }
 	// GetBestBlockRes is the result from a call to GetBestBlock
 	GetBestBlockRes struct { Res *btcjson.GetBestBlockResult; Err error }
 	// GetBestBlockHashRes is the result from a call to GetBestBlockHash
@@ -87,6 +97,8 @@ type (
 	GetCFilterRes struct { Res *string; Err error }
 	// GetCFilterHeaderRes is the result from a call to GetCFilterHeader
 	GetCFilterHeaderRes struct { Res *string; Err error }
+	// GetChainTipsRes is the result from a call to GetChainTips
+	GetChainTipsRes struct { Res *[]btcjson.GetChainTipsResult; Err error }
 	// GetConnectionCountRes is the result from a call to GetConnectionCount
 	GetConnectionCountRes struct { Res *int32; Err error }
 	// GetCurrentNetRes is the result from a call to GetCurrentNet
@@ -101,6 +113,8 @@ type (
 	GetHeadersRes struct { Res *[]string; Err error }
 	// GetInfoRes is the result from a call to GetInfo
 	GetInfoRes struct { Res *btcjson.InfoChainResult0; Err error }
+	// GetMempoolEntryRes is the result from a call to GetMempoolEntry
+	GetMempoolEntryRes struct { Res *btcjson.GetMempoolEntryResult; Err error }
 	// GetMempoolInfoRes is the result from a call to GetMempoolInfo
 	GetMempoolInfoRes struct { Res *btcjson.GetMempoolInfoResult; Err error }
 	// GetMiningInfoRes is the result from a call to GetMiningInfo
@@ -109,6 +123,8 @@ type (
 	GetNetTotalsRes struct { Res *btcjson.GetNetTotalsResult; Err error }
 	// GetNetworkHashPSRes is the result from a call to GetNetworkHashPS
 	GetNetworkHashPSRes struct { Res *[]btcjson.GetPeerInfoResult; Err error }
+	// GetNetworkInfoRes is the result from a call to GetNetworkInfo
+	GetNetworkInfoRes struct { Res *btcjson.GetNetworkInfoResult; Err error }
 	// GetPeerInfoRes is the result from a call to GetPeerInfo
 	GetPeerInfoRes struct { Res *[]btcjson.GetPeerInfoResult; Err error }
 	// GetRawMempoolRes is the result from a call to GetRawMempool
@@ -117,12 +133,22 @@ type (
 	GetRawTransactionRes struct { Res *string; Err error }
 	// GetTxOutRes is the result from a call to GetTxOut
 	GetTxOutRes struct { Res *string; Err error }
+	// GetTxOutSetInfoRes is the result from a call to GetTxOutSetInfo
+	GetTxOutSetInfoRes struct { Res *btcjson.GetTxOutSetInfoResult; Err error }
+	// GetWorkRes is the result from a call to GetWork
+	GetWorkRes struct { Res *btcjson.GetWorkResult; Err error }
 	// HelpRes is the result from a call to Help
 	HelpRes struct { Res *string; Err error }
+	// InvalidateBlockRes is the result from a call to InvalidateBlock
+	InvalidateBlockRes struct { Res *None; Err error }
 	// NodeRes is the result from a call to Node
 	NodeRes struct { Res *None; Err error }
 	// PingRes is the result from a call to Ping
 	PingRes struct { Res *None; Err error }
+	// PreciousBlockRes is the result from a call to PreciousBlock
+	PreciousBlockRes struct { Res *None; Err error }
+	// ReconsiderBlockRes is the result from a call to ReconsiderBlock
+	ReconsiderBlockRes struct { Res *None; Err error }
 	// ResetChainRes is the result from a call to ResetChain
 	ResetChainRes struct { Res *None; Err error }
 	// RestartRes is the result from a call to Restart
@@ -184,6 +210,21 @@ var RPCHandlersBeforeInit = map[string]CommandHandler{
 	"getaddednodeinfo":{ 
 		Fn: HandleGetAddedNodeInfo, Call: make(chan API, 32), 
 		Result: func() API { return API{Ch: make(chan GetAddedNodeInfoRes)} }}, 
+	"getaddressbalance":{ 
+		Fn: HandleGetAddressBalance, Call: make(chan API, 32), 
+		Result: func() API { return API{Ch: make(chan GetAddressBalanceRes)} }}, 
+	"getaddressdeltas":{ 
+		Fn: HandleGetAddressDeltas, Call: make(chan API, 32), 
+		Result: func() API { return API{Ch: make(chan GetAddressDeltasRes)} }}, 
+	"getaddresstxids":{ 
+		Fn: HandleGetAddressTxIDs, Call: make(chan API, 32), 
+		Result: func() API { return API{Ch: make(chan GetAddressTxIDsRes)} }}, 
+	"getaddressutxos":{ 
+		Fn: HandleGetAddressUtxos, Call: make(chan API, 32), 
+		Result: func() API { return API{Ch: make(chan GetAddressUtxosRes)} }}, 
+	"getalgostats":{ 
+		Fn: HandleGetAlgoStats, Call: make(chan API, 32), 
+		Result: func() API { return API{Ch: make(chan GetAlgoStatsRes)} }}, 
 	"getbestblock":{ 
 		Fn: HandleGetBestBlock, Call: make(chan API, 32), 
 		Result: func() API { return API{Ch: make(chan GetBestBlockRes)} }}, 
@@ -214,6 +255,9 @@ var RPCHandlersBeforeInit = map[string]CommandHandler{
 	"getcfilterheader":{ 
 		Fn: HandleGetCFilterHeader, Call: make(chan API, 32), 
 		Result: func() API { return API{Ch: make(chan GetCFilterHeaderRes)} }}, 
+	"getchaintips":{ 
+		Fn: HandleGetChainTips, Call: make(chan API, 32), 
+		Result: func() API { return API{Ch: make(chan GetChainTipsRes)} }}, 
 	"getconnectioncount":{ 
 		Fn: HandleGetConnectionCount, Call: make(chan API, 32), 
 		Result: func() API { return API{Ch: make(chan GetConnectionCountRes)} }}, 
@@ -235,6 +279,9 @@ var RPCHandlersBeforeInit = map[string]CommandHandler{
 	"getinfo":{ 
 		Fn: HandleGetInfo, Call: make(chan API, 32), 
 		Result: func() API { return API{Ch: make(chan GetInfoRes)} }}, 
+	"getmempoolentry":{ 
+		Fn: HandleGetMempoolEntry, Call: make(chan API, 32), 
+		Result: func() API { return API{Ch: make(chan GetMempoolEntryRes)} }}, 
 	"getmempoolinfo":{ 
 		Fn: HandleGetMempoolInfo, Call: make(chan API, 32), 
 		Result: func() API { return API{Ch: make(chan GetMempoolInfoRes)} }}, 
@@ -247,6 +294,9 @@ var RPCHandlersBeforeInit = map[string]CommandHandler{
 	"getnetworkhashps":{ 
 		Fn: HandleGetNetworkHashPS, Call: make(chan API, 32), 
 		Result: func() API { return API{Ch: make(chan GetNetworkHashPSRes)} }}, 
+	"getnetworkinfo":{ 
+		Fn: HandleGetNetworkInfo, Call: make(chan API, 32), 
+		Result: func() API { return API{Ch: make(chan GetNetworkInfoRes)} }}, 
 	"getpeerinfo":{ 
 		Fn: HandleGetPeerInfo, Call: make(chan API, 32), 
 		Result: func() API { return API{Ch: make(chan GetPeerInfoRes)} }}, 
@@ -259,15 +309,30 @@ var RPCHandlersBeforeInit = map[string]CommandHandler{
 	"gettxout":{ 
 		Fn: HandleGetTxOut, Call: make(chan API, 32), 
 		Result: func() API { return API{Ch: make(chan GetTxOutRes)} }}, 
+	"gettxoutsetinfo":{ 
+		Fn: HandleGetTxOutSetInfo, Call: make(chan API, 32), 
+		Result: func() API { return API{Ch: make(chan GetTxOutSetInfoRes)} }}, 
+	"getwork":{ 
+		Fn: HandleGetWork, Call: make(chan API, 32), 
+		Result: func() API { return API{Ch: make(chan GetWorkRes)} }}, 
 	"help":{ 
 		Fn: HandleHelp, Call: make(chan API, 32), 
 		Result: func() API { return API{Ch: make(chan HelpRes)} }}, 
+	"invalidateblock":{ 
+		Fn: HandleInvalidateBlock, Call: make(chan API, 32), 
+		Result: func() API { return API{Ch: make(chan InvalidateBlockRes)} }}, 
 	"node":{ 
 		Fn: HandleNode, Call: make(chan API, 32), 
 		Result: func() API { return API{Ch: make(chan NodeRes)} }}, 
 	"ping":{ 
 		Fn: HandlePing, Call: make(chan API, 32), 
 		Result: func() API { return API{Ch: make(chan PingRes)} }}, 
+	"preciousblock":{ 
+		Fn: HandlePreciousBlock, Call: make(chan API, 32), 
+		Result: func() API { return API{Ch: make(chan PreciousBlockRes)} }}, 
+	"reconsiderblock":{ 
+		Fn: HandleReconsiderBlock, Call: make(chan API, 32), 
+		Result: func() API { return API{Ch: make(chan ReconsiderBlockRes)} }}, 
 	"resetchain":{ 
 		Fn: HandleResetChain, Call: make(chan API, 32), 
 		Result: func() API { return API{Ch: make(chan ResetChainRes)} }}, 
@@ -601,6 +666,211 @@ func (a API) GetAddedNodeInfoWait(cmd *btcjson.GetAddedNodeInfoCmd) (out *[]btcj
 	return
 }
 
+// GetAddressBalance calls the method with the given parameters
+func (a API) GetAddressBalance(cmd *btcjson.GetAddressBalanceCmd) (e error) {
+	RPCHandlers["getaddressbalance"].Call <-API{a.Ch, cmd, nil}
+	return
+}
+
+// GetAddressBalanceChk checks if a new message arrived on the result channel and
+// returns true if it does, as well as storing the value in the Result field
+func (a API) GetAddressBalanceChk() (isNew bool) {
+	select {
+	case o := <-a.Ch.(chan GetAddressBalanceRes):
+		if o.Err != nil {
+			a.Result = o.Err
+		} else {
+			a.Result = o.Res
+		}
+		isNew = true
+	default:
+	}
+	return
+}
+
+// GetAddressBalanceGetRes returns a pointer to the value in the Result field
+func (a API) GetAddressBalanceGetRes() (out *btcjson.GetAddressBalanceResult, e error) {
+	out, _ = a.Result.(*btcjson.GetAddressBalanceResult)
+	e, _ = a.Result.(error)
+	return 
+}
+
+// GetAddressBalanceWait calls the method and blocks until it returns or 5 seconds passes
+func (a API) GetAddressBalanceWait(cmd *btcjson.GetAddressBalanceCmd) (out *btcjson.GetAddressBalanceResult, e error) {
+	RPCHandlers["getaddressbalance"].Call <-API{a.Ch, cmd, nil}
+	select {
+	case <-time.After(time.Second*5):
+		break
+	case o := <-a.Ch.(chan GetAddressBalanceRes):
+		out, e = o.Res, o.Err
+	}
+	return
+}
+
+// GetAddressDeltas calls the method with the given parameters
+func (a API) GetAddressDeltas(cmd *btcjson.GetAddressDeltasCmd) (e error) {
+	RPCHandlers["getaddressdeltas"].Call <-API{a.Ch, cmd, nil}
+	return
+}
+
+// GetAddressDeltasChk checks if a new message arrived on the result channel and
+// returns true if it does, as well as storing the value in the Result field
+func (a API) GetAddressDeltasChk() (isNew bool) {
+	select {
+	case o := <-a.Ch.(chan GetAddressDeltasRes):
+		if o.Err != nil {
+			a.Result = o.Err
+		} else {
+			a.Result = o.Res
+		}
+		isNew = true
+	default:
+	}
+	return
+}
+
+// GetAddressDeltasGetRes returns a pointer to the value in the Result field
+func (a API) GetAddressDeltasGetRes() (out *[]btcjson.GetAddressDeltasResult, e error) {
+	out, _ = a.Result.(*[]btcjson.GetAddressDeltasResult)
+	e, _ = a.Result.(error)
+	return 
+}
+
+// GetAddressDeltasWait calls the method and blocks until it returns or 5 seconds passes
+func (a API) GetAddressDeltasWait(cmd *btcjson.GetAddressDeltasCmd) (out *[]btcjson.GetAddressDeltasResult, e error) {
+	RPCHandlers["getaddressdeltas"].Call <-API{a.Ch, cmd, nil}
+	select {
+	case <-time.After(time.Second*5):
+		break
+	case o := <-a.Ch.(chan GetAddressDeltasRes):
+		out, e = o.Res, o.Err
+	}
+	return
+}
+
+// GetAddressTxIDs calls the method with the given parameters
+func (a API) GetAddressTxIDs(cmd *btcjson.GetAddressTxIDsCmd) (e error) {
+	RPCHandlers["getaddresstxids"].Call <-API{a.Ch, cmd, nil}
+	return
+}
+
+// GetAddressTxIDsChk checks if a new message arrived on the result channel and
+// returns true if it does, as well as storing the value in the Result field
+func (a API) GetAddressTxIDsChk() (isNew bool) {
+	select {
+	case o := <-a.Ch.(chan GetAddressTxIDsRes):
+		if o.Err != nil {
+			a.Result = o.Err
+		} else {
+			a.Result = o.Res
+		}
+		isNew = true
+	default:
+	}
+	return
+}
+
+// GetAddressTxIDsGetRes returns a pointer to the value in the Result field
+func (a API) GetAddressTxIDsGetRes() (out *[]string, e error) {
+	out, _ = a.Result.(*[]string)
+	e, _ = a.Result.(error)
+	return 
+}
+
+// GetAddressTxIDsWait calls the method and blocks until it returns or 5 seconds passes
+func (a API) GetAddressTxIDsWait(cmd *btcjson.GetAddressTxIDsCmd) (out *[]string, e error) {
+	RPCHandlers["getaddresstxids"].Call <-API{a.Ch, cmd, nil}
+	select {
+	case <-time.After(time.Second*5):
+		break
+	case o := <-a.Ch.(chan GetAddressTxIDsRes):
+		out, e = o.Res, o.Err
+	}
+	return
+}
+
+// GetAddressUtxos calls the method with the given parameters
+func (a API) GetAddressUtxos(cmd *btcjson.GetAddressUtxosCmd) (e error) {
+	RPCHandlers["getaddressutxos"].Call <-API{a.Ch, cmd, nil}
+	return
+}
+
+// GetAddressUtxosChk checks if a new message arrived on the result channel and
+// returns true if it does, as well as storing the value in the Result field
+func (a API) GetAddressUtxosChk() (isNew bool) {
+	select {
+	case o := <-a.Ch.(chan GetAddressUtxosRes):
+		if o.Err != nil {
+			a.Result = o.Err
+		} else {
+			a.Result = o.Res
+		}
+		isNew = true
+	default:
+	}
+	return
+}
+
+// GetAddressUtxosGetRes returns a pointer to the value in the Result field
+func (a API) GetAddressUtxosGetRes() (out *[]btcjson.GetAddressUtxosResult, e error) {
+	out, _ = a.Result.(*[]btcjson.GetAddressUtxosResult)
+	e, _ = a.Result.(error)
+	return 
+}
+
+// GetAddressUtxosWait calls the method and blocks until it returns or 5 seconds passes
+func (a API) GetAddressUtxosWait(cmd *btcjson.GetAddressUtxosCmd) (out *[]btcjson.GetAddressUtxosResult, e error) {
+	RPCHandlers["getaddressutxos"].Call <-API{a.Ch, cmd, nil}
+	select {
+	case <-time.After(time.Second*5):
+		break
+	case o := <-a.Ch.(chan GetAddressUtxosRes):
+		out, e = o.Res, o.Err
+	}
+	return
+}
+
+// GetAlgoStats calls the method with the given parameters
+func (a API) GetAlgoStats(cmd *btcjson.GetAlgoStatsCmd) (e error) {
+	RPCHandlers["getalgostats"].Call <-API{a.Ch, cmd, nil}
+	return
+}
+
+// GetAlgoStatsChk checks if a new message arrived on the result channel and
+// returns true if it does, as well as storing the value in the Result field
+func (a API) GetAlgoStatsChk() (isNew bool) {
+	select {
+	case o := <-a.Ch.(chan GetAlgoStatsRes):
+		if o.Err != nil {
+			a.Result = o.Err
+		} else {
+			a.Result = o.Res
+		}
+		isNew = true
+	default:
+	}
+	return
+}
+
+// GetAlgoStatsGetRes returns a pointer to the value in the Result field
+func (a API) GetAlgoStatsGetRes() (out *btcjson.GetAlgoStatsResult, e error) {
+	out, _ = a.Result.(*btcjson.GetAlgoStatsResult)
+	e, _ = a.Result.(error)
+	return 
+}
+
+// GetAlgoStatsWait calls the method and blocks until it returns or 5 seconds passes
+func (a API) GetAlgoStatsWait(cmd *btcjson.GetAlgoStatsCmd) (out *btcjson.GetAlgoStatsResult, e error) {
+	RPCHandlers["getalgostats"].Call <-API{a.Ch, cmd, nil}
+	select {
+	case <-time.After(time.Second*5):
+		break
+	case o := <-a.Ch.(chan GetAlgoStatsRes):
+		out, e = o.Res, o.Err
+	}
+	return
+}
+
 // GetBestBlock calls the method with the given parameters
 func (a API) GetBestBlock(cmd *None) (e error) {
 	RPCHandlers["getbestblock"].Call <-API{a.Ch, cmd, nil}
@@ -1011,6 +1281,47 @@ func (a API) GetCFilterHeaderWait(cmd *btcjson.GetCFilterHeaderCmd) (out *string
 	return
 }
 
+// GetChainTips calls the method with the given parameters
+func (a API) GetChainTips(cmd *None) (e error) {
+	RPCHandlers["getchaintips"].Call <-API{a.Ch, cmd, nil}
+	return
+}
+
+// GetChainTipsChk checks if a new message arrived on the result channel and
+// returns true if it does, as well as storing the value in the Result field
+func (a API) GetChainTipsChk() (isNew bool) {
+	select {
+	case o := <-a.Ch.(chan GetChainTipsRes):
+		if o.Err != nil {
+			a.Result = o.Err
+		} else {
+			a.Result = o.Res
+		}
+		isNew = true
+	default:
+	}
+	return
+}
+
+// GetChainTipsGetRes returns a pointer to the value in the Result field
+func (a API) GetChainTipsGetRes() (out *[]btcjson.GetChainTipsResult, e error) {
+	out, _ = a.Result.(*[]btcjson.GetChainTipsResult)
+	e, _ = a.Result.(error)
+	return 
+}
+
+// GetChainTipsWait calls the method and blocks until it returns or 5 seconds passes
+func (a API) GetChainTipsWait(cmd *None) (out *[]btcjson.GetChainTipsResult, e error) {
+	RPCHandlers["getchaintips"].Call <-API{a.Ch, cmd, nil}
+	select {
+	case <-time.After(time.Second*5):
+		break
+	case o := <-a.Ch.(chan GetChainTipsRes):
+		out, e = o.Res, o.Err
+	}
+	return
+}
+
 // GetConnectionCount calls the method with the given parameters
 func (a API) GetConnectionCount(cmd *None) (e error) {
 	RPCHandlers["getconnectioncount"].Call <-API{a.Ch, cmd, nil}
@@ -1298,6 +1609,47 @@ func (a API) GetInfoWait(cmd *None) (out *btcjson.InfoChainResult0, e error) {
 	return
 }
 
+// GetMempoolEntry calls the method with the given parameters
+func (a API) GetMempoolEntry(cmd *btcjson.GetMempoolEntryCmd) (e error) {
+	RPCHandlers["getmempoolentry"].Call <-API{a.Ch, cmd, nil}
+	return
+}
+
+// GetMempoolEntryChk checks if a new message arrived on the result channel and
+// returns true if it does, as well as storing the value in the Result field
+func (a API) GetMempoolEntryChk() (isNew bool) {
+	select {
+	case o := <-a.Ch.(chan GetMempoolEntryRes):
+		if o.Err != nil {
+			a.Result = o.Err
+		} else {
+			a.Result = o.Res
+		}
+		isNew = true
+	default:
+	}
+	return
+}
+
+// GetMempoolEntryGetRes returns a pointer to the value in the Result field
+func (a API) GetMempoolEntryGetRes() (out *btcjson.GetMempoolEntryResult, e error) {
+	out, _ = a.Result.(*btcjson.GetMempoolEntryResult)
+	e, _ = a.Result.(error)
+	return 
+}
+
+// GetMempoolEntryWait calls the method and blocks until it returns or 5 seconds passes
+func (a API) GetMempoolEntryWait(cmd *btcjson.GetMempoolEntryCmd) (out *btcjson.GetMempoolEntryResult, e error) {
+	RPCHandlers["getmempoolentry"].Call <-API{a.Ch, cmd, nil}
+	select {
+	case <-time.After(time.Second*5):
+		break
+	case o := <-a.Ch.(chan GetMempoolEntryRes):
+		out, e = o.Res, o.Err
+	}
+	return
+}
+
 // GetMempoolInfo calls the method with the given parameters
 func (a API) GetMempoolInfo(cmd *None) (e error) {
 	RPCHandlers["getmempoolinfo"].Call <-API{a.Ch, cmd, nil}
@@ -1462,6 +1814,47 @@ func (a API) GetNetworkHashPSWait(cmd *btcjson.GetNetworkHashPSCmd) (out *[]btcj
 	return
 }
 
+// GetNetworkInfo calls the method with the given parameters
+func (a API) GetNetworkInfo(cmd *None) (e error) {
+	RPCHandlers["getnetworkinfo"].Call <-API{a.Ch, cmd, nil}
+	return
+}
+
+// GetNetworkInfoChk checks if a new message arrived on the result channel and
+// returns true if it does, as well as storing the value in the Result field
+func (a API) GetNetworkInfoChk() (isNew bool) {
+	select {
+	case o := <-a.Ch.(chan GetNetworkInfoRes):
+		if o.Err != nil {
+			a.Result = o.Err
+		} else {
+			a.Result = o.Res
+		}
+		isNew = true
+	default:
+	}
+	return
+}
+
+// GetNetworkInfoGetRes returns a pointer to the value in the Result field
+func (a API) GetNetworkInfoGetRes() (out *btcjson.GetNetworkInfoResult, e error) {
+	out, _ = a.Result.(*btcjson.GetNetworkInfoResult)
+	e, _ = a.Result.(error)
+	return 
+}
+
+// GetNetworkInfoWait calls the method and blocks until it returns or 5 seconds passes
+func (a API) GetNetworkInfoWait(cmd *None) (out *btcjson.GetNetworkInfoResult, e error) {
+	RPCHandlers["getnetworkinfo"].Call <-API{a.Ch, cmd, nil}
+	select {
+	case <-time.After(time.Second*5):
+		break
+	case o := <-a.Ch.(chan GetNetworkInfoRes):
+		out, e = o.Res, o.Err
+	}
+	return
+}
+
 // GetPeerInfo calls the method with the given parameters
 func (a API) GetPeerInfo(cmd *None) (e error) {
 	RPCHandlers["getpeerinfo"].Call <-API{a.Ch, cmd, nil}
@@ -1626,6 +2019,88 @@ func (a API) GetTxOutWait(cmd *btcjson.GetTxOutCmd) (out *string, e error) {
 	return
 }
 
+// GetTxOutSetInfo calls the method with the given parameters
+func (a API) GetTxOutSetInfo(cmd *None) (e error) {
+	RPCHandlers["gettxoutsetinfo"].Call <-API{a.Ch, cmd, nil}
+	return
+}
+
+// GetTxOutSetInfoChk checks if a new message arrived on the result channel and
+// returns true if it does, as well as storing the value in the Result field
+func (a API) GetTxOutSetInfoChk() (isNew bool) {
+	select {
+	case o := <-a.Ch.(chan GetTxOutSetInfoRes):
+		if o.Err != nil {
+			a.Result = o.Err
+		} else {
+			a.Result = o.Res
+		}
+		isNew = true
+	default:
+	}
+	return
+}
+
+// GetTxOutSetInfoGetRes returns a pointer to the value in the Result field
+func (a API) GetTxOutSetInfoGetRes() (out *btcjson.GetTxOutSetInfoResult, e error) {
+	out, _ = a.Result.(*btcjson.GetTxOutSetInfoResult)
+	e, _ = a.Result.(error)
+	return 
+}
+
+// GetTxOutSetInfoWait calls the method and blocks until it returns or 5 seconds passes
+func (a API) GetTxOutSetInfoWait(cmd *None) (out *btcjson.GetTxOutSetInfoResult, e error) {
+	RPCHandlers["gettxoutsetinfo"].Call <-API{a.Ch, cmd, nil}
+	select {
+	case <-time.After(time.Second*5):
+		break
+	case o := <-a.Ch.(chan GetTxOutSetInfoRes):
+		out, e = o.Res, o.Err
+	}
+	return
+}
+
+// GetWork calls the method with the given parameters
+func (a API) GetWork(cmd *btcjson.GetWorkCmd) (e error) {
+	RPCHandlers["getwork"].Call <-API{a.Ch, cmd, nil}
+	return
+}
+
+// GetWorkChk checks if a new message arrived on the result channel and
+// returns true if it does, as well as storing the value in the Result field
+func (a API) GetWorkChk() (isNew bool) {
+	select {
+	case o := <-a.Ch.(chan GetWorkRes):
+		if o.Err != nil {
+			a.Result = o.Err
+		} else {
+			a.Result = o.Res
+		}
+		isNew = true
+	default:
+	}
+	return
+}
+
+// GetWorkGetRes returns a pointer to the value in the Result field
+func (a API) GetWorkGetRes() (out *btcjson.GetWorkResult, e error) {
+	out, _ = a.Result.(*btcjson.GetWorkResult)
+	e, _ = a.Result.(error)
+	return 
+}
+
+// GetWorkWait calls the method and blocks until it returns or 5 seconds passes
+func (a API) GetWorkWait(cmd *btcjson.GetWorkCmd) (out *btcjson.GetWorkResult, e error) {
+	RPCHandlers["getwork"].Call <-API{a.Ch, cmd, nil}
+	select {
+	case <-time.After(time.Second*5):
+		break
+	case o := <-a.Ch.(chan GetWorkRes):
+		out, e = o.Res, o.Err
+	}
+	return
+}
+
 // Help calls the method with the given parameters
 func (a API) Help(cmd *btcjson.HelpCmd) (e error) {
 	RPCHandlers["help"].Call <-API{a.Ch, cmd, nil}
@@ -1667,17 +2142,140 @@ func (a API) HelpWait(cmd *btcjson.HelpCmd) (out *string, e error) {
 	return
 }
 
+// InvalidateBlock calls the method with the given parameters
+func (a API) InvalidateBlock(cmd *btcjson.InvalidateBlockCmd) (e error) {
+	RPCHandlers["invalidateblock"].Call <-API{a.Ch, cmd, nil}
+	return
+}
+
+// InvalidateBlockChk checks if a new message arrived on the result channel and
+// returns true if it does, as well as storing the value in the Result field
+func (a API) InvalidateBlockChk() (isNew bool) {
+	select {
+	case o := <-a.Ch.(chan InvalidateBlockRes):
+		if o.Err != nil {
+			a.Result = o.Err
+		} else {
+			a.Result = o.Res
+		}
+		isNew = true
+	default:
+	}
+	return
+}
+
+// InvalidateBlockGetRes returns a pointer to the value in the Result field
+func (a API) InvalidateBlockGetRes() (out *None, e error) {
+	out, _ = a.Result.(*None)
+	e, _ = a.Result.(error)
+	return 
+}
+
+// InvalidateBlockWait calls the method and blocks until it returns or 5 seconds passes
+func (a API) InvalidateBlockWait(cmd *btcjson.InvalidateBlockCmd) (out *None, e error) {
+	RPCHandlers["invalidateblock"].Call <-API{a.Ch, cmd, nil}
+	select {
+	case <-time.After(time.Second*5):
+		break
+	case o := <-a.Ch.(chan InvalidateBlockRes):
+		out, e = o.Res, o.Err
+	}
+	return
+}
+
 // Node calls the method with the given parameters
 func (a API) Node(cmd *btcjson.NodeCmd) (e error) {
 	RPCHandlers["node"].Call <-API{a.Ch, cmd, nil}
 	return
 }
 
-// NodeChk checks if a new message arrived on the result channel and
+// NodeChk checks if a new message arrived on the result channel and
+// returns true if it does, as well as storing the value in the Result field
+func (a API) NodeChk() (isNew bool) {
+	select {
+	case o := <-a.Ch.(chan NodeRes):
+		if o.Err != nil {
+			a.Result = o.Err
+		} else {
+			a.Result = o.Res
+		}
+		isNew = true
+	default:
+	}
+	return
+}
+
+// NodeGetRes returns a pointer to the value in the Result field
+func (a API) NodeGetRes() (out *None, e error) {
+	out, _ = a.Result.(*None)
+	e, _ = a.Result.(error)
+	return 
+}
+
+// NodeWait calls the method and blocks until it returns or 5 seconds passes
+func (a API) NodeWait(cmd *btcjson.NodeCmd) (out *None, e error) {
+	RPCHandlers["node"].Call <-API{a.Ch, cmd, nil}
+	select {
+	case <-time.After(time.Second*5):
+		break
+	case o := <-a.Ch.(chan NodeRes):
+		out, e = o.Res, o.Err
+	}
+	return
+}
+
+// Ping calls the method with the given parameters
+func (a API) Ping(cmd *None) (e error) {
+	RPCHandlers["ping"].Call <-API{a.Ch, cmd, nil}
+	return
+}
+
+// PingChk checks if a new message arrived on the result channel and
+// returns true if it does, as well as storing the value in the Result field
+func (a API) PingChk() (isNew bool) {
+	select {
+	case o := <-a.Ch.(chan PingRes):
+		if o.Err != nil {
+			a.Result = o.Err
+		} else {
+			a.Result = o.Res
+		}
+		isNew = true
+	default:
+	}
+	return
+}
+
+// PingGetRes returns a pointer to the value in the Result field
+func (a API) PingGetRes() (out *None, e error) {
+	out, _ = a.Result.(*None)
+	e, _ = a.Result.(error)
+	return 
+}
+
+// PingWait calls the method and blocks until it returns or 5 seconds passes
+func (a API) PingWait(cmd *None) (out *None, e error) {
+	RPCHandlers["ping"].Call <-API{a.Ch, cmd, nil}
+	select {
+	case <-time.After(time.Second*5):
+		break
+	case o := <-a.Ch.(chan PingRes):
+		out, e = o.Res, o.Err
+	}
+	return
+}
+
+// PreciousBlock calls the method with the given parameters
+func (a API) PreciousBlock(cmd *btcjson.PreciousBlockCmd) (e error) {
+	RPCHandlers["preciousblock"].Call <-API{a.Ch, cmd, nil}
+	return
+}
+
+// PreciousBlockChk checks if a new message arrived on the result channel and
 // returns true if it does, as well as storing the value in the Result field
-func (a API) NodeChk() (isNew bool) {
+func (a API) PreciousBlockChk() (isNew bool) {
 	select {
-	case o := <-a.Ch.(chan NodeRes):
+	case o := <-a.Ch.(chan PreciousBlockRes):
 		if o.Err != nil {
 			a.Result = o.Err
 		} else {
@@ -1689,36 +2287,36 @@ func (a API) NodeChk() (isNew bool) {
 	return
 }
 
-// NodeGetRes returns a pointer to the value in the Result field
-func (a API) NodeGetRes() (out *None, e error) {
+// PreciousBlockGetRes returns a pointer to the value in the Result field
+func (a API) PreciousBlockGetRes() (out *None, e error) {
 	out, _ = a.Result.(*None)
 	e, _ = a.Result.(error)
 	return 
 }
 
-// NodeWait calls the method and blocks until it returns or 5 seconds passes
-func (a API) NodeWait(cmd *btcjson.NodeCmd) (out *None, e error) {
-	RPCHandlers["node"].Call <-API{a.Ch, cmd, nil}
+// PreciousBlockWait calls the method and blocks until it returns or 5 seconds passes
+func (a API) PreciousBlockWait(cmd *btcjson.PreciousBlockCmd) (out *None, e error) {
+	RPCHandlers["preciousblock"].Call <-API{a.Ch, cmd, nil}
 	select {
 	case <-time.After(time.Second*5):
 		break
-	case o := <-a.Ch.(chan NodeRes):
+	case o := <-a.Ch.(chan PreciousBlockRes):
 		out, e = o.Res, o.Err
 	}
 	return
 }
 
-// Ping calls the method with the given parameters
-func (a API) Ping(cmd *None) (e error) {
-	RPCHandlers["ping"].Call <-API{a.Ch, cmd, nil}
+// ReconsiderBlock calls the method with the given parameters
+func (a API) ReconsiderBlock(cmd *btcjson.ReconsiderBlockCmd) (e error) {
+	RPCHandlers["reconsiderblock"].Call <-API{a.Ch, cmd, nil}
 	return
 }
 
-// PingChk checks if a new message arrived on the result channel and
+// ReconsiderBlockChk checks if a new message arrived on the result channel and
 // returns true if it does, as well as storing the value in the Result field
-func (a API) PingChk() (isNew bool) {
+func (a API) ReconsiderBlockChk() (isNew bool) {
 	select {
-	case o := <-a.Ch.(chan PingRes):
+	case o := <-a.Ch.(chan ReconsiderBlockRes):
 		if o.Err != nil {
 			a.Result = o.Err
 		} else {
@@ -1730,20 +2328,20 @@ func (a API) PingChk() (isNew bool) {
 	return
 }
 
-// PingGetRes returns a pointer to the value in the Result field
-func (a API) PingGetRes() (out *None, e error) {
+// ReconsiderBlockGetRes returns a pointer to the value in the Result field
+func (a API) ReconsiderBlockGetRes() (out *None, e error) {
 	out, _ = a.Result.(*None)
 	e, _ = a.Result.(error)
 	return 
 }
 
-// PingWait calls the method and blocks until it returns or 5 seconds passes
-func (a API) PingWait(cmd *None) (out *None, e error) {
-	RPCHandlers["ping"].Call <-API{a.Ch, cmd, nil}
+// ReconsiderBlockWait calls the method and blocks until it returns or 5 seconds passes
+func (a API) ReconsiderBlockWait(cmd *btcjson.ReconsiderBlockCmd) (out *None, e error) {
+	RPCHandlers["reconsiderblock"].Call <-API{a.Ch, cmd, nil}
 	select {
 	case <-time.After(time.Second*5):
 		break
-	case o := <-a.Ch.(chan PingRes):
+	case o := <-a.Ch.(chan ReconsiderBlockRes):
 		out, e = o.Res, o.Err
 	}
 	return
@@ -2295,6 +2893,36 @@ func RunAPI(server *Server, quit qu.C) {
 				}
 				if r, ok := res.([]btcjson.GetAddedNodeInfoResultAddr); ok { 
 					msg.Ch.(chan GetAddedNodeInfoRes) <-GetAddedNodeInfoRes{&r, e} } 
+			case msg := <-nrh["getaddressbalance"].Call:
+				if res, e = nrh["getaddressbalance"].
+					Fn(server, msg.Params.(*btcjson.GetAddressBalanceCmd), nil); E.Chk(e) {
+				}
+				if r, ok := res.(btcjson.GetAddressBalanceResult); ok { 
+					msg.Ch.(chan GetAddressBalanceRes) <-GetAddressBalanceRes{&r, e} } 
+			case msg := <-nrh["getaddressdeltas"].Call:
+				if res, e = nrh["getaddressdeltas"].
+					Fn(server, msg.Params.(*btcjson.GetAddressDeltasCmd), nil); E.Chk(e) {
+				}
+				if r, ok := res.([]btcjson.GetAddressDeltasResult); ok { 
+					msg.Ch.(chan GetAddressDeltasRes) <-GetAddressDeltasRes{&r, e} } 
+			case msg := <-nrh["getaddresstxids"].Call:
+				if res, e = nrh["getaddresstxids"].
+					Fn(server, msg.Params.(*btcjson.GetAddressTxIDsCmd), nil); E.Chk(e) {
+				}
+				if r, ok := res.([]string); ok { 
+					msg.Ch.(chan GetAddressTxIDsRes) <-GetAddressTxIDsRes{&r, e} } 
+			case msg := <-nrh["getaddressutxos"].Call:
+				if res, e = nrh["getaddressutxos"].
+					Fn(server, msg.Params.(*btcjson.GetAddressUtxosCmd), nil); E.Chk(e) {
+				}
+				if r, ok := res.([]btcjson.GetAddressUtxosResult); ok { 
+					msg.Ch.(chan GetAddressUtxosRes) <-GetAddressUtxosRes{&r, e} } 
+			case msg := <-nrh["getalgostats"].Call:
+				if res, e = nrh["getalgostats"].
+					Fn(server, msg.Params.(*btcjson.GetAlgoStatsCmd), nil); E.Chk(e) {
+				}
+				if r, ok := res.(btcjson.GetAlgoStatsResult); ok { 
+					msg.Ch.(chan GetAlgoStatsRes) <-GetAlgoStatsRes{&r, e} } 
 			case msg := <-nrh["getbestblock"].Call:
 				if res, e = nrh["getbestblock"].
 					Fn(server, msg.Params.(*None), nil); E.Chk(e) {
@@ -2355,6 +2983,12 @@ func RunAPI(server *Server, quit qu.C) {
 				}
 				if r, ok := res.(string); ok { 
 					msg.Ch.(chan GetCFilterHeaderRes) <-GetCFilterHeaderRes{&r, e} } 
+			case msg := <-nrh["getchaintips"].Call:
+				if res, e = nrh["getchaintips"].
+					Fn(server, msg.Params.(*None), nil); E.Chk(e) {
+				}
+				if r, ok := res.([]btcjson.GetChainTipsResult); ok { 
+					msg.Ch.(chan GetChainTipsRes) <-GetChainTipsRes{&r, e} } 
 			case msg := <-nrh["getconnectioncount"].Call:
 				if res, e = nrh["getconnectioncount"].
 					Fn(server, msg.Params.(*None), nil); E.Chk(e) {
@@ -2397,6 +3031,12 @@ func RunAPI(server *Server, quit qu.C) {
 				}
 				if r, ok := res.(btcjson.InfoChainResult0); ok { 
 					msg.Ch.(chan GetInfoRes) <-GetInfoRes{&r, e} } 
+			case msg := <-nrh["getmempoolentry"].Call:
+				if res, e = nrh["getmempoolentry"].
+					Fn(server, msg.Params.(*btcjson.GetMempoolEntryCmd), nil); E.Chk(e) {
+				}
+				if r, ok := res.(btcjson.GetMempoolEntryResult); ok { 
+					msg.Ch.(chan GetMempoolEntryRes) <-GetMempoolEntryRes{&r, e} } 
 			case msg := <-nrh["getmempoolinfo"].Call:
 				if res, e = nrh["getmempoolinfo"].
 					Fn(server, msg.Params.(*None), nil); E.Chk(e) {
@@ -2421,6 +3061,12 @@ func RunAPI(server *Server, quit qu.C) {
 				}
 				if r, ok := res.([]btcjson.GetPeerInfoResult); ok { 
 					msg.Ch.(chan GetNetworkHashPSRes) <-GetNetworkHashPSRes{&r, e} } 
+			case msg := <-nrh["getnetworkinfo"].Call:
+				if res, e = nrh["getnetworkinfo"].
+					Fn(server, msg.Params.(*None), nil); E.Chk(e) {
+				}
+				if r, ok := res.(btcjson.GetNetworkInfoResult); ok { 
+					msg.Ch.(chan GetNetworkInfoRes) <-GetNetworkInfoRes{&r, e} } 
 			case msg := <-nrh["getpeerinfo"].Call:
 				if res, e = nrh["getpeerinfo"].
 					Fn(server, msg.Params.(*None), nil); E.Chk(e) {
@@ -2445,12 +3091,30 @@ func RunAPI(server *Server, quit qu.C) {
 				}
 				if r, ok := res.(string); ok { 
 					msg.Ch.(chan GetTxOutRes) <-GetTxOutRes{&r, e} } 
+			case msg := <-nrh["gettxoutsetinfo"].Call:
+				if res, e = nrh["gettxoutsetinfo"].
+					Fn(server, msg.Params.(*None), nil); E.Chk(e) {
+				}
+				if r, ok := res.(btcjson.GetTxOutSetInfoResult); ok { 
+					msg.Ch.(chan GetTxOutSetInfoRes) <-GetTxOutSetInfoRes{&r, e} } 
+			case msg := <-nrh["getwork"].Call:
+				if res, e = nrh["getwork"].
+					Fn(server, msg.Params.(*btcjson.GetWorkCmd), nil); E.Chk(e) {
+				}
+				if r, ok := res.(btcjson.GetWorkResult); ok { 
+					msg.Ch.(chan GetWorkRes) <-GetWorkRes{&r, e} } 
 			case msg := <-nrh["help"].Call:
 				if res, e = nrh["help"].
 					Fn(server, msg.Params.(*btcjson.HelpCmd), nil); E.Chk(e) {
 				}
 				if r, ok := res.(string); ok { 
 					msg.Ch.(chan HelpRes) <-HelpRes{&r, e} } 
+			case msg := <-nrh["invalidateblock"].Call:
+				if res, e = nrh["invalidateblock"].
+					Fn(server, msg.Params.(*btcjson.InvalidateBlockCmd), nil); E.Chk(e) {
+				}
+				if r, ok := res.(None); ok { 
+					msg.Ch.(chan InvalidateBlockRes) <-InvalidateBlockRes{&r, e} } 
 			case msg := <-nrh["node"].Call:
 				if res, e = nrh["node"].
 					Fn(server, msg.Params.(*btcjson.NodeCmd), nil); E.Chk(e) {
@@ -2463,6 +3127,18 @@ func RunAPI(server *Server, quit qu.C) {
 				}
 				if r, ok := res.(None); ok { 
 					msg.Ch.(chan PingRes) <-PingRes{&r, e} } 
+			case msg := <-nrh["preciousblock"].Call:
+				if res, e = nrh["preciousblock"].
+					Fn(server, msg.Params.(*btcjson.PreciousBlockCmd), nil); E.Chk(e) {
+				}
+				if r, ok := res.(None); ok { 
+					msg.Ch.(chan PreciousBlockRes) <-PreciousBlockRes{&r, e} } 
+			case msg := <-nrh["reconsiderblock"].Call:
+				if res, e = nrh["reconsiderblock"].
+					Fn(server, msg.Params.(*btcjson.ReconsiderBlockCmd), nil); E.Chk(e) {
+				}
+				if r, ok := res.(None); ok { 
+					msg.Ch.(chan ReconsiderBlockRes) <-ReconsiderBlockRes{&r, e} } 
 			case msg := <-nrh["resetchain"].Call:
 				if res, e = nrh["resetchain"].
 					Fn(server, msg.Params.(*None), nil); E.Chk(e) {
@@ -2636,6 +3312,71 @@ func (c *CAPI) GetAddedNodeInfo(req *btcjson.GetAddedNodeInfoCmd, resp []btcjson
 	return 
 }
 
+func (c *CAPI) GetAddressBalance(req *btcjson.GetAddressBalanceCmd, resp btcjson.GetAddressBalanceResult) (e error) {
+	nrh := RPCHandlers
+	res := nrh["getaddressbalance"].Result()
+	res.Params = req
+	nrh["getaddressbalance"].Call <- res
+	select {
+	case resp = <-res.Ch.(chan btcjson.GetAddressBalanceResult):
+	case <-time.After(c.Timeout):
+	case <-c.quit.Wait():
+	} 
+	return 
+}
+
+func (c *CAPI) GetAddressDeltas(req *btcjson.GetAddressDeltasCmd, resp []btcjson.GetAddressDeltasResult) (e error) {
+	nrh := RPCHandlers
+	res := nrh["getaddressdeltas"].Result()
+	res.Params = req
+	nrh["getaddressdeltas"].Call <- res
+	select {
+	case resp = <-res.Ch.(chan []btcjson.GetAddressDeltasResult):
+	case <-time.After(c.Timeout):
+	case <-c.quit.Wait():
+	} 
+	return 
+}
+
+func (c *CAPI) GetAddressTxIDs(req *btcjson.GetAddressTxIDsCmd, resp []string) (e error) {
+	nrh := RPCHandlers
+	res := nrh["getaddresstxids"].Result()
+	res.Params = req
+	nrh["getaddresstxids"].Call <- res
+	select {
+	case resp = <-res.Ch.(chan []string):
+	case <-time.After(c.Timeout):
+	case <-c.quit.Wait():
+	} 
+	return 
+}
+
+func (c *CAPI) GetAddressUtxos(req *btcjson.GetAddressUtxosCmd, resp []btcjson.GetAddressUtxosResult) (e error) {
+	nrh := RPCHandlers
+	res := nrh["getaddressutxos"].Result()
+	res.Params = req
+	nrh["getaddressutxos"].Call <- res
+	select {
+	case resp = <-res.Ch.(chan []btcjson.GetAddressUtxosResult):
+	case <-time.After(c.Timeout):
+	case <-c.quit.Wait():
+	} 
+	return 
+}
+
+func (c *CAPI) GetAlgoStats(req *btcjson.GetAlgoStatsCmd, resp btcjson.GetAlgoStatsResult) (e error) {
+	nrh := RPCHandlers
+	res := nrh["getalgostats"].Result()
+	res.Params = req
+	nrh["getalgostats"].Call <- res
+	select {
+	case resp = <-res.Ch.(chan btcjson.GetAlgoStatsResult):
+	case <-time.After(c.Timeout):
+	case <-c.quit.Wait():
+	} 
+	return 
+}
+
 func (c *CAPI) GetBestBlock(req *None, resp btcjson.GetBestBlockResult) (e error) {
 	nrh := RPCHandlers
 	res := nrh["getbestblock"].Result()
@@ -2766,6 +3507,19 @@ func (c *CAPI) GetCFilterHeader(req *btcjson.GetCFilterHeaderCmd, resp string) (
 	return 
 }
 
+func (c *CAPI) GetChainTips(req *None, resp []btcjson.GetChainTipsResult) (e error) {
+	nrh := RPCHandlers
+	res := nrh["getchaintips"].Result()
+	res.Params = req
+	nrh["getchaintips"].Call <- res
+	select {
+	case resp = <-res.Ch.(chan []btcjson.GetChainTipsResult):
+	case <-time.After(c.Timeout):
+	case <-c.quit.Wait():
+	} 
+	return 
+}
+
 func (c *CAPI) GetConnectionCount(req *None, resp int32) (e error) {
 	nrh := RPCHandlers
 	res := nrh["getconnectioncount"].Result()
@@ -2857,6 +3611,19 @@ func (c *CAPI) GetInfo(req *None, resp btcjson.InfoChainResult0) (e error) {
 	return 
 }
 
+func (c *CAPI) GetMempoolEntry(req *btcjson.GetMempoolEntryCmd, resp btcjson.GetMempoolEntryResult) (e error) {
+	nrh := RPCHandlers
+	res := nrh["getmempoolentry"].Result()
+	res.Params = req
+	nrh["getmempoolentry"].Call <- res
+	select {
+	case resp = <-res.Ch.(chan btcjson.GetMempoolEntryResult):
+	case <-time.After(c.Timeout):
+	case <-c.quit.Wait():
+	} 
+	return 
+}
+
 func (c *CAPI) GetMempoolInfo(req *None, resp btcjson.GetMempoolInfoResult) (e error) {
 	nrh := RPCHandlers
 	res := nrh["getmempoolinfo"].Result()
@@ -2909,6 +3676,19 @@ func (c *CAPI) GetNetworkHashPS(req *btcjson.GetNetworkHashPSCmd, resp []btcjson
 	return 
 }
 
+func (c *CAPI) GetNetworkInfo(req *None, resp btcjson.GetNetworkInfoResult) (e error) {
+	nrh := RPCHandlers
+	res := nrh["getnetworkinfo"].Result()
+	res.Params = req
+	nrh["getnetworkinfo"].Call <- res
+	select {
+	case resp = <-res.Ch.(chan btcjson.GetNetworkInfoResult):
+	case <-time.After(c.Timeout):
+	case <-c.quit.Wait():
+	} 
+	return 
+}
+
 func (c *CAPI) GetPeerInfo(req *None, resp []btcjson.GetPeerInfoResult) (e error) {
 	nrh := RPCHandlers
 	res := nrh["getpeerinfo"].Result()
@@ -2961,6 +3741,32 @@ func (c *CAPI) GetTxOut(req *btcjson.GetTxOutCmd, resp string) (e error) {
 	return 
 }
 
+func (c *CAPI) GetTxOutSetInfo(req *None, resp btcjson.GetTxOutSetInfoResult) (e error) {
+	nrh := RPCHandlers
+	res := nrh["gettxoutsetinfo"].Result()
+	res.Params = req
+	nrh["gettxoutsetinfo"].Call <- res
+	select {
+	case resp = <-res.Ch.(chan btcjson.GetTxOutSetInfoResult):
+	case <-time.After(c.Timeout):
+	case <-c.quit.Wait():
+	} 
+	return 
+}
+
+func (c *CAPI) GetWork(req *btcjson.GetWorkCmd, resp btcjson.GetWorkResult) (e error) {
+	nrh := RPCHandlers
+	res := nrh["getwork"].Result()
+	res.Params = req
+	nrh["getwork"].Call <- res
+	select {
+	case resp = <-res.Ch.(chan btcjson.GetWorkResult):
+	case <-time.After(c.Timeout):
+	case <-c.quit.Wait():
+	} 
+	return 
+}
+
 func (c *CAPI) Help(req *btcjson.HelpCmd, resp string) (e error) {
 	nrh := RPCHandlers
 	res := nrh["help"].Result()
@@ -2974,6 +3780,19 @@ func (c *CAPI) Help(req *btcjson.HelpCmd, resp string) (e error) {
 	return 
 }
 
+func (c *CAPI) InvalidateBlock(req *btcjson.InvalidateBlockCmd, resp None) (e error) {
+	nrh := RPCHandlers
+	res := nrh["invalidateblock"].Result()
+	res.Params = req
+	nrh["invalidateblock"].Call <- res
+	select {
+	case resp = <-res.Ch.(chan None):
+	case <-time.After(c.Timeout):
+	case <-c.quit.Wait():
+	} 
+	return 
+}
+
 func (c *CAPI) Node(req *btcjson.NodeCmd, resp None) (e error) {
 	nrh := RPCHandlers
 	res := nrh["node"].Result()
@@ -3000,6 +3819,32 @@ func (c *CAPI) Ping(req *None, resp None) (e error) {
 	return 
 }
 
+func (c *CAPI) PreciousBlock(req *btcjson.PreciousBlockCmd, resp None) (e error) {
+	nrh := RPCHandlers
+	res := nrh["preciousblock"].Result()
+	res.Params = req
+	nrh["preciousblock"].Call <- res
+	select {
+	case resp = <-res.Ch.(chan None):
+	case <-time.After(c.Timeout):
+	case <-c.quit.Wait():
+	} 
+	return 
+}
+
+func (c *CAPI) ReconsiderBlock(req *btcjson.ReconsiderBlockCmd, resp None) (e error) {
+	nrh := RPCHandlers
+	res := nrh["reconsiderblock"].Result()
+	res.Params = req
+	nrh["reconsiderblock"].Call <- res
+	select {
+	case resp = <-res.Ch.(chan None):
+	case <-time.After(c.Timeout):
+	case <-c.quit.Wait():
+	} 
+	return 
+}
+
 func (c *CAPI) ResetChain(req *None, resp None) (e error) {
 	nrh := RPCHandlers
 	res := nrh["resetchain"].Result()
@@ -3228,6 +4073,56 @@ func (r *CAPIClient) GetAddedNodeInfo(cmd ...*btcjson.GetAddedNodeInfoCmd) (res
 	return
 }
 
+func (r *CAPIClient) GetAddressBalance(cmd ...*btcjson.GetAddressBalanceCmd) (res btcjson.GetAddressBalanceResult, e error) {
+	var c *btcjson.GetAddressBalanceCmd
+	if len(cmd) > 0 {
+		c = cmd[0]
+	}
+	if e = r.Call("CAPI.GetAddressBalance", c, &res); E.Chk(e) {
+	}
+	return
+}
+
+func (r *CAPIClient) GetAddressDeltas(cmd ...*btcjson.GetAddressDeltasCmd) (res []btcjson.GetAddressDeltasResult, e error) {
+	var c *btcjson.GetAddressDeltasCmd
+	if len(cmd) > 0 {
+		c = cmd[0]
+	}
+	if e = r.Call("CAPI.GetAddressDeltas", c, &res); E.Chk(e) {
+	}
+	return
+}
+
+func (r *CAPIClient) GetAddressTxIDs(cmd ...*btcjson.GetAddressTxIDsCmd) (res []string, e error) {
+	var c *btcjson.GetAddressTxIDsCmd
+	if len(cmd) > 0 {
+		c = cmd[0]
+	}
+	if e = r.Call("CAPI.GetAddressTxIDs", c, &res); E.Chk(e) {
+	}
+	return
+}
+
+func (r *CAPIClient) GetAddressUtxos(cmd ...*btcjson.GetAddressUtxosCmd) (res []btcjson.GetAddressUtxosResult, e error) {
+	var c *btcjson.GetAddressUtxosCmd
+	if len(cmd) > 0 {
+		c = cmd[0]
+	}
+	if e = r.Call("CAPI.GetAddressUtxos", c, &res); E.Chk(e) {
+	}
+	return
+}
+
+func (r *CAPIClient) GetAlgoStats(cmd ...*btcjson.GetAlgoStatsCmd) (res btcjson.GetAlgoStatsResult, e error) {
+	var c *btcjson.GetAlgoStatsCmd
+	if len(cmd) > 0 {
+		c = cmd[0]
+	}
+	if e = r.Call("CAPI.GetAlgoStats", c, &res); E.Chk(e) {
+	}
+	return
+}
+
 func (r *CAPIClient) GetBestBlock(cmd ...*None) (res btcjson.GetBestBlockResult, e error) {
 	var c *None
 	if len(cmd) > 0 {
@@ -3328,6 +4223,16 @@ func (r *CAPIClient) GetCFilterHeader(cmd ...*btcjson.GetCFilterHeaderCmd) (res
 	return
 }
 
+func (r *CAPIClient) GetChainTips(cmd ...*None) (res []btcjson.GetChainTipsResult, e error) {
+	var c *None
+	if len(cmd) > 0 {
+		c = cmd[0]
+	}
+	if e = r.Call("CAPI.GetChainTips", c, &res); E.Chk(e) {
+	}
+	return
+}
+
 func (r *CAPIClient) GetConnectionCount(cmd ...*None) (res int32, e error) {
 	var c *None
 	if len(cmd) > 0 {
@@ -3398,6 +4303,16 @@ func (r *CAPIClient) GetInfo(cmd ...*None) (res btcjson.InfoChainResult0, e erro
 	return
 }
 
+func (r *CAPIClient) GetMempoolEntry(cmd ...*btcjson.GetMempoolEntryCmd) (res btcjson.GetMempoolEntryResult, e error) {
+	var c *btcjson.GetMempoolEntryCmd
+	if len(cmd) > 0 {
+		c = cmd[0]
+	}
+	if e = r.Call("CAPI.GetMempoolEntry", c, &res); E.Chk(e) {
+	}
+	return
+}
+
 func (r *CAPIClient) GetMempoolInfo(cmd ...*None) (res btcjson.GetMempoolInfoResult, e error) {
 	var c *None
 	if len(cmd) > 0 {
@@ -3438,6 +4353,16 @@ func (r *CAPIClient) GetNetworkHashPS(cmd ...*btcjson.GetNetworkHashPSCmd) (res
 	return
 }
 
+func (r *CAPIClient) GetNetworkInfo(cmd ...*None) (res btcjson.GetNetworkInfoResult, e error) {
+	var c *None
+	if len(cmd) > 0 {
+		c = cmd[0]
+	}
+	if e = r.Call("CAPI.GetNetworkInfo", c, &res); E.Chk(e) {
+	}
+	return
+}
+
 func (r *CAPIClient) GetPeerInfo(cmd ...*None) (res []btcjson.GetPeerInfoResult, e error) {
 	var c *None
 	if len(cmd) > 0 {
@@ -3478,6 +4403,26 @@ func (r *CAPIClient) GetTxOut(cmd ...*btcjson.GetTxOutCmd) (res string, e error)
 	return
 }
 
+func (r *CAPIClient) GetTxOutSetInfo(cmd ...*None) (res btcjson.GetTxOutSetInfoResult, e error) {
+	var c *None
+	if len(cmd) > 0 {
+		c = cmd[0]
+	}
+	if e = r.Call("CAPI.GetTxOutSetInfo", c, &res); E.Chk(e) {
+	}
+	return
+}
+
+func (r *CAPIClient) GetWork(cmd ...*btcjson.GetWorkCmd) (res btcjson.GetWorkResult, e error) {
+	var c *btcjson.GetWorkCmd
+	if len(cmd) > 0 {
+		c = cmd[0]
+	}
+	if e = r.Call("CAPI.GetWork", c, &res); E.Chk(e) {
+	}
+	return
+}
+
 func (r *CAPIClient) Help(cmd ...*btcjson.HelpCmd) (res string, e error) {
 	var c *btcjson.HelpCmd
 	if len(cmd) > 0 {
@@ -3488,6 +4433,16 @@ func (r *CAPIClient) Help(cmd ...*btcjson.HelpCmd) (res string, e error) {
 	return
 }
 
+func (r *CAPIClient) InvalidateBlock(cmd ...*btcjson.InvalidateBlockCmd) (res None, e error) {
+	var c *btcjson.InvalidateBlockCmd
+	if len(cmd) > 0 {
+		c = cmd[0]
+	}
+	if e = r.Call("CAPI.InvalidateBlock", c, &res); E.Chk(e) {
+	}
+	return
+}
+
 func (r *CAPIClient) Node(cmd ...*btcjson.NodeCmd) (res None, e error) {
 	var c *btcjson.NodeCmd
 	if len(cmd) > 0 {
@@ -3508,6 +4463,26 @@ func (r *CAPIClient) Ping(cmd ...*None) (res None, e error) {
 	return
 }
 
+func (r *CAPIClient) PreciousBlock(cmd ...*btcjson.PreciousBlockCmd) (res None, e error) {
+	var c *btcjson.PreciousBlockCmd
+	if len(cmd) > 0 {
+		c = cmd[0]
+	}
+	if e = r.Call("CAPI.PreciousBlock", c, &res); E.Chk(e) {
+	}
+	return
+}
+
+func (r *CAPIClient) ReconsiderBlock(cmd ...*btcjson.ReconsiderBlockCmd) (res None, e error) {
+	var c *btcjson.ReconsiderBlockCmd
+	if len(cmd) > 0 {
+		c = cmd[0]
+	}
+	if e = r.Call("CAPI.ReconsiderBlock", c, &res); E.Chk(e) {
+	}
+	return
+}
+
 func (r *CAPIClient) ResetChain(cmd ...*None) (res None, e error) {
 	var c *None
 	if len(cmd) > 0 {
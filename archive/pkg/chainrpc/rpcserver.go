@@ -27,6 +27,7 @@ import (
 	"github.com/btcsuite/websocket"
 	uberatomic "go.uber.org/atomic"
 
+	"github.com/p9c/pod/pkg/addrmgr"
 	"github.com/p9c/pod/pkg/amt"
 	"github.com/p9c/pod/pkg/bits"
 	"github.com/p9c/pod/pkg/block"
@@ -73,6 +74,8 @@ type GBTWorkState struct {
 	Algo          string
 	StateCfg      *active.Config
 	Config        *config.Config
+	// payouts are the addresses the coinbase of the template was created for, when they were requested or configured
+	payouts []mining.Payout
 }
 
 // ParsedRPCCmd represents a JSON-RPC request object that has been parsed into a known concrete command along with any
@@ -108,6 +111,7 @@ type Server struct {
 	StatusLock                      sync.RWMutex
 	WG                              sync.WaitGroup
 	GBTWorkState                    *GBTWorkState
+	GetWorkState                    *GetWorkState
 	HelpCacher                      *HelpCacher
 	RequestProcessShutdown          qu.C
 	Quit                            qu.C
@@ -159,7 +163,8 @@ type ServerConfig struct {
 	// The fee estimator keeps track of how long transactions are left in the mempool before they are mined into blocks.
 	FeeEstimator *mempool.FeeEstimator
 	// Algo sets the algorithm expected from the RPC endpoint. This allows multiple ports to serve multiple types of
-	// miners with one main node per algorithm. Currently 514 for Scrypt and anything else passes for SHA256d.
+	// miners with one main node per algorithm. It is the name of the algorithm getwork hands out work for, and getwork
+	// is refused at heights where it is not an algorithm of the current hard fork.
 	Algo string
 	// CPUMiner *exec.Cmd
 	Hashrate                        uberatomic.Uint64
@@ -196,6 +201,10 @@ type ServerConnManager interface {
 	ConnectedCount() int32
 	// NetTotals returns the sum of all bytes received and sent across the network for all peers.
 	NetTotals() (uint64, uint64)
+	// LocalAddresses returns the local addresses advertised to peers along with their scores.
+	LocalAddresses() []addrmgr.LocalAddress
+	// Services returns the service flags advertised to peers.
+	Services() wire.ServiceFlag
 	// ConnectedPeers returns an array consisting of all connected peers.
 	ConnectedPeers() []ServerPeer
 	// PersistentPeers returns an array consisting of all the persistent peers.
@@ -327,7 +336,6 @@ var (
 		"getreceivedbyaccount":   {},
 		"getreceivedbyaddress":   {},
 		"gettransaction":         {},
-		"gettxoutsetinfo":        {},
 		"getunconfirmedbalance":  {},
 		"getwalletinfo":          {},
 		"importprivkey":          {},
@@ -662,23 +670,32 @@ var (
 		"decoderawtransaction":  {},
 		"decodescript":          {},
 		"estimatefee":           {},
+		"getaddressbalance":     {},
+		"getaddressdeltas":      {},
+		"getaddresstxids":       {},
+		"getaddressutxos":       {},
+		"getalgostats":          {},
 		"getbestblock":          {},
 		"getbestblockhash":      {},
 		"getblock":              {},
 		"getblockcount":         {},
 		"getblockhash":          {},
 		"getblockheader":        {},
+		"getchaintips":          {},
 		"getcfilter":            {},
 		"getcfilterheader":      {},
 		"getcurrentnet":         {},
 		"getdifficulty":         {},
 		"getheaders":            {},
 		"getinfo":               {},
+		"getmempoolentry":       {},
 		"getnettotals":          {},
 		"getnetworkhashps":      {},
+		"getnetworkinfo":        {},
 		"getrawmempool":         {},
 		"getrawtransaction":     {},
 		"gettxout":              {},
+		"gettxoutsetinfo":       {},
 		"searchrawtransactions": {},
 		"sendrawtransaction":    {},
 		"submitblock":           {},
@@ -690,13 +707,6 @@ var (
 	// RPCUnimplemented is commands that are currently unimplemented, but should ultimately be.
 	RPCUnimplemented = map[string]struct{}{
 		"estimatepriority": {},
-		"getchaintips":     {},
-		"getmempoolentry":  {},
-		"getnetworkinfo":   {},
-		"getwork":          {},
-		"invalidateblock":  {},
-		"preciousblock":    {},
-		"reconsiderblock":  {},
 	}
 )
 
@@ -839,7 +849,12 @@ func (state *GBTWorkState) BlockTemplateResult(
 	// }
 	if useCoinbaseValue {
 		reply.CoinbaseAux = GBTCoinbaseAux
-		reply.CoinbaseValue = &msgBlock.Transactions[0].TxOut[0].Value
+		// the coinbase may pay several addresses, the value a miner can claim is the total of all its outputs
+		var coinbaseValue int64
+		for _, txOut := range msgBlock.Transactions[0].TxOut {
+			coinbaseValue += txOut.Value
+		}
+		reply.CoinbaseValue = &coinbaseValue
 	} else {
 		// Ensure the template has a valid payment address associated with it when a full coinbase is requested.
 		if !template.ValidPayAddress {
@@ -948,12 +963,14 @@ func (state *GBTWorkState) TemplateUpdateChan(
 //
 // Finally, if the useCoinbaseValue flag is false and the existing block template does not already contain a valid
 // payment address, the block template will be updated with a randomly selected payment address from the list of
-// configured addresses.
+// configured addresses. When payouts are given and the useCoinbaseValue flag is false, the coinbase splits the block
+// reward between them instead, and a new template is generated if the existing one was made for other payouts.
 //
 // This function MUST be called with the state locked.
 func (state *GBTWorkState) UpdateBlockTemplate(
 	s *Server,
 	useCoinbaseValue bool,
+	payouts []mining.Payout,
 ) (e error) {
 	generator := s.Cfg.Generator
 	lastTxUpdate := generator.GetTxSource().LastUpdated()
@@ -968,6 +985,7 @@ func (state *GBTWorkState) UpdateBlockTemplate(
 	template := state.Template
 	if template == nil || state.prevHash == nil ||
 		!state.prevHash.IsEqual(latestHash) ||
+		(!useCoinbaseValue && !mining.SamePayouts(state.payouts, payouts)) ||
 		(state.LastTxUpdate != lastTxUpdate &&
 			time.Now().After(
 				state.LastGenerated.Add(
@@ -980,20 +998,26 @@ func (state *GBTWorkState) UpdateBlockTemplate(
 		state.prevHash = nil
 		// Choose a payment address at random if the caller requests a full coinbase as opposed to only the pertinent
 		// details needed to create their own coinbase.
-		var payAddr btcaddr.Address
+		var templatePayouts []mining.Payout
+		state.payouts = nil
 		if !useCoinbaseValue {
-			payAddr = s.StateCfg.ActiveMiningAddrs[rand.Intn(
-				len(
-					s.StateCfg.
-						ActiveMiningAddrs,
-				),
-			)]
+			if len(payouts) > 0 {
+				templatePayouts = payouts
+				state.payouts = payouts
+			} else {
+				templatePayouts = []mining.Payout{
+					{
+						Address: s.StateCfg.ActiveMiningAddrs[rand.Intn(len(s.StateCfg.ActiveMiningAddrs))],
+						Weight:  1,
+					},
+				}
+			}
 		}
 		// Create a new block template that has a coinbase which anyone can redeem.
 		//
 		// This is only acceptable because the returned block template doesn't include the coinbase, so the caller will
 		// ultimately create their own coinbase which pays to the appropriate address(es).
-		blkTemplate, e := generator.NewBlockTemplate(payAddr, state.Algo)
+		blkTemplate, e := generator.NewBlockTemplateWithPayouts(templatePayouts, state.Algo)
 		if e != nil {
 			return InternalRPCError(
 				"(rpcserver.go) Failed to create new block "+
@@ -1082,6 +1106,21 @@ func (state *GBTWorkState) UpdateBlockTemplate(
 	return nil
 }
 
+// MiningPayouts returns the payouts configured with --miningpayouts, or else a payout to an address chosen at random
+// from the ones configured with --miningaddr. It returns nil when neither is configured.
+func (s *Server) MiningPayouts() []mining.Payout {
+	if len(s.StateCfg.ActiveMiningPayouts) > 0 {
+		return s.StateCfg.ActiveMiningPayouts
+	}
+	if len(s.StateCfg.ActiveMiningAddrs) == 0 {
+		return nil
+	}
+	rand.Seed(time.Now().UnixNano())
+	return []mining.Payout{
+		{Address: s.StateCfg.ActiveMiningAddrs[rand.Intn(len(s.StateCfg.ActiveMiningAddrs))], Weight: 1},
+	}
+}
+
 // NotifyNewTransactions notifies both websocket and getblocktemplate long poll clients of the passed transactions.
 //
 // This function should be called whenever new transactions are added to the mempool.
@@ -1156,6 +1195,8 @@ func (s *Server) Start() {
 			s.WebsocketHandler(ws, r.RemoteAddr, authenticated, isAdmin)
 		},
 	)
+	// REST endpoint, which doesn't require authentication as it only serves public data.
+	s.RegisterREST(rpcServeMux)
 	for _, listener := range s.Cfg.Listeners {
 		s.WG.Add(1)
 		go func(listener net.Listener) {
@@ -2089,6 +2130,7 @@ func NewRPCServer(
 		StateCfg:               statecfg,
 		StatusLines:            make(map[int]string),
 		GBTWorkState:           NewGbtWorkState(config.TimeSource, config.Algo),
+		GetWorkState:           NewGetWorkState(),
 		HelpCacher:             NewHelpCacher(),
 		RequestProcessShutdown: qu.T(),
 		Quit:                   qu.T(),
@@ -2161,6 +2203,22 @@ func DecodeHexError(gotHex string) *btcjson.RPCError {
 	)
 }
 
+// KnownBlockHash decodes the provided block hash string and checks that the block is in the block index, returning a
+// nicely formatted RPC error if either fails.
+func KnownBlockHash(s *Server, hashStr string) (*chainhash.Hash, error) {
+	hash, e := chainhash.NewHashFromStr(hashStr)
+	if e != nil {
+		return nil, DecodeHexError(hashStr)
+	}
+	if s.Cfg.Chain.Index.LookupNode(hash) == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCBlockNotFound,
+			Message: "Block not found",
+		}
+	}
+	return hash, nil
+}
+
 // NoTxInfoError is a convenience function for returning a nicely formatted RPC
 // error which indicates there is no information available for the provided
 // transaction hash.
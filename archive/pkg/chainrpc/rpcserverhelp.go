@@ -141,6 +141,67 @@ var HelpDescsEnUS = map[string]string{
 	"getaddednodeinfo--condition0": "dns=false",
 	"getaddednodeinfo--condition1": "dns=true",
 	"getaddednodeinfo--result0":    "List of added peers",
+	// AddressRequest help.
+	"addressrequest-addresses": "The addresses",
+	"addressrequest-start":     "The height of the first block to include, zero for no limit",
+	"addressrequest-end":       "The height of the last block to include, zero for no limit",
+	// GetAddressBalanceCmd help.
+	"getaddressbalance--synopsis": "Returns the balance of the addresses and the total amount they have received in satoshis.\n" +
+		"Requires the address index to be enabled.",
+	"getaddressbalance-request": "JSON object with the addresses",
+	// GetAddressBalanceResult help.
+	"getaddressbalanceresult-balance":  "The sum of the unspent outputs paying the addresses",
+	"getaddressbalanceresult-received": "The sum of all outputs that have paid the addresses",
+	// GetAddressDeltasCmd help.
+	"getaddressdeltas--synopsis": "Returns the changes of the balances of the addresses by the outputs paying them and the inputs spending those outputs, in the order of the block chain.\n" +
+		"Requires the address index to be enabled.",
+	"getaddressdeltas-request": "JSON object with the addresses and optionally the range of block heights",
+	// GetAddressDeltasResult help.
+	"getaddressdeltasresult-satoshis":   "The amount the balance changed by, negative for inputs",
+	"getaddressdeltasresult-txid":       "The hash of the transaction",
+	"getaddressdeltasresult-index":      "The index of the output, or of the input for negative amounts",
+	"getaddressdeltasresult-blockindex": "The position of the transaction in its block",
+	"getaddressdeltasresult-height":     "The height of the block of the transaction",
+	"getaddressdeltasresult-address":    "The address whose balance changed",
+	// GetAddressTxIDsCmd help.
+	"getaddresstxids--synopsis": "Returns the hashes of the transactions involving the addresses in the order of the block chain.\n" +
+		"Requires the address index to be enabled.",
+	"getaddresstxids-request": "JSON object with the addresses and optionally the range of block heights",
+	"getaddresstxids--result0": "The hashes of the transactions",
+	// GetAddressUtxosCmd help.
+	"getaddressutxos--synopsis": "Returns the unspent outputs paying the addresses.\n" +
+		"Requires the address index to be enabled.",
+	"getaddressutxos-request": "JSON object with the addresses",
+	// GetAddressUtxosResult help.
+	"getaddressutxosresult-address":     "The address the output pays",
+	"getaddressutxosresult-txid":        "The hash of the transaction of the output",
+	"getaddressutxosresult-outputIndex": "The index of the output in its transaction",
+	"getaddressutxosresult-script":      "The hex-encoded public key script of the output",
+	"getaddressutxosresult-satoshis":    "The amount of the output",
+	"getaddressutxosresult-height":      "The height of the block of the transaction",
+	// GetAlgoStatsCmd help.
+	"getalgostats--synopsis": "Returns the statistics of each of the plan 9 proof of work algorithms over a window of blocks ending at the best block.",
+	"getalgostats-blocks":    "The number of blocks in the window",
+	// GetAlgoStatsResult help.
+	"getalgostatsresult-height":             "The height of the best block",
+	"getalgostatsresult-blocks":             "The number of blocks in the window",
+	"getalgostatsresult-timespan":           "The number of seconds between the first and last block of the window",
+	"getalgostatsresult-alltimeaverage":     "The average number of seconds between blocks since the hard fork",
+	"getalgostatsresult-alltimedivisor":     "The adjustment of the all time average of the difficulty adjustment",
+	"getalgostatsresult-quarterhourdivisor": "The adjustment of the quarter hour average of the difficulty adjustment",
+	"getalgostatsresult-hourdivisor":        "The adjustment of the hour average of the difficulty adjustment",
+	"getalgostatsresult-daydivisor":         "The adjustment of the day average of the difficulty adjustment",
+	"getalgostatsresult-algos":              "The statistics of each algorithm",
+	// GetAlgoStatsResultAlgo help.
+	"getalgostatsresultalgo-algo":            "The name of the algorithm",
+	"getalgostatsresultalgo-version":         "The block version of the algorithm",
+	"getalgostatsresultalgo-blocks":          "The number of blocks in the window mined with the algorithm",
+	"getalgostatsresultalgo-averageinterval": "The weighted moving average of the seconds between blocks of the algorithm",
+	"getalgostatsresultalgo-targetinterval":  "The target number of seconds between blocks of the algorithm",
+	"getalgostatsresultalgo-adjustment":      "The ratio of the average interval to the target interval",
+	"getalgostatsresultalgo-bits":            "The hex-encoded target of the next block of the algorithm",
+	"getalgostatsresultalgo-difficulty":      "The difficulty of the next block of the algorithm",
+	"getalgostatsresultalgo-networkhashps":   "The estimated hashes per second of the algorithm over the window",
 	// GetBestBlockResult help.
 	"getbestblockresult-hash":   "Hex-encoded bytes of the best block hash",
 	"getbestblockresult-height": "Height of the best block",
@@ -290,6 +351,10 @@ var HelpDescsEnUS = map[string]string{
 	"templaterequest-target":     "The desired target for the block template (this parameter is ignored)",
 	"templaterequest-data":       "Hex-encoded block data (only for mode=proposal)",
 	"templaterequest-workid":     "The Server provided workid if provided in block template (not applicable)",
+	"templaterequest-payouts":    "Addresses to split the block reward between in the coinbase transaction, implies the coinbasetxn capability",
+	// TemplateRequestPayout help.
+	"templaterequestpayout-address": "The address to pay to",
+	"templaterequestpayout-weight":  "The weight of the address, the block reward is split in proportion to the weights",
 	// GetBlockTemplateResultTx help.
 	"getblocktemplateresulttx-data": "Hex-encoded transaction data (byte-for-byte)",
 	"getblocktemplateresulttx-hash": "Hex-encoded transaction hash (little endian if treated as a 256-bit number)",
@@ -368,6 +433,15 @@ var HelpDescsEnUS = map[string]string{
 	"getcfilterheader-hash":       "The hash of the block",
 	"getcfilterheader--result0":   "The block's gcs filter header",
 	
+	// GetChainTipsCmd help.
+	"getchaintips--synopsis": "Returns information about all known tips in the block tree, including the main chain and orphaned branches.",
+	
+	// GetChainTipsResult help.
+	"getchaintipsresult-height":    "Height of the chain tip",
+	"getchaintipsresult-hash":      "Hex-encoded hash of the chain tip",
+	"getchaintipsresult-branchlen": "Number of blocks between the tip and the main chain, zero for the main chain",
+	"getchaintipsresult-status":    "Status of the branch: active (the main chain), valid-fork (fully validated but not the main chain), valid-headers (all blocks available but not fully validated), headers-only (some blocks are missing) or invalid (contains an invalid block)",
+	
 	// GetConnectionCountCmd help.
 	"getconnectioncount--synopsis": "Returns the number of active connections to other peers.",
 	"getconnectioncount--result0":  "The number of connections",
@@ -427,6 +501,26 @@ var HelpDescsEnUS = map[string]string{
 	// GetInfoCmd help.
 	"getinfo--synopsis": "Returns a JSON object containing various state info.",
 	
+	// GetMempoolEntryCmd help.
+	"getmempoolentry--synopsis": "Returns mempool data for the given transaction.",
+	"getmempoolentry-txid":      "The hash of the transaction",
+	
+	// GetMempoolEntryResult help.
+	"getmempoolentryresult-size":             "Virtual transaction size",
+	"getmempoolentryresult-fee":              "Transaction fee in DUO",
+	"getmempoolentryresult-modifiedfee":      "Transaction fee with fee deltas used for mining priority",
+	"getmempoolentryresult-time":             "Local time the transaction entered the pool in seconds since 1 Jan 1970 GMT",
+	"getmempoolentryresult-height":           "Block height when the transaction entered the pool",
+	"getmempoolentryresult-startingpriority": "Priority when the transaction entered the pool",
+	"getmempoolentryresult-currentpriority":  "Current priority",
+	"getmempoolentryresult-descendantcount":  "Number of in-mempool descendant transactions, including this one",
+	"getmempoolentryresult-descendantsize":   "Virtual size of in-mempool descendants, including this one",
+	"getmempoolentryresult-descendantfees":   "Fees of in-mempool descendants, including this one, in DUO",
+	"getmempoolentryresult-ancestorcount":    "Number of in-mempool ancestor transactions, including this one",
+	"getmempoolentryresult-ancestorsize":     "Virtual size of in-mempool ancestors, including this one",
+	"getmempoolentryresult-ancestorfees":     "Fees of in-mempool ancestors, including this one, in DUO",
+	"getmempoolentryresult-depends":          "Unconfirmed transactions used as inputs for this transaction",
+	
 	// GetMempoolInfoCmd help.
 	"getmempoolinfo--synopsis": "Returns memory pool information",
 	
@@ -465,6 +559,36 @@ var HelpDescsEnUS = map[string]string{
 	"getnettotalsresult-totalbytessent": "Total bytes sent",
 	"getnettotalsresult-timemillis":     "Number of milliseconds since 1 Jan 1970 GMT",
 	
+	// GetNetworkInfoCmd help.
+	"getnetworkinfo--synopsis": "Returns a JSON object containing various state info regarding P2P networking.",
+	
+	// GetNetworkInfoResult help.
+	"getnetworkinforesult-version":         "The version of the node as a numeric value",
+	"getnetworkinforesult-subversion":      "The user agent the node advertises to its peers",
+	"getnetworkinforesult-protocolversion": "The latest supported protocol version",
+	"getnetworkinforesult-localservices":   "The services the node offers to the network as 16 hex digits",
+	"getnetworkinforesult-localrelay":      "Whether transactions are requested from and relayed to peers",
+	"getnetworkinforesult-timeoffset":      "The time offset in seconds",
+	"getnetworkinforesult-connections":     "The number of connected peers",
+	"getnetworkinforesult-networkactive":   "Whether p2p networking is enabled",
+	"getnetworkinforesult-networks":        "Information about each network type",
+	"getnetworkinforesult-relayfee":        "Minimum relay fee for transactions in DUO/kB",
+	"getnetworkinforesult-incrementalfee":  "Minimum fee increment for mempool limiting or replacement in DUO/kB",
+	"getnetworkinforesult-localaddresses":  "The local addresses advertised to peers",
+	"getnetworkinforesult-warnings":        "Any network warnings",
+	
+	// NetworksResult help.
+	"networksresult-name":                        "The network name, one of ipv4, ipv6 or onion",
+	"networksresult-limited":                     "Whether connections are limited to other networks",
+	"networksresult-reachable":                   "Whether addresses on this network can be connected to",
+	"networksresult-proxy":                       "The proxy used for this network, empty if none",
+	"networksresult-proxy_randomize_credentials": "Whether random proxy credentials are used for tor stream isolation",
+	
+	// LocalAddressesResult help.
+	"localaddressesresult-address": "The local address",
+	"localaddressesresult-port":    "The port the address is advertised with",
+	"localaddressesresult-score":   "The priority of the address",
+	
 	// GetPeerInfoResult help.
 	"getpeerinforesult-id":             "A unique node ID",
 	"getpeerinforesult-addr":           "The ip address and port of the peer",
@@ -531,6 +655,35 @@ var HelpDescsEnUS = map[string]string{
 	"gettxout-vout":           "The index of the output",
 	"gettxout-includemempool": "Include the mempool when true",
 	
+	// GetTxOutSetInfoCmd help.
+	"gettxoutsetinfo--synopsis": "Returns statistics about the unspent transaction output set.",
+	
+	// GetTxOutSetInfoResult help.
+	"gettxoutsetinforesult-height":         "The height of the best block",
+	"gettxoutsetinforesult-bestblock":      "The hash of the best block",
+	"gettxoutsetinforesult-transactions":   "The number of transactions with unspent outputs",
+	"gettxoutsetinforesult-txouts":         "The number of unspent transaction outputs",
+	"gettxoutsetinforesult-bogosize":       "The size of the unspent output set estimated the way bitcoind does, which doesn't depend on the database",
+	"gettxoutsetinforesult-serializedsize": "The size of the unspent outputs as they are stored in the database",
+	"gettxoutsetinforesult-muhash":         "The MuHash3072 of the unspent output set, computed the same way as bitcoind",
+	"gettxoutsetinforesult-total_amount":   "The total amount of the unspent outputs in DUO",
+	
+	// GetWorkResult help.
+	"getworkresult-data":     "Hex-encoded block header and sha256 padding, each 32-bit word byte swapped",
+	"getworkresult-hash1":    "Hex-encoded zero hash with sha256 padding, kept for compatibility with legacy miners",
+	"getworkresult-midstate": "Hex-encoded sha256 state after the first 64 bytes of the block header",
+	"getworkresult-target":   "Hex-encoded little-endian target the block hash must not exceed",
+	
+	// GetWorkCmd help.
+	"getwork--synopsis": "Returns formatted block header data to work on, or checks and submits solved data.\n" +
+		"The work is for the algorithm asked for, or else for the algorithm served by the RPC endpoint, sha256d on the main RPC listeners and scrypt on the scrypt RPC listeners.\n" +
+		"After the plan 9 hard fork neither sha256d nor scrypt is used any more, and an algorithm of the hard fork must be asked for.",
+	"getwork-data":        "Hex-encoded data of a solved block returned by an earlier call",
+	"getwork-algo":        "The name or block version of the algorithm to work on, defaults to the algorithm of the RPC endpoint",
+	"getwork--condition0": "no data provided",
+	"getwork--condition1": "data provided",
+	"getwork--result1":    "Whether or not the solved data is valid and was accepted as a new block",
+	
 	// HelpCmd help.
 	"help--synopsis":   "Returns a list of all commands or help for a specified command.",
 	"help-command":     "The command to retrieve help for",
@@ -539,10 +692,25 @@ var HelpDescsEnUS = map[string]string{
 	"help--result0":    "List of commands",
 	"help--result1":    "Help for specified command",
 	
+	// InvalidateBlockCmd help.
+	"invalidateblock--synopsis": "Permanently marks a block and all of its descendants as invalid, as if it violated a consensus rule.\n" +
+		"If the block is in the main chain the chain is reorganized onto the best remaining valid branch.",
+	"invalidateblock-blockhash": "The hash of the block to mark as invalid",
+	
 	// PingCmd help.
 	"ping--synopsis": "Queues a ping to be sent to each connected peer.\n" +
 		"Ping times are provided by getpeerinfo via the pingtime and pingwait fields.",
 	
+	// PreciousBlockCmd help.
+	"preciousblock--synopsis": "Treats a block as if it were received before others with the same height.\n" +
+		"The chain is reorganized onto the block if its branch is at least as long as the main chain.",
+	"preciousblock-blockhash": "The hash of the block to mark as precious",
+	
+	// ReconsiderBlockCmd help.
+	"reconsiderblock--synopsis": "Removes the invalid status of a block, its ancestors and its descendants, undoing the effect of invalidateblock.\n" +
+		"The blocks are validated again and the chain is reorganized onto them if they form the best branch.",
+	"reconsiderblock-blockhash": "The hash of the block to reconsider",
+	
 	// SearchRawTransactionsCmd help.
 	"searchrawtransactions--synopsis": "Returns raw data for transactions involving the passed address.\n" +
 		"Returned transactions are pulled from both the database, and transactions currently in the mempool.\n" +
@@ -708,6 +876,11 @@ var ResultTypes = map[string][]interface{}{
 	"estimatefee":           {(*float64)(nil)},
 	"generate":              {(*[]string)(nil)},
 	"getaddednodeinfo":      {(*[]string)(nil), (*[]btcjson.GetAddedNodeInfoResult)(nil)},
+	"getaddressbalance":     {(*btcjson.GetAddressBalanceResult)(nil)},
+	"getaddressdeltas":      {(*[]btcjson.GetAddressDeltasResult)(nil)},
+	"getaddresstxids":       {(*[]string)(nil)},
+	"getaddressutxos":       {(*[]btcjson.GetAddressUtxosResult)(nil)},
+	"getalgostats":          {(*btcjson.GetAlgoStatsResult)(nil)},
 	"getbestblock":          {(*btcjson.GetBestBlockResult)(nil)},
 	"getbestblockhash":      {(*string)(nil)},
 	"getblock":              {(*string)(nil), (*btcjson.GetBlockVerboseResult)(nil)},
@@ -719,23 +892,31 @@ var ResultTypes = map[string][]interface{}{
 	"getcfilter":            {(*string)(nil)},
 	"getcfilterheader":      {(*string)(nil)},
 	"getconnectioncount":    {(*int32)(nil)},
+	"getchaintips":          {(*[]btcjson.GetChainTipsResult)(nil)},
 	"getcurrentnet":         {(*uint32)(nil)},
 	"getdifficulty":         {(*float64)(nil)},
 	"getgenerate":           {(*bool)(nil)},
 	"gethashespersec":       {(*float64)(nil)},
 	"getheaders":            {(*[]string)(nil)},
 	"getinfo":               {(*btcjson.InfoChainResult)(nil)},
+	"getmempoolentry":       {(*btcjson.GetMempoolEntryResult)(nil)},
 	"getmempoolinfo":        {(*btcjson.GetMempoolInfoResult)(nil)},
 	"getmininginfo":         {(*btcjson.GetMiningInfoResult)(nil)},
 	"getnettotals":          {(*btcjson.GetNetTotalsResult)(nil)},
 	"getnetworkhashps":      {(*int64)(nil)},
+	"getnetworkinfo":        {(*btcjson.GetNetworkInfoResult)(nil)},
 	"getpeerinfo":           {(*[]btcjson.GetPeerInfoResult)(nil)},
 	"getrawmempool":         {(*[]string)(nil), (*btcjson.GetRawMempoolVerboseResult)(nil)},
 	"getrawtransaction":     {(*string)(nil), (*btcjson.TxRawResult)(nil)},
 	"gettxout":              {(*btcjson.GetTxOutResult)(nil)},
+	"gettxoutsetinfo":       {(*btcjson.GetTxOutSetInfoResult)(nil)},
+	"getwork":               {(*btcjson.GetWorkResult)(nil), (*bool)(nil)},
 	"node":                  nil,
 	"help":                  {(*string)(nil), (*string)(nil)},
+	"invalidateblock":       nil,
 	"ping":                  nil,
+	"preciousblock":         nil,
+	"reconsiderblock":       nil,
 	"searchrawtransactions": {(*string)(nil), (*[]btcjson.SearchRawTransactionsResult)(nil)},
 	"sendrawtransaction":    {(*string)(nil)},
 	"setgenerate":           nil,
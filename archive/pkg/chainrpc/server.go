@@ -157,6 +157,8 @@ type (
 		TxIndex   *indexers.TxIndex
 		AddrIndex *indexers.AddrIndex
 		CFIndex   *indexers.CFIndex
+		// ZMQ publishes blocks and transactions on the configured zmq endpoints, it is nil if none are configured.
+		ZMQ *ZMQNotifier
 		// The fee estimator keeps track of how long transactions are left in the mempool before they are mined into
 		// blocks.
 		FeeEstimator *mempool.FeeEstimator
@@ -173,12 +175,23 @@ type (
 		HighestKnown                    uberatomic.Int32
 		peerState                       *PeerState
 		StartController, StopController qu.C
+		// netGroupKey is the secret key the network groups of inbound peers are compared under when protecting them
+		// from eviction.
+		netGroupKey [16]byte
+		// newAddress returns an address from the address manager to make an outbound connection to, it is nil when
+		// only the configured peers are connected to.
+		newAddress func() (net.Addr, error)
 	}
 	// NodePeer extends the peer to maintain state shared by the server and the blockmanager.
 	NodePeer struct {
 		*peer.Peer
 		// The following variables must only be used atomically
-		FeeFilter      int64
+		FeeFilter int64
+		// MinPingMicros is the lowest ping time of the peer, LastBlockTime and LastTxTime are the times in nanoseconds
+		// it last relayed a new block and transaction. They protect inbound peers from eviction.
+		MinPingMicros  int64
+		LastBlockTime  int64
+		LastTxTime     int64
 		ConnReq        *connmgr.ConnReq
 		Server         *Node
 		ContinueHash   *chainhash.Hash
@@ -194,6 +207,8 @@ type (
 		IsWhitelisted  bool
 		Persistent     bool
 		DisableRelayTx bool
+		// BlockRelayOnly is set for the outbound peers that only blocks are relayed with.
+		BlockRelayOnly bool
 		IP             net.IP
 		Port           uint16
 	}
@@ -220,12 +235,21 @@ const (
 		/*wire.SFNodeWitness |*/ wire.SFNodeCF
 	// DefaultRequiredServices describes the default services that are required to be supported by outbound peers.
 	DefaultRequiredServices = wire.SFNodeNetwork
+	// MinPruneTarget is the smallest prune target in MiB, which leaves room for the most recent blocks after the oldest
+	// block files are deleted.
+	MinPruneTarget = 1024
 	// DefaultTargetOutbound is the default number of outbound peers to target.
 	DefaultTargetOutbound = 125
 	// ConnectionRetryInterval is the base amount of time to wait in between retries
 	// when connecting to persistent peers. It is adjusted by the number of retries
 	// such that there is a retry backoff.
 	ConnectionRetryInterval = time.Minute
+	// MaxCmpctBlockDepth is the number of blocks below the best chain tip up to which blocks requested with a
+	// MSG_CMPCT_BLOCK inventory vector are sent as compact blocks rather than in full.
+	MaxCmpctBlockDepth = 10
+	// MaxBlockTxnDepth is the number of blocks below the best chain tip up to which the transactions of a block are
+	// sent in answer to a getblocktxn message rather than the full block.
+	MaxBlockTxnDepth = 15
 )
 
 var (
@@ -318,6 +342,9 @@ func (n *Node) AnnounceNewTransactions(txns []*mempool.TxDesc) {
 			n.RPCServers[i].NotifyNewTransactions(txns)
 		}
 	}
+	if n.ZMQ != nil {
+		n.ZMQ.NotifyNewTransactions(txns)
+	}
 }
 
 // BanPeer bans a peer that has already been connected to the server by ip.
@@ -429,6 +456,11 @@ func (n *Node) Start() {
 		n.WG.Add(1)
 		go n.UPNPUpdateThread()
 	}
+	// Keep up the block relay only connections, unless only the configured peers are connected to.
+	if n.newAddress != nil {
+		n.WG.Add(1)
+		go n.BlockRelayHandler()
+	}
 	if n.Config.DisableRPC.False() {
 		n.WG.Add(1)
 		// Start the rebroadcastHandler, which ensures user tx received by the RPC server are rebroadcast until being
@@ -491,6 +523,9 @@ func (n *Node) Stop() (e error) {
 		},
 	); E.Chk(e) {
 	}
+	if n.ZMQ != nil {
+		n.ZMQ.Close()
+	}
 	// Stop the CPU miner if needed
 	// consume.Kill(n.StateCfg.Miner)
 	// D.Ln("miner has stopped")
@@ -567,8 +602,9 @@ func (n *Node) HandleAddPeerMsg(state *PeerState, sp *NodePeer) bool {
 	}
 	// TODO: Chk for max peers from a single IP.
 
-	// Limit max number of total peers.
-	if state.Count() >= n.Config.MaxPeers.V() {
+	// Limit max number of total peers. A new inbound peer takes the place of an existing inbound peer that is not
+	// protected from eviction.
+	if state.Count() >= n.Config.MaxPeers.V() && !(sp.Inbound() && n.EvictInboundPeer(state)) {
 		I.F(
 			"max peers reached [%d] - disconnecting peer %n",
 			n.Config.MaxPeers, sp.Addr(),
@@ -856,11 +892,26 @@ func (n *Node) HandleQuery(state *PeerState, querymsg interface{}) {
 // HandleRelayInvMsg deals with relaying inventory to peers that are not already known to have it. It is invoked from
 // the peerHandler goroutine.
 func (n *Node) HandleRelayInvMsg(state *PeerState, msg RelayMsg) {
+	// The compact block pushed to peers that asked for new blocks that way is only built once.
+	var cmpctBlock *wire.MsgCmpctBlock
 	state.ForAllPeers(
 		func(sp *NodePeer) {
 			if !sp.Connected() {
 				return
 			}
+			// If the inventory is a block and the peer asked for new blocks to be pushed as compact blocks, send one
+			// right away instead of announcing it.
+			if msg.InvVect.Type == wire.InvTypeBlock && sp.WantsCmpctBlocks() && !sp.KnowsInventory(msg.InvVect) {
+				if cmpctBlock == nil {
+					var e error
+					if cmpctBlock, e = n.NewCmpctBlockMsg(&msg.InvVect.Hash); E.Chk(e) {
+						return
+					}
+				}
+				sp.AddKnownInventory(msg.InvVect)
+				sp.QueueMessage(cmpctBlock, nil)
+				return
+			}
 			// If the inventory is a block and the peer prefers headers, generate and send a headers message instead of an
 			// inventory message.
 			if msg.InvVect.Type == wire.InvTypeBlock && sp.WantsHeaders() {
@@ -991,6 +1042,7 @@ func (n *Node) OutboundPeerConnected(c *connmgr.ConnReq, conn net.Conn) {
 	}
 	localIP := net.ParseIP(hh)
 	sp := NewServerPeer(n, localIP, c.Permanent)
+	sp.BlockRelayOnly = c.BlockRelayOnly
 	p, e := peer.NewOutboundPeer(NewPeerConfig(sp), c.Addr.String())
 	if e != nil {
 		E.F("cannot create outbound peer %n: %v %n", c.Addr, e)
@@ -1123,6 +1175,7 @@ out:
 			n.HandleQuery(n.peerState, qmsg)
 		case <-n.Quit.Wait():
 			D.Ln("chain peer server shutting down")
+			n.saveAnchors(n.peerState)
 			// Disconnect all peers on server shutdown.
 			n.peerState.ForAllPeers(
 				func(sp *NodePeer) {
@@ -1174,10 +1227,14 @@ func (n *Node) PushBlockMsg(
 		},
 	)
 	if e != nil {
-		E.F(
-			"unable to fetch requested block hash %v: %v",
-			hash, e,
-		)
+		if dbErr, ok := e.(database.DBError); ok && dbErr.ErrorCode == database.ErrBlockPruned {
+			D.F("not sending pruned block %v to %v", hash, sp)
+		} else {
+			E.F(
+				"unable to fetch requested block hash %v: %v",
+				hash, e,
+			)
+		}
 		if doneChan != nil {
 			doneChan <- struct{}{}
 		}
@@ -1243,10 +1300,14 @@ func (n *Node) PushMerkleBlockMsg(
 	// Fetch the raw block bytes from the database.
 	blk, e := sp.Server.Chain.BlockByHash(hash)
 	if e != nil {
-		E.F(
-			"unable to fetch requested block hash %v: %v",
-			hash, e,
-		)
+		if dbErr, ok := e.(database.DBError); ok && dbErr.ErrorCode == database.ErrBlockPruned {
+			D.F("not sending pruned block %v to %v", hash, sp)
+		} else {
+			E.F(
+				"unable to fetch requested block hash %v: %v",
+				hash, e,
+			)
+		}
 		if doneChan != nil {
 			doneChan <- struct{}{}
 		}
@@ -1282,6 +1343,47 @@ func (n *Node) PushMerkleBlockMsg(
 	return nil
 }
 
+// NewCmpctBlockMsg returns a cmpctblock message for the provided block hash with a random nonce. An error is returned if
+// the block is not in the main chain.
+func (n *Node) NewCmpctBlockMsg(hash *chainhash.Hash) (msg *wire.MsgCmpctBlock, e error) {
+	var blk *block2.Block
+	if blk, e = n.Chain.BlockByHash(hash); E.Chk(e) {
+		return
+	}
+	var nonce uint64
+	if nonce, e = wire.RandomUint64(); E.Chk(e) {
+		return
+	}
+	return wire.NewMsgCmpctBlock(blk.WireBlock(), nonce), nil
+}
+
+// PushCmpctBlockMsg sends a cmpctblock message for the provided block hash to the connected peer. Blocks that are more
+// than MaxCmpctBlockDepth blocks below the best chain tip are sent in full, as the peer is unlikely to have their
+// transactions. An error is returned if the block hash is not known.
+func (n *Node) PushCmpctBlockMsg(
+	sp *NodePeer, hash *chainhash.Hash,
+	doneChan chan<- struct{}, waitChan qu.C,
+) (e error) {
+	var height int32
+	if height, e = n.Chain.BlockHeightByHash(hash); e == nil &&
+		n.Chain.BestSnapshot().Height-height > MaxCmpctBlockDepth {
+		return n.PushBlockMsg(sp, hash, doneChan, waitChan, wire.BaseEncoding)
+	}
+	var msg *wire.MsgCmpctBlock
+	if msg, e = n.NewCmpctBlockMsg(hash); e != nil {
+		if doneChan != nil {
+			doneChan <- struct{}{}
+		}
+		return
+	}
+	// Once we have fetched data wait for any previous operation to finish.
+	if waitChan != nil {
+		<-waitChan
+	}
+	sp.QueueMessage(msg, doneChan)
+	return
+}
+
 // PushTxMsg sends a tx message for the provided transaction hash to the connected peer.
 //
 // An error is returned if the transaction hash is not known.
@@ -1431,20 +1533,39 @@ func (np *NodePeer) OnAddr(
 	if (np.Server.Config.Network.V())[0] == 's' {
 		return
 	}
-	// Ignore old style addresses which don't include a timestamp.
-	if np.ProtocolVersion() < wire.NetAddressTimeVersion {
+	// Ignore old style addresses which don't include a timestamp, and addresses from block relay only peers.
+	if np.ProtocolVersion() < wire.NetAddressTimeVersion || np.BlockRelayOnly {
+		return
+	}
+	np.addAddresses(msg.Command(), msg.AddrList)
+}
+
+// OnAddrV2 is invoked when a peer receives an addrv2 bitcoin message and is used like an addr message, except that it
+// may also carry Tor v3 and I2P addresses.
+func (np *NodePeer) OnAddrV2(
+	_ *peer.Peer,
+	msg *wire.MsgAddrV2,
+) {
+	// Ignore addresses when running on the simulation test network or from block relay only peers, see OnAddr.
+	if (np.Server.Config.Network.V())[0] == 's' || np.BlockRelayOnly {
 		return
 	}
+	np.addAddresses(msg.Command(), msg.AddrList)
+}
+
+// addAddresses adds the addresses received in an addr or addrv2 message to the known addresses of the peer and the
+// address manager.
+func (np *NodePeer) addAddresses(cmd string, addrList []*wire.NetAddress) {
 	// A message that has no addresses is invalid.
-	if len(msg.AddrList) == 0 {
+	if len(addrList) == 0 {
 		E.F(
 			"command [%s] from %s does not contain any addresses",
-			msg.Command(), np.Peer,
+			cmd, np.Peer,
 		)
 		np.Disconnect()
 		return
 	}
-	for _, na := range msg.AddrList {
+	for _, na := range addrList {
 		// Don't add more address if we're disconnecting.
 		if !np.Connected() {
 			return
@@ -1461,7 +1582,7 @@ func (np *NodePeer) OnAddr(
 	// Add addresses to server address manager. The address manager handles the details of things such as preventing
 	// duplicate addresses, max addresses, and last seen updates. XXX bitcoind gives a 2 hour time penalty here, do we
 	// want to do the same?
-	np.Server.AddrManager.AddAddresses(msg.AddrList, np.NA())
+	np.Server.AddrManager.AddAddresses(addrList, np.NA())
 }
 
 // OnBlock is invoked when a peer receives a block bitcoin message. It blocks until the bitcoin block has been fully
@@ -1483,8 +1604,78 @@ func (np *NodePeer) OnBlock(p *peer.Peer, msg *wire.Block, buf []byte) {
 	// Additionally, this behavior is depended on by at least the block acceptance test tool as the reference
 	// implementation processes blocks in the same thread and therefore blocks further messages until the bitcoin block
 	// has been fully processed.
-	np.Server.SyncManager.QueueBlock(block, np.Peer, np.BlockProcessed)
+	np.processBlock(
+		block.Hash(), func() {
+			np.Server.SyncManager.QueueBlock(block, np.Peer, np.BlockProcessed)
+		},
+	)
+}
+
+// OnCmpctBlock is invoked when a peer receives a cmpctblock bitcoin message. It is queued up to be rebuilt from the
+// mempool by the sync manager, which blocks further receives until the block is processed just like OnBlock.
+func (np *NodePeer) OnCmpctBlock(p *peer.Peer, msg *wire.MsgCmpctBlock) {
+	T.Ln("OnCmpctBlock from", p.Addr())
+	hash := msg.BlockHash()
+	np.processBlock(
+		&hash, func() {
+			np.Server.SyncManager.QueueCmpctBlock(msg, np.Peer, np.BlockProcessed)
+		},
+	)
+}
+
+// OnBlockTxn is invoked when a peer receives a blocktxn bitcoin message with the transactions of a compact block that
+// were missing from the mempool.
+func (np *NodePeer) OnBlockTxn(p *peer.Peer, msg *wire.MsgBlockTxn) {
+	T.Ln("OnBlockTxn from", p.Addr())
+	np.processBlock(
+		&msg.BlockHash, func() {
+			np.Server.SyncManager.QueueBlockTxn(msg, np.Peer, np.BlockProcessed)
+		},
+	)
+}
+
+// processBlock queues up a block message to be handled by the sync manager and waits until it is processed. When the
+// block is new to the chain and is accepted the time is recorded, as the peers that relay new blocks are protected from
+// eviction.
+func (np *NodePeer) processBlock(hash *chainhash.Hash, queue func()) {
+	chain := np.Server.Chain
+	known, e := chain.HaveBlock(hash)
+	if E.Chk(e) {
+	}
+	queue()
 	<-np.BlockProcessed
+	if known {
+		return
+	}
+	if have, e := chain.HaveBlock(hash); !E.Chk(e) && have {
+		atomic.StoreInt64(&np.LastBlockTime, time.Now().UnixNano())
+	}
+}
+
+// OnGetBlockTxn is invoked when a peer receives a getblocktxn bitcoin message and is used by peers to request the
+// transactions of a compact block that they are missing. Blocks that are more than MaxBlockTxnDepth blocks below the
+// best chain tip are sent in full instead, and peers requesting transactions that are not in the block are banned.
+func (np *NodePeer) OnGetBlockTxn(_ *peer.Peer, msg *wire.MsgGetBlockTxn) {
+	chain := np.Server.Chain
+	blk, e := chain.BlockByHash(&msg.BlockHash)
+	if e != nil {
+		D.F("unable to fetch block %v requested by %v: %v", msg.BlockHash, np, e)
+		return
+	}
+	if chain.BestSnapshot().Height-blk.Height() > MaxBlockTxnDepth {
+		np.QueueMessage(blk.WireBlock(), nil)
+		return
+	}
+	txs := blk.WireBlock().Transactions
+	reply := wire.NewMsgBlockTxn(&msg.BlockHash, make([]*wire.MsgTx, 0, len(msg.Indexes)))
+	for _, index := range msg.Indexes {
+		if int(index) >= len(txs) {
+			np.AddBanScore(100, 0, msg.Command())
+			return
+		}
+		reply.AddTransaction(txs[index])
+	}
+	np.QueueMessage(reply, nil)
 }
 
 // OnFeeFilter is invoked when a peer receives a feefilter bitcoin message and is used by remote peers to request that
@@ -1950,6 +2141,8 @@ func (np *NodePeer) OnGetData(_ *peer.Peer, msg *wire.MsgGetData) {
 				np, &iv.Hash, c, waitChan,
 				wire.BaseEncoding,
 			)
+		case wire.InvTypeCmpctBlock:
+			e = np.Server.PushCmpctBlockMsg(np, &iv.Hash, c, waitChan)
 		// case wire.InvTypeFilteredWitnessBlock:
 		// 	e = np.Server.PushMerkleBlockMsg(
 		// 		np, &iv.Hash, c, waitChan,
@@ -2030,7 +2223,7 @@ func (np *NodePeer) OnInv(
 	_ *peer.Peer,
 	msg *wire.MsgInv,
 ) {
-	if !np.Server.Config.BlocksOnly.True() {
+	if !np.Server.Config.BlocksOnly.True() && !np.BlockRelayOnly {
 		if len(msg.InvList) > 0 {
 			np.Server.SyncManager.QueueInv(msg, np.Peer)
 		}
@@ -2039,7 +2232,7 @@ func (np *NodePeer) OnInv(
 	newInv := wire.NewMsgInvSizeHint(uint(len(msg.InvList)))
 	for _, invVect := range msg.InvList {
 		if invVect.Type == wire.InvTypeTx {
-			T.F("ignoring tx %v in inv from %v -- blocksonly enabled", invVect.Hash, np)
+			T.F("ignoring tx %v in inv from %v -- not relaying transactions", invVect.Hash, np)
 			if np.ProtocolVersion() >= wire.BIP0037Version {
 				I.F("peer %v is announcing transactions -- disconnecting", np)
 				np.Disconnect()
@@ -2119,8 +2312,8 @@ func (np *NodePeer) OnTx(
 	_ *peer.Peer,
 	msg *wire.MsgTx,
 ) {
-	if np.Server.Config.BlocksOnly.True() {
-		T.F("ignoring tx %v from %v - blocksonly enabled", msg.TxHash(), np)
+	if np.Server.Config.BlocksOnly.True() || np.BlockRelayOnly {
+		T.F("ignoring tx %v from %v - not relaying transactions", msg.TxHash(), np)
 		return
 	}
 	// Add the transaction to the known inventory for the peer. Convert the raw MsgTx to a util.Tx which provides some
@@ -2133,8 +2326,14 @@ func (np *NodePeer) OnTx(
 	//
 	// This helps prevent a malicious peer from queuing up a bunch of bad transactions before disconnecting (or being
 	// disconnected) and wasting memory.
+	txPool := np.Server.TxMemPool
+	known := txPool.IsTransactionInPool(tx.Hash())
 	np.Server.SyncManager.QueueTx(tx, np.Peer, np.TxProcessed)
 	<-np.TxProcessed
+	// The peers that relay new transactions are protected from eviction.
+	if !known && txPool.IsTransactionInPool(tx.Hash()) {
+		atomic.StoreInt64(&np.LastTxTime, time.Now().UnixNano())
+	}
 }
 
 // OnVersion is invoked when a peer receives a version bitcoin message and is used to negotiate the protocol version
@@ -2199,8 +2398,9 @@ func (np *NodePeer) OnVersion(
 		// 	return nil
 		// }
 		// Advertise the local address when the server accepts incoming connections and it believes itself to be close
-		// to the best known tip.
-		if !np.Server.Config.DisableListen.True() && np.Server.SyncManager.IsCurrent() {
+		// to the best known tip. Addresses are not exchanged with block relay only peers, so they can't be told apart
+		// from other peers by the addresses they learn from us.
+		if !np.Server.Config.DisableListen.True() && np.Server.SyncManager.IsCurrent() && !np.BlockRelayOnly {
 			// Get address that best matches.
 			lna := addrManager.GetBestLocalAddress(remoteAddr)
 			if addrmgr.IsRoutable(lna) {
@@ -2212,7 +2412,7 @@ func (np *NodePeer) OnVersion(
 		// Request known addresses if the server address manager needs more and the peer has a protocol version new
 		// enough to include a timestamp with addresses.
 		hasTimestamp := np.ProtocolVersion() >= wire.NetAddressTimeVersion
-		if addrManager.NeedMoreAddresses() && hasTimestamp {
+		if addrManager.NeedMoreAddresses() && hasTimestamp && !np.BlockRelayOnly {
 			np.QueueMessage(wire.NewMsgGetAddr(), nil)
 		}
 		// Mark the address as a known good address.
@@ -2224,7 +2424,7 @@ func (np *NodePeer) OnVersion(
 	// Signal the sync manager this peer is a new sync candidate.
 	np.Server.SyncManager.NewPeer(np.Peer)
 	// Choose whether or not to relay transactions before a filter command is received.
-	np.SetDisableRelayTx(msg.DisableRelayTx)
+	np.SetDisableRelayTx(msg.DisableRelayTx || np.BlockRelayOnly)
 	hn := np.Server.HighestKnown.Load()
 	if msg.LastBlock >= hn {
 		np.Server.HighestKnown.Store(msg.LastBlock)
@@ -2234,6 +2434,15 @@ func (np *NodePeer) OnVersion(
 	return nil
 }
 
+// OnPong is invoked when a peer receives a pong bitcoin message. The lowest ping time of the peer is kept, as the
+// inbound peers with the lowest ping times are protected from eviction.
+func (np *NodePeer) OnPong(p *peer.Peer, _ *wire.MsgPong) {
+	ping := p.LastPingMicros()
+	if minPing := atomic.LoadInt64(&np.MinPingMicros); ping > 0 && (minPing == 0 || ping < minPing) {
+		atomic.StoreInt64(&np.MinPingMicros, ping)
+	}
+}
+
 // OnWrite is invoked when a peer sends a message and it is used to update the bytes sent by the server.
 func (np *NodePeer) OnWrite(
 	_ *peer.Peer, bytesWritten int,
@@ -2675,6 +2884,9 @@ func NewPeerConfig(sp *NodePeer) *peer.Config {
 			OnMemPool:      sp.OnMemPool,
 			OnTx:           sp.OnTx,
 			OnBlock:        sp.OnBlock,
+			OnCmpctBlock:   sp.OnCmpctBlock,
+			OnGetBlockTxn:  sp.OnGetBlockTxn,
+			OnBlockTxn:     sp.OnBlockTxn,
 			OnInv:          sp.OnInv,
 			OnHeaders:      sp.OnHeaders,
 			OnGetData:      sp.OnGetData,
@@ -2689,8 +2901,10 @@ func NewPeerConfig(sp *NodePeer) *peer.Config {
 			OnFilterLoad:   sp.OnFilterLoad,
 			OnGetAddr:      sp.OnGetAddr,
 			OnAddr:         sp.OnAddr,
+			OnAddrV2:       sp.OnAddrV2,
 			OnRead:         sp.OnRead,
 			OnWrite:        sp.OnWrite,
+			OnPong:         sp.OnPong,
 			// Note: The reference client currently bans peers that send alerts not signed with its key. We could verify
 			// against their key, but since the reference client is currently unwilling to support other
 			// implementations' alert messages, we will not relay theirs.
@@ -2704,7 +2918,7 @@ func NewPeerConfig(sp *NodePeer) *peer.Config {
 		UserAgentComments: sp.Server.Config.UserAgentComments.S(),
 		ChainParams:       sp.Server.ChainParams,
 		Services:          sp.Server.Services,
-		DisableRelayTx:    sp.Server.Config.BlocksOnly.True(),
+		DisableRelayTx:    sp.Server.Config.BlocksOnly.True() || sp.BlockRelayOnly,
 		ProtocolVersion:   peer.MaxProtocolVersion,
 		TrickleInterval:   sp.Server.Config.TrickleInterval.V(),
 		IP:                sp.IP,
@@ -2739,6 +2953,30 @@ func NewNode(listenAddrs []string, db database.DB, interruptChan qu.C, cx *Conte
 	if cx.Config.NoCFilters.True() {
 		services &^= wire.SFNodeCF
 	}
+	// A pruned node can't serve the full block chain, but still serves headers, compact filters and recent blocks.
+	var pruneTarget uint64
+	if cx.Config.PruneTarget.V() > 0 {
+		if cx.Config.PruneTarget.V() < MinPruneTarget {
+			return nil, fmt.Errorf("the prune target must be at least %d MiB", MinPruneTarget)
+		}
+		if cx.Config.TxIndex.True() || cx.Config.AddrIndex.True() {
+			return nil, errors.New("the transaction and address indexes can't be used with a prune target")
+		}
+		pruneTarget = uint64(cx.Config.PruneTarget.V()) << 20
+		services &^= wire.SFNodeNetwork
+	}
+	// A node bootstrapped from a utxo snapshot doesn't have the blocks below it until they are downloaded, so it can't
+	// serve them or build the indexes that need them.
+	historyMissing := blockchain.DBHistoryMissing(db)
+	if historyMissing {
+		if cx.Config.TxIndex.True() || cx.Config.AddrIndex.True() {
+			return nil, errors.New(
+				"the transaction and address indexes can't be built until the blocks below the utxo snapshot" +
+					" have been downloaded",
+			)
+		}
+		services &^= wire.SFNodeNetwork | wire.SFNodeCF
+	}
 	aMgr := addrmgr.New(cx.Config.DataDir.V()+string(os.PathSeparator)+cx.ActiveNet.Name, Lookup(cx.StateCfg))
 	var lstn []net.Listener
 	var nat upnp.NAT
@@ -2786,6 +3024,9 @@ func NewNode(listenAddrs []string, db database.DB, interruptChan qu.C, cx *Conte
 		StartController:      qu.Ts(2),
 		StopController:       qu.Ts(2),
 	}
+	if _, e := rand.Read(s.netGroupKey[:]); E.Chk(e) {
+		return nil, e
+	}
 	// Create the transaction and address indexes if needed.
 	//
 	// CAUTION: the txindex needs to be first in the indexes array because the addrindex uses data from the txindex
@@ -2810,7 +3051,9 @@ func NewNode(listenAddrs []string, db database.DB, interruptChan qu.C, cx *Conte
 		s.AddrIndex = indexers.NewAddrIndex(db, cx.ActiveNet)
 		indexes = append(indexes, s.AddrIndex)
 	}
-	if !cx.Config.NoCFilters.True() {
+	if !cx.Config.NoCFilters.True() && historyMissing {
+		W.Ln("committed filter index is disabled until the blocks below the utxo snapshot have been downloaded")
+	} else if !cx.Config.NoCFilters.True() {
 		T.Ln("committed filter index is enabled")
 		s.CFIndex = indexers.NewCfIndex(db, cx.ActiveNet)
 		indexes = append(indexes, s.CFIndex)
@@ -2829,6 +3072,12 @@ func NewNode(listenAddrs []string, db database.DB, interruptChan qu.C, cx *Conte
 	}
 	// Create a new block chain instance with the appropriate configuration.
 	var e error
+	var assumeValid *chainhash.Hash
+	if av := cx.Config.AssumeValid.V(); av != "" {
+		if assumeValid, e = chainhash.NewHashFromStr(av); E.Chk(e) {
+			return nil, fmt.Errorf("invalid assume-valid block hash %q: %v", av, e)
+		}
+	}
 	s.Chain, e = blockchain.New(
 		&blockchain.Config{
 			DB:           s.DB,
@@ -2839,13 +3088,26 @@ func NewNode(listenAddrs []string, db database.DB, interruptChan qu.C, cx *Conte
 			SigCache:     s.SigCache,
 			IndexManager: indexManager,
 			HashCache:    s.HashCache,
+			PruneTarget:  pruneTarget,
+			AssumeValid:  assumeValid,
 		},
 	)
 	if e != nil {
 		return nil, e
 	}
+	// The blocks may have been pruned by an earlier run without a prune target.
+	if s.Chain.Pruned() && s.Services&wire.SFNodeNetwork != 0 {
+		I.Ln("the block chain has been pruned, not advertising it as a full node")
+		s.Services &^= wire.SFNodeNetwork
+	}
 	s.Chain.DifficultyAdjustments = make(map[string]float64)
 	s.Chain.DifficultyBits.Store(make(blockchain.Diffs))
+	if s.ZMQ, e = NewZMQNotifier(cx.Config, s.Chain, s.ChainParams); E.Chk(e) {
+		return nil, e
+	}
+	if s.ZMQ != nil {
+		s.Chain.Subscribe(s.ZMQ.HandleBlockchainNotification)
+	}
 	// Search for a FeeEstimator state in the database. If none can be found or if it cannot be loaded, create a new
 	// one.
 	e = db.Update(
@@ -2915,13 +3177,12 @@ func NewNode(listenAddrs []string, db database.DB, interruptChan qu.C, cx *Conte
 	s.SyncManager, e =
 		netsync.New(
 			&netsync.Config{
-				PeerNotifier:       &s,
-				Chain:              s.Chain,
-				TxMemPool:          s.TxMemPool,
-				ChainParams:        s.ChainParams,
-				DisableCheckpoints: cx.Config.DisableCheckpoints.True(),
-				MaxPeers:           cx.Config.MaxPeers.V(),
-				FeeEstimator:       s.FeeEstimator,
+				PeerNotifier: &s,
+				Chain:        s.Chain,
+				TxMemPool:    s.TxMemPool,
+				ChainParams:  s.ChainParams,
+				MaxPeers:     cx.Config.MaxPeers.V(),
+				FeeEstimator: s.FeeEstimator,
 			},
 		)
 	if e != nil {
@@ -2976,6 +3237,11 @@ func NewNode(listenAddrs []string, db database.DB, interruptChan qu.C, cx *Conte
 				if s.OutboundGroupCount(key) != 0 {
 					continue
 				}
+				// I2P addresses are only relayed, and Tor addresses can only be dialed through the onion proxy.
+				if addrmgr.IsI2P(addr.NetAddress()) || (!cx.Config.OnionEnabled.True() &&
+					(addrmgr.IsTorV3(addr.NetAddress()) || addrmgr.IsOnionCatTor(addr.NetAddress()))) {
+					continue
+				}
 				// only allow recent nodes (10 min) after we failed 30 times
 				if tries < 30 && time.Since(addr.LastAttempt()) < 10*time.Minute {
 					continue
@@ -2991,6 +3257,7 @@ func NewNode(listenAddrs []string, db database.DB, interruptChan qu.C, cx *Conte
 			return nil, errors.New("no valid connect address")
 		}
 	}
+	s.newAddress = newAddressFunc
 	// Create a connection manager.
 	targetOutbound := DefaultTargetOutbound
 	if cx.Config.MaxPeers.V() < targetOutbound {
@@ -3034,6 +3301,11 @@ func NewNode(listenAddrs []string, db database.DB, interruptChan qu.C, cx *Conte
 		listeners := map[string][]string{
 			fork.SHA256d: cx.Config.RPCListeners.S(),
 		}
+		// an rpc server handing out scrypt work to getwork miners is only started if it has listen addresses
+		if scryptListeners := cx.Config.ScryptRPCListeners.S(); len(scryptListeners) > 0 {
+			listeners[fork.Scrypt] = scryptListeners
+		}
+		blockTemplateGenerator := GetBlkTemplateGenerator(&s, cx.Config, cx.StateCfg)
 		for l := range listeners {
 			rpcListeners, e := SetupRPCListeners(cx.Config, listeners[l])
 			if e != nil {
@@ -3053,7 +3325,7 @@ func NewNode(listenAddrs []string, db database.DB, interruptChan qu.C, cx *Conte
 					ChainParams: cx.ActiveNet,
 					DB:          db,
 					TxMemPool:   s.TxMemPool,
-					// Generator:    blockTemplateGenerator,
+					Generator:   blockTemplateGenerator,
 					// CPUMiner:     s.CPUMiner,
 					TxIndex:         s.TxIndex,
 					AddrIndex:       s.AddrIndex,
@@ -0,0 +1,62 @@
+// Package share is the message kopach workers send to a controller in pool mode for each hash that meets the share
+// target of a template, crediting the work to the payout address of the worker.
+package share
+
+import (
+	"errors"
+
+	"github.com/p9c/pod/pkg/chainrpc/codec"
+	"github.com/p9c/pod/pkg/wire"
+)
+
+// Magic is the marker for packets containing a share
+var Magic = []byte{'s', 'h', 'r', 1}
+
+// Share is a block header that meets the share target of a template, along with the address the share is credited to
+type Share struct {
+	Nonce   uint64
+	UUID    uint64
+	Address string
+	Header  *wire.BlockHeader
+}
+
+// Encode a message for a share, in the encoding described in package codec
+func Encode(nonce uint64, uuid uint64, address string, hdr *wire.BlockHeader) []byte {
+	return codec.NewEncoder(Magic).
+		PutUint64(1, nonce).
+		PutUint64(2, uuid).
+		PutString(3, address).
+		PutHeader(4, hdr).
+		Encode()
+}
+
+// Deserialize a share message
+func Deserialize(b []byte) (s *Share, e error) {
+	var dec *codec.Decoder
+	if dec, e = codec.NewDecoder(Magic, b); E.Chk(e) {
+		return
+	}
+	s = &Share{}
+	for tag, v, ok := dec.Next(); ok && e == nil; tag, v, ok = dec.Next() {
+		switch tag {
+		case 1:
+			s.Nonce, e = codec.Uint64(v)
+		case 2:
+			s.UUID, e = codec.Uint64(v)
+		case 3:
+			s.Address = string(v)
+		case 4:
+			s.Header, e = codec.Header(v)
+		}
+	}
+	if e == nil {
+		e = dec.Err()
+	}
+	if e == nil && s.Header == nil {
+		e = errors.New("share has no block header")
+	}
+	if E.Chk(e) {
+		return nil, e
+	}
+	return
+}
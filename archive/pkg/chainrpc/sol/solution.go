@@ -1,10 +1,9 @@
 package sol
 
 import (
-	"bytes"
-	
-	"github.com/niubaoshu/gotiny"
-	
+	"errors"
+
+	"github.com/p9c/pod/pkg/chainrpc/codec"
 	"github.com/p9c/pod/pkg/wire"
 )
 
@@ -14,26 +13,44 @@ var Magic = []byte{'s', 'o', 'l', 1}
 type Solution struct {
 	Nonce uint64
 	UUID  uint64
-	// *wire.Block
-	Bytes []byte
+	// Header is the block header of the solution
+	Header *wire.BlockHeader
 }
 
-// Encode a message for a solution
+// Encode a message for a solution, in the encoding described in package codec
 func Encode(nonce uint64, uuid uint64, mb *wire.BlockHeader) []byte {
-	var buf []byte
-	wr := bytes.NewBuffer(buf)
-	var e error
-	if e = mb.Serialize(wr); E.Chk(e) {
-	}
-	s := Solution{Nonce: nonce, UUID: uuid, Bytes: wr.Bytes()} // Block: mb}
-	return gotiny.Marshal(&s)
+	return codec.NewEncoder(Magic).
+		PutUint64(1, nonce).
+		PutUint64(2, uuid).
+		PutHeader(3, mb).
+		Encode()
 }
 
-// Decode an encoded solution message to a wire.BlockHeader
-func (s *Solution) Decode() (mb *wire.BlockHeader, e error) {
-	buf := bytes.NewBuffer(s.Bytes)
-	mb = &wire.BlockHeader{}
-	if e = mb.Deserialize(buf); E.Chk(e) {
+// Deserialize a solution message
+func Deserialize(b []byte) (s *Solution, e error) {
+	var dec *codec.Decoder
+	if dec, e = codec.NewDecoder(Magic, b); E.Chk(e) {
+		return
+	}
+	s = &Solution{}
+	for tag, v, ok := dec.Next(); ok && e == nil; tag, v, ok = dec.Next() {
+		switch tag {
+		case 1:
+			s.Nonce, e = codec.Uint64(v)
+		case 2:
+			s.UUID, e = codec.Uint64(v)
+		case 3:
+			s.Header, e = codec.Header(v)
+		}
+	}
+	if e == nil {
+		e = dec.Err()
+	}
+	if e == nil && s.Header == nil {
+		e = errors.New("solution has no block header")
+	}
+	if E.Chk(e) {
+		return nil, e
 	}
 	return
 }
@@ -1,12 +1,14 @@
 package templates
 
 import (
+	"encoding/binary"
 	"errors"
+	"sort"
 	"time"
 
-	"github.com/niubaoshu/gotiny"
-
 	"github.com/p9c/pod/pkg/chainhash"
+	"github.com/p9c/pod/pkg/chainrpc/codec"
+	"github.com/p9c/pod/pkg/chainrpc/job"
 	"github.com/p9c/pod/pkg/wire"
 )
 
@@ -28,6 +30,9 @@ type Message struct {
 	Height    int32
 	PrevBlock chainhash.Hash
 	Bits      Diffs
+	// ShareBits are the targets a header must meet to be counted as a share by a
+	// controller in pool mode, it is empty when the controller is not in pool mode
+	ShareBits Diffs
 	Merkles   Merkles
 	txs       Txs
 	Timestamp time.Time
@@ -46,16 +51,105 @@ func (m *Message) GetTxs() (txs map[int32][]*wire.MsgTx) {
 	return m.txs
 }
 
-// Serialize the Message for the wire
+// Serialize the Message for the wire, in the encoding described in package codec
 func (m *Message) Serialize() []byte {
-	return gotiny.Marshal(m)
+	enc := codec.NewEncoder(job.Magic).
+		PutUint64(1, m.Nonce).
+		PutUint64(2, m.UUID).
+		PutInt32(3, m.Height).
+		PutHash(4, &m.PrevBlock)
+	for _, ver := range sortedVersions(m.Bits) {
+		enc.PutBytes(5, versionValue(ver, m.Bits[ver]))
+	}
+	merkleVers := make([]int32, 0, len(m.Merkles))
+	for ver := range m.Merkles {
+		merkleVers = append(merkleVers, ver)
+	}
+	sort.Slice(merkleVers, func(i, j int) bool { return merkleVers[i] < merkleVers[j] })
+	for _, ver := range merkleVers {
+		v := make([]byte, 4+chainhash.HashSize)
+		binary.BigEndian.PutUint32(v, uint32(ver))
+		merkle := m.Merkles[ver]
+		copy(v[4:], merkle[:])
+		enc.PutBytes(6, v)
+	}
+	enc.PutTime(7, m.Timestamp)
+	for _, ver := range sortedVersions(m.ShareBits) {
+		enc.PutBytes(8, versionValue(ver, m.ShareBits[ver]))
+	}
+	return enc.Encode()
 }
 
 // DeserializeMsgBlockTemplate takes a message expected to be a Message
 // and reconstitutes it
-func DeserializeMsgBlockTemplate(b []byte) (m *Message) {
-	m = &Message{}
-	gotiny.Unmarshal(b, m)
+func DeserializeMsgBlockTemplate(b []byte) (m *Message, e error) {
+	var dec *codec.Decoder
+	if dec, e = codec.NewDecoder(job.Magic, b); E.Chk(e) {
+		return
+	}
+	m = &Message{Bits: make(Diffs), Merkles: make(Merkles)}
+	for tag, v, ok := dec.Next(); ok && e == nil; tag, v, ok = dec.Next() {
+		switch tag {
+		case 1:
+			m.Nonce, e = codec.Uint64(v)
+		case 2:
+			m.UUID, e = codec.Uint64(v)
+		case 3:
+			m.Height, e = codec.Int32(v)
+		case 4:
+			m.PrevBlock, e = codec.Hash(v)
+		case 5:
+			e = putVersionValue(m.Bits, v)
+		case 6:
+			if len(v) != 4+chainhash.HashSize {
+				e = errors.New("merkle root entry has the wrong length")
+				break
+			}
+			var merkle chainhash.Hash
+			copy(merkle[:], v[4:])
+			m.Merkles[int32(binary.BigEndian.Uint32(v))] = merkle
+		case 7:
+			m.Timestamp, e = codec.Time(v)
+		case 8:
+			if m.ShareBits == nil {
+				m.ShareBits = make(Diffs)
+			}
+			e = putVersionValue(m.ShareBits, v)
+		}
+	}
+	if e == nil {
+		e = dec.Err()
+	}
+	if E.Chk(e) {
+		return nil, e
+	}
+	return
+}
+
+// sortedVersions returns the block versions in a set of difficulty bits in
+// ascending order, so the encoding of a message is always the same
+func sortedVersions(d Diffs) (vers []int32) {
+	for ver := range d {
+		vers = append(vers, ver)
+	}
+	sort.Slice(vers, func(i, j int) bool { return vers[i] < vers[j] })
+	return
+}
+
+// versionValue encodes a block version and the difficulty bits for it
+func versionValue(ver int32, bits uint32) []byte {
+	v := make([]byte, 8)
+	binary.BigEndian.PutUint32(v, uint32(ver))
+	binary.BigEndian.PutUint32(v[4:], bits)
+	return v
+}
+
+// putVersionValue decodes a block version and difficulty bits entry into d
+func putVersionValue(d Diffs, v []byte) (e error) {
+	if len(v) != 8 {
+		return errors.New("difficulty bits entry has the wrong length")
+	}
+	d[int32(binary.BigEndian.Uint32(v))] = binary.BigEndian.Uint32(v[4:])
 	return
 }
 
@@ -135,3 +229,14 @@ func (rm *RecentMessages) Find(nonce uint64) *Message {
 	}
 	return nil
 }
+
+// Get returns the cached Message with the given nonce without removing it from
+// the list, or nil if there is none
+func (rm *RecentMessages) Get(nonce uint64) *Message {
+	for i := range rm.msgs {
+		if rm.msgs[i] != nil && rm.msgs[i].Nonce == nonce {
+			return rm.msgs[i]
+		}
+	}
+	return nil
+}
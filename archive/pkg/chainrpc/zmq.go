@@ -0,0 +1,177 @@
+package chainrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	block2 "github.com/p9c/pod/pkg/block"
+	"github.com/p9c/pod/pkg/blockchain"
+	"github.com/p9c/pod/pkg/chaincfg"
+	"github.com/p9c/pod/pkg/chainhash"
+	"github.com/p9c/pod/pkg/fork"
+	"github.com/p9c/pod/pkg/mempool"
+	"github.com/p9c/pod/pkg/util"
+	"github.com/p9c/pod/pkg/zmqpub"
+	"github.com/p9c/pod/pod/config"
+)
+
+// The topics the node publishes on its zmq endpoints. The first four are the same as those of bitcoind, so its
+// subscribers, such as the bitcoind chain client of the wallet, can be pointed at the node.
+const (
+	ZMQTopicRawBlock  = "rawblock"
+	ZMQTopicHashBlock = "hashblock"
+	ZMQTopicRawTx     = "rawtx"
+	ZMQTopicHashTx    = "hashtx"
+	ZMQTopicAlgoBlock = "algoblock"
+)
+
+// ZMQAlgoBlock is the body of the messages on the algoblock topic, in JSON
+type ZMQAlgoBlock struct {
+	Hash       string  `json:"hash"`
+	Height     int32   `json:"height"`
+	Version    int32   `json:"version"`
+	Algo       string  `json:"algo"`
+	Bits       string  `json:"bits"`
+	Difficulty float64 `json:"difficulty"`
+	// NextDifficulties is the difficulty the next block of each algorithm must meet
+	NextDifficulties map[string]float64 `json:"nextdifficulties"`
+}
+
+// ZMQNotifier publishes the blocks connected to the chain and the transactions accepted to the mempool on the zmq
+// endpoints configured for their topics
+type ZMQNotifier struct {
+	chain      *blockchain.BlockChain
+	params     *chaincfg.Params
+	publishers map[string]*zmqpub.Publisher
+}
+
+// NewZMQNotifier starts a publisher on each endpoint configured for a topic, topics with the same endpoint sharing a
+// publisher. It returns nil if no endpoint is configured.
+func NewZMQNotifier(cfg *config.Config, chain *blockchain.BlockChain, params *chaincfg.Params) (
+	z *ZMQNotifier, e error,
+) {
+	endpoints := map[string]string{
+		ZMQTopicRawBlock:  cfg.ZMQPubRawBlock.V(),
+		ZMQTopicHashBlock: cfg.ZMQPubHashBlock.V(),
+		ZMQTopicRawTx:     cfg.ZMQPubRawTx.V(),
+		ZMQTopicHashTx:    cfg.ZMQPubHashTx.V(),
+		ZMQTopicAlgoBlock: cfg.ZMQPubAlgoBlock.V(),
+	}
+	z = &ZMQNotifier{chain: chain, params: params, publishers: make(map[string]*zmqpub.Publisher)}
+	byEndpoint := make(map[string]*zmqpub.Publisher)
+	for topic, endpoint := range endpoints {
+		if endpoint == "" {
+			continue
+		}
+		p, ok := byEndpoint[endpoint]
+		if !ok {
+			if p, e = zmqpub.Listen(endpoint); E.Chk(e) {
+				z.Close()
+				return nil, fmt.Errorf("unable to publish %s on %s: %v", topic, endpoint, e)
+			}
+			byEndpoint[endpoint] = p
+		}
+		z.publishers[topic] = p
+	}
+	if len(z.publishers) == 0 {
+		return nil, nil
+	}
+	return
+}
+
+// HandleBlockchainNotification publishes the blocks connected to the chain and their transactions
+func (z *ZMQNotifier) HandleBlockchainNotification(notification *blockchain.Notification) {
+	if notification.Type != blockchain.NTBlockConnected {
+		return
+	}
+	block, ok := notification.Data.(*block2.Block)
+	if !ok {
+		W.Ln("chain connected notification is not a block")
+		return
+	}
+	if p, ok := z.publishers[ZMQTopicHashBlock]; ok {
+		p.Publish(ZMQTopicHashBlock, reversedHash(block.Hash()))
+	}
+	if p, ok := z.publishers[ZMQTopicRawBlock]; ok {
+		if b, e := block.Bytes(); !E.Chk(e) {
+			p.Publish(ZMQTopicRawBlock, b)
+		}
+	}
+	if p, ok := z.publishers[ZMQTopicAlgoBlock]; ok {
+		if b, e := json.Marshal(z.algoBlock(block)); !E.Chk(e) {
+			p.Publish(ZMQTopicAlgoBlock, b)
+		}
+	}
+	txs := block.Transactions()
+	for i := range txs {
+		z.publishTx(txs[i])
+	}
+}
+
+// NotifyNewTransactions publishes the transactions accepted to the mempool
+func (z *ZMQNotifier) NotifyNewTransactions(txns []*mempool.TxDesc) {
+	for i := range txns {
+		z.publishTx(txns[i].Tx)
+	}
+}
+
+// Close stops the publishers
+func (z *ZMQNotifier) Close() {
+	closed := make(map[*zmqpub.Publisher]struct{})
+	for _, p := range z.publishers {
+		if _, ok := closed[p]; ok {
+			continue
+		}
+		closed[p] = struct{}{}
+		if e := p.Close(); E.Chk(e) {
+		}
+	}
+}
+
+// publishTx publishes a transaction on the transaction topics
+func (z *ZMQNotifier) publishTx(tx *util.Tx) {
+	if p, ok := z.publishers[ZMQTopicHashTx]; ok {
+		p.Publish(ZMQTopicHashTx, reversedHash(tx.Hash()))
+	}
+	if p, ok := z.publishers[ZMQTopicRawTx]; ok {
+		var buf bytes.Buffer
+		buf.Grow(tx.MsgTx().SerializeSize())
+		if e := tx.MsgTx().Serialize(&buf); !E.Chk(e) {
+			p.Publish(ZMQTopicRawTx, buf.Bytes())
+		}
+	}
+}
+
+// algoBlock returns the algorithm and difficulty of a block, and the difficulties of the next block of every
+// algorithm
+func (z *ZMQNotifier) algoBlock(block *block2.Block) (a *ZMQAlgoBlock) {
+	header := &block.WireBlock().Header
+	height := block.Height()
+	a = &ZMQAlgoBlock{
+		Hash:             block.Hash().String(),
+		Height:           height,
+		Version:          header.Version,
+		Algo:             fork.GetAlgoName(header.Version, height),
+		Bits:             fmt.Sprintf("%08x", header.Bits),
+		Difficulty:       GetDifficultyRatio(header.Bits, z.params, header.Version),
+		NextDifficulties: make(map[string]float64),
+	}
+	for version, name := range fork.List[fork.GetCurrent(height+1)].AlgoVers {
+		bits, e := z.chain.CalcNextRequiredDifficulty(name)
+		if E.Chk(e) {
+			continue
+		}
+		a.NextDifficulties[name] = GetDifficultyRatio(bits, z.params, version)
+	}
+	return
+}
+
+// reversedHash returns the bytes of a hash in the order it is displayed in, which is how bitcoind publishes hashes
+func reversedHash(h *chainhash.Hash) []byte {
+	b := make([]byte, chainhash.HashSize)
+	for i := range h {
+		b[chainhash.HashSize-1-i] = h[i]
+	}
+	return b
+}
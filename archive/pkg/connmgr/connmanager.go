@@ -60,6 +60,11 @@ type ConnReq struct {
 	state      ConnState
 	stateMtx   sync.RWMutex
 	retryCount uint32
+	// BlockRelayOnly marks a connection that is only used to relay blocks. These
+	// are kept up by the caller, so they are neither retried nor replaced with a
+	// connection to a new address when they fail or disconnect, and they are not
+	// counted towards the target number of outbound connections.
+	BlockRelayOnly bool
 }
 
 // updateState updates the state of the connection request.
@@ -164,14 +169,17 @@ type handleFailed struct {
 // ConnManager provides a manager to handle network connections.
 type ConnManager struct {
 	// The following variables must only be used atomically.
-	connReqCount   uint64
-	start          int32
-	stop           int32
-	Cfg            Config
-	wg             sync.WaitGroup
-	failedAttempts uint64
-	requests       chan interface{}
-	quit           qu.C
+	connReqCount uint64
+	// blockRelayReqCount is the number of the connection requests counted in
+	// connReqCount that only relay blocks.
+	blockRelayReqCount uint64
+	start              int32
+	stop               int32
+	Cfg                Config
+	wg                 sync.WaitGroup
+	failedAttempts     uint64
+	requests           chan interface{}
+	quit               qu.C
 }
 
 // handleFailedConn handles a connection failed due to a disconnect or any other failure.
@@ -225,6 +233,9 @@ func (cm *ConnManager) connHandler() {
 		pending = make(map[uint64]*ConnReq)
 		// conns represents the set of all actively connected peers.
 		conns = make(map[uint64]*ConnReq, cm.Cfg.TargetOutbound)
+		// blockRelayConns holds the actively connected peers that only relay blocks, apart from conns so they don't
+		// take the place of the target outbound connections.
+		blockRelayConns = make(map[uint64]*ConnReq)
 	)
 out:
 	for {
@@ -248,7 +259,11 @@ out:
 				}
 				connReq.updateState(ConnEstablished)
 				connReq.conn = msg.conn
-				conns[connReq.id] = connReq
+				if connReq.BlockRelayOnly {
+					blockRelayConns[connReq.id] = connReq
+				} else {
+					conns[connReq.id] = connReq
+				}
 				T.Ln("connected to ", connReq)
 				connReq.retryCount = 0
 				cm.failedAttempts = 0
@@ -258,6 +273,9 @@ out:
 				}
 			case handleDisconnected:
 				connReq, ok := conns[msg.id]
+				if !ok {
+					connReq, ok = blockRelayConns[msg.id]
+				}
 				if !ok {
 					connReq, ok = pending[msg.id]
 					if !ok {
@@ -274,6 +292,7 @@ out:
 				// An existing connection was located, mark as disconnected and execute disconnection callback.
 				T.Ln("disconnected from", connReq)
 				delete(conns, msg.id)
+				delete(blockRelayConns, msg.id)
 				if connReq.conn != nil {
 					if e := connReq.conn.Close(); E.Chk(e) {
 					}
@@ -283,7 +302,7 @@ out:
 				}
 				// All internal state has been cleaned up, if this connection is being removed, we will make no further
 				// attempts with this request.
-				if !msg.retry {
+				if !msg.retry || connReq.BlockRelayOnly {
 					connReq.updateState(ConnDisconnected)
 					continue
 				}
@@ -305,6 +324,10 @@ out:
 				connReq.updateState(ConnFailing)
 				// T.F
 				// ("failed to connect to %v: %v", connReq, msg.err)
+				if connReq.BlockRelayOnly {
+					delete(pending, connReq.id)
+					continue
+				}
 				cm.handleFailedConn(connReq)
 			}
 		case <-cm.quit.Wait():
@@ -365,6 +388,9 @@ func (cm *ConnManager) Connect(c *ConnReq) {
 	}
 	if atomic.LoadUint64(&c.id) == 0 {
 		atomic.StoreUint64(&c.id, atomic.AddUint64(&cm.connReqCount, 1))
+		if c.BlockRelayOnly {
+			atomic.AddUint64(&cm.blockRelayReqCount, 1)
+		}
 		// Submit a request of a pending connection attempt to the connection manager. By registering the id before the
 		// connection is even established, we'll be able to later cancel the connection via the Remove method.
 		T.Ln("sending request to register connection")
@@ -471,7 +497,10 @@ func (cm *ConnManager) Start() {
 			go cm.listenHandler(listner)
 		}
 	}
-	for i := atomic.LoadUint64(&cm.connReqCount); i < uint64(cm.Cfg.TargetOutbound); i++ {
+	// Block relay only requests made before starting don't take the place of the target outbound connections. Their
+	// count is loaded first as it is raised after the count of all requests.
+	blockRelayReqs := atomic.LoadUint64(&cm.blockRelayReqCount)
+	for i := atomic.LoadUint64(&cm.connReqCount) - blockRelayReqs; i < uint64(cm.Cfg.TargetOutbound); i++ {
 		go cm.NewConnReq()
 	}
 }
@@ -209,6 +209,89 @@ func TestTargetOutbound(t *testing.T) {
 	cmgr.Stop()
 }
 
+// TestBlockRelayOnlyOutbound tests that block relay only connections are not counted towards the target number of
+// outbound connections, whether they are requested before the connection manager is started or disconnected from.
+func TestBlockRelayOnlyOutbound(t *testing.T) {
+	targetOutbound := uint32(2)
+	connected := make(chan *ConnReq)
+	disconnected := make(chan *ConnReq)
+	cmgr, e := New(&Config{
+		RetryDuration:  time.Millisecond,
+		TargetOutbound: targetOutbound,
+		Dial:           mockDialer,
+		GetNewAddress: func() (net.Addr, error) {
+			return &net.TCPAddr{
+				IP:   net.ParseIP("127.0.0.1"),
+				Port: 18555,
+			}, nil
+		},
+		OnConnection: func(c *ConnReq, conn net.Conn) {
+			connected <- c
+		},
+		OnDisconnection: func(c *ConnReq) {
+			disconnected <- c
+		},
+	},
+	)
+	if e != nil {
+		t.Fatalf("New error: %v", e)
+	}
+	cr := &ConnReq{
+		Addr: &net.TCPAddr{
+			IP:   net.ParseIP("127.0.0.2"),
+			Port: 18555,
+		},
+		BlockRelayOnly: true,
+	}
+	go cmgr.Connect(cr)
+	for cr.ID() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cmgr.Start()
+	var outbound []*ConnReq
+	for i := uint32(0); i <= targetOutbound; i++ {
+		select {
+		case c := <-connected:
+			if !c.BlockRelayOnly {
+				outbound = append(outbound, c)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("block relay only: got %d outbound connections, want %d", len(outbound), targetOutbound)
+		}
+	}
+	if uint32(len(outbound)) != targetOutbound {
+		t.Fatalf("block relay only: got %d outbound connections, want %d", len(outbound), targetOutbound)
+	}
+	select {
+	case c := <-connected:
+		t.Fatalf("block relay only: got unexpected connection - %v", c.Addr)
+	case <-time.After(time.Millisecond):
+	}
+	// The outbound connection that is disconnected from is replaced even though the block relay only connection is up.
+	cmgr.Disconnect(outbound[0].ID())
+	<-disconnected
+	select {
+	case c := <-connected:
+		if c.BlockRelayOnly {
+			t.Fatalf("block relay only: the outbound connection was replaced with %v", c)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("block relay only: the outbound connection was not replaced")
+	}
+	// The block relay only connection is neither retried nor replaced.
+	cmgr.Disconnect(cr.ID())
+	<-disconnected
+	select {
+	case c := <-connected:
+		t.Fatalf("block relay only: got unexpected connection - %v", c.Addr)
+	case <-time.After(10 * time.Millisecond):
+	}
+	if gotState := cr.State(); gotState != ConnDisconnected {
+		t.Fatalf("block relay only: want state %v, got state %v", ConnDisconnected, gotState)
+	}
+	cmgr.Stop()
+}
+
 // TestRetryPermanent tests that permanent connection requests are retried. We make a permanent connection request using
 // Connect, disconnect it using Disconnect and we wait for it to be connected back.
 func TestRetryPermanent(t *testing.T) {
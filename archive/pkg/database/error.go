@@ -72,6 +72,10 @@ const (
 	// not correspond to an existing block, the error will be ErrBlockNotFound
 	// instead.
 	ErrBlockRegionInvalid
+	// ErrBlockPruned indicates the block with the provided hash is known to the
+	// database but its data was deleted from the block files when they were
+	// pruned.
+	ErrBlockPruned
 	// ErrDriverSpecific indicates the Err field is a driver-specific error. This
 	// provides a mechanism for drivers to plug-in their own custom errors for
 	// any situations which aren't already covered by the error codes provided by
@@ -103,6 +107,7 @@ var errorCodeStrings = map[ErrorCode]string{
 	ErrBlockNotFound:      "ErrBlockNotFound",
 	ErrBlockExists:        "ErrBlockExists",
 	ErrBlockRegionInvalid: "ErrBlockRegionInvalid",
+	ErrBlockPruned:        "ErrBlockPruned",
 	ErrDriverSpecific:     "ErrDriverSpecific",
 }
 
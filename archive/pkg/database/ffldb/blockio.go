@@ -131,6 +131,9 @@ type (
 		openBlocksLRU    *list.List // Contains uint32 block file numbers.
 		fileNumToLRUElem map[uint32]*list.Element
 		openBlockFiles   map[uint32]*lockableFile
+		// firstFileNum is the number of the oldest block file that has not been pruned. Files before it have been
+		// deleted, so the blocks stored in them can no longer be read. It is protected by obfMutex.
+		firstFileNum uint32
 		// writeCursor houses the state for the current file and location that new blocks are written to.
 		writeCursor *writeCursor
 		// These functions are set to openFile, openWriteFile, and deleteFile by default, but are exposed here to allow
@@ -299,6 +302,12 @@ func (s *blockStore) blockFile(fileNum uint32) (*lockableFile, error) {
 		s.obfMutex.Unlock()
 		return obf, nil
 	}
+	// Pruned files are never opened again, so they are not found in the open block files map.
+	if fileNum < s.firstFileNum {
+		s.obfMutex.Unlock()
+		str := fmt.Sprintf("block file %d has been pruned", fileNum)
+		return nil, makeDbErr(database.ErrBlockPruned, str, nil)
+	}
 	// The file isn't open, so open it while potentially closing the least recently used one as needed.
 	obf, e := s.openFileFunc(fileNum)
 	if e != nil {
@@ -621,6 +630,110 @@ func (s *blockStore) handleRollback(oldBlockFileNum, oldBlockOffset uint32) {
 	}
 }
 
+// pruneSize returns the range of block files to prune for the block files to take up no more than targetSize bytes,
+// without pruning the file keepFileNum or the current write file. The range starts from the first file that is not
+// pruned yet or the file fromFileNum, whichever is later, and ends before upTo.
+//
+// This function MUST only be called during a write transaction so the write cursor and the first file number can't
+// change while it runs.
+func (s *blockStore) pruneSize(targetSize uint64, keepFileNum, fromFileNum uint32) (first, upTo uint32, e error) {
+	wc := s.writeCursor
+	wc.RLock()
+	curFileNum, curOffset := wc.curFileNum, wc.curOffset
+	wc.RUnlock()
+	s.obfMutex.RLock()
+	firstFileNum := s.firstFileNum
+	s.obfMutex.RUnlock()
+	if fromFileNum < firstFileNum {
+		fromFileNum = firstFileNum
+	}
+	if keepFileNum > curFileNum {
+		keepFileNum = curFileNum
+	}
+	if keepFileNum <= fromFileNum {
+		return fromFileNum, fromFileNum, nil
+	}
+	sizes := make([]uint64, 0, keepFileNum-fromFileNum)
+	total := uint64(curOffset)
+	for fileNum := fromFileNum; fileNum < curFileNum; fileNum++ {
+		filePath := blockFilePath(s.basePath, fileNum)
+		var st os.FileInfo
+		if st, e = os.Stat(filePath); E.Chk(e) {
+			str := fmt.Sprintf("failed to stat file %q: %v", filePath, e)
+			return fromFileNum, fromFileNum, makeDbErr(database.ErrDriverSpecific, str, e)
+		}
+		if fileNum < keepFileNum {
+			sizes = append(sizes, uint64(st.Size()))
+		}
+		total += uint64(st.Size())
+	}
+	upTo = fromFileNum
+	for i := 0; i < len(sizes) && total > targetSize; i++ {
+		total -= sizes[i]
+		upTo++
+	}
+	return fromFileNum, upTo, nil
+}
+
+// pruneFiles closes and deletes the block files before upTo that have not been pruned yet. Reading the blocks stored in
+// them returns ErrBlockPruned from then on.
+//
+// Failures to delete the files are only logged, since the blocks in them are no longer read once the files are marked
+// as pruned, and deleting them again after a restart would fail the same way.
+func (s *blockStore) pruneFiles(upTo uint32) {
+	s.obfMutex.Lock()
+	firstFileNum := s.firstFileNum
+	if upTo <= firstFileNum {
+		s.obfMutex.Unlock()
+		return
+	}
+	s.firstFileNum = upTo
+	// Close the files being pruned that are open under the write lock for the file in case any readers are currently
+	// reading from it so it's not closed out from under them.
+	s.lruMutex.Lock()
+	for fileNum := firstFileNum; fileNum < upTo; fileNum++ {
+		obf, ok := s.openBlockFiles[fileNum]
+		if !ok {
+			continue
+		}
+		obf.Lock()
+		_ = obf.file.Close()
+		obf.Unlock()
+		s.openBlocksLRU.Remove(s.fileNumToLRUElem[fileNum])
+		delete(s.openBlockFiles, fileNum)
+		delete(s.fileNumToLRUElem, fileNum)
+	}
+	s.lruMutex.Unlock()
+	s.obfMutex.Unlock()
+	for fileNum := firstFileNum; fileNum < upTo; fileNum++ {
+		if e := s.deleteFileFunc(fileNum); E.Chk(e) {
+			W.F("failed to delete pruned block file %d: %v", fileNum, e)
+		}
+	}
+	D.F("pruned block files %d to %d", firstFileNum, upTo-1)
+}
+
+// firstBlockFile returns the number of the oldest flat block file in the database directory, which is not 0 when the
+// block files have been pruned, or -1 when there are no block files.
+func firstBlockFile(dbPath string) int {
+	paths, e := filepath.Glob(filepath.Join(dbPath, "*.fdb"))
+	if e != nil {
+		T.Ln(e)
+		return -1
+	}
+	first := -1
+	for i := range paths {
+		var fileNum uint32
+		if _, e = fmt.Sscanf(filepath.Base(paths[i]), blockFilenameTemplate, &fileNum); e != nil {
+			continue
+		}
+		if first == -1 || int(fileNum) < first {
+			first = int(fileNum)
+		}
+	}
+	return first
+}
+
 // scanBlockFiles searches the database directory for all flat block files to find the end of the most recent file.
 //
 // This position is considered the current write cursor which is also stored in the metadata.
@@ -629,7 +742,12 @@ func (s *blockStore) handleRollback(oldBlockFileNum, oldBlockOffset uint32) {
 func scanBlockFiles(dbPath string) (int, uint32) {
 	lastFile := -1
 	fileLen := uint32(0)
-	for i := 0; ; i++ {
+	// The files are contiguous from the oldest one that has not been pruned.
+	first := firstBlockFile(dbPath)
+	if first == -1 {
+		first = 0
+	}
+	for i := first; ; i++ {
 		filePath := blockFilePath(dbPath, uint32(i))
 		st, e := os.Stat(filePath)
 		if e != nil {
@@ -652,6 +770,11 @@ func newBlockStore(basePath string, network wire.BitcoinNet) *blockStore {
 		fileNum = 0
 		fileOff = 0
 	}
+	// The files before the oldest one on disk have been pruned.
+	firstFileNum := firstBlockFile(basePath)
+	if firstFileNum == -1 {
+		firstFileNum = fileNum
+	}
 	store := &blockStore{
 		network:          network,
 		basePath:         basePath,
@@ -659,6 +782,7 @@ func newBlockStore(basePath string, network wire.BitcoinNet) *blockStore {
 		openBlockFiles:   make(map[uint32]*lockableFile),
 		openBlocksLRU:    list.New(),
 		fileNumToLRUElem: make(map[uint32]*list.Element),
+		firstFileNum:     uint32(firstFileNum),
 		writeCursor: &writeCursor{
 			curFile:    &lockableFile{},
 			curFileNum: uint32(fileNum),
@@ -897,6 +897,8 @@ type transaction struct {
 	// The pendingBlocks map is kept to allow quick lookups of pending data by block hash.
 	pendingBlocks    map[chainhash.Hash]int
 	pendingBlockData []pendingBlock
+	// The block files before pruneUpTo are deleted on commit.
+	pruneUpTo uint32
 	// Keys that need to be stored or deleted on commit.
 	pendingKeys   *treap.Mutable
 	pendingRemove *treap.Mutable
@@ -1480,6 +1482,69 @@ func (tx *transaction) FetchBlockRegions(regions []database.BlockRegion) ([][]by
 	return blockRegions, nil
 }
 
+// PruneBlocks deletes the oldest block files until the block files take up no more than targetSize bytes, or the file
+// holding the block identified by keep would be next. The current write file is never deleted. The files are deleted
+// after the transaction is committed, and the entries of their blocks are kept in the block index so fetching them
+// returns ErrBlockPruned.
+//
+// Returns the following errors as required by the interface contract:
+//
+//   - ErrBlockNotFound if the block identified by keep does not exist
+//
+//   - ErrTxNotWritable if attempted against a read-only transaction
+//
+//   - ErrTxClosed if the transaction has already been closed
+//
+// In addition, returns ErrDriverSpecific if the sizes of the block files can't be determined.
+//
+// This function is part of the database.Tx interface implementation.
+func (tx *transaction) PruneBlocks(targetSize uint64, keep *chainhash.Hash) (pruned []chainhash.Hash, e error) {
+	// Ensure transaction state is valid.
+	if e = tx.checkClosed(); E.Chk(e) {
+		return nil, e
+	}
+	// Ensure the transaction is writable.
+	if !tx.writable {
+		str := "prune blocks requires a writable database transaction"
+		return nil, makeDbErr(database.ErrTxNotWritable, str, nil)
+	}
+	// A block that is pending to be written on commit goes into the current write file or a later one, which are never
+	// pruned.
+	keepFileNum := ^uint32(0)
+	if _, exists := tx.pendingBlocks[*keep]; !exists {
+		var blockRow []byte
+		if blockRow, e = tx.fetchBlockRow(keep); E.Chk(e) {
+			return nil, e
+		}
+		keepFileNum = deserializeBlockLoc(blockRow).blockFileNum
+	}
+	// Files already marked for pruning in this transaction are not counted again.
+	var fromFileNum, upTo uint32
+	if fromFileNum, upTo, e = tx.db.store.pruneSize(targetSize, keepFileNum, tx.pruneUpTo); E.Chk(e) {
+		return nil, e
+	}
+	if upTo <= fromFileNum {
+		return nil, nil
+	}
+	// Find the blocks stored in the files being pruned.
+	e = tx.blockIdxBucket.ForEach(
+		func(k, v []byte) (e error) {
+			fileNum := deserializeBlockLoc(v).blockFileNum
+			if fileNum >= fromFileNum && fileNum < upTo {
+				var hash chainhash.Hash
+				copy(hash[:], k)
+				pruned = append(pruned, hash)
+			}
+			return nil
+		},
+	)
+	if E.Chk(e) {
+		return nil, e
+	}
+	tx.pruneUpTo = upTo
+	return pruned, nil
+}
+
 // close marks the transaction closed then releases any pending data, the underlying snapshot, the transaction read
 // lock, and the write lock when the transaction is writable.
 func (tx *transaction) close() {
@@ -1579,7 +1644,18 @@ func (tx *transaction) Commit() (e error) {
 		return makeDbErr(database.ErrTxNotWritable, str, nil)
 	}
 	// Write pending data.  The function will rollback if any errors occur.
-	return tx.writePendingAndCommit()
+	if e = tx.writePendingAndCommit(); E.Chk(e) {
+		return e
+	}
+	// Delete the pruned block files. The metadata is flushed first, so after an unexpected shutdown it can't describe a
+	// state from before the pruning, such as a lower prune height recorded by the caller, while the files are gone.
+	if tx.pruneUpTo > 0 {
+		if e = tx.db.cache.flush(); E.Chk(e) {
+			return e
+		}
+		tx.db.store.pruneFiles(tx.pruneUpTo)
+	}
+	return nil
 }
 
 // Rollback undoes all changes that have been made to the root bucket and all of its sub-buckets.
@@ -16,6 +16,7 @@ import (
 	ldberrors "github.com/btcsuite/goleveldb/leveldb/errors"
 	
 	"github.com/p9c/pod/pkg/chaincfg"
+	"github.com/p9c/pod/pkg/chainhash"
 	"github.com/p9c/pod/pkg/database"
 	"github.com/p9c/pod/pkg/wire"
 )
@@ -236,6 +237,108 @@ func TestCornerCases(t *testing.T) {
 	}
 }
 
+// TestPruneBlocks ensures pruning deletes the oldest block files down to the target size without touching the file of
+// the block to keep, and that the data of the pruned blocks can't be fetched, also after reopening the database.
+func TestPruneBlocks(t *testing.T) {
+	t.Parallel()
+	dbPath := filepath.Join(os.TempDir(), "ffldb-prune")
+	_ = os.RemoveAll(dbPath)
+	idb, e := openDB(dbPath, blockDataNet, true)
+	if e != nil {
+		t.Fatalf("openDB: unexpected error: %v", e)
+	}
+	defer func() {
+		if e = idb.Close(); E.Chk(e) {
+		}
+		if e = os.RemoveAll(dbPath); E.Chk(e) {
+		}
+	}()
+	// Use small files so the test blocks are spread over several of them.
+	store := idb.(*db).store
+	store.maxBlockFileSize = 2048
+	// Make distinct blocks from the genesis block by changing its nonce.
+	blocks := make([]*block.Block, 64)
+	for i := range blocks {
+		msgBlock := *chaincfg.MainNetParams.GenesisBlock
+		msgBlock.Header.Nonce = uint32(i)
+		blocks[i] = block.NewBlock(&msgBlock)
+	}
+	for i := range blocks {
+		if e = idb.Update(
+			func(tx database.Tx) (e error) {
+				return tx.StoreBlock(blocks[i])
+			},
+		); e != nil {
+			t.Fatalf("StoreBlock #%d: unexpected error: %v", i, e)
+		}
+	}
+	keep := blocks[len(blocks)/2]
+	var keepFileNum uint32
+	var pruned []chainhash.Hash
+	if e = idb.Update(
+		func(tx database.Tx) (e error) {
+			keepFileNum = deserializeBlockLoc(tx.(*transaction).blockIdxBucket.Get(keep.Hash()[:])).blockFileNum
+			pruned, e = tx.PruneBlocks(0, keep.Hash())
+			return e
+		},
+	); e != nil {
+		t.Fatalf("PruneBlocks: unexpected error: %v", e)
+	}
+	if store.firstFileNum != keepFileNum {
+		t.Fatalf("PruneBlocks: first file is %d, want %d", store.firstFileNum, keepFileNum)
+	}
+	if len(pruned) == 0 || len(pruned) >= len(blocks)/2 {
+		t.Fatalf("PruneBlocks: pruned %d blocks of the first %d", len(pruned), len(blocks)/2)
+	}
+	for fileNum := uint32(0); fileNum < keepFileNum; fileNum++ {
+		if _, e = os.Stat(blockFilePath(dbPath, fileNum)); !os.IsNotExist(e) {
+			t.Fatalf("pruned block file %d was not deleted", fileNum)
+		}
+	}
+	check := func(testName string, tx database.Tx) {
+		for i := range pruned {
+			if has, e := tx.HasBlock(&pruned[i]); !has || e != nil {
+				t.Errorf("%s: pruned block %s is not known to the database", testName, pruned[i])
+			}
+		}
+		if _, e := tx.FetchBlock(blocks[0].Hash()); !checkDbError(t, testName, e, database.ErrBlockPruned) {
+			return
+		}
+		if _, e := tx.FetchBlockHeader(blocks[1].Hash()); !checkDbError(t, testName, e, database.ErrBlockPruned) {
+			return
+		}
+		for _, b := range []*block.Block{keep, blocks[len(blocks)-1]} {
+			if _, e := tx.FetchBlock(b.Hash()); e != nil {
+				t.Errorf("%s: FetchBlock %s: unexpected error: %v", testName, b.Hash(), e)
+			}
+		}
+	}
+	if e = idb.View(
+		func(tx database.Tx) (e error) {
+			check("pruned", tx)
+			return nil
+		},
+	); E.Chk(e) {
+	}
+	// Ensure the pruned files are still known to be pruned after reopening the database.
+	if e = idb.Close(); e != nil {
+		t.Fatalf("Close: unexpected error: %v", e)
+	}
+	if idb, e = openDB(dbPath, blockDataNet, false); e != nil {
+		t.Fatalf("openDB: unexpected error: %v", e)
+	}
+	if first := idb.(*db).store.firstFileNum; first != keepFileNum {
+		t.Fatalf("reopened: first file is %d, want %d", first, keepFileNum)
+	}
+	if e = idb.View(
+		func(tx database.Tx) (e error) {
+			check("reopened", tx)
+			return nil
+		},
+	); E.Chk(e) {
+	}
+}
+
 // resetDatabase removes everything from the opened database associated with the test context including all metadata and the mock files.
 func resetDatabase(tc *testContext) bool {
 	// Reset the metadata.
@@ -224,6 +224,8 @@ type Tx interface {
 	//
 	//   - ErrBlockNotFound if the requested block hash does not exist
 	//
+	//   - ErrBlockPruned if the block exists but its data was pruned
+	//
 	//   - ErrTxClosed if the transaction has already been closed
 	//
 	//   - ErrCorruption if the database has somehow become corrupted
@@ -247,6 +249,8 @@ type Tx interface {
 	//
 	//   - ErrBlockNotFound if any of the request block hashes do not exist
 	//
+	//   - ErrBlockPruned if the data of any of the requested blocks was pruned
+	//
 	//   - ErrTxClosed if the transaction has already been closed
 	//
 	//   - ErrCorruption if the database has somehow become corrupted
@@ -263,6 +267,8 @@ type Tx interface {
 	//
 	//   - ErrBlockNotFound if the requested block hash does not exist
 	//
+	//   - ErrBlockPruned if the block exists but its data was pruned
+	//
 	//   - ErrTxClosed if the transaction has already been closed
 	//
 	//   - ErrCorruption if the database has somehow become corrupted
@@ -279,6 +285,8 @@ type Tx interface {
 	//
 	//   - ErrBlockNotFound if the any of the requested block hashes do not exist
 	//
+	//   - ErrBlockPruned if the data of any of the requested blocks was pruned
+	//
 	//   - ErrTxClosed if the transaction has already been closed
 	//
 	//   - ErrCorruption if the database has somehow become corrupted
@@ -304,6 +312,8 @@ type Tx interface {
 	//   - ErrBlockRegionInvalid if the region exceeds the bounds of the
 	//   associated block
 	//
+	//   - ErrBlockPruned if the data of the block was pruned
+	//
 	//   - ErrTxClosed if the transaction has already been closed
 	//
 	//   - ErrCorruption if the database has somehow become corrupted
@@ -328,6 +338,8 @@ type Tx interface {
 	//
 	//   - ErrBlockRegionInvalid if one or more region exceed the bounds of the associated block
 	//
+	//   - ErrBlockPruned if the data of one or more of the blocks was pruned
+	//
 	//   - ErrTxClosed if the transaction has already been closed
 	//
 	//   - ErrCorruption if the database has somehow become corrupted
@@ -336,6 +348,22 @@ type Tx interface {
 	// after a transaction has ended results in undefined behavior. This constraint prevents additional data copies and
 	// allows support for memory-mapped database implementations.
 	FetchBlockRegions(regions []BlockRegion) ([][]byte, error)
+	// PruneBlocks deletes the data of the oldest blocks until the block storage takes up no more than targetSize bytes,
+	// or no more can be deleted without deleting the block identified by keep or any block stored after it. Depending
+	// on the implementation, the storage may only be freed in large units, such as whole files of blocks. The deletion
+	// happens when the transaction is committed. The blocks remain known to the database, so HasBlock still
+	// reports them, while fetching their data, including their headers, returns ErrBlockPruned. It returns the hashes
+	// of the blocks whose data will be deleted.
+	//
+	// The interface contract guarantees at least the following errors will be returned (other implementation-specific
+	// errors are possible):
+	//
+	//   - ErrBlockNotFound if the block identified by keep does not exist
+	//
+	//   - ErrTxNotWritable if attempted against a read-only transaction
+	//
+	//   - ErrTxClosed if the transaction has already been closed
+	PruneBlocks(targetSize uint64, keep *chainhash.Hash) ([]chainhash.Hash, error)
 	// Commit commits all changes that have been made to the metadata or block storage. Depending on the backend
 	// implementation this could be to a cache that is periodically synced to persistent storage or directly to
 	// persistent storage.
@@ -0,0 +1,279 @@
+// Package diffsim simulates the difficulty adjustment of the hard forks in fork.List by mining synthetic blocks on a
+// chain without a database, with the hashrate of each algorithm following a profile, so the effect of changes to the
+// fork parameters can be seen before they are tried on testnet.
+//
+// Each block is found by racing the algorithms against each other, the time each takes being drawn from an exponential
+// distribution with a mean of the target interval of the algorithm multiplied by its difficulty relative to the start
+// of the simulation and divided by its hashrate. The random source is seeded from the scenario, so a scenario always
+// produces the same blocks.
+package diffsim
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"math/rand"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/p9c/pod/pkg/bits"
+	"github.com/p9c/pod/pkg/blockchain"
+	"github.com/p9c/pod/pkg/chaincfg"
+	"github.com/p9c/pod/pkg/fork"
+	"github.com/p9c/pod/pkg/wire"
+)
+
+// Profile gives the hashrate of the algorithm of a block version when mining the block at a height, as a multiple of
+// the hashrate that finds blocks at the target interval of the algorithm at its difficulty at the start of the
+// simulation.
+type Profile func(version int32, height int32) float64
+
+// Skew gives the number of seconds the miners of the algorithm of a block version move the timestamp of the block at a
+// height away from the actual time. The timestamp is kept after the median time of the past blocks and no further
+// ahead of the actual time than the consensus rules allow.
+type Skew func(version int32, height int32) int64
+
+// Scenario is the parameters of a simulation
+type Scenario struct {
+	Name string
+	// Fork is the index in fork.List of the hard fork whose difficulty adjustment is simulated
+	Fork int
+	// Blocks is the number of blocks to mine
+	Blocks   int32
+	Seed     int64
+	Hashrate Profile
+	// Skew is optional
+	Skew Skew
+}
+
+// Algo is an algorithm of the simulated hard fork
+type Algo struct {
+	Name    string
+	Version int32
+	// Interval is the target number of seconds between blocks of the algorithm
+	Interval float64
+}
+
+// Block is a block mined in a simulation
+type Block struct {
+	Height int32
+	// Time is the timestamp of the block in seconds from the genesis block, and Actual is when it was actually found
+	Time, Actual int64
+	Version      int32
+	Bits         uint32
+	// Difficulty is the difficulty of the next block of each algorithm relative to the start of the simulation, in
+	// the order of Result.Algos. It is zero or negative when the target is not a positive number, which no block can
+	// meet.
+	Difficulty []float64
+}
+
+// Result is the blocks mined in a simulation
+type Result struct {
+	Scenario *Scenario
+	Algos    []Algo
+	Blocks   []Block
+}
+
+// Run mines the blocks of a scenario. As the activation of the hard forks depends on fork.IsTestnet, which is set while
+// the simulation runs, only one simulation can be run at a time.
+func Run(sc *Scenario) (r *Result, e error) {
+	if sc.Fork < 0 || sc.Fork > 1 {
+		e = fmt.Errorf("cannot simulate hard fork %d", sc.Fork)
+		return
+	}
+	if sc.Hashrate == nil {
+		e = errors.New("scenario has no hashrate profile")
+		return
+	}
+	// The plan 9 hard fork is active from the genesis block on testnet
+	isTestnet := fork.IsTestnet
+	fork.IsTestnet = sc.Fork == 1
+	defer func() { fork.IsTestnet = isTestnet }()
+	params := chaincfg.MainNetParams
+	if fork.IsTestnet {
+		params = chaincfg.TestNet3Params
+	}
+	r = &Result{Scenario: sc}
+	hf := fork.List[sc.Fork]
+	for name, a := range hf.Algos {
+		interval := float64(a.VersionInterval)
+		if interval == 0 {
+			interval = float64(hf.TargetTimePerBlock) * float64(len(hf.Algos))
+		}
+		r.Algos = append(r.Algos, Algo{Name: name, Version: a.Version, Interval: interval})
+	}
+	sort.Slice(r.Algos, func(i, j int) bool { return r.Algos[i].Version < r.Algos[j].Version })
+	b := blockchain.NewSimChain(&params)
+	tip := b.BestChain.Tip()
+	var diffs blockchain.Diffs
+	if diffs, e = b.CalcNextRequiredDifficultyPlan9Controller(tip); E.Chk(e) {
+		return
+	}
+	base := make([]*big.Float, len(r.Algos))
+	for i, a := range r.Algos {
+		base[i] = new(big.Float).SetInt(bits.CompactToBig(diffs[a.Version]))
+	}
+	difficulty := func(nBits uint32, i int) float64 {
+		d, _ := new(big.Float).Quo(base[i], new(big.Float).SetInt(bits.CompactToBig(nBits))).Float64()
+		return d
+	}
+	rng := rand.New(rand.NewSource(sc.Seed))
+	genesis := params.GenesisBlock.Header.Timestamp.Unix()
+	prevHash := params.GenesisBlock.Header.BlockHash()
+	var actual float64
+	current := make([]float64, len(r.Algos))
+	for i, a := range r.Algos {
+		current[i] = difficulty(diffs[a.Version], i)
+	}
+	for height := int32(1); height <= sc.Blocks; height++ {
+		// A time is drawn for every algorithm whether or not it has any hashrate, so that the profile of one algorithm
+		// does not change the draws of the others.
+		winner, soonest := -1, 0.0
+		for i, a := range r.Algos {
+			draw := rng.ExpFloat64()
+			rate := sc.Hashrate(a.Version, height)
+			if rate <= 0 || current[i] <= 0 {
+				continue
+			}
+			t := draw * a.Interval * current[i] / rate
+			if winner < 0 || t < soonest {
+				winner, soonest = i, t
+			}
+		}
+		if winner < 0 {
+			e = fmt.Errorf("no algorithm can mine the block at height %d", height)
+			return
+		}
+		actual += soonest
+		version := r.Algos[winner].Version
+		now := genesis + int64(actual)
+		stamp := now
+		if sc.Skew != nil {
+			stamp += sc.Skew(version, height)
+		}
+		if mtp := tip.CalcPastMedianTime().Unix(); stamp <= mtp {
+			stamp = mtp + 1
+		}
+		if stamp > now+blockchain.MaxTimeOffsetSeconds {
+			stamp = now + blockchain.MaxTimeOffsetSeconds
+		}
+		header := &wire.BlockHeader{
+			Version:   version,
+			PrevBlock: prevHash,
+			Bits:      diffs[version],
+			Timestamp: time.Unix(stamp, 0),
+			Nonce:     uint32(height),
+		}
+		prevHash = header.BlockHash()
+		tip = blockchain.NewBlockNode(header, tip)
+		b.BestChain.SetTip(tip)
+		if diffs, e = b.CalcNextRequiredDifficultyPlan9Controller(tip); E.Chk(e) {
+			return
+		}
+		blk := Block{
+			Height:     height,
+			Time:       stamp - genesis,
+			Actual:     int64(actual),
+			Version:    version,
+			Bits:       header.Bits,
+			Difficulty: make([]float64, len(r.Algos)),
+		}
+		for i, a := range r.Algos {
+			current[i] = difficulty(diffs[a.Version], i)
+			blk.Difficulty[i] = current[i]
+		}
+		r.Blocks = append(r.Blocks, blk)
+	}
+	return
+}
+
+// WriteCSV writes a row for each block with its height, timestamp and actual time in seconds from the genesis block,
+// the seconds since the timestamp of the previous block, its algorithm and bits, and the difficulty of the next block
+// of each algorithm.
+func (r *Result) WriteCSV(w io.Writer) (e error) {
+	cw := csv.NewWriter(w)
+	row := []string{"height", "time", "actual", "interval", "algo", "version", "bits"}
+	names := make(map[int32]string, len(r.Algos))
+	for _, a := range r.Algos {
+		row = append(row, a.Name)
+		names[a.Version] = a.Name
+	}
+	if e = cw.Write(row); E.Chk(e) {
+		return
+	}
+	var prev int64
+	for _, blk := range r.Blocks {
+		row = append(
+			row[:0],
+			strconv.FormatInt(int64(blk.Height), 10),
+			strconv.FormatInt(blk.Time, 10),
+			strconv.FormatInt(blk.Actual, 10),
+			strconv.FormatInt(blk.Time-prev, 10),
+			names[blk.Version],
+			strconv.FormatInt(int64(blk.Version), 10),
+			fmt.Sprintf("%08x", blk.Bits),
+		)
+		for _, d := range blk.Difficulty {
+			row = append(row, strconv.FormatFloat(d, 'g', 8, 64))
+		}
+		if e = cw.Write(row); E.Chk(e) {
+			return
+		}
+		prev = blk.Time
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// AlgoSummary is the number of blocks of an algorithm and the average number of seconds between them
+type AlgoSummary struct {
+	Algo
+	Blocks          int
+	AverageInterval float64
+}
+
+// Summary is the number of blocks mined from a height on and the average number of seconds between them, in total and
+// for each algorithm
+type Summary struct {
+	Blocks          int
+	AverageInterval float64
+	Algos           []AlgoSummary
+}
+
+// Summarise returns the summary of the blocks from the passed height on, which should be after the difficulty has
+// settled from the start of the simulation.
+func (r *Result) Summarise(from int32) (s Summary) {
+	s.Algos = make([]AlgoSummary, len(r.Algos))
+	index := make(map[int32]int, len(r.Algos))
+	for i, a := range r.Algos {
+		s.Algos[i].Algo = a
+		index[a.Version] = i
+	}
+	var first int64
+	firstAlgo := make([]int64, len(r.Algos))
+	for _, blk := range r.Blocks {
+		if blk.Height < from {
+			continue
+		}
+		if s.Blocks == 0 {
+			first = blk.Time
+		}
+		s.Blocks++
+		if s.Blocks > 1 {
+			s.AverageInterval = float64(blk.Time-first) / float64(s.Blocks-1)
+		}
+		i := index[blk.Version]
+		as := &s.Algos[i]
+		if as.Blocks == 0 {
+			firstAlgo[i] = blk.Time
+		}
+		as.Blocks++
+		if as.Blocks > 1 {
+			as.AverageInterval = float64(blk.Time-firstAlgo[i]) / float64(as.Blocks-1)
+		}
+	}
+	return
+}
@@ -0,0 +1,74 @@
+package diffsim
+
+import (
+	"github.com/p9c/pod/pkg/blockchain"
+	"github.com/p9c/pod/pkg/fork"
+)
+
+// Steady is the same hashrate for every algorithm at every height
+func Steady(rate float64) Profile {
+	return func(int32, int32) float64 { return rate }
+}
+
+// Rates is a constant hashrate for each algorithm, those not in the map having none
+func Rates(rates map[int32]float64) Profile {
+	return func(version int32, _ int32) float64 { return rates[version] }
+}
+
+// Step switches from one profile to another at a height
+func Step(before, after Profile, height int32) Profile {
+	return func(version int32, h int32) float64 {
+		if h < height {
+			return before(version, h)
+		}
+		return after(version, h)
+	}
+}
+
+// Scale multiplies the hashrate of one algorithm of a profile from a height on
+func Scale(p Profile, version int32, height int32, factor float64) Profile {
+	return func(v int32, h int32) float64 {
+		if v == version && h >= height {
+			return p(v, h) * factor
+		}
+		return p(v, h)
+	}
+}
+
+// Vanish removes all of the hashrate of one algorithm of a profile from a height on
+func Vanish(p Profile, version int32, height int32) Profile {
+	return Scale(p, version, height, 0)
+}
+
+// Manipulate moves the timestamps of the blocks of one algorithm by offset seconds between two heights
+func Manipulate(version int32, offset int64, from, to int32) Skew {
+	return func(v int32, h int32) int64 {
+		if v == version && h >= from && h <= to {
+			return offset
+		}
+		return 0
+	}
+}
+
+// Scenarios returns the standard scenarios for a hard fork: a steady hashrate, the hashrate of every algorithm
+// quadrupling, the algorithm of the lowest version losing all of its hashrate, and the miners of that algorithm pushing their
+// timestamps as far ahead as they can. Each lasts the given number of blocks with the change half way in, and starts
+// with every algorithm at the given hashrate, which should be well above 1 so that the difficulty can settle above the
+// minimum before the change.
+func Scenarios(hf int, blocks int32, seed int64, rate float64) (scenarios []*Scenario) {
+	if hf < 0 || hf >= len(fork.AlgoSlices) {
+		return
+	}
+	// the algorithm slices are sorted with the highest version first
+	version := fork.AlgoSlices[hf][len(fork.AlgoSlices[hf])-1].Version
+	at := blocks / 2
+	return []*Scenario{
+		{Name: "steady", Fork: hf, Blocks: blocks, Seed: seed, Hashrate: Steady(rate)},
+		{Name: "step", Fork: hf, Blocks: blocks, Seed: seed, Hashrate: Step(Steady(rate), Steady(rate*4), at)},
+		{Name: "vanish", Fork: hf, Blocks: blocks, Seed: seed, Hashrate: Vanish(Steady(rate), version, at)},
+		{
+			Name: "timewarp", Fork: hf, Blocks: blocks, Seed: seed, Hashrate: Steady(rate),
+			Skew: Manipulate(version, blockchain.MaxTimeOffsetSeconds, at, blocks),
+		},
+	}
+}
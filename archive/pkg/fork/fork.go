@@ -3,12 +3,9 @@ package fork
 
 import (
 	"encoding/hex"
-	"fmt"
 	"github.com/p9c/pod/pkg/bits"
-	"github.com/p9c/log"
 	"math/big"
 	"math/rand"
-	"sort"
 	"time"
 )
 
@@ -55,81 +52,15 @@ func (a AlgoSpecs) Swap(i, j int) {
 	a[i], a[j] = a[j], a[i]
 }
 
-func init() {
-	ForkCalc()
-}
-
-var done bool
-
-func ForkCalc() {
-	if done {
-		I.Ln(log.Caller("called again", 1))
-		return
-	}
-	done = true
-	T.Ln("running fork data init")
-	for i := range P9AlgosNumeric {
-		List[1].AlgoVers[i] = fmt.Sprintf("Div%d", P9AlgosNumeric[i].VersionInterval)
-	}
-	for i, v := range P9AlgoVers {
-		List[1].Algos[v] = P9AlgosNumeric[i]
-	}
-	AlgoSlices = append(AlgoSlices, AlgoSpecs{})
-	for i := range Algos {
-		AlgoSlices[0] = append(
-			AlgoSlices[0], AlgoSpec{
-				List[0].Algos[i].Version,
-				i,
-			},
-		)
-	}
-	AlgoSlices = append(AlgoSlices, AlgoSpecs{})
-	for i := range P9Algos {
-		AlgoSlices[1] = append(
-			AlgoSlices[1], AlgoSpec{
-				List[1].Algos[i].Version,
-				i,
-			},
-		)
-	}
-	sort.Sort(AlgoSlices[0])
-	sort.Sort(AlgoSlices[1])
-	D.Ln(P9AlgoVers)
-	baseVersionName := AlgoSlices[1][0].Name
-	baseVersionInterval := float64(P9Algos[baseVersionName].VersionInterval)
-	D.Ln(baseVersionName, baseVersionInterval)
-	P9Average = 0
-	for _, i := range AlgoSlices[1] {
-		vi := float64(P9Algos[i.Name].VersionInterval)
-		p9a := baseVersionInterval / vi
-		P9Average += p9a
-		// Tracef("P9Average %4.4f %4.4f %d %4.4f", p9a, P9Average, IntervalBase, vi)
-	}
-	D.Ln(P9Average)
-	P9Average = baseVersionInterval / P9Average
-	D.Ln(P9Average)}
-
 var (
+	// AlgoSlices is the algorithms of each hard fork sorted from the highest version to the lowest
 	AlgoSlices []AlgoSpecs
 	// AlgoVers is the lookup for pre hardfork
 	//
-	AlgoVers = map[int32]string{
-		2:   SHA256d,
-		514: Scrypt,
-	}
+	AlgoVers = make(map[int32]string)
 	// Algos are the specifications identifying the algorithm used in the
 	// block proof
-	Algos = map[string]AlgoParams{
-		AlgoVers[2]: {
-			Version: 2,
-			MinBits: MainPowLimitBits,
-		},
-		AlgoVers[514]: {
-			Version: 514,
-			MinBits: MainPowLimitBits,
-			AlgoID:  1,
-		},
-	}
+	Algos = make(map[string]AlgoParams)
 	// FirstPowLimit is
 	FirstPowLimit = func() big.Int {
 		mplb, _ := hex.DecodeString(
@@ -147,7 +78,8 @@ var (
 		)
 		return *big.NewInt(0).SetBytes(mplb)
 	}()
-	p9PowLimitBits = bits.BigToCompact(&p9PowLimit)
+	// P9PowLimitBits is the minimum bits of the plan 9 algorithms
+	P9PowLimitBits = bits.BigToCompact(&p9PowLimit)
 	// IsTestnet is set at startup here to be accessible to all other libraries
 	IsTestnet bool
 	// List is the list of existing hard forks and when they activate
@@ -183,17 +115,9 @@ var (
 	IntervalBase    = 9
 	// P9Algos is the algorithm specifications after the hard fork
 	P9Algos        = make(map[string]AlgoParams)
-	P9AlgosNumeric = map[int32]AlgoParams{
-		5:  {5, p9PowLimitBits, 0, IntervalBase * P9PrimeSequence[0] / IntervalDivisor},  // 2
-		6:  {6, p9PowLimitBits, 1, IntervalBase * P9PrimeSequence[1] / IntervalDivisor},  // 3
-		7:  {7, p9PowLimitBits, 2, IntervalBase * P9PrimeSequence[2] / IntervalDivisor},  // 5
-		8:  {8, p9PowLimitBits, 3, IntervalBase * P9PrimeSequence[3] / IntervalDivisor},  // 7
-		9:  {9, p9PowLimitBits, 4, IntervalBase * P9PrimeSequence[4] / IntervalDivisor},  // 11
-		10: {10, p9PowLimitBits, 5, IntervalBase * P9PrimeSequence[5] / IntervalDivisor}, // 13
-		11: {11, p9PowLimitBits, 7, IntervalBase * P9PrimeSequence[7] / IntervalDivisor}, // 17
-		12: {12, p9PowLimitBits, 6, IntervalBase * P9PrimeSequence[6] / IntervalDivisor}, // 19
-		13: {13, p9PowLimitBits, 8, IntervalBase * P9PrimeSequence[8] / IntervalDivisor}, // 23
-	}
+	// P9AlgosNumeric is the plan 9 algorithm specifications by version. The algorithms of all of the hard forks are
+	// defined by registering them (see Register), which fills these tables.
+	P9AlgosNumeric = make(map[int32]AlgoParams)
 	
 	P9Average float64
 	
@@ -239,7 +163,8 @@ func GetAlgoName(algoVer int32, height int32) (name string) {
 // GetRandomVersion returns a random version relevant to the current hard fork state and height
 func GetRandomVersion(height int32) int32 {
 	rand.Seed(time.Now().UnixNano())
-	return int32(rand.Intn(len(List[GetCurrent(height)].Algos)) + 5)
+	vers := GetAlgoVerSlice(height)
+	return vers[rand.Intn(len(vers))]
 }
 
 // GetAlgoVer returns the version number for a given algorithm (by string name) at a given height. If "random" is given,
@@ -255,22 +180,12 @@ func GetAlgoVer(name string, height int32) (version int32) {
 	return
 }
 
+// algoVerSlice is the versions of the algorithms of each hard fork in ascending order
 var algoVerSlice [][]int32
 
+// GetAlgoVerSlice returns the versions of the algorithms at a height in ascending order
 func GetAlgoVerSlice(height int32) (o []int32) {
-	hf := GetCurrent(height)
-	if algoVerSlice == nil {
-		algoVerSlice = make([][]int32, 0, len(List))
-		for i := range List {
-			av := make([]int32, 0, len(List[i].AlgoVers))
-			for j := range List[i].AlgoVers {
-				av = append(av, j)
-			}
-			algoVerSlice = append(algoVerSlice, av)
-		}
-	}
-	// D.S(algoVerSlice)
-	return algoVerSlice[hf]
+	return algoVerSlice[GetCurrent(height)]
 }
 
 // AlgoVerIterator returns a next and more function to use in a for loop to
@@ -0,0 +1,273 @@
+package fork
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// HashFunc computes the proof of work hash of a serialized block header at a height
+type HashFunc func(header []byte, height int32) []byte
+
+// Algorithm is a proof of work algorithm of one of the hard forks in List
+type Algorithm struct {
+	// Fork is the index in List of the hard fork the algorithm is used in
+	Fork int
+	Name string
+	AlgoParams
+	Hash HashFunc
+	// Networks is the names of the networks the algorithm is used on, or all of them when it is empty, so that cheap
+	// algorithms can be defined for the test networks only
+	Networks []string
+}
+
+// Override replaces the parameters of a registered algorithm on one network. The fields left at their zero value keep
+// the registered value.
+type Override struct {
+	Network         string
+	Fork            int
+	Name            string
+	MinBits         uint32
+	VersionInterval int
+	Hash            HashFunc
+}
+
+var (
+	registered []Algorithm
+	overrides  []Override
+	// network is the name of the network the algorithm tables are built for
+	network = "mainnet"
+	// hashFuncs is the hash function of each algorithm of each hard fork
+	hashFuncs []map[string]HashFunc
+)
+
+// onNetwork returns whether an algorithm is used on a network
+func (a *Algorithm) onNetwork(name string) bool {
+	if len(a.Networks) == 0 {
+		return true
+	}
+	for _, n := range a.Networks {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// overlaps returns whether there is a network both algorithms are used on
+func (a *Algorithm) overlaps(b *Algorithm) bool {
+	if len(a.Networks) == 0 || len(b.Networks) == 0 {
+		return true
+	}
+	for _, n := range a.Networks {
+		if b.onNetwork(n) {
+			return true
+		}
+	}
+	return false
+}
+
+// Register adds a proof of work algorithm to a hard fork. The algorithm must have a name, minimum bits and hash
+// function, those of the hard forks after the first a version interval, and its name, version and algorithm ID must not
+// be used by another algorithm of the same hard fork on any of its networks. The registry is not safe for concurrent
+// access, so algorithms should be registered in init functions.
+func Register(a Algorithm) (e error) {
+	switch {
+	case a.Fork < 0 || a.Fork >= len(List):
+		e = fmt.Errorf("algorithm %q is registered to hard fork %d which does not exist", a.Name, a.Fork)
+	case a.Name == "":
+		e = fmt.Errorf("algorithm with version %d of hard fork %d has no name", a.Version, a.Fork)
+	case a.Version <= 0:
+		e = fmt.Errorf("algorithm %q has invalid version %d", a.Name, a.Version)
+	case a.MinBits == 0:
+		e = fmt.Errorf("algorithm %q has no minimum bits", a.Name)
+	case a.Hash == nil:
+		e = fmt.Errorf("algorithm %q has no hash function", a.Name)
+	case a.Fork > 0 && a.VersionInterval <= 0:
+		e = fmt.Errorf("algorithm %q of hard fork %d has no version interval", a.Name, a.Fork)
+	}
+	if e != nil {
+		return
+	}
+	for i := range registered {
+		r := &registered[i]
+		if r.Fork != a.Fork || !r.overlaps(&a) {
+			continue
+		}
+		switch {
+		case r.Name == a.Name:
+			e = fmt.Errorf("algorithm %q is already registered to hard fork %d", a.Name, a.Fork)
+		case r.Version == a.Version:
+			e = fmt.Errorf("algorithm %q has the same version %d as %q", a.Name, a.Version, r.Name)
+		case r.AlgoID == a.AlgoID:
+			e = fmt.Errorf("algorithm %q has the same algorithm ID %d as %q", a.Name, a.AlgoID, r.Name)
+		}
+		if e != nil {
+			return
+		}
+	}
+	registered = append(registered, a)
+	build()
+	return
+}
+
+// MustRegister registers an algorithm and panics if it is not consistent with those already registered. It is
+// intended for use in init functions.
+func MustRegister(a Algorithm) {
+	if e := Register(a); e != nil {
+		panic(e)
+	}
+}
+
+// RegisterOverride adds an override of the parameters of a registered algorithm on a network
+func RegisterOverride(o Override) (e error) {
+	var found bool
+	for i := range registered {
+		r := &registered[i]
+		if r.Fork == o.Fork && r.Name == o.Name && r.onNetwork(o.Network) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("there is no algorithm %q of hard fork %d on %s to override", o.Name, o.Fork, o.Network)
+	}
+	for _, r := range overrides {
+		if r.Network == o.Network && r.Fork == o.Fork && r.Name == o.Name {
+			return fmt.Errorf("algorithm %q of hard fork %d is already overridden on %s", o.Name, o.Fork, o.Network)
+		}
+	}
+	if o.VersionInterval < 0 {
+		return fmt.Errorf("override of algorithm %q has invalid version interval %d", o.Name, o.VersionInterval)
+	}
+	overrides = append(overrides, o)
+	build()
+	return
+}
+
+// MustRegisterOverride registers an override and panics if it does not apply to a registered algorithm
+func MustRegisterOverride(o Override) {
+	if e := RegisterOverride(o); e != nil {
+		panic(e)
+	}
+}
+
+// SetNetwork rebuilds the algorithm tables of the hard forks with the algorithms and overrides of a network, by its
+// name in the chain parameters, and checks that every hard fork has an algorithm on it
+func SetNetwork(name string) (e error) {
+	network = name
+	build()
+	return Validate()
+}
+
+// Network returns the name of the network the algorithm tables are built for
+func Network() string {
+	return network
+}
+
+// Validate checks that every hard fork has at least one algorithm on the current network
+func Validate() (e error) {
+	if len(registered) == 0 {
+		return errors.New("no algorithms are registered")
+	}
+	for i := range List {
+		if len(List[i].Algos) == 0 {
+			return fmt.Errorf("hard fork %d %q has no algorithms on %s", i, List[i].Name, network)
+		}
+	}
+	return
+}
+
+// GetHashFunc returns the hash function of an algorithm at a height, or nil if there is no algorithm by the name
+func GetHashFunc(name string, height int32) HashFunc {
+	hf := GetCurrent(height)
+	if hf >= len(hashFuncs) {
+		return nil
+	}
+	return hashFuncs[hf][name]
+}
+
+// Algorithms returns the algorithms of a hard fork on the current network with the overrides applied, in order of
+// their versions
+func Algorithms(hf int) (algos []Algorithm) {
+	for _, a := range active() {
+		if a.Fork == hf {
+			algos = append(algos, a)
+		}
+	}
+	return
+}
+
+// active returns the algorithms on the current network with the overrides applied, in order of their versions
+func active() (algos []Algorithm) {
+	for i := range registered {
+		if !registered[i].onNetwork(network) {
+			continue
+		}
+		a := registered[i]
+		for _, o := range overrides {
+			if o.Network != network || o.Fork != a.Fork || o.Name != a.Name {
+				continue
+			}
+			if o.MinBits != 0 {
+				a.MinBits = o.MinBits
+			}
+			if o.VersionInterval != 0 {
+				a.VersionInterval = o.VersionInterval
+			}
+			if o.Hash != nil {
+				a.Hash = o.Hash
+			}
+		}
+		algos = append(algos, a)
+	}
+	sort.SliceStable(algos, func(i, j int) bool { return algos[i].Version < algos[j].Version })
+	return
+}
+
+// build fills the algorithm tables of the hard forks from the registered algorithms on the current network. The maps
+// are emptied and refilled rather than replaced as they are shared between List and the variables naming them.
+func build() {
+	for i := range List {
+		for name := range List[i].Algos {
+			delete(List[i].Algos, name)
+		}
+		for version := range List[i].AlgoVers {
+			delete(List[i].AlgoVers, version)
+		}
+	}
+	for version := range P9AlgosNumeric {
+		delete(P9AlgosNumeric, version)
+	}
+	hashFuncs = make([]map[string]HashFunc, len(List))
+	AlgoSlices = make([]AlgoSpecs, len(List))
+	algoVerSlice = make([][]int32, len(List))
+	for i := range List {
+		hashFuncs[i] = make(map[string]HashFunc)
+	}
+	for _, a := range active() {
+		List[a.Fork].Algos[a.Name] = a.AlgoParams
+		List[a.Fork].AlgoVers[a.Version] = a.Name
+		hashFuncs[a.Fork][a.Name] = a.Hash
+		AlgoSlices[a.Fork] = append(AlgoSlices[a.Fork], AlgoSpec{a.Version, a.Name})
+		algoVerSlice[a.Fork] = append(algoVerSlice[a.Fork], a.Version)
+		if a.Fork == 1 {
+			P9AlgosNumeric[a.Version] = a.AlgoParams
+		}
+	}
+	for i := range AlgoSlices {
+		sort.Sort(AlgoSlices[i])
+	}
+	// The average block interval of the plan 9 hard fork is the interval at which the algorithms together would find
+	// blocks if each of them found blocks at its own interval. As it is used in the difficulty adjustment it is
+	// calculated in the same order as it always has been, relative to the interval of the highest version.
+	P9Average = 0
+	if len(AlgoSlices) > 1 && len(AlgoSlices[1]) > 0 {
+		baseVersionInterval := float64(P9Algos[AlgoSlices[1][0].Name].VersionInterval)
+		for _, i := range AlgoSlices[1] {
+			P9Average += baseVersionInterval / float64(P9Algos[i.Name].VersionInterval)
+		}
+		P9Average = baseVersionInterval / P9Average
+	}
+	T.Ln("algorithm tables built for", network, P9AlgoVers, P9Average)
+}
@@ -0,0 +1,73 @@
+package fork
+
+import (
+	"testing"
+)
+
+func testHash(header []byte, height int32) []byte { return header }
+
+func cheapHash(header []byte, height int32) []byte { return nil }
+
+// TestRegistry registers algorithms for both hard forks, checks that inconsistent ones are refused, and that the tables
+// follow the network with its own algorithms and overrides.
+func TestRegistry(t *testing.T) {
+	defer func() {
+		registered, overrides, network = nil, nil, "mainnet"
+		build()
+	}()
+	if e := Validate(); e == nil {
+		t.Fatal("an empty registry is valid")
+	}
+	MustRegister(Algorithm{Fork: 0, Name: "zero", AlgoParams: AlgoParams{Version: 2, MinBits: 1}, Hash: testHash})
+	if e := Validate(); e == nil {
+		t.Fatal("registry without an algorithm for the second hard fork is valid")
+	}
+	p9 := AlgoParams{Version: 5, MinBits: 1, VersionInterval: 18}
+	MustRegister(Algorithm{Fork: 1, Name: "one", AlgoParams: p9, Hash: testHash})
+	if e := Validate(); e != nil {
+		t.Fatal(e)
+	}
+	invalid := []Algorithm{
+		{Fork: 2, Name: "nofork", AlgoParams: p9, Hash: testHash},
+		{Fork: 1, AlgoParams: AlgoParams{Version: 6, MinBits: 1, AlgoID: 1, VersionInterval: 18}, Hash: testHash},
+		{Fork: 1, Name: "nohash", AlgoParams: AlgoParams{Version: 6, MinBits: 1, AlgoID: 1, VersionInterval: 18}},
+		{Fork: 1, Name: "nointerval", AlgoParams: AlgoParams{Version: 6, MinBits: 1, AlgoID: 1}, Hash: testHash},
+		{Fork: 1, Name: "one", AlgoParams: AlgoParams{Version: 6, MinBits: 1, AlgoID: 1, VersionInterval: 18}, Hash: testHash},
+		{Fork: 1, Name: "sameversion", AlgoParams: AlgoParams{Version: 5, MinBits: 1, AlgoID: 1, VersionInterval: 18}, Hash: testHash},
+		{Fork: 1, Name: "sameid", AlgoParams: AlgoParams{Version: 6, MinBits: 1, VersionInterval: 18}, Hash: testHash},
+		{Fork: 1, Name: "regtestsameid", AlgoParams: AlgoParams{Version: 6, MinBits: 1, VersionInterval: 18}, Hash: testHash,
+			Networks: []string{"regtest"},
+		},
+	}
+	for _, a := range invalid {
+		if e := Register(a); e == nil {
+			t.Fatalf("algorithm %+v was registered", a)
+		}
+	}
+	cheap := AlgoParams{Version: 6, MinBits: 1, AlgoID: 1, VersionInterval: 36}
+	MustRegister(Algorithm{Fork: 1, Name: "cheap", AlgoParams: cheap, Hash: cheapHash, Networks: []string{"regtest"}})
+	MustRegisterOverride(Override{Network: "regtest", Fork: 1, Name: "one", VersionInterval: 36})
+	if e := RegisterOverride(Override{Network: "mainnet", Fork: 1, Name: "cheap", MinBits: 2}); e == nil {
+		t.Fatal("an algorithm was overridden on a network it is not used on")
+	}
+	if len(P9AlgosNumeric) != 1 || P9AlgosNumeric[5].VersionInterval != 18 || P9Average != 18 {
+		t.Fatalf("mainnet plan 9 tables are %v with an average of %v", P9AlgosNumeric, P9Average)
+	}
+	if e := SetNetwork("regtest"); e != nil {
+		t.Fatal(e)
+	}
+	if len(P9AlgosNumeric) != 2 || P9AlgosNumeric[5].VersionInterval != 36 || P9Average != 18 {
+		t.Fatalf("regtest plan 9 tables are %v with an average of %v", P9AlgosNumeric, P9Average)
+	}
+	IsTestnet = true
+	defer func() { IsTestnet = false }()
+	if vers := GetAlgoVerSlice(1); len(vers) != 2 || vers[0] != 5 || vers[1] != 6 {
+		t.Fatalf("regtest plan 9 versions are %v", vers)
+	}
+	if hf := GetHashFunc("cheap", 1); hf == nil || hf([]byte{1}, 1) != nil {
+		t.Fatal("the regtest algorithm does not have its hash function")
+	}
+	if GetAlgoName(6, 1) != "cheap" || GetAlgoVer("one", 1) != 5 {
+		t.Fatal("the regtest algorithms are not in the tables")
+	}
+}
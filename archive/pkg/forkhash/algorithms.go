@@ -0,0 +1,55 @@
+package forkhash
+
+import (
+	"fmt"
+	
+	"github.com/p9c/pod/pkg/fork"
+)
+
+// p9Algos is the version of each plan 9 algorithm and the position of its interval in fork.P9PrimeSequence, which is
+// also its algorithm ID
+var p9Algos = []struct {
+	version int32
+	index   int
+}{
+	{5, 0}, {6, 1}, {7, 2}, {8, 3}, {9, 4}, {10, 5}, {11, 7}, {12, 6}, {13, 8},
+}
+
+// init registers the algorithms of the hard forks in fork.List
+func init() {
+	fork.MustRegister(
+		fork.Algorithm{
+			Fork:       0,
+			Name:       fork.SHA256d,
+			AlgoParams: fork.AlgoParams{Version: 2, MinBits: fork.MainPowLimitBits},
+			Hash:       SHA256dPoW,
+		},
+	)
+	fork.MustRegister(
+		fork.Algorithm{
+			Fork:       0,
+			Name:       fork.Scrypt,
+			AlgoParams: fork.AlgoParams{Version: 514, MinBits: fork.MainPowLimitBits, AlgoID: 1},
+			Hash:       ScryptPoW,
+		},
+	)
+	for _, a := range p9Algos {
+		interval := fork.IntervalBase * fork.P9PrimeSequence[a.index] / fork.IntervalDivisor
+		fork.MustRegister(
+			fork.Algorithm{
+				Fork: 1,
+				Name: fmt.Sprintf("Div%d", interval),
+				AlgoParams: fork.AlgoParams{
+					Version:         a.version,
+					MinBits:         fork.P9PowLimitBits,
+					AlgoID:          uint32(a.index),
+					VersionInterval: interval,
+				},
+				Hash: DivBlake3,
+			},
+		)
+	}
+	if e := fork.Validate(); E.Chk(e) {
+		panic(e)
+	}
+}
@@ -116,40 +116,47 @@ func DivHash(hf func([]byte) []byte, blockbytes []byte, howmany int) []byte {
 	return hf(ddd)
 }
 
-// Hash computes the hash of bytes using the named hash
+// Hash computes the hash of bytes using the hash function registered for the named algorithm at the height. The
+// algorithms without one of their own use the plan 9 hash.
 func Hash(bytes []byte, name string, height int32) (out chainhash.Hash) {
-	hR := HashReps
-	if fork.IsTestnet {
-		switch {
-		case height == 1:
-			hR = 0
-			// case height < 10:
-			//	hR = 6
-		}
-	}
-	switch name {
-	case fork.Scrypt:
-		if fork.GetCurrent(height) > 0 {
-			_ = out.SetBytes(DivHash(ScryptHash, bytes, hR))
-		} else {
-			_ = out.SetBytes(ScryptHash(bytes))
-		}
-	case fork.SHA256d:
-		if fork.GetCurrent(height) > 0 {
-			_ = out.SetBytes(DivHash(chainhash.DoubleHashB, bytes, hR))
-		} else {
-			_ = out.SetBytes(
-				chainhash.DoubleHashB(
-					bytes,
-				),
-			)
-		}
-	default:
-		_ = out.SetBytes(DivHash(Blake3, bytes, hR))
+	hf := fork.GetHashFunc(name, height)
+	if hf == nil {
+		hf = DivBlake3
 	}
+	_ = out.SetBytes(hf(bytes, height))
 	return
 }
 
+// hashReps returns the number of repetitions of DivHash at a height, which are skipped for the first block on the test
+// networks
+func hashReps(height int32) int {
+	if fork.IsTestnet && height == 1 {
+		return 0
+	}
+	return HashReps
+}
+
+// SHA256dPoW is the proof of work hash of the sha256d algorithm, which is run through DivHash after the first hard fork
+func SHA256dPoW(bytes []byte, height int32) []byte {
+	if fork.GetCurrent(height) > 0 {
+		return DivHash(chainhash.DoubleHashB, bytes, hashReps(height))
+	}
+	return chainhash.DoubleHashB(bytes)
+}
+
+// ScryptPoW is the proof of work hash of the scrypt algorithm, which is run through DivHash after the first hard fork
+func ScryptPoW(bytes []byte, height int32) []byte {
+	if fork.GetCurrent(height) > 0 {
+		return DivHash(ScryptHash, bytes, hashReps(height))
+	}
+	return ScryptHash(bytes)
+}
+
+// DivBlake3 is the proof of work hash of the plan 9 algorithms, DivHash with Blake3
+func DivBlake3(bytes []byte, height int32) []byte {
+	return DivHash(Blake3, bytes, hashReps(height))
+}
+
 // Keccak takes bytes and returns a keccak (sha-3) 256 bit hash
 func Keccak(bytes []byte) []byte {
 	sum := sha3.Sum256(bytes)
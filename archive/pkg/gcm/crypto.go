@@ -10,22 +10,38 @@ import (
 // GetCipher returns a GCM cipher given a password string. Note that this cipher must be renewed every 4gb of encrypted
 // data
 func GetCipher(password []byte) (gcm cipher.AEAD, e error) {
+	ark := Key(password)
+	if gcm, e = NewCipher(ark); E.Chk(e) {
+	}
+	for i := range ark {
+		ark[i] = 0
+	}
+	return
+}
+
+// Key derives the 32 byte key of a password with argon2
+func Key(password []byte) []byte {
 	bytes := make([]byte, len(password))
 	rb := make([]byte, len(password))
 	copy(bytes, password)
 	copy(rb, password)
-	var c cipher.Block
 	rb = reverse(bytes)
 	ark := argon2.IDKey(rb, bytes, 1, 64*1024, 4, 32)
-	if c, e = aes.NewCipher(ark); E.Chk(e) {
-		return
-	}
-	if gcm, e = cipher.NewGCM(c); E.Chk(e) {
-	}
 	for i := range bytes {
 		bytes[i] = 0
 		rb[i] = 0
 	}
+	return ark
+}
+
+// NewCipher returns a GCM cipher using a 32 byte key
+func NewCipher(key []byte) (gcm cipher.AEAD, e error) {
+	var c cipher.Block
+	if c, e = aes.NewCipher(key); E.Chk(e) {
+		return
+	}
+	if gcm, e = cipher.NewGCM(c); E.Chk(e) {
+	}
 	return
 }
 
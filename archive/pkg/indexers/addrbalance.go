@@ -0,0 +1,499 @@
+package indexers
+
+import (
+	"encoding/binary"
+	"errors"
+	"github.com/p9c/pod/pkg/block"
+	"github.com/p9c/pod/pkg/btcaddr"
+
+	"github.com/p9c/pod/pkg/blockchain"
+	"github.com/p9c/pod/pkg/chainhash"
+	"github.com/p9c/pod/pkg/database"
+	"github.com/p9c/pod/pkg/txscript"
+	"github.com/p9c/pod/pkg/wire"
+)
+
+// Besides the transactions involving each address, the address index tracks the outputs paying each address, whether
+// they are spent, and the resulting balance, which back the insight style address queries of explorers. They are kept
+// in three buckets nested in the address index bucket, so dropping the index drops them as well.
+//
+// The deltas bucket has an entry for every output paying an address and for every input spending one of them. The
+// heights and positions in the keys are big endian so that the entries of an address are ordered by block height and
+// then by the position of the transaction in the block:
+//   <addr key><height><tx index><tx hash><index><spending>
+//   Field           Type      Size
+//   addr key        [21]byte  21 bytes
+//   height          uint32    4 bytes
+//   tx index        uint32    4 bytes
+//   tx hash         hash      32 bytes
+//   index           uint32    4 bytes (output index, or input index when spending)
+//   spending        uint8     1 byte
+// The value is the amount of the output as a uint64, which is subtracted from the balance when spending.
+//
+// The utxos bucket has an entry for every unspent output paying an address:
+//   <addr key><tx hash><output index> -> <amount><height><pk script>
+//
+// The balances bucket has the balance and the total amount ever received by each address:
+//   <addr key> -> <balance><received>
+//
+// The outputs of the genesis block can't be spent, so they are not counted.
+const (
+	// addrDeltaKeySize is the size of the key of an entry in the deltas bucket
+	addrDeltaKeySize = addrKeySize + 4 + 4 + chainhash.HashSize + 4 + 1
+	// addrUtxoKeySize is the size of the key of an entry in the utxos bucket
+	addrUtxoKeySize = addrKeySize + chainhash.HashSize + 4
+)
+
+var (
+	// addrDeltasBucketName is the name of the bucket of the changes of the balances of addresses
+	addrDeltasBucketName = []byte("deltas")
+	// addrUtxosBucketName is the name of the bucket of the unspent outputs of addresses
+	addrUtxosBucketName = []byte("utxos")
+	// addrBalancesBucketName is the name of the bucket of the balances of addresses
+	addrBalancesBucketName = []byte("balances")
+	// addrBalancesHeightKey is the key in the address index bucket of the height up to which the balances have been
+	// built from the blocks indexed before the address index tracked them. It only exists until they are caught up.
+	addrBalancesHeightKey = []byte("balancesheight")
+	// errBalancesMigrating is returned by the balance queries while the balances are being built for an address index
+	// that was created before it tracked them.
+	errBalancesMigrating = errors.New("the balances of the address index are still being built")
+	// keyOrder is the byte order of the numbers in the keys of the balance buckets, which sort in the same order as the
+	// numbers
+	keyOrder = binary.BigEndian
+)
+
+// AddrDelta is a change of the balance of an address, by an output paying it or by an input spending such an output.
+type AddrDelta struct {
+	// Height is the height of the block of the transaction
+	Height int32
+	// TxIndex is the position of the transaction in its block
+	TxIndex uint32
+	TxHash  chainhash.Hash
+	// Index is the index of the output, or of the input if Spending is set
+	Index    uint32
+	Spending bool
+	// Amount is the amount of the output, which is negative if Spending is set
+	Amount int64
+}
+
+// AddrUtxo is an unspent output paying an address.
+type AddrUtxo struct {
+	OutPoint wire.OutPoint
+	Amount   int64
+	Height   int32
+	PkScript []byte
+}
+
+// addrDeltaKey returns the key in the deltas bucket of a delta of the address
+func addrDeltaKey(addrKey [addrKeySize]byte, d *AddrDelta) []byte {
+	key := make([]byte, addrDeltaKeySize)
+	copy(key, addrKey[:])
+	offset := addrKeySize
+	keyOrder.PutUint32(key[offset:], uint32(d.Height))
+	keyOrder.PutUint32(key[offset+4:], d.TxIndex)
+	copy(key[offset+8:], d.TxHash[:])
+	offset += 8 + chainhash.HashSize
+	keyOrder.PutUint32(key[offset:], d.Index)
+	if d.Spending {
+		key[offset+4] = 1
+	}
+	return key
+}
+
+// deserializeAddrDelta decodes an entry of the deltas bucket
+func deserializeAddrDelta(key, value []byte) (d AddrDelta, e error) {
+	if len(key) != addrDeltaKeySize || len(value) != 8 {
+		return d, errDeserialize("address delta entry has the wrong size")
+	}
+	offset := addrKeySize
+	d.Height = int32(keyOrder.Uint32(key[offset:]))
+	d.TxIndex = keyOrder.Uint32(key[offset+4:])
+	copy(d.TxHash[:], key[offset+8:])
+	offset += 8 + chainhash.HashSize
+	d.Index = keyOrder.Uint32(key[offset:])
+	d.Spending = key[offset+4] != 0
+	d.Amount = int64(byteOrder.Uint64(value))
+	if d.Spending {
+		d.Amount = -d.Amount
+	}
+	return
+}
+
+// addrUtxoKey returns the key in the utxos bucket of an output paying the address
+func addrUtxoKey(addrKey [addrKeySize]byte, outpoint *wire.OutPoint) []byte {
+	key := make([]byte, addrUtxoKeySize)
+	copy(key, addrKey[:])
+	copy(key[addrKeySize:], outpoint.Hash[:])
+	keyOrder.PutUint32(key[addrKeySize+chainhash.HashSize:], outpoint.Index)
+	return key
+}
+
+// serializeAddrUtxo returns the value in the utxos bucket of an unspent output
+func serializeAddrUtxo(amount int64, height int32, pkScript []byte) []byte {
+	value := make([]byte, 12+len(pkScript))
+	byteOrder.PutUint64(value, uint64(amount))
+	byteOrder.PutUint32(value[8:], uint32(height))
+	copy(value[12:], pkScript)
+	return value
+}
+
+// deserializeAddrUtxo decodes an entry of the utxos bucket
+func deserializeAddrUtxo(key, value []byte) (u AddrUtxo, e error) {
+	if len(key) != addrUtxoKeySize || len(value) < 12 {
+		return u, errDeserialize("address utxo entry has the wrong size")
+	}
+	copy(u.OutPoint.Hash[:], key[addrKeySize:])
+	u.OutPoint.Index = keyOrder.Uint32(key[addrKeySize+chainhash.HashSize:])
+	u.Amount = int64(byteOrder.Uint64(value))
+	u.Height = int32(byteOrder.Uint32(value[8:]))
+	u.PkScript = append([]byte{}, value[12:]...)
+	return
+}
+
+// dbUpdateAddrBalance adds the amount to the balance of the address, and received to the total amount it received. The
+// entry is removed when both are zero again, which only happens when blocks are disconnected.
+func dbUpdateAddrBalance(bucket database.Bucket, addrKey [addrKeySize]byte, amount, received int64) (e error) {
+	var balance, total int64
+	if value := bucket.Get(addrKey[:]); len(value) == 16 {
+		balance = int64(byteOrder.Uint64(value))
+		total = int64(byteOrder.Uint64(value[8:]))
+	}
+	balance += amount
+	total += received
+	if balance == 0 && total == 0 {
+		return bucket.Delete(addrKey[:])
+	}
+	value := make([]byte, 16)
+	byteOrder.PutUint64(value, uint64(balance))
+	byteOrder.PutUint64(value[8:], uint64(total))
+	return bucket.Put(addrKey[:], value)
+}
+
+// addrBalanceBuckets are the buckets of the balances of the address index opened in a database transaction
+type addrBalanceBuckets struct {
+	deltas, utxos, balances database.Bucket
+}
+
+// openAddrBalanceBuckets returns the buckets of the balances in the address index bucket
+func openAddrBalanceBuckets(dbTx database.Tx) (b addrBalanceBuckets) {
+	idxBucket := dbTx.Metadata().Bucket(addrIndexKey)
+	return addrBalanceBuckets{
+		deltas:   idxBucket.Bucket(addrDeltasBucketName),
+		utxos:    idxBucket.Bucket(addrUtxosBucketName),
+		balances: idxBucket.Bucket(addrBalancesBucketName),
+	}
+}
+
+// dbCreateAddrBalanceBuckets creates the buckets of the balances in the address index bucket
+func dbCreateAddrBalanceBuckets(dbTx database.Tx) (e error) {
+	idxBucket := dbTx.Metadata().Bucket(addrIndexKey)
+	for _, name := range [][]byte{addrDeltasBucketName, addrUtxosBucketName, addrBalancesBucketName} {
+		if _, e = idxBucket.CreateBucketIfNotExists(name); E.Chk(e) {
+			return
+		}
+	}
+	return
+}
+
+// dbFetchAddrBalancesHeight returns the height up to which the balances have been built for an address index that was
+// created before it tracked them, and whether they are still being built.
+func dbFetchAddrBalancesHeight(dbTx database.Tx) (height int32, migrating bool) {
+	value := dbTx.Metadata().Bucket(addrIndexKey).Get(addrBalancesHeightKey)
+	if len(value) != 4 {
+		return 0, false
+	}
+	return int32(byteOrder.Uint32(value)), true
+}
+
+// dbPutAddrBalancesHeight stores the height up to which the balances have been built
+func dbPutAddrBalancesHeight(dbTx database.Tx, height int32) (e error) {
+	value := make([]byte, 4)
+	byteOrder.PutUint32(value, uint32(height))
+	return dbTx.Metadata().Bucket(addrIndexKey).Put(addrBalancesHeightKey, value)
+}
+
+// scriptAddrKeys returns the keys of the supported addresses a public key script pays, without duplicates.
+func (idx *AddrIndex) scriptAddrKeys(pkScript []byte) (keys [][addrKeySize]byte) {
+	_, addrs, _, e := txscript.ExtractPkScriptAddrs(pkScript, idx.chainParams)
+	if e != nil {
+		return
+	}
+next:
+	for _, addr := range addrs {
+		var addrKey [addrKeySize]byte
+		if addrKey, e = addrToKey(addr); e != nil {
+			// Ignore unsupported address types.
+			continue
+		}
+		for i := range keys {
+			if keys[i] == addrKey {
+				continue next
+			}
+		}
+		keys = append(keys, addrKey)
+	}
+	return
+}
+
+// connectBalances adds the outputs of the block to the unspent outputs and balances of the addresses they pay, removes
+// the outputs its inputs spend, and records the changes in the deltas bucket.
+func (idx *AddrIndex) connectBalances(dbTx database.Tx, blk *block.Block, stxos []blockchain.SpentTxOut) (e error) {
+	height := blk.Height()
+	if height == 0 {
+		return
+	}
+	b := openAddrBalanceBuckets(dbTx)
+	stxoIndex := 0
+	for txIdx, tx := range blk.Transactions() {
+		msgTx := tx.MsgTx()
+		// The coinbase is the first transaction and has no inputs that spend outputs.
+		if txIdx != 0 {
+			for inIdx, txIn := range msgTx.TxIn {
+				stxo := &stxos[stxoIndex]
+				stxoIndex++
+				d := AddrDelta{
+					Height: height, TxIndex: uint32(txIdx), TxHash: *tx.Hash(), Index: uint32(inIdx), Spending: true,
+				}
+				value := make([]byte, 8)
+				byteOrder.PutUint64(value, uint64(stxo.Amount))
+				for _, addrKey := range idx.scriptAddrKeys(stxo.PkScript) {
+					if e = b.deltas.Put(addrDeltaKey(addrKey, &d), value); E.Chk(e) {
+						return
+					}
+					if e = b.utxos.Delete(addrUtxoKey(addrKey, &txIn.PreviousOutPoint)); E.Chk(e) {
+						return
+					}
+					if e = dbUpdateAddrBalance(b.balances, addrKey, -stxo.Amount, 0); E.Chk(e) {
+						return
+					}
+				}
+			}
+		}
+		for outIdx, txOut := range msgTx.TxOut {
+			d := AddrDelta{Height: height, TxIndex: uint32(txIdx), TxHash: *tx.Hash(), Index: uint32(outIdx)}
+			value := make([]byte, 8)
+			byteOrder.PutUint64(value, uint64(txOut.Value))
+			outpoint := wire.OutPoint{Hash: *tx.Hash(), Index: uint32(outIdx)}
+			for _, addrKey := range idx.scriptAddrKeys(txOut.PkScript) {
+				if e = b.deltas.Put(addrDeltaKey(addrKey, &d), value); E.Chk(e) {
+					return
+				}
+				if e = b.utxos.Put(
+					addrUtxoKey(addrKey, &outpoint), serializeAddrUtxo(txOut.Value, height, txOut.PkScript),
+				); E.Chk(e) {
+					return
+				}
+				if e = dbUpdateAddrBalance(b.balances, addrKey, txOut.Value, txOut.Value); E.Chk(e) {
+					return
+				}
+			}
+		}
+	}
+	return
+}
+
+// disconnectBalances undoes connectBalances for the block, going through its transactions in reverse so that outputs
+// spent in the same block they were created in are restored before they are removed.
+func (idx *AddrIndex) disconnectBalances(dbTx database.Tx, blk *block.Block, stxos []blockchain.SpentTxOut) (e error) {
+	height := blk.Height()
+	if height == 0 {
+		return
+	}
+	b := openAddrBalanceBuckets(dbTx)
+	stxoIndex := len(stxos)
+	txs := blk.Transactions()
+	for txIdx := len(txs) - 1; txIdx >= 0; txIdx-- {
+		tx := txs[txIdx]
+		msgTx := tx.MsgTx()
+		for outIdx, txOut := range msgTx.TxOut {
+			d := AddrDelta{Height: height, TxIndex: uint32(txIdx), TxHash: *tx.Hash(), Index: uint32(outIdx)}
+			outpoint := wire.OutPoint{Hash: *tx.Hash(), Index: uint32(outIdx)}
+			for _, addrKey := range idx.scriptAddrKeys(txOut.PkScript) {
+				if e = b.deltas.Delete(addrDeltaKey(addrKey, &d)); E.Chk(e) {
+					return
+				}
+				if e = b.utxos.Delete(addrUtxoKey(addrKey, &outpoint)); E.Chk(e) {
+					return
+				}
+				if e = dbUpdateAddrBalance(b.balances, addrKey, -txOut.Value, -txOut.Value); E.Chk(e) {
+					return
+				}
+			}
+		}
+		if txIdx == 0 {
+			break
+		}
+		stxoIndex -= len(msgTx.TxIn)
+		for inIdx, txIn := range msgTx.TxIn {
+			stxo := &stxos[stxoIndex+inIdx]
+			d := AddrDelta{
+				Height: height, TxIndex: uint32(txIdx), TxHash: *tx.Hash(), Index: uint32(inIdx), Spending: true,
+			}
+			for _, addrKey := range idx.scriptAddrKeys(stxo.PkScript) {
+				if e = b.deltas.Delete(addrDeltaKey(addrKey, &d)); E.Chk(e) {
+					return
+				}
+				if e = b.utxos.Put(
+					addrUtxoKey(addrKey, &txIn.PreviousOutPoint),
+					serializeAddrUtxo(stxo.Amount, stxo.Height, stxo.PkScript),
+				); E.Chk(e) {
+					return
+				}
+				if e = dbUpdateAddrBalance(b.balances, addrKey, stxo.Amount, 0); E.Chk(e) {
+					return
+				}
+			}
+		}
+	}
+	return
+}
+
+// migrateBalances builds the balances of an address index that was created before it tracked them from the blocks it
+// has already indexed. The progress is stored after every block, so an interrupted migration resumes where it stopped.
+func (idx *AddrIndex) migrateBalances(chain *blockchain.BlockChain, interrupt <-chan struct{}) (e error) {
+	var height, tipHeight int32
+	var migrating bool
+	if e = idx.db.View(
+		func(dbTx database.Tx) (e error) {
+			height, migrating = dbFetchAddrBalancesHeight(dbTx)
+			_, tipHeight, e = dbFetchIndexerTip(dbTx, addrIndexKey)
+			return
+		},
+	); E.Chk(e) {
+		return
+	}
+	if !migrating {
+		return
+	}
+	I.F("building the balances of the %s from height %d to %d", addrIndexName, height+1, tipHeight)
+	progressLogger := newBlockProgressLogger("Built balances of")
+	for height++; height <= tipHeight; height++ {
+		var blk *block.Block
+		if blk, e = chain.BlockByHeight(height); E.Chk(e) {
+			return
+		}
+		var stxos []blockchain.SpentTxOut
+		if stxos, e = chain.FetchSpendJournal(blk); E.Chk(e) {
+			return
+		}
+		if e = idx.db.Update(
+			func(dbTx database.Tx) (e error) {
+				if e = idx.connectBalances(dbTx, blk, stxos); E.Chk(e) {
+					return
+				}
+				return dbPutAddrBalancesHeight(dbTx, height)
+			},
+		); E.Chk(e) {
+			return
+		}
+		progressLogger.LogBlockHeight(blk)
+		if interruptRequested(interrupt) {
+			return errInterruptRequested
+		}
+	}
+	if e = idx.db.Update(
+		func(dbTx database.Tx) (e error) {
+			return dbTx.Metadata().Bucket(addrIndexKey).Delete(addrBalancesHeightKey)
+		},
+	); E.Chk(e) {
+		return
+	}
+	I.Ln("the balances of the", addrIndexName, "are caught up to height", tipHeight)
+	return
+}
+
+// balancesView runs fn with the buckets of the balances in a read only database transaction, failing if they are still
+// being built.
+func (idx *AddrIndex) balancesView(addr btcaddr.Address, fn func(b addrBalanceBuckets, addrKey [addrKeySize]byte) error) (
+	e error,
+) {
+	var addrKey [addrKeySize]byte
+	if addrKey, e = addrToKey(addr); E.Chk(e) {
+		return
+	}
+	return idx.db.View(
+		func(dbTx database.Tx) (e error) {
+			if _, migrating := dbFetchAddrBalancesHeight(dbTx); migrating {
+				return errBalancesMigrating
+			}
+			return fn(openAddrBalanceBuckets(dbTx), addrKey)
+		},
+	)
+}
+
+// AddressBalance returns the balance of the address in the main chain and the total amount it has received. This
+// function is safe for concurrent access.
+func (idx *AddrIndex) AddressBalance(addr btcaddr.Address) (balance, received int64, e error) {
+	e = idx.balancesView(
+		addr, func(b addrBalanceBuckets, addrKey [addrKeySize]byte) (e error) {
+			if value := b.balances.Get(addrKey[:]); len(value) == 16 {
+				balance = int64(byteOrder.Uint64(value))
+				received = int64(byteOrder.Uint64(value[8:]))
+			}
+			return
+		},
+	)
+	return
+}
+
+// AddressUtxos returns the unspent outputs in the main chain paying the address, ordered by transaction hash and output
+// index. This function is safe for concurrent access.
+func (idx *AddrIndex) AddressUtxos(addr btcaddr.Address) (utxos []AddrUtxo, e error) {
+	e = idx.balancesView(
+		addr, func(b addrBalanceBuckets, addrKey [addrKeySize]byte) (e error) {
+			cursor := b.utxos.Cursor()
+			for ok := cursor.Seek(addrKey[:]); ok; ok = cursor.Next() {
+				key := cursor.Key()
+				if len(key) < addrKeySize || addrKeyOf(key) != addrKey {
+					break
+				}
+				var u AddrUtxo
+				if u, e = deserializeAddrUtxo(key, cursor.Value()); E.Chk(e) {
+					return
+				}
+				utxos = append(utxos, u)
+			}
+			return
+		},
+	)
+	return
+}
+
+// AddressDeltas returns the changes of the balance of the address in the main chain in the blocks from the start to the
+// end height, ordered by height and position in the block. An end of zero or less means there is no upper limit. This
+// function is safe for concurrent access.
+func (idx *AddrIndex) AddressDeltas(addr btcaddr.Address, start, end int32) (deltas []AddrDelta, e error) {
+	if start < 0 {
+		start = 0
+	}
+	e = idx.balancesView(
+		addr, func(b addrBalanceBuckets, addrKey [addrKeySize]byte) (e error) {
+			seek := make([]byte, addrKeySize+4)
+			copy(seek, addrKey[:])
+			keyOrder.PutUint32(seek[addrKeySize:], uint32(start))
+			cursor := b.deltas.Cursor()
+			for ok := cursor.Seek(seek); ok; ok = cursor.Next() {
+				key := cursor.Key()
+				if len(key) < addrKeySize || addrKeyOf(key) != addrKey {
+					break
+				}
+				var d AddrDelta
+				if d, e = deserializeAddrDelta(key, cursor.Value()); E.Chk(e) {
+					return
+				}
+				if end > 0 && d.Height > end {
+					break
+				}
+				deltas = append(deltas, d)
+			}
+			return
+		},
+	)
+	return
+}
+
+// addrKeyOf returns the address key a key of one of the balance buckets starts with
+func addrKeyOf(key []byte) (addrKey [addrKeySize]byte) {
+	copy(addrKey[:], key)
+	return
+}
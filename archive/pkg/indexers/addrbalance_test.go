@@ -0,0 +1,143 @@
+package indexers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/p9c/pod/pkg/block"
+	"github.com/p9c/pod/pkg/blockchain"
+	"github.com/p9c/pod/pkg/btcaddr"
+	"github.com/p9c/pod/pkg/chaincfg"
+	"github.com/p9c/pod/pkg/chainhash"
+	"github.com/p9c/pod/pkg/database"
+	_ "github.com/p9c/pod/pkg/database/ffldb"
+	"github.com/p9c/pod/pkg/txscript"
+	"github.com/p9c/pod/pkg/wire"
+)
+
+// TestAddrBalances connects two blocks to the balances of the address index, the second spending an output of the
+// first, checks the balances, unspent outputs and deltas of the addresses, and that disconnecting the blocks again
+// removes them.
+func TestAddrBalances(t *testing.T) {
+	params := &chaincfg.MainNetParams
+	dir, e := ioutil.TempDir("", "addrbalancetest")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+	db, e := database.Create("ffldb", filepath.Join(dir, "db"), params.Net)
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer db.Close()
+	idx := NewAddrIndex(db, params)
+	if e = db.Update(idx.Create); e != nil {
+		t.Fatal(e)
+	}
+	if e = idx.Init(); e != nil {
+		t.Fatal(e)
+	}
+	var addrs []btcaddr.Address
+	var scripts [][]byte
+	for i := byte(0); i < 2; i++ {
+		hash := make([]byte, 20)
+		hash[0] = i + 1
+		addr, e := btcaddr.NewPubKeyHash(hash, params)
+		if e != nil {
+			t.Fatal(e)
+		}
+		script, e := txscript.PayToAddrScript(addr)
+		if e != nil {
+			t.Fatal(e)
+		}
+		addrs, scripts = append(addrs, addr), append(scripts, script)
+	}
+	var zeroHash chainhash.Hash
+	coinbase := func(height byte, script []byte) *wire.MsgTx {
+		tx := wire.NewMsgTx(1)
+		tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&zeroHash, wire.MaxPrevOutIndex), []byte{height}, nil))
+		tx.AddTxOut(wire.NewTxOut(5000, script))
+		return tx
+	}
+	cb1 := coinbase(1, scripts[0])
+	blk1 := block.NewBlock(&wire.Block{Transactions: []*wire.MsgTx{cb1}})
+	blk1.SetHeight(1)
+	spend := wire.NewMsgTx(1)
+	spend.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&zeroHash, 0), nil, nil))
+	spend.TxIn[0].PreviousOutPoint.Hash = cb1.TxHash()
+	spend.AddTxOut(wire.NewTxOut(2000, scripts[0]))
+	spend.AddTxOut(wire.NewTxOut(3000, scripts[1]))
+	blk2 := block.NewBlock(&wire.Block{Transactions: []*wire.MsgTx{coinbase(2, scripts[1]), spend}})
+	blk2.SetHeight(2)
+	stxos2 := []blockchain.SpentTxOut{{Amount: 5000, PkScript: scripts[0], Height: 1, IsCoinBase: true}}
+	update := func(fn func(database.Tx) error) {
+		if e := db.Update(fn); e != nil {
+			t.Fatal(e)
+		}
+	}
+	check := func(addr btcaddr.Address, balance, received int64, utxos, deltas int) {
+		gotBalance, gotReceived, e := idx.AddressBalance(addr)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if gotBalance != balance || gotReceived != received {
+			t.Fatalf("balance of %v is %d of %d received, expected %d of %d", addr, gotBalance, gotReceived,
+				balance, received,
+			)
+		}
+		gotUtxos, e := idx.AddressUtxos(addr)
+		if e != nil {
+			t.Fatal(e)
+		}
+		var sum int64
+		for _, u := range gotUtxos {
+			sum += u.Amount
+		}
+		if len(gotUtxos) != utxos || sum != balance {
+			t.Fatalf("%v has %d utxos with a sum of %d, expected %d with %d", addr, len(gotUtxos), sum, utxos, balance)
+		}
+		gotDeltas, e := idx.AddressDeltas(addr, 0, 0)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if len(gotDeltas) != deltas {
+			t.Fatalf("%v has %d deltas, expected %d", addr, len(gotDeltas), deltas)
+		}
+	}
+	update(func(dbTx database.Tx) error { return idx.connectBalances(dbTx, blk1, nil) })
+	check(addrs[0], 5000, 5000, 1, 1)
+	update(func(dbTx database.Tx) error { return idx.connectBalances(dbTx, blk2, stxos2) })
+	check(addrs[0], 2000, 7000, 1, 3)
+	check(addrs[1], 8000, 8000, 2, 2)
+	// the deltas are ordered by height and then by the position in the block, and limited to the range of heights
+	deltas, e := idx.AddressDeltas(addrs[0], 2, 2)
+	if e != nil {
+		t.Fatal(e)
+	}
+	expected := []AddrDelta{
+		{Height: 2, TxIndex: 1, TxHash: spend.TxHash(), Index: 0, Amount: 2000},
+		{Height: 2, TxIndex: 1, TxHash: spend.TxHash(), Index: 0, Spending: true, Amount: -5000},
+	}
+	if !reflect.DeepEqual(deltas, expected) {
+		t.Fatalf("deltas are %+v, expected %+v", deltas, expected)
+	}
+	update(func(dbTx database.Tx) error { return idx.disconnectBalances(dbTx, blk2, stxos2) })
+	check(addrs[0], 5000, 5000, 1, 1)
+	check(addrs[1], 0, 0, 0, 0)
+	update(func(dbTx database.Tx) error { return idx.disconnectBalances(dbTx, blk1, nil) })
+	check(addrs[0], 0, 0, 0, 0)
+	update(
+		func(dbTx database.Tx) error {
+			b := openAddrBalanceBuckets(dbTx)
+			for _, bucket := range []database.Bucket{b.deltas, b.utxos, b.balances} {
+				if bucket.Cursor().First() {
+					t.Fatal("balance buckets are not empty after disconnecting all blocks")
+				}
+			}
+			return nil
+		},
+	)
+}
@@ -486,7 +486,8 @@ func addrToKey(addr btcaddr.Address) ([addrKeySize]byte, error) {
 // reference a given address because they are either crediting or debiting the address. The returned transactions are
 // ordered according to their order of appearance in the blockchain. In other words, first by block height and then by
 // offset inside the block. In addition, support is provided for a memory-only index of unconfirmed transactions such as
-// those which are kept in the memory pool before inclusion in a block.
+// those which are kept in the memory pool before inclusion in a block. The index also keeps the unspent outputs and the
+// balances of the addresses for the address queries of block explorers.
 type AddrIndex struct {
 	// The following fields are set when the instance is created and can't be changed afterwards, so there is no need to
 	// protect them with a separate mutex.
@@ -515,11 +516,22 @@ func (idx *AddrIndex) NeedsInputs() bool {
 	return true
 }
 
-// Init is only provided to satisfy the Indexer interface as there is nothing to initialize for this index. This is part
-// of the Indexer interface.
+// Init creates the buckets of the balances if the index was created before it tracked them, and marks them to be built
+// from the blocks already indexed, which the index manager does before catching up the index. This is part of the
+// Indexer interface.
 func (idx *AddrIndex) Init() (e error) {
-	// Nothing to do.
-	return nil
+	return idx.db.Update(
+		func(dbTx database.Tx) (e error) {
+			if dbTx.Metadata().Bucket(addrIndexKey).Bucket(addrBalancesBucketName) != nil {
+				return
+			}
+			I.Ln("the", addrIndexName, "doesn't have the balances of addresses yet, they will be built from its blocks")
+			if e = dbCreateAddrBalanceBuckets(dbTx); E.Chk(e) {
+				return
+			}
+			return dbPutAddrBalancesHeight(dbTx, 0)
+		},
+	)
 }
 
 // Key returns the database key to use for the index as a byte slice. This is part of the Indexer interface.
@@ -533,10 +545,12 @@ func (idx *AddrIndex) Name() string {
 }
 
 // Create is invoked when the indexer manager determines the index needs to be created for the first time. It creates
-// the bucket for the address index. This is part of the Indexer interface.
+// the bucket for the address index and the buckets of the balances inside it. This is part of the Indexer interface.
 func (idx *AddrIndex) Create(dbTx database.Tx) (e error) {
-	_, e = dbTx.Metadata().CreateBucket(addrIndexKey)
-	return e
+	if _, e = dbTx.Metadata().CreateBucket(addrIndexKey); E.Chk(e) {
+		return e
+	}
+	return dbCreateAddrBalanceBuckets(dbTx)
 }
 
 // writeIndexData represents the address index data to be written for one block. It consists of the address mapped to an
@@ -634,7 +648,11 @@ func (idx *AddrIndex) ConnectBlock(
 			}
 		}
 	}
-	return nil
+	// The balances of an index created before they were tracked are built up to its tip before it is caught up.
+	if _, migrating := dbFetchAddrBalancesHeight(dbTx); migrating {
+		return nil
+	}
+	return idx.connectBalances(dbTx, block, stxos)
 }
 
 // DisconnectBlock is invoked by the index manager when a block has been disconnected from the main chain. This indexer
@@ -654,6 +672,17 @@ func (idx *AddrIndex) DisconnectBlock(
 			return e
 		}
 	}
+	// While the balances are being built, only the blocks they have been built for are removed from them.
+	height, migrating := dbFetchAddrBalancesHeight(dbTx)
+	if migrating && block.Height() > height {
+		return nil
+	}
+	if e = idx.disconnectBalances(dbTx, block, stxos); E.Chk(e) {
+		return e
+	}
+	if migrating {
+		return dbPutAddrBalancesHeight(dbTx, block.Height()-1)
+	}
 	return nil
 }
 
@@ -215,6 +215,27 @@ func (m *Manager) Init(chain *blockchain.BlockChain, interrupt <-chan struct{})
 	if interruptRequested(interrupt) {
 		return errInterruptRequested
 	}
+	// The transaction and address indexes refer to the data of every block, which a pruned chain no longer has.
+	if chain.Pruned() {
+		for _, indexer := range m.enabledIndexes {
+			switch indexer.(type) {
+			case *TxIndex, *AddrIndex:
+				return fmt.Errorf("the %s can't be used with a pruned block chain", indexer.Name())
+			}
+		}
+	}
+	// Neither can they be built before the blocks below a utxo snapshot the chain was imported from are downloaded.
+	if chain.HistoryMissing() {
+		for _, indexer := range m.enabledIndexes {
+			switch indexer.(type) {
+			case *TxIndex, *AddrIndex:
+				return fmt.Errorf(
+					"the %s can't be built until the blocks below the utxo snapshot have been downloaded",
+					indexer.Name(),
+				)
+			}
+		}
+	}
 	// Finish and drops that were previously interrupted.
 	if e = m.maybeFinishDrops(interrupt); E.Chk(e) {
 		return e
@@ -325,6 +346,15 @@ func (m *Manager) Init(chain *blockchain.BlockChain, interrupt <-chan struct{})
 			)
 		}
 	}
+	// Build the balances of an address index created before it tracked them up to its tip, so catching it up below
+	// updates them along with the rest of the index.
+	for _, indexer := range m.enabledIndexes {
+		if addrIndex, ok := indexer.(*AddrIndex); ok {
+			if e = addrIndex.migrateBalances(chain, interrupt); E.Chk(e) {
+				return e
+			}
+		}
+	}
 	// Fetch the current tip heights for each index along with tracking the lowest one so the catchup code only needs to
 	// start at the earliest block and is able to skip connecting the block for the indexes that don't need it.
 	bestHeight := chain.BestSnapshot().Height
@@ -0,0 +1,123 @@
+package mempool
+
+import (
+	"fmt"
+
+	"github.com/p9c/pod/pkg/block"
+	"github.com/p9c/pod/pkg/blockchain"
+	"github.com/p9c/pod/pkg/chainhash"
+	"github.com/p9c/pod/pkg/wire"
+)
+
+// CompactBlock is a block announced with a cmpctblock message (BIP0152) that is being rebuilt from the transactions in
+// the pool and those requested from the peer that announced it.
+type CompactBlock struct {
+	header  wire.BlockHeader
+	txs     []*wire.MsgTx
+	missing []uint32
+}
+
+// ReconstructBlock starts rebuilding a block announced with a cmpctblock message from its prefilled transactions and
+// those in the pool whose short IDs match.
+//
+// The indexes of the transactions that are still missing are returned by Missing so they can be requested with a
+// getblocktxn message. This includes those whose short ID matches more than one transaction in the pool, as there is no
+// telling which one is in the block. An error is returned when the message can not describe a valid block, such as when
+// two of its short IDs are the same, in which case the block should be requested in full.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) ReconstructBlock(msg *wire.MsgCmpctBlock) (cb *CompactBlock, e error) {
+	count := msg.TxCount()
+	if count == 0 {
+		return nil, fmt.Errorf("compact block %v has no transactions", msg.BlockHash())
+	}
+	cb = &CompactBlock{header: msg.Header, txs: make([]*wire.MsgTx, count)}
+	for _, ptx := range msg.PrefilledTxs {
+		if int(ptx.Index) >= count || cb.txs[ptx.Index] != nil || ptx.Tx == nil {
+			return nil, fmt.Errorf("compact block %v has an invalid prefilled transaction at %d",
+				msg.BlockHash(), ptx.Index,
+			)
+		}
+		cb.txs[ptx.Index] = ptx.Tx
+	}
+	// The short IDs stand for the transactions that are not prefilled, in order.
+	slots := make(map[uint64]int, len(msg.ShortIDs))
+	index := 0
+	for _, id := range msg.ShortIDs {
+		for cb.txs[index] != nil {
+			index++
+		}
+		if _, ok := slots[id]; ok {
+			return nil, fmt.Errorf("compact block %v has duplicate short ID %x", msg.BlockHash(), id)
+		}
+		slots[id] = index
+		index++
+	}
+	key := msg.ShortIDKey()
+	collided := make(map[int]struct{})
+	mp.mtx.RLock()
+	for hash, desc := range mp.pool {
+		i, ok := slots[wire.ShortID(&key, &hash)]
+		if !ok {
+			continue
+		}
+		if _, ok = collided[i]; ok {
+			continue
+		}
+		if cb.txs[i] != nil {
+			cb.txs[i] = nil
+			collided[i] = struct{}{}
+			continue
+		}
+		cb.txs[i] = desc.Tx.MsgTx()
+	}
+	mp.mtx.RUnlock()
+	for i, tx := range cb.txs {
+		if tx == nil {
+			cb.missing = append(cb.missing, uint32(i))
+		}
+	}
+	return
+}
+
+// BlockHash returns the hash of the block being rebuilt.
+func (cb *CompactBlock) BlockHash() chainhash.Hash {
+	return cb.header.BlockHash()
+}
+
+// Missing returns the indexes of the transactions of the block that are not known yet, in ascending order.
+func (cb *CompactBlock) Missing() []uint32 {
+	return cb.missing
+}
+
+// Fill adds the transactions of a blocktxn message sent in answer to a getblocktxn message for the missing
+// transactions.
+func (cb *CompactBlock) Fill(msg *wire.MsgBlockTxn) (e error) {
+	if len(msg.Transactions) != len(cb.missing) {
+		return fmt.Errorf("blocktxn for block %v has %d transactions, %d are missing",
+			msg.BlockHash, len(msg.Transactions), len(cb.missing),
+		)
+	}
+	for i, index := range cb.missing {
+		cb.txs[index] = msg.Transactions[i]
+	}
+	cb.missing = nil
+	return
+}
+
+// Block returns the rebuilt block once no transactions are missing. An error is returned when the transactions do not
+// match the merkle root of the header, which happens when a short ID matched the wrong transaction in the pool, in which
+// case the block should be requested in full.
+func (cb *CompactBlock) Block() (blk *block.Block, e error) {
+	if len(cb.missing) > 0 {
+		return nil, fmt.Errorf("block %v is missing %d transactions", cb.header.BlockHash(), len(cb.missing))
+	}
+	blk = block.NewBlock(&wire.Block{Header: cb.header, Transactions: cb.txs})
+	merkles := blockchain.BuildMerkleTreeStore(blk.Transactions(), false)
+	if root := merkles.GetRoot(); !root.IsEqual(&cb.header.MerkleRoot) {
+		return nil, fmt.Errorf("rebuilt block %v has merkle root %v, the header has %v",
+			blk.Hash(), root, cb.header.MerkleRoot,
+		)
+	}
+	return
+}
@@ -0,0 +1,78 @@
+package mempool
+
+import (
+	"testing"
+
+	"github.com/p9c/pod/pkg/blockchain"
+	"github.com/p9c/pod/pkg/chaincfg"
+	"github.com/p9c/pod/pkg/util"
+	"github.com/p9c/pod/pkg/wire"
+)
+
+// TestReconstructBlock ensures a block announced with a cmpctblock message is rebuilt from the prefilled coinbase, the
+// transactions in the pool and those delivered in a blocktxn message, and that a compact block which does not match its
+// transactions is refused.
+func TestReconstructBlock(t *testing.T) {
+	t.Parallel()
+	harness, outputs, e := newPoolHarness(&chaincfg.MainNetParams)
+	if e != nil {
+		t.Fatalf("unable to create test pool: %v", e)
+	}
+	chainedTxns, e := harness.CreateTxChain(outputs[0], 4)
+	if e != nil {
+		t.Fatalf("unable to create transaction chain: %v", e)
+	}
+	// All but the last transaction of the chain are in the pool.
+	for _, tx := range chainedTxns[:3] {
+		if _, e = harness.txPool.ProcessTransaction(nil, tx, false, false, 0); e != nil {
+			t.Fatalf("ProcessTransaction: failed to accept tx: %v", e)
+		}
+	}
+	coinbase, e := harness.CreateCoinbaseTx(harness.chain.BestHeight()+1, 1, 1)
+	if e != nil {
+		t.Fatalf("unable to create coinbase: %v", e)
+	}
+	txs := append([]*util.Tx{coinbase}, chainedTxns...)
+	header := wire.BlockHeader{MerkleRoot: *blockchain.BuildMerkleTreeStore(txs, false).GetRoot()}
+	msgBlock := wire.NewMsgBlock(&header)
+	for _, tx := range txs {
+		_ = msgBlock.AddTransaction(tx.MsgTx())
+	}
+	msg := wire.NewMsgCmpctBlock(msgBlock, 1)
+	cb, e := harness.txPool.ReconstructBlock(msg)
+	if e != nil {
+		t.Fatalf("ReconstructBlock: %v", e)
+	}
+	if missing := cb.Missing(); len(missing) != 1 || missing[0] != 4 {
+		t.Fatalf("ReconstructBlock: wrong missing transactions - got %v, want [4]", missing)
+	}
+	if _, e = cb.Block(); e == nil {
+		t.Fatal("Block: returned a block with missing transactions")
+	}
+	// A wrong transaction in the blocktxn message gives a block that does not match the header.
+	if e = cb.Fill(wire.NewMsgBlockTxn(&header.PrevBlock, []*wire.MsgTx{coinbase.MsgTx()})); e != nil {
+		t.Fatalf("Fill: %v", e)
+	}
+	if _, e = cb.Block(); e == nil {
+		t.Fatal("Block: returned a block that does not match its merkle root")
+	}
+	cb, _ = harness.txPool.ReconstructBlock(msg)
+	if e = cb.Fill(wire.NewMsgBlockTxn(&header.PrevBlock, nil)); e == nil {
+		t.Fatal("Fill: accepted a blocktxn without the missing transactions")
+	}
+	if e = cb.Fill(wire.NewMsgBlockTxn(&header.PrevBlock, []*wire.MsgTx{chainedTxns[3].MsgTx()})); e != nil {
+		t.Fatalf("Fill: %v", e)
+	}
+	blk, e := cb.Block()
+	if e != nil {
+		t.Fatalf("Block: %v", e)
+	}
+	if *blk.Hash() != msgBlock.BlockHash() {
+		t.Fatalf("Block: wrong block - got %v, want %v", blk.Hash(), msgBlock.BlockHash())
+	}
+	// Duplicate short IDs can not describe a valid block.
+	msg.ShortIDs[1] = msg.ShortIDs[0]
+	if _, e = harness.txPool.ReconstructBlock(msg); e == nil {
+		t.Fatal("ReconstructBlock: accepted a compact block with duplicate short IDs")
+	}
+}
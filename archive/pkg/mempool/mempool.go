@@ -340,6 +340,94 @@ func (mp *TxPool) RawMempoolVerbose() map[string]*btcjson.GetRawMempoolVerboseRe
 	return result
 }
 
+// MempoolEntry returns the entry for the transaction with the given hash as a fully populated json result including
+// the counts, sizes and fees of its in-pool ancestors and descendants. The counts and totals include the transaction
+// itself. This function is safe for concurrent access.
+func (mp *TxPool) MempoolEntry(txHash *chainhash.Hash) (*btcjson.GetMempoolEntryResult, error) {
+	mp.mtx.RLock()
+	defer mp.mtx.RUnlock()
+	desc, exists := mp.pool[*txHash]
+	if !exists {
+		return nil, fmt.Errorf("transaction is not in the pool")
+	}
+	tx := desc.Tx
+	var currentPriority float64
+	utxos, e := mp.fetchInputUtxos(tx)
+	if e == nil {
+		currentPriority = mining.CalcPriority(tx.MsgTx(), utxos, mp.cfg.BestHeight()+1)
+	}
+	size := GetTxVirtualSize(tx)
+	entry := &btcjson.GetMempoolEntryResult{
+		Size:             int32(size),
+		Fee:              amt.Amount(desc.Fee).ToDUO(),
+		ModifiedFee:      amt.Amount(desc.Fee).ToDUO(),
+		Time:             desc.Added.Unix(),
+		Height:           int64(desc.Height),
+		StartingPriority: desc.StartingPriority,
+		CurrentPriority:  currentPriority,
+		Depends:          make([]string, 0),
+	}
+	for _, txIn := range tx.MsgTx().TxIn {
+		hash := &txIn.PreviousOutPoint.Hash
+		if mp.haveTransaction(hash) {
+			entry.Depends = append(entry.Depends, hash.String())
+		}
+	}
+	ancestors := mp.ancestors(tx, map[chainhash.Hash]*TxDesc{})
+	descendants := mp.descendants(tx, map[chainhash.Hash]*TxDesc{})
+	var ancestorSize, descendantSize, ancestorFees, descendantFees int64
+	for _, d := range ancestors {
+		ancestorSize += GetTxVirtualSize(d.Tx)
+		ancestorFees += d.Fee
+	}
+	for _, d := range descendants {
+		descendantSize += GetTxVirtualSize(d.Tx)
+		descendantFees += d.Fee
+	}
+	entry.AncestorCount = int64(len(ancestors)) + 1
+	entry.AncestorSize = ancestorSize + size
+	entry.AncestorFees = amt.Amount(ancestorFees + desc.Fee).ToDUO()
+	entry.DescendantCount = int64(len(descendants)) + 1
+	entry.DescendantSize = descendantSize + size
+	entry.DescendantFees = amt.Amount(descendantFees + desc.Fee).ToDUO()
+	return entry, nil
+}
+
+// ancestors adds to found every transaction in the pool that the passed transaction depends on directly or indirectly
+// and returns it. This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) ancestors(tx *util.Tx, found map[chainhash.Hash]*TxDesc) map[chainhash.Hash]*TxDesc {
+	for _, txIn := range tx.MsgTx().TxIn {
+		hash := txIn.PreviousOutPoint.Hash
+		if _, ok := found[hash]; ok {
+			continue
+		}
+		if parent, ok := mp.pool[hash]; ok {
+			found[hash] = parent
+			mp.ancestors(parent.Tx, found)
+		}
+	}
+	return found
+}
+
+// descendants adds to found every transaction in the pool that spends an output of the passed transaction directly or
+// indirectly and returns it. This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) descendants(tx *util.Tx, found map[chainhash.Hash]*TxDesc) map[chainhash.Hash]*TxDesc {
+	for i := range tx.MsgTx().TxOut {
+		spender, ok := mp.outpoints[wire.OutPoint{Hash: *tx.Hash(), Index: uint32(i)}]
+		if !ok {
+			continue
+		}
+		if _, ok = found[*spender.Hash()]; ok {
+			continue
+		}
+		if child, ok := mp.pool[*spender.Hash()]; ok {
+			found[*spender.Hash()] = child
+			mp.descendants(child.Tx, found)
+		}
+	}
+	return found
+}
+
 // RemoveDoubleSpends removes all transactions which spend outputs spent by the passed transaction from the memory pool.
 // Removing those transactions then leads to removing all transactions which rely on them, recursively. This is
 // necessary when a block is connected to the main chain because the block may contain transactions which were
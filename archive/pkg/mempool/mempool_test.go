@@ -835,3 +835,42 @@ func TestCheckSpend(t *testing.T) {
 		t.Fatalf("Unexpeced spend found in pool: %v", spend)
 	}
 }
+
+// TestMempoolEntry ensures the entry of a transaction in the middle of a chain counts its in-pool ancestors and
+// descendants including itself, and depends on its parent only.
+func TestMempoolEntry(t *testing.T) {
+	t.Parallel()
+	harness, spendableOuts, e := newPoolHarness(&chaincfg.MainNetParams)
+	if e != nil {
+		t.Fatalf("unable to create test pool: %v", e)
+	}
+	chainedTxns, e := harness.CreateTxChain(spendableOuts[0], 3)
+	if e != nil {
+		t.Fatalf("unable to create transaction chain: %v", e)
+	}
+	for _, tx := range chainedTxns {
+		if _, e = harness.txPool.ProcessTransaction(nil, tx, false, false, 0); e != nil {
+			t.Fatalf("ProcessTransaction: failed to accept valid transaction %v", e)
+		}
+	}
+	if _, e = harness.txPool.MempoolEntry(&chainhash.Hash{}); e == nil {
+		t.Fatal("MempoolEntry: returned an entry for a transaction not in the pool")
+	}
+	entry, e := harness.txPool.MempoolEntry(chainedTxns[1].Hash())
+	if e != nil {
+		t.Fatalf("MempoolEntry: %v", e)
+	}
+	if entry.AncestorCount != 2 || entry.DescendantCount != 2 {
+		t.Fatalf(
+			"MempoolEntry: got %d ancestors and %d descendants, want 2 and 2",
+			entry.AncestorCount, entry.DescendantCount,
+		)
+	}
+	size := GetTxVirtualSize(chainedTxns[1])
+	if int64(entry.Size) != size || entry.AncestorSize != size+GetTxVirtualSize(chainedTxns[0]) {
+		t.Fatalf("MempoolEntry: got size %d and ancestor size %d", entry.Size, entry.AncestorSize)
+	}
+	if len(entry.Depends) != 1 || entry.Depends[0] != chainedTxns[0].Hash().String() {
+		t.Fatalf("MempoolEntry: got depends %v, want %v", entry.Depends, chainedTxns[0].Hash())
+	}
+}
@@ -239,41 +239,33 @@ func standardCoinbaseScript(nextBlockHeight int32, extraNonce uint64) ([]byte, e
 		Script()
 }
 
+// isHardForkDisbursement returns whether the block at the given height pays the special hard fork disbursement instead
+// of the normal block subsidy.
+func isHardForkDisbursement(params *chaincfg.Params, nextBlockHeight int32) bool {
+	return nextBlockHeight == fork.List[1].ActivationHeight &&
+		params.Net == wire.MainNet ||
+		nextBlockHeight == fork.List[1].TestnetStart &&
+			params.Net == wire.TestNet3
+}
+
 // createCoinbaseTx returns a coinbase transaction paying an appropriate subsidy
-// based on the passed block height to the provided address. When the address is
-// nil, the coinbase transaction will instead be redeemable by anyone. See the
-// comment for NewBlockTemplate for more information about why the nil address
-// handling is useful.
+// based on the passed block height to the provided payouts, split in proportion
+// to their weights. A payout with a nil address, or an empty list of payouts,
+// creates an output that is redeemable by anyone. See the comment for
+// NewBlockTemplate for more information about why the nil address handling is
+// useful.
 func createCoinbaseTx(
 	params *chaincfg.Params, coinbaseScript []byte, nextBlockHeight int32,
-	addr btcaddr.Address, version int32,
+	payouts []Payout, version int32,
 ) (*util.Tx, error) {
 	// if this is the hard fork activation height coming up, we create the special
 	// disbursement coinbase
-	if nextBlockHeight == fork.List[1].ActivationHeight &&
-		params.Net == wire.MainNet ||
-		nextBlockHeight == fork.List[1].TestnetStart &&
-			params.Net == wire.TestNet3 {
-		return blockchain.CreateHardForkSubsidyTx(params, coinbaseScript, nextBlockHeight, addr, version)
-	}
-	
-	// Create the script to pay to the provided payment address if one was
-	// specified. Otherwise create a script that allows the coinbase to be
-	// redeemable by anyone.
-	var pkScript []byte
-	if addr != nil {
-		var e error
-		pkScript, e = txscript.PayToAddrScript(addr)
-		if e != nil {
-			return nil, e
-		}
-	} else {
-		var e error
-		scriptBuilder := txscript.NewScriptBuilder()
-		pkScript, e = scriptBuilder.AddOp(txscript.OP_TRUE).Script()
-		if e != nil {
-			return nil, e
+	if isHardForkDisbursement(params, nextBlockHeight) {
+		var addr btcaddr.Address
+		if len(payouts) > 0 {
+			addr = payouts[0].Address
 		}
+		return blockchain.CreateHardForkSubsidyTx(params, coinbaseScript, nextBlockHeight, addr, version)
 	}
 	tx := wire.NewMsgTx(wire.TxVersion)
 	tx.AddTxIn(
@@ -288,12 +280,30 @@ func createCoinbaseTx(
 			Sequence:        wire.MaxTxInSequenceNum,
 		},
 	)
-	tx.AddTxOut(
-		&wire.TxOut{
-			Value:    blockchain.CalcBlockSubsidy(nextBlockHeight, params, version),
-			PkScript: pkScript,
-		},
-	)
+	values := splitValue(blockchain.CalcBlockSubsidy(nextBlockHeight, params, version), payouts)
+	for i := range values {
+		// Create the script to pay to the provided payment address if one was
+		// specified. Otherwise create a script that allows the coinbase to be
+		// redeemable by anyone.
+		var pkScript []byte
+		var e error
+		if i < len(payouts) && payouts[i].Address != nil {
+			if pkScript, e = txscript.PayToAddrScript(payouts[i].Address); E.Chk(e) {
+				return nil, e
+			}
+		} else {
+			scriptBuilder := txscript.NewScriptBuilder()
+			if pkScript, e = scriptBuilder.AddOp(txscript.OP_TRUE).Script(); E.Chk(e) {
+				return nil, e
+			}
+		}
+		tx.AddTxOut(
+			&wire.TxOut{
+				Value:    values[i],
+				PkScript: pkScript,
+			},
+		)
+	}
 	return util.NewTx(tx), nil
 }
 
@@ -435,6 +445,18 @@ func NewBlkTmplGenerator(
 //  |  <= policy.BlockMinSize)          |   |
 //   -----------------------------------  --
 func (g *BlkTmplGenerator) NewBlockTemplate(payToAddress btcaddr.Address, algo string,) (*BlockTemplate, error) {
+	var payouts []Payout
+	if payToAddress != nil {
+		payouts = []Payout{{Address: payToAddress, Weight: 1}}
+	}
+	return g.NewBlockTemplateWithPayouts(payouts, algo)
+}
+
+// NewBlockTemplateWithPayouts returns a new block template in the same way as
+// NewBlockTemplate, with a coinbase that splits the block subsidy and the fees
+// of the selected transactions between the given payouts in proportion to their
+// weights. The coinbase is redeemable by anyone if there are no payouts.
+func (g *BlkTmplGenerator) NewBlockTemplateWithPayouts(payouts []Payout, algo string) (*BlockTemplate, error) {
 	T.Ln("NewBlockTemplate", algo)
 	if algo == "" {
 		algo = "random"
@@ -462,7 +484,7 @@ func (g *BlkTmplGenerator) NewBlockTemplate(payToAddress btcaddr.Address, algo s
 	}
 	var coinbaseTx *util.Tx
 	if coinbaseTx, e = createCoinbaseTx(
-		g.ChainParams, coinbaseScript, nextBlockHeight, payToAddress,
+		g.ChainParams, coinbaseScript, nextBlockHeight, payouts,
 		vers,
 	); E.Chk(e) {
 		return nil, e
@@ -793,7 +815,14 @@ mempoolLoop:
 	// accordingly.
 	blockWeight -= wire.MaxVarIntPayload -
 		(uint32(wire.VarIntSerializeSize(uint64(len(blockTxns)))))
-	coinbaseTx.MsgTx().TxOut[0].Value += totalFees
+	if isHardForkDisbursement(g.ChainParams, nextBlockHeight) {
+		coinbaseTx.MsgTx().TxOut[0].Value += totalFees
+	} else {
+		fees := splitValue(totalFees, payouts)
+		for i := range fees {
+			coinbaseTx.MsgTx().TxOut[i].Value += fees[i]
+		}
+	}
 	txFees[0] = -totalFees
 	// If segwit is active and we included transactions with witness data, then
 	// // we'll need to include a commitment to the witness data in an OP_RETURN output
@@ -907,7 +936,7 @@ mempoolLoop:
 		Fees:            txFees,
 		SigOpCosts:      txSigOpCosts,
 		Height:          nextBlockHeight,
-		ValidPayAddress: payToAddress != nil,
+		ValidPayAddress: len(payouts) > 0 && payouts[0].Address != nil,
 	}, nil
 }
 
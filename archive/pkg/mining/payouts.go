@@ -0,0 +1,96 @@
+package mining
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/p9c/pod/pkg/btcaddr"
+	"github.com/p9c/pod/pkg/chaincfg"
+)
+
+// Payout is one of the outputs of a coinbase that splits the block reward between several addresses. The reward is
+// divided in proportion to the weights of the payouts.
+type Payout struct {
+	Address btcaddr.Address
+	Weight  float64
+}
+
+// splitValue divides value between the payouts in proportion to their weights. Any amount left over from rounding
+// down goes to the first payout. With no payouts, or no positive weights, the whole value is in a single part.
+func splitValue(value int64, payouts []Payout) (parts []int64) {
+	var total float64
+	for i := range payouts {
+		if payouts[i].Weight > 0 {
+			total += payouts[i].Weight
+		}
+	}
+	if len(payouts) < 1 || total <= 0 {
+		parts = make([]int64, 1, len(payouts)+1)
+		parts[0] = value
+		for i := 1; i < len(payouts); i++ {
+			parts = append(parts, 0)
+		}
+		return
+	}
+	parts = make([]int64, len(payouts))
+	remainder := value
+	for i := range payouts {
+		if payouts[i].Weight > 0 {
+			parts[i] = int64(float64(value) * payouts[i].Weight / total)
+			remainder -= parts[i]
+		}
+	}
+	parts[0] += remainder
+	return
+}
+
+// NewPayout checks an address and weight for the given network and returns them as a Payout.
+func NewPayout(address string, weight float64, params *chaincfg.Params) (p Payout, e error) {
+	if weight <= 0 || math.IsInf(weight, 0) || math.IsNaN(weight) {
+		return p, fmt.Errorf("weight of payout address %s must be a positive number, got %v", address, weight)
+	}
+	var addr btcaddr.Address
+	if addr, e = btcaddr.Decode(address, params); e != nil {
+		return p, fmt.Errorf("invalid payout address %s: %v", address, e)
+	}
+	if !addr.IsForNet(params) {
+		return p, fmt.Errorf("payout address %s is not for the %s network", address, params.Name)
+	}
+	return Payout{Address: addr, Weight: weight}, nil
+}
+
+// ParsePayouts parses payout specifications in the form address:weight, where the weight may be left out to give the
+// address a weight of 1.
+func ParsePayouts(specs []string, params *chaincfg.Params) (payouts []Payout, e error) {
+	for _, spec := range specs {
+		address, weight := spec, 1.0
+		if i := strings.LastIndex(spec, ":"); i >= 0 {
+			address = spec[:i]
+			if weight, e = strconv.ParseFloat(spec[i+1:], 64); e != nil {
+				return nil, fmt.Errorf("invalid weight in payout %s: %v", spec, e)
+			}
+		}
+		var p Payout
+		if p, e = NewPayout(address, weight, params); e != nil {
+			return nil, e
+		}
+		payouts = append(payouts, p)
+	}
+	return
+}
+
+// SamePayouts returns whether two lists of payouts pay the same addresses with the same weights in the same order.
+func SamePayouts(a, b []Payout) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Weight != b[i].Weight || (a[i].Address == nil) != (b[i].Address == nil) ||
+			a[i].Address != nil && a[i].Address.EncodeAddress() != b[i].Address.EncodeAddress() {
+			return false
+		}
+	}
+	return true
+}
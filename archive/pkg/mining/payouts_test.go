@@ -0,0 +1,75 @@
+package mining
+
+import (
+	"testing"
+
+	"github.com/p9c/pod/pkg/btcaddr"
+	"github.com/p9c/pod/pkg/chaincfg"
+)
+
+// TestSplitValue checks that values are split in proportion to the weights and that nothing is lost to rounding.
+func TestSplitValue(t *testing.T) {
+	tests := []struct {
+		value   int64
+		weights []float64
+		want    []int64
+	}{
+		{100, nil, []int64{100}},
+		{100, []float64{1}, []int64{100}},
+		{100, []float64{3, 1}, []int64{75, 25}},
+		{100, []float64{1, 1, 1}, []int64{34, 33, 33}},
+		{100, []float64{0, 1}, []int64{0, 100}},
+		{100, []float64{0, 0}, []int64{100, 0}},
+		{7, []float64{0.5, 0.25, 0.25}, []int64{5, 1, 1}},
+	}
+	for _, test := range tests {
+		payouts := make([]Payout, len(test.weights))
+		for i := range test.weights {
+			payouts[i].Weight = test.weights[i]
+		}
+		got := splitValue(test.value, payouts)
+		if len(got) != len(test.want) {
+			t.Fatalf("split of %d by %v is %v, want %v", test.value, test.weights, got, test.want)
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Fatalf("split of %d by %v is %v, want %v", test.value, test.weights, got, test.want)
+			}
+		}
+	}
+}
+
+// TestParsePayouts checks the parsing of address:weight payout specifications.
+func TestParsePayouts(t *testing.T) {
+	params := &chaincfg.MainNetParams
+	var addrs []string
+	for i := 0; i < 2; i++ {
+		addr, e := btcaddr.NewPubKeyHash(make([]byte, 20), params)
+		if e != nil {
+			t.Fatal(e)
+		}
+		addrs = append(addrs, addr.EncodeAddress())
+	}
+	payouts, e := ParsePayouts([]string{addrs[0] + ":2.5", addrs[1]}, params)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if len(payouts) != 2 || payouts[0].Weight != 2.5 || payouts[1].Weight != 1 ||
+		payouts[0].Address.EncodeAddress() != addrs[0] {
+		t.Fatalf("unexpected payouts %v", payouts)
+	}
+	if !SamePayouts(payouts, payouts) {
+		t.Fatal("payouts are not the same as themselves")
+	}
+	if SamePayouts(payouts, payouts[:1]) {
+		t.Fatal("payouts of different lengths are the same")
+	}
+	for _, spec := range []string{addrs[0] + ":0", addrs[0] + ":-1", addrs[0] + ":x", "notanaddress:1"} {
+		if _, e = ParsePayouts([]string{spec}, params); e == nil {
+			t.Errorf("invalid payout %s was accepted", spec)
+		}
+	}
+	if _, e = ParsePayouts([]string{addrs[0]}, &chaincfg.TestNet3Params); e == nil {
+		t.Error("payout address for another network was accepted")
+	}
+}
@@ -0,0 +1,125 @@
+// Package muhash implements MuHash3072, a hash of a set of byte strings that can be updated by adding and removing
+// elements in any order, so that a hash of a large set such as the utxo set can be maintained without hashing the whole
+// set again after every change.
+//
+// Each element is hashed to a number modulo the prime 2^3072 - 1103717, and the set is represented by the product of
+// the numbers of its elements. As removing an element requires the modular inverse of its number, which is slow to
+// compute, removed elements are multiplied into a separate denominator that is only divided out when the hash is
+// computed. The hash is the same as the one of the MuHash3072 class of bitcoind.
+package muhash
+
+import (
+	"crypto/sha256"
+	"errors"
+	"math/big"
+
+	"golang.org/x/crypto/chacha20"
+)
+
+const (
+	// NumSize is the size in bytes of the numbers a set is represented by
+	NumSize = 384
+	// SerializedSize is the size of a serialized MuHash
+	SerializedSize = NumSize * 2
+)
+
+// prime is the modulus, 2^3072 - 1103717
+var prime = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), NumSize*8), big.NewInt(1103717))
+
+// MuHash is the state of the hash of a set
+type MuHash struct {
+	numerator   *big.Int
+	denominator *big.Int
+}
+
+// New returns the hash state of an empty set
+func New() *MuHash {
+	return &MuHash{numerator: big.NewInt(1), denominator: big.NewInt(1)}
+}
+
+// Add adds an element to the set
+func (m *MuHash) Add(data []byte) {
+	m.numerator.Mod(m.numerator.Mul(m.numerator, toNum(data)), prime)
+}
+
+// Remove removes an element from the set
+func (m *MuHash) Remove(data []byte) {
+	m.denominator.Mod(m.denominator.Mul(m.denominator, toNum(data)), prime)
+}
+
+// Combine adds the elements of another set to the set
+func (m *MuHash) Combine(o *MuHash) {
+	m.numerator.Mod(m.numerator.Mul(m.numerator, o.numerator), prime)
+	m.denominator.Mod(m.denominator.Mul(m.denominator, o.denominator), prime)
+}
+
+// Clone returns a copy of the hash state
+func (m *MuHash) Clone() *MuHash {
+	return &MuHash{numerator: new(big.Int).Set(m.numerator), denominator: new(big.Int).Set(m.denominator)}
+}
+
+// Finalize returns the hash of the set. The division of the numerator by the denominator is kept, so the state is
+// serialized compactly afterwards and computing the hash again is fast.
+func (m *MuHash) Finalize() (h [sha256.Size]byte) {
+	if m.denominator.Cmp(big.NewInt(1)) != 0 {
+		inverse := new(big.Int).ModInverse(m.denominator, prime)
+		m.numerator.Mod(m.numerator.Mul(m.numerator, inverse), prime)
+		m.denominator.SetInt64(1)
+	}
+	var num [NumSize]byte
+	putNum(num[:], m.numerator)
+	return sha256.Sum256(num[:])
+}
+
+// Serialize returns the numerator and denominator of the hash state as little endian numbers
+func (m *MuHash) Serialize() []byte {
+	b := make([]byte, SerializedSize)
+	putNum(b[:NumSize], m.numerator)
+	putNum(b[NumSize:], m.denominator)
+	return b
+}
+
+// Deserialize returns the hash state serialized by Serialize
+func Deserialize(b []byte) (m *MuHash, e error) {
+	if len(b) != SerializedSize {
+		return nil, errors.New("serialized muhash has the wrong size")
+	}
+	m = &MuHash{numerator: getNum(b[:NumSize]), denominator: getNum(b[NumSize:])}
+	if m.numerator.Cmp(prime) >= 0 || m.denominator.Cmp(prime) >= 0 || m.denominator.Sign() == 0 {
+		return nil, errors.New("serialized muhash is out of range")
+	}
+	return
+}
+
+// toNum hashes an element to a number, by taking the sha256 hash of the element as the key of a chacha20 keystream
+// that provides the little endian bytes of the number
+func toNum(data []byte) *big.Int {
+	key := sha256.Sum256(data)
+	var num [NumSize]byte
+	c, e := chacha20.NewUnauthenticatedCipher(key[:], make([]byte, chacha20.NonceSize))
+	if E.Chk(e) {
+		panic(e)
+	}
+	c.XORKeyStream(num[:], num[:])
+	return getNum(num[:])
+}
+
+// getNum returns the little endian number in b
+func getNum(b []byte) *big.Int {
+	be := make([]byte, len(b))
+	for i := range b {
+		be[len(b)-1-i] = b[i]
+	}
+	return new(big.Int).SetBytes(be)
+}
+
+// putNum writes n to b as a little endian number, which must fit in b
+func putNum(b []byte, n *big.Int) {
+	for i := range b {
+		b[i] = 0
+	}
+	be := n.Bytes()
+	for i := range be {
+		b[len(be)-1-i] = be[i]
+	}
+}
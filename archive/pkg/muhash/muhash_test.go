@@ -0,0 +1,79 @@
+package muhash
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/p9c/pod/pkg/chainhash"
+)
+
+// fromInt returns the element bitcoind's tests use for a small integer
+func fromInt(i byte) []byte {
+	b := make([]byte, 32)
+	b[0] = i
+	return b
+}
+
+// TestMuHash checks the hash against the test vector of bitcoind and that it doesn't depend on the order the
+// elements are added and removed in.
+func TestMuHash(t *testing.T) {
+	m := New()
+	m.Add(fromInt(0))
+	m.Add(fromInt(1))
+	m.Remove(fromInt(2))
+	m.Remove(fromInt(0))
+	m.Add(fromInt(0))
+	expected, e := chainhash.NewHashFromStr("10d312b100cbd32ada024a6646e40d3482fcff103668d2625f10002a607d5863")
+	if e != nil {
+		t.Fatal(e)
+	}
+	if h := chainhash.Hash(m.Finalize()); h != *expected {
+		t.Fatalf("hash is %s, expected %s", h, expected)
+	}
+	other := New()
+	other.Remove(fromInt(2))
+	other.Add(fromInt(1))
+	other.Add(fromInt(0))
+	if other.Finalize() != m.Finalize() {
+		t.Fatal("hash depends on the order of the elements")
+	}
+	// a set that had elements added and removed again hashes like the empty set
+	empty := New()
+	empty.Add(fromInt(3))
+	empty.Add(fromInt(4))
+	empty.Remove(fromInt(3))
+	empty.Remove(fromInt(4))
+	if empty.Finalize() != New().Finalize() {
+		t.Fatal("hash of the empty set changed after adding and removing elements")
+	}
+	combined := New()
+	combined.Add(fromInt(1))
+	part := New()
+	part.Add(fromInt(0))
+	part.Remove(fromInt(2))
+	combined.Combine(part)
+	if combined.Finalize() != m.Finalize() {
+		t.Fatal("combined hash differs")
+	}
+}
+
+// TestSerialize checks that a hash state with a pending denominator is restored by Deserialize.
+func TestSerialize(t *testing.T) {
+	m := New()
+	m.Add(fromInt(5))
+	m.Remove(fromInt(6))
+	serialized := m.Serialize()
+	restored, e := Deserialize(serialized)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !bytes.Equal(restored.Serialize(), serialized) || restored.Finalize() != m.Clone().Finalize() {
+		t.Fatal("deserialized hash state differs")
+	}
+	if _, e = Deserialize(serialized[1:]); e == nil {
+		t.Fatal("deserialized a hash state of the wrong size")
+	}
+	if _, e = Deserialize(make([]byte, SerializedSize)); e == nil {
+		t.Fatal("deserialized a hash state with a zero denominator")
+	}
+}
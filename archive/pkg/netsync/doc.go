@@ -1,7 +1,11 @@
 /*Package netsync implements a concurrency safe block syncing protocol.
 
 The SyncManager communicates with connected peers to perform an initial block download, keep the chain and unconfirmed
-transaction pool in sync, and announce new blocks connected to the chain. Currently the sync manager selects a single
-sync peer that it downloads all blocks from until it is up to date with the longest chain the sync peer is aware of.
+transaction pool in sync, and announce new blocks connected to the chain. The sync manager selects a single sync peer
+that it downloads the whole chain of block headers from. Each header is validated as it arrives, and only once the chain
+of headers is known to have more work than the best chain are the blocks it describes requested from all of the sync
+candidates at once, a window of blocks at a time, and the blocks of peers that stall are requested from the others. The
+blocks are still connected in order, so those that arrive early are held back until the blocks before them are
+connected.
 */
 package netsync
@@ -0,0 +1,267 @@
+package netsync
+
+import (
+	"sort"
+	"time"
+
+	"github.com/p9c/pod/pkg/chainhash"
+	peerpkg "github.com/p9c/pod/pkg/peer"
+	"github.com/p9c/pod/pkg/wire"
+)
+
+const (
+	// blockDownloadWindow is the number of blocks of the header list past the
+	// next one to be connected that can be requested at once. Blocks that arrive
+	// ahead of those before them are held until they can be connected, so this
+	// also bounds the memory used by them.
+	blockDownloadWindow = 1024
+	// maxBlocksInFlightPerPeer is the maximum number of blocks of the header list
+	// that are requested from a single peer at a time.
+	maxBlocksInFlightPerPeer = 16
+	// blockStallTimeout is how long a peer with blocks of the header list in
+	// flight can go without delivering one before they are assigned to other
+	// peers. The peer is not assigned new blocks for as long again.
+	blockStallTimeout = 15 * time.Second
+	// stallSampleInterval is the interval at which the block downloads are checked
+	// for stalled peers.
+	stallSampleInterval = 5 * time.Second
+)
+
+// blockDownload is a block of the header list that has been requested in
+// headers-first mode and is not connected yet.
+type blockDownload struct {
+	node *headerNode
+	// peer is the peer the block is requested from, or nil when it is waiting to
+	// be assigned to another one.
+	peer *peerpkg.Peer
+	// block is set when the block arrived ahead of the ones before it.
+	block *blockMsg
+}
+
+// fetchHeaderBlocks requests the blocks of the header list from the sync
+// candidates. Blocks up to blockDownloadWindow past the next one to be
+// connected are spread over the peers that have them, each of which has at most
+// maxBlocksInFlightPerPeer in flight, and the blocks of peers that stalled or
+// disconnected are requested again first.
+func (sm *SyncManager) fetchHeaderBlocks() {
+	// Blocks are only requested once all the headers of the sync peer are known to
+	// be valid and to have more work than the best chain.
+	front := sm.headerList.Front()
+	if front == nil || !sm.headersSynced {
+		return
+	}
+	limit := front.Value.(*headerNode).height + blockDownloadWindow
+	requests := make(map[*peerpkg.Peer]*wire.MsgGetData)
+	// The retried blocks are requested in order of height, as the lowest of them
+	// hold up the ones after them.
+	sort.Slice(
+		sm.downloadRetries, func(i, j int) bool {
+			return sm.downloadRetries[i].node.height < sm.downloadRetries[j].node.height
+		},
+	)
+	retries := sm.downloadRetries[:0]
+	for _, d := range sm.downloadRetries {
+		// The block may have been delivered by the peer it was first requested from
+		// in the meantime.
+		if d.peer != nil || d.block != nil {
+			continue
+		}
+		if _, exists := sm.blockDownloads[*d.node.hash]; !exists {
+			continue
+		}
+		if !sm.assignBlockDownload(d, requests) {
+			retries = append(retries, d)
+		}
+	}
+	sm.downloadRetries = retries
+	for ; sm.startHeader != nil; sm.startHeader = sm.startHeader.Next() {
+		node, ok := sm.startHeader.Value.(*headerNode)
+		if !ok {
+			D.Ln("header list node type is not a headerNode")
+			continue
+		}
+		if node.height >= limit {
+			break
+		}
+		iv := wire.NewInvVect(wire.InvTypeBlock, node.hash)
+		haveInv, e := sm.haveInventory(iv)
+		if e != nil {
+			T.Ln(
+				"unexpected failure when checking for existing inventory during header block fetch:",
+				e,
+			)
+		}
+		if haveInv {
+			continue
+		}
+		d := &blockDownload{node: node}
+		if !sm.assignBlockDownload(d, requests) {
+			break
+		}
+		sm.blockDownloads[*node.hash] = d
+	}
+	for peer, gdmsg := range requests {
+		T.F("requesting %d blocks from %s", len(gdmsg.InvList), peer)
+		peer.QueueMessage(gdmsg, nil)
+	}
+}
+
+// assignBlockDownload adds a block of the header list to the request for the
+// sync candidate with the fewest blocks in flight that has it, and returns
+// false when none of them can take more blocks.
+func (sm *SyncManager) assignBlockDownload(d *blockDownload, requests map[*peerpkg.Peer]*wire.MsgGetData) bool {
+	now := time.Now()
+	var best *peerpkg.Peer
+	var bestState *peerSyncState
+	for peer, state := range sm.peerStates {
+		if !state.syncCandidate || now.Before(state.stalledUntil) ||
+			len(state.blockDownloads) >= maxBlocksInFlightPerPeer {
+			continue
+		}
+		// The sync peer sent the headers so it has the blocks, while the others
+		// must have announced a chain that reaches the block.
+		if peer != sm.syncPeer && peer.LastBlock() < d.node.height {
+			continue
+		}
+		if bestState == nil || len(state.blockDownloads) < len(bestState.blockDownloads) {
+			best, bestState = peer, state
+		}
+	}
+	if best == nil {
+		return false
+	}
+	gdmsg, exists := requests[best]
+	if !exists {
+		gdmsg = wire.NewMsgGetDataSizeHint(maxBlocksInFlightPerPeer)
+		requests[best] = gdmsg
+	}
+	if e := gdmsg.AddInvVect(wire.NewInvVect(wire.InvTypeBlock, d.node.hash)); E.Chk(e) {
+		return false
+	}
+	// The time a peer has to deliver starts when it is given blocks while it has
+	// none in flight, otherwise it is from when it last delivered one.
+	if len(bestState.blockDownloads) == 0 {
+		bestState.lastBlockTime = now
+	}
+	d.peer = best
+	bestState.blockDownloads[*d.node.hash] = d
+	sm.markBlockRequested(bestState, d.node.hash)
+	return true
+}
+
+// deferHeaderBlock records the delivery of a block of the header list, and holds
+// it back when it arrived ahead of the blocks before it so it can be connected in
+// order later. It returns whether the block was held back.
+func (sm *SyncManager) deferHeaderBlock(bmsg *blockMsg) bool {
+	blockHash := bmsg.block.Hash()
+	d, exists := sm.blockDownloads[*blockHash]
+	if !exists {
+		return false
+	}
+	if d.block != nil {
+		// Another peer delivered the block first after it was assigned again, so
+		// this copy is not needed.
+		T.F("ignoring block %v from %s that already arrived", blockHash, bmsg.peer)
+		if state, exists := sm.peerStates[bmsg.peer]; exists {
+			delete(state.requestedBlocks, *blockHash)
+		}
+		return true
+	}
+	if d.peer != nil {
+		if state, exists := sm.peerStates[d.peer]; exists {
+			delete(state.blockDownloads, *blockHash)
+		}
+		d.peer = nil
+	}
+	if state, exists := sm.peerStates[bmsg.peer]; exists {
+		state.lastBlockTime = time.Now()
+	}
+	if front := sm.headerList.Front(); front == nil || front.Value.(*headerNode).hash.IsEqual(blockHash) {
+		delete(sm.blockDownloads, *blockHash)
+		return false
+	}
+	d.block = bmsg
+	sm.fetchHeaderBlocks()
+	return true
+}
+
+// connectDeferredBlocks connects the blocks that were held back by
+// deferHeaderBlock for as long as the next block of the header list is one of
+// them. It is called again by the blocks it connects, which return at once.
+func (sm *SyncManager) connectDeferredBlocks(workerNumber uint32) {
+	if sm.connectingDeferred {
+		return
+	}
+	sm.connectingDeferred = true
+	defer func() { sm.connectingDeferred = false }()
+	for sm.headersFirstMode {
+		front := sm.headerList.Front()
+		if front == nil {
+			return
+		}
+		hash := front.Value.(*headerNode).hash
+		d, exists := sm.blockDownloads[*hash]
+		if !exists || d.block == nil {
+			return
+		}
+		delete(sm.blockDownloads, *hash)
+		sm.handleBlockMsg(workerNumber, d.block)
+		// Stop when the block could not be connected, as the ones after it cannot be
+		// either.
+		if have, e := sm.chain.HaveBlock(hash); E.Chk(e) || !have {
+			return
+		}
+	}
+}
+
+// releaseBlockDownloads takes the blocks of the header list in flight from a
+// peer away from it so they are requested from other peers. When the peer is
+// disconnecting, the blocks it delivered that are held back are requested again
+// as well.
+func (sm *SyncManager) releaseBlockDownloads(peer *peerpkg.Peer, state *peerSyncState, done bool) {
+	for hash, d := range state.blockDownloads {
+		d.peer = nil
+		sm.downloadRetries = append(sm.downloadRetries, d)
+		delete(state.blockDownloads, hash)
+	}
+	if !done {
+		return
+	}
+	for _, d := range sm.blockDownloads {
+		if d.block != nil && d.block.peer == peer {
+			d.block = nil
+			sm.downloadRetries = append(sm.downloadRetries, d)
+		}
+	}
+}
+
+// resetBlockDownloads forgets the blocks of the header list in flight and held
+// back, as the header list is being reset.
+func (sm *SyncManager) resetBlockDownloads() {
+	sm.blockDownloads = make(map[chainhash.Hash]*blockDownload)
+	sm.downloadRetries = nil
+	for _, state := range sm.peerStates {
+		state.blockDownloads = make(map[chainhash.Hash]*blockDownload)
+	}
+}
+
+// handleStallSample assigns the blocks of the header list in flight from peers
+// that have not delivered any of them within blockStallTimeout to other peers.
+func (sm *SyncManager) handleStallSample() {
+	if !sm.headersFirstMode {
+		return
+	}
+	now := time.Now()
+	for peer, state := range sm.peerStates {
+		if len(state.blockDownloads) == 0 || now.Sub(state.lastBlockTime) < blockStallTimeout {
+			continue
+		}
+		D.F(
+			"peer %s has not delivered any of %d blocks for %v -- requesting them from other peers",
+			peer, len(state.blockDownloads), now.Sub(state.lastBlockTime).Truncate(time.Second),
+		)
+		state.stalledUntil = now.Add(blockStallTimeout)
+		sm.releaseBlockDownloads(peer, state, false)
+	}
+	sm.fetchHeaderBlocks()
+}
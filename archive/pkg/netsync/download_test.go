@@ -0,0 +1,388 @@
+package netsync
+
+import (
+	"container/list"
+	"sort"
+	"testing"
+	"time"
+
+	block2 "github.com/p9c/pod/pkg/block"
+	"github.com/p9c/pod/pkg/blockchain"
+	"github.com/p9c/pod/pkg/chaincfg"
+	"github.com/p9c/pod/pkg/chainhash"
+	"github.com/p9c/pod/pkg/mempool"
+	peerpkg "github.com/p9c/pod/pkg/peer"
+	"github.com/p9c/pod/pkg/wire"
+)
+
+// fakePeer returns a peer that is not connected, so the messages queued for it are dropped, whose chain reaches
+// lastBlock.
+func fakePeer(t *testing.T, lastBlock int32) *peerpkg.Peer {
+	p, e := peerpkg.NewOutboundPeer(&peerpkg.Config{ChainParams: &chaincfg.MainNetParams}, "10.0.0.1:11047")
+	if e != nil {
+		t.Fatal(e)
+	}
+	p.UpdateLastBlockHeight(lastBlock)
+	return p
+}
+
+// fakeSyncState returns the state of a peer with a number of blocks in flight that are not in the header list.
+func fakeSyncState(candidate bool, inFlight int) *peerSyncState {
+	state := &peerSyncState{
+		syncCandidate:   candidate,
+		requestedTxns:   make(map[chainhash.Hash]struct{}),
+		requestedBlocks: make(map[chainhash.Hash]struct{}),
+		compactBlocks:   make(map[chainhash.Hash]*mempool.CompactBlock),
+		blockDownloads:  make(map[chainhash.Hash]*blockDownload),
+	}
+	for i := 0; i < inFlight; i++ {
+		hash := chainhash.Hash{0xff, byte(i)}
+		state.blockDownloads[hash] = &blockDownload{node: &headerNode{hash: &hash}}
+	}
+	return state
+}
+
+// downloadSyncManager returns a sync manager without a chain in headers-first mode with a header list of fake headers
+// from height 1, all of which were handed out to the peers already.
+func downloadSyncManager(headers int) (sm *SyncManager, nodes []*headerNode) {
+	sm = &SyncManager{
+		requestedBlocks:  make(map[chainhash.Hash]struct{}),
+		blockDownloads:   make(map[chainhash.Hash]*blockDownload),
+		peerStates:       make(map[*peerpkg.Peer]*peerSyncState),
+		headerList:       list.New(),
+		headersFirstMode: true,
+		headersSynced:    true,
+	}
+	for i := 0; i < headers; i++ {
+		hash := chainhash.Hash{byte(i + 1)}
+		node := &headerNode{height: int32(i + 1), hash: &hash}
+		sm.headerList.PushBack(node)
+		nodes = append(nodes, node)
+	}
+	return
+}
+
+// giveBlocks records the blocks of the header list at the indexes as in flight from a peer.
+func giveBlocks(sm *SyncManager, peer *peerpkg.Peer, nodes []*headerNode, indexes []int) {
+	state := sm.peerStates[peer]
+	for _, i := range indexes {
+		d := &blockDownload{node: nodes[i], peer: peer}
+		sm.blockDownloads[*nodes[i].hash] = d
+		state.blockDownloads[*nodes[i].hash] = d
+		sm.markBlockRequested(state, nodes[i].hash)
+	}
+}
+
+// inFlight returns the indexes of the blocks of the header list in flight from a peer in ascending order.
+func inFlight(sm *SyncManager, peer *peerpkg.Peer, nodes []*headerNode) (indexes []int) {
+	for i, node := range nodes {
+		if d, exists := sm.peerStates[peer].blockDownloads[*node.hash]; exists && d.peer == peer {
+			indexes = append(indexes, i)
+		}
+	}
+	return
+}
+
+// retried returns the indexes of the blocks of the header list waiting to be assigned again in ascending order.
+func retried(sm *SyncManager, nodes []*headerNode) (indexes []int) {
+	for i, node := range nodes {
+		for _, d := range sm.downloadRetries {
+			if d.node == node {
+				indexes = append(indexes, i)
+			}
+		}
+	}
+	return
+}
+
+// sameIndexes returns whether two lists of indexes are the same, where nil and empty are.
+func sameIndexes(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sort.Ints(a)
+	sort.Ints(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// TestAssignBlockDownload checks that a block of the header list is requested from the sync candidate with the fewest
+// blocks in flight among those that have it, are not stalled and can take more.
+func TestAssignBlockDownload(t *testing.T) {
+	type fakeState struct {
+		lastBlock int32
+		candidate bool
+		inFlight  int
+		stalled   bool
+	}
+	tests := []struct {
+		name     string
+		peers    []fakeState
+		syncPeer int
+		want     int
+	}{
+		{"candidate with the block", []fakeState{{10, true, 0, false}}, -1, 0},
+		{"not a sync candidate", []fakeState{{10, false, 0, false}}, -1, -1},
+		{"chain too short", []fakeState{{9, true, 0, false}}, -1, -1},
+		{"sync peer with a shorter announced chain", []fakeState{{0, true, 0, false}}, 0, 0},
+		{"stalled", []fakeState{{10, true, 0, true}}, -1, -1},
+		{"full", []fakeState{{10, true, maxBlocksInFlightPerPeer, false}}, -1, -1},
+		{
+			"fewest in flight",
+			[]fakeState{{10, true, 5, false}, {10, true, 2, false}, {10, true, 7, false}}, -1, 1,
+		},
+		{
+			"fewest in flight that can take it",
+			[]fakeState{
+				{10, true, 0, true}, {10, true, maxBlocksInFlightPerPeer, false}, {9, true, 0, false},
+				{10, true, 3, false}, {10, false, 0, false},
+			}, -1, 3,
+		},
+	}
+	for _, test := range tests {
+		sm, _ := downloadSyncManager(0)
+		peers := make([]*peerpkg.Peer, len(test.peers))
+		for i, fs := range test.peers {
+			peers[i] = fakePeer(t, fs.lastBlock)
+			state := fakeSyncState(fs.candidate, fs.inFlight)
+			if fs.stalled {
+				state.stalledUntil = time.Now().Add(blockStallTimeout)
+			}
+			sm.peerStates[peers[i]] = state
+		}
+		if test.syncPeer >= 0 {
+			sm.syncPeer = peers[test.syncPeer]
+		}
+		hash := chainhash.Hash{1}
+		d := &blockDownload{node: &headerNode{height: 10, hash: &hash}}
+		requests := make(map[*peerpkg.Peer]*wire.MsgGetData)
+		assigned := sm.assignBlockDownload(d, requests)
+		if test.want < 0 {
+			if assigned || d.peer != nil || len(requests) != 0 {
+				t.Errorf("%s: block was assigned", test.name)
+			}
+			continue
+		}
+		best := peers[test.want]
+		state := sm.peerStates[best]
+		if !assigned || d.peer != best {
+			t.Errorf("%s: block was not assigned to peer %d", test.name, test.want)
+			continue
+		}
+		if gdmsg := requests[best]; len(requests) != 1 || len(gdmsg.InvList) != 1 || gdmsg.InvList[0].Hash != hash {
+			t.Errorf("%s: unexpected requests %v", test.name, requests)
+		}
+		_, inState := state.blockDownloads[hash]
+		_, requested := state.requestedBlocks[hash]
+		_, requestedAll := sm.requestedBlocks[hash]
+		if !inState || !requested || !requestedAll {
+			t.Errorf("%s: block is not recorded as requested from the peer", test.name)
+		}
+		// the time the peer has to deliver starts when it is given its first block
+		if test.peers[test.want].inFlight == 0 && time.Since(state.lastBlockTime) > time.Minute {
+			t.Errorf("%s: time of the last block was not set", test.name)
+		}
+	}
+}
+
+// TestHandleStallSample checks that the blocks of peers that did not deliver any of them for blockStallTimeout are
+// requested from the other peers, and that those peers are not given more blocks for a while.
+func TestHandleStallSample(t *testing.T) {
+	tests := []struct {
+		name string
+		// blocks are the indexes of the blocks of the header list in flight from each peer, and since how long ago it
+		// delivered the last one.
+		blocks  [][]int
+		since   []time.Duration
+		want    [][]int
+		retries []int
+		stalled []bool
+	}{
+		{
+			"stalled blocks go to another peer",
+			[][]int{{0, 1, 2}, nil}, []time.Duration{blockStallTimeout + time.Second, 0},
+			[][]int{nil, {0, 1, 2}}, nil, []bool{true, false},
+		},
+		{
+			"no other peer",
+			[][]int{{0, 1, 2}}, []time.Duration{blockStallTimeout + time.Second},
+			[][]int{nil}, []int{0, 1, 2}, []bool{true},
+		},
+		{
+			"peer within the timeout",
+			[][]int{{0, 1, 2}, nil}, []time.Duration{blockStallTimeout - time.Second, 0},
+			[][]int{{0, 1, 2}, nil}, nil, []bool{false, false},
+		},
+		{
+			"other peer full",
+			[][]int{{0, 1}, {2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17}},
+			[]time.Duration{blockStallTimeout + time.Second, 0},
+			[][]int{nil, {2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17}}, []int{0, 1}, []bool{true, false},
+		},
+		{
+			"both stalled",
+			[][]int{{0}, {1}}, []time.Duration{blockStallTimeout + time.Second, blockStallTimeout * 2},
+			[][]int{nil, nil}, []int{0, 1}, []bool{true, true},
+		},
+	}
+	for _, test := range tests {
+		sm, nodes := downloadSyncManager(20)
+		peers := make([]*peerpkg.Peer, len(test.blocks))
+		now := time.Now()
+		for i := range test.blocks {
+			peers[i] = fakePeer(t, 20)
+			sm.peerStates[peers[i]] = fakeSyncState(true, 0)
+			giveBlocks(sm, peers[i], nodes, test.blocks[i])
+			sm.peerStates[peers[i]].lastBlockTime = now.Add(-test.since[i])
+		}
+		sm.handleStallSample()
+		for i, peer := range peers {
+			if got := inFlight(sm, peer, nodes); !sameIndexes(got, test.want[i]) {
+				t.Errorf("%s: peer %d has blocks %v in flight, expected %v", test.name, i, got, test.want[i])
+			}
+			if stalled := now.Before(sm.peerStates[peer].stalledUntil); stalled != test.stalled[i] {
+				t.Errorf("%s: peer %d stalled %v, expected %v", test.name, i, stalled, test.stalled[i])
+			}
+		}
+		if got := retried(sm, nodes); !sameIndexes(got, test.retries) {
+			t.Errorf("%s: blocks %v wait to be requested again, expected %v", test.name, got, test.retries)
+		}
+		for _, i := range test.retries {
+			if sm.blockDownloads[*nodes[i].hash].peer != nil {
+				t.Errorf("%s: block %d waiting to be requested again is assigned to a peer", test.name, i)
+			}
+		}
+	}
+}
+
+// TestReleaseBlockDownloads checks that the blocks in flight from a peer are requested from the others when it stalls
+// or disconnects, and that the blocks it delivered ahead of the ones before them are only dropped when it disconnects.
+func TestReleaseBlockDownloads(t *testing.T) {
+	tests := []struct {
+		name       string
+		disconnect bool
+		// retries are the indexes of the blocks of the header list waiting to be requested again, held those still
+		// held back and other those in flight from the other peer afterwards.
+		retries []int
+		held    []int
+		other   []int
+	}{
+		{"stalled", false, []int{2, 3}, []int{1}, nil},
+		{"disconnected", true, nil, nil, []int{1, 2, 3}},
+	}
+	for _, test := range tests {
+		sm, nodes := downloadSyncManager(4)
+		peer, other := fakePeer(t, 4), fakePeer(t, 4)
+		sm.peerStates[peer] = fakeSyncState(true, 0)
+		sm.peerStates[other] = fakeSyncState(true, 0)
+		giveBlocks(sm, other, nodes, []int{0})
+		giveBlocks(sm, peer, nodes, []int{1, 2, 3})
+		// the peer delivered the second block ahead of the first one
+		d := sm.blockDownloads[*nodes[1].hash]
+		delete(sm.peerStates[peer].blockDownloads, *nodes[1].hash)
+		d.peer, d.block = nil, &blockMsg{peer: peer}
+		if test.disconnect {
+			sm.handleDonePeerMsg(peer)
+		} else {
+			sm.releaseBlockDownloads(peer, sm.peerStates[peer], false)
+			if got := inFlight(sm, peer, nodes); len(got) != 0 {
+				t.Errorf("%s: blocks %v are still in flight from the peer", test.name, got)
+			}
+		}
+		if got := retried(sm, nodes); !sameIndexes(got, test.retries) {
+			t.Errorf("%s: blocks %v wait to be requested again, expected %v", test.name, got, test.retries)
+		}
+		var held []int
+		for i, node := range nodes {
+			if d, exists := sm.blockDownloads[*node.hash]; exists && d.block != nil {
+				held = append(held, i)
+			}
+		}
+		if !sameIndexes(held, test.held) {
+			t.Errorf("%s: blocks %v are held back, expected %v", test.name, held, test.held)
+		}
+		want := append([]int{0}, test.other...)
+		if got := inFlight(sm, other, nodes); !sameIndexes(got, want) {
+			t.Errorf("%s: other peer has blocks %v in flight, expected %v", test.name, got, want)
+		}
+	}
+}
+
+// testChain returns a chain of blocks on top of the mainnet genesis block that were connected by another chain.
+func testChain(t *testing.T, n int) (blocks []*wire.Block) {
+	sm, cleanup := newTestSyncManager(t)
+	defer cleanup()
+	for i := 0; i < n; i++ {
+		blk := testBlock(t, sm)
+		if _, _, e := sm.chain.ProcessBlock(
+			0, block2.NewBlock(blk), blockchain.BFNone, sm.chain.BestSnapshot().Height+1,
+		); e != nil {
+			t.Fatal(e)
+		}
+		blocks = append(blocks, blk)
+	}
+	return
+}
+
+// TestDeferHeaderBlock checks that the blocks of the header list are connected in order whatever order they are
+// delivered in by the peers they were requested from, holding back those that arrive ahead of the ones before them.
+func TestDeferHeaderBlock(t *testing.T) {
+	blocks := testChain(t, 3)
+	headers := wire.NewMsgHeaders()
+	for _, blk := range blocks {
+		if e := headers.AddBlockHeader(&blk.Header); e != nil {
+			t.Fatal(e)
+		}
+	}
+	tests := []struct {
+		name  string
+		order []int
+		// heights are those of the best chain after each block is delivered
+		heights []int32
+	}{
+		{"in order", []int{0, 1, 2}, []int32{1, 2, 3}},
+		{"reversed", []int{2, 1, 0}, []int32{0, 0, 3}},
+		{"middle first", []int{1, 2, 0}, []int32{0, 0, 3}},
+		{"last held back", []int{0, 2, 1}, []int32{1, 1, 3}},
+	}
+	for _, test := range tests {
+		func() {
+			sm, cleanup := newTestSyncManager(t)
+			defer cleanup()
+			peers := []*peerpkg.Peer{fakePeer(t, 3), fakePeer(t, 3)}
+			for _, peer := range peers {
+				sm.peerStates[peer] = fakeSyncState(true, 0)
+			}
+			sm.headersFirstMode = true
+			sm.syncPeer = peers[0]
+			sm.handleHeadersMsg(&headersMsg{headers: headers, peer: peers[0]})
+			if len(sm.blockDownloads) != len(blocks) {
+				t.Fatalf("%s: %d blocks requested, expected %d", test.name, len(sm.blockDownloads), len(blocks))
+			}
+			for i, j := range test.order {
+				blockHash := blocks[j].BlockHash()
+				d, exists := sm.blockDownloads[blockHash]
+				if !exists || d.peer == nil {
+					t.Fatalf("%s: block %d is not in flight", test.name, j)
+				}
+				sm.handleBlockMsg(0, &blockMsg{block: block2.NewBlock(blocks[j]), peer: d.peer})
+				if height := sm.chain.BestSnapshot().Height; height != test.heights[i] {
+					t.Fatalf(
+						"%s: best height %d after block %d was delivered, expected %d",
+						test.name, height, j, test.heights[i],
+					)
+				}
+				if d, exists = sm.blockDownloads[blockHash]; exists != (d != nil && d.block != nil) {
+					t.Fatalf("%s: block %d is neither connected nor held back", test.name, j)
+				}
+			}
+			if len(sm.blockDownloads) != 0 || sm.headersFirstMode {
+				t.Fatalf("%s: headers-first mode did not finish", test.name)
+			}
+		}()
+	}
+}
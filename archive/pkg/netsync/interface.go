@@ -21,11 +21,10 @@ type PeerNotifier interface {
 
 // Config is a configuration struct used to initialize a new SyncManager.
 type Config struct {
-	PeerNotifier       PeerNotifier
-	Chain              *blockchain.BlockChain
-	TxMemPool          *mempool.TxPool
-	ChainParams        *chaincfg.Params
-	DisableCheckpoints bool
-	MaxPeers           int
-	FeeEstimator       *mempool.FeeEstimator
+	PeerNotifier PeerNotifier
+	Chain        *blockchain.BlockChain
+	TxMemPool    *mempool.TxPool
+	ChainParams  *chaincfg.Params
+	MaxPeers     int
+	FeeEstimator *mempool.FeeEstimator
 }
@@ -45,11 +45,22 @@ type (
 		requestedBlocks map[chainhash.Hash]struct{}
 		syncPeer        *peerpkg.Peer
 		peerStates      map[*peerpkg.Peer]*peerSyncState
+		// highBandwidthPeers are the peers asked to push new blocks as compact blocks, least recent first.
+		highBandwidthPeers []*peerpkg.Peer
 		// The following fields are used for headers-first mode.
 		headersFirstMode bool
 		headerList       *list.List
 		startHeader      *list.Element
-		nextCheckpoint   *chaincfg.Checkpoint
+		// headerTip is the last header checked so far, and headersSynced is set
+		// once the sync peer has sent all of its headers.
+		headerTip     *blockchain.HeaderTip
+		headersSynced bool
+		// blockDownloads are the blocks of the header list requested from the sync
+		// candidates that are not connected yet, and downloadRetries those of them
+		// waiting to be requested from another peer.
+		blockDownloads     map[chainhash.Hash]*blockDownload
+		downloadRetries    []*blockDownload
+		connectingDeferred bool
 		// An optional fee estimator.
 		feeEstimator *mempool.FeeEstimator
 	}
@@ -60,6 +71,20 @@ type (
 		peer  *peerpkg.Peer
 		reply qu.C
 	}
+	// blockTxnMsg packages a bitcoin blocktxn message and the peer it came from
+	// together so the block handler has access to that information.
+	blockTxnMsg struct {
+		msg   *wire.MsgBlockTxn
+		peer  *peerpkg.Peer
+		reply qu.C
+	}
+	// cmpctBlockMsg packages a bitcoin cmpctblock message and the peer it came
+	// from together so the block handler has access to that information.
+	cmpctBlockMsg struct {
+		msg   *wire.MsgCmpctBlock
+		peer  *peerpkg.Peer
+		reply qu.C
+	}
 	// donePeerMsg signifies a newly disconnected peer to the block handler.
 	donePeerMsg struct {
 		peer *peerpkg.Peer
@@ -69,8 +94,8 @@ type (
 	getSyncPeerMsg struct {
 		reply chan int32
 	}
-	// headerNode is used as a node in a list of headers that are linked together
-	// between checkpoints.
+	// headerNode is used as a node in the list of headers of the blocks that are
+	// fetched in headers-first mode.
 	headerNode struct {
 		height int32
 		hash   *chainhash.Hash
@@ -110,6 +135,15 @@ type (
 		requestQueue    []*wire.InvVect
 		requestedTxns   map[chainhash.Hash]struct{}
 		requestedBlocks map[chainhash.Hash]struct{}
+		// compactBlocks are the compact blocks from the peer waiting for the
+		// transactions requested with a getblocktxn message.
+		compactBlocks map[chainhash.Hash]*mempool.CompactBlock
+		// blockDownloads are the blocks of the header list in flight from the peer,
+		// lastBlockTime when it last delivered one and stalledUntil when it can be
+		// given more after it stalled.
+		blockDownloads map[chainhash.Hash]*blockDownload
+		lastBlockTime  time.Time
+		stalledUntil   time.Time
 	}
 	// processBlockMsg is a message type to be sent across the message channel for
 	// requested a block is processed. Note this call differs from blockMsg above in
@@ -139,6 +173,9 @@ const (
 	// minInFlightBlocks is the minimum number of blocks that should be in the
 	// request queue for headers-first mode before requesting more.
 	minInFlightBlocks = 10
+	// historyBlocksPerRequest is the number of blocks below a utxo snapshot the chain was imported from that are
+	// requested at a time once the chain is current.
+	historyBlocksPerRequest = 100
 	// maxRejectedTxns is the maximum number of rejected transactions hashes to
 	// store in memory.
 	maxRejectedTxns = 1000
@@ -148,6 +185,13 @@ const (
 	// maxRequestedTxns is the maximum number of requested transactions hashes to
 	// store in memory.
 	maxRequestedTxns = wire.MaxInvPerMsg
+	// maxHighBandwidthPeers is the maximum number of peers asked to push new
+	// blocks as compact blocks without announcing them first.
+	maxHighBandwidthPeers = 3
+	// maxCompactBlocksInFlight is the maximum number of compact blocks per peer
+	// that can be waiting for their missing transactions. Blocks after that are
+	// requested in full.
+	maxCompactBlocksInFlight = 3
 )
 
 // zeroHash is the zero value hash (all zeros)
@@ -214,6 +258,29 @@ func (sm *SyncManager) QueueBlock(block *block2.Block, peer *peerpkg.Peer, done
 	sm.msgChan <- &blockMsg{block: block, peer: peer, reply: done}
 }
 
+// QueueBlockTxn adds the passed blocktxn message and peer to the block handling
+// queue. Responds to the done channel argument after the message is processed.
+func (sm *SyncManager) QueueBlockTxn(msg *wire.MsgBlockTxn, peer *peerpkg.Peer, done qu.C) {
+	// Don't accept more blocks if we're shutting down.
+	if atomic.LoadInt32(&sm.shutdown) != 0 {
+		done <- struct{}{}
+		return
+	}
+	sm.msgChan <- &blockTxnMsg{msg: msg, peer: peer, reply: done}
+}
+
+// QueueCmpctBlock adds the passed cmpctblock message and peer to the block
+// handling queue. Responds to the done channel argument after the message is
+// processed.
+func (sm *SyncManager) QueueCmpctBlock(msg *wire.MsgCmpctBlock, peer *peerpkg.Peer, done qu.C) {
+	// Don't accept more blocks if we're shutting down.
+	if atomic.LoadInt32(&sm.shutdown) != 0 {
+		done <- struct{}{}
+		return
+	}
+	sm.msgChan <- &cmpctBlockMsg{msg: msg, peer: peer, reply: done}
+}
+
 // QueueHeaders adds the passed headers message and peer to the block handling
 // queue.
 func (sm *SyncManager) QueueHeaders(headers *wire.MsgHeaders, peer *peerpkg.Peer) {
@@ -284,9 +351,13 @@ func (sm *SyncManager) SyncPeerID() int32 {
 // because the sync manager controls which blocks are needed and how the
 // fetching should proceed.
 func (sm *SyncManager) blockHandler(workerNumber uint32) {
+	stallTicker := time.NewTicker(stallSampleInterval)
+	defer stallTicker.Stop()
 out:
 	for {
 		select {
+		case <-stallTicker.C:
+			sm.handleStallSample()
 		case m := <-sm.msgChan:
 			switch msg := m.(type) {
 			case *newPeerMsg:
@@ -297,6 +368,12 @@ out:
 			case *blockMsg:
 				sm.handleBlockMsg(0, msg)
 				msg.reply <- struct{}{}
+			case *cmpctBlockMsg:
+				sm.handleCmpctBlockMsg(0, msg)
+				msg.reply <- struct{}{}
+			case *blockTxnMsg:
+				sm.handleBlockTxnMsg(0, msg)
+				msg.reply <- struct{}{}
 			case *invMsg:
 				sm.handleInvMsg(msg)
 			case *headersMsg:
@@ -386,85 +463,34 @@ func (sm *SyncManager) current() bool {
 	return true
 }
 
-// fetchHeaderBlocks creates and sends a request to the syncPeer for the next
-// list of blocks to be downloaded based on the current list of headers.
-func (sm *SyncManager) fetchHeaderBlocks() {
-	// Nothing to do if there is no start header.
-	if sm.startHeader == nil {
-		D.Ln("fetchHeaderBlocks called with no start header")
+// fetchHistoryBlocks requests the blocks below a utxo snapshot the chain was imported from that are missing from the
+// sync peer, when the chain is current and few blocks are in flight.
+func (sm *SyncManager) fetchHistoryBlocks() {
+	if sm.syncPeer == nil || len(sm.requestedBlocks) >= minInFlightBlocks || !sm.current() {
 		return
 	}
-	// Build up a getdata request for the list of blocks the headers describe. The
-	// size hint will be limited to wire.MaxInvPerMsg by the function, so no need to
-	// double check it here.
-	gdmsg := wire.NewMsgGetDataSizeHint(uint(sm.headerList.Len()))
-	numRequested := 0
-	var sh *list.Element
-	for sh = sm.startHeader; sh != nil; sh = sh.Next() {
-		node, ok := sh.Value.(*headerNode)
-		if !ok {
-			D.Ln("header list node type is not a headerNode")
+	syncPeerState, exists := sm.peerStates[sm.syncPeer]
+	if !exists {
+		return
+	}
+	hashes := sm.chain.MissingHistoryBlocks(len(sm.requestedBlocks) + historyBlocksPerRequest)
+	gdmsg := wire.NewMsgGetDataSizeHint(uint(len(hashes)))
+	for i := range hashes {
+		if _, exists = sm.requestedBlocks[hashes[i]]; exists {
 			continue
 		}
-		iv := wire.NewInvVect(wire.InvTypeBlock, node.hash)
-		haveInv, e := sm.haveInventory(iv)
-		if e != nil {
-			T.Ln(
-				"unexpected failure when checking for existing inventory during header block fetch:",
-				e,
-			)
-		}
-		var ee error
-		if !haveInv {
-			syncPeerState := sm.peerStates[sm.syncPeer]
-			sm.requestedBlocks[*node.hash] = struct{}{}
-			syncPeerState.requestedBlocks[*node.hash] = struct{}{}
-			// If we're fetching from a witness enabled peer post-fork, then ensure that we
-			// receive all the witness data in the blocks.
-			// if sm.syncPeer.IsWitnessEnabled() {
-			// 	iv.Type = wire.InvTypeWitnessBlock
-			// }
-			ee = gdmsg.AddInvVect(iv)
-			if ee != nil {
-				D.Ln(ee)
-			}
-			numRequested++
-		}
-		sm.startHeader = sh.Next()
-		if numRequested >= wire.MaxInvPerMsg {
+		if e := gdmsg.AddInvVect(wire.NewInvVect(wire.InvTypeBlock, &hashes[i])); E.Chk(e) {
 			break
 		}
+		sm.requestedBlocks[hashes[i]] = struct{}{}
+		syncPeerState.requestedBlocks[hashes[i]] = struct{}{}
 	}
 	if len(gdmsg.InvList) > 0 {
+		D.F("requesting %d blocks below the utxo snapshot from %s", len(gdmsg.InvList), sm.syncPeer)
 		sm.syncPeer.QueueMessage(gdmsg, nil)
 	}
 }
 
-// findNextHeaderCheckpoint returns the next checkpoint after the passed height.
-// It returns nil when there is not one either because the height is already
-// later than the final checkpoint or some other reason such as disabled
-// checkpoints.
-func (sm *SyncManager) findNextHeaderCheckpoint(height int32) *chaincfg.Checkpoint {
-	checkpoints := sm.chain.Checkpoints()
-	if len(checkpoints) == 0 {
-		return nil
-	}
-	// There is no next checkpoint if the height is already after the final checkpoint.
-	finalCheckpoint := &checkpoints[len(checkpoints)-1]
-	if height >= finalCheckpoint.Height {
-		return nil
-	}
-	// Find the next checkpoint.
-	nextCheckpoint := finalCheckpoint
-	for i := len(checkpoints) - 2; i >= 0; i-- {
-		if height >= checkpoints[i].Height {
-			break
-		}
-		nextCheckpoint = &checkpoints[i]
-	}
-	return nextCheckpoint
-}
-
 // handleBlockMsg handles block messages from all peers.
 func (sm *SyncManager) handleBlockMsg(workerNumber uint32, bmsg *blockMsg) {
 	pp := bmsg.peer
@@ -496,25 +522,28 @@ func (sm *SyncManager) handleBlockMsg(workerNumber uint32, bmsg *blockMsg) {
 			return
 		}
 	}
+	// Blocks of the header list are requested from several peers at once, so those
+	// that arrive ahead of the ones before them are held back until they can be
+	// connected in order.
+	if sm.headersFirstMode && sm.deferHeaderBlock(bmsg) {
+		return
+	}
 	// When in headers-first mode, if the block matches the hash of the first header
-	// in the list of headers that are being fetched, it's eligible for less
-	// validation since the headers have already been verified to link together and
-	// are valid up to the next checkpoint. Also, remove the list entry for all
-	// blocks except the checkpoint since it is needed to verify the next round of
-	// headers links properly.
-	isCheckpointBlock := false
+	// in the list of headers that are being fetched, remove the list entry. The
+	// blocks up to the latest checkpoint are eligible for less validation, as the
+	// headers have already been verified to link together and match it.
 	behaviorFlags := blockchain.BFNone
 	if sm.headersFirstMode {
 		firstNodeEl := sm.headerList.Front()
 		if firstNodeEl != nil {
 			firstNode := firstNodeEl.Value.(*headerNode)
 			if blockHash.IsEqual(firstNode.hash) {
-				behaviorFlags |= blockchain.BFFastAdd
-				if firstNode.hash.IsEqual(sm.nextCheckpoint.Hash) {
-					isCheckpointBlock = true
-				} else {
-					sm.headerList.Remove(firstNodeEl)
+				checkpoint := sm.chain.LatestCheckpoint()
+				if checkpoint != nil && firstNode.height <= checkpoint.Height &&
+					sm.headerTip.Height() >= checkpoint.Height {
+					behaviorFlags |= blockchain.BFFastAdd
 				}
+				sm.headerList.Remove(firstNodeEl)
 			}
 		}
 	}
@@ -523,6 +552,19 @@ func (sm *SyncManager) handleBlockMsg(workerNumber uint32, bmsg *blockMsg) {
 	// insert and thus we'll retry next time we get an inv.
 	delete(state.requestedBlocks, *blockHash)
 	delete(sm.requestedBlocks, *blockHash)
+	delete(state.compactBlocks, *blockHash)
+	// Blocks below a utxo snapshot the chain was imported from are only stored, as the chain state already includes
+	// them.
+	if stored, e := sm.chain.StoreHistoryBlock(bmsg.block); stored {
+		if e != nil {
+			E.F("rejected block %v from %s: %v", blockHash, pp, e)
+			code, reason := mempool.ErrToRejectErr(e)
+			pp.PushRejectMsg(wire.CmdBlock, code, reason, blockHash, false)
+			return
+		}
+		sm.fetchHistoryBlocks()
+		return
+	}
 	var heightUpdate int32
 	var blkHashUpdate *chainhash.Hash
 	header := &bmsg.block.WireBlock().Header
@@ -622,6 +664,11 @@ func (sm *SyncManager) handleBlockMsg(workerNumber uint32, bmsg *blockMsg) {
 		best := sm.chain.BestSnapshot()
 		heightUpdate = best.Height
 		blkHashUpdate = &best.Hash
+		// The peers that most recently delivered a new tip are asked to push the next
+		// blocks as compact blocks, as they are likely to be the fastest to have them.
+		if best.Hash.IsEqual(blockHash) && sm.current() {
+			sm.updateHighBandwidthPeers(pp)
+		}
 		// Clear the rejected transactions.
 		sm.rejectedTxns = make(map[chainhash.Hash]struct{})
 	}
@@ -638,57 +685,26 @@ func (sm *SyncManager) handleBlockMsg(workerNumber uint32, bmsg *blockMsg) {
 			)
 		}
 	}
-	// Nothing more to do if we aren't in headers-first mode.
+	// Nothing more to do if we aren't in headers-first mode, other than downloading the blocks below a utxo snapshot
+	// once the chain has caught up.
 	if !sm.headersFirstMode {
+		sm.fetchHistoryBlocks()
 		return
 	}
-	// This is headers-first mode, so if the block is not a checkpoint request more
-	// blocks using the header list when the request queue is getting short.
-	if !isCheckpointBlock {
-		if sm.startHeader != nil &&
-			len(state.requestedBlocks) < minInFlightBlocks {
-			sm.fetchHeaderBlocks()
-		}
-		return
-	}
-	// This is headers-first mode and the block is a checkpoint. When there is a
-	// next checkpoint, get the next round of headers by asking for headers starting
-	// from the block after this one up to the next checkpoint.
-	prevHeight := sm.nextCheckpoint.Height
-	prevHash := sm.nextCheckpoint.Hash
-	sm.nextCheckpoint = sm.findNextHeaderCheckpoint(prevHeight)
-	if sm.nextCheckpoint != nil {
-		locator := blockchain.BlockLocator([]*chainhash.Hash{prevHash})
-		e = pp.PushGetHeadersMsg(locator, sm.nextCheckpoint.Hash)
-		if e != nil {
-			E.F(
-				"failed to send getheaders message to peer %s: %v",
-				pp.Addr(), e,
-			)
-			return
-		}
-		I.F(
-			"downloading headers for blocks %d to %d from peer %s",
-			prevHeight+1, sm.nextCheckpoint.Height, sm.syncPeer.Addr(),
-		)
+	// This is headers-first mode, so connect the blocks after this one that arrived
+	// already, which is left to the call that connects them when this is one of
+	// them, and request more using the header list.
+	if sm.connectingDeferred {
 		return
 	}
-	// This is headers-first mode, the block is a checkpoint, and there are no more
-	// checkpoints, so switch to normal mode by requesting blocks from the block
-	// after this one up to the end of the chain (zero hash).
-	sm.headersFirstMode = false
-	sm.headerList.Init()
-	I.Ln(
-		"reached the final checkpoint -- switching to normal mode",
-	)
-	locator := blockchain.BlockLocator([]*chainhash.Hash{blockHash})
-	e = pp.PushGetBlocksMsg(locator, &zeroHash)
-	if e != nil {
-		E.Ln(
-			"failed to send getblocks message to peer", pp, ":", e,
-		)
+	sm.connectDeferredBlocks(workerNumber)
+	if sm.headerList.Len() > 0 {
+		sm.fetchHeaderBlocks()
 		return
 	}
+	// This is headers-first mode and the blocks of all the headers are connected,
+	// so switch to normal mode.
+	sm.finishHeadersFirst()
 }
 
 // handleBlockchainNotification handles notifications from blockchain. It does
@@ -773,6 +789,155 @@ func (sm *SyncManager) handleBlockchainNotification(notification *blockchain.Not
 	}
 }
 
+// handleCmpctBlockMsg handles cmpctblock messages from all peers. The block is
+// rebuilt from the transactions in the mempool, and those that are missing are
+// requested from the peer with a getblocktxn message. When the block can not be
+// rebuilt it is requested in full instead.
+func (sm *SyncManager) handleCmpctBlockMsg(workerNumber uint32, cmsg *cmpctBlockMsg) {
+	peer := cmsg.peer
+	state, exists := sm.peerStates[peer]
+	if !exists {
+		T.Ln("received cmpctblock message from unknown peer", peer)
+		return
+	}
+	blockHash := cmsg.msg.BlockHash()
+	iv := wire.NewInvVect(wire.InvTypeBlock, &blockHash)
+	peer.AddKnownInventory(iv)
+	// Compact blocks are only pushed unannounced by peers that were asked to.
+	_, requested := state.requestedBlocks[blockHash]
+	if !requested && !peer.CmpctBlocksRequested() {
+		D.Ln("ignoring unrequested compact block", blockHash, "from", peer)
+		return
+	}
+	// Blocks are downloaded in full in headers-first mode.
+	if sm.headersFirstMode {
+		return
+	}
+	if haveInv, e := sm.haveInventory(iv); E.Chk(e) || haveInv {
+		delete(state.requestedBlocks, blockHash)
+		delete(sm.requestedBlocks, blockHash)
+		return
+	}
+	if _, exists = state.compactBlocks[blockHash]; exists {
+		return
+	}
+	cb, e := sm.txMemPool.ReconstructBlock(cmsg.msg)
+	if e != nil {
+		D.Ln("unable to rebuild compact block from", peer, "--", e)
+		sm.requestFullBlock(peer, state, &blockHash)
+		return
+	}
+	missing := cb.Missing()
+	if len(missing) == 0 {
+		sm.handleCompactBlock(workerNumber, peer, state, cb)
+		return
+	}
+	if len(state.compactBlocks) >= maxCompactBlocksInFlight {
+		sm.requestFullBlock(peer, state, &blockHash)
+		return
+	}
+	T.F("requesting %d of %d transactions of compact block %v from %s",
+		len(missing), cmsg.msg.TxCount(), blockHash, peer,
+	)
+	state.compactBlocks[blockHash] = cb
+	sm.markBlockRequested(state, &blockHash)
+	peer.QueueMessage(wire.NewMsgGetBlockTxn(&blockHash, missing), nil)
+}
+
+// handleBlockTxnMsg handles blocktxn messages from all peers, completing the
+// compact block the transactions were requested for.
+func (sm *SyncManager) handleBlockTxnMsg(workerNumber uint32, bmsg *blockTxnMsg) {
+	peer := bmsg.peer
+	state, exists := sm.peerStates[peer]
+	if !exists {
+		T.Ln("received blocktxn message from unknown peer", peer)
+		return
+	}
+	blockHash := bmsg.msg.BlockHash
+	cb, exists := state.compactBlocks[blockHash]
+	if !exists {
+		W.C(
+			func() string {
+				return fmt.Sprintf(
+					"got unrequested transactions of block %v from %s -- disconnecting",
+					blockHash,
+					peer.Addr(),
+				)
+			},
+		)
+		peer.Disconnect()
+		return
+	}
+	delete(state.compactBlocks, blockHash)
+	if e := cb.Fill(bmsg.msg); e != nil {
+		D.Ln("unable to complete compact block from", peer, "--", e)
+		sm.requestFullBlock(peer, state, &blockHash)
+		return
+	}
+	sm.handleCompactBlock(workerNumber, peer, state, cb)
+}
+
+// handleCompactBlock processes a compact block that has all of its
+// transactions like a block received in full. The block is requested in full
+// when the transactions do not match its header.
+func (sm *SyncManager) handleCompactBlock(
+	workerNumber uint32, peer *peerpkg.Peer, state *peerSyncState,
+	cb *mempool.CompactBlock,
+) {
+	blk, e := cb.Block()
+	if e != nil {
+		D.Ln("unable to rebuild compact block from", peer, "--", e)
+		blockHash := cb.BlockHash()
+		sm.requestFullBlock(peer, state, &blockHash)
+		return
+	}
+	sm.markBlockRequested(state, blk.Hash())
+	sm.handleBlockMsg(workerNumber, &blockMsg{block: blk, peer: peer})
+}
+
+// markBlockRequested records a block as requested from a peer.
+func (sm *SyncManager) markBlockRequested(state *peerSyncState, hash *chainhash.Hash) {
+	if _, exists := sm.requestedBlocks[*hash]; !exists {
+		sm.limitMap(sm.requestedBlocks, maxRequestedBlocks)
+		sm.requestedBlocks[*hash] = struct{}{}
+	}
+	state.requestedBlocks[*hash] = struct{}{}
+}
+
+// requestFullBlock requests a block in full from a peer after its compact block
+// could not be rebuilt.
+func (sm *SyncManager) requestFullBlock(peer *peerpkg.Peer, state *peerSyncState, hash *chainhash.Hash) {
+	sm.markBlockRequested(state, hash)
+	gdmsg := wire.NewMsgGetData()
+	if e := gdmsg.AddInvVect(wire.NewInvVect(wire.InvTypeBlock, hash)); E.Chk(e) {
+		return
+	}
+	peer.QueueMessage(gdmsg, nil)
+}
+
+// updateHighBandwidthPeers asks a peer that delivered a new tip to push the
+// next blocks as compact blocks. Only the most recent few peers are kept in
+// this mode, and the one that delivered a tip the longest time ago is asked to
+// announce blocks again when there are too many.
+func (sm *SyncManager) updateHighBandwidthPeers(peer *peerpkg.Peer) {
+	if !peer.SupportsCmpctBlocks() {
+		return
+	}
+	for i, p := range sm.highBandwidthPeers {
+		if p == peer {
+			sm.highBandwidthPeers = append(sm.highBandwidthPeers[:i], sm.highBandwidthPeers[i+1:]...)
+			sm.highBandwidthPeers = append(sm.highBandwidthPeers, peer)
+			return
+		}
+	}
+	sm.highBandwidthPeers = append(sm.highBandwidthPeers, peer)
+	peer.PushSendCmpctMsg(true)
+	if len(sm.highBandwidthPeers) > maxHighBandwidthPeers {
+		sm.highBandwidthPeers[0].PushSendCmpctMsg(false)
+		sm.highBandwidthPeers = sm.highBandwidthPeers[1:]
+	}
+}
+
 // handleDonePeerMsg deals with peers that have signalled they are done. It
 // removes the peer as a candidate for syncing and in the case where it was the
 // current sync peer, attempts to select a new best peer to sync from. It is
@@ -792,26 +957,36 @@ func (sm *SyncManager) handleDonePeerMsg(peer *peerpkg.Peer) {
 		delete(sm.requestedTxns, txHash)
 	}
 	// Remove requested blocks from the global map so that they will be fetched from
-	// elsewhere next time we get an inv.
-	//
-	// TODO: we could possibly here check which peers have these blocks and request them now to speed things up a little.
+	// elsewhere next time we get an inv. The blocks of the header list the peer had
+	// in flight are requested from the other peers right away.
 	for blockHash := range state.requestedBlocks {
 		delete(sm.requestedBlocks, blockHash)
 	}
+	sm.releaseBlockDownloads(peer, state, true)
+	for i, p := range sm.highBandwidthPeers {
+		if p == peer {
+			sm.highBandwidthPeers = append(sm.highBandwidthPeers[:i], sm.highBandwidthPeers[i+1:]...)
+			break
+		}
+	}
 	// Attempt to find a new peer to sync from if the quitting peer is the sync
 	// peer. Also, reset the headers-first state if in headers-first mode so
 	if sm.syncPeer == peer {
 		sm.syncPeer = nil
 		if sm.headersFirstMode {
-			best := sm.chain.BestSnapshot()
-			sm.resetHeaderState(&best.Hash, best.Height)
+			sm.resetHeaderState()
 		}
 		sm.startSync()
+		return
+	}
+	if sm.headersFirstMode {
+		sm.fetchHeaderBlocks()
 	}
 }
 
-// handleHeadersMsg handles block header messages from all peers. Headers are
-// requested when performing a headers-first sync.
+// handleHeadersMsg handles block header messages from the sync peer. Headers
+// are requested when performing a headers-first sync, until the peer has sent
+// all of them.
 func (sm *SyncManager) handleHeadersMsg(hmsg *headersMsg) {
 	peer := hmsg.peer
 	_, exists := sm.peerStates[peer]
@@ -830,95 +1005,83 @@ func (sm *SyncManager) handleHeadersMsg(hmsg *headersMsg) {
 		peer.Disconnect()
 		return
 	}
-	// Nothing to do for an empty headers message.
-	if numHeaders == 0 {
+	// Headers are only requested from the sync peer, and once they are all known
+	// only blocks are.
+	if peer != sm.syncPeer || sm.headersSynced {
+		T.F("ignoring %d headers from %s", numHeaders, peer)
 		return
 	}
-	// Process all of the received headers ensuring each one connects to the
-	// previous and that checkpoints match.
-	receivedCheckpoint := false
-	var finalHash *chainhash.Hash
-	for _, blockHeader := range msg.Headers {
-		blockHash := blockHeader.BlockHash()
-		finalHash = &blockHash
-		// Ensure there is a previous header to compare against.
-		prevNodeEl := sm.headerList.Back()
-		if prevNodeEl == nil {
-			W.Ln(
-				"header list does not contain a previous element as expected -- disconnecting peer",
-			)
-			peer.Disconnect()
-			return
+	// Check that the headers link together and to the ones received before, and
+	// that each of them is valid where it is in the chain, including the proof of
+	// work and difficulty of its algorithm, and add them to the list of headers.
+	if numHeaders > 0 {
+		headers := make([]wire.BlockHeader, numHeaders)
+		for i, header := range msg.Headers {
+			headers[i] = *header
 		}
-		// Ensure the header properly connects to the previous one and add it to the
-		// list of headers.
-		node := headerNode{hash: &blockHash}
-		prevNode := prevNodeEl.Value.(*headerNode)
-		if prevNode.hash.IsEqual(&blockHeader.PrevBlock) {
-			node.height = prevNode.height + 1
-			e := sm.headerList.PushBack(&node)
-			if sm.startHeader == nil {
-				sm.startHeader = e
-			}
-		} else {
+		if sm.headerTip != nil && !headers[0].PrevBlock.IsEqual(sm.headerTip.Hash()) {
 			T.Ln(
-				"received block header that does not properly connect to the chain from peer",
+				"received block header that does not properly connect to the previous headers from peer",
 				peer,
 				"-- disconnecting",
 			)
 			peer.Disconnect()
 			return
 		}
-		// Verify the header at the next checkpoint height matches.
-		if node.height == sm.nextCheckpoint.Height {
-			if node.hash.IsEqual(sm.nextCheckpoint.Hash) {
-				receivedCheckpoint = true
-				I.F(
-					"verified downloaded block header against checkpoint at height %d/hash %s",
-					node.height,
-					node.hash,
-				)
-			} else {
-				T.F(
-					"block header at height %d/hash %s from peer %s does NOT match expected checkpoint hash of"+
-						" %s -- disconnecting",
-					node.height,
-					node.hash,
-					peer,
-					sm.nextCheckpoint.Hash,
-				)
-				peer.Disconnect()
-				return
+		tip, e := sm.chain.CheckBlockHeaders(sm.headerTip, headers)
+		if e != nil {
+			W.F("received invalid block headers from peer %s: %v -- disconnecting", peer, e)
+			peer.Disconnect()
+			return
+		}
+		sm.headerTip = tip
+		height := tip.Height() - int32(numHeaders)
+		for i := range headers {
+			height++
+			blockHash := headers[i].BlockHash()
+			e := sm.headerList.PushBack(&headerNode{height: height, hash: &blockHash})
+			if sm.startHeader == nil {
+				sm.startHeader = e
 			}
-			break
 		}
 	}
-	// When this header is a checkpoint, switch to fetching the blocks for all of
-	// the headers since the last checkpoint.
-	if receivedCheckpoint {
-		// Since the first entry of the list is always the final block that is already
-		// in the database and is only used to ensure the next header links properly, it
-		// must be removed before fetching the blocks.
-		sm.headerList.Remove(sm.headerList.Front())
-		I.F(
-			"received %v block headers: Fetching blocks",
-			sm.headerList.Len(),
-		)
-		sm.progressLogger.SetLastLogTime(time.Now())
-		sm.fetchHeaderBlocks()
+	// A full headers message means the peer has more, so request the next batch
+	// starting from the latest known header.
+	if numHeaders == wire.MaxBlockHeadersPerMsg {
+		locator := blockchain.BlockLocator([]*chainhash.Hash{sm.headerTip.Hash()})
+		if e := peer.PushGetHeadersMsg(locator, &zeroHash); e != nil {
+			E.F(
+				"failed to send getheaders message to peer %s: %v", peer,
+				e,
+			)
+		}
 		return
 	}
-	// This header is not a checkpoint, so request the next batch of headers
-	// starting from the latest known header and ending with the next checkpoint.
-	locator := blockchain.BlockLocator([]*chainhash.Hash{finalHash})
-	e := peer.PushGetHeadersMsg(locator, sm.nextCheckpoint.Hash)
-	if e != nil {
-		E.F(
-			"failed to send getheaders message to peer %s: %v", peer,
-			e,
-		)
+	// The peer has sent all of its headers. Its chain is only downloaded when it
+	// has more work than the best chain.
+	if sm.headerTip == nil || !sm.chain.IsBetterHeaderChain(sm.headerTip) {
+		I.F("the headers from peer %s do not have more work than the best chain", peer)
+		sm.finishHeadersFirst()
 		return
 	}
+	// The first headers may be of blocks that are already known when the peer is
+	// on another branch, and they are connected already.
+	for front := sm.headerList.Front(); front != nil; front = sm.headerList.Front() {
+		if have, e := sm.chain.HaveBlock(front.Value.(*headerNode).hash); E.Chk(e) || !have {
+			break
+		}
+		if sm.startHeader == front {
+			sm.startHeader = front.Next()
+		}
+		sm.headerList.Remove(front)
+	}
+	sm.headersSynced = true
+	I.F(
+		"received block headers up to height %d: fetching %d blocks",
+		sm.headerTip.Height(), sm.headerList.Len(),
+	)
+	sm.progressLogger.SetLastLogTime(time.Now())
+	sm.fetchHeaderBlocks()
 }
 
 // handleInvMsg handles inv messages from all peers. We examine the inventory
@@ -1055,6 +1218,11 @@ func (sm *SyncManager) handleInvMsg(imsg *invMsg) {
 				// if peer.IsWitnessEnabled() {
 				// 	iv.Type = wire.InvTypeWitnessBlock
 				// }
+				// New blocks are requested as compact blocks from peers that support
+				// them as most of their transactions should already be in the mempool.
+				if sm.current() && peer.SupportsCmpctBlocks() {
+					iv = wire.NewInvVect(wire.InvTypeCmpctBlock, &iv.Hash)
+				}
 				e := gdmsg.AddInvVect(iv)
 				if e != nil {
 				}
@@ -1107,10 +1275,13 @@ func (sm *SyncManager) handleNewPeerMsg(peer *peerpkg.Peer) {
 		syncCandidate:   isSyncCandidate,
 		requestedTxns:   make(map[chainhash.Hash]struct{}),
 		requestedBlocks: make(map[chainhash.Hash]struct{}),
+		compactBlocks:   make(map[chainhash.Hash]*mempool.CompactBlock),
+		blockDownloads:  make(map[chainhash.Hash]*blockDownload),
 	}
 	// Start syncing by choosing the best candidate if needed.
 	if isSyncCandidate && sm.syncPeer == nil {
 		sm.startSync()
+		sm.fetchHistoryBlocks()
 	}
 }
 
@@ -1251,18 +1422,9 @@ func (sm *SyncManager) isSyncCandidate(peer *peerpkg.Peer) bool {
 		if host != "127.0.0.1" && host != "localhost" {
 			return false
 		}
-		// } else {
-		// // The peer is not a candidate for sync if it's not a full node. Additionally, if the segwit soft-fork package
-		// // has activated, then the peer must also be upgraded.
-		// segwitActive, e := sm.chain.IsDeploymentActive(chaincfg.DeploymentSegwit)
-		// if e != nil  {
-		// 			// 	Error("unable to query for segwit soft-fork state:", e)
-		// }
-		// nodeServices := peer.Services()
-		// if nodeServices&wire.SFNodeNetwork != wire.SFNodeNetwork ||
-		// 	(segwitActive && !peer.IsWitnessEnabled()) {
-		// 	return false
-		// }
+	} else if peer.Services()&wire.SFNodeNetwork != wire.SFNodeNetwork {
+		// The peer is not a candidate for sync if it's not a full node, such as a node that prunes its blocks.
+		return false
 	}
 	// Candidate if all checks passed.
 	return true
@@ -1287,16 +1449,33 @@ func (sm *SyncManager) limitMap(m map[chainhash.Hash]struct{}, limit int) {
 
 // resetHeaderState sets the headers-first mode state to values appropriate for
 // syncing from a new peer.
-func (sm *SyncManager) resetHeaderState(newestHash *chainhash.Hash, newestHeight int32) {
+func (sm *SyncManager) resetHeaderState() {
 	sm.headersFirstMode = false
+	sm.headersSynced = false
 	sm.headerList.Init()
 	sm.startHeader = nil
-	// When there is a next checkpoint, add an entry for the latest known block into
-	// the header pool. This allows the next downloaded header to prove it links to
-	// the chain properly.
-	if sm.nextCheckpoint != nil {
-		node := headerNode{height: newestHeight, hash: newestHash}
-		sm.headerList.PushBack(&node)
+	sm.headerTip = nil
+	sm.resetBlockDownloads()
+}
+
+// finishHeadersFirst switches to normal mode once the blocks of the headers
+// from the sync peer are connected, or when its chain is not better, by asking
+// it for the blocks after the best one up to the end of the chain (zero hash).
+func (sm *SyncManager) finishHeadersFirst() {
+	sm.resetHeaderState()
+	if sm.syncPeer == nil {
+		return
+	}
+	I.Ln("headers-first sync done -- switching to normal mode")
+	locator, e := sm.chain.LatestBlockLocator()
+	if e != nil {
+		E.Ln("failed to get block locator for the latest block:", e)
+		return
+	}
+	if e = sm.syncPeer.PushGetBlocksMsg(locator, &zeroHash); e != nil {
+		E.Ln(
+			"failed to send getblocks message to peer", sm.syncPeer, ":", e,
+		)
 	}
 }
 
@@ -1357,31 +1536,34 @@ func (sm *SyncManager) startSync() {
 				return fmt.Sprintf("syncing to block height %d from peer %v", bestPeer.LastBlock(), bestPeer.Addr())
 			},
 		)
-		// When the current height is less than a known checkpoint we can use block
-		// headers to learn about which blocks comprise the chain up to the checkpoint
-		// and perform less validation for them. This is possible since each header
-		// contains the hash of the previous header and a merkle root.
+		// When the sync peer has blocks past the best one we use block headers to learn
+		// about which blocks comprise its chain before downloading any of them. Each
+		// header contains the hash of the previous header and a merkle root, and its
+		// proof of work and difficulty can be checked against the headers before it,
+		// so the headers show how much work the chain has and that it matches the
+		// checkpoints without the blocks.
 		//
-		// Therefore if we validate all of the received headers link together properly
-		// and the checkpoint hashes match, we can be sure the hashes for the blocks in
-		// between are accurate. Further, once the full blocks are downloaded, the
-		// merkle root is computed and compared against the value in the header which
-		// proves the full block hasn't been tampered with. Once we have passed the
-		// final checkpoint, or checkpoints are disabled, use standard inv messages
-		// learn about the blocks and fully validate them. Finally, regression test mode
-		// does not support the headers-first approach so do normal block downloads when
-		// in regression test mode.
-		if sm.nextCheckpoint != nil &&
-			best.Height < sm.nextCheckpoint.Height &&
+		// Therefore once all of the headers are received and validated and their chain
+		// has more work than the best chain, the blocks can be requested from all of
+		// the sync candidates at once. Further, once the full blocks are downloaded,
+		// the merkle root is computed and compared against the value in the header
+		// which proves the full block hasn't been tampered with. Once the blocks of all
+		// the headers are connected, use standard inv messages to learn about the
+		// blocks. Finally, regression test mode does not support the headers-first
+		// approach so do normal block downloads when in regression test mode.
+		if best.Height < bestPeer.LastBlock() &&
 			sm.chainParams != &chaincfg.RegressionTestParams {
-			e := bestPeer.PushGetHeadersMsg(locator, sm.nextCheckpoint.Hash)
+			sm.resetHeaderState()
+			e := bestPeer.PushGetHeadersMsg(locator, &zeroHash)
 			if e != nil {
+				E.F("failed to send getheaders message to peer %s: %v", bestPeer, e)
+				return
 			}
 			sm.headersFirstMode = true
 			I.F(
 				"downloading headers for blocks %d to %d from peer %s",
 				best.Height+1,
-				sm.nextCheckpoint.Height,
+				bestPeer.LastBlock(),
 				bestPeer.Addr(),
 			)
 		} else {
@@ -1406,6 +1588,7 @@ func New(config *Config) (*SyncManager, error) {
 		rejectedTxns:    make(map[chainhash.Hash]struct{}),
 		requestedTxns:   make(map[chainhash.Hash]struct{}),
 		requestedBlocks: make(map[chainhash.Hash]struct{}),
+		blockDownloads:  make(map[chainhash.Hash]*blockDownload),
 		peerStates:      make(map[*peerpkg.Peer]*peerSyncState),
 		progressLogger:  newBlockProgressLogger("processed"),
 		msgChan:         make(chan interface{}, config.MaxPeers*3),
@@ -1413,16 +1596,6 @@ func New(config *Config) (*SyncManager, error) {
 		quit:            qu.T(),
 		feeEstimator:    config.FeeEstimator,
 	}
-	best := sm.chain.BestSnapshot()
-	if !config.DisableCheckpoints {
-		// Initialize the next checkpoint based on the current height.
-		sm.nextCheckpoint = sm.findNextHeaderCheckpoint(best.Height)
-		if sm.nextCheckpoint != nil {
-			sm.resetHeaderState(&best.Hash, best.Height)
-		}
-	} else {
-		I.Ln("checkpoints are disabled")
-	}
 	sm.chain.Subscribe(sm.handleBlockchainNotification)
 	return &sm, nil
 }
@@ -0,0 +1,391 @@
+package netsync
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/p9c/qu"
+
+	"github.com/p9c/pod/pkg/bits"
+	"github.com/p9c/pod/pkg/blockchain"
+	"github.com/p9c/pod/pkg/chaincfg"
+	"github.com/p9c/pod/pkg/chainhash"
+	"github.com/p9c/pod/pkg/database"
+	_ "github.com/p9c/pod/pkg/database/ffldb"
+	"github.com/p9c/pod/pkg/fork"
+	"github.com/p9c/pod/pkg/mempool"
+	peerpkg "github.com/p9c/pod/pkg/peer"
+	"github.com/p9c/pod/pkg/util"
+	"github.com/p9c/pod/pkg/wire"
+)
+
+// conn mocks a network connection by implementing the net.Conn interface, so peers can be connected to each other
+// without opening a network connection.
+type conn struct {
+	io.Reader
+	io.Writer
+	io.Closer
+	laddr, raddr string
+}
+
+func (c conn) LocalAddr() net.Addr                { return &addr{"tcp", c.laddr} }
+func (c conn) RemoteAddr() net.Addr               { return &addr{"tcp", c.raddr} }
+func (c conn) SetDeadline(t time.Time) error      { return nil }
+func (c conn) SetReadDeadline(t time.Time) error  { return nil }
+func (c conn) SetWriteDeadline(t time.Time) error { return nil }
+
+// addr mocks a network address
+type addr struct {
+	net, address string
+}
+
+func (m addr) Network() string { return m.net }
+func (m addr) String() string  { return m.address }
+
+// pipe turns two mock connections into a full-duplex connection similar to net.Pipe.
+func pipe(c1, c2 *conn) (*conn, *conn) {
+	r1, w1 := io.Pipe()
+	r2, w2 := io.Pipe()
+	c1.Writer, c1.Closer, c2.Reader = w1, w1, r1
+	c2.Writer, c2.Closer, c1.Reader = w2, w2, r2
+	return c1, c2
+}
+
+func init() {
+	// the peers of the tests are connected to each other within the process
+	peerpkg.AllowSelfConns = true
+}
+
+// fakePeerNotifier ignores the notifications of the sync manager for the server.
+type fakePeerNotifier struct{}
+
+func (fakePeerNotifier) AnnounceNewTransactions(newTxs []*mempool.TxDesc) {}
+func (fakePeerNotifier) UpdatePeerHeights(latestBlkHash *chainhash.Hash, latestHeight int32, updateSource *peerpkg.Peer) {
+}
+func (fakePeerNotifier) RelayInventory(invVect *wire.InvVect, data interface{}) {}
+func (fakePeerNotifier) TransactionConfirmed(tx *util.Tx)                       {}
+
+// newTestSyncManager returns a sync manager of a mainnet chain of only the genesis block with an empty mempool.
+func newTestSyncManager(t *testing.T) (sm *SyncManager, cleanup func()) {
+	params := &chaincfg.MainNetParams
+	dir, e := ioutil.TempDir("", "netsynctest")
+	if e != nil {
+		t.Fatal(e)
+	}
+	db, e := database.Create("ffldb", filepath.Join(dir, "db"), params.Net)
+	if e != nil {
+		os.RemoveAll(dir)
+		t.Fatal(e)
+	}
+	cleanup = func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+	chain, e := blockchain.New(
+		&blockchain.Config{DB: db, ChainParams: params, TimeSource: blockchain.NewMedianTime()},
+	)
+	if e != nil {
+		cleanup()
+		t.Fatal(e)
+	}
+	if sm, e = New(
+		&Config{
+			PeerNotifier: fakePeerNotifier{},
+			Chain:        chain,
+			TxMemPool:    mempool.New(&mempool.Config{ChainParams: params}),
+			ChainParams:  params,
+			MaxPeers:     8,
+		},
+	); e != nil {
+		cleanup()
+		t.Fatal(e)
+	}
+	return
+}
+
+// connectPeer returns a peer connected to a remote peer, whose messages are sent to received, after they exchanged
+// their versions. The remote peer only supports compact blocks when cmpct is set. The peer is added to the
+// sync manager if it is not nil.
+func connectPeer(t *testing.T, sm *SyncManager, cmpct bool) (p *peerpkg.Peer, received chan wire.Message) {
+	verack := qu.Ts(2)
+	sendCmpct := qu.Ts(1)
+	received = make(chan wire.Message, 100)
+	remoteCfg := &peerpkg.Config{
+		Listeners: peerpkg.MessageListeners{
+			OnVerAck: func(p *peerpkg.Peer, msg *wire.MsgVerAck) {
+				verack <- struct{}{}
+			},
+			OnRead: func(p *peerpkg.Peer, bytesRead int, msg wire.Message, e error) {
+				if msg != nil {
+					received <- msg
+				}
+			},
+		},
+		ChainParams:     &chaincfg.MainNetParams,
+		Services:        wire.SFNodeNetwork,
+		TrickleInterval: time.Second * 10,
+	}
+	cfg := &peerpkg.Config{
+		Listeners: peerpkg.MessageListeners{
+			OnVerAck: func(p *peerpkg.Peer, msg *wire.MsgVerAck) {
+				verack <- struct{}{}
+			},
+			OnSendCmpct: func(p *peerpkg.Peer, msg *wire.MsgSendCmpct) {
+				sendCmpct <- struct{}{}
+			},
+		},
+		ChainParams:     &chaincfg.MainNetParams,
+		TrickleInterval: time.Second * 10,
+	}
+	inConn, outConn := pipe(
+		&conn{laddr: "10.0.0.2:11047", raddr: "10.0.0.1:11047"},
+		&conn{laddr: "10.0.0.1:11047", raddr: "10.0.0.2:11047"},
+	)
+	if !cmpct {
+		remoteCfg.ProtocolVersion = wire.SendCmpctVersion - 1
+	}
+	remote := peerpkg.NewInboundPeer(remoteCfg)
+	remote.AssociateConnection(inConn)
+	p, e := peerpkg.NewOutboundPeer(cfg, "10.0.0.1:11047")
+	if e != nil {
+		t.Fatal(e)
+	}
+	p.AssociateConnection(outConn)
+	for i := 0; i < 2; i++ {
+		select {
+		case <-verack.Wait():
+		case <-time.After(time.Second * 5):
+			t.Fatal("verack timeout")
+		}
+	}
+	// peers announce their support for compact blocks once they exchanged their versions
+	if cmpct {
+		expectMsg(t, received, wire.CmdSendCmpct)
+		select {
+		case <-sendCmpct.Wait():
+		case <-time.After(time.Second * 5):
+			t.Fatal("sendcmpct timeout")
+		}
+	}
+	if sm != nil {
+		sm.peerStates[p] = &peerSyncState{
+			requestedTxns:   make(map[chainhash.Hash]struct{}),
+			requestedBlocks: make(map[chainhash.Hash]struct{}),
+			compactBlocks:   make(map[chainhash.Hash]*mempool.CompactBlock),
+			blockDownloads:  make(map[chainhash.Hash]*blockDownload),
+		}
+	}
+	return
+}
+
+// expectMsg returns the next message with the command the remote peer received, skipping the others.
+func expectMsg(t *testing.T, received chan wire.Message, command string) wire.Message {
+	timeout := time.After(time.Second * 5)
+	for {
+		select {
+		case msg := <-received:
+			if msg.Command() == command {
+				return msg
+			}
+		case <-timeout:
+			t.Fatalf("no %s message received", command)
+			return nil
+		}
+	}
+}
+
+// testBlock returns a block on top of the tip of the chain of the sync manager with only a coinbase, the bits the difficulty adjustment
+// requires and a valid proof of work.
+func testBlock(t *testing.T, sm *SyncManager) *wire.Block {
+	chain := sm.chain
+	best := chain.BestSnapshot()
+	height := best.Height + 1
+	tip, e := chain.BlockByHash(&best.Hash)
+	if e != nil {
+		t.Fatal(e)
+	}
+	coinbase := wire.NewMsgTx(1)
+	coinbase.AddTxIn(
+		&wire.TxIn{
+			PreviousOutPoint: *wire.NewOutPoint(&chainhash.Hash{}, wire.MaxPrevOutIndex),
+			SignatureScript:  []byte{1, byte(height)},
+			Sequence:         wire.MaxTxInSequenceNum,
+		},
+	)
+	coinbase.AddTxOut(wire.NewTxOut(blockchain.CalcBlockSubsidy(height, sm.chainParams, 2), []byte{0x51}))
+	blk := &wire.Block{
+		Header: wire.BlockHeader{
+			Version:   2,
+			PrevBlock: best.Hash,
+			Timestamp: tip.WireBlock().Header.Timestamp.Add(time.Minute),
+		},
+		Transactions: []*wire.MsgTx{coinbase},
+	}
+	blk.Header.MerkleRoot = coinbase.TxHash()
+	if blk.Header.Bits, e = chain.CalcNextRequiredDifficulty(fork.GetAlgoName(2, height)); e != nil {
+		t.Fatal(e)
+	}
+	target := bits.CompactToBig(blk.Header.Bits)
+	for blk.Header.Nonce = 0; ; blk.Header.Nonce++ {
+		hash := blk.Header.BlockHashWithAlgos(height)
+		if blockchain.HashToBig(&hash).Cmp(target) <= 0 {
+			break
+		}
+		if blk.Header.Nonce == ^uint32(0) {
+			t.Fatal("no nonce meets the target")
+		}
+	}
+	return blk
+}
+
+// cmpctBlock returns a compact block of a block with nothing prefilled, so every transaction has to be requested.
+func cmpctBlock(blk *wire.Block) *wire.MsgCmpctBlock {
+	msg := &wire.MsgCmpctBlock{Header: blk.Header, Nonce: 1}
+	key := msg.ShortIDKey()
+	for _, tx := range blk.Transactions {
+		txHash := tx.TxHash()
+		msg.ShortIDs = append(msg.ShortIDs, wire.ShortID(&key, &txHash))
+	}
+	return msg
+}
+
+// TestCompactBlocks checks that compact blocks are only taken from peers that were asked for them, that the missing
+// transactions are requested and complete the block, and that the block is requested in full when they do not match
+// it or too many compact blocks are waiting for their transactions.
+func TestCompactBlocks(t *testing.T) {
+	sm, cleanup := newTestSyncManager(t)
+	defer cleanup()
+	blk := testBlock(t, sm)
+	blockHash := blk.BlockHash()
+	cmsg := cmpctBlock(blk)
+	p, received := connectPeer(t, sm, true)
+	defer p.Disconnect()
+	state := sm.peerStates[p]
+	// compact blocks of unknown peers and those pushed by peers that were not asked to are ignored
+	unknown, _ := connectPeer(t, nil, true)
+	defer unknown.Disconnect()
+	sm.handleCmpctBlockMsg(0, &cmpctBlockMsg{msg: cmsg, peer: unknown})
+	sm.handleCmpctBlockMsg(0, &cmpctBlockMsg{msg: cmsg, peer: p})
+	if len(state.compactBlocks) != 0 || len(sm.requestedBlocks) != 0 {
+		t.Fatal("unrequested compact block was not ignored")
+	}
+	// the missing coinbase of a requested compact block is asked for
+	sm.markBlockRequested(state, &blockHash)
+	sm.handleCmpctBlockMsg(0, &cmpctBlockMsg{msg: cmsg, peer: p})
+	getBlockTxn := expectMsg(t, received, wire.CmdGetBlockTxn).(*wire.MsgGetBlockTxn)
+	if getBlockTxn.BlockHash != blockHash || len(getBlockTxn.Indexes) != 1 || getBlockTxn.Indexes[0] != 0 {
+		t.Fatalf("unexpected getblocktxn %+v", getBlockTxn)
+	}
+	if _, exists := state.compactBlocks[blockHash]; !exists {
+		t.Fatal("compact block waiting for its transactions was not kept")
+	}
+	// transactions that do not match the block get it requested in full
+	wrong := blk.Transactions[0].Copy()
+	wrong.TxOut[0].Value--
+	sm.handleBlockTxnMsg(0, &blockTxnMsg{msg: wire.NewMsgBlockTxn(&blockHash, []*wire.MsgTx{wrong}), peer: p})
+	getData := expectMsg(t, received, wire.CmdGetData).(*wire.MsgGetData)
+	if len(getData.InvList) != 1 || getData.InvList[0].Hash != blockHash {
+		t.Fatalf("unexpected getdata %+v", getData)
+	}
+	if len(state.compactBlocks) != 0 {
+		t.Fatal("compact block with the wrong transactions was kept")
+	}
+	// the block completed with the right transactions is connected
+	sm.handleCmpctBlockMsg(0, &cmpctBlockMsg{msg: cmsg, peer: p})
+	expectMsg(t, received, wire.CmdGetBlockTxn)
+	sm.handleBlockTxnMsg(0, &blockTxnMsg{msg: wire.NewMsgBlockTxn(&blockHash, blk.Transactions), peer: p})
+	if best := sm.chain.BestSnapshot(); best.Hash != blockHash {
+		t.Fatalf("best block is %v at height %d, expected the compact block", best.Hash, best.Height)
+	}
+	if len(state.compactBlocks) != 0 || len(state.requestedBlocks) != 0 || len(sm.requestedBlocks) != 0 {
+		t.Fatal("connected compact block is still requested")
+	}
+	// a peer asked to push compact blocks can send them unannounced, which are requested in full when too many are
+	// waiting for their transactions
+	p.PushSendCmpctMsg(true)
+	for i := 0; i <= maxCompactBlocksInFlight; i++ {
+		pending := cmpctBlock(blk)
+		pending.Header.Nonce += uint32(i + 1)
+		pendingHash := pending.BlockHash()
+		sm.handleCmpctBlockMsg(0, &cmpctBlockMsg{msg: pending, peer: p})
+		if i < maxCompactBlocksInFlight {
+			expectMsg(t, received, wire.CmdGetBlockTxn)
+			continue
+		}
+		getData = expectMsg(t, received, wire.CmdGetData).(*wire.MsgGetData)
+		if len(getData.InvList) != 1 || getData.InvList[0].Hash != pendingHash {
+			t.Fatalf("unexpected getdata %+v", getData)
+		}
+	}
+	if len(state.compactBlocks) != maxCompactBlocksInFlight {
+		t.Fatalf("%d compact blocks are waiting, expected %d", len(state.compactBlocks), maxCompactBlocksInFlight)
+	}
+	// transactions of a block that was not announced as a compact block get the peer disconnected
+	other, _ := connectPeer(t, sm, true)
+	sm.handleBlockTxnMsg(0, &blockTxnMsg{msg: wire.NewMsgBlockTxn(&blockHash, blk.Transactions), peer: other})
+	if other.Connected() {
+		t.Fatal("peer sending unrequested transactions was not disconnected")
+	}
+}
+
+// TestUpdateHighBandwidthPeers checks that the peers that delivered the latest tips are asked to push compact blocks,
+// that the least recent of them is asked to stop when there are too many, and that peers leave the list when they
+// disconnect.
+func TestUpdateHighBandwidthPeers(t *testing.T) {
+	sm, cleanup := newTestSyncManager(t)
+	defer cleanup()
+	unsupported, _ := connectPeer(t, sm, false)
+	defer unsupported.Disconnect()
+	sm.updateHighBandwidthPeers(unsupported)
+	if len(sm.highBandwidthPeers) != 0 {
+		t.Fatal("peer without compact blocks was asked to push them")
+	}
+	peers := make([]*peerpkg.Peer, maxHighBandwidthPeers+1)
+	received := make([]chan wire.Message, len(peers))
+	for i := range peers {
+		peers[i], received[i] = connectPeer(t, sm, true)
+		defer peers[i].Disconnect()
+	}
+	expectSendCmpct := func(i int, highBandwidth bool) {
+		msg := expectMsg(t, received[i], wire.CmdSendCmpct).(*wire.MsgSendCmpct)
+		if msg.AnnounceUsingCmpctBlock != highBandwidth {
+			t.Fatalf("peer %d got sendcmpct %v, expected %v", i, msg.AnnounceUsingCmpctBlock, highBandwidth)
+		}
+	}
+	expectPeers := func(expected ...int) {
+		if len(sm.highBandwidthPeers) != len(expected) {
+			t.Fatalf("%d high bandwidth peers, expected %d", len(sm.highBandwidthPeers), len(expected))
+		}
+		for i, j := range expected {
+			if sm.highBandwidthPeers[i] != peers[j] {
+				t.Fatalf("high bandwidth peer %d is not peer %d", i, j)
+			}
+		}
+	}
+	for i := 0; i < maxHighBandwidthPeers; i++ {
+		sm.updateHighBandwidthPeers(peers[i])
+		expectSendCmpct(i, true)
+		if !peers[i].CmpctBlocksRequested() {
+			t.Fatalf("peer %d is not marked as asked for compact blocks", i)
+		}
+	}
+	expectPeers(0, 1, 2)
+	// a peer delivering another tip becomes the most recent one
+	sm.updateHighBandwidthPeers(peers[0])
+	expectPeers(1, 2, 0)
+	// the least recent peer is asked to stop when one more is added
+	sm.updateHighBandwidthPeers(peers[3])
+	expectSendCmpct(3, true)
+	expectSendCmpct(1, false)
+	expectPeers(2, 0, 3)
+	if peers[1].CmpctBlocksRequested() {
+		t.Fatal("peer asked to stop is still marked as asked for compact blocks")
+	}
+	sm.handleDonePeerMsg(peers[0])
+	expectPeers(2, 3)
+}
@@ -110,6 +110,9 @@ func messageSummary(msg wire.Message) string {
 	case *wire.MsgAddr:
 		return fmt.Sprintf("%d addr", len(msg.AddrList))
 	
+	case *wire.MsgAddrV2:
+		return fmt.Sprintf("%d addr", len(msg.AddrList))
+	
 	case *wire.MsgPing:
 		// No summary - perhaps add Nonce.
 	
@@ -152,6 +155,22 @@ func messageSummary(msg wire.Message) string {
 	case *wire.MsgHeaders:
 		return fmt.Sprintf("num %d", len(msg.Headers))
 	
+	case *wire.MsgSendCmpct:
+		return fmt.Sprintf("announce %v, version %d",
+			msg.AnnounceUsingCmpctBlock, msg.CmpctBlockVersion,
+		)
+	
+	case *wire.MsgCmpctBlock:
+		return fmt.Sprintf("hash %s, %d short ids, %d prefilled",
+			msg.BlockHash(), len(msg.ShortIDs), len(msg.PrefilledTxs),
+		)
+	
+	case *wire.MsgGetBlockTxn:
+		return fmt.Sprintf("hash %s, %d indexes", msg.BlockHash, len(msg.Indexes))
+	
+	case *wire.MsgBlockTxn:
+		return fmt.Sprintf("hash %s, %d tx", msg.BlockHash, len(msg.Transactions))
+	
 	case *wire.MsgGetCFHeaders:
 		return fmt.Sprintf("start_height=%d, stop_hash=%v",
 			msg.StartHeight, msg.StopHash,
@@ -25,7 +25,7 @@ import (
 
 const (
 	// MaxProtocolVersion is the max protocol version the peer supports.
-	MaxProtocolVersion = wire.FeeFilterVersion
+	MaxProtocolVersion = wire.AddrV2Version
 	// DefaultTrickleInterval is the min time between attempts to send an inv message to a peer.
 	DefaultTrickleInterval = time.Second
 	// MinAcceptableProtocolVersion is the lowest protocol version that a connected peer may support.
@@ -78,6 +78,10 @@ type MessageListeners struct {
 	OnGetAddr func(p *Peer, msg *wire.MsgGetAddr)
 	// OnAddr is invoked when a peer receives an addr bitcoin message.
 	OnAddr func(p *Peer, msg *wire.MsgAddr)
+	// OnSendAddrV2 is invoked when a peer receives a sendaddrv2 bitcoin message.
+	OnSendAddrV2 func(p *Peer, msg *wire.MsgSendAddrV2)
+	// OnAddrV2 is invoked when a peer receives an addrv2 bitcoin message.
+	OnAddrV2 func(p *Peer, msg *wire.MsgAddrV2)
 	// OnPing is invoked when a peer receives a ping bitcoin message.
 	OnPing func(p *Peer, msg *wire.MsgPing)
 	// OnPong is invoked when a peer receives a pong bitcoin message.
@@ -142,6 +146,14 @@ type MessageListeners struct {
 	// OnSendHeaders is invoked when a peer receives a sendheaders bitcoin
 	// message.
 	OnSendHeaders func(p *Peer, msg *wire.MsgSendHeaders)
+	// OnSendCmpct is invoked when a peer receives a sendcmpct bitcoin message.
+	OnSendCmpct func(p *Peer, msg *wire.MsgSendCmpct)
+	// OnCmpctBlock is invoked when a peer receives a cmpctblock bitcoin message.
+	OnCmpctBlock func(p *Peer, msg *wire.MsgCmpctBlock)
+	// OnGetBlockTxn is invoked when a peer receives a getblocktxn bitcoin message.
+	OnGetBlockTxn func(p *Peer, msg *wire.MsgGetBlockTxn)
+	// OnBlockTxn is invoked when a peer receives a blocktxn bitcoin message.
+	OnBlockTxn func(p *Peer, msg *wire.MsgBlockTxn)
 	// OnRead is invoked when a peer receives a bitcoin message.
 	//
 	// It consists of the number of bytes read, the message, and whether or not an error in the read occurred.
@@ -356,6 +368,10 @@ type Peer struct {
 	advertisedProtoVer   uint32 // protocol version advertised by remote
 	protocolVersion      uint32 // negotiated protocol version
 	sendHeadersPreferred bool   // peer sent a sendheaders message
+	wantsAddrV2          bool   // peer sent a sendaddrv2 message before its verack
+	cmpctBlockVersion    uint64 // compact block version of the last supported sendcmpct message from the peer
+	cmpctBlocksWanted    bool   // peer asked for new blocks to be pushed as compact blocks
+	cmpctBlocksRequested bool   // we asked the peer to push new blocks as compact blocks
 	verAckReceived       bool
 	witnessEnabled       bool
 	wireEncoding         wire.MessageEncoding
@@ -429,6 +445,13 @@ func (p *Peer) AddKnownInventory(invVect *wire.InvVect) {
 	p.knownInventory.Add(invVect)
 }
 
+// KnowsInventory returns whether the peer is known to have the passed inventory.
+//
+// This function is safe for concurrent access.
+func (p *Peer) KnowsInventory(invVect *wire.InvVect) bool {
+	return p.knownInventory.Exists(invVect)
+}
+
 // StatsSnapshot returns a snapshot of the current peer flags and statistics.
 //
 // This function is safe for concurrent access.
@@ -676,6 +699,42 @@ func (p *Peer) WantsHeaders() bool {
 	return sendHeadersPreferred
 }
 
+// WantsAddrV2 returns whether the peer asked for addresses to be sent in addrv2 messages rather than addr messages. This
+// function is safe for concurrent access.
+func (p *Peer) WantsAddrV2() bool {
+	p.flagsMtx.Lock()
+	wantsAddrV2 := p.wantsAddrV2
+	p.flagsMtx.Unlock()
+	return wantsAddrV2
+}
+
+// SupportsCmpctBlocks returns whether the peer announced that it supports the version of compact blocks this package
+// supports. This function is safe for concurrent access.
+func (p *Peer) SupportsCmpctBlocks() bool {
+	p.flagsMtx.Lock()
+	supported := p.cmpctBlockVersion == wire.CmpctBlockVersion
+	p.flagsMtx.Unlock()
+	return supported
+}
+
+// WantsCmpctBlocks returns whether the peer asked for new blocks to be pushed to it as compact blocks without
+// announcing them first (BIP0152 high bandwidth mode). This function is safe for concurrent access.
+func (p *Peer) WantsCmpctBlocks() bool {
+	p.flagsMtx.Lock()
+	wanted := p.cmpctBlocksWanted
+	p.flagsMtx.Unlock()
+	return wanted
+}
+
+// CmpctBlocksRequested returns whether we asked the peer to push new blocks to us as compact blocks without announcing
+// them first. This function is safe for concurrent access.
+func (p *Peer) CmpctBlocksRequested() bool {
+	p.flagsMtx.Lock()
+	requested := p.cmpctBlocksRequested
+	p.flagsMtx.Unlock()
+	return requested
+}
+
 // // IsWitnessEnabled returns true if the peer has signalled that it supports
 // // segregated witness. This function is safe for concurrent access.
 // func (p *Peer) IsWitnessEnabled() bool {
@@ -686,34 +745,61 @@ func (p *Peer) WantsHeaders() bool {
 // }
 
 // PushAddrMsg sends an addr message to the connected peer using the provided
-// addresses.
+// addresses, or an addrv2 message if the peer asked for them with a sendaddrv2 message.
 //
 // This function is useful over manually sending the message via QueueMessage since it automatically limits the
 // addresses to the maximum number allowed by the message and randomizes the chosen addresses when there are too many.
+// Addresses that are not IP addresses, such as Tor v3 addresses, are left out of addr messages.
 //
 // It returns the addresses that were actually sent and no message will be sent if there are no entries in the provided
 // addresses slice. This function is safe for concurrent access.
 func (p *Peer) PushAddrMsg(addresses []*wire.NetAddress) ([]*wire.NetAddress, error) {
-	addressCount := len(addresses)
+	var addrList []*wire.NetAddress
+	wantsAddrV2 := p.WantsAddrV2()
+	for _, na := range addresses {
+		if wantsAddrV2 || na.IsAddrV1Compatible() {
+			addrList = append(addrList, na)
+		}
+	}
+	addressCount := len(addrList)
 	// Nothing to send.
 	if addressCount == 0 {
 		return nil, nil
 	}
-	msg := wire.NewMsgAddr()
-	msg.AddrList = make([]*wire.NetAddress, addressCount)
-	copy(msg.AddrList, addresses)
 	// Randomize the addresses sent if there are more than the maximum allowed.
 	if addressCount > wire.MaxAddrPerMsg {
 		// Shuffle the address list.
 		for i := 0; i < wire.MaxAddrPerMsg; i++ {
 			j := i + rand.Intn(addressCount-i)
-			msg.AddrList[i], msg.AddrList[j] = msg.AddrList[j], msg.AddrList[i]
+			addrList[i], addrList[j] = addrList[j], addrList[i]
 		}
 		// Truncate it to the maximum size.
-		msg.AddrList = msg.AddrList[:wire.MaxAddrPerMsg]
+		addrList = addrList[:wire.MaxAddrPerMsg]
 	}
-	p.QueueMessage(msg, nil)
-	return msg.AddrList, nil
+	if wantsAddrV2 {
+		msg := wire.NewMsgAddrV2()
+		msg.AddrList = addrList
+		p.QueueMessage(msg, nil)
+	} else {
+		msg := wire.NewMsgAddr()
+		msg.AddrList = addrList
+		p.QueueMessage(msg, nil)
+	}
+	return addrList, nil
+}
+
+// PushSendCmpctMsg sends a sendcmpct message announcing support for compact blocks to the connected peer, asking it to
+// push new blocks as compact blocks when highBandwidth is set, or to announce them with inv or headers messages
+// otherwise. Nothing is sent if the negotiated protocol version does not support compact blocks. This function is safe
+// for concurrent access.
+func (p *Peer) PushSendCmpctMsg(highBandwidth bool) {
+	if p.ProtocolVersion() < wire.SendCmpctVersion {
+		return
+	}
+	p.flagsMtx.Lock()
+	p.cmpctBlocksRequested = highBandwidth
+	p.flagsMtx.Unlock()
+	p.QueueMessage(wire.NewMsgSendCmpct(highBandwidth, wire.CmpctBlockVersion), nil)
 }
 
 // PushGetBlocksMsg sends a getblocks message for the provided block locator and stop hash. It will ignore back-to-back
@@ -1027,6 +1113,9 @@ func (p *Peer) maybeAddDeadline(pendingResponses map[string]time.Time, msgCmd st
 		pendingResponses[wire.CmdMerkleBlock] = deadline
 		pendingResponses[wire.CmdTx] = deadline
 		pendingResponses[wire.CmdNotFound] = deadline
+	case wire.CmdGetBlockTxn:
+		// Expects a blocktxn message.
+		pendingResponses[wire.CmdBlockTxn] = deadline
 	case wire.CmdGetHeaders:
 		// Expects a headers message.
 		//
@@ -1079,6 +1168,8 @@ out:
 				switch msgCmd := msg.message.Command(); msgCmd {
 				case wire.CmdBlock:
 					fallthrough
+				case wire.CmdCmpctBlock:
+					fallthrough
 				case wire.CmdMerkleBlock:
 					fallthrough
 				case wire.CmdTx:
@@ -1252,6 +1343,20 @@ out:
 			if p.cfg.Listeners.OnAddr != nil {
 				p.cfg.Listeners.OnAddr(p, msg)
 			}
+		case *wire.MsgSendAddrV2:
+			// The message is only valid before the verack message, later ones are ignored.
+			if !p.verAckReceived {
+				p.flagsMtx.Lock()
+				p.wantsAddrV2 = true
+				p.flagsMtx.Unlock()
+			}
+			if p.cfg.Listeners.OnSendAddrV2 != nil {
+				p.cfg.Listeners.OnSendAddrV2(p, msg)
+			}
+		case *wire.MsgAddrV2:
+			if p.cfg.Listeners.OnAddrV2 != nil {
+				p.cfg.Listeners.OnAddrV2(p, msg)
+			}
 		case *wire.MsgPing:
 			p.handlePingMsg(msg)
 			if p.cfg.Listeners.OnPing != nil {
@@ -1353,6 +1458,29 @@ out:
 			if p.cfg.Listeners.OnSendHeaders != nil {
 				p.cfg.Listeners.OnSendHeaders(p, msg)
 			}
+		case *wire.MsgSendCmpct:
+			// Versions of compact blocks that are not supported are ignored, as required by BIP0152.
+			if msg.CmpctBlockVersion == wire.CmpctBlockVersion {
+				p.flagsMtx.Lock()
+				p.cmpctBlockVersion = msg.CmpctBlockVersion
+				p.cmpctBlocksWanted = msg.AnnounceUsingCmpctBlock
+				p.flagsMtx.Unlock()
+			}
+			if p.cfg.Listeners.OnSendCmpct != nil {
+				p.cfg.Listeners.OnSendCmpct(p, msg)
+			}
+		case *wire.MsgCmpctBlock:
+			if p.cfg.Listeners.OnCmpctBlock != nil {
+				p.cfg.Listeners.OnCmpctBlock(p, msg)
+			}
+		case *wire.MsgGetBlockTxn:
+			if p.cfg.Listeners.OnGetBlockTxn != nil {
+				p.cfg.Listeners.OnGetBlockTxn(p, msg)
+			}
+		case *wire.MsgBlockTxn:
+			if p.cfg.Listeners.OnBlockTxn != nil {
+				p.cfg.Listeners.OnBlockTxn(p, msg)
+			}
 		default:
 			D.F(
 				"Received unhandled message of type %v from %v %s",
@@ -1871,10 +1999,12 @@ func (p *Peer) start(msgChan chan *wire.MsgVersion) (e error) {
 		if p.inbound {
 			if msg, ee = p.negotiateInboundProtocol(); E.Chk(ee) {
 				negotiateErr <- ee
+				return
 			}
 		} else {
-			if msg, e = p.negotiateOutboundProtocol(); E.Chk(ee) {
+			if msg, ee = p.negotiateOutboundProtocol(); E.Chk(ee) {
 				negotiateErr <- ee
+				return
 			}
 		}
 		I.Ln("sending version message back")
@@ -1903,8 +2033,14 @@ func (p *Peer) start(msgChan chan *wire.MsgVersion) (e error) {
 	go p.queueHandler()
 	go p.outHandler()
 	go p.pingHandler()
+	// Ask for addresses in addrv2 messages, which has to be done before the verack message.
+	if p.ProtocolVersion() >= wire.AddrV2Version {
+		p.QueueMessage(wire.NewMsgSendAddrV2(), nil)
+	}
 	// Send our verack message now that the IO processing machinery has started.
 	p.QueueMessage(wire.NewMsgVerAck(), nil)
+	// Announce support for compact blocks in low bandwidth mode. The peer can be asked to push new blocks later on.
+	p.PushSendCmpctMsg(false)
 	return
 }
 
@@ -0,0 +1,242 @@
+// Package pool keeps the share ledger of a mining controller in pool mode and splits block rewards between the payout
+// addresses of the miners by pay per last N shares (PPLNS).
+package pool
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/p9c/pod/pkg/database"
+)
+
+// sharesBucketName is the name of the metadata bucket of the node database that holds the share ledger
+var sharesBucketName = []byte("poolshares")
+
+const (
+	// shareHeaderSize is the size of the fixed part of a serialized share, the height, the block version and the
+	// weight
+	shareHeaderSize = 4 + 4 + 8
+	// seenHeights is how many of the most recent heights the work of the added shares is kept for to find duplicates
+	seenHeights = 4
+)
+
+var (
+	// ErrDuplicateShare is returned by Add for a share with the same work as one that was already added
+	ErrDuplicateShare = errors.New("duplicate share")
+	// ErrStaleShare is returned by Add for a share for a height older than those duplicates are looked for in
+	ErrStaleShare = errors.New("stale share")
+)
+
+// Share is an entry in the share ledger, a hash found by a miner that met the share target of a template
+type Share struct {
+	// Address is the payout address the share is credited to
+	Address string
+	// Weight is the difficulty of the share relative to the minimum difficulty of its algorithm
+	Weight float64
+	// Height is the height of the block the share was found for
+	Height int32
+	// Version is the block version, and thus the algorithm, of the share
+	Version int32
+	// Work identifies the work that was done for the share, everything the miner varied to find it, so the same work
+	// is only credited once. It is not stored in the ledger.
+	Work string
+}
+
+// Credit is the total weight of the shares of one payout address within a PPLNS window
+type Credit struct {
+	Address string
+	Weight  float64
+}
+
+// Ledger records shares in order in its own bucket of the node database, so they are kept across restarts. Every share
+// is written in its own transaction as it is added, so the shares that were credited are not lost if the node stops.
+type Ledger struct {
+	mx  sync.Mutex
+	db  database.DB
+	seq uint64
+	// seen is the work of the shares added for each of the seenHeights most recent heights, up to seenHeight
+	seen       map[int32]map[string]struct{}
+	seenHeight int32
+}
+
+// NewLedger opens the share ledger in the given database, creating its bucket if it does not exist yet
+func NewLedger(db database.DB) (l *Ledger, e error) {
+	l = &Ledger{db: db, seen: make(map[int32]map[string]struct{})}
+	if e = db.Update(
+		func(tx database.Tx) (e error) {
+			var bucket database.Bucket
+			if bucket, e = tx.Metadata().CreateBucketIfNotExists(sharesBucketName); E.Chk(e) {
+				return
+			}
+			// new shares are numbered on from the last one in the ledger
+			cursor := bucket.Cursor()
+			if cursor.Last() {
+				l.seq = binary.BigEndian.Uint64(cursor.Key())
+			}
+			return
+		},
+	); E.Chk(e) {
+		return nil, e
+	}
+	return
+}
+
+// Add appends a share to the ledger, or returns ErrDuplicateShare if a share with the same work was already added for
+// the same height. Duplicates are looked for among the shares of the seenHeights most recent heights, shares for
+// heights older than those are refused with ErrStaleShare. The share is written to the database before Add returns.
+func (l *Ledger) Add(sh *Share) (e error) {
+	if sh.Weight <= 0 || math.IsInf(sh.Weight, 0) || math.IsNaN(sh.Weight) {
+		return errors.New("share weight must be a positive number")
+	}
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	if sh.Height > l.seenHeight {
+		l.seenHeight = sh.Height
+		for height := range l.seen {
+			if height <= l.seenHeight-seenHeights {
+				delete(l.seen, height)
+			}
+		}
+	}
+	if sh.Height <= l.seenHeight-seenHeights {
+		return ErrStaleShare
+	}
+	seen := l.seen[sh.Height]
+	if sh.Work != "" {
+		if _, ok := seen[sh.Work]; ok {
+			return ErrDuplicateShare
+		}
+	}
+	if e = l.db.Update(
+		func(tx database.Tx) (e error) {
+			key := make([]byte, 8)
+			binary.BigEndian.PutUint64(key, l.seq+1)
+			return tx.Metadata().Bucket(sharesBucketName).Put(key, serializeShare(sh))
+		},
+	); E.Chk(e) {
+		return
+	}
+	l.seq++
+	if sh.Work != "" {
+		if seen == nil {
+			seen = make(map[string]struct{})
+			l.seen[sh.Height] = seen
+		}
+		seen[sh.Work] = struct{}{}
+	}
+	return
+}
+
+// Len returns the number of shares in the ledger
+func (l *Ledger) Len() (n int, e error) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	e = l.db.View(
+		func(tx database.Tx) (e error) {
+			return tx.Metadata().Bucket(sharesBucketName).ForEach(
+				func(k, v []byte) (e error) {
+					n++
+					return
+				},
+			)
+		},
+	)
+	return
+}
+
+// Credits returns the total weight for each payout address in the most recent shares that add up to the given window
+// of weight, ordered by weight from the largest. The oldest share in the window only counts with the part of its
+// weight that fits in the window. It also returns the sequence number of the oldest share in the window, which is zero
+// when the shares in the ledger do not fill it.
+func (l *Ledger) Credits(window float64) (credits []Credit, oldest uint64, e error) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	weights := make(map[string]float64)
+	e = l.db.View(
+		func(tx database.Tx) (e error) {
+			cursor := tx.Metadata().Bucket(sharesBucketName).Cursor()
+			remaining := window
+			for ok := cursor.Last(); ok && remaining > 0; ok = cursor.Prev() {
+				var sh *Share
+				if sh, e = deserializeShare(cursor.Value()); E.Chk(e) {
+					return
+				}
+				weight := sh.Weight
+				if weight >= remaining {
+					weight = remaining
+					oldest = binary.BigEndian.Uint64(cursor.Key())
+				}
+				weights[sh.Address] += weight
+				remaining -= weight
+			}
+			return
+		},
+	)
+	if e != nil {
+		return
+	}
+	for addr, weight := range weights {
+		credits = append(credits, Credit{Address: addr, Weight: weight})
+	}
+	sort.Slice(
+		credits, func(i, j int) bool {
+			if credits[i].Weight == credits[j].Weight {
+				return credits[i].Address < credits[j].Address
+			}
+			return credits[i].Weight > credits[j].Weight
+		},
+	)
+	return
+}
+
+// Prune removes the shares older than the one with the given sequence number from the ledger
+func (l *Ledger) Prune(oldest uint64) (e error) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	return l.db.Update(
+		func(tx database.Tx) (e error) {
+			bucket := tx.Metadata().Bucket(sharesBucketName)
+			var keys [][]byte
+			cursor := bucket.Cursor()
+			for ok := cursor.First(); ok && binary.BigEndian.Uint64(cursor.Key()) < oldest; ok = cursor.Next() {
+				keys = append(keys, append([]byte{}, cursor.Key()...))
+			}
+			for i := range keys {
+				if e = bucket.Delete(keys[i]); E.Chk(e) {
+					return
+				}
+			}
+			if len(keys) > 0 {
+				D.Ln("pruned", len(keys), "shares from the pool share ledger")
+			}
+			return
+		},
+	)
+}
+
+// serializeShare encodes a share for the ledger as the height, the version and the weight, followed by the address
+func serializeShare(sh *Share) (b []byte) {
+	b = make([]byte, shareHeaderSize+len(sh.Address))
+	binary.BigEndian.PutUint32(b[0:4], uint32(sh.Height))
+	binary.BigEndian.PutUint32(b[4:8], uint32(sh.Version))
+	binary.BigEndian.PutUint64(b[8:16], math.Float64bits(sh.Weight))
+	copy(b[shareHeaderSize:], sh.Address)
+	return
+}
+
+// deserializeShare decodes a share stored in the ledger
+func deserializeShare(b []byte) (sh *Share, e error) {
+	if len(b) < shareHeaderSize {
+		return nil, errors.New("share ledger entry is too short")
+	}
+	sh = &Share{
+		Height:  int32(binary.BigEndian.Uint32(b[0:4])),
+		Version: int32(binary.BigEndian.Uint32(b[4:8])),
+		Weight:  math.Float64frombits(binary.BigEndian.Uint64(b[8:16])),
+		Address: string(b[shareHeaderSize:]),
+	}
+	return
+}
@@ -0,0 +1,164 @@
+package pool
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/p9c/pod/pkg/database"
+	_ "github.com/p9c/pod/pkg/database/ffldb"
+	"github.com/p9c/pod/pkg/wire"
+)
+
+// TestLedger checks the credits in PPLNS windows of different sizes, that the ledger survives reopening the database
+// and that pruning removes only the shares older than the window.
+func TestLedger(t *testing.T) {
+	dir, e := ioutil.TempDir("", "poolledger")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+	dbPath := filepath.Join(dir, "db")
+	db, e := database.Create("ffldb", dbPath, wire.MainNet)
+	if e != nil {
+		t.Fatal(e)
+	}
+	l, e := NewLedger(db)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if e = l.Add(&Share{Address: "a", Weight: 0}); e == nil {
+		t.Fatal("share with zero weight was added")
+	}
+	// oldest first
+	shares := []Share{
+		{Address: "a", Weight: 4, Height: 1, Version: 2},
+		{Address: "b", Weight: 2, Height: 1, Version: 514},
+		{Address: "a", Weight: 1, Height: 2, Version: 2},
+		{Address: "c", Weight: 3, Height: 2, Version: 2},
+	}
+	for i := range shares {
+		if e = l.Add(&shares[i]); e != nil {
+			t.Fatal(e)
+		}
+	}
+	// the window does not reach the shares from the first address, the first share in the window counts in part
+	credits, oldest, e := l.Credits(5)
+	if e != nil {
+		t.Fatal(e)
+	}
+	want := []Credit{{"c", 3}, {"a", 1}, {"b", 1}}
+	if len(credits) != len(want) {
+		t.Fatalf("credits %v, want %v", credits, want)
+	}
+	for i := range want {
+		if credits[i] != want[i] {
+			t.Fatalf("credits %v, want %v", credits, want)
+		}
+	}
+	if oldest != 2 {
+		t.Fatalf("oldest share in window %d, want 2", oldest)
+	}
+	// a window larger than the ledger counts everything and cannot be pruned
+	if credits, oldest, e = l.Credits(100); e != nil {
+		t.Fatal(e)
+	}
+	if len(credits) != 3 || credits[0] != (Credit{"a", 5}) || oldest != 0 {
+		t.Fatalf("credits %v oldest %d for a window larger than the ledger", credits, oldest)
+	}
+	if e = db.Close(); e != nil {
+		t.Fatal(e)
+	}
+	if db, e = database.Open("ffldb", dbPath, wire.MainNet); e != nil {
+		t.Fatal(e)
+	}
+	defer db.Close()
+	if l, e = NewLedger(db); e != nil {
+		t.Fatal(e)
+	}
+	if e = l.Add(&Share{Address: "d", Weight: 1, Height: 3, Version: 2}); e != nil {
+		t.Fatal(e)
+	}
+	if credits, oldest, e = l.Credits(5); e != nil {
+		t.Fatal(e)
+	}
+	if oldest != 3 || len(credits) != 3 || credits[0] != (Credit{"c", 3}) {
+		t.Fatalf("after reopening credits %v oldest %d", credits, oldest)
+	}
+	if e = l.Prune(oldest); e != nil {
+		t.Fatal(e)
+	}
+	var n int
+	if n, e = l.Len(); e != nil {
+		t.Fatal(e)
+	}
+	if n != 3 {
+		t.Fatalf("%d shares left after pruning, want 3", n)
+	}
+}
+
+// TestLedgerDuplicates checks that every share is written to the database as it is added, and that shares with the same
+// work are only added once for each height, also when the shares alternate between heights, while shares older than
+// the heights duplicates are looked for in are refused.
+func TestLedgerDuplicates(t *testing.T) {
+	dir, e := ioutil.TempDir("", "poolledger")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+	db, e := database.Create("ffldb", filepath.Join(dir, "db"), wire.MainNet)
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer db.Close()
+	l, e := NewLedger(db)
+	if e != nil {
+		t.Fatal(e)
+	}
+	stored := func() (n int) {
+		if e := db.View(
+			func(tx database.Tx) error {
+				return tx.Metadata().Bucket(sharesBucketName).ForEach(
+					func(k, v []byte) error {
+						n++
+						return nil
+					},
+				)
+			},
+		); e != nil {
+			t.Fatal(e)
+		}
+		return
+	}
+	tests := []struct {
+		name   string
+		height int32
+		work   string
+		e      error
+	}{
+		{"first share", 10, "job1 0", nil},
+		{"other work", 10, "job1 1", nil},
+		{"same work", 10, "job1 0", ErrDuplicateShare},
+		{"same nonce with another extra nonce", 10, "job1 0 extranonce2", nil},
+		{"same work for the next block", 11, "job1 0", nil},
+		{"same work again for the previous block", 10, "job1 0", ErrDuplicateShare},
+		{"same work again for the next block", 11, "job1 0", ErrDuplicateShare},
+		{"oldest height duplicates are looked for in", 11 - seenHeights + 1, "job1 0", nil},
+		{"height older than duplicates are looked for in", 11 - seenHeights, "job1 0", ErrStaleShare},
+		{"height far ahead", 20, "job2 0", nil},
+		{"height that was forgotten", 11, "job1 0", ErrStaleShare},
+	}
+	var added int
+	for _, test := range tests {
+		if e = l.Add(&Share{Address: "a", Weight: 1, Height: test.height, Work: test.work}); e != test.e {
+			t.Fatalf("%s: got %v, want %v", test.name, e, test.e)
+		}
+		if e == nil {
+			added++
+		}
+		if n := stored(); n != added {
+			t.Fatalf("%s: %d shares written, want %d", test.name, n, added)
+		}
+	}
+}
@@ -358,6 +358,39 @@ func (c *Client) GetBlockHeaderVerbose(blockHash *chainhash.Hash) (*btcjson.GetB
 	return c.GetBlockHeaderVerboseAsync(blockHash).Receive()
 }
 
+// FutureGetChainTipsResult is a future promise to deliver the result of a GetChainTipsAsync RPC invocation (or an
+// applicable error).
+type FutureGetChainTipsResult chan *response
+
+// Receive waits for the response promised by the future and returns information about all known tips in the block
+// tree.
+func (r FutureGetChainTipsResult) Receive() ([]btcjson.GetChainTipsResult, error) {
+	res, e := receiveFuture(r)
+	if e != nil {
+		return nil, e
+	}
+	// Unmarshal the result as an array of chain tip results.
+	var chainTips []btcjson.GetChainTipsResult
+	e = js.Unmarshal(res, &chainTips)
+	if e != nil {
+		return nil, e
+	}
+	return chainTips, nil
+}
+
+// GetChainTipsAsync returns an instance of a type that can be used to get the result of the RPC at some future time by
+// invoking the Receive function on the returned instance. See GetChainTips for the blocking version and more details.
+func (c *Client) GetChainTipsAsync() FutureGetChainTipsResult {
+	cmd := btcjson.NewGetChainTipsCmd()
+	return c.sendCmd(cmd)
+}
+
+// GetChainTips returns information about all known tips in the block tree, including the main chain and any branches
+// off it.
+func (c *Client) GetChainTips() ([]btcjson.GetChainTipsResult, error) {
+	return c.GetChainTipsAsync().Receive()
+}
+
 // FutureGetMempoolEntryResult is a future promise to deliver the result of a GetMempoolEntryAsync RPC invocation (or an
 // applicable error).
 type FutureGetMempoolEntryResult chan *response
@@ -602,6 +635,201 @@ func (c *Client) GetTxOut(txHash *chainhash.Hash, index uint32, mempool bool) (*
 	return c.GetTxOutAsync(txHash, index, mempool).Receive()
 }
 
+// FutureGetTxOutSetInfoResult is a future promise to deliver the result of a GetTxOutSetInfoAsync RPC invocation (or
+// an applicable error).
+type FutureGetTxOutSetInfoResult chan *response
+
+// Receive waits for the response promised by the future and returns the statistics of the unspent transaction output
+// set.
+func (r FutureGetTxOutSetInfoResult) Receive() (*btcjson.GetTxOutSetInfoResult, error) {
+	res, e := receiveFuture(r)
+	if e != nil {
+		return nil, e
+	}
+	var info btcjson.GetTxOutSetInfoResult
+	if e = js.Unmarshal(res, &info); E.Chk(e) {
+		return nil, e
+	}
+	return &info, nil
+}
+
+// GetTxOutSetInfoAsync returns an instance of a type that can be used to get the result of the RPC at some future time
+// by invoking the Receive function on the returned instance. See GetTxOutSetInfo for the blocking version and more
+// details.
+func (c *Client) GetTxOutSetInfoAsync() FutureGetTxOutSetInfoResult {
+	cmd := btcjson.NewGetTxOutSetInfoCmd()
+	return c.sendCmd(cmd)
+}
+
+// GetTxOutSetInfo returns statistics about the unspent transaction output set, including its MuHash.
+func (c *Client) GetTxOutSetInfo() (*btcjson.GetTxOutSetInfoResult, error) {
+	return c.GetTxOutSetInfoAsync().Receive()
+}
+
+// FutureGetAddressBalanceResult is a future promise to deliver the result of a GetAddressBalanceAsync RPC invocation
+// (or an applicable error).
+type FutureGetAddressBalanceResult chan *response
+
+// Receive waits for the response promised by the future and returns the balance of the addresses and the total amount
+// they have received.
+func (r FutureGetAddressBalanceResult) Receive() (*btcjson.GetAddressBalanceResult, error) {
+	res, e := receiveFuture(r)
+	if e != nil {
+		return nil, e
+	}
+	var balance btcjson.GetAddressBalanceResult
+	if e = js.Unmarshal(res, &balance); E.Chk(e) {
+		return nil, e
+	}
+	return &balance, nil
+}
+
+// GetAddressBalanceAsync returns an instance of a type that can be used to get the result of the RPC at some future
+// time by invoking the Receive function on the returned instance. See GetAddressBalance for the blocking version and
+// more details.
+func (c *Client) GetAddressBalanceAsync(addresses []string) FutureGetAddressBalanceResult {
+	cmd := btcjson.NewGetAddressBalanceCmd(addresses)
+	return c.sendCmd(cmd)
+}
+
+// GetAddressBalance returns the balance of the addresses and the total amount they have received. It requires the
+// address index to be enabled on the server.
+func (c *Client) GetAddressBalance(addresses []string) (*btcjson.GetAddressBalanceResult, error) {
+	return c.GetAddressBalanceAsync(addresses).Receive()
+}
+
+// FutureGetAddressDeltasResult is a future promise to deliver the result of a GetAddressDeltasAsync RPC invocation (or
+// an applicable error).
+type FutureGetAddressDeltasResult chan *response
+
+// Receive waits for the response promised by the future and returns the changes of the balances of the addresses.
+func (r FutureGetAddressDeltasResult) Receive() ([]btcjson.GetAddressDeltasResult, error) {
+	res, e := receiveFuture(r)
+	if e != nil {
+		return nil, e
+	}
+	var deltas []btcjson.GetAddressDeltasResult
+	if e = js.Unmarshal(res, &deltas); E.Chk(e) {
+		return nil, e
+	}
+	return deltas, nil
+}
+
+// GetAddressDeltasAsync returns an instance of a type that can be used to get the result of the RPC at some future time
+// by invoking the Receive function on the returned instance. See GetAddressDeltas for the blocking version and more
+// details.
+func (c *Client) GetAddressDeltasAsync(addresses []string, start, end int32) FutureGetAddressDeltasResult {
+	cmd := btcjson.NewGetAddressDeltasCmd(addresses, start, end)
+	return c.sendCmd(cmd)
+}
+
+// GetAddressDeltas returns the changes of the balances of the addresses in the blocks from start to end, where zero
+// means no limit. It requires the address index to be enabled on the server.
+func (c *Client) GetAddressDeltas(addresses []string, start, end int32) ([]btcjson.GetAddressDeltasResult, error) {
+	return c.GetAddressDeltasAsync(addresses, start, end).Receive()
+}
+
+// FutureGetAddressTxIDsResult is a future promise to deliver the result of a GetAddressTxIDsAsync RPC invocation (or an
+// applicable error).
+type FutureGetAddressTxIDsResult chan *response
+
+// Receive waits for the response promised by the future and returns the hashes of the transactions involving the
+// addresses.
+func (r FutureGetAddressTxIDsResult) Receive() ([]*chainhash.Hash, error) {
+	res, e := receiveFuture(r)
+	if e != nil {
+		return nil, e
+	}
+	var txids []string
+	if e = js.Unmarshal(res, &txids); E.Chk(e) {
+		return nil, e
+	}
+	hashes := make([]*chainhash.Hash, 0, len(txids))
+	for _, txid := range txids {
+		var hash *chainhash.Hash
+		if hash, e = chainhash.NewHashFromStr(txid); E.Chk(e) {
+			return nil, e
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, nil
+}
+
+// GetAddressTxIDsAsync returns an instance of a type that can be used to get the result of the RPC at some future time
+// by invoking the Receive function on the returned instance. See GetAddressTxIDs for the blocking version and more
+// details.
+func (c *Client) GetAddressTxIDsAsync(addresses []string, start, end int32) FutureGetAddressTxIDsResult {
+	cmd := btcjson.NewGetAddressTxIDsCmd(addresses, start, end)
+	return c.sendCmd(cmd)
+}
+
+// GetAddressTxIDs returns the hashes of the transactions involving the addresses in the blocks from start to end,
+// where zero means no limit. It requires the address index to be enabled on the server.
+func (c *Client) GetAddressTxIDs(addresses []string, start, end int32) ([]*chainhash.Hash, error) {
+	return c.GetAddressTxIDsAsync(addresses, start, end).Receive()
+}
+
+// FutureGetAddressUtxosResult is a future promise to deliver the result of a GetAddressUtxosAsync RPC invocation (or an
+// applicable error).
+type FutureGetAddressUtxosResult chan *response
+
+// Receive waits for the response promised by the future and returns the unspent outputs paying the addresses.
+func (r FutureGetAddressUtxosResult) Receive() ([]btcjson.GetAddressUtxosResult, error) {
+	res, e := receiveFuture(r)
+	if e != nil {
+		return nil, e
+	}
+	var utxos []btcjson.GetAddressUtxosResult
+	if e = js.Unmarshal(res, &utxos); E.Chk(e) {
+		return nil, e
+	}
+	return utxos, nil
+}
+
+// GetAddressUtxosAsync returns an instance of a type that can be used to get the result of the RPC at some future time
+// by invoking the Receive function on the returned instance. See GetAddressUtxos for the blocking version and more
+// details.
+func (c *Client) GetAddressUtxosAsync(addresses []string) FutureGetAddressUtxosResult {
+	cmd := btcjson.NewGetAddressUtxosCmd(addresses)
+	return c.sendCmd(cmd)
+}
+
+// GetAddressUtxos returns the unspent outputs paying the addresses. It requires the address index to be enabled on the
+// server.
+func (c *Client) GetAddressUtxos(addresses []string) ([]btcjson.GetAddressUtxosResult, error) {
+	return c.GetAddressUtxosAsync(addresses).Receive()
+}
+
+// FutureGetAlgoStatsResult is a future promise to deliver the result of a GetAlgoStatsAsync RPC invocation (or an
+// applicable error).
+type FutureGetAlgoStatsResult chan *response
+
+// Receive waits for the response promised by the future and returns the statistics of each proof of work algorithm.
+func (r FutureGetAlgoStatsResult) Receive() (*btcjson.GetAlgoStatsResult, error) {
+	res, e := receiveFuture(r)
+	if e != nil {
+		return nil, e
+	}
+	var stats btcjson.GetAlgoStatsResult
+	if e = js.Unmarshal(res, &stats); E.Chk(e) {
+		return nil, e
+	}
+	return &stats, nil
+}
+
+// GetAlgoStatsAsync returns an instance of a type that can be used to get the result of the RPC at some future time by
+// invoking the Receive function on the returned instance. See GetAlgoStats for the blocking version and more details.
+func (c *Client) GetAlgoStatsAsync(blocks int) FutureGetAlgoStatsResult {
+	cmd := btcjson.NewGetAlgoStatsCmd(&blocks)
+	return c.sendCmd(cmd)
+}
+
+// GetAlgoStats returns the block count, average block interval, difficulty and estimated hashrate of each of the plan 9
+// proof of work algorithms over the given number of blocks ending at the best block.
+func (c *Client) GetAlgoStats(blocks int) (*btcjson.GetAlgoStatsResult, error) {
+	return c.GetAlgoStatsAsync(blocks).Receive()
+}
+
 // FutureRescanBlocksResult is a future promise to deliver the result of a RescanBlocksAsync RPC invocation (or an
 // applicable error).
 //
@@ -672,6 +900,61 @@ func (c *Client) InvalidateBlock(blockHash *chainhash.Hash) (e error) {
 	return c.InvalidateBlockAsync(blockHash).Receive()
 }
 
+// FuturePreciousBlockResult is a future promise to deliver the result of a PreciousBlockAsync RPC invocation (or an
+// applicable error).
+type FuturePreciousBlockResult chan *response
+
+// Receive waits for the response promised by the future and returns an error if the block could not be marked as
+// precious.
+func (r FuturePreciousBlockResult) Receive() (e error) {
+	_, e = receiveFuture(r)
+	return e
+}
+
+// PreciousBlockAsync returns an instance of a type that can be used to get the result of the RPC at some future time
+// by invoking the Receive function on the returned instance. See PreciousBlock for the blocking version and more
+// details.
+func (c *Client) PreciousBlockAsync(blockHash *chainhash.Hash) FuturePreciousBlockResult {
+	hash := ""
+	if blockHash != nil {
+		hash = blockHash.String()
+	}
+	cmd := btcjson.NewPreciousBlockCmd(hash)
+	return c.sendCmd(cmd)
+}
+
+// PreciousBlock treats a block as if it were received before others with the same height.
+func (c *Client) PreciousBlock(blockHash *chainhash.Hash) (e error) {
+	return c.PreciousBlockAsync(blockHash).Receive()
+}
+
+// FutureReconsiderBlockResult is a future promise to deliver the result of a ReconsiderBlockAsync RPC invocation (or
+// an applicable error).
+type FutureReconsiderBlockResult chan *response
+
+// Receive waits for the response promised by the future and returns an error if the block could not be reconsidered.
+func (r FutureReconsiderBlockResult) Receive() (e error) {
+	_, e = receiveFuture(r)
+	return e
+}
+
+// ReconsiderBlockAsync returns an instance of a type that can be used to get the result of the RPC at some future time
+// by invoking the Receive function on the returned instance. See ReconsiderBlock for the blocking version and more
+// details.
+func (c *Client) ReconsiderBlockAsync(blockHash *chainhash.Hash) FutureReconsiderBlockResult {
+	hash := ""
+	if blockHash != nil {
+		hash = blockHash.String()
+	}
+	cmd := btcjson.NewReconsiderBlockCmd(hash)
+	return c.sendCmd(cmd)
+}
+
+// ReconsiderBlock removes the invalid status of a block previously marked invalid with InvalidateBlock.
+func (c *Client) ReconsiderBlock(blockHash *chainhash.Hash) (e error) {
+	return c.ReconsiderBlockAsync(blockHash).Receive()
+}
+
 // FutureGetCFilterResult is a future promise to deliver the result of a GetCFilterAsync RPC invocation (or an
 // applicable error).
 type FutureGetCFilterResult chan *response
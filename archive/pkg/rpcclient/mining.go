@@ -263,7 +263,7 @@ func (r FutureGetWork) Receive() (*btcjson.GetWorkResult, error) {
 //
 // See GetWork for the blocking version and more details.
 func (c *Client) GetWorkAsync() FutureGetWork {
-	cmd := btcjson.NewGetWorkCmd(nil)
+	cmd := btcjson.NewGetWorkCmd(nil, nil)
 	return c.sendCmd(cmd)
 }
 
@@ -272,6 +272,21 @@ func (c *Client) GetWork() (*btcjson.GetWorkResult, error) {
 	return c.GetWorkAsync().Receive()
 }
 
+// GetWorkAlgoAsync returns an instance of a type that can be used to get the result of the RPC at some future time by
+// invoking the Receive function on the returned instance.
+//
+// See GetWorkAlgo for the blocking version and more details.
+func (c *Client) GetWorkAlgoAsync(algo string) FutureGetWork {
+	cmd := btcjson.NewGetWorkCmd(nil, &algo)
+	return c.sendCmd(cmd)
+}
+
+// GetWorkAlgo returns hash data to work on for the algorithm with the given name or block version. See GetWorkSubmit to
+// submit the found solution.
+func (c *Client) GetWorkAlgo(algo string) (*btcjson.GetWorkResult, error) {
+	return c.GetWorkAlgoAsync(algo).Receive()
+}
+
 // FutureGetWorkSubmit is a future promise to deliver the result of a GetWorkSubmitAsync RPC invocation (or an
 // applicable error).
 type FutureGetWorkSubmit chan *response
@@ -295,7 +310,7 @@ func (r FutureGetWorkSubmit) Receive() (bool, error) {
 // GetWorkSubmitAsync returns an instance of a type that can be used to get the result of the RPC at some future time by
 // invoking the Receive function on the returned instance. See GetWorkSubmit for the blocking version and more details.
 func (c *Client) GetWorkSubmitAsync(data string) FutureGetWorkSubmit {
-	cmd := btcjson.NewGetWorkCmd(&data)
+	cmd := btcjson.NewGetWorkCmd(&data, nil)
 	return c.sendCmd(cmd)
 }
 
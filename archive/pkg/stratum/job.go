@@ -0,0 +1,245 @@
+package stratum
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/p9c/pod/pkg/bits"
+	"github.com/p9c/pod/pkg/blockchain"
+	"github.com/p9c/pod/pkg/chainhash"
+	"github.com/p9c/pod/pkg/chainrpc/templates"
+	"github.com/p9c/pod/pkg/fork"
+	"github.com/p9c/pod/pkg/mining"
+	"github.com/p9c/pod/pkg/txscript"
+	"github.com/p9c/pod/pkg/wire"
+)
+
+const (
+	// ExtraNonce1Size is the number of bytes of the coinbase extra nonce that the server assigns to each connection
+	ExtraNonce1Size = 4
+	// ExtraNonce2Size is the number of bytes of the coinbase extra nonce that the miner rolls
+	ExtraNonce2Size = 4
+)
+
+// maxTarget is the largest possible share target, used when the share difficulty is so low that every hash is a share
+var maxTarget = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+// job is the work for one algorithm version of a block template, in the form it is handed out to stratum miners.
+//
+// The coinbase of the template is split around the extra nonce, miners put the connection's extra nonce 1 and their own
+// extra nonce 2 between the two halves and hash it up the merkle branch to get the merkle root of their block header.
+type job struct {
+	id        string
+	version   int32
+	height    int32
+	prevBlock chainhash.Hash
+	bits      uint32
+	timestamp time.Time
+	coinbase1 []byte
+	coinbase2 []byte
+	branch    []chainhash.Hash
+	txs       []*wire.MsgTx
+}
+
+// newJob creates the job for the given block version from a template message.
+func newJob(id string, msg *templates.Message, version int32) (j *job, e error) {
+	txs := msg.GetTxs()[version]
+	if len(txs) == 0 {
+		return nil, fmt.Errorf("template has no transactions for block version %d", version)
+	}
+	j = &job{
+		id:        id,
+		version:   version,
+		height:    msg.Height,
+		prevBlock: msg.PrevBlock,
+		bits:      msg.Bits[version],
+		timestamp: msg.Timestamp,
+		txs:       txs,
+	}
+	// the coinbase script starts with the block height as required by BIP34, followed by a push of the extra nonce and
+	// the coinbase flags, the extra nonce is zero for now and marks the place where the coinbase is split
+	var prefix, suffix []byte
+	if prefix, e = txscript.NewScriptBuilder().AddInt64(int64(msg.Height)).Script(); E.Chk(e) {
+		return
+	}
+	if suffix, e = txscript.NewScriptBuilder().AddData([]byte(mining.CoinbaseFlags)).Script(); E.Chk(e) {
+		return
+	}
+	script := make([]byte, 0, len(prefix)+1+ExtraNonce1Size+ExtraNonce2Size+len(suffix))
+	script = append(script, prefix...)
+	// pushes of up to 75 bytes use the length as the opcode
+	script = append(script, ExtraNonce1Size+ExtraNonce2Size)
+	script = append(script, make([]byte, ExtraNonce1Size+ExtraNonce2Size)...)
+	script = append(script, suffix...)
+	if len(script) > blockchain.MaxCoinbaseScriptLen {
+		return nil, fmt.Errorf(
+			"coinbase script length of %d is over the maximum of %d", len(script), blockchain.MaxCoinbaseScriptLen,
+		)
+	}
+	coinbase := txs[0].Copy()
+	coinbase.TxIn[0].SignatureScript = script
+	var buf bytes.Buffer
+	if e = coinbase.Serialize(&buf); E.Chk(e) {
+		return
+	}
+	serialized := buf.Bytes()
+	// the extra nonce starts after the version, the input count, the previous outpoint of the coinbase input, the
+	// script length, the height push and the extra nonce push opcode
+	split := 4 + wire.VarIntSerializeSize(uint64(len(coinbase.TxIn))) + chainhash.HashSize + 4 +
+		wire.VarIntSerializeSize(uint64(len(script))) + len(prefix) + 1
+	j.coinbase1 = serialized[:split]
+	j.coinbase2 = serialized[split+ExtraNonce1Size+ExtraNonce2Size:]
+	j.branch = merkleBranch(txs[1:])
+	return
+}
+
+// merkleBranch returns the hashes that the coinbase hash is combined with, in order, to compute the merkle root of a
+// block, given the transactions of the block after the coinbase.
+func merkleBranch(txs []*wire.MsgTx) (branch []chainhash.Hash) {
+	// the first entry of each level is the one the coinbase hash ends up in, its value is never used
+	level := make([]chainhash.Hash, len(txs)+1)
+	for i := range txs {
+		level[i+1] = txs[i].TxHash()
+	}
+	for len(level) > 1 {
+		branch = append(branch, level[1])
+		next := make([]chainhash.Hash, 1, len(level)/2+1)
+		for i := 2; i < len(level); i += 2 {
+			right := level[i]
+			if i+1 < len(level) {
+				right = level[i+1]
+			}
+			next = append(next, *blockchain.HashMerkleBranches(&level[i], &right))
+		}
+		level = next
+	}
+	return
+}
+
+// merkleRoot computes the merkle root of a block from the hash of its coinbase and the merkle branch of its job.
+func merkleRoot(coinbaseHash chainhash.Hash, branch []chainhash.Hash) chainhash.Hash {
+	root := coinbaseHash
+	for i := range branch {
+		root = *blockchain.HashMerkleBranches(&root, &branch[i])
+	}
+	return root
+}
+
+// coinbase reconstructs the coinbase transaction with the given extra nonce parts.
+func (j *job) coinbase(extraNonce1, extraNonce2 []byte) (tx *wire.MsgTx, e error) {
+	serialized := make([]byte, 0, len(j.coinbase1)+len(extraNonce1)+len(extraNonce2)+len(j.coinbase2))
+	serialized = append(serialized, j.coinbase1...)
+	serialized = append(serialized, extraNonce1...)
+	serialized = append(serialized, extraNonce2...)
+	serialized = append(serialized, j.coinbase2...)
+	tx = &wire.MsgTx{}
+	if e = tx.Deserialize(bytes.NewReader(serialized)); E.Chk(e) {
+	}
+	return
+}
+
+// header returns the block header a miner hashes for the given coinbase, time and nonce.
+func (j *job) header(coinbase *wire.MsgTx, ntime, nonce uint32) wire.BlockHeader {
+	return wire.BlockHeader{
+		Version:    j.version,
+		PrevBlock:  j.prevBlock,
+		MerkleRoot: merkleRoot(coinbase.TxHash(), j.branch),
+		Timestamp:  time.Unix(int64(ntime), 0),
+		Bits:       j.bits,
+		Nonce:      nonce,
+	}
+}
+
+// block assembles the full block for a solved header and its coinbase.
+func (j *job) block(header wire.BlockHeader, coinbase *wire.MsgTx) *wire.Block {
+	txs := make([]*wire.MsgTx, len(j.txs))
+	copy(txs, j.txs)
+	txs[0] = coinbase
+	return &wire.Block{Header: header, Transactions: txs}
+}
+
+// shareTarget returns the target a share hash must not exceed at the given share difficulty. Difficulty 1 is the
+// minimum difficulty of the algorithm of the job, as given by fork.GetMinDiff, so shares of different algorithms are
+// weighted by their difficulty in the same way. The target is never harder than the block target, as every hash that
+// meets that is a block.
+func (j *job) shareTarget(difficulty float64) (target *big.Int) {
+	target, _ = new(big.Float).Quo(new(big.Float).SetInt(j.diff1()), big.NewFloat(difficulty)).Int(nil)
+	if target.Cmp(maxTarget) > 0 {
+		target = maxTarget
+	}
+	if blockTarget := j.blockTarget(); target.Cmp(blockTarget) < 0 {
+		target = blockTarget
+	}
+	return
+}
+
+// blockTarget returns the target a block hash must not exceed for the header to be a valid block.
+func (j *job) blockTarget() *big.Int {
+	return bits.CompactToBig(j.bits)
+}
+
+// blockDifficulty returns the share difficulty of the block target of the job.
+func (j *job) blockDifficulty() (difficulty float64) {
+	difficulty, _ = new(big.Float).Quo(new(big.Float).SetInt(j.diff1()), new(big.Float).SetInt(j.blockTarget())).Float64()
+	return
+}
+
+// diff1 returns the target of share difficulty 1, the minimum difficulty of the algorithm of the job.
+func (j *job) diff1() *big.Int {
+	return fork.GetMinDiff(fork.GetAlgoName(j.version, j.height), j.height)
+}
+
+// notifyParams returns the parameters of the mining.notify message for the job.
+func (j *job) notifyParams(cleanJobs bool) []interface{} {
+	branch := make([]string, len(j.branch))
+	for i := range j.branch {
+		branch[i] = hex.EncodeToString(j.branch[i][:])
+	}
+	prevBlock := j.prevBlock
+	swapWords(prevBlock[:])
+	return []interface{}{
+		j.id,
+		hex.EncodeToString(prevBlock[:]),
+		hex.EncodeToString(j.coinbase1),
+		hex.EncodeToString(j.coinbase2),
+		branch,
+		fmt.Sprintf("%08x", uint32(j.version)),
+		fmt.Sprintf("%08x", j.bits),
+		fmt.Sprintf("%08x", uint32(j.timestamp.Unix())),
+		cleanJobs,
+	}
+}
+
+// swapWords reverses the byte order of each 32 bit word of b, which is how stratum encodes the previous block hash.
+func swapWords(b []byte) {
+	for i := 0; i+3 < len(b); i += 4 {
+		b[i], b[i+1], b[i+2], b[i+3] = b[i+3], b[i+2], b[i+1], b[i]
+	}
+}
+
+// parseUint32 decodes an 8 digit hex encoded big endian 32 bit value as sent in mining.submit.
+func parseUint32(s string) (v uint32, e error) {
+	var b []byte
+	if b, e = hex.DecodeString(s); e != nil || len(b) != 4 {
+		return 0, errors.New("expected 8 hex digits, got " + strconv.Quote(s))
+	}
+	return binary.BigEndian.Uint32(b), nil
+}
+
+// algoVersion resolves the algorithm a miner asked for, either by name or by block version number, to the block
+// version at the given height. Anything not valid at the height resolves to the first algorithm of the current hard
+// fork.
+func algoVersion(algo string, height int32) int32 {
+	if v, e := strconv.ParseInt(algo, 10, 32); e == nil {
+		if _, ok := fork.List[fork.GetCurrent(height)].AlgoVers[int32(v)]; ok {
+			return int32(v)
+		}
+	}
+	return fork.GetAlgoVer(algo, height)
+}
@@ -0,0 +1,700 @@
+// Package stratum implements a stratum v1 mining server, which hands out the block templates of the mining controller
+// to miners over TCP, one job for each algorithm version, with variable share difficulty.
+package stratum
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/p9c/qu"
+
+	block2 "github.com/p9c/pod/pkg/block"
+	"github.com/p9c/pod/pkg/blockchain"
+	"github.com/p9c/pod/pkg/chainhash"
+	"github.com/p9c/pod/pkg/chainrpc/templates"
+	"github.com/p9c/pod/pkg/fork"
+	"github.com/p9c/pod/pkg/wire"
+)
+
+const (
+	// DefaultShareTime is the average time between the shares of one miner that the variable share difficulty aims for
+	DefaultShareTime = time.Second * 10
+	// retargetShares is the number of shares after which the share difficulty of a miner is adjusted, the difficulty is
+	// also adjusted when a miner has not found this many shares in this many share times
+	retargetShares = 16
+	// maxRetarget is the largest factor by which the share difficulty changes in one adjustment
+	maxRetarget = 4.0
+	// maxLineLength is the longest request line accepted from a miner
+	maxLineLength = 4096
+	// keptTemplates is how many templates the jobs are kept for, so shares for the previous templates of the same
+	// block are still accepted after a new template was sent out
+	keptTemplates = 3
+	// sendQueueSize is how many messages can wait to be written to a miner before it is disconnected for not reading
+	// them
+	sendQueueSize = 64
+)
+
+// Stratum error codes as returned in the error field of a response.
+const (
+	errOther        = 20
+	errJobNotFound  = 21
+	errDuplicate    = 22
+	errLowDiff      = 23
+	errUnauthorized = 24
+	errNotSubscribe = 25
+)
+
+// BlockSubmitter processes blocks that were solved by stratum miners. It is satisfied by the chainrpc sync manager
+// adapter, which processes the block the same way as blocks coming from other nodes and relays it.
+type BlockSubmitter interface {
+	SubmitBlock(block *block2.Block, flags blockchain.BehaviorFlags) (bool, error)
+}
+
+// Config is the configuration of a stratum Server.
+type Config struct {
+	// Listeners are the addresses the server accepts miner connections on
+	Listeners []string
+	// Submitter processes the blocks that miners find
+	Submitter BlockSubmitter
+	// Difficulty is the share difficulty given to newly connected miners. Difficulty 1 is the minimum difficulty of the
+	// algorithm being mined.
+	Difficulty float64
+	// ShareTime is the average time between the shares of one miner that the variable share difficulty aims for
+	ShareTime time.Duration
+	// CheckWorker, when it is not nil, is called with the worker name of a miner that authorizes, which is refused
+	// when it returns an error, so a pool can require the worker names to hold the payout addresses of the miners
+	CheckWorker func(worker string) error
+	// OnShare, when it is not nil, is called for every share accepted from a miner with the worker name, the share
+	// difficulty the share was accepted at, the height and block version of its job and a string that identifies the
+	// work of the share among those of all miners
+	OnShare func(worker string, difficulty float64, height, version int32, work string) error
+}
+
+// Server is a stratum v1 mining server.
+type Server struct {
+	sync.Mutex
+	cfg       Config
+	listeners []net.Listener
+	clients   map[uint32]*client
+	nextID    uint32
+	jobs      map[string]*job
+	current   map[int32]*job
+	templates []map[string]*job
+	prevBlock chainhash.Hash
+	jobCount  uint64
+	quit      qu.C
+	stop      qu.C
+	wg        sync.WaitGroup
+}
+
+// request is a JSON-RPC request from a miner.
+type request struct {
+	ID     interface{}       `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+// response is a JSON-RPC response to a miner, or a notification when the ID is nil and Method is set.
+type response struct {
+	ID     interface{}   `json:"id"`
+	Method string        `json:"method,omitempty"`
+	Params []interface{} `json:"params,omitempty"`
+	Result interface{}   `json:"result"`
+	Error  interface{}   `json:"error"`
+}
+
+// stratumError is the error field of a response, which stratum sends as a list of the code, the message and a
+// traceback.
+type stratumError struct {
+	code    int
+	message string
+}
+
+// MarshalJSON encodes the error in the form stratum miners expect.
+func (se *stratumError) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{se.code, se.message, nil})
+}
+
+// New creates a stratum server listening on the configured addresses. Accepting connections begins when Start is
+// called, and the server stops when the quit channel is closed.
+func New(cfg Config, quit qu.C) (s *Server, e error) {
+	if cfg.Submitter == nil {
+		return nil, errors.New("stratum server needs a block submitter")
+	}
+	if cfg.Difficulty <= 0 {
+		cfg.Difficulty = 1
+	}
+	if cfg.ShareTime <= 0 {
+		cfg.ShareTime = DefaultShareTime
+	}
+	s = &Server{
+		cfg:     cfg,
+		clients: make(map[uint32]*client),
+		jobs:    make(map[string]*job),
+		current: make(map[int32]*job),
+		quit:    quit,
+		stop:    qu.T(),
+	}
+	for _, addr := range cfg.Listeners {
+		var l net.Listener
+		if l, e = net.Listen("tcp", addr); E.Chk(e) {
+			for i := range s.listeners {
+				if e := s.listeners[i].Close(); E.Chk(e) {
+				}
+			}
+			return nil, e
+		}
+		s.listeners = append(s.listeners, l)
+	}
+	return
+}
+
+// Addrs returns the addresses the server is listening on.
+func (s *Server) Addrs() (addrs []net.Addr) {
+	for i := range s.listeners {
+		addrs = append(addrs, s.listeners[i].Addr())
+	}
+	return
+}
+
+// Start begins accepting miner connections.
+func (s *Server) Start() {
+	for i := range s.listeners {
+		I.Ln("stratum server listening on", s.listeners[i].Addr())
+		s.wg.Add(1)
+		go s.acceptHandler(s.listeners[i])
+	}
+	s.wg.Add(1)
+	go s.retargetHandler()
+	go func() {
+		select {
+		case <-s.quit.Wait():
+			s.Stop()
+		case <-s.stop.Wait():
+		}
+	}()
+}
+
+// Stop closes the listeners and all miner connections and waits for their handlers to finish.
+func (s *Server) Stop() {
+	s.Lock()
+	for i := range s.listeners {
+		if e := s.listeners[i].Close(); E.Chk(e) {
+		}
+	}
+	s.listeners = nil
+	for _, c := range s.clients {
+		c.close()
+	}
+	s.Unlock()
+	s.stop.Q()
+	s.wg.Wait()
+}
+
+// SetTemplate makes jobs for every algorithm version of a new block template and sends each miner the job for the
+// algorithm it mines. Miners are told to drop their current work when the template is for a new block.
+func (s *Server) SetTemplate(msg *templates.Message) {
+	s.Lock()
+	defer s.Unlock()
+	clean := msg.PrevBlock != s.prevBlock
+	if clean {
+		s.jobs = make(map[string]*job)
+		s.templates = nil
+		s.prevBlock = msg.PrevBlock
+	}
+	jobs := make(map[string]*job)
+	current := make(map[int32]*job)
+	for next, curr, more := fork.AlgoVerIterator(msg.Height); more(); next() {
+		if _, ok := msg.GetTxs()[curr()]; !ok {
+			continue
+		}
+		s.jobCount++
+		j, e := newJob(strconv.FormatUint(s.jobCount, 16), msg, curr())
+		if E.Chk(e) {
+			continue
+		}
+		jobs[j.id] = j
+		current[j.version] = j
+	}
+	s.current = current
+	// forget the jobs of the oldest template that is kept
+	s.templates = append(s.templates, jobs)
+	if len(s.templates) > keptTemplates {
+		for id := range s.templates[0] {
+			delete(s.jobs, id)
+		}
+		s.templates = s.templates[1:]
+	}
+	for id, j := range jobs {
+		s.jobs[id] = j
+	}
+	D.F("stratum jobs created for %d algorithms at height %d", len(current), msg.Height)
+	for _, c := range s.clients {
+		if c.ready() {
+			s.sendJob(c, clean)
+		}
+	}
+}
+
+// sendJob sends a miner the current job for its algorithm.
+//
+// This function MUST be called with the server lock held.
+func (s *Server) sendJob(c *client, clean bool) {
+	if len(s.current) == 0 {
+		return
+	}
+	j := c.currentJob(s.current)
+	if j == nil {
+		return
+	}
+	if clean {
+		// shares for the previous block are no longer accepted, and neither is the difficulty from before the last
+		// adjustment
+		c.Lock()
+		c.submitted = make(map[string]struct{})
+		c.prevDifficulty = c.difficulty
+		c.Unlock()
+	}
+	if e := c.send(&response{Method: "mining.notify", Params: j.notifyParams(clean)}); E.Chk(e) {
+		c.close()
+	}
+}
+
+// acceptHandler accepts miner connections on a listener until it is closed.
+func (s *Server) acceptHandler(l net.Listener) {
+	defer s.wg.Done()
+	for {
+		conn, e := l.Accept()
+		if e != nil {
+			select {
+			case <-s.stop.Wait():
+			default:
+				D.Ln("stratum listener", l.Addr(), "stopped:", e)
+			}
+			return
+		}
+		s.Lock()
+		s.nextID++
+		c := newClient(s.nextID, conn, s.cfg.Difficulty)
+		s.clients[c.id] = c
+		s.Unlock()
+		D.Ln("stratum miner connected from", conn.RemoteAddr())
+		s.wg.Add(2)
+		go s.clientHandler(c)
+		go s.writeHandler(c)
+	}
+}
+
+// retargetHandler periodically adjusts the share difficulty of miners that have not submitted enough shares for the
+// difficulty to be adjusted when they submit.
+func (s *Server) retargetHandler() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(s.cfg.ShareTime)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			s.Lock()
+			for _, c := range s.clients {
+				if c.ready() {
+					s.retarget(c, now)
+				}
+			}
+			s.Unlock()
+		case <-s.stop.Wait():
+			return
+		}
+	}
+}
+
+// clientHandler reads and handles the requests of a miner until the connection is closed.
+func (s *Server) clientHandler(c *client) {
+	defer s.wg.Done()
+	defer func() {
+		c.close()
+		s.Lock()
+		delete(s.clients, c.id)
+		s.Unlock()
+		D.Ln("stratum miner", c.conn.RemoteAddr(), "disconnected")
+	}()
+	scanner := bufio.NewScanner(c.conn)
+	scanner.Buffer(make([]byte, maxLineLength), maxLineLength)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var req request
+		if e := json.Unmarshal([]byte(line), &req); e != nil {
+			D.Ln("invalid stratum request from", c.conn.RemoteAddr(), e)
+			return
+		}
+		result, se := s.handle(c, &req)
+		res := &response{ID: req.ID, Result: result}
+		if se != nil {
+			res.Error = se
+		}
+		if e := c.send(res); e != nil {
+			return
+		}
+		if req.Method == "mining.authorize" && se == nil {
+			// the difficulty and the first job follow the response to the authorization
+			s.Lock()
+			c.Lock()
+			difficulty := c.difficulty
+			c.Unlock()
+			if e := c.send(&response{Method: "mining.set_difficulty", Params: []interface{}{difficulty}}); E.Chk(e) {
+			}
+			s.sendJob(c, true)
+			s.Unlock()
+		}
+	}
+}
+
+// writeHandler writes the messages queued for a miner to its connection until the connection is closed. The messages
+// are queued while holding the server lock where needed so they are sent in order, and written here so a miner that is
+// slow to read never holds up the server.
+func (s *Server) writeHandler(c *client) {
+	defer s.wg.Done()
+	for {
+		select {
+		case b := <-c.queue:
+			if e := c.conn.SetWriteDeadline(time.Now().Add(time.Second * 10)); E.Chk(e) {
+				c.close()
+				return
+			}
+			if _, e := c.conn.Write(b); e != nil {
+				D.Ln("failed to write to stratum miner", c.conn.RemoteAddr(), e)
+				c.close()
+				return
+			}
+		case <-c.closed.Wait():
+			return
+		}
+	}
+}
+
+// handle dispatches a request to the handler for its method.
+func (s *Server) handle(c *client, req *request) (result interface{}, se *stratumError) {
+	switch req.Method {
+	case "mining.subscribe":
+		return s.handleSubscribe(c)
+	case "mining.authorize":
+		return s.handleAuthorize(c, req.Params)
+	case "mining.submit":
+		return s.handleSubmit(c, req.Params)
+	case "mining.extranonce.subscribe":
+		return false, nil
+	default:
+		return nil, &stratumError{errOther, "unknown method " + req.Method}
+	}
+}
+
+// handleSubscribe handles mining.subscribe, the reply gives the subscriptions, the extra nonce 1 of the connection and
+// the size of the extra nonce 2.
+func (s *Server) handleSubscribe(c *client) (result interface{}, se *stratumError) {
+	c.Lock()
+	c.subscribed = true
+	c.Unlock()
+	id := hex.EncodeToString(c.extraNonce1)
+	return []interface{}{
+		[]interface{}{
+			[]interface{}{"mining.set_difficulty", id},
+			[]interface{}{"mining.notify", id},
+		},
+		id,
+		ExtraNonce2Size,
+	}, nil
+}
+
+// handleAuthorize handles mining.authorize. Any worker name is accepted, the password can hold a comma separated list
+// of options, algo=<name or block version> selects the algorithm to mine and d=<difficulty> the starting share
+// difficulty.
+func (s *Server) handleAuthorize(c *client, params []json.RawMessage) (result interface{}, se *stratumError) {
+	var worker, password string
+	if len(params) < 1 || json.Unmarshal(params[0], &worker) != nil {
+		return false, &stratumError{errOther, "missing worker name"}
+	}
+	if len(params) > 1 {
+		if e := json.Unmarshal(params[1], &password); E.Chk(e) {
+		}
+	}
+	if s.cfg.CheckWorker != nil {
+		if e := s.cfg.CheckWorker(worker); e != nil {
+			return false, &stratumError{errUnauthorized, e.Error()}
+		}
+	}
+	c.Lock()
+	defer c.Unlock()
+	if !c.subscribed {
+		return false, &stratumError{errNotSubscribe, "not subscribed"}
+	}
+	for _, option := range strings.Split(password, ",") {
+		kv := strings.SplitN(strings.TrimSpace(option), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "algo", "a":
+			c.algo = kv[1]
+		case "d":
+			if d, e := strconv.ParseFloat(kv[1], 64); e == nil && d > 0 {
+				c.difficulty = d
+			}
+		}
+	}
+	c.worker = worker
+	c.authorized = true
+	c.lastRetarget = time.Now()
+	I.F("stratum worker '%s' authorized from %v mining '%s'", worker, c.conn.RemoteAddr(), c.algo)
+	return true, nil
+}
+
+// handleSubmit handles mining.submit, submitting the block when the share meets the block difficulty and checking that
+// the share meets the share difficulty.
+func (s *Server) handleSubmit(c *client, params []json.RawMessage) (result interface{}, se *stratumError) {
+	if !c.ready() {
+		return false, &stratumError{errUnauthorized, "unauthorized worker"}
+	}
+	var p [5]string
+	if len(params) < len(p) {
+		return false, &stratumError{errOther, "mining.submit needs 5 parameters"}
+	}
+	for i := range p {
+		if e := json.Unmarshal(params[i], &p[i]); e != nil {
+			return false, &stratumError{errOther, fmt.Sprintf("parameter %d is not a string", i)}
+		}
+	}
+	jobID, extraNonce2Hex, ntimeHex, nonceHex := p[1], p[2], p[3], p[4]
+	s.Lock()
+	j := s.jobs[jobID]
+	s.Unlock()
+	if j == nil {
+		return false, &stratumError{errJobNotFound, "job not found"}
+	}
+	extraNonce2, e := hex.DecodeString(extraNonce2Hex)
+	if e != nil || len(extraNonce2) != ExtraNonce2Size {
+		return false, &stratumError{errOther, "invalid extranonce2"}
+	}
+	var ntime, nonce uint32
+	if ntime, e = parseUint32(ntimeHex); e != nil {
+		return false, &stratumError{errOther, "invalid ntime: " + e.Error()}
+	}
+	if nonce, e = parseUint32(nonceHex); e != nil {
+		return false, &stratumError{errOther, "invalid nonce: " + e.Error()}
+	}
+	if int64(ntime) < j.timestamp.Unix() || int64(ntime) > time.Now().Unix()+blockchain.MaxTimeOffsetSeconds {
+		return false, &stratumError{errOther, "ntime out of range"}
+	}
+	// the key is made from the decoded values so the same share can't be sent again with the hex in another case
+	key := fmt.Sprintf("%s:%x:%08x:%08x", jobID, extraNonce2, ntime, nonce)
+	c.Lock()
+	_, duplicate := c.submitted[key]
+	c.submitted[key] = struct{}{}
+	difficulty := c.difficulty
+	if c.prevDifficulty < difficulty {
+		difficulty = c.prevDifficulty
+	}
+	c.Unlock()
+	if duplicate {
+		return false, &stratumError{errDuplicate, "duplicate share"}
+	}
+	coinbase, e := j.coinbase(c.extraNonce1, extraNonce2)
+	if e != nil {
+		return false, &stratumError{errOther, "invalid coinbase"}
+	}
+	header := j.header(coinbase, ntime, nonce)
+	hash := header.BlockHashWithAlgos(j.height)
+	hashNum := blockchain.HashToBig(&hash)
+	// A block is submitted before the share is checked and credited, so it is not lost when the share difficulty is
+	// above the block difficulty or the share can't be credited. Such a share is credited at the block difficulty.
+	if hashNum.Cmp(j.blockTarget()) <= 0 {
+		s.submitBlock(c, j, header, coinbase)
+		if blockDifficulty := j.blockDifficulty(); blockDifficulty < difficulty {
+			difficulty = blockDifficulty
+		}
+	}
+	if hashNum.Cmp(j.shareTarget(difficulty)) > 0 {
+		return false, &stratumError{errLowDiff, "low difficulty share"}
+	}
+	if s.cfg.OnShare != nil {
+		// the extra nonce 1 tells apart the otherwise same work of different miners
+		work := hex.EncodeToString(c.extraNonce1) + ":" + key
+		if e = s.cfg.OnShare(c.worker, difficulty, j.height, j.version, work); e != nil {
+			return false, &stratumError{errOther, "share not credited: " + e.Error()}
+		}
+	}
+	s.Lock()
+	c.Lock()
+	c.shares++
+	c.Unlock()
+	s.retarget(c, time.Now())
+	s.Unlock()
+	T.F("stratum share from worker '%s' for job %s accepted", c.worker, jobID)
+	return true, nil
+}
+
+// submitBlock hands a block found by a miner to the block submitter.
+func (s *Server) submitBlock(c *client, j *job, header wire.BlockHeader, coinbase *wire.MsgTx) {
+	blk := block2.NewBlock(j.block(header, coinbase))
+	blk.SetHeight(j.height)
+	I.F(
+		"stratum worker '%s' found block %v at height %d with algorithm %s", c.worker, blk.Hash(), j.height,
+		fork.GetAlgoName(j.version, j.height),
+	)
+	isOrphan, e := s.cfg.Submitter.SubmitBlock(blk, blockchain.BFNone)
+	if e != nil {
+		// Anything other than a rule violation is an unexpected error, so log that error as an internal error.
+		if _, ok := e.(blockchain.RuleError); !ok {
+			E.Ln("unexpected error while processing block submitted via stratum:", e)
+			return
+		}
+		W.Ln("block submitted via stratum rejected:", e)
+		return
+	}
+	if isOrphan {
+		W.Ln("block submitted via stratum is an orphan")
+		return
+	}
+	I.Ln("block submitted via stratum accepted:", blk.Hash())
+}
+
+// retarget adjusts the share difficulty of a miner so that it submits a share about every share time, and sends the
+// new difficulty to the miner when it changed.
+//
+// This function MUST be called with the server lock held.
+func (s *Server) retarget(c *client, now time.Time) {
+	c.Lock()
+	elapsed := now.Sub(c.lastRetarget)
+	if c.shares < retargetShares && elapsed < s.cfg.ShareTime*retargetShares {
+		c.Unlock()
+		return
+	}
+	ratio := maxRetarget
+	if elapsed > 0 {
+		ratio = float64(c.shares) * s.cfg.ShareTime.Seconds() / elapsed.Seconds()
+	}
+	if ratio > maxRetarget {
+		ratio = maxRetarget
+	} else if ratio < 1/maxRetarget {
+		ratio = 1 / maxRetarget
+	}
+	c.shares = 0
+	c.lastRetarget = now
+	// small deviations are expected from the randomness of finding shares
+	if ratio > 0.7 && ratio < 1.4 {
+		c.Unlock()
+		return
+	}
+	// shares for the jobs sent before the change are accepted at the difficulty they were mined at until the next block
+	c.prevDifficulty = c.difficulty
+	c.difficulty *= ratio
+	difficulty := c.difficulty
+	c.Unlock()
+	D.F("stratum worker '%s' share difficulty set to %g", c.worker, difficulty)
+	if e := c.send(&response{Method: "mining.set_difficulty", Params: []interface{}{difficulty}}); E.Chk(e) {
+		c.close()
+		return
+	}
+	// resend the current job so the miner starts using the new difficulty
+	s.sendJob(c, false)
+}
+
+// client is the state of a miner connection.
+type client struct {
+	sync.Mutex
+	id             uint32
+	conn           net.Conn
+	queue          chan []byte
+	closed         qu.C
+	closeOnce      sync.Once
+	extraNonce1    []byte
+	worker         string
+	algo           string
+	subscribed     bool
+	authorized     bool
+	difficulty     float64
+	prevDifficulty float64
+	shares         int
+	lastRetarget   time.Time
+	submitted      map[string]struct{}
+}
+
+// newClient creates the state for a new miner connection, the extra nonce 1 of the connection is its id.
+func newClient(id uint32, conn net.Conn, difficulty float64) (c *client) {
+	c = &client{
+		id:             id,
+		conn:           conn,
+		queue:          make(chan []byte, sendQueueSize),
+		closed:         qu.T(),
+		extraNonce1:    make([]byte, ExtraNonce1Size),
+		difficulty:     difficulty,
+		prevDifficulty: difficulty,
+		lastRetarget:   time.Now(),
+		submitted:      make(map[string]struct{}),
+	}
+	binary.BigEndian.PutUint32(c.extraNonce1, id)
+	return
+}
+
+// ready returns true when the miner has subscribed and authorized and can be sent jobs.
+func (c *client) ready() bool {
+	c.Lock()
+	defer c.Unlock()
+	return c.subscribed && c.authorized
+}
+
+// currentJob returns the job for the algorithm the miner mines from the current jobs by version.
+func (c *client) currentJob(current map[int32]*job) *job {
+	var height int32
+	for _, j := range current {
+		height = j.height
+		break
+	}
+	c.Lock()
+	algo := c.algo
+	c.Unlock()
+	return current[algoVersion(algo, height)]
+}
+
+// send queues a message to the miner as a line of JSON for its writeHandler. It never blocks, so it can be called with
+// the server lock held, and a miner that does not read its messages fast enough to keep the queue from filling up is
+// disconnected.
+func (c *client) send(res *response) (e error) {
+	var b []byte
+	if b, e = json.Marshal(res); E.Chk(e) {
+		return
+	}
+	select {
+	case <-c.closed.Wait():
+		return errors.New("stratum miner connection is closed")
+	default:
+	}
+	select {
+	case c.queue <- append(b, '\n'):
+	default:
+		c.close()
+		e = errors.New("stratum miner is not reading its messages")
+	}
+	return
+}
+
+// close closes the miner connection, which ends its handler.
+func (c *client) close() {
+	c.closeOnce.Do(
+		func() {
+			c.closed.Q()
+			if e := c.conn.Close(); E.Chk(e) {
+			}
+		},
+	)
+}
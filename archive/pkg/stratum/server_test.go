@@ -0,0 +1,465 @@
+package stratum
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/p9c/qu"
+
+	"github.com/p9c/pod/pkg/bits"
+	block2 "github.com/p9c/pod/pkg/block"
+	"github.com/p9c/pod/pkg/blockchain"
+	"github.com/p9c/pod/pkg/chainhash"
+	"github.com/p9c/pod/pkg/chainrpc/templates"
+	"github.com/p9c/pod/pkg/fork"
+	"github.com/p9c/pod/pkg/util"
+	"github.com/p9c/pod/pkg/wire"
+)
+
+// testSubmitter records the blocks submitted by the stratum server.
+type testSubmitter struct {
+	blocks chan *block2.Block
+}
+
+func (ts *testSubmitter) SubmitBlock(block *block2.Block, flags blockchain.BehaviorFlags) (bool, error) {
+	ts.blocks <- block
+	return false, nil
+}
+
+// testTxs returns a coinbase and n other transactions for a test template.
+func testTxs(n int) (txs []*wire.MsgTx) {
+	coinbase := wire.NewMsgTx(1)
+	coinbase.AddTxIn(
+		wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{}, math.MaxUint32), []byte{1, 100, 0}, nil),
+	)
+	coinbase.AddTxOut(wire.NewTxOut(5000000000, []byte{0x51}))
+	txs = append(txs, coinbase)
+	for i := 0; i < n; i++ {
+		tx := wire.NewMsgTx(1)
+		tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{byte(i + 1)}, 0), []byte{0x51}, nil))
+		tx.AddTxOut(wire.NewTxOut(int64(i+1), []byte{0x51}))
+		txs = append(txs, tx)
+	}
+	return
+}
+
+// TestMerkleBranch checks that the merkle root computed from the coinbase hash and the merkle branch matches the merkle
+// root of the whole block for different numbers of transactions.
+func TestMerkleBranch(t *testing.T) {
+	for n := 0; n < 9; n++ {
+		txs := testTxs(n)
+		utxs := make([]*util.Tx, len(txs))
+		for i := range txs {
+			utxs[i] = util.NewTx(txs[i])
+		}
+		want := *blockchain.BuildMerkleTreeStore(utxs, false).GetRoot()
+		got := merkleRoot(txs[0].TxHash(), merkleBranch(txs[1:]))
+		if got != want {
+			t.Errorf("%d transactions: merkle root from branch %v, want %v", n+1, got, want)
+		}
+	}
+}
+
+// testClient is a minimal stratum miner for exercising the server.
+type testClient struct {
+	t       *testing.T
+	conn    net.Conn
+	r       *bufio.Reader
+	id      int
+	pending []map[string]interface{}
+}
+
+func (tc *testClient) call(method string, params ...interface{}) map[string]interface{} {
+	tc.id++
+	b, e := json.Marshal(map[string]interface{}{"id": tc.id, "method": method, "params": params})
+	if e != nil {
+		tc.t.Fatal(e)
+	}
+	if _, e = tc.conn.Write(append(b, '\n')); e != nil {
+		tc.t.Fatal(e)
+	}
+	for {
+		msg := tc.read()
+		if id, ok := msg["id"].(float64); ok && int(id) == tc.id {
+			return msg
+		}
+		tc.pending = append(tc.pending, msg)
+	}
+}
+
+func (tc *testClient) read() (msg map[string]interface{}) {
+	if e := tc.conn.SetReadDeadline(time.Now().Add(time.Second * 10)); e != nil {
+		tc.t.Fatal(e)
+	}
+	line, e := tc.r.ReadBytes('\n')
+	if e != nil {
+		tc.t.Fatal(e)
+	}
+	if e = json.Unmarshal(line, &msg); e != nil {
+		tc.t.Fatal(e)
+	}
+	return
+}
+
+// notification returns the next notification with the given method.
+func (tc *testClient) notification(method string) []interface{} {
+	for {
+		var msg map[string]interface{}
+		if len(tc.pending) > 0 {
+			msg, tc.pending = tc.pending[0], tc.pending[1:]
+		} else {
+			msg = tc.read()
+		}
+		if msg["method"] == method {
+			return msg["params"].([]interface{})
+		}
+	}
+}
+
+// testWork is a mining.notify job as a miner puts it together.
+type testWork struct {
+	jobID                string
+	prevBlock            chainhash.Hash
+	coinbase1            []byte
+	coinbase2            []byte
+	branch               []chainhash.Hash
+	version, bits, ntime uint32
+}
+
+func decodeHex(t *testing.T, s interface{}) []byte {
+	b, e := hex.DecodeString(s.(string))
+	if e != nil {
+		t.Fatal(e)
+	}
+	return b
+}
+
+func parseNotify(t *testing.T, params []interface{}) (w testWork) {
+	w.jobID = params[0].(string)
+	prev := decodeHex(t, params[1])
+	swapWords(prev)
+	copy(w.prevBlock[:], prev)
+	w.coinbase1 = decodeHex(t, params[2])
+	w.coinbase2 = decodeHex(t, params[3])
+	for _, h := range params[4].([]interface{}) {
+		var hash chainhash.Hash
+		copy(hash[:], decodeHex(t, h))
+		w.branch = append(w.branch, hash)
+	}
+	w.version = binary.BigEndian.Uint32(decodeHex(t, params[5]))
+	w.bits = binary.BigEndian.Uint32(decodeHex(t, params[6]))
+	w.ntime = binary.BigEndian.Uint32(decodeHex(t, params[7]))
+	return
+}
+
+// header builds the block header for the work the way a miner does, returning it with its proof of work hash.
+func (w *testWork) header(
+	t *testing.T, extraNonce1, extraNonce2 []byte, nonce uint32, height int32,
+) (hdr wire.BlockHeader, hash chainhash.Hash) {
+	coinbase := append(append(append(append([]byte{}, w.coinbase1...), extraNonce1...), extraNonce2...), w.coinbase2...)
+	root := chainhash.DoubleHashH(coinbase)
+	for i := range w.branch {
+		root = chainhash.DoubleHashH(append(append([]byte{}, root[:]...), w.branch[i][:]...))
+	}
+	hdr = wire.BlockHeader{
+		Version:    int32(w.version),
+		PrevBlock:  w.prevBlock,
+		MerkleRoot: root,
+		Timestamp:  time.Unix(int64(w.ntime), 0),
+		Bits:       w.bits,
+		Nonce:      nonce,
+	}
+	return hdr, hdr.BlockHashWithAlgos(height)
+}
+
+// TestServer runs a miner against the server through subscription, authorization, a job, a share that is not a block
+// and a share that is a block, and checks the block handed to the submitter and the shares that are credited.
+func TestServer(t *testing.T) {
+	const height = 100
+	quit := qu.T()
+	defer quit.Q()
+	submitter := &testSubmitter{blocks: make(chan *block2.Block, 1)}
+	credited := make(chan string, 4)
+	s, e := New(
+		Config{
+			Listeners: []string{"127.0.0.1:0"},
+			Submitter: submitter,
+			CheckWorker: func(worker string) error {
+				if worker != "worker" {
+					return errors.New("unknown worker")
+				}
+				return nil
+			},
+			OnShare: func(worker string, difficulty float64, height, version int32, work string) error {
+				credited <- fmt.Sprintf("%s %g %d %d", worker, difficulty, height, version)
+				return nil
+			},
+		}, quit,
+	)
+	if e != nil {
+		t.Fatal(e)
+	}
+	s.Start()
+	// a template with a target that about every second hash meets
+	msg := &templates.Message{
+		Height:    height,
+		PrevBlock: chainhash.Hash{1, 2, 3},
+		Bits:      templates.Diffs{2: 0x207fffff, 514: 0x207fffff},
+		Merkles:   templates.Merkles{},
+		Timestamp: time.Now().Truncate(time.Second),
+	}
+	msg.SetTxs(2, testTxs(3))
+	msg.SetTxs(514, testTxs(2))
+	s.SetTemplate(msg)
+	conn, e := net.Dial("tcp", s.Addrs()[0].String())
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer conn.Close()
+	tc := &testClient{t: t, conn: conn, r: bufio.NewReader(conn)}
+	res := tc.call("mining.submit", "worker", "1", "00000000", "00000000", "00000000")
+	if res["error"] == nil {
+		t.Fatal("submit before authorization was accepted")
+	}
+	res = tc.call("mining.subscribe", "test/1.0")
+	sub := res["result"].([]interface{})
+	extraNonce1 := decodeHex(t, sub[1])
+	if len(extraNonce1) != ExtraNonce1Size || int(sub[2].(float64)) != ExtraNonce2Size {
+		t.Fatalf("unexpected subscription result %v", sub)
+	}
+	if res = tc.call("mining.authorize", "other", ""); res["result"] == true {
+		t.Fatal("worker refused by the server configuration was authorized")
+	}
+	// every hash meets the share target at this difficulty
+	res = tc.call("mining.authorize", "worker", "algo=sha256d,d=0.000000001")
+	if res["result"] != true {
+		t.Fatalf("authorization failed: %v", res)
+	}
+	if d := tc.notification("mining.set_difficulty"); d[0].(float64) != 0.000000001 {
+		t.Fatalf("unexpected share difficulty %v", d[0])
+	}
+	w := parseNotify(t, tc.notification("mining.notify"))
+	if w.version != 2 || w.bits != 0x207fffff || w.prevBlock != msg.PrevBlock {
+		t.Fatalf("unexpected job %+v", w)
+	}
+	extraNonce2 := []byte{0, 0, 0, 7}
+	target := bits.CompactToBig(w.bits)
+	var shareNonce, blockNonce uint32
+	var foundShare, foundBlock bool
+	var blockHeader wire.BlockHeader
+	for nonce := uint32(0); !foundShare || !foundBlock; nonce++ {
+		hdr, hash := w.header(t, extraNonce1, extraNonce2, nonce, height)
+		if blockchain.HashToBig(&hash).Cmp(target) > 0 {
+			shareNonce, foundShare = nonce, true
+		} else if !foundBlock {
+			blockNonce, foundBlock, blockHeader = nonce, true, hdr
+		}
+	}
+	submit := func(nonce uint32) map[string]interface{} {
+		return tc.call(
+			"mining.submit", "worker", w.jobID, hex.EncodeToString(extraNonce2),
+			fmt.Sprintf("%08x", w.ntime), fmt.Sprintf("%08x", nonce),
+		)
+	}
+	if res = submit(shareNonce); res["result"] != true {
+		t.Fatalf("share was rejected: %v", res)
+	}
+	if c := <-credited; c != "worker 1e-09 100 2" {
+		t.Fatalf("share credited as %q", c)
+	}
+	select {
+	case <-submitter.blocks:
+		t.Fatal("share that does not meet the block target was submitted as a block")
+	default:
+	}
+	if res = submit(shareNonce); res["error"] == nil || res["error"].([]interface{})[0].(float64) != errDuplicate {
+		t.Fatalf("duplicate share was not rejected: %v", res)
+	}
+	if len(credited) != 0 {
+		t.Fatal("duplicate share was credited")
+	}
+	if res = submit(blockNonce); res["result"] != true {
+		t.Fatalf("block share was rejected: %v", res)
+	}
+	var blk *block2.Block
+	select {
+	case blk = <-submitter.blocks:
+	case <-time.After(time.Second * 10):
+		t.Fatal("block was not submitted")
+	}
+	if blk.Height() != height || blk.WireBlock().Header != blockHeader {
+		t.Fatalf("submitted block header %+v, want %+v", blk.WireBlock().Header, blockHeader)
+	}
+	if root := *blockchain.BuildMerkleTreeStore(blk.Transactions(), false).GetRoot(); root != blockHeader.MerkleRoot {
+		t.Fatalf("merkle root of submitted block %v, want %v", root, blockHeader.MerkleRoot)
+	}
+	script := blk.WireBlock().Transactions[0].TxIn[0].SignatureScript
+	if !bytes.Contains(script, append(append([]byte{}, extraNonce1...), extraNonce2...)) {
+		t.Fatalf("coinbase script %x does not contain the extra nonce", script)
+	}
+	// a new block makes the miner drop its work, and the jobs for the previous block are gone
+	msg2 := *msg
+	msg2.PrevBlock = blockHeader.BlockHash()
+	msg2.SetTxs(2, testTxs(1))
+	s.SetTemplate(&msg2)
+	if params := tc.notification("mining.notify"); params[8] != true {
+		t.Fatalf("job for new block does not clear the previous jobs: %v", params)
+	}
+	if res = submit(shareNonce + 1); res["error"] == nil || res["error"].([]interface{})[0].(float64) != errJobNotFound {
+		t.Fatalf("share for a stale job was not rejected: %v", res)
+	}
+}
+
+// TestBlockAboveShareDifficulty checks that a block is submitted and credited at the block difficulty when the share
+// difficulty of the miner is above the block difficulty, and that it is submitted when the share can't be credited.
+func TestBlockAboveShareDifficulty(t *testing.T) {
+	const height = 100
+	quit := qu.T()
+	defer quit.Q()
+	submitter := &testSubmitter{blocks: make(chan *block2.Block, 1)}
+	credited := make(chan float64, 4)
+	var creditErr error
+	s, e := New(
+		Config{
+			Listeners: []string{"127.0.0.1:0"},
+			Submitter: submitter,
+			OnShare: func(worker string, difficulty float64, height, version int32, work string) error {
+				if creditErr != nil {
+					return creditErr
+				}
+				credited <- difficulty
+				return nil
+			},
+		}, quit,
+	)
+	if e != nil {
+		t.Fatal(e)
+	}
+	s.Start()
+	// a template with a target that about every second hash meets, far below the share difficulty of the miner
+	msg := &templates.Message{
+		Height:    height,
+		PrevBlock: chainhash.Hash{1, 2, 3},
+		Bits:      templates.Diffs{2: 0x207fffff},
+		Merkles:   templates.Merkles{},
+		Timestamp: time.Now().Truncate(time.Second),
+	}
+	msg.SetTxs(2, testTxs(1))
+	s.SetTemplate(msg)
+	conn, e := net.Dial("tcp", s.Addrs()[0].String())
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer conn.Close()
+	tc := &testClient{t: t, conn: conn, r: bufio.NewReader(conn)}
+	extraNonce1 := decodeHex(t, tc.call("mining.subscribe", "test/1.0")["result"].([]interface{})[1])
+	if res := tc.call("mining.authorize", "worker", "algo=sha256d,d=1000000"); res["result"] != true {
+		t.Fatalf("authorization failed: %v", res)
+	}
+	w := parseNotify(t, tc.notification("mining.notify"))
+	extraNonce2 := []byte{0, 0, 0, 7}
+	target := bits.CompactToBig(w.bits)
+	var blockNonces []uint32
+	var otherNonce uint32
+	var foundOther bool
+	for nonce := uint32(0); len(blockNonces) < 2 || !foundOther; nonce++ {
+		if _, hash := w.header(t, extraNonce1, extraNonce2, nonce, height); blockchain.HashToBig(&hash).Cmp(target) > 0 {
+			otherNonce, foundOther = nonce, true
+		} else if len(blockNonces) < 2 {
+			blockNonces = append(blockNonces, nonce)
+		}
+	}
+	submit := func(nonce uint32) map[string]interface{} {
+		return tc.call(
+			"mining.submit", "worker", w.jobID, hex.EncodeToString(extraNonce2),
+			fmt.Sprintf("%08x", w.ntime), fmt.Sprintf("%08x", nonce),
+		)
+	}
+	block := func(nonce uint32) {
+		select {
+		case blk := <-submitter.blocks:
+			if blk.WireBlock().Header.Nonce != nonce {
+				t.Fatalf("submitted block with nonce %d, want %d", blk.WireBlock().Header.Nonce, nonce)
+			}
+		case <-time.After(time.Second * 10):
+			t.Fatalf("block with nonce %d was not submitted", nonce)
+		}
+	}
+	if res := submit(otherNonce); res["error"] == nil || res["error"].([]interface{})[0].(float64) != errLowDiff {
+		t.Fatalf("share that is not a block was not rejected: %v", res)
+	}
+	if res := submit(blockNonces[0]); res["result"] != true {
+		t.Fatalf("block share was rejected: %v", res)
+	}
+	block(blockNonces[0])
+	want, _ := new(big.Float).Quo(
+		new(big.Float).SetInt(fork.GetMinDiff("sha256d", height)), new(big.Float).SetInt(target),
+	).Float64()
+	if d := <-credited; d != want {
+		t.Fatalf("block share credited at difficulty %g, want the block difficulty %g", d, want)
+	}
+	// the block is submitted even though the share is not credited
+	creditErr = errors.New("ledger is not writable")
+	if res := submit(blockNonces[1]); res["error"] == nil {
+		t.Fatalf("share that could not be credited was accepted: %v", res)
+	}
+	block(blockNonces[1])
+}
+
+// TestRetargetFloor ensures that the lowest share difficulty accepted after an adjustment is the difficulty from before
+// that adjustment and not the lowest the miner ever had, and that messages to a miner that does not read them are
+// queued without blocking the server until it is disconnected.
+func TestRetargetFloor(t *testing.T) {
+	quit := qu.T()
+	defer quit.Q()
+	s, e := New(Config{Submitter: &testSubmitter{}, ShareTime: time.Second}, quit)
+	if e != nil {
+		t.Fatal(e)
+	}
+	conn, other := net.Pipe()
+	defer other.Close()
+	c := newClient(1, conn, 1)
+	c.subscribed, c.authorized = true, true
+	now := c.lastRetarget
+	adjust := func(elapsed time.Duration, want float64) {
+		now = now.Add(elapsed)
+		c.shares = retargetShares
+		s.Lock()
+		s.retarget(c, now)
+		s.Unlock()
+		if c.difficulty != want {
+			t.Fatalf("share difficulty %g, want %g", c.difficulty, want)
+		}
+	}
+	// too few shares halves the difficulty, too many doubles it
+	adjust(time.Second*retargetShares*2, 0.5)
+	adjust(time.Second*retargetShares/2, 1)
+	adjust(time.Second*retargetShares/2, 2)
+	if c.prevDifficulty != 1 {
+		t.Fatalf("share difficulty floor %g, want the difficulty before the last adjustment 1", c.prevDifficulty)
+	}
+	// nothing reads from the connection, so the queue fills up and the miner is disconnected
+	for i := 0; i <= sendQueueSize; i++ {
+		if e = c.send(&response{Method: "mining.set_difficulty", Params: []interface{}{1}}); e != nil {
+			break
+		}
+	}
+	if e == nil {
+		t.Fatal("a miner that does not read its messages was not disconnected")
+	}
+	select {
+	case <-c.closed.Wait():
+	default:
+		t.Fatal("connection of a miner that does not read its messages was not closed")
+	}
+}
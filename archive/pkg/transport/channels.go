@@ -1,17 +1,21 @@
 package transport
 
 import (
+	"bytes"
 	"crypto/cipher"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"net"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/p9c/log"
 
 	"github.com/p9c/qu"
+	"golang.org/x/crypto/ed25519"
 
 	"github.com/p9c/pod/pkg/fec"
 	"github.com/p9c/pod/pkg/gcm"
@@ -49,9 +53,16 @@ type (
 		firstSender     *string
 		lastSent        *time.Time
 		MaxDatagramSize int
-		receiveCiph     cipher.AEAD
+		networkCiph     cipher.AEAD
+		identity        *Identity
+		trust           *Trust
+		sessionMx       sync.Mutex
+		session         *sendSession
+		next            *sendSession
+		pending         map[uint64]*pendingSession
+		sessions        map[uint64]*recvSession
+		unpinned        map[string]struct{}
 		Receiver        *net.UDPConn
-		sendCiph        cipher.AEAD
 		Sender          *net.UDPConn
 	}
 )
@@ -65,41 +76,127 @@ func (c *Channel) SetDestination(dst string) (e error) {
 	return
 }
 
-// Send fires off some data through the configured multicast's outbound.
-func (c *Channel) Send(magic []byte, nonce []byte, data []byte) (
-	n int, e error,
-) {
-	if len(data) == 0 {
-		e = errors.New("not sending empty packet")
+// SendMany sends the shards of a message as produced by GetShards, in the current session of the channel, starting a
+// new session when the current one has been used for too long
+func (c *Channel) SendMany(magic []byte, b [][]byte) (e error) {
+	D.Ln("magic", string(magic), log.Caller("sending from", 1))
+	if len(b) == 0 {
+		e = errors.New("not sending empty message")
 		E.Ln(e)
 		return
 	}
-	var msg []byte
-	if msg, e = EncryptMessage(c.Creator, c.sendCiph, magic, nonce, data); E.Chk(e) {
+	var size int
+	for i := range b {
+		size += len(b[i])
+	}
+	c.sessionMx.Lock()
+	defer c.sessionMx.Unlock()
+	if c.session == nil || c.session.expired(len(b), size) {
+		if e = c.rotate(); E.Chk(e) {
+			return
+		}
+		if e = c.announce(); E.Chk(e) {
+			return
+		}
+	}
+	seq := c.session.seq
+	c.session.seq++
+	for i := range b {
+		if _, e = c.Sender.Write(c.session.seal(c.identity, magic, seq, b[i])); E.Chk(e) {
+		}
 	}
-	n, e = c.Sender.Write(msg)
-	// D.Ln(msg)
 	return
 }
 
-// SendMany sends a BufIter of shards as produced by GetShards
-func (c *Channel) SendMany(magic []byte, b [][]byte) (e error) {
-	D.Ln("magic", string(magic), log.Caller("sending from", 1))
-	var nonce []byte
-	if nonce, e = GetNonce(c.sendCiph); E.Chk(e) {
-	} else {
-		for i := 0; i < len(b); i++ {
-			// D.Ln(i)
-			// D.Ln("segment length", len(b[i]))
-			if _, e = c.Send(magic, nonce, b[i]); E.Chk(e) {
-				// debug.PrintStack()
-			}
+// rotate replaces the session of the channel with the next one, which has been announced for a while so receivers have
+// its key already, or with a new one if there is no next session yet. The keys of the old session are wiped so that
+// its messages can't be decrypted with anything kept by the sender.
+//
+// This function MUST be called with the session lock held.
+func (c *Channel) rotate() (e error) {
+	next := c.next
+	if next == nil {
+		if next, e = newSendSession(); E.Chk(e) {
+			return
+		}
+	}
+	if c.session != nil {
+		c.session.forget()
+	}
+	D.Ln(c.Creator, "starting new multicast session")
+	c.session, c.next = next, nil
+	return
+}
+
+// announce sends the announcements of the current session and, when the current session is due to be replaced soon,
+// of the next one.
+//
+// This function MUST be called with the session lock held.
+func (c *Channel) announce() (e error) {
+	if c.session.expired(0, 0) {
+		if e = c.rotate(); E.Chk(e) {
+			return
+		}
+	}
+	if c.next == nil && time.Since(c.session.created) >= maxSessionAge-sessionOverlap {
+		if c.next, e = newSendSession(); E.Chk(e) {
+			return
+		}
+	}
+	for _, session := range []*sendSession{c.session, c.next} {
+		if session == nil {
+			continue
+		}
+		var hello []byte
+		if hello, e = c.sealHandshake(HelloMagic, session.hello(c.identity)); E.Chk(e) {
+			return
+		}
+		if _, e = c.Sender.Write(hello); E.Chk(e) {
+			return
 		}
-		// T.Ln(c.Creator, "sent packets", string(magic), hex.EncodeToString(nonce), c.Sender.LocalAddr(), c.Sender.RemoteAddr())
 	}
 	return
 }
 
+// announcer repeats the announcements of the sessions of the channel once it has started sending, so receivers that
+// start later can join them, and the next session is joined before it is used
+func (c *Channel) announcer(quit qu.C) {
+	ticker := time.NewTicker(helloInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.sessionMx.Lock()
+			if c.session != nil {
+				if e := c.announce(); E.Chk(e) {
+				}
+			}
+			c.sessionMx.Unlock()
+		case <-quit.Wait():
+			return
+		}
+	}
+}
+
+// sealHandshake encrypts a handshake packet with the key derived from the pre shared key, so only members of the
+// network can take part in the handshakes
+func (c *Channel) sealHandshake(magic, plain []byte) (packet []byte, e error) {
+	var nonce []byte
+	if nonce, e = GetNonce(c.networkCiph); E.Chk(e) {
+		return
+	}
+	return c.networkCiph.Seal(append(append([]byte{}, magic...), nonce...), nonce, plain, magic), nil
+}
+
+// openHandshake decrypts a handshake packet sealed by sealHandshake
+func (c *Channel) openHandshake(packet []byte) (plain []byte, e error) {
+	nonceSize := c.networkCiph.NonceSize()
+	if len(packet) < 4+nonceSize {
+		return nil, errors.New("handshake packet is too short")
+	}
+	return c.networkCiph.Open(nil, packet[4:4+nonceSize], packet[4+nonceSize:], packet[:4])
+}
+
 // Close the multicast
 func (c *Channel) Close() (e error) {
 	// if e = c.Sender.Close(); E.Chk(e) {
@@ -118,37 +215,60 @@ func GetShards(data []byte) (shards [][]byte) {
 	return
 }
 
-// NewUnicastChannel sets up a listener and sender for a specified destination
-func NewUnicastChannel(
-	creator string, ctx interface{}, key []byte, sender, receiver string,
-	maxDatagramSize int,
-	handlers Handlers, quit qu.C,
+// newChannel sets up the keys of a channel. The network key derived from the pre shared key only encrypts the
+// handshakes of the sessions, which are signed with the identity, a random one if it is nil. Messages are sent and
+// received in sessions with random keys that are exchanged with ephemeral keys in the handshakes.
+func newChannel(
+	creator string, ctx interface{}, key []byte, id *Identity, trust *Trust, maxDatagramSize int,
 ) (channel *Channel, e error) {
 	channel = &Channel{
 		Creator:         creator,
 		MaxDatagramSize: maxDatagramSize,
 		buffers:         make(map[string]*MsgBuffer),
 		context:         ctx,
+		identity:        id,
+		trust:           trust,
+		pending:         make(map[uint64]*pendingSession),
+		sessions:        make(map[uint64]*recvSession),
+		unpinned:        make(map[string]struct{}),
 	}
-	var magics []string
-	bytes := make([]byte, len(key))
-	copy(bytes, key)
-	for i := range handlers {
-		magics = append(magics, i)
+	if channel.identity == nil {
+		if channel.identity, e = NewIdentity(); E.Chk(e) {
+			return
+		}
 	}
-	if channel.sendCiph, e = gcm.GetCipher(bytes); E.Chk(e) {
+	networkKey := gcm.Key(key)
+	for i := range key {
+		key[i] = 0
 	}
-	copy(bytes, key)
-	if channel.receiveCiph, e = gcm.GetCipher(bytes); E.Chk(e) {
+	if channel.networkCiph, e = gcm.NewCipher(networkKey); E.Chk(e) {
 	}
-	for i := range bytes {
-		bytes[i] = 0
-		key[i] = 0
+	for i := range networkKey {
+		networkKey[i] = 0
 	}
+	return
+}
+
+// NewUnicastChannel sets up a listener and sender for a specified destination
+func NewUnicastChannel(
+	creator string, ctx interface{}, key []byte, id *Identity, trust *Trust, sender, receiver string,
+	maxDatagramSize int,
+	handlers Handlers, quit qu.C,
+) (channel *Channel, e error) {
+	if channel, e = newChannel(creator, ctx, key, id, trust, maxDatagramSize); E.Chk(e) {
+		return
+	}
+	var magics []string
+	for i := range handlers {
+		magics = append(magics, i)
+	}
+	channel.Ready = qu.T()
 	if channel.Receiver, e = Listen(receiver, channel, maxDatagramSize, handlers, quit); E.Chk(e) {
 	}
 	if channel.Sender, e = NewSender(sender, maxDatagramSize); E.Chk(e) {
 	}
+	go channel.announcer(quit)
+	channel.Ready.Q()
 	D.Ln("starting unicast multicast:", channel.Creator, sender, receiver, magics)
 	return
 }
@@ -194,36 +314,22 @@ func Listen(
 
 // NewBroadcastChannel returns a broadcaster and listener with a given handler
 // on a multicast address and specified port. The handlers define the messages
-// that will be processed and any other messages are ignored
+// that will be processed and any other messages are ignored. Messages with the magics of the trust are only accepted from
+// the pinned keys, if any
 func NewBroadcastChannel(
-	creator string, ctx interface{}, key []byte, port int, maxDatagramSize int,
+	creator string, ctx interface{}, key []byte, id *Identity, trust *Trust, port int, maxDatagramSize int,
 	handlers Handlers,
 	quit qu.C,
 ) (channel *Channel, e error) {
-	channel = &Channel{
-		Creator:         creator,
-		MaxDatagramSize: maxDatagramSize,
-		buffers:         make(map[string]*MsgBuffer),
-		context:         ctx,
-		Ready:           qu.T(),
-	}
-	bytes := make([]byte, len(key))
-	copy(bytes, key)
-	if channel.sendCiph, e = gcm.GetCipher(bytes); E.Chk(e) {
+	if channel, e = newChannel(creator, ctx, key, id, trust, maxDatagramSize); E.Chk(e) {
 		panic(e)
 	}
-	copy(bytes, key)
-	if channel.receiveCiph, e = gcm.GetCipher(bytes); E.Chk(e) {
-		panic(e)
-	}
-	for i := range bytes {
-		key[i] = 0
-		bytes[i] = 0
-	}
+	channel.Ready = qu.T()
 	if channel.Receiver, e = ListenBroadcast(port, channel, maxDatagramSize, handlers, quit); E.Chk(e) {
 	}
 	if channel.Sender, e = NewBroadcaster(port, maxDatagramSize); E.Chk(e) {
 	}
+	go channel.announcer(quit)
 	channel.Ready.Q()
 	return
 }
@@ -308,21 +414,50 @@ out:
 			case success:
 			}
 		}
+		if numBytes < 4 {
+			continue
+		}
 		// Filter messages by magic, if there is no match in the map the packet is
 		// ignored
 		magic := string(buffer[:4])
-		if handler, ok := handlers[magic]; ok {
-			if channel.lastSent != nil && channel.firstSender != nil {
-				*channel.lastSent = time.Now()
+		// sessions are only joined by channels that have handlers for the messages sent in them
+		switch magic {
+		case string(HelloMagic):
+			if len(handlers) > 0 {
+				channel.receiveHello(buffer[:numBytes])
+			}
+			continue
+		case string(JoinMagic):
+			channel.receiveJoin(buffer[:numBytes], src)
+			continue
+		case string(KeyMagic):
+			if len(handlers) > 0 {
+				channel.receiveKey(buffer[:numBytes])
 			}
+			continue
+		}
+		if handler, ok := handlers[magic]; ok {
 			msg := buffer[:numBytes]
-			nL := channel.receiveCiph.NonceSize()
-			nonceBytes := msg[4 : 4+nL]
-			nonce := string(nonceBytes)
+			if len(msg) < packetOverhead {
+				continue
+			}
+			session, ok := channel.sessions[binary.BigEndian.Uint64(msg[4:4+sessionIDSize])]
+			if !ok {
+				T.Ln(channel.Creator, "dropping packet of unknown session from", src)
+				continue
+			}
+			var seq uint64
 			var shard []byte
-			if shard, e = channel.receiveCiph.Open(nil, nonceBytes, msg[4+len(nonceBytes):], nil); e != nil {
+			if seq, shard, e = session.open(msg); e != nil {
+				D.Ln(channel.Creator, "dropping packet from", src, e)
 				continue
 			}
+			if channel.lastSent != nil && channel.firstSender != nil {
+				*channel.lastSent = time.Now()
+			}
+			var seqBytes [8]byte
+			binary.BigEndian.PutUint64(seqBytes[:], seq)
+			nonce := string(msg[4:4+sessionIDSize]) + string(seqBytes[:])
 			// D.Ln("read", numBytes, "from", src, e, hex.EncodeToString(msg))
 			if bn, ok := channel.buffers[nonce]; ok {
 				if !bn.Decoded {
@@ -335,6 +470,9 @@ out:
 						}
 						// D.F("received packet with magic %s from %s len %d bytes", magic, src.String(), len(cipherText))
 						bn.Decoded = true
+						if !channel.accept(magic, session.pub) {
+							continue
+						}
 						if e = handler(channel.context, src, address, cipherText); E.Chk(e) {
 							continue
 						}
@@ -365,6 +503,131 @@ out:
 	}
 }
 
+// receiveHello asks for the key of the session announced in a hello packet if it is valid and not joined yet. The
+// request is repeated with every announcement until the key arrives.
+func (c *Channel) receiveHello(msg []byte) {
+	var e error
+	var hello []byte
+	if hello, e = c.openHandshake(msg); e != nil || len(hello) != helloSize {
+		return
+	}
+	// a repeated announcement must not reset the replay window of the session
+	id := binary.BigEndian.Uint64(hello[ed25519.PublicKeySize:])
+	if _, ok := c.sessions[id]; ok {
+		return
+	}
+	if bytes.Equal(hello[:ed25519.PublicKeySize], c.identity.key.Public().(ed25519.PublicKey)) {
+		return
+	}
+	session, ok := c.pending[id]
+	if !ok {
+		if id, session, e = parseHello(hello); e != nil {
+			D.Ln(c.Creator, "ignoring session announcement:", e)
+			return
+		}
+		for i := range c.pending {
+			if time.Since(c.pending[i].created) > maxSessionAge+clockSkew {
+				delete(c.pending, i)
+			}
+		}
+		if len(c.pending) >= maxSessions {
+			return
+		}
+		c.pending[id] = session
+	}
+	var join []byte
+	if join, e = c.sealHandshake(JoinMagic, session.join(c.identity, id)); E.Chk(e) {
+		return
+	}
+	if _, e = c.Sender.Write(join); E.Chk(e) {
+	}
+}
+
+// receiveJoin gives the key of the current or next session of the channel to a receiver that asked for it
+func (c *Channel) receiveJoin(msg []byte, src net.Addr) {
+	var e error
+	var join []byte
+	if join, e = c.openHandshake(msg); e != nil || len(join) != joinSize {
+		return
+	}
+	sessionID := join[ed25519.PublicKeySize : ed25519.PublicKeySize+sessionIDSize]
+	var reply []byte
+	var receiver ed25519.PublicKey
+	c.sessionMx.Lock()
+	for _, session := range []*sendSession{c.session, c.next} {
+		if session != nil && bytes.Equal(session.id, sessionID) {
+			reply, receiver, e = session.giveKey(c.identity, join)
+			break
+		}
+	}
+	c.sessionMx.Unlock()
+	if e != nil {
+		D.Ln(c.Creator, "ignoring request for a session key from", src, e)
+		return
+	}
+	if reply == nil {
+		return
+	}
+	T.F("%s giving the key of multicast session %x to %x", c.Creator, sessionID, []byte(receiver))
+	var packet []byte
+	if packet, e = c.sealHandshake(KeyMagic, reply); E.Chk(e) {
+		return
+	}
+	if _, e = c.Sender.Write(packet); E.Chk(e) {
+	}
+}
+
+// receiveKey adds the session a key packet gives the key of, if it is for a session the channel asked for the key of.
+// Key packets for other receivers are ignored, as every member of the group receives them.
+func (c *Channel) receiveKey(msg []byte) {
+	var e error
+	var reply []byte
+	if reply, e = c.openHandshake(msg); e != nil || len(reply) != keySize {
+		return
+	}
+	id := binary.BigEndian.Uint64(reply)
+	pending, ok := c.pending[id]
+	if !ok {
+		return
+	}
+	var session *recvSession
+	if session, e = pending.receiveKey(c.identity, reply); e != nil {
+		return
+	}
+	delete(c.pending, id)
+	var oldest uint64
+	var oldestCreated time.Time
+	for i := range c.sessions {
+		if time.Since(c.sessions[i].created) > maxSessionAge+clockSkew {
+			delete(c.sessions, i)
+		} else if oldestCreated.IsZero() || c.sessions[i].created.Before(oldestCreated) {
+			oldest, oldestCreated = i, c.sessions[i].created
+		}
+	}
+	if len(c.sessions) >= maxSessions {
+		delete(c.sessions, oldest)
+	}
+	T.F("%s joined multicast session %016x of %x", c.Creator, id, []byte(session.pub))
+	c.sessions[id] = session
+}
+
+// accept returns whether a message with the given magic from a sender with the given public key is trusted, warning
+// once for each key that is accepted only because no keys are pinned
+func (c *Channel) accept(magic string, pub []byte) bool {
+	if !c.trust.Trusted(magic, pub) {
+		D.F("%s ignoring %s message from multicast key %x that is not pinned", c.Creator, magic, pub)
+		return false
+	}
+	if c.trust.unpinned(magic) {
+		if _, ok := c.unpinned[string(pub)]; !ok {
+			c.unpinned[string(pub)] = struct{}{}
+			W.F("%s accepting %s messages from multicast key %x, pin it to stop others from sending them",
+				c.Creator, magic, pub)
+		}
+	}
+	return true
+}
+
 func PrevCallers() (out string) {
 	for i := 0; i < 10; i++ {
 		_, loc, iline, _ := runtime.Caller(i)
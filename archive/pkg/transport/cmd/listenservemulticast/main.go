@@ -27,7 +27,7 @@ func main() {
 	var c *transport.Channel
 	var e error
 	if c, e = transport.NewBroadcastChannel(
-		"test", nil, []byte("cipher"),
+		"test", nil, []byte("cipher"), nil, nil,
 		1234, 8192, transport.Handlers{
 			TestMagic: func(
 				ctx interface{}, src net.Addr, dst string,
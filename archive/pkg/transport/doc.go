@@ -1,3 +1,45 @@
-// Package transport provides a listener and sender channel for unicast and multicast UDP IPv4 short message chat
-// protocol with a pre shared key, forward error correction facilities with a nice friendly declaration syntax
+/*
+Package transport provides a listener and sender channel for unicast and multicast UDP IPv4 short message chat
+protocol with a pre shared key, forward error correction facilities with a nice friendly declaration syntax
+
+Sessions
+
+Every channel has a static ed25519 identity. Controllers keep theirs in their data directory so miners can pin it, other
+channels, such as the workers of a miner that have not been given the identity of the miner yet, generate a new one each
+time they start.
+
+Messages are sent in sessions. A sender starts a session with a random id, a random session key and an ephemeral X25519
+key, and announces it in a hello signed with its identity. Receivers that see the hello generate their own ephemeral key
+and ask for the session key with a join signed with their identity. The sender answers each join with the session key
+encrypted with a key derived with HKDF-SHA256 from the X25519 secret of both ephemeral keys, the session id and both
+identities. The handshake packets are encrypted with the key derived from the pre shared key, so only members of the
+network take part in them.
+
+	hello   'h' 'l' 'o' 0x02 | nonce (12) | sealed(public key (32) | session id (8) | created (8) | ephemeral key (32) | signature (64))
+	join    'j' 'o' 'n' 0x01 | nonce (12) | sealed(public key (32) | session id (8) | ephemeral key (32) | signature (64))
+	key     'k' 'e' 'y' 0x01 | nonce (12) | sealed(session id (8) | ephemeral key of the receiver (32) | wrapped session key (48))
+
+The hello is repeated every second, so receivers that start later join the session, and receivers repeat their join until
+they have the key. Sessions are replaced every ten minutes, and well before the counter or the amount of encrypted data
+reach the limits of GCM. A minute before a session is replaced the next one is announced along with it, so receivers
+already have its key when it is used. The ephemeral keys and the session keys are wiped once they are no longer needed, so
+a pre shared key or identity that is leaked later does not open the messages of earlier sessions.
+
+The packets of the messages carry the session id and a packet counter, which is the GCM nonce, and are signed with the
+identity of the sender. The shard of the message is preceded by the sequence number of the message in the session, which
+groups the shards of a message for the forward error correction.
+
+	packet  magic (4) | session id (8) | counter (8) | sealed(message sequence (8) | shard) | signature (64)
+
+Receivers drop packets of sessions they have no key for, packets whose signature does not match the key that announced
+the session, and packets with a counter they have already seen or that is too far behind the highest one. Hellos that are
+older than the lifetime of a session are ignored.
+
+Everyone who knows the pre shared key can join the sessions and read the messages, as a multicast group cannot keep
+messages from its own members. The signatures make sure that the pre shared key alone is not enough to send messages in
+the name of another node, and receivers can pin the keys that messages with certain magics are accepted from: miners pin
+the key of the controller for the work it sends out, and controllers pin the keys of the miners (MulticastWorkerKeys)
+for the solutions, shares and hashrate reports. Miners keep their identity in their data directory and give it to their
+workers, so one key covers all of the workers of a miner.
+*/
 package transport
@@ -0,0 +1,121 @@
+package transport
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/ed25519"
+)
+
+// Identity is the static key a node signs its session announcements and packets with
+type Identity struct {
+	key ed25519.PrivateKey
+}
+
+// NewIdentity generates a random identity, for senders whose key does not need to be pinned by anyone
+func NewIdentity() (id *Identity, e error) {
+	var key ed25519.PrivateKey
+	if _, key, e = ed25519.GenerateKey(rand.Reader); E.Chk(e) {
+		return
+	}
+	return &Identity{key: key}, nil
+}
+
+// LoadIdentity reads the identity stored in a file, generating and storing a new one if the file does not exist yet
+func LoadIdentity(path string) (id *Identity, e error) {
+	var b []byte
+	if b, e = ioutil.ReadFile(path); e != nil {
+		if !os.IsNotExist(e) {
+			E.Ln(e)
+			return
+		}
+		if id, e = NewIdentity(); E.Chk(e) {
+			return
+		}
+		if e = os.MkdirAll(filepath.Dir(path), 0700); E.Chk(e) {
+			return
+		}
+		seed := hex.EncodeToString(id.key.Seed())
+		if e = ioutil.WriteFile(path, []byte(seed+"\n"), 0600); E.Chk(e) {
+			return
+		}
+		I.Ln("generated new multicast identity", id.Public(), "in", path)
+		return
+	}
+	var seed []byte
+	if seed, e = hex.DecodeString(strings.TrimSpace(string(b))); E.Chk(e) {
+		return
+	}
+	if id, e = IdentityFromSeed(seed); e != nil {
+		return nil, fmt.Errorf("multicast identity in %s: %v", path, e)
+	}
+	return
+}
+
+// IdentityFromSeed returns the identity with the given private seed, as returned by Seed
+func IdentityFromSeed(seed []byte) (id *Identity, e error) {
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("identity seed has %d bytes, expected %d", len(seed), ed25519.SeedSize)
+	}
+	return &Identity{key: ed25519.NewKeyFromSeed(seed)}, nil
+}
+
+// Seed returns the private seed of the identity, for handing it to the worker processes that send messages in its name
+func (id *Identity) Seed() []byte {
+	return id.key.Seed()
+}
+
+// Public returns the public key of the identity in hex, the form in which it is pinned
+func (id *Identity) Public() string {
+	return hex.EncodeToString(id.key.Public().(ed25519.PublicKey))
+}
+
+// Trust is the set of pinned static keys the senders of messages with the given magics must have. Messages with other
+// magics are accepted from any sender that knows the multicast password, as are all messages when no keys are pinned.
+type Trust struct {
+	keys   map[string]struct{}
+	magics map[string]struct{}
+}
+
+// NewTrust returns the trust for a list of hex encoded public keys, applied to messages with the given magics
+func NewTrust(keys []string, magics ...[]byte) (t *Trust, e error) {
+	t = &Trust{keys: make(map[string]struct{}), magics: make(map[string]struct{})}
+	for i := range keys {
+		var key []byte
+		if key, e = hex.DecodeString(keys[i]); e != nil || len(key) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid multicast public key " + keys[i])
+		}
+		t.keys[string(key)] = struct{}{}
+	}
+	for i := range magics {
+		t.magics[string(magics[i])] = struct{}{}
+	}
+	return
+}
+
+// Trusted returns whether a message with the given magic from a sender with the given public key is accepted
+func (t *Trust) Trusted(magic string, pub ed25519.PublicKey) bool {
+	if t == nil || len(t.keys) == 0 {
+		return true
+	}
+	if _, ok := t.magics[magic]; !ok {
+		return true
+	}
+	_, ok := t.keys[string(pub)]
+	return ok
+}
+
+// unpinned returns whether messages with the given magic should come from pinned keys but none are pinned
+func (t *Trust) unpinned(magic string) bool {
+	if t == nil || len(t.keys) > 0 {
+		return false
+	}
+	_, ok := t.magics[magic]
+	return ok
+}
@@ -0,0 +1,353 @@
+package transport
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/ed25519"
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/p9c/pod/pkg/gcm"
+)
+
+var (
+	// HelloMagic is the marker for the packets announcing a session
+	HelloMagic = []byte{'h', 'l', 'o', 2}
+	// JoinMagic is the marker for the packets a receiver asks for the key of a session with
+	JoinMagic = []byte{'j', 'o', 'n', 1}
+	// KeyMagic is the marker for the packets a sender gives a receiver the key of a session in
+	KeyMagic = []byte{'k', 'e', 'y', 1}
+)
+
+const (
+	// maxSessionPackets is the number of packets after which a session is replaced, far below the point where the
+	// counter nonces could repeat
+	maxSessionPackets = 1 << 31
+	// maxSessionBytes is the amount of data after which a session is replaced, as a GCM key should not encrypt more
+	// than about 4gb
+	maxSessionBytes = 1 << 32
+	// maxSessionAge is the time after which a session is replaced
+	maxSessionAge = time.Minute * 10
+	// sessionOverlap is how long before a session is replaced the next one is announced, so receivers have its key by
+	// the time it is used
+	sessionOverlap = time.Minute
+	// clockSkew is how far the clocks of the nodes on the network may disagree
+	clockSkew = time.Minute * 5
+	// helloInterval is how often a sender repeats the announcement of its session, so receivers that start later can
+	// join it, and how often a receiver repeats its request for the key of a session it has not received yet
+	helloInterval = time.Second
+	// maxSessions is the most sessions a receiver keeps track of
+	maxSessions = 1024
+	// replayWindow is how many counters below the highest one seen are still accepted if not seen before
+	replayWindow = 64
+	// sessionIDSize, counterSize, exchangeKeySize and sessionKeySize are the sizes of the fields of packets
+	sessionIDSize   = 8
+	counterSize     = 8
+	exchangeKeySize = 32
+	sessionKeySize  = 32
+	// helloSize, joinSize and keySize are the sizes of the plaintext of the handshake packets
+	helloSize = ed25519.PublicKeySize + sessionIDSize + 8 + exchangeKeySize + ed25519.SignatureSize
+	joinSize  = ed25519.PublicKeySize + sessionIDSize + exchangeKeySize + ed25519.SignatureSize
+	keySize   = sessionIDSize + exchangeKeySize + sessionKeySize + 16
+	// packetOverhead is the size of a data packet other than its shard
+	packetOverhead = 4 + sessionIDSize + counterSize + 8 + 16 + ed25519.SignatureSize
+)
+
+// sendSession is the state of the session a channel sends its packets in
+type sendSession struct {
+	id      []byte
+	created time.Time
+	// exchange is the private ephemeral key the session key is given to receivers with, which is forgotten with the
+	// session, and exchangePub the public key announced in the hello
+	exchange    []byte
+	exchangePub []byte
+	ciph        cipher.AEAD
+	key         []byte
+	counter     uint64
+	seq         uint64
+	bytes       uint64
+}
+
+// pendingSession is a session of another sender that a receiver has asked for the key of
+type pendingSession struct {
+	pub         ed25519.PublicKey
+	created     time.Time
+	senderPub   []byte
+	exchange    []byte
+	exchangePub []byte
+	lastJoin    time.Time
+}
+
+// recvSession is the state of a session of another sender
+type recvSession struct {
+	pub     ed25519.PublicKey
+	created time.Time
+	ciph    cipher.AEAD
+	highest uint64
+	seen    uint64
+}
+
+// newExchangeKey generates an ephemeral X25519 key pair
+func newExchangeKey() (private, public []byte, e error) {
+	private = make([]byte, curve25519.ScalarSize)
+	if _, e = io.ReadFull(rand.Reader, private); E.Chk(e) {
+		return
+	}
+	public, e = curve25519.X25519(private, curve25519.Basepoint)
+	return
+}
+
+// wrapCipher derives the cipher a session key is given to one receiver with, from the ephemeral keys of the sender and
+// the receiver and both of their identities
+func wrapCipher(
+	secret, sessionID []byte, sender, receiver ed25519.PublicKey, senderExchange, receiverExchange []byte,
+) (ciph cipher.AEAD, e error) {
+	info := append([]byte("pod multicast session key"), sender...)
+	info = append(append(append(info, receiver...), senderExchange...), receiverExchange...)
+	key := make([]byte, 32)
+	if _, e = io.ReadFull(hkdf.New(sha256.New, secret, sessionID, info), key); E.Chk(e) {
+		return
+	}
+	ciph, e = gcm.NewCipher(key)
+	for i := range key {
+		key[i] = 0
+	}
+	return
+}
+
+// newSendSession starts a new session with a random id and key
+func newSendSession() (s *sendSession, e error) {
+	s = &sendSession{
+		id:      make([]byte, sessionIDSize),
+		key:     make([]byte, sessionKeySize),
+		created: time.Now(),
+	}
+	if _, e = io.ReadFull(rand.Reader, s.id); E.Chk(e) {
+		return
+	}
+	if _, e = io.ReadFull(rand.Reader, s.key); E.Chk(e) {
+		return
+	}
+	if s.exchange, s.exchangePub, e = newExchangeKey(); E.Chk(e) {
+		return
+	}
+	s.ciph, e = gcm.NewCipher(s.key)
+	return
+}
+
+// expired returns whether the session has to be replaced before sending a message with the given number of packets
+// and bytes
+func (s *sendSession) expired(packets int, size int) bool {
+	return s.counter+uint64(packets) >= maxSessionPackets || s.bytes+uint64(size) >= maxSessionBytes ||
+		time.Since(s.created) >= maxSessionAge
+}
+
+// forget wipes the secrets of a session that has been replaced
+func (s *sendSession) forget() {
+	for i := range s.key {
+		s.key[i] = 0
+	}
+	for i := range s.exchange {
+		s.exchange[i] = 0
+	}
+}
+
+// hello returns the plaintext of the announcement of the session, signed with the identity
+func (s *sendSession) hello(id *Identity) []byte {
+	b := make([]byte, 0, helloSize)
+	b = append(b, id.key.Public().(ed25519.PublicKey)...)
+	b = append(b, s.id...)
+	var created [8]byte
+	binary.BigEndian.PutUint64(created[:], uint64(s.created.UnixNano()))
+	b = append(b, created[:]...)
+	b = append(b, s.exchangePub...)
+	return append(b, ed25519.Sign(id.key, append(append([]byte{}, HelloMagic...), b...))...)
+}
+
+// giveKey checks the signature of a request for the key of the session and returns the plaintext of the reply, with the
+// session key encrypted for the ephemeral key of the receiver, along with the identity of the receiver
+func (s *sendSession) giveKey(id *Identity, join []byte) (reply []byte, receiver ed25519.PublicKey, e error) {
+	if len(join) != joinSize {
+		return nil, nil, errors.New("key request has the wrong size")
+	}
+	signed, sig := join[:joinSize-ed25519.SignatureSize], join[joinSize-ed25519.SignatureSize:]
+	receiver = ed25519.PublicKey(append([]byte{}, signed[:ed25519.PublicKeySize]...))
+	if !ed25519.Verify(receiver, append(append([]byte{}, JoinMagic...), signed...), sig) {
+		return nil, nil, errors.New("key request has an invalid signature")
+	}
+	rest := signed[ed25519.PublicKeySize:]
+	if !bytes.Equal(rest[:sessionIDSize], s.id) {
+		return nil, nil, errors.New("key request is for another session")
+	}
+	receiverExchange := rest[sessionIDSize:]
+	var secret []byte
+	if secret, e = curve25519.X25519(s.exchange, receiverExchange); e != nil {
+		return
+	}
+	var ciph cipher.AEAD
+	if ciph, e = wrapCipher(
+		secret, s.id, id.key.Public().(ed25519.PublicKey), receiver, s.exchangePub, receiverExchange,
+	); E.Chk(e) {
+		return
+	}
+	reply = append(append(make([]byte, 0, keySize), s.id...), receiverExchange...)
+	// the wrapping key is only ever used for this one session key, so the nonce can be fixed
+	return ciph.Seal(reply, make([]byte, ciph.NonceSize()), s.key, reply), receiver, nil
+}
+
+// seal returns the packet for a shard of a message
+func (s *sendSession) seal(id *Identity, magic []byte, seq uint64, shard []byte) []byte {
+	s.counter++
+	s.bytes += uint64(len(shard))
+	header := make([]byte, 0, packetOverhead+len(shard))
+	header = append(header, magic[:4]...)
+	header = append(header, s.id...)
+	var counter [counterSize]byte
+	binary.BigEndian.PutUint64(counter[:], s.counter)
+	header = append(header, counter[:]...)
+	plain := make([]byte, 8, 8+len(shard))
+	binary.BigEndian.PutUint64(plain, seq)
+	plain = append(plain, shard...)
+	packet := s.ciph.Seal(header, counterNonce(s.ciph, s.counter), plain, header)
+	return append(packet, ed25519.Sign(id.key, packet)...)
+}
+
+// counterNonce returns the GCM nonce for a packet counter
+func counterNonce(ciph cipher.AEAD, counter uint64) []byte {
+	nonce := make([]byte, ciph.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-counterSize:], counter)
+	return nonce
+}
+
+// parseHello checks the signature and the age of a session announcement and returns the session, with a new ephemeral
+// key to ask for its key with
+func parseHello(b []byte) (id uint64, s *pendingSession, e error) {
+	if len(b) != helloSize {
+		return 0, nil, errors.New("session announcement has the wrong size")
+	}
+	signed, sig := b[:helloSize-ed25519.SignatureSize], b[helloSize-ed25519.SignatureSize:]
+	pub := ed25519.PublicKey(append([]byte{}, signed[:ed25519.PublicKeySize]...))
+	if !ed25519.Verify(pub, append(append([]byte{}, HelloMagic...), signed...), sig) {
+		return 0, nil, errors.New("session announcement has an invalid signature")
+	}
+	rest := signed[ed25519.PublicKeySize:]
+	created := time.Unix(0, int64(binary.BigEndian.Uint64(rest[sessionIDSize:])))
+	if age := time.Since(created); age > maxSessionAge+clockSkew || age < -clockSkew {
+		return 0, nil, errors.New("session announcement is too old or from the future")
+	}
+	s = &pendingSession{
+		pub:       pub,
+		created:   created,
+		senderPub: append([]byte{}, rest[sessionIDSize+8:]...),
+	}
+	if s.exchange, s.exchangePub, e = newExchangeKey(); E.Chk(e) {
+		return
+	}
+	return binary.BigEndian.Uint64(rest[:sessionIDSize]), s, nil
+}
+
+// join returns the plaintext of the request for the key of a session, signed with the identity of the receiver
+func (s *pendingSession) join(id *Identity, sessionID uint64) []byte {
+	b := make([]byte, 0, joinSize)
+	b = append(b, id.key.Public().(ed25519.PublicKey)...)
+	var sid [sessionIDSize]byte
+	binary.BigEndian.PutUint64(sid[:], sessionID)
+	b = append(b, sid[:]...)
+	b = append(b, s.exchangePub...)
+	return append(b, ed25519.Sign(id.key, append(append([]byte{}, JoinMagic...), b...))...)
+}
+
+// receiveKey opens the session key a sender encrypted for the ephemeral key of the receiver and returns the session.
+// Only the sender of the announcement has the ephemeral key it can be encrypted with, so it needs no signature.
+func (s *pendingSession) receiveKey(id *Identity, reply []byte) (rs *recvSession, e error) {
+	if len(reply) != keySize {
+		return nil, errors.New("session key has the wrong size")
+	}
+	header := reply[:sessionIDSize+exchangeKeySize]
+	if !bytes.Equal(header[sessionIDSize:], s.exchangePub) {
+		return nil, errors.New("session key is for another receiver")
+	}
+	var secret []byte
+	if secret, e = curve25519.X25519(s.exchange, s.senderPub); e != nil {
+		return
+	}
+	var ciph cipher.AEAD
+	if ciph, e = wrapCipher(
+		secret, header[:sessionIDSize], s.pub, id.key.Public().(ed25519.PublicKey), s.senderPub, s.exchangePub,
+	); E.Chk(e) {
+		return
+	}
+	var key []byte
+	if key, e = ciph.Open(nil, make([]byte, ciph.NonceSize()), reply[len(header):], header); e != nil {
+		return
+	}
+	rs = &recvSession{pub: s.pub, created: s.created}
+	rs.ciph, e = gcm.NewCipher(key)
+	for i := range key {
+		key[i] = 0
+	}
+	for i := range s.exchange {
+		s.exchange[i] = 0
+	}
+	return
+}
+
+// open checks the signature of a packet and that its counter was not seen before, and returns the message sequence
+// number and the shard it carries
+func (s *recvSession) open(packet []byte) (seq uint64, shard []byte, e error) {
+	if len(packet) < packetOverhead {
+		return 0, nil, errors.New("packet is too short")
+	}
+	headerSize := 4 + sessionIDSize + counterSize
+	counter := binary.BigEndian.Uint64(packet[4+sessionIDSize : headerSize])
+	if !s.fresh(counter) {
+		return 0, nil, errors.New("packet is a replay")
+	}
+	signed, sig := packet[:len(packet)-ed25519.SignatureSize], packet[len(packet)-ed25519.SignatureSize:]
+	if !ed25519.Verify(s.pub, signed, sig) {
+		return 0, nil, errors.New("packet has an invalid signature")
+	}
+	var plain []byte
+	if plain, e = s.ciph.Open(nil, counterNonce(s.ciph, counter), signed[headerSize:], signed[:headerSize]); e != nil {
+		return
+	}
+	s.mark(counter)
+	return binary.BigEndian.Uint64(plain), plain[8:], nil
+}
+
+// fresh returns whether a packet counter has not been seen before in the session
+func (s *recvSession) fresh(counter uint64) bool {
+	switch {
+	case counter == 0:
+		return false
+	case counter > s.highest:
+		return true
+	case s.highest-counter >= replayWindow:
+		return false
+	default:
+		return s.seen&(1<<(s.highest-counter)) == 0
+	}
+}
+
+// mark records a packet counter as seen
+func (s *recvSession) mark(counter uint64) {
+	if counter > s.highest {
+		shift := counter - s.highest
+		if shift >= replayWindow {
+			s.seen = 0
+		} else {
+			s.seen <<= shift
+		}
+		s.seen |= 1
+		s.highest = counter
+		return
+	}
+	s.seen |= 1 << (s.highest - counter)
+}
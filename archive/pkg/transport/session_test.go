@@ -0,0 +1,247 @@
+package transport
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/p9c/qu"
+	"golang.org/x/crypto/ed25519"
+)
+
+// TestSession checks that a receiver that joined a session through its hello gets the session key in exchange for its
+// ephemeral key, which no other receiver can open, that the packets sealed in the session are opened by it, and that
+// replayed, forged and tampered handshakes and packets are rejected.
+func TestSession(t *testing.T) {
+	sender, e := NewIdentity()
+	if e != nil {
+		t.Fatal(e)
+	}
+	receiver, e := NewIdentity()
+	if e != nil {
+		t.Fatal(e)
+	}
+	ss, e := newSendSession()
+	if e != nil {
+		t.Fatal(e)
+	}
+	id, ps, e := parseHello(ss.hello(sender))
+	if e != nil {
+		t.Fatal(e)
+	}
+	if id != binary.BigEndian.Uint64(ss.id) || !bytes.Equal(ps.pub, sender.key.Public().(ed25519.PublicKey)) {
+		t.Fatal("hello announced another session")
+	}
+	tampered := ss.hello(sender)
+	tampered[len(tampered)-ed25519.SignatureSize-1] ^= 1
+	if _, _, e = parseHello(tampered); e == nil {
+		t.Fatal("hello with a changed ephemeral key was accepted")
+	}
+	join := ps.join(receiver, id)
+	tampered = append([]byte{}, join...)
+	tampered[len(tampered)-ed25519.SignatureSize-1] ^= 1
+	if _, _, e = ss.giveKey(sender, tampered); e == nil {
+		t.Fatal("key request with a changed ephemeral key was accepted")
+	}
+	if _, _, e = ss.giveKey(sender, ps.join(receiver, id+1)); e == nil {
+		t.Fatal("key request for another session was accepted")
+	}
+	reply, who, e := ss.giveKey(sender, join)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !bytes.Equal(who, receiver.key.Public().(ed25519.PublicKey)) {
+		t.Fatal("key request is from another receiver")
+	}
+	// another member of the network that joined the same session can't open the key given to the receiver, even by
+	// putting its own ephemeral key in the reply
+	other, e := NewIdentity()
+	if e != nil {
+		t.Fatal(e)
+	}
+	_, otherPending, e := parseHello(ss.hello(sender))
+	if e != nil {
+		t.Fatal(e)
+	}
+	if _, e = otherPending.receiveKey(other, reply); e == nil {
+		t.Fatal("session key for another receiver was accepted")
+	}
+	stolen := append([]byte{}, reply...)
+	copy(stolen[sessionIDSize:], otherPending.exchangePub)
+	if _, e = otherPending.receiveKey(other, stolen); e == nil {
+		t.Fatal("session key for another receiver was opened")
+	}
+	rs, e := ps.receiveKey(receiver, reply)
+	if e != nil {
+		t.Fatal(e)
+	}
+	// the ephemeral key is wiped once it has been used, so the session key can't be recovered later
+	if !bytes.Equal(ps.exchange, make([]byte, len(ps.exchange))) {
+		t.Fatal("ephemeral key of the receiver was kept")
+	}
+	magic := []byte("TEST")
+	var packets [][]byte
+	for i := 0; i < 3; i++ {
+		packets = append(packets, ss.seal(sender, magic, 7, []byte{byte(i)}))
+	}
+	// out of order delivery within the window is fine
+	for _, i := range []int{1, 0, 2} {
+		seq, shard, e := rs.open(packets[i])
+		if e != nil {
+			t.Fatal(e)
+		}
+		if seq != 7 || !bytes.Equal(shard, []byte{byte(i)}) {
+			t.Fatalf("packet %d opened as message %d shard %x", i, seq, shard)
+		}
+	}
+	for i := range packets {
+		if _, _, e = rs.open(packets[i]); e == nil {
+			t.Fatalf("replay of packet %d was accepted", i)
+		}
+	}
+	// a member of the network that knows the session key still cannot sign for the sender
+	forger, e := NewIdentity()
+	if e != nil {
+		t.Fatal(e)
+	}
+	if _, _, e = rs.open(ss.seal(forger, magic, 8, []byte{9})); e == nil {
+		t.Fatal("packet signed by another identity was accepted")
+	}
+	packet := ss.seal(sender, magic, 9, []byte{9})
+	packet[len(packet)-ed25519.SignatureSize-1] ^= 1
+	if _, _, e = rs.open(packet); e == nil {
+		t.Fatal("tampered packet was accepted")
+	}
+	// packets too far behind the highest counter are rejected even if not seen
+	old := ss.seal(sender, magic, 10, nil)
+	for i := 0; i < replayWindow; i++ {
+		if _, _, e = rs.open(ss.seal(sender, magic, 11, nil)); e != nil {
+			t.Fatal(e)
+		}
+	}
+	if _, _, e = rs.open(old); e == nil {
+		t.Fatal("packet behind the replay window was accepted")
+	}
+}
+
+// TestTrust checks that pinned keys only restrict the messages with the pinned magics.
+func TestTrust(t *testing.T) {
+	pinned, e := NewIdentity()
+	if e != nil {
+		t.Fatal(e)
+	}
+	other, e := NewIdentity()
+	if e != nil {
+		t.Fatal(e)
+	}
+	trust, e := NewTrust([]string{pinned.Public()}, []byte("job\x01"))
+	if e != nil {
+		t.Fatal(e)
+	}
+	pub := func(id *Identity) ed25519.PublicKey { return id.key.Public().(ed25519.PublicKey) }
+	if !trust.Trusted("job\x01", pub(pinned)) || trust.Trusted("job\x01", pub(other)) {
+		t.Fatal("pinned magic is not restricted to the pinned key")
+	}
+	if !trust.Trusted("hsh\x01", pub(other)) {
+		t.Fatal("magic that is not pinned is restricted")
+	}
+	if trust, e = NewTrust(nil, []byte("job\x01")); e != nil {
+		t.Fatal(e)
+	}
+	if !trust.Trusted("job\x01", pub(other)) || !trust.unpinned("job\x01") {
+		t.Fatal("messages are restricted without pinned keys")
+	}
+	if _, e = NewTrust([]string{"00"}); e == nil {
+		t.Fatal("invalid key was accepted")
+	}
+}
+
+// freeAddr returns a local UDP address that is not in use
+func freeAddr(t *testing.T) string {
+	conn, e := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer conn.Close()
+	return conn.LocalAddr().String()
+}
+
+// TestChannels runs the handshake between two channels over the loopback interface and checks that the messages sent
+// once the receiver has the session key are handled, and only when they come from the pinned key.
+func TestChannels(t *testing.T) {
+	quit := qu.T()
+	defer quit.Q()
+	addrA, addrB := freeAddr(t), freeAddr(t)
+	senderID, e := NewIdentity()
+	if e != nil {
+		t.Fatal(e)
+	}
+	magic := []byte("TST\x01")
+	trust, e := NewTrust([]string{senderID.Public()}, magic)
+	if e != nil {
+		t.Fatal(e)
+	}
+	received := make(chan []byte, 16)
+	handlers := Handlers{
+		string(magic): func(ctx interface{}, src net.Addr, dst string, b []byte) (e error) {
+			received <- b
+			return
+		},
+	}
+	if _, e = NewUnicastChannel(
+		"receiver", nil, []byte("pa55word"), nil, trust, addrA, addrB, 8192, handlers, quit,
+	); e != nil {
+		t.Fatal(e)
+	}
+	sender, e := NewUnicastChannel(
+		"sender", nil, []byte("pa55word"), senderID, nil, addrB, addrA, 8192, Handlers{}, quit,
+	)
+	if e != nil {
+		t.Fatal(e)
+	}
+	// the first messages are lost while the receiver asks for the key of the new session
+	msg := []byte("hello multicast")
+	timeout := time.After(time.Second * 10)
+	for done := false; !done; {
+		if e = sender.SendMany(magic, GetShards(msg)); e != nil {
+			t.Fatal(e)
+		}
+		select {
+		case b := <-received:
+			if !bytes.Equal(b, msg) {
+				t.Fatalf("received %q, want %q", b, msg)
+			}
+			done = true
+		case <-time.After(time.Millisecond * 100):
+		case <-timeout:
+			t.Fatal("message was not received")
+		}
+	}
+	// a channel with another identity completes the handshake with a receiver that pins the key, but its messages are
+	// not accepted
+	addrC, addrD := freeAddr(t), freeAddr(t)
+	if _, e = NewUnicastChannel(
+		"receiver", nil, []byte("pa55word"), nil, trust, addrC, addrD, 8192, handlers, quit,
+	); e != nil {
+		t.Fatal(e)
+	}
+	impostor, e := NewUnicastChannel(
+		"impostor", nil, []byte("pa55word"), nil, nil, addrD, addrC, 8192, Handlers{}, quit,
+	)
+	if e != nil {
+		t.Fatal(e)
+	}
+	for i := 0; i < 20; i++ {
+		if e = impostor.SendMany(magic, GetShards(msg)); e != nil {
+			t.Fatal(e)
+		}
+		time.Sleep(time.Millisecond * 100)
+	}
+	select {
+	case <-received:
+		t.Fatal("message from a key that is not pinned was handled")
+	default:
+	}
+}
@@ -102,6 +102,12 @@ func promptListBool(reader *bufio.Reader, prefix string, defaultEntry string) (b
 	return response == "yes" || response == "y", nil
 }
 
+// Confirm prompts the user with a yes/no question on the standard input and returns true only if they answered yes.
+// The default answer is no so that an accidental enter does not trigger a destructive operation.
+func Confirm(prefix string) (bool, error) {
+	return promptListBool(bufio.NewReader(os.Stdin), prefix, "no")
+}
+
 // promptPass prompts the user for a passphrase with the given prefix. The function will ask the user to confirm the
 // passphrase and will repeat the prompts until they enter a matching response.
 func promptPass(reader *bufio.Reader, prefix string, confirm bool) ([]byte, error) {
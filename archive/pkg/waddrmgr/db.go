@@ -1725,6 +1725,49 @@ func putSyncedTo(ns walletdb.ReadWriteBucket, bs *BlockStamp) (e error) {
 	return nil
 }
 
+// rewindSyncedTo replaces the synced to blockstamp with the start block and deletes the block hashes stored by height
+// above it. The previous synced to and the start block stamps are returned.
+func rewindSyncedTo(ns walletdb.ReadWriteBucket) (from, to *BlockStamp, e error) {
+	if from, e = fetchSyncedTo(ns); E.Chk(e) {
+		return
+	}
+	if to, e = fetchStartBlock(ns); E.Chk(e) {
+		return
+	}
+	bucket := ns.NestedReadWriteBucket(syncBucketName)
+	errStr := fmt.Sprintf("failed to rewind sync information to %v", to.Hash)
+	// Block hashes are keyed by 4 byte big endian height, which no other key in the sync bucket shares the length of.
+	var stale [][]byte
+	if e = bucket.ForEach(
+		func(k, v []byte) (e error) {
+			if len(k) == 4 && int32(binary.BigEndian.Uint32(k)) > to.Height {
+				stale = append(stale, append([]byte{}, k...))
+			}
+			return nil
+		},
+	); E.Chk(e) {
+		return nil, nil, managerError(ErrDatabase, errStr, e)
+	}
+	for i := range stale {
+		if e = bucket.Delete(stale[i]); E.Chk(e) {
+			return nil, nil, managerError(ErrDatabase, errStr, e)
+		}
+	}
+	height := make([]byte, 4)
+	binary.BigEndian.PutUint32(height, uint32(to.Height))
+	if e = bucket.Put(height, to.Hash[0:32]); E.Chk(e) {
+		return nil, nil, managerError(ErrDatabase, errStr, e)
+	}
+	// The start block has no timestamp so the short form of the synced to format is written.
+	buf := make([]byte, 36)
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(to.Height))
+	copy(buf[4:36], to.Hash[0:32])
+	if e = bucket.Put(syncedToName, buf); E.Chk(e) {
+		return nil, nil, managerError(ErrDatabase, errStr, e)
+	}
+	return
+}
+
 // fetchBlockHash loads the block hash for the provided height from the database.
 func fetchBlockHash(ns walletdb.ReadBucket, height int32) (h *chainhash.Hash, e error) {
 	bucket := ns.NestedReadBucket(syncBucketName)
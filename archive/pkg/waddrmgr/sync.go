@@ -58,6 +58,14 @@ func (m *Manager) SetSyncedTo(ns walletdb.ReadWriteBucket, bs *BlockStamp) (e er
 	return nil
 }
 
+// RewindSyncedTo moves the sync tip stored in the address manager namespace back to the start block, the block the
+// wallet was created at, and forgets the block hashes recorded above it so the wallet rescans from its birthday on the
+// next sync. It works on the database directly so the manager does not need to be loaded, and returns the replaced and
+// the new sync tip.
+func RewindSyncedTo(ns walletdb.ReadWriteBucket) (from, to *BlockStamp, e error) {
+	return rewindSyncedTo(ns)
+}
+
 // SyncedTo returns details about the block height and hash that the address
 // manager is synced through at the very least. The intention is that callers
 // can use this information for intelligently initiating rescans to sync back to
@@ -3,6 +3,7 @@ package bdb
 import (
 	"io"
 	"os"
+	"time"
 	
 	bolt "go.etcd.io/bbolt"
 	
@@ -17,6 +18,8 @@ func convertErr(e1 error) (e error) {
 		return walletdb.ErrDbNotOpen
 	case bolt.ErrInvalid:
 		return walletdb.ErrInvalid
+	case bolt.ErrTimeout:
+		return walletdb.ErrDbLocked
 	// Transaction errors.
 	case bolt.ErrTxNotWritable:
 		return walletdb.ErrTxNotWritable
@@ -317,15 +320,16 @@ func fileExists(name string) bool {
 	return true
 }
 
-// openDB opens the database at the provided path.
+// openDB opens the database at the provided path. A non-zero timeout limits how long to wait for the file lock, after
+// which walletdb.ErrDbLocked is returned, otherwise it waits until the lock is released.
 //
 // walletdb.ErrDbDoesNotExist is returned if the database doesn't exist and the create flag is not set.
-func openDB(dbPath string, create bool) (d walletdb.DB, e error) {
+func openDB(dbPath string, create bool, timeout time.Duration) (d walletdb.DB, e error) {
 	if !create && !fileExists(dbPath) {
 		return nil, walletdb.ErrDbDoesNotExist
 	}
 	var boltDB *bolt.DB
-	if boltDB, e = bolt.Open(dbPath, 0600, nil); E.Chk(e) {
+	if boltDB, e = bolt.Open(dbPath, 0600, &bolt.Options{Timeout: timeout}); E.Chk(e) {
 	}
 	return (*db)(boltDB), convertErr(e)
 }
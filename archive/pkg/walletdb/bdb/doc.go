@@ -3,8 +3,9 @@ datastore.
 
 Usage
 
-This package is only a driver to the walletdb package and provides the database type of "bdb". The only parameter the
-Open and Create functions take is the database path as a string:
+This package is only a driver to the walletdb package and provides the database type of "bdb". The Open and Create
+functions take the database path as a string, optionally followed by a time.Duration limiting how long to wait for
+another process to release the database lock before failing with walletdb.ErrDbLocked:
 
 	db, e := walletdb.Open("bdb", "path/to/database.db")
 	if e != nil  {
@@ -14,5 +15,9 @@ Open and Create functions take is the database path as a string:
 	if e != nil  {
 		// Handle error
 	}
+	db, e := walletdb.Open("bdb", "path/to/database.db", time.Second)
+	if e == walletdb.ErrDbLocked {
+		// Database is in use
+	}
 */
 package bdb
@@ -2,6 +2,7 @@ package bdb
 
 import (
 	"fmt"
+	"time"
 	
 	"github.com/p9c/pod/pkg/walletdb"
 )
@@ -10,42 +11,53 @@ const (
 	dbType = "bdb"
 )
 
-// parseArgs parses the arguments from the walletdb Open/Create methods.
-func parseArgs(funcName string, args ...interface{}) (string, error) {
-	if len(args) != 1 {
-		return "", fmt.Errorf(
+// parseArgs parses the arguments from the walletdb Open/Create methods. The database path is required and may be
+// followed by a time.Duration limiting how long to wait for the database lock.
+func parseArgs(funcName string, args ...interface{}) (dbPath string, timeout time.Duration, e error) {
+	if len(args) != 1 && len(args) != 2 {
+		return "", 0, fmt.Errorf(
 			"invalid arguments to %s.%s -- "+
 				"expected database path", dbType, funcName,
 		)
 	}
-	dbPath, ok := args[0].(string)
-	if !ok {
-		return "", fmt.Errorf(
+	var ok bool
+	if dbPath, ok = args[0].(string); !ok {
+		return "", 0, fmt.Errorf(
 			"first argument to %s.%s is invalid -- "+
 				"expected database path string", dbType, funcName,
 		)
 	}
-	return dbPath, nil
+	if len(args) == 2 {
+		if timeout, ok = args[1].(time.Duration); !ok {
+			return "", 0, fmt.Errorf(
+				"second argument to %s.%s is invalid -- "+
+					"expected lock timeout duration", dbType, funcName,
+			)
+		}
+	}
+	return dbPath, timeout, nil
 }
 
 // openDBDriver is the callback provided during driver registration that opens
 // an existing database for use.
 func openDBDriver(args ...interface{}) (d walletdb.DB, e error) {
 	var dbPath string
-	if dbPath, e = parseArgs("Open", args...); E.Chk(e) {
+	var timeout time.Duration
+	if dbPath, timeout, e = parseArgs("Open", args...); E.Chk(e) {
 		return
 	}
-	return openDB(dbPath, false)
+	return openDB(dbPath, false, timeout)
 }
 
 // createDBDriver is the callback provided during driver registration that
 // creates, initializes, and opens a database for use.
 func createDBDriver(args ...interface{}) (d walletdb.DB, e error) {
 	var dbPath string
-	if dbPath, e = parseArgs("Create", args...); E.Chk(e) {
+	var timeout time.Duration
+	if dbPath, timeout, e = parseArgs("Create", args...); E.Chk(e) {
 		return
 	}
-	return openDB(dbPath, true)
+	return openDB(dbPath, true, timeout)
 }
 func init() {
 	// Register the driver.
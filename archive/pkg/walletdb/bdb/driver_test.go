@@ -5,6 +5,7 @@ import (
 	"os"
 	"reflect"
 	"testing"
+	"time"
 	
 	"github.com/p9c/pod/pkg/walletdb"
 	"github.com/p9c/pod/pkg/walletdb/bdb"
@@ -164,3 +165,39 @@ func TestPersistence(t *testing.T) {
 		return
 	}
 }
+
+// TestOpenLocked ensures that opening a database that is held open elsewhere with a lock timeout fails with the
+// expected error instead of blocking.
+func TestOpenLocked(t *testing.T) {
+	dbPath := "lockedtest.db"
+	db, e := walletdb.Create(dbType, dbPath)
+	if e != nil {
+		t.Errorf("Failed to create test database (%s) %v", dbType, e)
+		return
+	}
+	defer func() {
+		if e = os.Remove(dbPath); bdb.E.Chk(e) {
+		}
+	}()
+	defer func() {
+		if e = db.Close(); bdb.E.Chk(e) {
+		}
+	}()
+	wantErr := walletdb.ErrDbLocked
+	if _, e = walletdb.Open(dbType, dbPath, time.Millisecond*100); e != wantErr {
+		t.Errorf("Open: did not receive expected error - got %v, "+
+			"want %v", e, wantErr,
+		)
+		return
+	}
+	// Ensure a timeout of the wrong type is rejected.
+	wantErr = fmt.Errorf("second argument to %s.Open is invalid -- "+
+		"expected lock timeout duration", dbType,
+	)
+	if _, e = walletdb.Open(dbType, dbPath, 1); e == nil || e.Error() != wantErr.Error() {
+		t.Errorf("Open: did not receive expected error - got %v, "+
+			"want %v", e, wantErr,
+		)
+		return
+	}
+}
@@ -25,6 +25,8 @@ var (
 	ErrDbAlreadyOpen = errors.New("database already open")
 	// ErrInvalid is returned if the specified database is not valid.
 	ErrInvalid = errors.New("invalid database")
+	// ErrDbLocked is returned when open times out because another process holds the database lock.
+	ErrDbLocked = errors.New("database is locked by another process")
 )
 
 // Errors that can occur when beginning or committing a transaction.
@@ -26,6 +26,7 @@ const (
 	InvTypeTx                   InvType = 1
 	InvTypeBlock                InvType = 2
 	InvTypeFilteredBlock        InvType = 3
+	InvTypeCmpctBlock           InvType = 4
 	// InvTypeWitnessBlock                 = InvTypeBlock | InvWitnessFlag
 	// InvTypeWitnessTx                    = InvTypeTx | InvWitnessFlag
 	// InvTypeFilteredWitnessBlock         = InvTypeFilteredBlock | InvWitnessFlag
@@ -37,6 +38,7 @@ var ivStrings = map[InvType]string{
 	InvTypeTx:                   "MSG_TX",
 	InvTypeBlock:                "MSG_BLOCK",
 	InvTypeFilteredBlock:        "MSG_FILTERED_BLOCK",
+	InvTypeCmpctBlock:           "MSG_CMPCT_BLOCK",
 	// InvTypeWitnessBlock:         "MSG_WITNESS_BLOCK",
 	// InvTypeWitnessTx:            "MSG_WITNESS_TX",
 	// InvTypeFilteredWitnessBlock: "MSG_FILTERED_WITNESS_BLOCK",
@@ -70,6 +70,20 @@ func TestMessage(t *testing.T) {
 	)
 	msgCFHeaders := NewMsgCFHeaders()
 	msgCFCheckpt := NewMsgCFCheckpt(GCSFilterRegular, &chainhash.Hash{}, 0)
+	msgSendCmpct := NewMsgSendCmpct(true, CmpctBlockVersion)
+	msgCmpctBlock := NewMsgCmpctBlock(&blockOne, 123123)
+	msgGetBlockTxn := NewMsgGetBlockTxn(&chainhash.Hash{}, []uint32{1, 2})
+	msgBlockTxn := NewMsgBlockTxn(&chainhash.Hash{}, []*MsgTx{NewMsgTx(1)})
+	msgSendAddrV2 := NewMsgSendAddrV2()
+	msgAddrV2 := NewMsgAddrV2()
+	if e := msgAddrV2.AddAddress(
+		&NetAddress{
+			Timestamp: time.Unix(0x495fab29, 0), Services: SFNodeNetwork, Port: 8333,
+			Network: NetworkTorV3, Addr: bytes.Repeat([]byte{0x01}, 32),
+		},
+	); e != nil {
+		t.Fatal(e)
+	}
 	tests := []struct {
 		in     Message    // value to encode
 		out    Message    // Expected decoded value
@@ -104,6 +118,12 @@ func TestMessage(t *testing.T) {
 		{msgCFilter, msgCFilter, pver, MainNet, 65},
 		{msgCFHeaders, msgCFHeaders, pver, MainNet, 90},
 		{msgCFCheckpt, msgCFCheckpt, pver, MainNet, 58},
+		{msgSendCmpct, msgSendCmpct, pver, MainNet, 33},
+		{msgCmpctBlock, msgCmpctBlock, pver, MainNet, 249},
+		{msgGetBlockTxn, msgGetBlockTxn, pver, MainNet, 59},
+		{msgBlockTxn, msgBlockTxn, pver, MainNet, 67},
+		{msgSendAddrV2, msgSendAddrV2, pver, MainNet, 24},
+		{msgAddrV2, msgAddrV2, pver, MainNet, 66},
 	}
 	t.Logf("Running %d tests", len(tests))
 	var msg Message
@@ -0,0 +1,110 @@
+package wire
+
+import (
+	"fmt"
+	"io"
+)
+
+// MsgAddrV2 implements the Message interface and represents a bitcoin addrv2 message. It is used like an addr message
+// to provide a list of known active peers on the network, but can also carry addresses that are not IP addresses, such
+// as those of Tor v3 onion services and I2P destinations. It is only sent to peers that asked for it with a sendaddrv2
+// message. Each message is limited to MaxAddrPerMsg addresses. This message was not added until protocol versions
+// starting with AddrV2Version.
+type MsgAddrV2 struct {
+	AddrList []*NetAddress
+}
+
+// AddAddress adds a known active peer to the message.
+func (msg *MsgAddrV2) AddAddress(na *NetAddress) (e error) {
+	if len(msg.AddrList)+1 > MaxAddrPerMsg {
+		str := fmt.Sprintf("too many addresses in message [max %v]", MaxAddrPerMsg)
+		return messageError("MsgAddrV2.AddAddress", str)
+	}
+	msg.AddrList = append(msg.AddrList, na)
+	return nil
+}
+
+// AddAddresses adds multiple known active peers to the message.
+func (msg *MsgAddrV2) AddAddresses(netAddrs ...*NetAddress) (e error) {
+	for _, na := range netAddrs {
+		if e = msg.AddAddress(na); E.Chk(e) {
+			return
+		}
+	}
+	return
+}
+
+// ClearAddresses removes all addresses from the message.
+func (msg *MsgAddrV2) ClearAddresses() {
+	msg.AddrList = []*NetAddress{}
+}
+
+// BtcDecode decodes r using the bitcoin protocol encoding into the receiver. This is part of the Message interface
+// implementation.
+func (msg *MsgAddrV2) BtcDecode(r io.Reader, pver uint32, enc MessageEncoding) (e error) {
+	if pver < AddrV2Version {
+		str := fmt.Sprintf("addrv2 message invalid for protocol version %d", pver)
+		return messageError("MsgAddrV2.BtcDecode", str)
+	}
+	var count uint64
+	if count, e = ReadVarInt(r, pver); E.Chk(e) {
+		return
+	}
+	// Limit to max addresses per message.
+	if count > MaxAddrPerMsg {
+		str := fmt.Sprintf("too many addresses for message [count %v, max %v]", count, MaxAddrPerMsg)
+		return messageError("MsgAddrV2.BtcDecode", str)
+	}
+	addrList := make([]NetAddress, count)
+	msg.AddrList = make([]*NetAddress, 0, count)
+	for i := uint64(0); i < count; i++ {
+		na := &addrList[i]
+		if e = readNetAddressV2(r, pver, na); E.Chk(e) {
+			return
+		}
+		msg.AddrList = append(msg.AddrList, na)
+	}
+	return
+}
+
+// BtcEncode encodes the receiver to w using the bitcoin protocol encoding. This is part of the Message interface
+// implementation.
+func (msg *MsgAddrV2) BtcEncode(w io.Writer, pver uint32, enc MessageEncoding) (e error) {
+	if pver < AddrV2Version {
+		str := fmt.Sprintf("addrv2 message invalid for protocol version %d", pver)
+		return messageError("MsgAddrV2.BtcEncode", str)
+	}
+	count := len(msg.AddrList)
+	if count > MaxAddrPerMsg {
+		str := fmt.Sprintf("too many addresses for message [count %v, max %v]", count, MaxAddrPerMsg)
+		return messageError("MsgAddrV2.BtcEncode", str)
+	}
+	if e = WriteVarInt(w, pver, uint64(count)); E.Chk(e) {
+		return
+	}
+	for _, na := range msg.AddrList {
+		if e = writeNetAddressV2(w, pver, na); E.Chk(e) {
+			return
+		}
+	}
+	return
+}
+
+// Command returns the protocol command string for the message. This is part of the Message interface implementation.
+func (msg *MsgAddrV2) Command() string {
+	return CmdAddrV2
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the receiver. This is part of the Message
+// interface implementation.
+func (msg *MsgAddrV2) MaxPayloadLength(pver uint32) uint32 {
+	// Num addresses (varInt) + max allowed addresses.
+	return MaxVarIntPayload + (MaxAddrPerMsg * maxNetAddressPayloadV2())
+}
+
+// NewMsgAddrV2 returns a new bitcoin addrv2 message that conforms to the Message interface. See MsgAddrV2 for details.
+func NewMsgAddrV2() *MsgAddrV2 {
+	return &MsgAddrV2{
+		AddrList: make([]*NetAddress, 0, MaxAddrPerMsg),
+	}
+}
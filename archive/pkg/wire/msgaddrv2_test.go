@@ -0,0 +1,136 @@
+package wire
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestAddrV2Wire tests the MsgAddrV2 wire encode and decode of the addresses of each network.
+func TestAddrV2Wire(t *testing.T) {
+	ts := time.Unix(0x495fab29, 0) // 2009-01-03 12:15:05 -0600 CST
+	torV3 := bytes.Repeat([]byte{0xab}, 32)
+	tests := []struct {
+		in      *NetAddress // Address to encode
+		out     *NetAddress // Expected decoded address
+		network NetworkID   // Expected network
+		buf     []byte      // Wire encoding
+	}{
+		// IPv4 address.
+		{
+			&NetAddress{Timestamp: ts, Services: SFNodeNetwork, IP: net.ParseIP("127.0.0.1"), Port: 8333},
+			&NetAddress{Timestamp: ts, Services: SFNodeNetwork, IP: net.ParseIP("127.0.0.1"), Port: 8333},
+			NetworkIPv4,
+			[]byte{
+				0x29, 0xab, 0x5f, 0x49, 0x01, 0x01, 0x04, 0x7f, 0x00, 0x00, 0x01, 0x20, 0x8d,
+			},
+		},
+		// IPv6 address.
+		{
+			&NetAddress{Timestamp: ts, Services: SFNodeNetwork, IP: net.ParseIP("2001:db8::1"), Port: 8333},
+			&NetAddress{Timestamp: ts, Services: SFNodeNetwork, IP: net.ParseIP("2001:db8::1"), Port: 8333},
+			NetworkIPv6,
+			[]byte{
+				0x29, 0xab, 0x5f, 0x49, 0x01, 0x02, 0x10,
+				0x20, 0x01, 0x0d, 0xb8, 0x00, 0x00, 0x00, 0x00,
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01,
+				0x20, 0x8d,
+			},
+		},
+		// Tor v2 address in its OnionCat encoding.
+		{
+			&NetAddress{Timestamp: ts, IP: net.ParseIP("fd87:d87e:eb43:102:304:506:708:90a"), Port: 8333},
+			&NetAddress{Timestamp: ts, IP: net.ParseIP("fd87:d87e:eb43:102:304:506:708:90a"), Port: 8333},
+			NetworkTorV2,
+			[]byte{
+				0x29, 0xab, 0x5f, 0x49, 0x00, 0x03, 0x0a,
+				0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a,
+				0x20, 0x8d,
+			},
+		},
+		// Tor v3 address.
+		{
+			&NetAddress{Timestamp: ts, Services: SFNodeNetwork, Network: NetworkTorV3, Addr: torV3, Port: 8333},
+			&NetAddress{Timestamp: ts, Services: SFNodeNetwork, Network: NetworkTorV3, Addr: torV3, Port: 8333},
+			NetworkTorV3,
+			append(
+				append([]byte{0x29, 0xab, 0x5f, 0x49, 0x01, 0x04, 0x20}, torV3...),
+				0x20, 0x8d,
+			),
+		},
+		// Address of an unknown network, which is relayed as it is.
+		{
+			&NetAddress{Timestamp: ts, Network: 0x42, Addr: []byte{0x01, 0x02, 0x03}, Port: 1},
+			&NetAddress{Timestamp: ts, Network: 0x42, Addr: []byte{0x01, 0x02, 0x03}, Port: 1},
+			0x42,
+			[]byte{0x29, 0xab, 0x5f, 0x49, 0x00, 0x42, 0x03, 0x01, 0x02, 0x03, 0x00, 0x01},
+		},
+	}
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		if network := test.in.NetworkID(); network != test.network {
+			t.Errorf("NetworkID #%d got: %v want: %v", i, network, test.network)
+		}
+		msg := NewMsgAddrV2()
+		if e := msg.AddAddress(test.in); e != nil {
+			t.Fatal(e)
+		}
+		var buf bytes.Buffer
+		if e := msg.BtcEncode(&buf, ProtocolVersion, BaseEncoding); e != nil {
+			t.Errorf("BtcEncode #%d error %v", i, e)
+			continue
+		}
+		want := append([]byte{0x01}, test.buf...)
+		if !bytes.Equal(buf.Bytes(), want) {
+			t.Errorf("BtcEncode #%d\n got: %s want: %s", i, spew.Sdump(buf.Bytes()), spew.Sdump(want))
+			continue
+		}
+		var readmsg MsgAddrV2
+		if e := readmsg.BtcDecode(bytes.NewReader(want), ProtocolVersion, BaseEncoding); e != nil {
+			t.Errorf("BtcDecode #%d error %v", i, e)
+			continue
+		}
+		if len(readmsg.AddrList) != 1 || !reflect.DeepEqual(readmsg.AddrList[0], test.out) {
+			t.Errorf("BtcDecode #%d\n got: %s want: %s", i, spew.Sdump(readmsg.AddrList), spew.Sdump(test.out))
+		}
+	}
+}
+
+// TestAddrV2WireErrors performs negative tests against wire decode of MsgAddrV2 to confirm error paths work correctly.
+func TestAddrV2WireErrors(t *testing.T) {
+	tests := []struct {
+		buf  []byte // Wire encoding
+		pver uint32 // Protocol version for wire encoding
+	}{
+		// Protocol version before the message was added.
+		{[]byte{0x00}, AddrV2Version - 1},
+		// Too many addresses.
+		{[]byte{0xfd, 0xe9, 0x03}, ProtocolVersion},
+		// IPv4 address of the wrong length.
+		{[]byte{0x01, 0x29, 0xab, 0x5f, 0x49, 0x00, 0x01, 0x03, 0x7f, 0x00, 0x01, 0x20, 0x8d}, ProtocolVersion},
+		// Address longer than any network allows.
+		{[]byte{0x01, 0x29, 0xab, 0x5f, 0x49, 0x00, 0x42, 0xfd, 0x01, 0x02}, ProtocolVersion},
+		// Truncated address.
+		{[]byte{0x01, 0x29, 0xab, 0x5f, 0x49, 0x00, 0x04, 0x20, 0x01}, ProtocolVersion},
+	}
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		var msg MsgAddrV2
+		if e := msg.BtcDecode(bytes.NewReader(test.buf), test.pver, BaseEncoding); e == nil {
+			t.Errorf("BtcDecode #%d succeeded for %x", i, test.buf)
+		}
+	}
+	// Encoding more than the maximum number of addresses fails.
+	msg := NewMsgAddrV2()
+	for i := 0; i <= MaxAddrPerMsg; i++ {
+		msg.AddrList = append(msg.AddrList, &NetAddress{IP: net.ParseIP("127.0.0.1")})
+	}
+	var buf bytes.Buffer
+	if e := msg.BtcEncode(&buf, ProtocolVersion, BaseEncoding); e == nil {
+		t.Error("BtcEncode succeeded for too many addresses")
+	}
+}
@@ -0,0 +1,96 @@
+package wire
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/p9c/pod/pkg/chainhash"
+)
+
+// MsgBlockTxn implements the Message interface and represents a bitcoin blocktxn message. It is used to deliver the
+// transactions of a block requested with a getblocktxn message, in the order of the requested indexes. This message was
+// not added until protocol versions starting with SendCmpctVersion.
+type MsgBlockTxn struct {
+	BlockHash    chainhash.Hash
+	Transactions []*MsgTx
+}
+
+// AddTransaction adds a transaction to the message.
+func (msg *MsgBlockTxn) AddTransaction(tx *MsgTx) {
+	msg.Transactions = append(msg.Transactions, tx)
+}
+
+// BtcDecode decodes r using the bitcoin protocol encoding into the receiver. This is part of the Message interface
+// implementation.
+func (msg *MsgBlockTxn) BtcDecode(r io.Reader, pver uint32, enc MessageEncoding) (e error) {
+	if pver < SendCmpctVersion {
+		str := fmt.Sprintf("blocktxn message invalid for protocol version %d", pver)
+		return messageError("MsgBlockTxn.BtcDecode", str)
+	}
+	if e = readElement(r, &msg.BlockHash); E.Chk(e) {
+		return
+	}
+	var txCount uint64
+	if txCount, e = ReadVarInt(r, pver); E.Chk(e) {
+		return
+	}
+	// Prevent more transactions than could possibly fit into a block. It would be possible to cause memory exhaustion
+	// and panics without a sane upper bound on this count.
+	if txCount > maxTxPerBlock {
+		str := fmt.Sprintf(
+			"too many transactions to fit into a block [count %d, max %d]", txCount, maxTxPerBlock,
+		)
+		return messageError("MsgBlockTxn.BtcDecode", str)
+	}
+	msg.Transactions = make([]*MsgTx, 0, txCount)
+	for i := uint64(0); i < txCount; i++ {
+		tx := MsgTx{}
+		if e = tx.BtcDecode(r, pver, enc); E.Chk(e) {
+			return
+		}
+		msg.Transactions = append(msg.Transactions, &tx)
+	}
+	return
+}
+
+// BtcEncode encodes the receiver to w using the bitcoin protocol encoding. This is part of the Message interface
+// implementation.
+func (msg *MsgBlockTxn) BtcEncode(w io.Writer, pver uint32, enc MessageEncoding) (e error) {
+	if pver < SendCmpctVersion {
+		str := fmt.Sprintf("blocktxn message invalid for protocol version %d", pver)
+		return messageError("MsgBlockTxn.BtcEncode", str)
+	}
+	if e = writeElement(w, &msg.BlockHash); E.Chk(e) {
+		return
+	}
+	if e = WriteVarInt(w, pver, uint64(len(msg.Transactions))); E.Chk(e) {
+		return
+	}
+	for _, tx := range msg.Transactions {
+		if e = tx.BtcEncode(w, pver, enc); E.Chk(e) {
+			return
+		}
+	}
+	return
+}
+
+// Command returns the protocol command string for the message. This is part of the Message interface implementation.
+func (msg *MsgBlockTxn) Command() string {
+	return CmdBlockTxn
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the receiver. This is part of the Message
+// interface implementation.
+func (msg *MsgBlockTxn) MaxPayloadLength(pver uint32) uint32 {
+	// The transactions are part of a block, so they can not be larger than it.
+	return MaxBlockPayload
+}
+
+// NewMsgBlockTxn returns a new bitcoin blocktxn message that conforms to the Message interface for the transactions of
+// a block. See MsgBlockTxn for details.
+func NewMsgBlockTxn(blockHash *chainhash.Hash, txs []*MsgTx) *MsgBlockTxn {
+	return &MsgBlockTxn{
+		BlockHash:    *blockHash,
+		Transactions: txs,
+	}
+}
@@ -0,0 +1,77 @@
+package wire
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+
+	"github.com/p9c/pod/pkg/chainhash"
+)
+
+// TestBlockTxnWire tests the MsgBlockTxn API and wire encode and decode for various protocol versions.
+func TestBlockTxnWire(t *testing.T) {
+	hash := chainhash.Hash{0x01, 0x02, 0x03}
+	msg := NewMsgBlockTxn(&hash, nil)
+	// Ensure the command is expected value.
+	wantCmd := "blocktxn"
+	if cmd := msg.Command(); cmd != wantCmd {
+		t.Errorf("NewMsgBlockTxn: wrong command - got %v want %v", cmd, wantCmd)
+	}
+	msg.AddTransaction(blockOne.Transactions[0])
+	msg.AddTransaction(multiTx)
+	msgEncoded := append(append([]byte{}, hash[:]...), 0x02) // Varint for number of transactions
+	msgEncoded = append(msgEncoded, blockOneBytes[blockHeaderLen+1:]...)
+	msgEncoded = append(msgEncoded, multiTxEncoded...)
+	tests := []struct {
+		in   *MsgBlockTxn // Message to encode
+		out  *MsgBlockTxn // Expected decoded message
+		buf  []byte       // Wire encoding
+		pver uint32       // Protocol version for wire encoding
+	}{
+		// Latest protocol version.
+		{msg, msg, msgEncoded, ProtocolVersion},
+		// Protocol version SendCmpctVersion.
+		{msg, msg, msgEncoded, SendCmpctVersion},
+	}
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Encode the message to wire format.
+		var buf bytes.Buffer
+		e := test.in.BtcEncode(&buf, test.pver, BaseEncoding)
+		if e != nil {
+			t.Errorf("BtcEncode #%d error %v", i, e)
+			continue
+		}
+		if !bytes.Equal(buf.Bytes(), test.buf) {
+			t.Errorf("BtcEncode #%d\n got: %s want: %s", i,
+				spew.Sdump(buf.Bytes()), spew.Sdump(test.buf),
+			)
+			continue
+		}
+		// Decode the message from wire format.
+		var msg MsgBlockTxn
+		rbuf := bytes.NewReader(test.buf)
+		e = msg.BtcDecode(rbuf, test.pver, BaseEncoding)
+		if e != nil {
+			t.Errorf("BtcDecode #%d error %v", i, e)
+			continue
+		}
+		if !reflect.DeepEqual(&msg, test.out) {
+			t.Errorf("BtcDecode #%d\n got: %s want: %s", i,
+				spew.Sdump(&msg), spew.Sdump(test.out),
+			)
+			continue
+		}
+	}
+	// Older protocol versions should fail since the message didn't exist yet.
+	var buf bytes.Buffer
+	if e := msg.BtcEncode(&buf, SendCmpctVersion-1, BaseEncoding); e == nil {
+		t.Errorf("BtcEncode for old protocol version succeeded")
+	}
+	var readmsg MsgBlockTxn
+	if e := readmsg.BtcDecode(bytes.NewReader(msgEncoded), SendCmpctVersion-1, BaseEncoding); e == nil {
+		t.Errorf("BtcDecode for old protocol version succeeded")
+	}
+}
@@ -0,0 +1,221 @@
+package wire
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/aead/siphash"
+
+	"github.com/p9c/pod/pkg/chainhash"
+)
+
+// ShortIDSize is the number of bytes of a short transaction ID in a cmpctblock message.
+const ShortIDSize = 6
+
+// PrefilledTx is a transaction sent in full in a cmpctblock message, usually because the receiver cannot have it yet,
+// as is the case with the coinbase.
+type PrefilledTx struct {
+	// Index is the position of the transaction in the block. On the wire it is encoded as the difference to the index
+	// of the previous prefilled transaction.
+	Index uint32
+	Tx    *MsgTx
+}
+
+// MsgCmpctBlock implements the Message interface and represents a bitcoin cmpctblock message. It is used to relay a
+// block as its header and a short ID for each of its transactions, so the receiver can rebuild the block from the
+// transactions it already has and only request those it is missing with a getblocktxn message. This message was not
+// added until protocol versions starting with SendCmpctVersion.
+type MsgCmpctBlock struct {
+	Header BlockHeader
+	// Nonce is mixed into the key of the short IDs so that collisions differ between peers and blocks.
+	Nonce        uint64
+	ShortIDs     []uint64
+	PrefilledTxs []PrefilledTx
+}
+
+// readIndexes reads count differentially encoded indexes from r, each of which is the difference to the previous index
+// less one, and checks that none of them reaches max.
+func readIndexes(r io.Reader, pver uint32, count uint64, max uint64, read func(index uint32) error) (e error) {
+	var next uint64
+	for i := uint64(0); i < count; i++ {
+		var diff uint64
+		if diff, e = ReadVarInt(r, pver); E.Chk(e) {
+			return
+		}
+		if diff >= max || next+diff >= max {
+			str := fmt.Sprintf("transaction index out of range [max %d]", max)
+			return messageError("readIndexes", str)
+		}
+		if e = read(uint32(next + diff)); E.Chk(e) {
+			return
+		}
+		next += diff + 1
+	}
+	return
+}
+
+// writeIndex writes an index differentially encoded against the index after the previous one.
+func writeIndex(w io.Writer, pver uint32, index, next uint32) (e error) {
+	if index < next {
+		str := fmt.Sprintf("transaction index %d is not in ascending order", index)
+		return messageError("writeIndex", str)
+	}
+	return WriteVarInt(w, pver, uint64(index-next))
+}
+
+// BtcDecode decodes r using the bitcoin protocol encoding into the receiver. This is part of the Message interface
+// implementation.
+func (msg *MsgCmpctBlock) BtcDecode(r io.Reader, pver uint32, enc MessageEncoding) (e error) {
+	if pver < SendCmpctVersion {
+		str := fmt.Sprintf("cmpctblock message invalid for protocol version %d", pver)
+		return messageError("MsgCmpctBlock.BtcDecode", str)
+	}
+	if e = readBlockHeader(r, pver, &msg.Header); E.Chk(e) {
+		return
+	}
+	if e = readElement(r, &msg.Nonce); E.Chk(e) {
+		return
+	}
+	var count uint64
+	if count, e = ReadVarInt(r, pver); E.Chk(e) {
+		return
+	}
+	// Prevent more short IDs than there could be transactions in a block. It would be possible to cause memory
+	// exhaustion and panics without a sane upper bound on this count.
+	if count > maxTxPerBlock {
+		str := fmt.Sprintf(
+			"too many short IDs to fit into a block [count %d, max %d]", count, maxTxPerBlock,
+		)
+		return messageError("MsgCmpctBlock.BtcDecode", str)
+	}
+	msg.ShortIDs = make([]uint64, count)
+	var id [8]byte
+	for i := range msg.ShortIDs {
+		if _, e = io.ReadFull(r, id[:ShortIDSize]); E.Chk(e) {
+			return
+		}
+		msg.ShortIDs[i] = binary.LittleEndian.Uint64(id[:])
+	}
+	var prefilled uint64
+	if prefilled, e = ReadVarInt(r, pver); E.Chk(e) {
+		return
+	}
+	if count+prefilled > maxTxPerBlock {
+		str := fmt.Sprintf(
+			"too many transactions to fit into a block [count %d, max %d]", count+prefilled, maxTxPerBlock,
+		)
+		return messageError("MsgCmpctBlock.BtcDecode", str)
+	}
+	msg.PrefilledTxs = make([]PrefilledTx, 0, prefilled)
+	return readIndexes(
+		r, pver, prefilled, count+prefilled, func(index uint32) (e error) {
+			tx := MsgTx{}
+			if e = tx.BtcDecode(r, pver, enc); E.Chk(e) {
+				return
+			}
+			msg.PrefilledTxs = append(msg.PrefilledTxs, PrefilledTx{Index: index, Tx: &tx})
+			return
+		},
+	)
+}
+
+// BtcEncode encodes the receiver to w using the bitcoin protocol encoding. This is part of the Message interface
+// implementation.
+func (msg *MsgCmpctBlock) BtcEncode(w io.Writer, pver uint32, enc MessageEncoding) (e error) {
+	if pver < SendCmpctVersion {
+		str := fmt.Sprintf("cmpctblock message invalid for protocol version %d", pver)
+		return messageError("MsgCmpctBlock.BtcEncode", str)
+	}
+	if e = writeBlockHeader(w, pver, &msg.Header); E.Chk(e) {
+		return
+	}
+	if e = writeElement(w, msg.Nonce); E.Chk(e) {
+		return
+	}
+	if e = WriteVarInt(w, pver, uint64(len(msg.ShortIDs))); E.Chk(e) {
+		return
+	}
+	var id [8]byte
+	for _, shortID := range msg.ShortIDs {
+		binary.LittleEndian.PutUint64(id[:], shortID)
+		if _, e = w.Write(id[:ShortIDSize]); E.Chk(e) {
+			return
+		}
+	}
+	if e = WriteVarInt(w, pver, uint64(len(msg.PrefilledTxs))); E.Chk(e) {
+		return
+	}
+	var next uint32
+	for _, ptx := range msg.PrefilledTxs {
+		if e = writeIndex(w, pver, ptx.Index, next); E.Chk(e) {
+			return
+		}
+		if e = ptx.Tx.BtcEncode(w, pver, enc); E.Chk(e) {
+			return
+		}
+		next = ptx.Index + 1
+	}
+	return
+}
+
+// Command returns the protocol command string for the message. This is part of the Message interface implementation.
+func (msg *MsgCmpctBlock) Command() string {
+	return CmdCmpctBlock
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the receiver. This is part of the Message
+// interface implementation.
+func (msg *MsgCmpctBlock) MaxPayloadLength(pver uint32) uint32 {
+	// A compact block is never larger than the block it describes.
+	return MaxBlockPayload
+}
+
+// BlockHash computes the hash of the block the message describes.
+func (msg *MsgCmpctBlock) BlockHash() chainhash.Hash {
+	return msg.Header.BlockHash()
+}
+
+// TxCount returns the number of transactions in the block the message describes.
+func (msg *MsgCmpctBlock) TxCount() int {
+	return len(msg.ShortIDs) + len(msg.PrefilledTxs)
+}
+
+// ShortIDKey returns the SipHash key of the short IDs of the message, which is the first 16 bytes of the single SHA256
+// hash of the block header followed by the nonce.
+func (msg *MsgCmpctBlock) ShortIDKey() (key [16]byte) {
+	// Ignore the error returns since there is no way the encode could fail except being out of memory which would cause
+	// a run-time panic.
+	buf := bytes.NewBuffer(make([]byte, 0, MaxBlockHeaderPayload+8))
+	_ = writeBlockHeader(buf, 0, &msg.Header)
+	_ = writeElement(buf, msg.Nonce)
+	copy(key[:], chainhash.HashB(buf.Bytes()))
+	return
+}
+
+// ShortID returns the short ID of a transaction under a key from ShortIDKey, which is the lowest 6 bytes of the
+// SipHash-2-4 of the transaction hash.
+func ShortID(key *[16]byte, txHash *chainhash.Hash) uint64 {
+	return siphash.Sum64(txHash[:], key) & (1<<(ShortIDSize*8) - 1)
+}
+
+// NewMsgCmpctBlock returns a new bitcoin cmpctblock message that conforms to the Message interface for a block and a
+// nonce. The coinbase is prefilled as the receiver cannot have it and every other transaction is sent as its short ID.
+func NewMsgCmpctBlock(block *Block, nonce uint64) *MsgCmpctBlock {
+	msg := &MsgCmpctBlock{
+		Header: block.Header,
+		Nonce:  nonce,
+	}
+	if len(block.Transactions) == 0 {
+		return msg
+	}
+	msg.PrefilledTxs = []PrefilledTx{{Index: 0, Tx: block.Transactions[0]}}
+	msg.ShortIDs = make([]uint64, 0, len(block.Transactions)-1)
+	key := msg.ShortIDKey()
+	for _, tx := range block.Transactions[1:] {
+		txHash := tx.TxHash()
+		msg.ShortIDs = append(msg.ShortIDs, ShortID(&key, &txHash))
+	}
+	return msg
+}
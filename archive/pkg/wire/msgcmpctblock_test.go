@@ -0,0 +1,171 @@
+package wire
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+
+	"github.com/p9c/pod/pkg/chainhash"
+)
+
+// TestCmpctBlock tests the MsgCmpctBlock API and the computation of the short IDs of the transactions of a block.
+func TestCmpctBlock(t *testing.T) {
+	pver := ProtocolVersion
+	block := NewMsgBlock(&blockOne.Header)
+	block.Transactions = []*MsgTx{blockOne.Transactions[0], multiTx, multiWitnessTx}
+	nonce := uint64(0x0102030405060708)
+	msg := NewMsgCmpctBlock(block, nonce)
+	// Ensure the command is expected value.
+	wantCmd := "cmpctblock"
+	if cmd := msg.Command(); cmd != wantCmd {
+		t.Errorf("NewMsgCmpctBlock: wrong command - got %v want %v", cmd, wantCmd)
+	}
+	// Ensure max payload is expected value for latest protocol version.
+	wantPayload := uint32(MaxBlockPayload)
+	maxPayload := msg.MaxPayloadLength(pver)
+	if maxPayload != wantPayload {
+		t.Errorf("MaxPayloadLength: wrong max payload length for protocol version %d - got %v, want %v",
+			pver, maxPayload, wantPayload,
+		)
+	}
+	// The coinbase is prefilled and the other transactions are sent as short IDs.
+	if msg.TxCount() != 3 || len(msg.PrefilledTxs) != 1 || msg.PrefilledTxs[0].Index != 0 ||
+		msg.PrefilledTxs[0].Tx != blockOne.Transactions[0] {
+		t.Fatalf("NewMsgCmpctBlock: wrong transactions - got %s", spew.Sdump(msg))
+	}
+	if msg.BlockHash() != blockOne.Header.BlockHash() {
+		t.Errorf("BlockHash: wrong hash - got %v, want %v", msg.BlockHash(), blockOne.Header.BlockHash())
+	}
+	// The key is the start of the single sha256 of the header followed by the nonce.
+	keyPreimage := append(append([]byte{}, blockOneBytes[:blockHeaderLen]...),
+		0x08, 0x07, 0x06, 0x05, 0x04, 0x03, 0x02, 0x01,
+	)
+	key := msg.ShortIDKey()
+	if !bytes.Equal(key[:], chainhash.HashB(keyPreimage)[:16]) {
+		t.Errorf("ShortIDKey: wrong key - got %x", key)
+	}
+	for i, tx := range block.Transactions[1:] {
+		txHash := tx.TxHash()
+		shortID := ShortID(&key, &txHash)
+		if shortID >= 1<<(ShortIDSize*8) {
+			t.Errorf("ShortID: short ID %x is more than %d bytes", shortID, ShortIDSize)
+		}
+		if msg.ShortIDs[i] != shortID {
+			t.Errorf("NewMsgCmpctBlock: wrong short ID %d - got %x, want %x", i, msg.ShortIDs[i], shortID)
+		}
+	}
+	// Another nonce gives other short IDs.
+	if other := NewMsgCmpctBlock(block, nonce+1); reflect.DeepEqual(other.ShortIDs, msg.ShortIDs) {
+		t.Errorf("NewMsgCmpctBlock: short IDs do not depend on the nonce")
+	}
+}
+
+// TestCmpctBlockWire tests the MsgCmpctBlock wire encode and decode for various protocol versions.
+func TestCmpctBlockWire(t *testing.T) {
+	coinbase := blockOne.Transactions[0]
+	coinbaseBytes := blockOneBytes[blockHeaderLen+1:]
+	msg := &MsgCmpctBlock{
+		Header:       blockOne.Header,
+		Nonce:        0x0102030405060708,
+		ShortIDs:     []uint64{0x010203040506, 0x0a0b0c0d0e0f},
+		PrefilledTxs: []PrefilledTx{{Index: 0, Tx: coinbase}, {Index: 2, Tx: coinbase}},
+	}
+	var msgEncoded []byte
+	msgEncoded = append(msgEncoded, blockOneBytes[:blockHeaderLen]...)
+	msgEncoded = append(msgEncoded,
+		0x08, 0x07, 0x06, 0x05, 0x04, 0x03, 0x02, 0x01, // Nonce
+		0x02,                               // Varint for number of short IDs
+		0x06, 0x05, 0x04, 0x03, 0x02, 0x01, // Short ID
+		0x0f, 0x0e, 0x0d, 0x0c, 0x0b, 0x0a, // Short ID
+		0x02, // Varint for number of prefilled transactions
+		0x00, // Index 0
+	)
+	msgEncoded = append(msgEncoded, coinbaseBytes...)
+	msgEncoded = append(msgEncoded, 0x01) // Index 2 as the difference to index 1
+	msgEncoded = append(msgEncoded, coinbaseBytes...)
+	tests := []struct {
+		in   *MsgCmpctBlock // Message to encode
+		out  *MsgCmpctBlock // Expected decoded message
+		buf  []byte         // Wire encoding
+		pver uint32         // Protocol version for wire encoding
+	}{
+		// Latest protocol version.
+		{msg, msg, msgEncoded, ProtocolVersion},
+		// Protocol version SendCmpctVersion.
+		{msg, msg, msgEncoded, SendCmpctVersion},
+	}
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Encode the message to wire format.
+		var buf bytes.Buffer
+		e := test.in.BtcEncode(&buf, test.pver, BaseEncoding)
+		if e != nil {
+			t.Errorf("BtcEncode #%d error %v", i, e)
+			continue
+		}
+		if !bytes.Equal(buf.Bytes(), test.buf) {
+			t.Errorf("BtcEncode #%d\n got: %s want: %s", i,
+				spew.Sdump(buf.Bytes()), spew.Sdump(test.buf),
+			)
+			continue
+		}
+		// Decode the message from wire format.
+		var msg MsgCmpctBlock
+		rbuf := bytes.NewReader(test.buf)
+		e = msg.BtcDecode(rbuf, test.pver, BaseEncoding)
+		if e != nil {
+			t.Errorf("BtcDecode #%d error %v", i, e)
+			continue
+		}
+		if !reflect.DeepEqual(&msg, test.out) {
+			t.Errorf("BtcDecode #%d\n got: %s want: %s", i,
+				spew.Sdump(&msg), spew.Sdump(test.out),
+			)
+			continue
+		}
+	}
+}
+
+// TestCmpctBlockWireErrors performs negative tests against wire encode and decode of MsgCmpctBlock to confirm error
+// paths work correctly.
+func TestCmpctBlockWireErrors(t *testing.T) {
+	header := blockOneBytes[:blockHeaderLen]
+	nonce := []byte{0x08, 0x07, 0x06, 0x05, 0x04, 0x03, 0x02, 0x01}
+	tests := []struct {
+		name string
+		buf  []byte // Wire encoding
+		pver uint32 // Protocol version for wire encoding
+	}{
+		{"old protocol version", append(append([]byte{}, header...), nonce...), SendCmpctVersion - 1},
+		{
+			"too many short IDs",
+			append(append(append([]byte{}, header...), nonce...), 0xfe, 0xff, 0xff, 0xff, 0xff),
+			ProtocolVersion,
+		},
+		{
+			// The only transaction of the block can not be at index 1.
+			"prefilled index out of range",
+			append(append(append([]byte{}, header...), nonce...), 0x00, 0x01, 0x01),
+			ProtocolVersion,
+		},
+	}
+	for _, test := range tests {
+		var msg MsgCmpctBlock
+		e := msg.BtcDecode(bytes.NewReader(test.buf), test.pver, BaseEncoding)
+		if _, ok := e.(*MessageError); !ok {
+			t.Errorf("BtcDecode %s: wrong error got: %v, want: %T", test.name, e, &MessageError{})
+		}
+	}
+	// Prefilled transactions must be in ascending order to be encoded.
+	msg := &MsgCmpctBlock{
+		Header:       blockOne.Header,
+		ShortIDs:     []uint64{1},
+		PrefilledTxs: []PrefilledTx{{Index: 1, Tx: multiTx}, {Index: 0, Tx: multiTx}},
+	}
+	var buf bytes.Buffer
+	if e := msg.BtcEncode(&buf, ProtocolVersion, BaseEncoding); e == nil {
+		t.Errorf("BtcEncode of prefilled transactions in descending order succeeded")
+	}
+}
@@ -0,0 +1,94 @@
+package wire
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/p9c/pod/pkg/chainhash"
+)
+
+// MsgGetBlockTxn implements the Message interface and represents a bitcoin getblocktxn message. It is used to request
+// the transactions of a block announced with a cmpctblock message that could not be found among the known
+// transactions, by their indexes in the block. This message was not added until protocol versions starting with
+// SendCmpctVersion.
+type MsgGetBlockTxn struct {
+	BlockHash chainhash.Hash
+	// Indexes are the positions of the requested transactions in the block in ascending order. On the wire each is
+	// encoded as the difference to the previous index.
+	Indexes []uint32
+}
+
+// BtcDecode decodes r using the bitcoin protocol encoding into the receiver. This is part of the Message interface
+// implementation.
+func (msg *MsgGetBlockTxn) BtcDecode(r io.Reader, pver uint32, enc MessageEncoding) (e error) {
+	if pver < SendCmpctVersion {
+		str := fmt.Sprintf("getblocktxn message invalid for protocol version %d", pver)
+		return messageError("MsgGetBlockTxn.BtcDecode", str)
+	}
+	if e = readElement(r, &msg.BlockHash); E.Chk(e) {
+		return
+	}
+	var count uint64
+	if count, e = ReadVarInt(r, pver); E.Chk(e) {
+		return
+	}
+	// Limit to the number of transactions that could be in a block to prevent memory exhaustion.
+	if count > maxTxPerBlock {
+		str := fmt.Sprintf(
+			"too many transaction indexes for a block [count %d, max %d]", count, maxTxPerBlock,
+		)
+		return messageError("MsgGetBlockTxn.BtcDecode", str)
+	}
+	msg.Indexes = make([]uint32, 0, count)
+	return readIndexes(
+		r, pver, count, maxTxPerBlock, func(index uint32) (e error) {
+			msg.Indexes = append(msg.Indexes, index)
+			return
+		},
+	)
+}
+
+// BtcEncode encodes the receiver to w using the bitcoin protocol encoding. This is part of the Message interface
+// implementation.
+func (msg *MsgGetBlockTxn) BtcEncode(w io.Writer, pver uint32, enc MessageEncoding) (e error) {
+	if pver < SendCmpctVersion {
+		str := fmt.Sprintf("getblocktxn message invalid for protocol version %d", pver)
+		return messageError("MsgGetBlockTxn.BtcEncode", str)
+	}
+	if e = writeElement(w, &msg.BlockHash); E.Chk(e) {
+		return
+	}
+	if e = WriteVarInt(w, pver, uint64(len(msg.Indexes))); E.Chk(e) {
+		return
+	}
+	var next uint32
+	for _, index := range msg.Indexes {
+		if e = writeIndex(w, pver, index, next); E.Chk(e) {
+			return
+		}
+		next = index + 1
+	}
+	return
+}
+
+// Command returns the protocol command string for the message. This is part of the Message interface implementation.
+func (msg *MsgGetBlockTxn) Command() string {
+	return CmdGetBlockTxn
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the receiver. This is part of the Message
+// interface implementation.
+func (msg *MsgGetBlockTxn) MaxPayloadLength(pver uint32) uint32 {
+	// Block hash + num indexes (varInt) + max indexes, each of which is a varInt of at most 5 bytes as they are smaller
+	// than maxTxPerBlock.
+	return chainhash.HashSize + MaxVarIntPayload + maxTxPerBlock*5
+}
+
+// NewMsgGetBlockTxn returns a new bitcoin getblocktxn message that conforms to the Message interface for the
+// transactions at the indexes in a block. See MsgGetBlockTxn for details.
+func NewMsgGetBlockTxn(blockHash *chainhash.Hash, indexes []uint32) *MsgGetBlockTxn {
+	return &MsgGetBlockTxn{
+		BlockHash: *blockHash,
+		Indexes:   indexes,
+	}
+}
@@ -0,0 +1,88 @@
+package wire
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+
+	"github.com/p9c/pod/pkg/chainhash"
+)
+
+// TestGetBlockTxnWire tests the MsgGetBlockTxn API and wire encode and decode for various protocol versions.
+func TestGetBlockTxnWire(t *testing.T) {
+	hash := chainhash.Hash{0x01, 0x02, 0x03}
+	msg := NewMsgGetBlockTxn(&hash, []uint32{0, 1, 5, 300})
+	// Ensure the command is expected value.
+	wantCmd := "getblocktxn"
+	if cmd := msg.Command(); cmd != wantCmd {
+		t.Errorf("NewMsgGetBlockTxn: wrong command - got %v want %v", cmd, wantCmd)
+	}
+	msgEncoded := append(append([]byte{}, hash[:]...),
+		0x04,             // Varint for number of indexes
+		0x00,             // Index 0
+		0x00,             // Index 1
+		0x03,             // Index 5
+		0xfd, 0x26, 0x01, // Index 300
+	)
+	tests := []struct {
+		in   *MsgGetBlockTxn // Message to encode
+		out  *MsgGetBlockTxn // Expected decoded message
+		buf  []byte          // Wire encoding
+		pver uint32          // Protocol version for wire encoding
+	}{
+		// Latest protocol version.
+		{msg, msg, msgEncoded, ProtocolVersion},
+		// Protocol version SendCmpctVersion.
+		{msg, msg, msgEncoded, SendCmpctVersion},
+	}
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Encode the message to wire format.
+		var buf bytes.Buffer
+		e := test.in.BtcEncode(&buf, test.pver, BaseEncoding)
+		if e != nil {
+			t.Errorf("BtcEncode #%d error %v", i, e)
+			continue
+		}
+		if !bytes.Equal(buf.Bytes(), test.buf) {
+			t.Errorf("BtcEncode #%d\n got: %s want: %s", i,
+				spew.Sdump(buf.Bytes()), spew.Sdump(test.buf),
+			)
+			continue
+		}
+		// Decode the message from wire format.
+		var msg MsgGetBlockTxn
+		rbuf := bytes.NewReader(test.buf)
+		e = msg.BtcDecode(rbuf, test.pver, BaseEncoding)
+		if e != nil {
+			t.Errorf("BtcDecode #%d error %v", i, e)
+			continue
+		}
+		if !reflect.DeepEqual(&msg, test.out) {
+			t.Errorf("BtcDecode #%d\n got: %s want: %s", i,
+				spew.Sdump(&msg), spew.Sdump(test.out),
+			)
+			continue
+		}
+	}
+	// Older protocol versions should fail since the message didn't exist yet, as should indexes that overflow and
+	// indexes that are not in ascending order.
+	var buf bytes.Buffer
+	if e := msg.BtcEncode(&buf, SendCmpctVersion-1, BaseEncoding); e == nil {
+		t.Errorf("BtcEncode for old protocol version succeeded")
+	}
+	var readmsg MsgGetBlockTxn
+	if e := readmsg.BtcDecode(bytes.NewReader(msgEncoded), SendCmpctVersion-1, BaseEncoding); e == nil {
+		t.Errorf("BtcDecode for old protocol version succeeded")
+	}
+	overflow := append(append([]byte{}, hash[:]...), 0x02, 0x00, 0xfe, 0xff, 0xff, 0xff, 0xff)
+	if e := readmsg.BtcDecode(bytes.NewReader(overflow), ProtocolVersion, BaseEncoding); e == nil {
+		t.Errorf("BtcDecode of overflowing index succeeded")
+	}
+	descending := NewMsgGetBlockTxn(&hash, []uint32{5, 1})
+	if e := descending.BtcEncode(&buf, ProtocolVersion, BaseEncoding); e == nil {
+		t.Errorf("BtcEncode of indexes in descending order succeeded")
+	}
+}
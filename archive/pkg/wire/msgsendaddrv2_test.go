@@ -0,0 +1,38 @@
+package wire
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestSendAddrV2 tests the MsgSendAddrV2 API against the latest protocol version and fails for older ones.
+func TestSendAddrV2(t *testing.T) {
+	pver := ProtocolVersion
+	// Ensure the command is expected value.
+	wantCmd := "sendaddrv2"
+	msg := NewMsgSendAddrV2()
+	if cmd := msg.Command(); cmd != wantCmd {
+		t.Errorf("NewMsgSendAddrV2: wrong command - got %v want %v", cmd, wantCmd)
+	}
+	// Ensure max payload is expected value.
+	if maxPayload := msg.MaxPayloadLength(pver); maxPayload != 0 {
+		t.Errorf("MaxPayloadLength: wrong max payload length for protocol version %d - got %v, want 0",
+			pver, maxPayload,
+		)
+	}
+	var buf bytes.Buffer
+	if e := msg.BtcEncode(&buf, pver, BaseEncoding); e != nil {
+		t.Errorf("encode of MsgSendAddrV2 failed %v", e)
+	}
+	if e := msg.BtcDecode(&buf, pver, BaseEncoding); e != nil {
+		t.Errorf("decode of MsgSendAddrV2 failed %v", e)
+	}
+	// Older protocol versions should fail encode and decode since the message didn't exist yet.
+	oldPver := AddrV2Version - 1
+	if e := msg.BtcEncode(&buf, oldPver, BaseEncoding); e == nil {
+		t.Errorf("encode of MsgSendAddrV2 passed for old protocol version %v", oldPver)
+	}
+	if e := msg.BtcDecode(&buf, oldPver, BaseEncoding); e == nil {
+		t.Errorf("decode of MsgSendAddrV2 passed for old protocol version %v", oldPver)
+	}
+}
@@ -0,0 +1,93 @@
+package wire
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestSendCmpct tests the MsgSendCmpct API against the latest protocol version.
+func TestSendCmpct(t *testing.T) {
+	pver := ProtocolVersion
+	// Ensure the command is expected value.
+	wantCmd := "sendcmpct"
+	msg := NewMsgSendCmpct(true, CmpctBlockVersion)
+	if cmd := msg.Command(); cmd != wantCmd {
+		t.Errorf("NewMsgSendCmpct: wrong command - got %v want %v", cmd, wantCmd)
+	}
+	// Ensure max payload is expected value.
+	wantPayload := uint32(9)
+	maxPayload := msg.MaxPayloadLength(pver)
+	if maxPayload != wantPayload {
+		t.Errorf("MaxPayloadLength: wrong max payload length for protocol version %d - got %v, want %v",
+			pver, maxPayload, wantPayload,
+		)
+	}
+	// Older protocol versions should fail encode and decode since the message didn't exist yet.
+	oldPver := SendCmpctVersion - 1
+	var buf bytes.Buffer
+	if e := msg.BtcEncode(&buf, oldPver, BaseEncoding); e == nil {
+		t.Errorf("encode of MsgSendCmpct passed for old protocol version %v", oldPver)
+	}
+	readmsg := MsgSendCmpct{}
+	if e := readmsg.BtcDecode(bytes.NewReader([]byte{0x01, 0x01, 0, 0, 0, 0, 0, 0, 0}), oldPver, BaseEncoding); e == nil {
+		t.Errorf("decode of MsgSendCmpct passed for old protocol version %v", oldPver)
+	}
+}
+
+// TestSendCmpctWire tests the MsgSendCmpct wire encode and decode for various protocol versions.
+func TestSendCmpctWire(t *testing.T) {
+	tests := []struct {
+		in   *MsgSendCmpct // Message to encode
+		out  *MsgSendCmpct // Expected decoded message
+		buf  []byte        // Wire encoding
+		pver uint32        // Protocol version for wire encoding
+	}{
+		// Latest protocol version, high bandwidth mode.
+		{
+			NewMsgSendCmpct(true, CmpctBlockVersion),
+			NewMsgSendCmpct(true, CmpctBlockVersion),
+			[]byte{0x01, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+			ProtocolVersion,
+		},
+		// Protocol version SendCmpctVersion, low bandwidth mode and an unknown version.
+		{
+			NewMsgSendCmpct(false, 0x0102),
+			NewMsgSendCmpct(false, 0x0102),
+			[]byte{0x00, 0x02, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+			SendCmpctVersion,
+		},
+	}
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Encode the message to wire format.
+		var buf bytes.Buffer
+		e := test.in.BtcEncode(&buf, test.pver, BaseEncoding)
+		if e != nil {
+			t.Errorf("BtcEncode #%d error %v", i, e)
+			continue
+		}
+		if !bytes.Equal(buf.Bytes(), test.buf) {
+			t.Errorf("BtcEncode #%d\n got: %s want: %s", i,
+				spew.Sdump(buf.Bytes()), spew.Sdump(test.buf),
+			)
+			continue
+		}
+		// Decode the message from wire format.
+		var msg MsgSendCmpct
+		rbuf := bytes.NewReader(test.buf)
+		e = msg.BtcDecode(rbuf, test.pver, BaseEncoding)
+		if e != nil {
+			t.Errorf("BtcDecode #%d error %v", i, e)
+			continue
+		}
+		if !reflect.DeepEqual(&msg, test.out) {
+			t.Errorf("BtcDecode #%d\n got: %s want: %s", i,
+				spew.Sdump(msg), spew.Sdump(test.out),
+			)
+			continue
+		}
+	}
+}
@@ -35,6 +35,13 @@ type NetAddress struct {
 	// Port the peer is using. This is encoded in big endian on the wire which
 	// differs from most everything else.
 	Port uint16
+	// Network is the network of an address that is not an IP address, such as
+	// a Tor v3 or I2P address, which can only be relayed in addrv2 messages. It
+	// is zero for IP addresses, including Tor v2 addresses in their OnionCat
+	// encoding.
+	Network NetworkID
+	// Addr is the address of the peer on Network. IP is nil for such addresses.
+	Addr []byte
 }
 
 // HasService returns whether the specified service is supported by the address.
@@ -0,0 +1,170 @@
+package wire
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+)
+
+// NetworkID identifies the network of an address in an addrv2 message (BIP0155).
+type NetworkID uint8
+
+const (
+	// NetworkIPv4 is the network of IPv4 addresses.
+	NetworkIPv4 NetworkID = 1
+	// NetworkIPv6 is the network of IPv6 addresses.
+	NetworkIPv6 NetworkID = 2
+	// NetworkTorV2 is the network of Tor v2 onion services, whose addresses are the 10 byte hashes of their keys.
+	NetworkTorV2 NetworkID = 3
+	// NetworkTorV3 is the network of Tor v3 onion services, whose addresses are their 32 byte ed25519 public keys.
+	NetworkTorV3 NetworkID = 4
+	// NetworkI2P is the network of I2P destinations, whose addresses are the 32 byte hashes of the destinations.
+	NetworkI2P NetworkID = 5
+	// NetworkCJDNS is the network of CJDNS addresses, which are IPv6 addresses in fc00::/8.
+	NetworkCJDNS NetworkID = 6
+)
+
+// MaxAddrV2Size is the maximum length of an address in an addrv2 message.
+const MaxAddrV2Size = 512
+
+// addrV2Sizes is the length of the addresses of the known networks. Addresses of known networks with another length
+// are invalid, those of unknown networks are relayed as they are.
+var addrV2Sizes = map[NetworkID]int{
+	NetworkIPv4:  4,
+	NetworkIPv6:  16,
+	NetworkTorV2: 10,
+	NetworkTorV3: 32,
+	NetworkI2P:   32,
+	NetworkCJDNS: 16,
+}
+
+// onionCatPrefix is the prefix of the IPv6 addresses Tor v2 addresses are encoded as in addr and version messages.
+var onionCatPrefix = []byte{0xfd, 0x87, 0xd8, 0x7e, 0xeb, 0x43}
+
+// networkStrings is the names of the known networks.
+var networkStrings = map[NetworkID]string{
+	NetworkIPv4:  "ipv4",
+	NetworkIPv6:  "ipv6",
+	NetworkTorV2: "torv2",
+	NetworkTorV3: "torv3",
+	NetworkI2P:   "i2p",
+	NetworkCJDNS: "cjdns",
+}
+
+// String returns the NetworkID in human-readable form.
+func (n NetworkID) String() string {
+	if s, ok := networkStrings[n]; ok {
+		return s
+	}
+	return fmt.Sprintf("Unknown NetworkID (%d)", uint8(n))
+}
+
+// IsKnown returns whether the network is one this package knows the addresses of.
+func (n NetworkID) IsKnown() bool {
+	_, ok := addrV2Sizes[n]
+	return ok
+}
+
+// NetworkID returns the network of the address. IP addresses in the OnionCat range are Tor v2 addresses.
+func (na *NetAddress) NetworkID() NetworkID {
+	switch {
+	case na.Network != 0:
+		return na.Network
+	case na.IP.To4() != nil:
+		return NetworkIPv4
+	case len(na.IP) == net.IPv6len && bytes.HasPrefix(na.IP, onionCatPrefix):
+		return NetworkTorV2
+	default:
+		return NetworkIPv6
+	}
+}
+
+// IsAddrV1Compatible returns whether the address can be sent in addr and version messages, which only carry IP
+// addresses.
+func (na *NetAddress) IsAddrV1Compatible() bool {
+	return na.Network == 0
+}
+
+// maxNetAddressPayloadV2 returns the max payload size for an address in an addrv2 message.
+func maxNetAddressPayloadV2() uint32 {
+	// Timestamp 4 bytes + services varint + network 1 byte + address varint + address + port 2 bytes.
+	return 4 + MaxVarIntPayload + 1 + MaxVarIntPayload + MaxAddrV2Size + 2
+}
+
+// readNetAddressV2 reads an address encoded as in an addrv2 message from r. IPv4, IPv6 and Tor v2 addresses are
+// decoded into the IP field as they are by readNetAddress.
+func readNetAddressV2(r io.Reader, pver uint32, na *NetAddress) (e error) {
+	if e = readElement(r, (*uint32Time)(&na.Timestamp)); E.Chk(e) {
+		return
+	}
+	var services uint64
+	if services, e = ReadVarInt(r, pver); E.Chk(e) {
+		return
+	}
+	var network uint8
+	if network, e = binarySerializer.Uint8(r); E.Chk(e) {
+		return
+	}
+	var addr []byte
+	if addr, e = ReadVarBytes(r, pver, MaxAddrV2Size, "addrv2 address"); E.Chk(e) {
+		return
+	}
+	var port uint16
+	if port, e = binarySerializer.Uint16(r, bigEndian); E.Chk(e) {
+		return
+	}
+	id := NetworkID(network)
+	if size, ok := addrV2Sizes[id]; ok && len(addr) != size {
+		str := fmt.Sprintf("invalid %v address length [len %d, want %d]", id, len(addr), size)
+		return messageError("readNetAddressV2", str)
+	}
+	*na = NetAddress{
+		Timestamp: na.Timestamp,
+		Services:  ServiceFlag(services),
+		Port:      port,
+	}
+	switch id {
+	case NetworkIPv4, NetworkIPv6:
+		na.IP = net.IP(addr).To16()
+	case NetworkTorV2:
+		na.IP = append(append(make(net.IP, 0, net.IPv6len), onionCatPrefix...), addr...)
+	default:
+		na.Network = id
+		na.Addr = addr
+	}
+	return
+}
+
+// writeNetAddressV2 serializes an address to w as in an addrv2 message.
+func writeNetAddressV2(w io.Writer, pver uint32, na *NetAddress) (e error) {
+	if e = writeElement(w, uint32(na.Timestamp.Unix())); E.Chk(e) {
+		return
+	}
+	if e = WriteVarInt(w, pver, uint64(na.Services)); E.Chk(e) {
+		return
+	}
+	network := na.NetworkID()
+	// Ensure to always write a full IP address even if the ip is nil.
+	var ip [16]byte
+	copy(ip[:], na.IP.To16())
+	var addr []byte
+	switch network {
+	case NetworkIPv4:
+		addr = ip[12:]
+	case NetworkIPv6:
+		addr = ip[:]
+	case NetworkTorV2:
+		addr = ip[len(onionCatPrefix):]
+	default:
+		addr = na.Addr
+	}
+	if e = binarySerializer.PutUint8(w, uint8(network)); E.Chk(e) {
+		return
+	}
+	if e = WriteVarBytes(w, pver, addr); E.Chk(e) {
+		return
+	}
+	return binary.Write(w, bigEndian, na.Port)
+}
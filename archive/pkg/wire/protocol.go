@@ -9,7 +9,7 @@ import (
 // XXX pedro: we will probably need to bump this.
 const (
 	// ProtocolVersion is the latest protocol version this package supports.
-	ProtocolVersion uint32 = 70013
+	ProtocolVersion uint32 = 70015
 	// MultipleAddressVersion is the protocol version which added multiple addresses per message (pver >=
 	// MultipleAddressVersion).
 	MultipleAddressVersion uint32 = 209
@@ -31,6 +31,12 @@ const (
 	SendHeadersVersion uint32 = 70012
 	// FeeFilterVersion is the protocol version which added a new feefilter message.
 	FeeFilterVersion uint32 = 70013
+	// SendCmpctVersion is the protocol version which added the sendcmpct, cmpctblock, getblocktxn and blocktxn messages
+	// for compact block relay (BIP0152).
+	SendCmpctVersion uint32 = 70014
+	// AddrV2Version is the protocol version which added the sendaddrv2 and addrv2 messages for relaying addresses of
+	// networks other than IPv4 and IPv6, such as Tor v3 and I2P (BIP0155).
+	AddrV2Version uint32 = 70015
 )
 
 // ServiceFlag identifies services supported by a bitcoin peer.
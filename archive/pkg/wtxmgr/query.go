@@ -422,3 +422,48 @@ func (s *Store) PreviousPkScripts(ns walletdb.ReadBucket, rec *TxRecord, block *
 	}
 	return pkScripts, nil
 }
+
+// StoreSummary counts the records held in a transaction store.
+type StoreSummary struct {
+	Blocks       int
+	Transactions int
+	Credits      int
+	Unspent      int
+	Debits       int
+	Unmined      int
+	MinedBalance amt.Amount
+}
+
+// Summarize counts the records of the transaction store in the namespace bucket ns without opening a Store, so it can
+// be used to report what is removed when the store is dropped.
+func Summarize(ns walletdb.ReadBucket) (s StoreSummary, e error) {
+	if s.MinedBalance, e = fetchMinedBalance(ns); E.Chk(e) {
+		return
+	}
+	for _, b := range []struct {
+		name  []byte
+		count *int
+	}{
+		{bucketBlocks, &s.Blocks},
+		{bucketTxRecords, &s.Transactions},
+		{bucketCredits, &s.Credits},
+		{bucketUnspent, &s.Unspent},
+		{bucketDebits, &s.Debits},
+		{bucketUnmined, &s.Unmined},
+	} {
+		bucket := ns.NestedReadBucket(b.name)
+		if bucket == nil {
+			continue
+		}
+		count := b.count
+		if e = bucket.ForEach(
+			func(k, v []byte) (e error) {
+				*count++
+				return nil
+			},
+		); E.Chk(e) {
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,15 @@
+/*
+Package zmqpub implements the publishing side of a ZeroMQ PUB socket over TCP, enough of ZMTP 3.0 with the NULL security
+mechanism for libzmq SUB sockets and clients like gozmq to subscribe to it.
+
+Messages are published the same way bitcoind does with its zmqpub options, in three frames: the topic, the body and a
+four byte little endian sequence number that is counted separately for each topic, so subscribers can tell when they
+missed a message.
+
+	topic | body | sequence (4)
+
+Subscribers receive the messages whose topic starts with one of the prefixes they subscribed to. Like a PUB socket, a
+publisher never blocks on slow subscribers, and messages are dropped for a subscriber that has too many messages waiting
+to be sent.
+*/
+package zmqpub
@@ -0,0 +1,289 @@
+package zmqpub
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/p9c/qu"
+)
+
+const (
+	// highWaterMark is the number of messages that may wait to be sent to a subscriber before further messages for
+	// it are dropped
+	highWaterMark = 1000
+	// handshakeTimeout is how long a subscriber has to complete the handshake after connecting
+	handshakeTimeout = time.Second * 10
+	// writeTimeout is how long writing a message to a subscriber may take before it is disconnected
+	writeTimeout = time.Minute
+)
+
+// Publisher accepts subscribers on a TCP endpoint and sends them the messages published on the topics they subscribed
+// to
+type Publisher struct {
+	listener net.Listener
+	mx       sync.Mutex
+	subs     map[*subscriber]struct{}
+	seq      map[string]uint32
+	wg       sync.WaitGroup
+	quit     qu.C
+}
+
+// subscriber is a connection to a SUB socket
+type subscriber struct {
+	conn     net.Conn
+	mx       sync.Mutex
+	prefixes map[string]int
+	out      chan []byte
+	quit     qu.C
+}
+
+// Listen starts a publisher on an endpoint in the form tcp://address:port, as used for the zmqpub options of bitcoind.
+// The address may be * to listen on all interfaces.
+func Listen(endpoint string) (p *Publisher, e error) {
+	addr := endpoint
+	if strings.Contains(endpoint, "://") {
+		if !strings.HasPrefix(endpoint, "tcp://") {
+			return nil, errors.New("only tcp zmq endpoints are supported: " + endpoint)
+		}
+		addr = strings.TrimPrefix(endpoint, "tcp://")
+	}
+	if strings.HasPrefix(addr, "*:") {
+		addr = addr[1:]
+	}
+	var listener net.Listener
+	if listener, e = net.Listen("tcp", addr); E.Chk(e) {
+		return
+	}
+	p = &Publisher{
+		listener: listener,
+		subs:     make(map[*subscriber]struct{}),
+		seq:      make(map[string]uint32),
+		quit:     qu.T(),
+	}
+	p.wg.Add(1)
+	go p.accept()
+	I.Ln("zmq publisher listening on", listener.Addr())
+	return
+}
+
+// Addr returns the address the publisher is listening on
+func (p *Publisher) Addr() net.Addr {
+	return p.listener.Addr()
+}
+
+// Publish sends a message on a topic to all subscribers of the topic, followed by the sequence number of the message
+// on the topic
+func (p *Publisher) Publish(topic string, body []byte) {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	var seq [4]byte
+	binary.LittleEndian.PutUint32(seq[:], p.seq[topic])
+	p.seq[topic]++
+	var msg []byte
+	for s := range p.subs {
+		if !s.subscribed(topic) {
+			continue
+		}
+		if msg == nil {
+			msg = appendMessage(nil, []byte(topic), body, seq[:])
+		}
+		select {
+		case s.out <- msg:
+		default:
+			D.Ln("dropping", topic, "message for slow zmq subscriber", s.conn.RemoteAddr())
+		}
+	}
+}
+
+// Close stops accepting subscribers and disconnects the ones connected
+func (p *Publisher) Close() (e error) {
+	p.quit.Q()
+	e = p.listener.Close()
+	p.mx.Lock()
+	for s := range p.subs {
+		s.close()
+	}
+	p.mx.Unlock()
+	p.wg.Wait()
+	return
+}
+
+// accept handles the connections of new subscribers until the publisher is closed
+func (p *Publisher) accept() {
+	defer p.wg.Done()
+	for {
+		conn, e := p.listener.Accept()
+		if e != nil {
+			select {
+			case <-p.quit.Wait():
+			default:
+				E.Ln("zmq publisher stopped accepting subscribers:", e)
+			}
+			return
+		}
+		s := &subscriber{
+			conn:     conn,
+			prefixes: make(map[string]int),
+			out:      make(chan []byte, highWaterMark),
+			quit:     qu.T(),
+		}
+		p.wg.Add(1)
+		go p.serve(s)
+	}
+}
+
+// serve completes the handshake with a subscriber, then sends it messages while reading its subscriptions
+func (p *Publisher) serve(s *subscriber) {
+	defer p.wg.Done()
+	defer s.close()
+	var e error
+	if e = s.handshake(); e != nil {
+		D.Ln("zmq subscriber", s.conn.RemoteAddr(), "failed handshake:", e)
+		return
+	}
+	p.mx.Lock()
+	select {
+	case <-p.quit.Wait():
+		p.mx.Unlock()
+		return
+	default:
+	}
+	p.subs[s] = struct{}{}
+	p.mx.Unlock()
+	D.Ln("zmq subscriber connected from", s.conn.RemoteAddr())
+	defer func() {
+		p.mx.Lock()
+		delete(p.subs, s)
+		p.mx.Unlock()
+		D.Ln("zmq subscriber", s.conn.RemoteAddr(), "disconnected")
+	}()
+	go s.read()
+	for {
+		select {
+		case msg := <-s.out:
+			if e = s.conn.SetWriteDeadline(time.Now().Add(writeTimeout)); E.Chk(e) {
+				return
+			}
+			if _, e = s.conn.Write(msg); e != nil {
+				return
+			}
+		case <-s.quit.Wait():
+			return
+		}
+	}
+}
+
+// handshake exchanges the greetings and READY commands with a subscriber
+func (s *subscriber) handshake() (e error) {
+	if e = s.conn.SetDeadline(time.Now().Add(handshakeTimeout)); E.Chk(e) {
+		return
+	}
+	if _, e = s.conn.Write(append(greeting(), readyCommand("PUB")...)); e != nil {
+		return
+	}
+	g := make([]byte, greetingSize)
+	if _, e = io.ReadFull(s.conn, g); e != nil {
+		return
+	}
+	if e = checkGreeting(g); e != nil {
+		return
+	}
+	var flags byte
+	var body []byte
+	if flags, body, e = readFrame(s.conn); e != nil {
+		return
+	}
+	var name string
+	if flags&flagCommand == 0 {
+		return errors.New("expected READY command")
+	}
+	if name, _, e = parseCommand(body); e != nil {
+		return
+	}
+	if name != "READY" {
+		return errors.New("expected READY command, got " + name)
+	}
+	return s.conn.SetDeadline(time.Time{})
+}
+
+// read processes the subscriptions sent by the subscriber until the connection fails
+func (s *subscriber) read() {
+	defer s.close()
+	more := false
+	for {
+		flags, body, e := readFrame(s.conn)
+		if e != nil {
+			return
+		}
+		switch {
+		case flags&flagCommand != 0:
+			// ZMTP 3.1 peers send subscriptions as commands
+			name, data, e := parseCommand(body)
+			if e != nil {
+				return
+			}
+			switch name {
+			case "SUBSCRIBE":
+				s.subscribe(string(data), true)
+			case "CANCEL":
+				s.subscribe(string(data), false)
+			}
+		case more:
+			// only single frame messages are subscriptions, the frames of other messages are ignored
+		case len(body) > 0 && flags&flagMore == 0:
+			switch body[0] {
+			case 1:
+				s.subscribe(string(body[1:]), true)
+			case 0:
+				s.subscribe(string(body[1:]), false)
+			}
+		}
+		more = flags&flagCommand == 0 && flags&flagMore != 0
+	}
+}
+
+// subscribe adds or cancels a subscription to the topics starting with a prefix
+func (s *subscriber) subscribe(prefix string, add bool) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if add {
+		s.prefixes[prefix]++
+		return
+	}
+	if s.prefixes[prefix] > 1 {
+		s.prefixes[prefix]--
+	} else {
+		delete(s.prefixes, prefix)
+	}
+}
+
+// subscribed returns whether the subscriber subscribed to a topic
+func (s *subscriber) subscribed(topic string) bool {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	for prefix := range s.prefixes {
+		if strings.HasPrefix(topic, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// close disconnects the subscriber
+func (s *subscriber) close() {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	select {
+	case <-s.quit.Wait():
+		return
+	default:
+	}
+	s.quit.Q()
+	if e := s.conn.Close(); E.Chk(e) {
+	}
+}
@@ -0,0 +1,101 @@
+package zmqpub
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/tstranex/gozmq"
+)
+
+// TestPublish checks that a gozmq subscriber, as used by the bitcoind chain client, receives the messages on the
+// topics it subscribed to with their sequence numbers.
+func TestPublish(t *testing.T) {
+	p, e := Listen("tcp://127.0.0.1:0")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer func() {
+		if e := p.Close(); e != nil {
+			t.Error(e)
+		}
+	}()
+	conn, e := gozmq.Subscribe(p.Addr().String(), []string{"raw"})
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer conn.Close()
+	// the subscription arrives after the handshake, so wait for it to be processed
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		p.mx.Lock()
+		subscribed := false
+		for s := range p.subs {
+			subscribed = subscribed || s.subscribed("rawblock")
+		}
+		p.mx.Unlock()
+		if subscribed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("subscription was not received")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	long := bytes.Repeat([]byte{0xab}, 1000)
+	p.Publish("hashblock", []byte{1})
+	p.Publish("rawblock", []byte{2})
+	p.Publish("rawtx", long)
+	p.Publish("rawblock", []byte{3})
+	expected := []struct {
+		topic string
+		body  []byte
+		seq   uint32
+	}{
+		{"rawblock", []byte{2}, 0},
+		{"rawtx", long, 0},
+		{"rawblock", []byte{3}, 1},
+	}
+	for i := range expected {
+		msg, e := conn.Receive()
+		if e != nil {
+			t.Fatal(e)
+		}
+		if len(msg) != 3 {
+			t.Fatalf("message %d has %d frames", i, len(msg))
+		}
+		if string(msg[0]) != expected[i].topic || !bytes.Equal(msg[1], expected[i].body) ||
+			binary.LittleEndian.Uint32(msg[2]) != expected[i].seq {
+			t.Fatalf(
+				"message %d is %s %x %x, expected %s %x %d", i, msg[0], msg[1], msg[2],
+				expected[i].topic, expected[i].body, expected[i].seq,
+			)
+		}
+	}
+}
+
+// TestSubscriptions checks the matching of topics against subscribed prefixes.
+func TestSubscriptions(t *testing.T) {
+	s := &subscriber{prefixes: make(map[string]int)}
+	if s.subscribed("rawtx") {
+		t.Fatal("subscriber without subscriptions matched a topic")
+	}
+	s.subscribe("hash", true)
+	s.subscribe("hash", true)
+	if !s.subscribed("hashtx") || !s.subscribed("hashblock") || s.subscribed("rawtx") {
+		t.Fatal("prefix subscription matched the wrong topics")
+	}
+	s.subscribe("hash", false)
+	if !s.subscribed("hashtx") {
+		t.Fatal("cancelling one of two subscriptions removed both")
+	}
+	s.subscribe("hash", false)
+	if s.subscribed("hashtx") {
+		t.Fatal("cancelled subscription still matches")
+	}
+	s.subscribe("", true)
+	if !s.subscribed("algoblock") {
+		t.Fatal("empty subscription did not match everything")
+	}
+}
@@ -0,0 +1,112 @@
+package zmqpub
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+const (
+	// flagMore marks a frame that is followed by another frame of the same message
+	flagMore = 1
+	// flagLong marks a frame with an eight byte size
+	flagLong = 2
+	// flagCommand marks a command frame
+	flagCommand = 4
+	// maxIncomingFrame is the largest frame accepted from a subscriber, which only sends subscriptions and commands
+	maxIncomingFrame = 1 << 16
+	// greetingSize is the size of the greeting each side sends first
+	greetingSize = 64
+)
+
+// greeting returns the greeting of a ZMTP 3.0 peer using the NULL security mechanism
+func greeting() []byte {
+	g := make([]byte, greetingSize)
+	g[0], g[9] = 0xff, 0x7f
+	g[10], g[11] = 3, 0
+	copy(g[12:32], "NULL")
+	return g
+}
+
+// checkGreeting checks that the greeting of the other side is of a ZMTP 3 peer using the NULL security mechanism
+func checkGreeting(g []byte) (e error) {
+	switch {
+	case g[0] != 0xff || g[9] != 0x7f:
+		e = errors.New("invalid zmtp signature")
+	case g[10] < 3:
+		e = errors.New("zmtp version of subscriber is too old")
+	case string(g[12:17]) != "NULL\x00":
+		e = errors.New("unsupported zmtp security mechanism")
+	}
+	return
+}
+
+// appendFrame appends a frame with the given flags to b
+func appendFrame(b []byte, flags byte, body []byte) []byte {
+	if len(body) > 255 {
+		var size [8]byte
+		binary.BigEndian.PutUint64(size[:], uint64(len(body)))
+		b = append(append(b, flags|flagLong), size[:]...)
+	} else {
+		b = append(b, flags, byte(len(body)))
+	}
+	return append(b, body...)
+}
+
+// appendMessage appends the frames of a message to b
+func appendMessage(b []byte, parts ...[]byte) []byte {
+	for i := range parts {
+		var flags byte
+		if i < len(parts)-1 {
+			flags = flagMore
+		}
+		b = appendFrame(b, flags, parts[i])
+	}
+	return b
+}
+
+// readyCommand returns the READY command announcing the socket type
+func readyCommand(socketType string) []byte {
+	const name, property = "READY", "Socket-Type"
+	body := append([]byte{byte(len(name))}, name...)
+	body = append(append(body, byte(len(property))), property...)
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(socketType)))
+	body = append(append(body, size[:]...), socketType...)
+	return appendFrame(nil, flagCommand, body)
+}
+
+// readFrame reads a frame sent by a subscriber
+func readFrame(r io.Reader) (flags byte, body []byte, e error) {
+	var head [9]byte
+	if _, e = io.ReadFull(r, head[:2]); e != nil {
+		return
+	}
+	flags = head[0]
+	if flags&^(flagMore|flagLong|flagCommand) != 0 {
+		return 0, nil, errors.New("invalid zmtp frame flags")
+	}
+	size := uint64(head[1])
+	if flags&flagLong != 0 {
+		if _, e = io.ReadFull(r, head[2:]); e != nil {
+			return
+		}
+		size = binary.BigEndian.Uint64(head[1:])
+	}
+	if size > maxIncomingFrame {
+		return 0, nil, errors.New("zmtp frame from subscriber is too large")
+	}
+	body = make([]byte, size)
+	if _, e = io.ReadFull(r, body); e != nil {
+		return
+	}
+	return flags &^ flagLong, body, nil
+}
+
+// parseCommand splits the body of a command frame into its name and data
+func parseCommand(body []byte) (name string, data []byte, e error) {
+	if len(body) < 1 || int(body[0]) > len(body)-1 {
+		return "", nil, errors.New("invalid zmtp command")
+	}
+	return string(body[1 : 1+body[0]]), body[1+body[0]:], nil
+}
@@ -28,6 +28,7 @@ type Config struct {
 	AddCheckpoints         *list.Opt
 	AddPeers               *list.Opt
 	AddrIndex              *binary.Opt
+	AssumeValid            *text.Opt
 	AutoListen             *binary.Opt
 	AutoPorts              *binary.Opt
 	BanDuration            *duration.Opt
@@ -68,7 +69,10 @@ type Config struct {
 	MaxOrphanTxs           *integer.Opt
 	MaxPeers               *integer.Opt
 	MinRelayTxFee          *float.Opt
+	MiningPayouts          *list.Opt
 	MulticastPass          *text.Opt
+	MulticastTrustedKeys   *list.Opt
+	MulticastWorkerKeys    *list.Opt
 	Network                *text.Opt
 	NoCFilters             *binary.Opt
 	NoInitialLoad          *binary.Opt
@@ -84,10 +88,15 @@ type Config struct {
 	P2PListeners           *list.Opt
 	Password               *text.Opt
 	PipeLog                *binary.Opt
+	PoolMode               *binary.Opt
+	PoolPayoutAddress      *text.Opt
+	PoolWindow             *integer.Opt
 	Profile                *text.Opt
 	ProxyAddress           *text.Opt
 	ProxyPass              *text.Opt
 	ProxyUser              *text.Opt
+	PruneTarget            *integer.Opt
+	REST                   *binary.Opt
 	RPCCert                *text.Opt
 	RPCConnect             *text.Opt
 	RPCKey                 *text.Opt
@@ -100,15 +109,21 @@ type Config struct {
 	RelayNonStd            *binary.Opt
 	RunAsService           *binary.Opt
 	Save                   *binary.Opt
+	ScryptRPCListeners     *list.Opt
 	ServerTLS              *binary.Opt
 	SigCacheMaxSize        *integer.Opt
 	Solo                   *binary.Opt
+	StratumDifficulty      *float.Opt
+	StratumListeners       *list.Opt
 	TLSSkipVerify          *binary.Opt
 	TorIsolation           *binary.Opt
 	TrickleInterval        *duration.Opt
 	TxIndex                *binary.Opt
 	UPNP                   *binary.Opt
+	UTXOSnapshot           *text.Opt
+	UTXOSnapshotHash       *text.Opt
 	UUID                   *integer.Opt
+	UseSPV                 *binary.Opt
 	UseWallet              *binary.Opt
 	UserAgentComments      *list.Opt
 	Username               *text.Opt
@@ -120,4 +135,9 @@ type Config struct {
 	WalletRPCMaxWebsockets *integer.Opt
 	WalletServer           *text.Opt
 	Whitelists             *list.Opt
+	ZMQPubAlgoBlock        *text.Opt
+	ZMQPubHashBlock        *text.Opt
+	ZMQPubHashTx           *text.Opt
+	ZMQPubRawBlock         *text.Opt
+	ZMQPubRawTx            *text.Opt
 }
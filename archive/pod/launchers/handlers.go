@@ -65,6 +65,68 @@ func NodeHandle(ifc interface{}) (e error) {
 	return nil
 }
 
+// NodeDropAddrIndexHandle drops the address index from the block database
+func NodeDropAddrIndexHandle(ifc interface{}) (e error) {
+	return nodeDropIndexes(ifc, true, false, false)
+}
+
+// NodeDropTxIndexHandle drops the transaction index, and the address index that depends on it, from the block database
+func NodeDropTxIndexHandle(ifc interface{}) (e error) {
+	return nodeDropIndexes(ifc, false, true, false)
+}
+
+// NodeDropCfIndexHandle drops the committed filter index from the block database
+func NodeDropCfIndexHandle(ifc interface{}) (e error) {
+	return nodeDropIndexes(ifc, false, false, true)
+}
+
+// NodeDropIndexesHandle drops all of the optional indexes from the block database
+func NodeDropIndexesHandle(ifc interface{}) (e error) {
+	return nodeDropIndexes(ifc, true, true, true)
+}
+
+func nodeDropIndexes(ifc interface{}, addr, tx, cf bool) (e error) {
+	var cx *state.State
+	var ok bool
+	if cx, ok = ifc.(*state.State); !ok {
+		return fmt.Errorf("cannot run without a state")
+	}
+	cx.StateCfg.DropAddrIndex = addr
+	cx.StateCfg.DropTxIndex = tx
+	cx.StateCfg.DropCfIndex = cf
+	return node.DropIndexes(cx)
+}
+
+// NodeResetChainHandle deletes the block database so the chain is downloaded again on the next start
+func NodeResetChainHandle(ifc interface{}) (e error) {
+	var cx *state.State
+	var ok bool
+	if cx, ok = ifc.(*state.State); !ok {
+		return fmt.Errorf("cannot run without a state")
+	}
+	return node.ResetChain(cx)
+}
+
+// NodeExportUtxoHandle writes a snapshot of the utxo set of the block database to a file
+func NodeExportUtxoHandle(ifc interface{}) (e error) {
+	var cx *state.State
+	var ok bool
+	if cx, ok = ifc.(*state.State); !ok {
+		return fmt.Errorf("cannot run without a state")
+	}
+	return node.ExportUtxoSnapshot(cx)
+}
+
+// NodeImportUtxoHandle creates a new block database from a utxo set snapshot
+func NodeImportUtxoHandle(ifc interface{}) (e error) {
+	var cx *state.State
+	var ok bool
+	if cx, ok = ifc.(*state.State); !ok {
+		return fmt.Errorf("cannot run without a state")
+	}
+	return node.ImportUtxoSnapshot(cx)
+}
+
 // WalletHandle runs the wallet server
 func WalletHandle(ifc interface{}) (e error) {
 	var cx *state.State
@@ -113,6 +175,20 @@ func WalletHandle(ifc interface{}) (e error) {
 	return
 }
 
+// WalletDropHistoryHandle clears the wallet transaction history with the wallet server stopped
+func WalletDropHistoryHandle(ifc interface{}) (e error) {
+	var cx *state.State
+	var ok bool
+	if cx, ok = ifc.(*state.State); !ok {
+		return fmt.Errorf("cannot run without a state")
+	}
+	cx.Config.WalletFile.Set(filepath.Join(cx.Config.DataDir.V(), cx.ActiveNet.Name, constant.DbName))
+	if !apputil.FileExists(cx.Config.WalletFile.V()) {
+		return fmt.Errorf("no wallet found at '%s'", cx.Config.WalletFile.V())
+	}
+	return wallet.DropHistory(cx.Config.WalletFile.V())
+}
+
 func CtlHandleList(ifc interface{}) (e error) {
 	fmt.Println(ctl.ListCommands())
 	return nil
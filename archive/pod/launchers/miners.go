@@ -51,6 +51,9 @@ func Worker(ifc interface{}) (e error) {
 		if os.Args[3] == chaincfg.TestNet3Params.Name {
 			fork.IsTestnet = true
 		}
+		if e = fork.SetNetwork(os.Args[3]); E.Chk(e) {
+			return
+		}
 	}
 	if len(os.Args) > 2 {
 		log.SetLogLevel(os.Args[4])
@@ -110,6 +110,19 @@ func GetConfigs() (c config.Configs) {
 		},
 			false,
 		),
+		"AssumeValid": text.New(meta.Data{
+			Aliases: []string{"AV"},
+			Group:   "node",
+			Tags:    tags("node"),
+			Label:   "Assume Valid",
+			Description:
+			"hash of a block whose scripts and those of its ancestors are not checked during the initial block" +
+				" download, empty to check them all",
+			Documentation: "<placeholder for detailed documentation>",
+			OmitEmpty:     true,
+		},
+			"",
+		),
 		"AutoPorts": binary.New(meta.Data{
 			Group: "debug",
 			Label: "Automatic Ports",
@@ -608,6 +621,42 @@ func GetConfigs() (c config.Configs) {
 		},
 			"pa55word",
 		),
+		"MulticastTrustedKeys": list.New(meta.Data{
+			Aliases: []string{"MTK"},
+			Group:   "config",
+			Tags:    tags("kopach"),
+			Label:   "Multicast Trusted Keys",
+			Description:
+			"public keys of the mining controllers that work and pause messages are accepted from, any controller is accepted if none are given",
+			Documentation: "<placeholder for detailed documentation>",
+			OmitEmpty:     true,
+		},
+			[]string{},
+		),
+		"MulticastWorkerKeys": list.New(meta.Data{
+			Aliases: []string{"MWK"},
+			Group:   "config",
+			Tags:    tags("node"),
+			Label:   "Multicast Worker Keys",
+			Description:
+			"public keys of the kopach miners that solutions, shares and hashrate reports are accepted from, which they print when they start, any miner is accepted with a warning if none are given",
+			Documentation: "<placeholder for detailed documentation>",
+			OmitEmpty:     true,
+		},
+			[]string{},
+		),
+		"MiningPayouts": list.New(meta.Data{
+			Aliases: []string{"MPO"},
+			Group:   "mining",
+			Tags:    tags("node"),
+			Label:   "Mining Payouts",
+			Description:
+			"addresses to split the reward of mined blocks between, as address:weight, the weight is 1 if left out",
+			Documentation: "<placeholder for detailed documentation>",
+			OmitEmpty:     true,
+		},
+			[]string{},
+		),
 		"MinRelayTxFee": float.New(meta.Data{
 			Aliases: []string{"MRTF"},
 			Group:   "policy",
@@ -816,6 +865,43 @@ func GetConfigs() (c config.Configs) {
 		},
 			false,
 		),
+		"PoolMode": binary.New(meta.Data{
+			Aliases: []string{"PLM"},
+			Group:   "mining",
+			Tags:    tags("node"),
+			Label:   "Pool Mode",
+			Description:
+			"record shares from kopach workers and stratum miners, whose worker names must start with their payout address, and split block rewards between their payout addresses by PPLNS",
+			Documentation: "<placeholder for detailed documentation>",
+			OmitEmpty:     true,
+		},
+			false,
+		),
+		"PoolPayoutAddress": text.New(meta.Data{
+			Aliases: []string{"PPA"},
+			Group:   "mining",
+			Tags:    tags("kopach"),
+			Label:   "Pool Payout Address",
+			Description:
+			"address that shares found by this miner are credited to when mining for a controller in pool mode",
+			Documentation: "<placeholder for detailed documentation>",
+			OmitEmpty:     true,
+		},
+			"",
+		),
+		"PoolWindow": integer.New(meta.Data{
+			Aliases: []string{"PLW"},
+			Group:   "mining",
+			Tags:    tags("node"),
+			Label:   "Pool Window",
+			Description:
+			"number of blocks worth of work in the last N shares that pool block rewards are split over",
+			Documentation: "<placeholder for detailed documentation>",
+			OmitEmpty:     true,
+		},
+			2,
+			1, 1000,
+		),
 		"Profile": text.New(meta.Data{
 			Aliases: []string{"HPR"},
 			Group:   "debug",
@@ -867,6 +953,19 @@ func GetConfigs() (c config.Configs) {
 		},
 			"proxyuser",
 		),
+		"PruneTarget": integer.New(meta.Data{
+			Aliases: []string{"PRUNE"},
+			Group:   "node",
+			Tags:    tags("node"),
+			Label:   "Prune Target",
+			Description:
+			"delete the oldest blocks to keep the stored blocks under this size in MiB, 0 keeps all blocks (minimum 1024)",
+			Documentation: "<placeholder for detailed documentation>",
+			OmitEmpty:     true,
+		},
+			0,
+			0, 1<<30,
+		),
 		"RejectNonStd": binary.New(meta.Data{
 			Aliases: []string{"REJ"},
 			Group:   "node",
@@ -891,6 +990,18 @@ func GetConfigs() (c config.Configs) {
 		},
 			false,
 		),
+		"REST": binary.New(meta.Data{
+			Aliases: []string{"REST"},
+			Group:   "rpc",
+			Tags:    tags("node"),
+			Label:   "REST API",
+			Description:
+			"serve the public REST interface for blocks, transactions, chain info, mempool and utxos under /rest/ on the RPC listeners",
+			Documentation: "<placeholder for detailed documentation>",
+			OmitEmpty:     true,
+		},
+			false,
+		),
 		"RPCCert": text.New(meta.Data{
 			Aliases: []string{"RC"},
 			Group:   "rpc",
@@ -1016,6 +1127,19 @@ func GetConfigs() (c config.Configs) {
 		},
 			false,
 		),
+		"ScryptRPCListeners": list.New(meta.Data{
+			Aliases: []string{"SRL"},
+			Group:   "rpc",
+			Tags:    tags("node"),
+			Label:   "Scrypt Node RPC Listeners",
+			Description:
+			"addresses to listen for RPC connections that hand out scrypt work to getwork miners",
+			Type:          sanitizers.NetAddress,
+			Documentation: "<placeholder for detailed documentation>",
+			OmitEmpty:     true,
+		},
+			[]string{},
+		),
 		"ServerTLS": binary.New(meta.Data{
 			Aliases: []string{"ST"},
 			Group:   "wallet",
@@ -1052,6 +1176,32 @@ func GetConfigs() (c config.Configs) {
 		},
 			false,
 		),
+		"StratumDifficulty": float.New(meta.Data{
+			Aliases: []string{"SDF"},
+			Group:   "mining",
+			Tags:    tags("node"),
+			Label:   "Stratum Difficulty",
+			Description:
+			"share difficulty given to newly connected stratum miners, 1 is the minimum difficulty of the algorithm they mine",
+			Documentation: "<placeholder for detailed documentation>",
+			OmitEmpty:     true,
+		},
+			1,
+			0, math.MaxFloat64,
+		),
+		"StratumListeners": list.New(meta.Data{
+			Aliases: []string{"SML"},
+			Group:   "mining",
+			Tags:    tags("node"),
+			Label:   "Stratum Listeners",
+			Description:
+			"addresses to listen for stratum v1 miner connections, the stratum server is disabled when empty",
+			Type:          sanitizers.NetAddress,
+			Documentation: "<placeholder for detailed documentation>",
+			OmitEmpty:     true,
+		},
+			[]string{},
+		),
 		"ClientTLS": binary.New(meta.Data{
 			Aliases: []string{"CT"},
 			Group:   "tls",
@@ -1149,6 +1299,32 @@ func GetConfigs() (c config.Configs) {
 		},
 			"username",
 		),
+		"UTXOSnapshot": text.New(meta.Data{
+			Aliases: []string{"UTXOSNAP"},
+			Group:   "node",
+			Tags:    tags("node"),
+			Label:   "UTXO Snapshot",
+			Description:
+			"file the node exportutxo and importutxo commands write and read the utxo set snapshot to and from," +
+				" empty for utxo.snapshot in the network data directory",
+			Documentation: "<placeholder for detailed documentation>",
+			OmitEmpty:     true,
+		},
+			"",
+		),
+		"UTXOSnapshotHash": text.New(meta.Data{
+			Aliases: []string{"UTXOSNAPHASH"},
+			Group:   "node",
+			Tags:    tags("node"),
+			Label:   "UTXO Snapshot Hash",
+			Description:
+			"set hash of the utxo set at the snapshot block as gettxoutsetinfo reports it on a node you trust," +
+				" which the snapshot must match to be imported, empty to use the one in the chain parameters",
+			Documentation: "<placeholder for detailed documentation>",
+			OmitEmpty:     true,
+		},
+			"",
+		),
 		"UUID": integer.New(meta.Data{
 			Label: "UUID",
 			Description:
@@ -1159,6 +1335,18 @@ func GetConfigs() (c config.Configs) {
 			int64(rand.Uint32()),
 			-math.MaxInt64, math.MaxInt64,
 		),
+		"UseSPV": binary.New(meta.Data{
+			Aliases: []string{"SPV"},
+			Group:   "wallet",
+			Tags:    tags("wallet"),
+			Label:   "Use SPV",
+			Description:
+			"sync the wallet from the peer to peer network with compact block filters instead of the chain server RPC",
+			Documentation: "<placeholder for detailed documentation>",
+			OmitEmpty:     true,
+		},
+			false,
+		),
 		"UseWallet": binary.New(meta.Data{
 			Aliases: []string{"WC"},
 			Group:   "debug",
@@ -1280,6 +1468,66 @@ func GetConfigs() (c config.Configs) {
 		},
 			[]string{},
 		),
+		"ZMQPubAlgoBlock": text.New(meta.Data{
+			Aliases: []string{"ZPA"},
+			Group:   "node",
+			Tags:    tags("node"),
+			Label:   "ZMQ Publish Algo Block",
+			Description:
+			"zmq endpoint to publish the hash, algorithm and difficulty of connected blocks on, with the next difficulty of every algorithm, on the algoblock topic",
+			Documentation: "<placeholder for detailed documentation>",
+			OmitEmpty:     true,
+		},
+			"",
+		),
+		"ZMQPubHashBlock": text.New(meta.Data{
+			Aliases: []string{"ZPHB"},
+			Group:   "node",
+			Tags:    tags("node"),
+			Label:   "ZMQ Publish Hash Block",
+			Description:
+			"zmq endpoint to publish the hashes of connected blocks on, on the hashblock topic",
+			Documentation: "<placeholder for detailed documentation>",
+			OmitEmpty:     true,
+		},
+			"",
+		),
+		"ZMQPubHashTx": text.New(meta.Data{
+			Aliases: []string{"ZPHT"},
+			Group:   "node",
+			Tags:    tags("node"),
+			Label:   "ZMQ Publish Hash Tx",
+			Description:
+			"zmq endpoint to publish the hashes of transactions accepted to the mempool or connected in blocks on, on the hashtx topic",
+			Documentation: "<placeholder for detailed documentation>",
+			OmitEmpty:     true,
+		},
+			"",
+		),
+		"ZMQPubRawBlock": text.New(meta.Data{
+			Aliases: []string{"ZPRB"},
+			Group:   "node",
+			Tags:    tags("node"),
+			Label:   "ZMQ Publish Raw Block",
+			Description:
+			"zmq endpoint to publish connected blocks on, on the rawblock topic",
+			Documentation: "<placeholder for detailed documentation>",
+			OmitEmpty:     true,
+		},
+			"",
+		),
+		"ZMQPubRawTx": text.New(meta.Data{
+			Aliases: []string{"ZPRT"},
+			Group:   "node",
+			Tags:    tags("node"),
+			Label:   "ZMQ Publish Raw Tx",
+			Description:
+			"zmq endpoint to publish transactions accepted to the mempool or connected in blocks on, on the rawtx topic",
+			Documentation: "<placeholder for detailed documentation>",
+			OmitEmpty:     true,
+		},
+			"",
+		),
 	}
 	for i := range c {
 		c[i].SetName(i)
@@ -51,23 +51,31 @@ func GetCommands() (c cmds.Commands) {
 			Commands: []cmds.Command{
 				{Name: "dropaddrindex", Title:
 				"drop the address database index",
-					Entrypoint: func(c interface{}) error { return nil },
+					Entrypoint: launchers.NodeDropAddrIndexHandle,
 				},
 				{Name: "droptxindex", Title:
 				"drop the transaction database index",
-					Entrypoint: func(c interface{}) error { return nil },
+					Entrypoint: launchers.NodeDropTxIndexHandle,
 				},
 				{Name: "dropcfindex", Title:
 				"drop the cfilter database index",
-					Entrypoint: func(c interface{}) error { return nil },
+					Entrypoint: launchers.NodeDropCfIndexHandle,
 				},
 				{Name: "dropindexes", Title:
 				"drop all of the indexes",
-					Entrypoint: func(c interface{}) error { return nil },
+					Entrypoint: launchers.NodeDropIndexesHandle,
 				},
 				{Name: "resetchain", Title:
 				"deletes the current blockchain cache to force redownload",
-					Entrypoint: func(c interface{}) error { return nil },
+					Entrypoint: launchers.NodeResetChainHandle,
+				},
+				{Name: "exportutxo", Title:
+				"write a snapshot of the utxo set at the chain tip to a file",
+					Entrypoint: launchers.NodeExportUtxoHandle,
+				},
+				{Name: "importutxo", Title:
+				"create a new chain from a utxo set snapshot and download the older blocks in the background",
+					Entrypoint: launchers.NodeImportUtxoHandle,
 				},
 			},
 		},
@@ -77,7 +85,7 @@ func GetCommands() (c cmds.Commands) {
 			Commands: []cmds.Command{
 				{Name: "drophistory", Title:
 				"reset the wallet transaction history",
-					Entrypoint: func(c interface{}) error { return nil },
+					Entrypoint: launchers.WalletDropHistoryHandle,
 				},
 			},
 			Colorizer: color.Bit24(255, 255, 128, false).Sprint,
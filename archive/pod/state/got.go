@@ -25,6 +25,7 @@ import (
 	"github.com/p9c/pod/pkg/chainrpc"
 	"github.com/p9c/pod/pkg/connmgr"
 	"github.com/p9c/pod/pkg/fork"
+	"github.com/p9c/pod/pkg/mining"
 	"github.com/p9c/pod/pkg/pipe"
 	"github.com/p9c/pod/pkg/util"
 	"github.com/p9c/pod/pkg/util/routeable"
@@ -74,6 +75,10 @@ func GetNew(
 		}
 		s.ActiveNet = &chaincfg.MainNetParams
 	}
+	// the proof of work algorithms can differ between the networks
+	if e = fork.SetNetwork(s.ActiveNet.Name); E.Chk(e) {
+		return
+	}
 	if (s.Config.LAN.True() || s.Config.Solo.True()) && s.ActiveNet.Name == "mainnet" {
 		if e = fmt.Errorf("neither Solo or LAN can be active on mainnet for obvious reasons"); F.Chk(e) {
 			return
@@ -234,6 +239,13 @@ func GetNew(
 		_, _ = fmt.Fprintln(os.Stderr, e)
 		os.Exit(0)
 	}
+	T.Ln("checking mining payouts")
+	if s.StateCfg.ActiveMiningPayouts, e = mining.ParsePayouts(s.Config.MiningPayouts.S(), s.ActiveNet); e != nil {
+		E.Ln(e)
+		e = fmt.Errorf("invalid miningpayouts: %v", e)
+		_, _ = fmt.Fprintln(os.Stderr, e)
+		os.Exit(1)
+	}
 	I.Ln("autolisten", s.Config.AutoListen.True())
 	// if autolisten is set, set default ports on all p2p listeners discovered to be available
 	if s.Config.AutoListen.True() {